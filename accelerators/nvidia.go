@@ -49,18 +49,25 @@ var sysFsPCIDevicesPath = "/sys/bus/pci/devices/"
 
 const nvidiaVendorID = "0x10de"
 
-func NewNvidiaManager(includedMetrics container.MetricSet) stats.Manager {
+// NewNvidiaManager returns a manager of the collectors of NVIDIA GPU metrics.
+// If NVIDIA devices are present but NVML cannot be initialized, the manager
+// is returned with an error wrapping stats.ErrUnavailable, and retries to
+// initialize NVML when collectors are requested.
+func NewNvidiaManager(includedMetrics container.MetricSet) (stats.Manager, error) {
 	if !includedMetrics.Has(container.AcceleratorUsageMetrics) {
 		klog.V(2).Info("NVIDIA GPU metrics disabled")
-		return &stats.NoopManager{}
+		return &stats.NoopManager{}, nil
 	}
 
 	manager := &nvidiaManager{}
 	err := manager.setup()
 	if err != nil {
 		klog.V(2).Infof("NVIDIA setup failed: %s", err)
+		if manager.devicesPresent {
+			return manager, fmt.Errorf("%w: %v", stats.ErrUnavailable, err)
+		}
 	}
-	return manager
+	return manager, nil
 }
 
 // setup initializes NVML if NVIDIA devices are present on the node.
@@ -0,0 +1,104 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+)
+
+// DownsampledStats are the stats of a container downsampled over an
+// interval.
+type DownsampledStats struct {
+	Stats       *info.ContainerStats
+	Aggregation v2.StatsAggregation
+}
+
+// bucket accumulates the samples of a downsampling interval.
+type bucket struct {
+	start   time.Time
+	samples int
+	last    *info.ContainerStats
+	max     v2.MaxStats
+	// Sums of the averaged values.
+	memoryUsage      uint64
+	memoryWorkingSet uint64
+	memoryRSS        uint64
+	memoryCache      uint64
+	memorySwap       uint64
+	loadAverage      int64
+	processCount     uint64
+	fdCount          uint64
+}
+
+// Adds stats to the bucket. prev is the previous sample, if any.
+func (b *bucket) add(prev, stats *info.ContainerStats) {
+	b.samples++
+	b.last = stats
+	b.memoryUsage += stats.Memory.Usage
+	b.memoryWorkingSet += stats.Memory.WorkingSet
+	b.memoryRSS += stats.Memory.RSS
+	b.memoryCache += stats.Memory.Cache
+	b.memorySwap += stats.Memory.Swap
+	b.loadAverage += int64(stats.Cpu.LoadAverage)
+	b.processCount += stats.Processes.ProcessCount
+	b.fdCount += stats.Processes.FdCount
+
+	b.max.MemoryUsage = maxUint64(b.max.MemoryUsage, stats.Memory.Usage)
+	b.max.MemoryWorkingSet = maxUint64(b.max.MemoryWorkingSet, stats.Memory.WorkingSet)
+	b.max.MemoryRSS = maxUint64(b.max.MemoryRSS, stats.Memory.RSS)
+	if stats.Cpu.LoadAverage > b.max.LoadAverage {
+		b.max.LoadAverage = stats.Cpu.LoadAverage
+	}
+	b.max.ProcessCount = maxUint64(b.max.ProcessCount, stats.Processes.ProcessCount)
+	b.max.FileDescriptorCount = maxUint64(b.max.FileDescriptorCount, stats.Processes.FdCount)
+	if prev != nil && stats.Timestamp.After(prev.Timestamp) && stats.Cpu.Usage.Total >= prev.Cpu.Usage.Total {
+		elapsed := stats.Timestamp.Sub(prev.Timestamp).Seconds()
+		usage := uint64(float64(stats.Cpu.Usage.Total-prev.Cpu.Usage.Total) / elapsed)
+		b.max.CpuUsage = maxUint64(b.max.CpuUsage, usage)
+	}
+}
+
+// Returns the downsampled stats of the bucket.
+func (b *bucket) downsample(interval time.Duration) *DownsampledStats {
+	n := uint64(b.samples)
+	stats := *b.last
+	stats.Memory.Usage = b.memoryUsage / n
+	stats.Memory.WorkingSet = b.memoryWorkingSet / n
+	stats.Memory.RSS = b.memoryRSS / n
+	stats.Memory.Cache = b.memoryCache / n
+	stats.Memory.Swap = b.memorySwap / n
+	stats.Cpu.LoadAverage = int32(b.loadAverage / int64(n))
+	stats.Processes.ProcessCount = b.processCount / n
+	stats.Processes.FdCount = b.fdCount / n
+	return &DownsampledStats{
+		Stats: &stats,
+		Aggregation: v2.StatsAggregation{
+			Start:    b.start,
+			Interval: interval,
+			Samples:  b.samples,
+			Max:      b.max,
+		},
+	}
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -29,6 +29,9 @@ import (
 // ErrDataNotFound is the error resulting if failed to find a container in memory cache.
 var ErrDataNotFound = errors.New("unable to find data in memory cache")
 
+// ErrDownsamplingDisabled is the error resulting if downsampled stats are requested from a memory cache that does not keep them.
+var ErrDownsamplingDisabled = errors.New("downsampled stats are not kept")
+
 // TODO(vmarmol): See about refactoring this class, we have an unnecessary redirection of containerCache and InMemoryCache.
 // containerCache is used to store per-container information
 type containerCache struct {
@@ -36,6 +39,13 @@ type containerCache struct {
 	recentStats *utils.TimedStore
 	maxAge      time.Duration
 	lock        sync.RWMutex
+
+	// Downsampled stats, nil when disabled.
+	downsampledStats   *utils.TimedStore
+	downsampleInterval time.Duration
+	// Interval being downsampled.
+	bucket    *bucket
+	lastStats *info.ContainerStats
 }
 
 func (c *containerCache) AddStats(stats *info.ContainerStats) error {
@@ -44,9 +54,47 @@ func (c *containerCache) AddStats(stats *info.ContainerStats) error {
 
 	// Add the stat to storage.
 	c.recentStats.Add(stats.Timestamp, stats)
+	if c.downsampledStats != nil {
+		c.downsample(stats)
+	}
 	return nil
 }
 
+// Adds stats to the interval being downsampled, storing the downsampled
+// stats of the previous interval once stats of a later interval come in.
+func (c *containerCache) downsample(stats *info.ContainerStats) {
+	start := stats.Timestamp.Truncate(c.downsampleInterval)
+	if c.bucket != nil && start.Before(c.bucket.start) {
+		// Out of order stats.
+		return
+	}
+	if c.bucket != nil && start.After(c.bucket.start) {
+		downsampled := c.bucket.downsample(c.downsampleInterval)
+		c.downsampledStats.Add(downsampled.Stats.Timestamp, downsampled)
+		c.bucket = nil
+	}
+	if c.bucket == nil {
+		c.bucket = &bucket{start: start}
+	}
+	c.bucket.add(c.lastStats, stats)
+	c.lastStats = stats
+}
+
+// DownsampledStats returns the downsampled stats of the complete intervals.
+func (c *containerCache) DownsampledStats(start, end time.Time, maxStats int) ([]*DownsampledStats, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.downsampledStats == nil {
+		return nil, ErrDownsamplingDisabled
+	}
+	result := c.downsampledStats.InTimeRange(start, end, maxStats)
+	converted := make([]*DownsampledStats, len(result))
+	for i, el := range result {
+		converted[i] = el.(*DownsampledStats)
+	}
+	return converted, nil
+}
+
 func (c *containerCache) RecentStats(start, end time.Time, maxStats int) ([]*info.ContainerStats, error) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -58,12 +106,17 @@ func (c *containerCache) RecentStats(start, end time.Time, maxStats int) ([]*inf
 	return converted, nil
 }
 
-func newContainerStore(ref info.ContainerReference, maxAge time.Duration) *containerCache {
-	return &containerCache{
-		ref:         ref,
-		recentStats: utils.NewTimedStore(maxAge, -1),
-		maxAge:      maxAge,
+func newContainerStore(ref info.ContainerReference, maxAge, downsampleInterval, downsampledMaxAge time.Duration) *containerCache {
+	c := &containerCache{
+		ref:                ref,
+		recentStats:        utils.NewTimedStore(maxAge, -1),
+		maxAge:             maxAge,
+		downsampleInterval: downsampleInterval,
+	}
+	if downsampledMaxAge > 0 {
+		c.downsampledStats = utils.NewTimedStore(downsampledMaxAge, -1)
 	}
+	return c
 }
 
 type InMemoryCache struct {
@@ -71,6 +124,9 @@ type InMemoryCache struct {
 	containerCacheMap map[string]*containerCache
 	maxAge            time.Duration
 	backend           []storage.StorageDriver
+
+	downsampleInterval time.Duration
+	downsampledMaxAge  time.Duration
 }
 
 func (c *InMemoryCache) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
@@ -81,7 +137,7 @@ func (c *InMemoryCache) AddStats(cInfo *info.ContainerInfo, stats *info.Containe
 		c.lock.Lock()
 		defer c.lock.Unlock()
 		if cstore, ok = c.containerCacheMap[cInfo.ContainerReference.Name]; !ok {
-			cstore = newContainerStore(cInfo.ContainerReference, c.maxAge)
+			cstore = newContainerStore(cInfo.ContainerReference, c.maxAge, c.downsampleInterval, c.downsampledMaxAge)
 			c.containerCacheMap[cInfo.ContainerReference.Name] = cstore
 		}
 	}()
@@ -115,6 +171,23 @@ func (c *InMemoryCache) RecentStats(name string, start, end time.Time, maxStats
 	return cstore.RecentStats(start, end, maxStats)
 }
 
+// DownsampledStats returns the downsampled stats of a container, see
+// NewWithDownsampling.
+func (c *InMemoryCache) DownsampledStats(name string, start, end time.Time, maxStats int) ([]*DownsampledStats, error) {
+	c.lock.RLock()
+	cstore, ok := c.containerCacheMap[name]
+	c.lock.RUnlock()
+	if !ok {
+		return nil, ErrDataNotFound
+	}
+	return cstore.DownsampledStats(start, end, maxStats)
+}
+
+// DownsamplingEnabled returns whether the cache keeps downsampled stats.
+func (c *InMemoryCache) DownsamplingEnabled() bool {
+	return c.downsampledMaxAge > 0
+}
+
 func (c *InMemoryCache) Close() error {
 	c.lock.Lock()
 	c.containerCacheMap = make(map[string]*containerCache, 32)
@@ -122,6 +195,51 @@ func (c *InMemoryCache) Close() error {
 	return nil
 }
 
+// RestoreStats adds stats of a container saved by a previous run, without
+// writing them to the storage drivers.
+func (c *InMemoryCache) RestoreStats(ref info.ContainerReference, stats []*info.ContainerStats) {
+	c.lock.Lock()
+	cstore, ok := c.containerCacheMap[ref.Name]
+	if !ok {
+		cstore = newContainerStore(ref, c.maxAge, c.downsampleInterval, c.downsampledMaxAge)
+		c.containerCacheMap[ref.Name] = cstore
+	}
+	c.lock.Unlock()
+	for _, s := range stats {
+		cstore.AddStats(s)
+	}
+}
+
+// PruneContainers removes the containers for which keep returns false.
+func (c *InMemoryCache) PruneContainers(keep func(containerName string) bool) {
+	c.lock.RLock()
+	names := make([]string, 0, len(c.containerCacheMap))
+	for name := range c.containerCacheMap {
+		names = append(names, name)
+	}
+	c.lock.RUnlock()
+	// keep is called without the lock, which the manager takes while
+	// holding its own locks.
+	for _, name := range names {
+		if !keep(name) {
+			c.RemoveContainer(name)
+		}
+	}
+}
+
+// Size returns the number of containers in the cache, and the number of
+// their recent stats.
+func (c *InMemoryCache) Size() (containers, stats int) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, cstore := range c.containerCacheMap {
+		cstore.lock.RLock()
+		stats += cstore.recentStats.Size()
+		cstore.lock.RUnlock()
+	}
+	return len(c.containerCacheMap), stats
+}
+
 func (c *InMemoryCache) RemoveContainer(containerName string) error {
 	c.lock.Lock()
 	delete(c.containerCacheMap, containerName)
@@ -140,3 +258,20 @@ func New(
 	}
 	return ret
 }
+
+// NewWithDownsampling creates a memory cache which, besides the stats of the
+// last maxAge, keeps the stats of the last downsampledMaxAge downsampled over
+// intervals of downsampleInterval.
+func NewWithDownsampling(
+	maxAge time.Duration,
+	downsampleInterval time.Duration,
+	downsampledMaxAge time.Duration,
+	backend []storage.StorageDriver,
+) *InMemoryCache {
+	ret := New(maxAge, backend)
+	if downsampleInterval > 0 {
+		ret.downsampleInterval = downsampleInterval
+		ret.downsampledMaxAge = downsampledMaxAge
+	}
+	return ret
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -64,6 +65,10 @@ func TestAddStats(t *testing.T) {
 	}
 	assert.Nil(memoryCache.AddStats(&cInfo2, makeStat(0)))
 	assert.Nil(memoryCache.AddStats(&cInfo2, makeStat(1)))
+
+	containers, stats := memoryCache.Size()
+	assert.Equal(2, containers)
+	assert.Equal(6, stats)
 }
 
 func TestRecentStatsNoRecentStats(t *testing.T) {
@@ -100,3 +105,105 @@ func TestRecentStatsGetAllStats(t *testing.T) {
 
 	assert.Len(t, getRecentStats(t, memoryCache, -1), 10)
 }
+
+func makeDownsamplingStat(i int) *info.ContainerStats {
+	stats := makeStat(i)
+	stats.Cpu.Usage.Total = uint64(i) * uint64(time.Second)
+	stats.Memory.Usage = uint64(i) * 10
+	stats.Processes.ProcessCount = 1
+	return stats
+}
+
+func TestDownsampledStats(t *testing.T) {
+	memoryCache := NewWithDownsampling(5*time.Second, 10*time.Second, time.Hour, nil)
+	require.True(t, memoryCache.DownsamplingEnabled())
+	for i := 0; i < 25; i++ {
+		stats := makeDownsamplingStat(i)
+		if i == 15 {
+			// Two cores used for a second.
+			stats.Cpu.Usage.Total += uint64(time.Second)
+		}
+		if i > 15 {
+			stats.Cpu.Usage.Total += uint64(time.Second)
+		}
+		require.NoError(t, memoryCache.AddStats(&cInfo, stats))
+	}
+	// Out of order stats are ignored.
+	require.NoError(t, memoryCache.AddStats(&cInfo, makeDownsamplingStat(3)))
+
+	// Only the complete intervals are downsampled.
+	downsampled, err := memoryCache.DownsampledStats(containerName, zero, zero, -1)
+	require.NoError(t, err)
+	require.Len(t, downsampled, 2)
+
+	first := downsampled[0]
+	assert.Equal(t, zero.Add(9*time.Second), first.Stats.Timestamp)
+	assert.Equal(t, uint64(9*time.Second), first.Stats.Cpu.Usage.Total)
+	assert.Equal(t, uint64(45), first.Stats.Memory.Usage)
+	assert.Equal(t, int32(4), first.Stats.Cpu.LoadAverage)
+	assert.Equal(t, zero, first.Aggregation.Start)
+	assert.Equal(t, 10*time.Second, first.Aggregation.Interval)
+	assert.Equal(t, 10, first.Aggregation.Samples)
+	assert.Equal(t, uint64(90), first.Aggregation.Max.MemoryUsage)
+	assert.Equal(t, int32(9), first.Aggregation.Max.LoadAverage)
+	assert.Equal(t, uint64(time.Second), first.Aggregation.Max.CpuUsage)
+	assert.Equal(t, uint64(1), first.Aggregation.Max.ProcessCount)
+
+	second := downsampled[1]
+	assert.Equal(t, zero.Add(10*time.Second), second.Aggregation.Start)
+	assert.Equal(t, uint64(145), second.Stats.Memory.Usage)
+	assert.Equal(t, uint64(2*time.Second), second.Aggregation.Max.CpuUsage)
+
+	// The raw stats are unaffected.
+	raw := getRecentStats(t, memoryCache, -1)
+	assert.Equal(t, uint64(240), raw[len(raw)-1].Memory.Usage)
+
+	latest, err := memoryCache.DownsampledStats(containerName, zero, zero, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []*DownsampledStats{second}, latest)
+}
+
+func TestDownsamplingDisabled(t *testing.T) {
+	memoryCache := New(time.Minute, nil)
+	assert.False(t, memoryCache.DownsamplingEnabled())
+	require.NoError(t, memoryCache.AddStats(&cInfo, makeStat(0)))
+	_, err := memoryCache.DownsampledStats(containerName, zero, zero, -1)
+	assert.Equal(t, ErrDownsamplingDisabled, err)
+}
+
+type countingDriver struct {
+	added int
+}
+
+func (d *countingDriver) AddStats(*info.ContainerInfo, *info.ContainerStats) error {
+	d.added++
+	return nil
+}
+
+func (d *countingDriver) Close() error {
+	return nil
+}
+
+func TestRestoreStats(t *testing.T) {
+	driver := &countingDriver{}
+	memoryCache := New(time.Minute, []storage.StorageDriver{driver})
+	memoryCache.RestoreStats(cInfo.ContainerReference, []*info.ContainerStats{makeStat(0), makeStat(1)})
+	require.NoError(t, memoryCache.AddStats(&cInfo, makeStat(2)))
+
+	stats := getRecentStats(t, memoryCache, -1)
+	assert.Equal(t, []*info.ContainerStats{makeStat(0), makeStat(1), makeStat(2)}, stats)
+	// Restored stats are not written to the storage drivers again.
+	assert.Equal(t, 1, driver.added)
+}
+
+func TestPruneContainers(t *testing.T) {
+	memoryCache := New(time.Minute, nil)
+	gone := info.ContainerReference{Name: "/gone"}
+	memoryCache.RestoreStats(gone, []*info.ContainerStats{makeStat(0)})
+	require.NoError(t, memoryCache.AddStats(&cInfo, makeStat(0)))
+
+	memoryCache.PruneContainers(func(name string) bool { return name == containerName })
+	_, err := memoryCache.RecentStats("/gone", zero, zero, -1)
+	assert.Equal(t, ErrDataNotFound, err)
+	assert.Len(t, getRecentStats(t, memoryCache, -1), 1)
+}
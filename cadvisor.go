@@ -0,0 +1,152 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cadvisor
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/cadvisor/cache/memory"
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/manager"
+	"github.com/google/cadvisor/utils/sysfs"
+
+	"k8s.io/klog/v2"
+)
+
+// Intervals between the collections of cAdvisor. The zero intervals keep
+// their defaults.
+type Intervals struct {
+	// Interval between the housekeepings of a container, collecting its
+	// stats, raised up to MaxHousekeeping while its stats don't change.
+	Housekeeping    time.Duration
+	MaxHousekeeping time.Duration
+	// Interval between the global housekeepings, detecting new containers.
+	GlobalHousekeeping time.Duration
+	// Interval between the updates of the machine info.
+	MachineInfoUpdate time.Duration
+}
+
+type config struct {
+	manager         manager.Options
+	storageDuration time.Duration
+	sysFs           sysfs.SysFs
+}
+
+// Option configures cAdvisor.
+type Option func(*config)
+
+// WithIntervals sets the intervals between the collections of cAdvisor.
+func WithIntervals(intervals Intervals) Option {
+	return func(c *config) {
+		set := func(dst *time.Duration, d time.Duration) {
+			if d > 0 {
+				*dst = d
+			}
+		}
+		set(&c.manager.HousekeepingInterval, intervals.Housekeeping)
+		set(&c.manager.MaxHousekeepingInterval, intervals.MaxHousekeeping)
+		set(&c.manager.GlobalHousekeepingInterval, intervals.GlobalHousekeeping)
+		set(&c.manager.UpdateMachineInfoInterval, intervals.MachineInfoUpdate)
+	}
+}
+
+// WithCollectors sets the kinds of metrics collected, instead of the
+// metrics which are not in container.DefaultDisabledMetrics.
+func WithCollectors(metrics ...container.MetricKind) Option {
+	return func(c *config) {
+		c.manager.IncludedMetrics = container.MetricSet{}
+		for _, metric := range metrics {
+			c.manager.IncludedMetrics.Add(metric)
+		}
+	}
+}
+
+// WithStorageDuration sets how long the stats are kept in memory, 2 minutes
+// by default.
+func WithStorageDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.storageDuration = d
+	}
+}
+
+// WithOnDemandHousekeeping only collects the stats of containers when they
+// are requested, serving the stats younger than ttl without collecting them
+// again.
+func WithOnDemandHousekeeping(ttl time.Duration) Option {
+	return func(c *config) {
+		c.manager.OnDemandHousekeeping = true
+		c.manager.OnDemandStatsTTL = ttl
+	}
+}
+
+// WithManagerOptions changes the options of the manager which have no
+// dedicated Option.
+func WithManagerOptions(f func(*manager.Options)) Option {
+	return func(c *config) {
+		f(&c.manager)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		manager:         manager.DefaultOptions(),
+		storageDuration: 2 * time.Minute,
+		sysFs:           sysfs.NewRealSysFs(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Cadvisor monitors the containers of the machine, whose info and stats are
+// served by its embedded manager.
+type Cadvisor struct {
+	manager.Manager
+	done chan struct{}
+}
+
+// New starts monitoring the containers of the machine until ctx is done.
+// Only cgroups are monitored unless the plugins of container runtimes are
+// imported, e.g. github.com/google/cadvisor/container/docker/install.
+//
+// Unlike the cadvisor command, New does not read any flag.
+func New(ctx context.Context, opts ...Option) (*Cadvisor, error) {
+	c := newConfig(opts)
+	m, err := manager.NewWithOptions(memory.New(c.storageDuration, nil), c.sysFs, c.manager)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Start(); err != nil {
+		m.Stop()
+		return nil, err
+	}
+	cadvisor := &Cadvisor{Manager: m, done: make(chan struct{})}
+	go func() {
+		<-ctx.Done()
+		if err := m.Stop(); err != nil {
+			klog.Warningf("Failed to stop cAdvisor: %v", err)
+		}
+		close(cadvisor.done)
+	}()
+	return cadvisor, nil
+}
+
+// Done returns a channel closed once cAdvisor stopped after its context is
+// done.
+func (c *Cadvisor) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cadvisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions(t *testing.T) {
+	c := newConfig(nil)
+	assert.Equal(t, manager.DefaultOptions(), c.manager)
+	assert.Equal(t, 2*time.Minute, c.storageDuration)
+
+	c = newConfig([]Option{
+		WithIntervals(Intervals{Housekeeping: 10 * time.Second, GlobalHousekeeping: 5 * time.Minute}),
+		WithCollectors(container.CpuUsageMetrics, container.MemoryUsageMetrics),
+		WithStorageDuration(time.Hour),
+		WithOnDemandHousekeeping(time.Second),
+		WithManagerOptions(func(o *manager.Options) {
+			o.ContainerInclude = "^/kubepods"
+		}),
+	})
+	expected := manager.DefaultOptions()
+	expected.HousekeepingInterval = 10 * time.Second
+	expected.GlobalHousekeepingInterval = 5 * time.Minute
+	expected.IncludedMetrics = container.MetricSet{
+		container.CpuUsageMetrics:    struct{}{},
+		container.MemoryUsageMetrics: struct{}{},
+	}
+	expected.OnDemandHousekeeping = true
+	expected.OnDemandStatsTTL = time.Second
+	expected.ContainerInclude = "^/kubepods"
+	assert.Equal(t, expected, c.manager)
+	assert.Equal(t, time.Hour, c.storageDuration)
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	_, err := New(context.Background(), WithIntervals(Intervals{Housekeeping: 2 * time.Minute}))
+	assert.Error(t, err, "the housekeeping interval is longer than the max one")
+}
@@ -0,0 +1,424 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v3 is a client of the cAdvisor v2.1 REST API whose methods take a
+// context, retry the requests which may succeed later and return the
+// structured errors of the API.
+package v3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+)
+
+// Client is a client of a cAdvisor instance.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client sending the requests, e.g. to set up
+// TLS or authentication. http.DefaultClient is used by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets the number of times a request is retried when cAdvisor
+// can't be reached or fails with a retryable error, and the delay before the
+// first retry, doubled for each of the following ones. Requests are retried
+// twice after 100ms by default.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// NewClient returns a client of the cAdvisor instance at baseURL, e.g.
+// "http://localhost:8080/".
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %v", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: the scheme must be http or https", baseURL)
+	}
+	u.Path = path.Join("/", u.Path, "api/v2.1") + "/"
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retries:    2,
+		backoff:    100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Error is the error returned by cAdvisor for a failed request.
+type Error struct {
+	// HTTP status code of the response.
+	StatusCode int `json:"code"`
+	// Machine-readable cause of the failure, e.g. "NotFound". Empty for
+	// servers which don't return structured errors.
+	Reason string `json:"reason"`
+	// Whether the same request may succeed later.
+	Retryable bool `json:"retryable"`
+	// Human-readable description of the failure.
+	Detail string `json:"detail"`
+}
+
+// Reasons of failed requests.
+const (
+	ReasonBadRequest        = "BadRequest"
+	ReasonUnauthenticated   = "Unauthenticated"
+	ReasonNotFound          = "NotFound"
+	ReasonPermissionDenied  = "PermissionDenied"
+	ReasonCollectorDisabled = "CollectorDisabled"
+	ReasonConflict          = "Conflict"
+	ReasonRateLimited       = "RateLimited"
+	ReasonInternal          = "InternalError"
+)
+
+func (e *Error) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("cadvisor: status %d: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("cadvisor: %s (%d): %s", e.Reason, e.StatusCode, e.Detail)
+}
+
+// IsNotFound returns whether err is an Error for a request whose container,
+// pod or resource does not exist.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// RequestOptions select the containers of a request and their stats.
+type RequestOptions struct {
+	// Type of the container identifier, v2.TypeName (default) or
+	// v2.TypeDocker.
+	IdType string
+	// Number of stats samples per container, 64 by default.
+	Count int
+	// Whether to include the subcontainers of the container.
+	Recursive bool
+	// Collect the stats again if they are older than MaxAge, nil never
+	// collects them on request.
+	MaxAge *time.Duration
+	// Only select the containers of the Kubernetes namespace, the containers
+	// named or aliased Container, or those whose labels match Selector, e.g.
+	// "app=web,!canary".
+	Namespace string
+	Container string
+	Selector  string
+	// Resolution of the stats, v2.ResolutionRaw (default) or
+	// v2.ResolutionDownsampled.
+	Resolution string
+	// Values derived from consecutive stats, v2.DerivedRate or empty.
+	Derived string
+}
+
+func (o *RequestOptions) values() url.Values {
+	values := url.Values{}
+	if o == nil {
+		return values
+	}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("type", o.IdType)
+	if o.Count > 0 {
+		values.Set("count", strconv.Itoa(o.Count))
+	}
+	if o.Recursive {
+		values.Set("recursive", "true")
+	}
+	if o.MaxAge != nil {
+		values.Set("max_age", o.MaxAge.String())
+	}
+	set("namespace", o.Namespace)
+	set("container", o.Container)
+	set("selector", o.Selector)
+	set("resolution", o.Resolution)
+	set("derived", o.Derived)
+	return values
+}
+
+// EventsOptions select the events of a request.
+type EventsOptions struct {
+	// Types of the events, all types if empty.
+	Types []v1.EventType
+	// Whether to include the events of the subcontainers.
+	Subcontainers bool
+	// Time range of past events, unbounded if zero.
+	Start time.Time
+	End   time.Time
+	// Maximum number of past events, the most recent ones. 10 by default.
+	MaxEvents int
+}
+
+// eventTypeParams are the query parameters selecting the types of events.
+var eventTypeParams = map[v1.EventType]string{
+	v1.EventOom:                "oom_events",
+	v1.EventOomKill:            "oom_kill_events",
+	v1.EventContainerCreation:  "creation_events",
+	v1.EventContainerDeletion:  "deletion_events",
+	v1.EventMachineInfoChanged: "machine_info_changed_events",
+	v1.EventMdArrayChanged:     "md_array_changed_events",
+	v1.EventContainerRestored:  "restoration_events",
+}
+
+func (o *EventsOptions) values() url.Values {
+	values := url.Values{}
+	if o == nil || len(o.Types) == 0 {
+		values.Set("all_events", "true")
+	} else {
+		for _, t := range o.Types {
+			if param, ok := eventTypeParams[t]; ok {
+				values.Set(param, "true")
+			}
+		}
+	}
+	if o == nil {
+		return values
+	}
+	if o.Subcontainers {
+		values.Set("subcontainers", "true")
+	}
+	if !o.Start.IsZero() {
+		values.Set("start_time", o.Start.Format(time.RFC3339))
+	}
+	if !o.End.IsZero() {
+		values.Set("end_time", o.End.Format(time.RFC3339))
+	}
+	if o.MaxEvents > 0 {
+		values.Set("max_events", strconv.Itoa(o.MaxEvents))
+	}
+	return values
+}
+
+// Version returns the version of cAdvisor.
+func (c *Client) Version(ctx context.Context) (string, error) {
+	var version string
+	err := c.get(ctx, "version", nil, &version)
+	return version, err
+}
+
+// MachineInfo returns the information of the machine.
+func (c *Client) MachineInfo(ctx context.Context) (*v1.MachineInfo, error) {
+	ret := new(v1.MachineInfo)
+	if err := c.get(ctx, "machine", nil, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// MachineStats returns the recent stats of the machine.
+func (c *Client) MachineStats(ctx context.Context, opts *RequestOptions) ([]v2.MachineStats, error) {
+	var ret []v2.MachineStats
+	err := c.get(ctx, "machinestats", opts.values(), &ret)
+	return ret, err
+}
+
+// Attributes returns the hardware and software attributes of the machine.
+func (c *Client) Attributes(ctx context.Context) (*v2.Attributes, error) {
+	ret := new(v2.Attributes)
+	if err := c.get(ctx, "attributes", nil, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// Stats returns the specs and recent stats of the requested containers, by
+// container name.
+func (c *Client) Stats(ctx context.Context, name string, opts *RequestOptions) (map[string]v2.ContainerInfo, error) {
+	var ret map[string]v2.ContainerInfo
+	err := c.get(ctx, path.Join("stats", name), opts.values(), &ret)
+	return ret, err
+}
+
+// Spec returns the specs of the requested containers, by container name.
+func (c *Client) Spec(ctx context.Context, name string, opts *RequestOptions) (map[string]v2.ContainerSpec, error) {
+	var ret map[string]v2.ContainerSpec
+	err := c.get(ctx, path.Join("spec", name), opts.values(), &ret)
+	return ret, err
+}
+
+// Summary returns the percentiles of the usage of the requested containers,
+// by container name.
+func (c *Client) Summary(ctx context.Context, name string, opts *RequestOptions) (map[string]v2.DerivedStats, error) {
+	var ret map[string]v2.DerivedStats
+	err := c.get(ctx, path.Join("summary", name), opts.values(), &ret)
+	return ret, err
+}
+
+// Processes returns the processes of the requested container.
+func (c *Client) Processes(ctx context.Context, name string, opts *RequestOptions) ([]v2.ProcessInfo, error) {
+	var ret []v2.ProcessInfo
+	err := c.get(ctx, path.Join("ps", name), opts.values(), &ret)
+	return ret, err
+}
+
+// Pods returns the Kubernetes pods of the machine, by pod UID.
+func (c *Client) Pods(ctx context.Context, opts *RequestOptions) (map[string]v2.PodInfo, error) {
+	var ret map[string]v2.PodInfo
+	err := c.get(ctx, "pods", opts.values(), &ret)
+	return ret, err
+}
+
+// Events returns the past events of the requested container.
+func (c *Client) Events(ctx context.Context, name string, opts *EventsOptions) ([]*v1.Event, error) {
+	var ret []*v1.Event
+	err := c.get(ctx, path.Join("events", name), opts.values(), &ret)
+	return ret, err
+}
+
+// Query aggregates a metric of the stats in memory of the subcontainers of
+// the requested container.
+func (c *Client) Query(ctx context.Context, name string, query v2.Query, opts *RequestOptions) ([]v2.QueryResult, error) {
+	values := opts.values()
+	values.Set("metric", query.Metric)
+	if query.Over != "" {
+		values.Set("over", query.Over)
+	}
+	if query.Op != "" {
+		values.Set("op", query.Op)
+	}
+	if len(query.By) > 0 {
+		values.Set("by", strings.Join(query.By, ","))
+	}
+	if query.Range > 0 {
+		values.Set("range", query.Range.String())
+	}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	var ret []v2.QueryResult
+	err := c.get(ctx, path.Join("query", name), values, &ret)
+	return ret, err
+}
+
+// Status returns the features of cAdvisor which are disabled.
+func (c *Client) Status(ctx context.Context) (v2.Status, error) {
+	var ret v2.Status
+	err := c.get(ctx, "status", nil, &ret)
+	return ret, err
+}
+
+// url returns the URL of the request for resource.
+func (c *Client) url(resource string, values url.Values) string {
+	u := *c.baseURL
+	u.Path += strings.TrimPrefix(resource, "/")
+	u.RawQuery = values.Encode()
+	return u.String()
+}
+
+// get decodes the response of the request for resource into ret, retrying
+// it while it may succeed.
+func (c *Client) get(ctx context.Context, resource string, values url.Values, ret interface{}) error {
+	resp, err := c.do(ctx, c.url(resource, values), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		return fmt.Errorf("failed to decode the response of %q: %v", resource, err)
+	}
+	return nil
+}
+
+// do sends a GET request to u and returns the successful response, retrying
+// the request while it may succeed.
+func (c *Client) do(ctx context.Context, u string, header http.Header) (*http.Response, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		retryable := true
+		delay := backoff
+		if err == nil {
+			err = readError(resp)
+			var e *Error
+			retryable = errors.As(err, &e) && e.Retryable
+			if s, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && time.Duration(s)*time.Second > delay {
+				delay = time.Duration(s) * time.Second
+			}
+		}
+		if !retryable || attempt >= c.retries || ctx.Err() != nil {
+			return nil, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// readError returns the error of a failed response and closes its body.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &Error{StatusCode: resp.StatusCode, Retryable: true, Detail: err.Error()}
+	}
+	var r struct {
+		Error *Error `json:"error"`
+	}
+	if json.Unmarshal(body, &r) == nil && r.Error != nil {
+		return r.Error
+	}
+	// Servers older than the structured errors.
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Retryable:  resp.StatusCode >= http.StatusInternalServerError,
+		Detail:     strings.TrimSpace(string(body)),
+	}
+}
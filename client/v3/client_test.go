@@ -0,0 +1,211 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc, opts ...Option) (*Client, *httptest.Server) {
+	ts := httptest.NewServer(handler)
+	opts = append([]Option{WithRetries(2, time.Millisecond)}, opts...)
+	client, err := NewClient(ts.URL, opts...)
+	require.NoError(t, err)
+	return client, ts
+}
+
+func writeError(w http.ResponseWriter, code int, reason string, retryable bool) {
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"version":"v1","error":{"code":%d,"reason":%q,"retryable":%t,"detail":"failed"}}`, code, reason, retryable)
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient("http://localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api/v2.1/stats/docker?count=1", client.url("stats/docker", map[string][]string{"count": {"1"}}))
+
+	client, err = NewClient("https://example.com/cadvisor/")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/cadvisor/api/v2.1/machine", client.url("machine", nil))
+
+	_, err = NewClient("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestStats(t *testing.T) {
+	maxAge := 10 * time.Second
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2.1/stats/docker/abc", r.URL.Path)
+		assert.Equal(t, "count=2&derived=rate&max_age=10s&recursive=true&selector=app%3Dweb", r.URL.RawQuery)
+		json.NewEncoder(w).Encode(map[string]v2.ContainerInfo{
+			"/docker/abc": {Spec: v2.ContainerSpec{Image: "nginx"}},
+		})
+	})
+	defer ts.Close()
+
+	stats, err := client.Stats(context.Background(), "/docker/abc", &RequestOptions{
+		Count:     2,
+		Recursive: true,
+		MaxAge:    &maxAge,
+		Selector:  "app=web",
+		Derived:   v2.DerivedRate,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "nginx", stats["/docker/abc"].Spec.Image)
+}
+
+func TestEvents(t *testing.T) {
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2.1/events", r.URL.Path)
+		assert.Equal(t, "max_events=5&oom_events=true&start_time=2021-03-01T10%3A00%3A00Z&subcontainers=true", r.URL.RawQuery)
+		json.NewEncoder(w).Encode([]*v1.Event{{ContainerName: "/a", EventType: v1.EventOom}})
+	})
+	defer ts.Close()
+
+	events, err := client.Events(context.Background(), "/", &EventsOptions{
+		Types:         []v1.EventType{v1.EventOom},
+		Subcontainers: true,
+		Start:         start,
+		MaxEvents:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "/a", events[0].ContainerName)
+}
+
+func TestErrors(t *testing.T) {
+	var requests int32
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/api/v2.1/spec/missing":
+			writeError(w, http.StatusNotFound, ReasonNotFound, false)
+		case "/api/v2.1/machine":
+			writeError(w, http.StatusInternalServerError, ReasonInternal, true)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "bad gateway")
+		}
+	})
+	defer ts.Close()
+	ctx := context.Background()
+
+	_, err := client.Spec(ctx, "/missing", nil)
+	assert.True(t, IsNotFound(err))
+	assert.Equal(t, &Error{StatusCode: http.StatusNotFound, Reason: ReasonNotFound, Detail: "failed"}, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "non-retryable errors are not retried")
+
+	atomic.StoreInt32(&requests, 0)
+	_, err = client.MachineInfo(ctx)
+	assert.Equal(t, &Error{StatusCode: http.StatusInternalServerError, Reason: ReasonInternal, Retryable: true, Detail: "failed"}, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+	_, err = client.Version(ctx)
+	assert.Equal(t, &Error{StatusCode: http.StatusBadGateway, Retryable: true, Detail: "bad gateway"}, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			writeError(w, http.StatusTooManyRequests, ReasonRateLimited, true)
+			return
+		}
+		fmt.Fprint(w, `"v0.39.0"`)
+	})
+	defer ts.Close()
+
+	version, err := client.Version(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "v0.39.0", version)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestContextCancellation(t *testing.T) {
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusInternalServerError, ReasonInternal, true)
+	}, WithRetries(10, time.Hour))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Status(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestStreamStats(t *testing.T) {
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2.1/stream/stats/docker", r.URL.Path)
+		assert.Equal(t, "100", r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": heartbeat\n\n")
+		fmt.Fprint(w, "id: 200\nevent: stats\ndata: {\"name\":\"/docker/a\",\"stats\":{\"cpu\":{\"load_average\":3}}}\n\n")
+		fmt.Fprint(w, "id: 300\nevent: stats\ndata: {\"name\":\"/docker/b\",\"stats\":{}}\n\n")
+	})
+	defer ts.Close()
+
+	stream, err := client.StreamStats(context.Background(), "/docker", nil, &StreamOptions{LastEventID: "100"})
+	require.NoError(t, err)
+	defer stream.Close()
+	assert.Equal(t, "100", stream.LastEventID())
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/a", update.Name)
+	assert.Equal(t, int32(3), update.Stats.Cpu.LoadAverage)
+	assert.Equal(t, "200", stream.LastEventID())
+
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "/docker/b", update.Name)
+	assert.Equal(t, "300", stream.LastEventID())
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamEvents(t *testing.T) {
+	client, ts := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2.1/stream/events", r.URL.Path)
+		assert.Equal(t, "all_events=true&subcontainers=true", r.URL.RawQuery)
+		fmt.Fprint(w, "id: 200\nevent: oom\ndata: {\"container_name\":\"/a\",\"event_type\":\"oom\"}\n\n")
+	})
+	defer ts.Close()
+
+	stream, err := client.StreamEvents(context.Background(), "/", &EventsOptions{Subcontainers: true, MaxEvents: 10}, nil)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, &v1.Event{ContainerName: "/a", EventType: v1.EventOom}, event)
+	assert.Equal(t, "200", stream.LastEventID())
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v3
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	v1 "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+)
+
+// StatsUpdate is a sample of the stats of a container received from a stats
+// stream.
+type StatsUpdate struct {
+	Name  string             `json:"name"`
+	Stats *v2.ContainerStats `json:"stats"`
+}
+
+// StreamOptions configure a stream.
+type StreamOptions struct {
+	// ID of the last event received by a previous stream, whose missed events
+	// are replayed first. See the LastEventID methods of the streams.
+	LastEventID string
+}
+
+// sseEvent is an event of a server-sent events stream.
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// stream reads the events of a server-sent events stream.
+type stream struct {
+	body        io.ReadCloser
+	reader      *bufio.Reader
+	cancel      context.CancelFunc
+	lastEventID string
+}
+
+func (c *Client) openStream(ctx context.Context, resource string, values url.Values, opts *StreamOptions) (*stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	header := http.Header{"Accept": {"text/event-stream"}}
+	var lastEventID string
+	if opts != nil && opts.LastEventID != "" {
+		lastEventID = opts.LastEventID
+		header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := c.do(ctx, c.url(resource, values), header)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &stream{
+		body:        resp.Body,
+		reader:      bufio.NewReader(resp.Body),
+		cancel:      cancel,
+		lastEventID: lastEventID,
+	}, nil
+}
+
+// next returns the next event of the stream, skipping the heartbeats.
+func (s *stream) next() (*sseEvent, error) {
+	var ev sseEvent
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(data) == 0 {
+				continue
+			}
+			ev.data = strings.Join(data, "\n")
+			if ev.id != "" {
+				s.lastEventID = ev.id
+			}
+			return &ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			ev.id = value
+		case "event":
+			ev.event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+func (s *stream) close() error {
+	s.cancel()
+	return s.body.Close()
+}
+
+// StatsStream is a stream of the stats of containers.
+type StatsStream struct {
+	s *stream
+}
+
+// StreamStats streams the stats of the requested containers as they are
+// collected, until the context is done or the stream is closed.
+func (c *Client) StreamStats(ctx context.Context, name string, opts *RequestOptions, streamOpts *StreamOptions) (*StatsStream, error) {
+	s, err := c.openStream(ctx, path.Join("stream/stats", name), opts.values(), streamOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsStream{s: s}, nil
+}
+
+// Recv blocks until the next stats sample is received. It returns io.EOF when
+// the server ends the stream.
+func (s *StatsStream) Recv() (*StatsUpdate, error) {
+	ev, err := s.s.next()
+	if err != nil {
+		return nil, err
+	}
+	update := new(StatsUpdate)
+	if err := json.Unmarshal([]byte(ev.data), update); err != nil {
+		return nil, fmt.Errorf("failed to decode stats event %q: %v", ev.id, err)
+	}
+	return update, nil
+}
+
+// LastEventID returns the ID of the last event received, resuming a new
+// stream after it.
+func (s *StatsStream) LastEventID() string {
+	return s.s.lastEventID
+}
+
+// Close closes the stream.
+func (s *StatsStream) Close() error {
+	return s.s.close()
+}
+
+// EventStream is a stream of container events.
+type EventStream struct {
+	s *stream
+}
+
+// StreamEvents streams the events of the requested container as they happen,
+// until the context is done or the stream is closed. The past events options
+// are ignored.
+func (c *Client) StreamEvents(ctx context.Context, name string, opts *EventsOptions, streamOpts *StreamOptions) (*EventStream, error) {
+	values := opts.values()
+	values.Del("start_time")
+	values.Del("end_time")
+	values.Del("max_events")
+	s, err := c.openStream(ctx, path.Join("stream/events", name), values, streamOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{s: s}, nil
+}
+
+// Recv blocks until the next event is received. It returns io.EOF when the
+// server ends the stream.
+func (s *EventStream) Recv() (*v1.Event, error) {
+	ev, err := s.s.next()
+	if err != nil {
+		return nil, err
+	}
+	event := new(v1.Event)
+	if err := json.Unmarshal([]byte(ev.data), event); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %q: %v", ev.event, ev.id, err)
+	}
+	return event, nil
+}
+
+// LastEventID returns the ID of the last event received, resuming a new
+// stream after it.
+func (s *EventStream) LastEventID() string {
+	return s.s.lastEventID
+}
+
+// Close closes the stream.
+func (s *EventStream) Close() error {
+	return s.s.close()
+}
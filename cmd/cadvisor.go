@@ -27,7 +27,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/cadvisor/cmd/internal/api"
+	"github.com/google/cadvisor/cmd/internal/apiauth"
+	"github.com/google/cadvisor/cmd/internal/audit"
+	"github.com/google/cadvisor/cmd/internal/clientlimit"
+	"github.com/google/cadvisor/cmd/internal/config"
+	"github.com/google/cadvisor/cmd/internal/eventsink"
+	cadvisorgrpc "github.com/google/cadvisor/cmd/internal/grpc"
 	cadvisorhttp "github.com/google/cadvisor/cmd/internal/http"
+	"github.com/google/cadvisor/cmd/internal/logging"
+	"github.com/google/cadvisor/cmd/internal/profiling"
+	"github.com/google/cadvisor/cmd/internal/storage/victoriametrics"
+	"github.com/google/cadvisor/cmd/internal/tlsconfig"
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/metrics"
@@ -41,14 +52,33 @@ import (
 	_ "github.com/google/cadvisor/utils/cloudinfo/aws"
 	_ "github.com/google/cadvisor/utils/cloudinfo/azure"
 	_ "github.com/google/cadvisor/utils/cloudinfo/gce"
+	_ "github.com/google/cadvisor/utils/cloudinfo/openstack"
 
+	"google.golang.org/grpc"
 	"k8s.io/klog/v2"
 )
 
 var argIp = flag.String("listen_ip", "", "IP to listen on, defaults to all IPs")
 var argPort = flag.Int("port", 8080, "port to listen")
+var grpcPort = flag.Int("grpc_port", 0, "port to serve the gRPC API on, 0 disables the gRPC API")
+var tlsCertFile = flag.String("tls_cert_file", "", "Certificate file to serve the HTTP and gRPC APIs over TLS with, reloaded when it changes. Empty value serves them in plain text.")
+var tlsKeyFile = flag.String("tls_key_file", "", "Key file of the certificate set by --tls_cert_file")
+var authTokenFile = flag.String("auth_token_file", "", "File of the bearer tokens accepted by the API and metrics endpoints, one \"<token>,<name>,<role>\" per line where role is read or control. Authentication is disabled if neither this nor auth_oidc_issuer_url is set.")
+var authOIDCIssuerURL = flag.String("auth_oidc_issuer_url", "", "URL of the OpenID Connect issuer whose ID tokens are accepted by the API and metrics endpoints")
+var authOIDCClientID = flag.String("auth_oidc_client_id", "", "Client ID the OpenID Connect ID tokens must be issued for")
+var authOIDCGroupsClaim = flag.String("auth_oidc_groups_claim", "groups", "Claim of the OpenID Connect ID tokens holding the groups of the client")
+var authOIDCControlGroups = flag.String("auth_oidc_control_groups", "", "Comma-separated list of OpenID Connect groups granted the control role, other authenticated clients are granted the read role")
+var tlsClientCA = flag.String("tls_client_ca", "", "File of the CA certificates that clients must present a certificate signed by when TLS is enabled. Empty value does not verify clients.")
+var auditLog = flag.String("audit_log", "", "File the API requests are logged to as JSON lines, \"-\" for stdout. Empty value disables audit logging.")
+var allowedCIDRs = flag.String("http_allowed_cidrs", "", "Comma-separated list of CIDRs or IP addresses allowed to reach the HTTP server, other clients are rejected except for /healthz. Empty value allows all clients.")
+var rateLimit = flag.Float64("http_rate_limit", 0, "Average number of HTTP requests per second allowed to each client IP address, other requests are rejected except for /healthz. 0 disables rate limiting.")
+var rateLimitBurst = flag.Int("http_rate_limit_burst", 20, "Number of HTTP requests each client IP address may send at once, above http_rate_limit")
 var maxProcs = flag.Int("max_procs", 0, "max number of CPUs that can be used simultaneously. Less than 1 for default (number of cores).")
 
+var configFile = flag.String("config", "", "Path to a YAML file setting flags by name, e.g. \"housekeeping_interval: 10s\". Flags set on the command line take precedence. The file is reloaded on change and on SIGHUP, which only applies the logging flags, other changes need a restart. Empty value disables the config file.")
+
+var logFormat = flag.String("log_format", "text", "Format of the logs, \"text\" or \"json\" for one JSON object per line written to stderr")
+
 var versionFlag = flag.Bool("version", false, "print cAdvisor version and exit")
 
 var httpAuthFile = flag.String("http_auth_file", "", "HTTP auth file for the web UI")
@@ -58,18 +88,26 @@ var httpDigestRealm = flag.String("http_digest_realm", "localhost", "HTTP digest
 
 var prometheusEndpoint = flag.String("prometheus_endpoint", "/metrics", "Endpoint to expose Prometheus metrics on")
 
-var housekeepingConfig = manager.HouskeepingConfig{
-	flag.Duration("max_housekeeping_interval", 60*time.Second, "Largest interval to allow between container housekeepings"),
-	flag.Bool("allow_dynamic_housekeeping", true, "Whether to allow the housekeeping interval to be dynamic"),
-}
-
 var enableProfiling = flag.Bool("profiling", false, "Enable profiling via web interface host:port/debug/pprof/")
 
+var profileCaptureInterval = flag.Duration("profile_capture_interval", 0, "Interval between the captures of CPU and heap profiles, saved to profile_capture_dir or pushed to profile_push_url. Zero disables the captures.")
+var profileCaptureCPUDuration = flag.Duration("profile_capture_cpu_duration", 10*time.Second, "Duration of the CPU profiles captured")
+var profileCaptureDir = flag.String("profile_capture_dir", "", "Directory the captured profiles are saved to")
+var profileCaptureKeep = flag.Int("profile_capture_keep", 10, "Number of captured profiles of each type kept in profile_capture_dir")
+var profilePushURL = flag.String("profile_push_url", "", "URL the captured profiles are POSTed to, e.g. http://pyroscope:4040/ingest")
+
 var collectorCert = flag.String("collector_cert", "", "Collector's certificate, exposed to endpoints for certificate based authentication.")
 var collectorKey = flag.String("collector_key", "", "Key for the collector's certificate")
 
 var storeContainerLabels = flag.Bool("store_container_labels", true, "convert container labels and environment variables into labels on prometheus metrics for each container. If flag set to false, then only metrics exported are container name, first alias, and image name")
-var whitelistedContainerLabels = flag.String("whitelisted_container_labels", "", "comma separated list of container labels to be converted to labels on prometheus metrics for each container. store_container_labels must be set to false for this to take effect.")
+var continuityLabel = flag.Bool("continuity_label", false, "add a continuity_id label to the prometheus metrics of each container, holding the ID of the first container of a chain of containers restored from CRIU checkpoints of each other, or the ID of the container if it was not restored")
+var identityLabel = flag.Bool("identity_label", false, "add an identity label to the prometheus metrics of each container, holding a hash of the pod UID and container name of Kubernetes containers or of the container name otherwise, which is stable across restarts of the container")
+var whitelistedContainerLabels = flag.String("whitelisted_container_labels", "", "comma separated list of container labels to be converted to labels on prometheus metrics for each container. Entries label:<pattern> and env:<pattern> select the container labels and environment variables matching a shell pattern, label:<key>=<name> and env:<key>=<name> rename them. store_container_labels must be set to false for this to take effect.")
+var prometheusExemplarLabel = flag.String("prometheus_exemplar_label", "", "container label holding a trace ID, attached as exemplar to the counters of the container when metrics are scraped in the OpenMetrics format")
+
+var eventSinkConfig = flag.String("event_sink_config", "", "Path to a YAML file listing webhooks the container events are POSTed to. Empty value disables event delivery.")
+
+var metricsConfig = flag.String("metrics_config", "", "Path to a YAML file with rules dropping metric families and dropping or renaming labels of the Prometheus metrics before they are exposed, and the series budget bounding their number. Empty value disables relabeling.")
 
 var urlBasePrefix = flag.String("url_base_prefix", "", "prefix path that will be prepended to all paths to support some reverse proxies")
 
@@ -80,18 +118,10 @@ var perfEvents = flag.String("perf_events_config", "", "Path to a JSON file cont
 var (
 	// Metrics to be ignored.
 	// Tcp metrics are ignored by default.
-	ignoreMetrics metricSetValue = metricSetValue{container.MetricSet{
-		container.MemoryNumaMetrics:              struct{}{},
-		container.NetworkTcpUsageMetrics:         struct{}{},
-		container.NetworkUdpUsageMetrics:         struct{}{},
-		container.NetworkAdvancedTcpUsageMetrics: struct{}{},
-		container.ProcessSchedulerMetrics:        struct{}{},
-		container.ProcessMetrics:                 struct{}{},
-		container.HugetlbUsageMetrics:            struct{}{},
-		container.ReferencedMemoryMetrics:        struct{}{},
-		container.CPUTopologyMetrics:             struct{}{},
-		container.ResctrlMetrics:                 struct{}{},
-	}}
+	ignoreMetrics metricSetValue = metricSetValue{container.DefaultDisabledMetrics()}
+
+	// Metrics to be enabled in addition to the defaults.
+	enableMetrics metricSetValue = metricSetValue{container.MetricSet{}}
 
 	// List of metrics that can be ignored.
 	ignoreWhitelist = container.MetricSet{
@@ -99,6 +129,7 @@ var (
 		container.DiskUsageMetrics:               struct{}{},
 		container.DiskIOMetrics:                  struct{}{},
 		container.MemoryNumaMetrics:              struct{}{},
+		container.MemoryStatMetrics:              struct{}{},
 		container.NetworkUsageMetrics:            struct{}{},
 		container.NetworkTcpUsageMetrics:         struct{}{},
 		container.NetworkAdvancedTcpUsageMetrics: struct{}{},
@@ -110,6 +141,18 @@ var (
 		container.ReferencedMemoryMetrics:        struct{}{},
 		container.CPUTopologyMetrics:             struct{}{},
 		container.ResctrlMetrics:                 struct{}{},
+		container.NetworkQueueMetrics:            struct{}{},
+		container.GvisorMetrics:                  struct{}{},
+		container.KsmMetrics:                     struct{}{},
+		container.CpuStealMetrics:                struct{}{},
+		container.CpuFrequencyMetrics:            struct{}{},
+		container.PowerMetrics:                   struct{}{},
+		container.ThermalMetrics:                 struct{}{},
+		container.ThermalThrottleMetrics:         struct{}{},
+		container.CpuUsageQuantileMetrics:        struct{}{},
+		container.MdRaidMetrics:                  struct{}{},
+		container.StorageSessionMetrics:          struct{}{},
+		container.SlabMetrics:                    struct{}{},
 	}
 )
 
@@ -134,14 +177,15 @@ func (ml *metricSetValue) Set(value string) error {
 		if ignoreWhitelist.Has(container.MetricKind(metric)) {
 			(*ml).Add(container.MetricKind(metric))
 		} else {
-			return fmt.Errorf("unsupported metric %q specified in disable_metrics", metric)
+			return fmt.Errorf("unsupported metric %q specified", metric)
 		}
 	}
 	return nil
 }
 
 func init() {
-	flag.Var(&ignoreMetrics, "disable_metrics", "comma-separated list of `metrics` to be disabled. Options are 'accelerator', 'cpu_topology','disk', 'diskIO', 'memory_numa', 'network', 'tcp', 'udp', 'percpu', 'sched', 'process', 'hugetlb', 'referenced_memory', 'resctrl'.")
+	flag.Var(&ignoreMetrics, "disable_metrics", "comma-separated list of `metrics` to be disabled. Options are 'accelerator', 'cpu_topology','disk', 'diskIO', 'memory_numa', 'memory_stat', 'network', 'tcp', 'udp', 'percpu', 'sched', 'process', 'hugetlb', 'referenced_memory', 'resctrl', 'nic_queues', 'gvisor', 'ksm', 'cpu_steal', 'cpu_frequency', 'power', 'thermal', 'thermal_throttle', 'cpu_usage_quantile', 'md_raid', 'storage_sessions', 'slab'.")
+	flag.Var(&enableMetrics, "enable_metrics", "comma-separated list of `metrics` to be enabled in addition to the defaults, takes precedence over disable_metrics. Options are the same as for disable_metrics.")
 
 	// Default logging verbosity to V(2)
 	flag.Set("v", "2")
@@ -157,10 +201,69 @@ func main() {
 		os.Exit(0)
 	}
 
-	includedMetrics := toIncludedMetrics(ignoreMetrics.MetricSet)
+	cfg, err := config.Load(flag.CommandLine, *configFile)
+	if err != nil {
+		klog.Fatalf("Failed to load config file: %v", err)
+	}
+	cfg.Watch()
+	api.SetConfig(cfg)
+
+	switch *logFormat {
+	case "text":
+	case "json":
+		if err := logging.SetJSONOutput(flag.CommandLine, os.Stderr); err != nil {
+			klog.Fatalf("Failed to set the JSON log format: %v", err)
+		}
+	default:
+		klog.Fatalf("Unknown log_format %q, expected \"text\" or \"json\"", *logFormat)
+	}
+	logLevels, err := logging.NewController(flag.CommandLine)
+	if err != nil {
+		klog.Fatalf("Failed to set up log levels: %v", err)
+	}
+	api.SetLogging(logLevels)
+
+	includedMetrics := toIncludedMetrics(ignoreMetrics.MetricSet.Difference(enableMetrics.MetricSet))
 
 	setMaxProcs()
 
+	containerLabelFunc := metrics.DefaultContainerLabels
+	if !*storeContainerLabels {
+		whitelistedLabels := strings.Split(*whitelistedContainerLabels, ",")
+		var err error
+		containerLabelFunc, err = metrics.MappedContainerLabels(whitelistedLabels)
+		if err != nil {
+			klog.Fatalf("Invalid whitelisted_container_labels: %v", err)
+		}
+	}
+	if *continuityLabel {
+		containerLabelFunc = metrics.WithContinuityLabel(containerLabelFunc)
+	}
+	if *identityLabel {
+		containerLabelFunc = metrics.WithIdentityLabel(containerLabelFunc)
+	}
+
+	var relabelConfig *metrics.RelabelConfig
+	if *metricsConfig != "" {
+		var err error
+		relabelConfig, err = metrics.ReadRelabelConfig(*metricsConfig)
+		if err != nil {
+			klog.Fatalf("Failed to load metrics config: %v", err)
+		}
+	}
+
+	var sinkConfig *eventsink.Config
+	if *eventSinkConfig != "" {
+		var err error
+		sinkConfig, err = eventsink.ReadConfig(*eventSinkConfig)
+		if err != nil {
+			klog.Fatalf("Failed to load event sink config: %v", err)
+		}
+	}
+
+	// Storage drivers pushing metrics push the metrics of the Prometheus endpoint.
+	victoriametrics.SetMetricsConfig(includedMetrics, containerLabelFunc, relabelConfig)
+
 	memoryStorage, err := NewMemoryStorage()
 	if err != nil {
 		klog.Fatalf("Failed to initialize storage driver: %s", err)
@@ -170,7 +273,11 @@ func main() {
 
 	collectorHttpClient := createCollectorHttpClient(*collectorCert, *collectorKey)
 
-	resourceManager, err := manager.New(memoryStorage, sysFs, housekeepingConfig, includedMetrics, &collectorHttpClient, strings.Split(*rawCgroupPrefixWhiteList, ","), *perfEvents)
+	managerOptions, err := managerOptions(includedMetrics, &collectorHttpClient)
+	if err != nil {
+		klog.Fatalf("Invalid manager configuration: %v", err)
+	}
+	resourceManager, err := manager.NewWithOptions(memoryStorage, sysFs, managerOptions)
 	if err != nil {
 		klog.Fatalf("Failed to create a manager: %s", err)
 	}
@@ -182,6 +289,23 @@ func main() {
 		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	if *profileCaptureInterval > 0 {
+		capturer, err := profiling.New(profiling.Config{
+			Interval:    *profileCaptureInterval,
+			CPUDuration: *profileCaptureCPUDuration,
+			Dir:         *profileCaptureDir,
+			Keep:        *profileCaptureKeep,
+			PushURL:     *profilePushURL,
+			AppName:     "cadvisor",
+			Client:      &http.Client{Timeout: 30 * time.Second},
+		})
+		if err != nil {
+			klog.Fatalf("Failed to set up profile capture: %v", err)
+		}
+		capturer.Start()
 	}
 
 	// Register all HTTP handlers.
@@ -190,30 +314,98 @@ func main() {
 		klog.Fatalf("Failed to register HTTP handlers: %v", err)
 	}
 
-	containerLabelFunc := metrics.DefaultContainerLabels
-	if !*storeContainerLabels {
-		whitelistedLabels := strings.Split(*whitelistedContainerLabels, ",")
-		containerLabelFunc = metrics.BaseContainerLabels(whitelistedLabels)
-	}
-
 	// Register Prometheus collector to gather information about containers, Go runtime, processes, and machine
-	cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics)
+	cadvisorhttp.RegisterPrometheusHandler(mux, resourceManager, *prometheusEndpoint, containerLabelFunc, includedMetrics, *prometheusExemplarLabel, relabelConfig)
 
 	// Start the manager.
 	if err := resourceManager.Start(); err != nil {
 		klog.Fatalf("Failed to start manager: %v", err)
 	}
+	if localStore != nil {
+		// Drop the restored stats of the containers that are gone.
+		memoryStorage.PruneContainers(resourceManager.Exists)
+	}
+	if sinkConfig != nil {
+		if err := eventsink.New(sinkConfig).Start(resourceManager); err != nil {
+			klog.Fatalf("Failed to start event sink: %v", err)
+		}
+	}
 
 	// Install signal handler.
 	installSignalHandler(resourceManager)
 
 	klog.V(1).Infof("Starting cAdvisor version: %s-%s on port %d", version.Info["version"], version.Info["revision"], *argPort)
 
+	var serverTLSConfig *tls.Config
+	if *tlsCertFile != "" || *tlsKeyFile != "" {
+		serverTLSConfig, err = tlsconfig.New(*tlsCertFile, *tlsKeyFile, *tlsClientCA)
+		if err != nil {
+			klog.Fatalf("Failed to configure TLS: %v", err)
+		}
+	} else if *tlsClientCA != "" {
+		klog.Fatal("The tls_cert_file and tls_key_file values must be specified if the tls_client_ca value is set.")
+	}
+
+	authenticator := createAuthenticator()
+	if authenticator != nil && serverTLSConfig == nil {
+		klog.Warning("Bearer tokens are sent in plain text, set tls_cert_file and tls_key_file to serve the APIs over TLS")
+	}
+
+	var auditLogger *audit.Logger
+	if *auditLog != "" {
+		auditLogger, err = audit.NewLogger(*auditLog)
+		if err != nil {
+			klog.Fatalf("Failed to open audit log: %v", err)
+		}
+	}
+
+	if *grpcPort != 0 {
+		var grpcOpts []grpc.ServerOption
+		if auditLogger != nil {
+			grpcOpts = append(grpcOpts, auditLogger.GRPCServerOptions()...)
+		}
+		if authenticator != nil {
+			grpcOpts = append(grpcOpts, apiauth.GRPCServerOptions(authenticator)...)
+		}
+		go func() {
+			klog.Fatal(cadvisorgrpc.ListenAndServe(fmt.Sprintf("%s:%d", *argIp, *grpcPort), resourceManager, *housekeepingInterval, serverTLSConfig, grpcOpts...))
+		}()
+	}
+
 	rootMux := http.NewServeMux()
 	rootMux.Handle(*urlBasePrefix+"/", http.StripPrefix(*urlBasePrefix, mux))
 
-	addr := fmt.Sprintf("%s:%d", *argIp, *argPort)
-	klog.Fatal(http.ListenAndServe(addr, rootMux))
+	var handler http.Handler = rootMux
+	if authenticator != nil {
+		handler = apiauth.Handler(authenticator, handler, *urlBasePrefix+"/healthz")
+	}
+	if *rateLimit != 0 {
+		limiter, err := clientlimit.NewLimiter(*rateLimit, *rateLimitBurst)
+		if err != nil {
+			klog.Fatalf("Failed to configure rate limiting: %v", err)
+		}
+		handler = limiter.Handler(handler, *urlBasePrefix+"/healthz")
+	}
+	if *allowedCIDRs != "" {
+		allowlist, err := clientlimit.ParseAllowlist(*allowedCIDRs)
+		if err != nil {
+			klog.Fatalf("Failed to parse http_allowed_cidrs: %v", err)
+		}
+		handler = allowlist.Handler(handler, *urlBasePrefix+"/healthz")
+	}
+	if auditLogger != nil {
+		handler = auditLogger.Handler(handler)
+	}
+	server := &http.Server{
+		Addr:      fmt.Sprintf("%s:%d", *argIp, *argPort),
+		Handler:   handler,
+		TLSConfig: serverTLSConfig,
+	}
+	if serverTLSConfig != nil {
+		// The certificates are served by the TLS config.
+		klog.Fatal(server.ListenAndServeTLS("", ""))
+	}
+	klog.Fatal(server.ListenAndServe())
 }
 
 func setMaxProcs() {
@@ -244,6 +436,18 @@ func installSignalHandler(containerManager manager.Manager) {
 		if err := containerManager.Stop(); err != nil {
 			klog.Errorf("Failed to stop container manager: %v", err)
 		}
+		// The queued stats and those buffered by the write-ahead logs are
+		// written, or spilled to disk, before exiting.
+		for _, driver := range storageDrivers {
+			if err := driver.Close(); err != nil {
+				klog.Errorf("Failed to close storage driver: %v", err)
+			}
+		}
+		if localStore != nil {
+			if err := localStore.Close(); err != nil {
+				klog.Errorf("Failed to close local store: %v", err)
+			}
+		}
 		klog.Infof("Exiting given signal: %v", sig)
 		os.Exit(0)
 	}()
@@ -275,6 +479,39 @@ func createCollectorHttpClient(collectorCert, collectorKey string) http.Client {
 	return http.Client{Transport: transport}
 }
 
+// createAuthenticator returns the authenticator of the clients of the APIs,
+// or nil if authentication is disabled.
+func createAuthenticator() apiauth.Authenticator {
+	var authenticators apiauth.Authenticators
+	if *authTokenFile != "" {
+		tokens, err := apiauth.NewTokenFile(*authTokenFile)
+		if err != nil {
+			klog.Fatalf("Failed to read the auth token file: %v", err)
+		}
+		authenticators = append(authenticators, tokens)
+	}
+	if *authOIDCIssuerURL != "" {
+		var controlGroups []string
+		if *authOIDCControlGroups != "" {
+			controlGroups = strings.Split(*authOIDCControlGroups, ",")
+		}
+		oidc, err := apiauth.NewOIDC(apiauth.OIDCConfig{
+			IssuerURL:     *authOIDCIssuerURL,
+			ClientID:      *authOIDCClientID,
+			GroupsClaim:   *authOIDCGroupsClaim,
+			ControlGroups: controlGroups,
+		}, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			klog.Fatalf("Failed to configure OpenID Connect authentication: %v", err)
+		}
+		authenticators = append(authenticators, oidc)
+	}
+	if len(authenticators) == 0 {
+		return nil
+	}
+	return authenticators
+}
+
 func toIncludedMetrics(ignoreMetrics container.MetricSet) container.MetricSet {
 	return container.AllMetrics.Difference(ignoreMetrics)
 }
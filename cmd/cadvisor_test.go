@@ -58,6 +58,95 @@ func TestMemoryNumaMetricsAreDisabledByDefault(t *testing.T) {
 	assert.True(t, ignoreMetrics.Has(container.MemoryNumaMetrics))
 }
 
+func TestMemoryStatMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.MemoryStatMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.MemoryStatMetrics))
+}
+
+func TestNetworkQueueMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.NetworkQueueMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.NetworkQueueMetrics))
+}
+
+func TestKsmMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.KsmMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.KsmMetrics))
+}
+
+func TestCpuStealMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.CpuStealMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.CpuStealMetrics))
+}
+
+func TestCpuFrequencyMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.CpuFrequencyMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.CpuFrequencyMetrics))
+}
+
+func TestPowerMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.PowerMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.PowerMetrics))
+}
+
+func TestThermalMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.ThermalMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.ThermalMetrics))
+}
+
+func TestThermalThrottleMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.ThermalThrottleMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.ThermalThrottleMetrics))
+}
+
+func TestCpuUsageQuantileMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.CpuUsageQuantileMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.CpuUsageQuantileMetrics))
+}
+
+func TestMdRaidMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.MdRaidMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.MdRaidMetrics))
+}
+
+func TestStorageSessionMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.StorageSessionMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.StorageSessionMetrics))
+}
+
+func TestSlabMetricsAreDisabledByDefault(t *testing.T) {
+	assert.True(t, ignoreMetrics.Has(container.SlabMetrics))
+	flag.Parse()
+	assert.True(t, ignoreMetrics.Has(container.SlabMetrics))
+}
+
+func TestEnableMetrics(t *testing.T) {
+	assert.NoError(t, enableMetrics.Set("nic_queues,tcp"))
+	defer enableMetrics.Set("")
+
+	ignored := container.MetricSet{
+		container.NetworkQueueMetrics:    struct{}{},
+		container.NetworkTcpUsageMetrics: struct{}{},
+		container.DiskUsageMetrics:       struct{}{},
+	}
+	included := toIncludedMetrics(ignored.Difference(enableMetrics.MetricSet))
+	assert.True(t, included.Has(container.NetworkQueueMetrics))
+	assert.True(t, included.Has(container.NetworkTcpUsageMetrics))
+	assert.False(t, included.Has(container.DiskUsageMetrics))
+
+	assert.Error(t, enableMetrics.Set("unknown"))
+}
+
 func TestIgnoreMetrics(t *testing.T) {
 	tests := []struct {
 		value    string
@@ -93,6 +182,7 @@ func TestToIncludedMetrics(t *testing.T) {
 			container.PerCpuUsageMetrics:             struct{}{},
 			container.MemoryUsageMetrics:             struct{}{},
 			container.MemoryNumaMetrics:              struct{}{},
+			container.MemoryStatMetrics:              struct{}{},
 			container.CpuLoadMetrics:                 struct{}{},
 			container.DiskIOMetrics:                  struct{}{},
 			container.AcceleratorUsageMetrics:        struct{}{},
@@ -108,6 +198,18 @@ func TestToIncludedMetrics(t *testing.T) {
 			container.ReferencedMemoryMetrics:        struct{}{},
 			container.CPUTopologyMetrics:             struct{}{},
 			container.ResctrlMetrics:                 struct{}{},
+			container.NetworkQueueMetrics:            struct{}{},
+			container.GvisorMetrics:                  struct{}{},
+			container.KsmMetrics:                     struct{}{},
+			container.CpuStealMetrics:                struct{}{},
+			container.CpuFrequencyMetrics:            struct{}{},
+			container.PowerMetrics:                   struct{}{},
+			container.ThermalMetrics:                 struct{}{},
+			container.ThermalThrottleMetrics:         struct{}{},
+			container.CpuUsageQuantileMetrics:        struct{}{},
+			container.MdRaidMetrics:                  struct{}{},
+			container.StorageSessionMetrics:          struct{}{},
+			container.SlabMetrics:                    struct{}{},
 		},
 		container.AllMetrics,
 		{},
@@ -0,0 +1,130 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// Maximum number of events in a debug bundle.
+const bundleMaxEvents = 10000
+
+// handleDebugRequest serves the debugging resources, only the bundle for now.
+func handleDebugRequest(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter) error {
+	if len(request) != 1 || request[0] != "bundle" {
+		return unknownResource("unknown debug resource %q", strings.Join(request, "/"))
+	}
+	klog.V(4).Infof("Api - Debug bundle, options %+v", opt)
+	now := time.Now()
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"cadvisor-bundle-%s.tar.gz\"", now.UTC().Format("20060102T150405Z")))
+	return writeBundle(w, m, opt, now)
+}
+
+// writeBundle writes a tar.gz archive of the state of cAdvisor and of the
+// containers it monitors to w. Parts of the state which cannot be gathered
+// are listed in errors.txt instead of failing the whole bundle.
+func writeBundle(w io.Writer, m manager.Manager, opt v2.RequestOptions, now time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	var errs []string
+	add := func(name string, v interface{}, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			return
+		}
+		if err := writeBundleFile(tw, name, out, now); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	version, err := m.GetVersionInfo()
+	add("version.json", version, err)
+	machine, err := m.GetMachineInfo()
+	add("machine.json", machine, err)
+	add("status.json", m.GetStatus(), nil)
+	if effectiveConfig != nil {
+		add("config.json", effectiveConfig.Effective(), nil)
+	}
+	add("watches.json", m.GetAdHocWatches(), nil)
+
+	opt.Recursive = true
+	infos, err := m.GetContainerInfoV2("/", opt)
+	if err != nil {
+		// The other containers are still in the bundle.
+		errs = append(errs, fmt.Sprintf("containers: %v", err))
+	}
+	specs := make(map[string]v2.ContainerSpec, len(infos))
+	stats := make(map[string][]*v2.ContainerStats, len(infos))
+	for name, cinfo := range infos {
+		specs[name] = cinfo.Spec
+		stats[name] = cinfo.Stats
+	}
+	add("specs.json", specs, nil)
+	add("stats.json", stats, nil)
+
+	request := events.NewRequest()
+	for _, t := range []info.EventType{info.EventOom, info.EventOomKill, info.EventContainerCreation, info.EventContainerDeletion, info.EventMachineInfoChanged, info.EventMdArrayChanged, info.EventContainerRestored} {
+		request.EventType[t] = true
+	}
+	request.ContainerName = "/"
+	request.IncludeSubcontainers = true
+	request.MaxEventsReturned = bundleMaxEvents
+	pastEvents, err := m.GetPastEvents(request)
+	add("events.json", pastEvents, err)
+
+	if len(errs) > 0 {
+		if err := writeBundleFile(tw, "errors.txt", []byte(strings.Join(errs, "\n")+"\n"), now); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeBundleFile(tw *tar.Writer, name string, content []byte, modTime time.Time) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    "cadvisor-bundle/" + name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(content)
+	return err
+}
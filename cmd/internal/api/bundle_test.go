@@ -0,0 +1,104 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bundleManager fails to return the machine info.
+type bundleManager struct {
+	manager.Manager
+}
+
+func (m *bundleManager) GetVersionInfo() (*info.VersionInfo, error) {
+	return &info.VersionInfo{CadvisorVersion: "v0.0.1"}, nil
+}
+
+func (m *bundleManager) GetMachineInfo() (*info.MachineInfo, error) {
+	return nil, errors.New("machine info unavailable")
+}
+
+func (m *bundleManager) GetStatus() v2.Status {
+	return v2.Status{}
+}
+
+func (m *bundleManager) GetAdHocWatches() []v2.AdHocWatch {
+	return nil
+}
+
+func (m *bundleManager) GetContainerInfoV2(containerName string, options v2.RequestOptions) (map[string]v2.ContainerInfo, error) {
+	return map[string]v2.ContainerInfo{"/a": {Spec: v2.ContainerSpec{Image: "a"}, Stats: []*v2.ContainerStats{{}}}}, nil
+}
+
+func (m *bundleManager) GetPastEvents(request *events.Request) ([]*info.Event, error) {
+	return []*info.Event{{ContainerName: "/a", EventType: info.EventOom}}, nil
+}
+
+func TestHandleDebugBundleRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	do := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/api/v2.1/debug"+path, nil)
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, &bundleManager{}, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("/other").Code)
+
+	w := do("/bundle")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	gz, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[h.Name] = string(content)
+	}
+	for _, name := range []string{"version.json", "status.json", "watches.json", "specs.json", "stats.json", "events.json", "errors.txt"} {
+		assert.Contains(t, files, "cadvisor-bundle/"+name)
+	}
+	assert.NotContains(t, files, "cadvisor-bundle/machine.json")
+	assert.Contains(t, files["cadvisor-bundle/errors.txt"], "machine.json: machine info unavailable")
+	assert.Contains(t, files["cadvisor-bundle/specs.json"], `"image": "a"`)
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// Maximum size of the collector configs accepted by the API.
+const maxCollectorConfigSize = 1 << 20
+
+// collectorResult is the response to the requests adding a collector.
+type collectorResult struct {
+	Name string `json:"name"`
+	// Whether the collector was only validated.
+	DryRun bool `json:"dry_run"`
+	// Specs of the metrics of the collector.
+	Metrics []info.MetricSpec `json:"metrics"`
+}
+
+// handleCollectorsRequest lists the collectors of a container on GET, adds
+// the collector named by the name parameter with the config in the body on
+// POST, and removes it on DELETE.
+func handleCollectorsRequest(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	containerName := getContainerName(request)
+	if r.Method == http.MethodGet {
+		klog.V(4).Infof("Api - Collectors of container %q, options %+v", containerName, opt)
+		names, err := m.GetCollectors(containerName, opt)
+		if err != nil {
+			return err
+		}
+		return writeResult(names, w)
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		return badRequest("missing 'name' parameter")
+	}
+	switch r.Method {
+	case http.MethodPost:
+		dryRun := false
+		if val := r.URL.Query().Get("dry_run"); val != "" {
+			var err error
+			dryRun, err = strconv.ParseBool(val)
+			if err != nil {
+				return badRequest("invalid 'dry_run' %q: %v", val, err)
+			}
+		}
+		config, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxCollectorConfigSize))
+		if err != nil {
+			return badRequest("failed to read config: %v", err)
+		}
+		klog.V(4).Infof("Api - Add collector %q to container %q, dry run %v", name, containerName, dryRun)
+		specs, err := m.AddCollector(containerName, name, config, dryRun, opt)
+		if err != nil {
+			return err
+		}
+		return writeResult(collectorResult{Name: name, DryRun: dryRun, Metrics: specs}, w)
+	case http.MethodDelete:
+		klog.V(4).Infof("Api - Remove collector %q from container %q", name, containerName)
+		if err := m.RemoveCollector(containerName, name, opt); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return &requestError{code: http.StatusMethodNotAllowed, reason: ReasonBadRequest, err: fmt.Errorf("method %s is not allowed", r.Method)}
+	}
+}
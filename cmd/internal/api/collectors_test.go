@@ -0,0 +1,104 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/cadvisor/collector"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectorsManager keeps the collectors of a single container.
+type collectorsManager struct {
+	manager.Manager
+	collectors []string
+}
+
+func (m *collectorsManager) GetCollectors(containerName string, options v2.RequestOptions) ([]string, error) {
+	return m.collectors, nil
+}
+
+func (m *collectorsManager) AddCollector(containerName, collectorName string, config []byte, dryRun bool, options v2.RequestOptions) ([]info.MetricSpec, error) {
+	if !json.Valid(config) {
+		return nil, fmt.Errorf("%w: not JSON", collector.ErrInvalidConfig)
+	}
+	if !dryRun {
+		m.collectors = append(m.collectors, collectorName)
+	}
+	return []info.MetricSpec{{Name: "requests", Type: info.MetricCumulative, Format: info.FloatType}}, nil
+}
+
+func (m *collectorsManager) RemoveCollector(containerName, collectorName string, options v2.RequestOptions) error {
+	for i, name := range m.collectors {
+		if name == collectorName {
+			m.collectors = append(m.collectors[:i], m.collectors[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", collector.ErrUnknownCollector, collectorName)
+}
+
+func TestHandleCollectorsRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	m := &collectorsManager{}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://localhost:8080/api/v2.1/collectors/docker/app"+path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, m, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	w := do(http.MethodPost, "?name=prometheus&dry_run=true", `{"endpoint": "http://localhost/metrics"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result collectorResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, collectorResult{Name: "prometheus", DryRun: true, Metrics: []info.MetricSpec{{Name: "requests", Type: info.MetricCumulative, Format: info.FloatType}}}, result)
+	assert.Empty(t, m.collectors)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "?name=prometheus", `{"endpoint": "http://localhost/metrics"}`).Code)
+	w = do(http.MethodGet, "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `["prometheus"]`, w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "?name=nginx", `{`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "?name=nginx&dry_run=maybe", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "", `{}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPut, "?name=nginx", `{}`).Code)
+
+	w = do(http.MethodDelete, "?name=prometheus", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	body, err := ioutil.ReadAll(w.Body)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+	assert.Empty(t, m.collectors)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "?name=prometheus", "").Code)
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/cadvisor/cmd/internal/config"
+
+	"k8s.io/klog/v2"
+)
+
+// Configuration served by the config endpoint.
+var effectiveConfig *config.Config
+
+// SetConfig sets the configuration served by the config endpoint.
+func SetConfig(c *config.Config) {
+	effectiveConfig = c
+}
+
+// handleConfigRequest serves the effective configuration of cAdvisor.
+func handleConfigRequest(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return &requestError{code: http.StatusMethodNotAllowed, reason: ReasonBadRequest, err: fmt.Errorf("method %s is not allowed", r.Method)}
+	}
+	if effectiveConfig == nil {
+		return unknownResource("the configuration is not available")
+	}
+	klog.V(4).Infof("Api - Config")
+	return writeResult(effectiveConfig.Effective(), w)
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/cadvisor/cmd/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleConfigRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	do := func(method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://localhost:8080/api/v2.1/config", nil)
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, nil, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	defer SetConfig(nil)
+	SetConfig(nil)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet).Code)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Int("port", 8080, "")
+	require.NoError(t, flags.Parse([]string{"--port=9090"}))
+	c, err := config.Load(flags, "")
+	require.NoError(t, err)
+	SetConfig(c)
+
+	w := do(http.MethodGet)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var effective config.Effective
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &effective))
+	assert.Equal(t, map[string]config.Setting{
+		"port": {Value: "9090", Default: "8080", Source: config.SourceFlag},
+	}, effective.Settings)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost).Code)
+}
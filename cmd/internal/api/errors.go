@@ -0,0 +1,157 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/cadvisor/cache/memory"
+	"github.com/google/cadvisor/cmd/internal/logging"
+	"github.com/google/cadvisor/collector"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrorSchemaVersion is the version of the error response format.
+const ErrorSchemaVersion = "v1"
+
+// Reasons of failed requests.
+const (
+	ReasonBadRequest        = "BadRequest"
+	ReasonUnauthenticated   = "Unauthenticated"
+	ReasonNotFound          = "NotFound"
+	ReasonPermissionDenied  = "PermissionDenied"
+	ReasonCollectorDisabled = "CollectorDisabled"
+	ReasonConflict          = "Conflict"
+	ReasonRateLimited       = "RateLimited"
+	ReasonInternal          = "InternalError"
+)
+
+// ErrorResponse is the body of failed requests.
+type ErrorResponse struct {
+	// Version of the error response format.
+	Version string `json:"version"`
+	Error   Error  `json:"error"`
+}
+
+// Error describes why a request failed.
+type Error struct {
+	// HTTP status code of the response.
+	Code int `json:"code"`
+	// Machine-readable cause of the failure, one of the Reason constants.
+	Reason string `json:"reason"`
+	// Whether the same request may succeed later.
+	Retryable bool `json:"retryable"`
+	// Human-readable description of the failure.
+	Detail string `json:"detail"`
+}
+
+var (
+	// ErrUnauthenticated is wrapped by the errors of requests without valid
+	// credentials.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is wrapped by the errors of requests whose credentials do
+	// not grant access to the endpoint.
+	ErrForbidden = errors.New("forbidden")
+	// ErrRateLimited is wrapped by the errors of requests exceeding the rate
+	// allowed to their client.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// requestError is an error with a known status and reason.
+type requestError struct {
+	code   int
+	reason string
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{code: http.StatusBadRequest, reason: ReasonBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func unknownResource(format string, args ...interface{}) error {
+	return &requestError{code: http.StatusNotFound, reason: ReasonNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// NewError maps err to the error reported to clients.
+func NewError(err error) Error {
+	e := Error{Code: http.StatusInternalServerError, Reason: ReasonInternal, Retryable: true, Detail: err.Error()}
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		e.Code, e.Reason, e.Retryable = reqErr.code, reqErr.reason, false
+	case errors.Is(err, ErrUnauthenticated):
+		e.Code, e.Reason, e.Retryable = http.StatusUnauthorized, ReasonUnauthenticated, false
+	case errors.Is(err, ErrForbidden):
+		e.Code, e.Reason, e.Retryable = http.StatusForbidden, ReasonPermissionDenied, false
+	case errors.Is(err, ErrRateLimited):
+		e.Code, e.Reason, e.Retryable = http.StatusTooManyRequests, ReasonRateLimited, true
+	case errors.Is(err, memory.ErrDownsamplingDisabled):
+		e.Code, e.Reason, e.Retryable = http.StatusBadRequest, ReasonBadRequest, false
+	case errors.Is(err, manager.ErrUnknownContainer):
+		e.Code, e.Reason, e.Retryable = http.StatusNotFound, ReasonNotFound, false
+	case errors.Is(err, manager.ErrCollectorDisabled):
+		e.Code, e.Reason, e.Retryable = http.StatusNotImplemented, ReasonCollectorDisabled, false
+	case errors.Is(err, logging.ErrInvalidLevels):
+		e.Code, e.Reason, e.Retryable = http.StatusBadRequest, ReasonBadRequest, false
+	case errors.Is(err, collector.ErrInvalidConfig):
+		e.Code, e.Reason, e.Retryable = http.StatusBadRequest, ReasonBadRequest, false
+	case errors.Is(err, collector.ErrUnknownCollector):
+		e.Code, e.Reason, e.Retryable = http.StatusNotFound, ReasonNotFound, false
+	case errors.Is(err, manager.ErrCollectorExists):
+		e.Code, e.Reason, e.Retryable = http.StatusConflict, ReasonConflict, false
+	case errors.Is(err, manager.ErrInvalidWatch):
+		e.Code, e.Reason, e.Retryable = http.StatusBadRequest, ReasonBadRequest, false
+	case errors.Is(err, manager.ErrInvalidQuery):
+		e.Code, e.Reason, e.Retryable = http.StatusBadRequest, ReasonBadRequest, false
+	case errors.Is(err, manager.ErrContainerMonitored):
+		e.Code, e.Reason, e.Retryable = http.StatusConflict, ReasonConflict, false
+	case errors.Is(err, manager.ErrTooManyWatches):
+		e.Code, e.Reason, e.Retryable = http.StatusConflict, ReasonConflict, true
+	case errors.Is(err, manager.ErrCollectorAPIDisabled):
+		e.Code, e.Reason, e.Retryable = http.StatusForbidden, ReasonPermissionDenied, false
+	case errors.Is(err, os.ErrPermission):
+		e.Code, e.Reason, e.Retryable = http.StatusForbidden, ReasonPermissionDenied, false
+	}
+	return e
+}
+
+// WriteError writes err as a JSON error response.
+func WriteError(w http.ResponseWriter, err error) {
+	e := NewError(err)
+	out, err := json.Marshal(ErrorResponse{Version: ErrorSchemaVersion, Error: e})
+	if err != nil {
+		klog.Errorf("failed to marshal error response %+v: %v", e, err)
+		http.Error(w, e.Detail, e.Code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.Code)
+	w.Write(out)
+}
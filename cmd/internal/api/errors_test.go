@@ -0,0 +1,108 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/cadvisor/cache/memory"
+	"github.com/google/cadvisor/cmd/internal/logging"
+	"github.com/google/cadvisor/collector"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewError(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		code      int
+		reason    string
+		retryable bool
+	}{
+		{badRequest("bad option"), http.StatusBadRequest, ReasonBadRequest, false},
+		{unknownResource("unknown request type"), http.StatusNotFound, ReasonNotFound, false},
+		{fmt.Errorf("failed to get container: %w", fmt.Errorf("%w %q", manager.ErrUnknownContainer, "/foo")), http.StatusNotFound, ReasonNotFound, false},
+		{fmt.Errorf("derived stats not enabled: %w", manager.ErrCollectorDisabled), http.StatusNotImplemented, ReasonCollectorDisabled, false},
+		{fmt.Errorf("%w: unknown field", collector.ErrInvalidConfig), http.StatusBadRequest, ReasonBadRequest, false},
+		{fmt.Errorf("%w %q", collector.ErrUnknownCollector, "nginx"), http.StatusNotFound, ReasonNotFound, false},
+		{fmt.Errorf("%w: %q", manager.ErrCollectorExists, "nginx"), http.StatusConflict, ReasonConflict, false},
+		{fmt.Errorf("%w: cannot add collector", manager.ErrCollectorAPIDisabled), http.StatusForbidden, ReasonPermissionDenied, false},
+		{fmt.Errorf("%w: invalid cgroup %q", manager.ErrInvalidWatch, "a"), http.StatusBadRequest, ReasonBadRequest, false},
+		{fmt.Errorf("%w: unknown metric %q", manager.ErrInvalidQuery, "a"), http.StatusBadRequest, ReasonBadRequest, false},
+		{fmt.Errorf("%w: %q", manager.ErrContainerMonitored, "/a"), http.StatusConflict, ReasonConflict, false},
+		{fmt.Errorf("%w: at most 1 cgroups", manager.ErrTooManyWatches), http.StatusConflict, ReasonConflict, true},
+		{fmt.Errorf("%w: missing bearer token", ErrUnauthenticated), http.StatusUnauthorized, ReasonUnauthenticated, false},
+		{fmt.Errorf("%w: unknown component %q", logging.ErrInvalidLevels, "gpu"), http.StatusBadRequest, ReasonBadRequest, false},
+		{fmt.Errorf("%w: read access only", ErrForbidden), http.StatusForbidden, ReasonPermissionDenied, false},
+		{fmt.Errorf("%w: retry in 1s", ErrRateLimited), http.StatusTooManyRequests, ReasonRateLimited, true},
+		{memory.ErrDownsamplingDisabled, http.StatusBadRequest, ReasonBadRequest, false},
+		{&os.PathError{Op: "open", Path: "/sys/fs/cgroup", Err: os.ErrPermission}, http.StatusForbidden, ReasonPermissionDenied, false},
+		{errors.New("docker daemon unavailable"), http.StatusInternalServerError, ReasonInternal, true},
+	} {
+		e := NewError(tc.err)
+		assert.Equal(t, Error{Code: tc.code, Reason: tc.reason, Retryable: tc.retryable, Detail: tc.err.Error()}, e)
+	}
+}
+
+type errorManager struct {
+	manager.Manager
+}
+
+func (m *errorManager) GetContainerInfo(containerName string, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+	return nil, fmt.Errorf("%w %q", manager.ErrUnknownContainer, containerName)
+}
+
+func TestHandleRequestErrors(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	for _, tc := range []struct {
+		path   string
+		code   int
+		reason string
+	}{
+		{"/api/", http.StatusBadRequest, ReasonBadRequest},
+		{"/api/v0.1/containers", http.StatusNotFound, ReasonNotFound},
+		{"/api/v1.3/unknown", http.StatusNotFound, ReasonNotFound},
+		{"/api/v1.3/containers/foo", http.StatusNotFound, ReasonNotFound},
+		{"/api/v2.0/stats/foo?type=pod", http.StatusBadRequest, ReasonBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080"+tc.path, nil)
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, &errorManager{}, w, r); err != nil {
+			WriteError(w, err)
+		}
+
+		assert.Equal(t, tc.code, w.Code, tc.path)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"), tc.path)
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), tc.path)
+		assert.Equal(t, ErrorSchemaVersion, resp.Version, tc.path)
+		assert.Equal(t, tc.code, resp.Error.Code, tc.path)
+		assert.Equal(t, tc.reason, resp.Error.Reason, tc.path)
+		assert.False(t, resp.Error.Retryable, tc.path)
+		assert.NotEmpty(t, resp.Error.Detail, tc.path)
+	}
+}
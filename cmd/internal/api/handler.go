@@ -50,7 +50,7 @@ func RegisterHandlers(mux httpmux.Mux, m manager.Manager) error {
 	mux.HandleFunc(apiResource, func(w http.ResponseWriter, r *http.Request) {
 		err := handleRequest(supportedApiVersions, m, w, r)
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			WriteError(w, err)
 		}
 	})
 	return nil
@@ -65,6 +65,30 @@ const (
 	apiRequestArgs
 )
 
+// Request types of the endpoints changing the state of cAdvisor on requests
+// other than GET, HEAD and OPTIONS.
+var stateChangingRequestTypes = map[string]bool{
+	collectorsApi: true,
+	loggingApi:    true,
+	watchApi:      true,
+}
+
+// ChangesState returns whether a request changes the state of cAdvisor. The
+// method alone doesn't tell: the v1 API is read with POST requests.
+func ChangesState(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	if !strings.Contains(r.URL.Path, apiResource) {
+		// Only the API serves requests changing the state of cAdvisor,
+		// other requests are assumed to change it for safety.
+		return true
+	}
+	requestElements := apiRegexp.FindStringSubmatch(r.URL.Path)
+	return len(requestElements) == 0 || stateChangingRequestTypes[requestElements[apiRequestType]]
+}
+
 func handleRequest(supportedApiVersions map[string]ApiVersion, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
 	start := time.Now()
 	defer func() {
@@ -75,7 +99,7 @@ func handleRequest(supportedApiVersions map[string]ApiVersion, m manager.Manager
 
 	const apiPrefix = "/api"
 	if !strings.HasPrefix(request, apiPrefix) {
-		return fmt.Errorf("incomplete API request %q", request)
+		return badRequest("incomplete API request %q", request)
 	}
 
 	// If the request doesn't have an API version, list those.
@@ -85,15 +109,14 @@ func handleRequest(supportedApiVersions map[string]ApiVersion, m manager.Manager
 			versions = append(versions, v)
 		}
 		sort.Strings(versions)
-		http.Error(w, fmt.Sprintf("Supported API versions: %s", strings.Join(versions, ",")), http.StatusBadRequest)
-		return nil
+		return badRequest("Supported API versions: %s", strings.Join(versions, ","))
 	}
 
 	// Verify that we have all the elements we expect:
 	// /<version>/<request type>[/<args...>]
 	requestElements := apiRegexp.FindStringSubmatch(request)
 	if len(requestElements) == 0 {
-		return fmt.Errorf("malformed request %q", request)
+		return badRequest("malformed request %q", request)
 	}
 	version := requestElements[apiVersion]
 	requestType := requestElements[apiRequestType]
@@ -102,15 +125,14 @@ func handleRequest(supportedApiVersions map[string]ApiVersion, m manager.Manager
 	// Check supported versions.
 	versionHandler, ok := supportedApiVersions[version]
 	if !ok {
-		return fmt.Errorf("unsupported API version %q", version)
+		return unknownResource("unsupported API version %q", version)
 	}
 
 	// If no request type, list possible request types.
 	if requestType == "" {
 		requestTypes := versionHandler.SupportedRequestTypes()
 		sort.Strings(requestTypes)
-		http.Error(w, fmt.Sprintf("Supported request types: %q", strings.Join(requestTypes, ",")), http.StatusBadRequest)
-		return nil
+		return badRequest("Supported request types: %q", strings.Join(requestTypes, ","))
 	}
 
 	// Trim the first empty element from the request.
@@ -201,10 +223,13 @@ func getEventRequest(r *http.Request) (*events.Request, bool, error) {
 		}
 	}
 	eventTypes := map[string]info.EventType{
-		"oom_events":      info.EventOom,
-		"oom_kill_events": info.EventOomKill,
-		"creation_events": info.EventContainerCreation,
-		"deletion_events": info.EventContainerDeletion,
+		"oom_events":                  info.EventOom,
+		"oom_kill_events":             info.EventOomKill,
+		"creation_events":             info.EventContainerCreation,
+		"deletion_events":             info.EventContainerDeletion,
+		"machine_info_changed_events": info.EventMachineInfoChanged,
+		"md_array_changed_events":     info.EventMdArrayChanged,
+		"restoration_events":          info.EventContainerRestored,
 	}
 	allEventTypes := false
 	if val, ok := urlMap["all_events"]; ok {
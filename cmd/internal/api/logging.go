@@ -0,0 +1,64 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/cadvisor/cmd/internal/logging"
+
+	"k8s.io/klog/v2"
+)
+
+// Maximum size of the log level updates accepted by the API.
+const maxLoggingUpdateSize = 1 << 16
+
+// Controller of the log levels served by the logging endpoint.
+var logLevels *logging.Controller
+
+// SetLogging sets the controller of the log levels served by the logging
+// endpoint.
+func SetLogging(c *logging.Controller) {
+	logLevels = c
+}
+
+// handleLoggingRequest returns the log levels on GET, and updates them with
+// the JSON body on PUT.
+func handleLoggingRequest(w http.ResponseWriter, r *http.Request) error {
+	if logLevels == nil {
+		return unknownResource("the log levels are not available")
+	}
+	switch r.Method {
+	case http.MethodGet:
+		klog.V(4).Infof("Api - Logging")
+		return writeResult(logLevels.Levels(), w)
+	case http.MethodPut:
+		var update logging.Update
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLoggingUpdateSize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&update); err != nil {
+			return badRequest("invalid log levels: %v", err)
+		}
+		levels, err := logLevels.Set(update)
+		if err != nil {
+			return err
+		}
+		return writeResult(levels, w)
+	default:
+		return &requestError{code: http.StatusMethodNotAllowed, reason: ReasonBadRequest, err: fmt.Errorf("method %s is not allowed", r.Method)}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/cadvisor/cmd/internal/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+func TestHandleLoggingRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	do := func(method, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://localhost:8080/api/v2.1/logging", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, nil, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	defer flags.Set("vmodule", "")
+	defer flags.Set("v", "0")
+	c, err := logging.NewController(flags)
+	require.NoError(t, err)
+	defer SetLogging(nil)
+	SetLogging(c)
+
+	w := do(http.MethodPut, `{"verbosity": 4, "components": {"discovery": 6}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var levels logging.Levels
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &levels))
+	assert.Equal(t, logging.Levels{Verbosity: 4, Components: map[string]int{"discovery": 6}}, levels)
+
+	w = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &levels))
+	assert.Equal(t, 4, levels.Verbosity)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"components": {"gpu": 2}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"level": 2}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodDelete, "").Code)
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// handleQueryRequest aggregates a metric of the subcontainers of the
+// requested container, grouped by labels.
+func handleQueryRequest(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	query, err := getQuery(r)
+	if err != nil {
+		return err
+	}
+	name := getContainerName(request)
+	klog.V(4).Infof("Api - Query(%q, %+v), options %+v", name, query, opt)
+	results, err := m.Query(name, query, opt)
+	if err != nil {
+		return err
+	}
+	return writeResult(results, w)
+}
+
+// getQuery returns the query of a HTTP request.
+func getQuery(r *http.Request) (v2.Query, error) {
+	values := r.URL.Query()
+	query := v2.Query{
+		Metric: values.Get("metric"),
+		Over:   values.Get("over"),
+		Op:     values.Get("op"),
+	}
+	if query.Metric == "" {
+		return query, badRequest("missing 'metric' parameter")
+	}
+	if by := values.Get("by"); by != "" {
+		query.By = strings.Split(by, ",")
+	}
+	if val := values.Get("range"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return query, badRequest("invalid 'range' %q", val)
+		}
+		query.Range = d
+	}
+	if val := values.Get("limit"); val != "" {
+		n, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return query, badRequest("invalid 'limit' %q", val)
+		}
+		query.Limit = int(n)
+	}
+	return query, nil
+}
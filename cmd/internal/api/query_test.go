@@ -0,0 +1,85 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryManager records the queries it answers.
+type queryManager struct {
+	manager.Manager
+	containerName string
+	query         v2.Query
+}
+
+func (m *queryManager) Query(containerName string, query v2.Query, options v2.RequestOptions) ([]v2.QueryResult, error) {
+	if query.Metric != "memory_working_set_bytes" {
+		return nil, fmt.Errorf("%w: unknown metric %q", manager.ErrInvalidQuery, query.Metric)
+	}
+	m.containerName, m.query = containerName, query
+	return []v2.QueryResult{{Labels: map[string]string{"team": "x"}, Value: 500, Containers: 2}}, nil
+}
+
+func TestHandleQueryRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	m := &queryManager{}
+	do := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8080/api/v2.1/query"+path, nil)
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, m, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	w := do("/kubepods?metric=memory_working_set_bytes&over=delta&op=max&by=team,app&range=5m&limit=3")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var results []v2.QueryResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "/kubepods", m.containerName)
+	assert.Equal(t, v2.Query{
+		Metric: "memory_working_set_bytes",
+		Over:   v2.QueryOverDelta,
+		Op:     v2.QueryOpMax,
+		By:     []string{"team", "app"},
+		Range:  5 * time.Minute,
+		Limit:  3,
+	}, m.query)
+
+	for _, path := range []string{
+		"",
+		"?metric=memory",
+		"?metric=memory_working_set_bytes&range=soon",
+		"?metric=memory_working_set_bytes&limit=-1",
+	} {
+		assert.Equal(t, http.StatusBadRequest, do(path).Code, path)
+	}
+}
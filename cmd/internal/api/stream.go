@@ -0,0 +1,286 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	streamStats  = "stats"
+	streamEvents = "events"
+
+	// Name of the query parameter carrying the resume token, for clients
+	// which cannot set the Last-Event-ID header.
+	lastEventIDParam = "last_event_id"
+)
+
+// Interval between the heartbeats sent on idle streams, keeping proxies from
+// closing them.
+var heartbeatInterval = 15 * time.Second
+
+// statsUpdate is the data of the events of the stats stream.
+type statsUpdate struct {
+	Name  string             `json:"name"`
+	Stats *v2.ContainerStats `json:"stats"`
+}
+
+// sseWriter writes server-sent events.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("could not access http.Flusher")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &sseWriter{w: w, flusher: flusher}, nil
+}
+
+// send writes an event whose ID is the resume token of t.
+func (s *sseWriter) send(event string, t time.Time, data interface{}) error {
+	out, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", t.UnixNano(), event, out); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseWriter) heartbeat() error {
+	if _, err := fmt.Fprint(s.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// resumeToken returns the time of the last event received by a reconnecting
+// client, or the zero time for new clients.
+func resumeToken(r *http.Request) (time.Time, error) {
+	token := r.Header.Get("Last-Event-ID")
+	if token == "" {
+		token = r.URL.Query().Get(lastEventIDParam)
+	}
+	if token == "" {
+		return time.Time{}, nil
+	}
+	nanos, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return time.Time{}, badRequest("invalid resume token %q", token)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+// handleStreamRequest serves /stream/stats/<container> and
+// /stream/events/<container> as server-sent events.
+func handleStreamRequest(request []string, opt v2.RequestOptions, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	if len(request) == 0 {
+		return badRequest("missing stream type, expected %q or %q", streamStats, streamEvents)
+	}
+	since, err := resumeToken(r)
+	if err != nil {
+		return err
+	}
+	name := getContainerName(request[1:])
+	switch request[0] {
+	case streamStats:
+		klog.V(4).Infof("Api - Stream stats of container %q, options %+v", name, opt)
+		return streamContainerStats(name, opt, since, m, w, r)
+	case streamEvents:
+		query, _, err := getEventRequest(r)
+		if err != nil {
+			return err
+		}
+		query.ContainerName = name
+		klog.V(4).Infof("Api - Stream events(%v)", query)
+		return streamEventsSince(query, since, m, w, r)
+	default:
+		return unknownResource("unknown stream type %q, expected %q or %q", request[0], streamStats, streamEvents)
+	}
+}
+
+func streamContainerStats(name string, opt v2.RequestOptions, since time.Time, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	statsChannel, err := m.WatchForStats(name, opt)
+	if err != nil {
+		return err
+	}
+	defer m.CloseStatsChannel(statsChannel.GetWatchId())
+
+	// Replay the cached stats the client missed while it was disconnected.
+	var missed []*info.ContainerInfo
+	if !since.IsZero() {
+		conts, err := m.GetRequestedContainersInfo(name, opt)
+		if err != nil && len(conts) == 0 {
+			return err
+		}
+		for _, cont := range conts {
+			for _, stats := range cont.Stats {
+				if stats.Timestamp.After(since) {
+					missed = append(missed, &info.ContainerInfo{
+						ContainerReference: cont.ContainerReference,
+						Spec:               cont.Spec,
+						Stats:              []*info.ContainerStats{stats},
+					})
+				}
+			}
+		}
+		sort.SliceStable(missed, func(i, j int) bool {
+			return missed[i].Stats[0].Timestamp.Before(missed[j].Stats[0].Timestamp)
+		})
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return err
+	}
+	// Timestamp of the last stats sent for each container, the live stats may
+	// repeat replayed ones.
+	sent := map[string]time.Time{}
+	send := func(cont *info.ContainerInfo) error {
+		stats := cont.Stats[0]
+		if !stats.Timestamp.After(sent[cont.Name]) {
+			return nil
+		}
+		sent[cont.Name] = stats.Timestamp
+		update := statsUpdate{
+			Name:  cont.Name,
+			Stats: v2.ContainerStatsFromV1(cont.Name, &cont.Spec, cont.Stats)[0],
+		}
+		return sse.send(streamStats, stats.Timestamp, update)
+	}
+	for _, cont := range missed {
+		if err := send(cont); err != nil {
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if err := sse.heartbeat(); err != nil {
+				return nil
+			}
+		case cont, ok := <-statsChannel.GetChannel():
+			if !ok {
+				return nil
+			}
+			if err := send(cont); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func streamEventsSince(query *events.Request, since time.Time, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	eventChannel, err := m.WatchForEvents(query)
+	if err != nil {
+		return err
+	}
+	defer m.CloseEventChannel(eventChannel.GetWatchId())
+
+	// Replay the stored events the client missed while it was disconnected.
+	var missed []*info.Event
+	if !since.IsZero() {
+		past := *query
+		past.StartTime = since
+		past.MaxEventsReturned = -1
+		pastEvents, err := m.GetPastEvents(&past)
+		if err != nil {
+			return err
+		}
+		for _, event := range pastEvents {
+			if event.Timestamp.After(since) {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return err
+	}
+	send := func(event *info.Event) error {
+		return sse.send(string(event.EventType), event.Timestamp, event)
+	}
+	// The watch is set up before the stored events are read, so the live
+	// events may repeat replayed ones. Other live events are all sent: their
+	// timestamps are not monotonic, e.g. the time of death of OOM events.
+	replayed := make(map[replayedEvent]struct{}, len(missed))
+	for _, event := range missed {
+		replayed[replayedEvent{event.ContainerName, event.EventType, event.Timestamp.UnixNano()}] = struct{}{}
+		if err := send(event); err != nil {
+			return nil
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-heartbeat.C:
+			if err := sse.heartbeat(); err != nil {
+				return nil
+			}
+		case event, ok := <-eventChannel.GetChannel():
+			if !ok {
+				return nil
+			}
+			key := replayedEvent{event.ContainerName, event.EventType, event.Timestamp.UnixNano()}
+			if _, ok := replayed[key]; ok {
+				delete(replayed, key)
+				continue
+			}
+			if err := send(event); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// replayedEvent identifies an event replayed to a resuming client.
+type replayedEvent struct {
+	containerName string
+	eventType     info.EventType
+	timestamp     int64
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamManager struct {
+	manager.Manager
+
+	stats      *manager.StatsChannel
+	events     *events.EventChannel
+	cached     map[string]*info.ContainerInfo
+	pastEvents []*info.Event
+	closed     chan int
+}
+
+func newStreamManager() *streamManager {
+	return &streamManager{
+		stats:  manager.NewStatsChannel(1),
+		events: events.NewEventChannel(2),
+		closed: make(chan int, 2),
+	}
+}
+
+func (m *streamManager) WatchForStats(containerName string, options v2.RequestOptions) (*manager.StatsChannel, error) {
+	return m.stats, nil
+}
+
+func (m *streamManager) CloseStatsChannel(watchID int) {
+	m.closed <- watchID
+}
+
+func (m *streamManager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return m.cached, nil
+}
+
+func (m *streamManager) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return m.events, nil
+}
+
+func (m *streamManager) CloseEventChannel(watchID int) {
+	m.closed <- watchID
+}
+
+func (m *streamManager) GetPastEvents(request *events.Request) ([]*info.Event, error) {
+	return m.pastEvents, nil
+}
+
+// sseEvent is a server-sent event, or a heartbeat when all fields are empty.
+type sseEvent struct {
+	id, event, data string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func startStream(t *testing.T, m manager.Manager, url string, header http.Header) (*http.Response, *bufio.Reader) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opt, err := GetRequestOptions(r)
+		require.NoError(t, err)
+		request := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v2.1/stream/"), "/")
+		if err := handleStreamRequest(request, opt, m, w, r); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest("GET", server.URL+url, nil)
+	require.NoError(t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+func containerInfo(name string, ts time.Time) *info.ContainerInfo {
+	return &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: name},
+		Spec:               info.ContainerSpec{HasCpu: true},
+		Stats: []*info.ContainerStats{{
+			Timestamp: ts,
+			Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: uint64(ts.Unix())}},
+		}},
+	}
+}
+
+func TestStreamStats(t *testing.T) {
+	m := newStreamManager()
+	start := time.Unix(1600000000, 0)
+	cached := containerInfo("/a", start)
+	cached.Stats = append(cached.Stats, containerInfo("/a", start.Add(time.Second)).Stats...)
+	m.cached = map[string]*info.ContainerInfo{"/a": cached}
+
+	resp, r := startStream(t, m, "/api/v2.1/stream/stats/a?recursive=true", http.Header{
+		"Last-Event-ID": {strconv.FormatInt(start.UnixNano(), 10)},
+	})
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Only the cached stats following the resume token are replayed.
+	ev := readEvent(t, r)
+	assert.Equal(t, strconv.FormatInt(start.Add(time.Second).UnixNano(), 10), ev.id)
+	assert.Equal(t, "stats", ev.event)
+	assert.Contains(t, ev.data, `"name":"/a"`)
+
+	// Live stats already replayed are skipped.
+	m.stats.GetChannel() <- containerInfo("/a", start.Add(time.Second))
+	m.stats.GetChannel() <- containerInfo("/b", start.Add(2*time.Second))
+	ev = readEvent(t, r)
+	assert.Equal(t, strconv.FormatInt(start.Add(2*time.Second).UnixNano(), 10), ev.id)
+	assert.Contains(t, ev.data, `"name":"/b"`)
+
+	resp.Body.Close()
+	select {
+	case id := <-m.closed:
+		assert.Equal(t, 1, id)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stats watch not closed")
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	m := newStreamManager()
+	start := time.Unix(1600000000, 0)
+	m.pastEvents = []*info.Event{
+		{ContainerName: "/a", Timestamp: start, EventType: info.EventOom},
+		{ContainerName: "/a", Timestamp: start.Add(time.Second), EventType: info.EventContainerCreation},
+	}
+
+	_, r := startStream(t, m, "/api/v2.1/stream/events?all_events=true&"+lastEventIDParam+"="+strconv.FormatInt(start.UnixNano(), 10), nil)
+	ev := readEvent(t, r)
+	assert.Equal(t, string(info.EventContainerCreation), ev.event)
+	assert.Equal(t, strconv.FormatInt(start.Add(time.Second).UnixNano(), 10), ev.id)
+
+	// Live events already replayed are skipped.
+	m.events.GetChannel() <- &info.Event{ContainerName: "/a", Timestamp: start.Add(time.Second), EventType: info.EventContainerCreation}
+	m.events.GetChannel() <- &info.Event{ContainerName: "/b", Timestamp: start.Add(2 * time.Second), EventType: info.EventOomKill}
+	ev = readEvent(t, r)
+	assert.Equal(t, string(info.EventOomKill), ev.event)
+	assert.Contains(t, ev.data, `"container_name":"/b"`)
+
+	// Live events older than the ones sent are not dropped.
+	m.events.GetChannel() <- &info.Event{ContainerName: "/c", Timestamp: start.Add(-time.Second), EventType: info.EventOom}
+	ev = readEvent(t, r)
+	assert.Equal(t, string(info.EventOom), ev.event)
+	assert.Contains(t, ev.data, `"container_name":"/c"`)
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	defer func(interval time.Duration) { heartbeatInterval = interval }(heartbeatInterval)
+	heartbeatInterval = 10 * time.Millisecond
+
+	_, r := startStream(t, newStreamManager(), "/api/v2.1/stream/stats/", nil)
+	assert.Equal(t, sseEvent{}, readEvent(t, r))
+}
+
+func TestResumeTokenInvalid(t *testing.T) {
+	_, err := resumeToken(makeHTTPRequest("http://localhost:8080/api/v2.1/stream/stats?last_event_id=yesterday", t))
+	assert.Error(t, err)
+}
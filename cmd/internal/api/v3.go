@@ -0,0 +1,321 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Number of items of a list page when the request sets no limit.
+	defaultPageLimit = 100
+	// Largest number of items of a list page.
+	maxPageLimit = 1000
+)
+
+// listResponse is the page of items returned by the list endpoints of the
+// v3 API.
+type listResponse struct {
+	Items []interface{} `json:"items"`
+	// Token returning the next page when passed as continue parameter,
+	// empty on the last page.
+	Continue string `json:"continue,omitempty"`
+}
+
+// container is an item of the containers list of the v3 API.
+type container struct {
+	Name  string               `json:"name"`
+	Spec  v2.ContainerSpec     `json:"spec"`
+	Stats []*v2.ContainerStats `json:"stats,omitempty"`
+}
+
+// continueToken is the decoded continue parameter of list requests.
+type continueToken struct {
+	// Name of the last container returned.
+	After string `json:"after,omitempty"`
+	// Timestamp of the last event returned, and number of events returned
+	// with that timestamp.
+	AfterTime int64 `json:"after_time,omitempty"`
+	Skip      int   `json:"skip,omitempty"`
+}
+
+func (t continueToken) encode() string {
+	out, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(out)
+}
+
+// listOptions holds the pagination and projection parameters of a request.
+type listOptions struct {
+	limit  int
+	token  continueToken
+	fields [][]string
+}
+
+func getListOptions(r *http.Request) (listOptions, error) {
+	opts := listOptions{limit: defaultPageLimit}
+	query := r.URL.Query()
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return opts, badRequest("invalid limit %q, expected a positive integer", limit)
+		}
+		if n > maxPageLimit {
+			n = maxPageLimit
+		}
+		opts.limit = n
+	}
+	if token := query.Get("continue"); token != "" {
+		data, err := base64.RawURLEncoding.DecodeString(token)
+		if err == nil {
+			err = json.Unmarshal(data, &opts.token)
+		}
+		if err != nil {
+			return opts, badRequest("invalid continue token %q", token)
+		}
+	}
+	if fields := query.Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			opts.fields = append(opts.fields, strings.Split(field, "."))
+		}
+	}
+	return opts, nil
+}
+
+// project keeps the requested fields of the JSON representation of v. Fields
+// are dot separated paths, applied to every element of arrays. All fields
+// are kept when none is requested.
+func project(v interface{}, fields [][]string) (interface{}, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	// Keep the precision of large counters.
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return selectFields(decoded, fields), nil
+}
+
+func selectFields(v interface{}, fields [][]string) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = selectFields(v[i], fields)
+		}
+		return out
+	case map[string]interface{}:
+		nested := map[string][][]string{}
+		whole := map[string]bool{}
+		for _, field := range fields {
+			if len(field) == 1 {
+				whole[field[0]] = true
+			} else {
+				nested[field[0]] = append(nested[field[0]], field[1:])
+			}
+		}
+		out := map[string]interface{}{}
+		for key, value := range v {
+			if whole[key] {
+				out[key] = value
+			} else if rest, ok := nested[key]; ok {
+				out[key] = selectFields(value, rest)
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// API v3
+
+type version3 struct{}
+
+func newVersion3() *version3 {
+	return &version3{}
+}
+
+func (api *version3) Version() string {
+	return "v3"
+}
+
+func (api *version3) SupportedRequestTypes() []string {
+	return []string{containersApi, machineApi, eventsApi}
+}
+
+func (api *version3) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	if err := api.handleRequest(requestType, request, m, w, r); err != nil {
+		WriteError(w, err)
+	}
+	return nil
+}
+
+func (api *version3) handleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	opts, err := getListOptions(r)
+	if err != nil {
+		return err
+	}
+	switch requestType {
+	case machineApi:
+		klog.V(4).Infof("Api - Machine")
+		machineInfo, err := m.GetMachineInfo()
+		if err != nil {
+			return err
+		}
+		res, err := project(machineInfo, opts.fields)
+		if err != nil {
+			return err
+		}
+		return writeResult(res, w)
+	case containersApi:
+		name := getContainerName(request)
+		reqOpts, err := GetRequestOptions(r)
+		if err != nil {
+			return badRequest("%v", err)
+		}
+		if r.URL.Query().Get("count") == "" {
+			// Only return the latest stats by default.
+			reqOpts.Count = 1
+		}
+		klog.V(4).Infof("Api - v3 Containers(%q), options %+v", name, reqOpts)
+		return listContainers(name, reqOpts, opts, m, w)
+	case eventsApi:
+		query, _, err := getEventRequest(r)
+		if err != nil {
+			return badRequest("%v", err)
+		}
+		query.ContainerName = getContainerName(request)
+		// Events are paginated rather than truncated.
+		query.MaxEventsReturned = -1
+		klog.V(4).Infof("Api - v3 Events(%v)", query)
+		return listEvents(query, opts, m, w)
+	default:
+		return unknownResource("unknown request type %q, expected one of %q", requestType, api.SupportedRequestTypes())
+	}
+}
+
+func listContainers(name string, reqOpts v2.RequestOptions, opts listOptions, m manager.Manager, w http.ResponseWriter) error {
+	conts, err := m.GetRequestedContainersInfo(name, reqOpts)
+	if err != nil {
+		if len(conts) == 0 {
+			// Mapped to a not found error by WriteError for unknown
+			// containers only.
+			return err
+		}
+		klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+	}
+	names := make([]string, 0, len(conts))
+	for name := range conts {
+		if name > opts.token.After {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	res := listResponse{Items: []interface{}{}}
+	if len(names) > opts.limit {
+		names = names[:opts.limit]
+		res.Continue = continueToken{After: names[len(names)-1]}.encode()
+	}
+	for _, name := range names {
+		cont := conts[name]
+		item, err := project(container{
+			Name:  name,
+			Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
+			Stats: v2.ContainerStatsFromV1(name, &cont.Spec, cont.Stats),
+		}, opts.fields)
+		if err != nil {
+			return err
+		}
+		res.Items = append(res.Items, item)
+	}
+	return writeResult(res, w)
+}
+
+func listEvents(query *events.Request, opts listOptions, m manager.Manager, w http.ResponseWriter) error {
+	if opts.token.AfterTime != 0 {
+		query.StartTime = time.Unix(0, opts.token.AfterTime)
+	}
+	evs, err := m.GetPastEvents(query)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(evs, func(i, j int) bool {
+		return evs[i].Timestamp.Before(evs[j].Timestamp)
+	})
+
+	// Skip the events of the last page sharing its last timestamp.
+	skipped := 0
+	for len(evs) > 0 && evs[0].Timestamp.UnixNano() == opts.token.AfterTime && skipped < opts.token.Skip {
+		evs = evs[1:]
+		skipped++
+	}
+
+	res := listResponse{Items: []interface{}{}}
+	if len(evs) > opts.limit {
+		evs = evs[:opts.limit]
+		res.Continue = nextEventsToken(evs, opts.token).encode()
+	}
+	for _, ev := range evs {
+		item, err := project(ev, opts.fields)
+		if err != nil {
+			return err
+		}
+		res.Items = append(res.Items, item)
+	}
+	return writeResult(res, w)
+}
+
+// nextEventsToken returns the token of the page following the events of page,
+// which was requested with token.
+func nextEventsToken(page []*info.Event, token continueToken) continueToken {
+	last := page[len(page)-1].Timestamp.UnixNano()
+	next := continueToken{AfterTime: last}
+	for _, ev := range page {
+		if ev.Timestamp.UnixNano() == last {
+			next.Skip++
+		}
+	}
+	if last == token.AfterTime {
+		// The whole page shares the timestamp of the previous page.
+		next.Skip += token.Skip
+	}
+	return next
+}
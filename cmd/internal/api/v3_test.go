@@ -0,0 +1,187 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type v3Manager struct {
+	manager.Manager
+
+	containers map[string]*info.ContainerInfo
+	events     []*info.Event
+}
+
+func (m *v3Manager) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{NumCores: 8, MemoryCapacity: 1 << 40, MachineID: "machine"}, nil
+}
+
+func (m *v3Manager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	switch containerName {
+	case "/unknown":
+		return nil, fmt.Errorf("%w %q", manager.ErrUnknownContainer, containerName)
+	case "/failing":
+		return nil, errors.New("failed to read cgroup")
+	}
+	return m.containers, nil
+}
+
+func (m *v3Manager) GetPastEvents(request *events.Request) ([]*info.Event, error) {
+	var evs []*info.Event
+	for _, ev := range m.events {
+		if !ev.Timestamp.Before(request.StartTime) {
+			evs = append(evs, ev)
+		}
+	}
+	return evs, nil
+}
+
+func getV3(t *testing.T, m manager.Manager, url string) (int, map[string]interface{}) {
+	api := newVersion3()
+	r := makeHTTPRequest("http://localhost:8080/api/v3/"+url, t)
+	req := apiRegexp.FindStringSubmatch(r.URL.Path)
+	request := []string{}
+	if args := req[apiRequestArgs]; args != "" {
+		request = []string{args[1:]}
+	}
+	w := httptest.NewRecorder()
+	require.NoError(t, api.HandleRequest(req[apiRequestType], request, m, w, r))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func itemNames(body map[string]interface{}) []string {
+	var names []string
+	for _, item := range body["items"].([]interface{}) {
+		names = append(names, item.(map[string]interface{})["name"].(string))
+	}
+	return names
+}
+
+func TestV3ContainersPagination(t *testing.T) {
+	m := &v3Manager{containers: map[string]*info.ContainerInfo{}}
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("/c%d", i)
+		m.containers[name] = &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: name}}
+	}
+
+	var pages [][]string
+	url := "containers/?recursive=true&limit=2"
+	for {
+		code, body := getV3(t, m, url)
+		require.Equal(t, http.StatusOK, code)
+		pages = append(pages, itemNames(body))
+		token, ok := body["continue"].(string)
+		if !ok {
+			break
+		}
+		url = "containers/?recursive=true&limit=2&continue=" + token
+	}
+	assert.Equal(t, [][]string{{"/c0", "/c1"}, {"/c2", "/c3"}, {"/c4"}}, pages)
+}
+
+func TestV3FieldSelection(t *testing.T) {
+	ts := time.Unix(1600000000, 0)
+	m := &v3Manager{containers: map[string]*info.ContainerInfo{
+		"/a": {
+			ContainerReference: info.ContainerReference{Name: "/a"},
+			Spec:               info.ContainerSpec{Labels: map[string]string{"app": "web"}, Image: "nginx", HasCpu: true},
+			Stats: []*info.ContainerStats{{
+				Timestamp: ts,
+				Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 18446744073709551615, User: 1}},
+			}},
+		},
+	}}
+
+	code, body := getV3(t, m, "containers/a?fields=name,spec.labels,stats.cpu.usage.total")
+	require.Equal(t, http.StatusOK, code)
+	out, err := json.Marshal(body["items"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"/a","spec":{"labels":{"app":"web"}},"stats":[{"cpu":{"usage":{"total":18446744073709551615}}}]}]`, string(out))
+
+	code, body = getV3(t, m, "machine?fields=num_cores")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"num_cores": float64(8)}, body)
+}
+
+func TestV3EventsPagination(t *testing.T) {
+	ts := time.Unix(1600000000, 0)
+	m := &v3Manager{}
+	// Events sharing a timestamp span pages.
+	for i, offset := range []int{0, 1, 1, 1, 2} {
+		m.events = append(m.events, &info.Event{
+			ContainerName: fmt.Sprintf("/c%d", i),
+			Timestamp:     ts.Add(time.Duration(offset) * time.Second),
+			EventType:     info.EventOom,
+		})
+	}
+
+	var names []string
+	url := "events/?all_events=true&subcontainers=true&limit=2&fields=container_name"
+	for {
+		code, body := getV3(t, m, url)
+		require.Equal(t, http.StatusOK, code)
+		for _, item := range body["items"].([]interface{}) {
+			names = append(names, item.(map[string]interface{})["container_name"].(string))
+		}
+		token, ok := body["continue"].(string)
+		if !ok {
+			break
+		}
+		url = "events/?all_events=true&subcontainers=true&limit=2&fields=container_name&continue=" + token
+	}
+	assert.Equal(t, []string{"/c0", "/c1", "/c2", "/c3", "/c4"}, names)
+}
+
+func TestV3Errors(t *testing.T) {
+	m := &v3Manager{}
+	for _, tc := range []struct {
+		url    string
+		status int
+		reason string
+	}{
+		{"containers/?limit=-1", http.StatusBadRequest, ReasonBadRequest},
+		{"containers/?continue=%21", http.StatusBadRequest, ReasonBadRequest},
+		{"containers/?type=pod", http.StatusBadRequest, ReasonBadRequest},
+		{"containers/unknown", http.StatusNotFound, ReasonNotFound},
+		{"containers/failing", http.StatusInternalServerError, ReasonInternal},
+		{"unknown", http.StatusNotFound, ReasonNotFound},
+	} {
+		status, body := getV3(t, m, tc.url)
+		assert.Equal(t, tc.status, status, tc.url)
+		assert.Equal(t, ErrorSchemaVersion, body["version"], tc.url)
+		apiErr := body["error"].(map[string]interface{})
+		assert.Equal(t, float64(tc.status), apiErr["code"], tc.url)
+		assert.Equal(t, tc.reason, apiErr["reason"], tc.url)
+		assert.NotEmpty(t, apiErr["detail"], tc.url)
+	}
+}
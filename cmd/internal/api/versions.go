@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"sort"
 	"strconv"
 	"time"
 
@@ -43,8 +44,20 @@ const (
 	versionApi       = "version"
 	psApi            = "ps"
 	customMetricsApi = "appmetrics"
+	podsApi          = "pods"
+	streamApi        = "stream"
+	collectorsApi    = "collectors"
+	configApi        = "config"
+	loggingApi       = "logging"
+	statusApi        = "status"
+	watchApi         = "watch"
+	queryApi         = "query"
+	debugApi         = "debug"
 )
 
+// Maximum depth of the storage breakdown of a container.
+const maxStorageBreakdownDepth = 5
+
 // Interface for a cAdvisor API version
 type ApiVersion interface {
 	// Returns the version string.
@@ -65,8 +78,9 @@ func getApiVersions() []ApiVersion {
 	v1_3 := newVersion1_3(v1_2)
 	v2_0 := newVersion2_0()
 	v2_1 := newVersion2_1(v2_0)
+	v3 := newVersion3()
 
-	return []ApiVersion{v1_0, v1_1, v1_2, v1_3, v2_0, v2_1}
+	return []ApiVersion{v1_0, v1_1, v1_2, v1_3, v2_0, v2_1, v3}
 
 }
 
@@ -111,7 +125,7 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 		// Get the container.
 		cont, err := m.GetContainerInfo(containerName, query)
 		if err != nil {
-			return fmt.Errorf("failed to get container %q with error: %s", containerName, err)
+			return fmt.Errorf("failed to get container %q with error: %w", containerName, err)
 		}
 
 		// Only output the container as JSON.
@@ -120,7 +134,7 @@ func (api *version1_0) HandleRequest(requestType string, request []string, m man
 			return err
 		}
 	default:
-		return fmt.Errorf("unknown request type %q", requestType)
+		return unknownResource("unknown request type %q", requestType)
 	}
 	return nil
 }
@@ -161,7 +175,7 @@ func (api *version1_1) HandleRequest(requestType string, request []string, m man
 		// Get the subcontainers.
 		containers, err := m.SubcontainersInfo(containerName, query)
 		if err != nil {
-			return fmt.Errorf("failed to get subcontainers for container %q with error: %s", containerName, err)
+			return fmt.Errorf("failed to get subcontainers for container %q with error: %w", containerName, err)
 		}
 
 		// Only output the containers as JSON.
@@ -217,20 +231,20 @@ func (api *version1_2) HandleRequest(requestType string, request []string, m man
 			// Get all Docker containers.
 			containers, err = m.AllDockerContainers(query)
 			if err != nil {
-				return fmt.Errorf("failed to get all Docker containers with error: %v", err)
+				return fmt.Errorf("failed to get all Docker containers with error: %w", err)
 			}
 		case 1:
 			// Get one Docker container.
 			var cont info.ContainerInfo
 			cont, err = m.DockerContainer(request[0], query)
 			if err != nil {
-				return fmt.Errorf("failed to get Docker container %q with error: %v", request[0], err)
+				return fmt.Errorf("failed to get Docker container %q with error: %w", request[0], err)
 			}
 			containers = map[string]info.ContainerInfo{
 				cont.Name: cont,
 			}
 		default:
-			return fmt.Errorf("unknown request for Docker container %v", request)
+			return badRequest("unknown request for Docker container %v", request)
 		}
 
 		// Only output the containers as JSON.
@@ -450,17 +464,58 @@ func (api *version2_0) HandleRequest(requestType string, request []string, m man
 	case psApi:
 		// reuse container type from request.
 		// ignore recursive.
-		// TODO(rjnagal): consider count to limit ps output.
 		name := getContainerName(request)
 		klog.V(4).Infof("Api - Spec for container %q, options %+v", name, opt)
 		ps, err := m.GetProcessList(name, opt)
 		if err != nil {
-			return fmt.Errorf("process listing failed: %v", err)
+			return fmt.Errorf("process listing failed: %w", err)
+		}
+		ps, err = sortProcessList(ps, r.URL.Query().Get("sort"), r.URL.Query().Get("limit"))
+		if err != nil {
+			return err
 		}
 		return writeResult(ps, w)
 	default:
-		return fmt.Errorf("unknown request type %q", requestType)
+		return unknownResource("unknown request type %q", requestType)
+	}
+}
+
+// Orderings of the process list by the "sort" parameter of the ps endpoint,
+// all but pid put the largest consumers first.
+var processListOrders = map[string]func(a, b *v2.ProcessInfo) bool{
+	"pid":         func(a, b *v2.ProcessInfo) bool { return a.Pid < b.Pid },
+	"cpu":         func(a, b *v2.ProcessInfo) bool { return a.PercentCpu > b.PercentCpu },
+	"cpu_time":    func(a, b *v2.ProcessInfo) bool { return a.CpuTimeSeconds > b.CpuTimeSeconds },
+	"mem":         func(a, b *v2.ProcessInfo) bool { return a.PercentMemory > b.PercentMemory },
+	"rss":         func(a, b *v2.ProcessInfo) bool { return a.RSS > b.RSS },
+	"threads":     func(a, b *v2.ProcessInfo) bool { return a.ThreadCount > b.ThreadCount },
+	"fds":         func(a, b *v2.ProcessInfo) bool { return a.FdCount > b.FdCount },
+	"read_bytes":  func(a, b *v2.ProcessInfo) bool { return a.ReadBytes > b.ReadBytes },
+	"write_bytes": func(a, b *v2.ProcessInfo) bool { return a.WriteBytes > b.WriteBytes },
+}
+
+// sortProcessList orders the process list by sortBy and keeps its first limit
+// processes, either of them may be empty.
+func sortProcessList(ps []v2.ProcessInfo, sortBy, limit string) ([]v2.ProcessInfo, error) {
+	if sortBy != "" {
+		less, ok := processListOrders[sortBy]
+		if !ok {
+			return nil, badRequest("unknown process sort order %q", sortBy)
+		}
+		sort.SliceStable(ps, func(i, j int) bool {
+			return less(&ps[i], &ps[j])
+		})
+	}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, badRequest("'limit' must be a non-negative integer, got %q", limit)
+		}
+		if n < len(ps) {
+			ps = ps[:n]
+		}
 	}
+	return ps, nil
 }
 
 type version2_1 struct {
@@ -478,7 +533,7 @@ func (api *version2_1) Version() string {
 }
 
 func (api *version2_1) SupportedRequestTypes() []string {
-	return append([]string{machineStatsApi}, api.baseVersion.SupportedRequestTypes()...)
+	return append([]string{machineStatsApi, podsApi, streamApi, collectorsApi, configApi, loggingApi, watchApi, statusApi, queryApi, debugApi}, api.baseVersion.SupportedRequestTypes()...)
 }
 
 func (api *version2_1) HandleRequest(requestType string, request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
@@ -502,6 +557,23 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 	case statsApi:
 		name := getContainerName(request)
 		klog.V(4).Infof("Api - Stats: Looking for stats for container %q, options %+v", name, opt)
+		if opt.Resolution == v2.ResolutionDownsampled {
+			// Only the v2 stats carry the aggregations of downsampled stats.
+			infos, err := m.GetContainerInfoV2(name, opt)
+			if err != nil {
+				if len(infos) == 0 {
+					return err
+				}
+				klog.Errorf("Error calling GetContainerInfoV2: %v", err)
+			}
+			delete(infos, "/")
+			if opt.Derived == v2.DerivedRate {
+				for _, cinfo := range infos {
+					v2.AddRateStats(cinfo.Stats)
+				}
+			}
+			return writeResult(infos, w)
+		}
 		conts, err := m.GetRequestedContainersInfo(name, opt)
 		if err != nil {
 			if len(conts) == 0 {
@@ -515,12 +587,72 @@ func (api *version2_1) HandleRequest(requestType string, request []string, m man
 				// Root cgroup stats should be exposed as machine stats
 				continue
 			}
+			stats := v2.ContainerStatsFromV1(name, &cont.Spec, cont.Stats)
+			if opt.Derived == v2.DerivedRate {
+				v2.AddRateStats(stats)
+			}
 			contStats[name] = v2.ContainerInfo{
 				Spec:  v2.ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
-				Stats: v2.ContainerStatsFromV1(name, &cont.Spec, cont.Stats),
+				Stats: stats,
 			}
 		}
 		return writeResult(contStats, w)
+	case podsApi:
+		klog.V(4).Infof("Api - Pods(%v), options %+v", request, opt)
+		opt.Recursive = true
+		conts, err := m.GetRequestedContainersInfo("/", opt)
+		if err != nil {
+			if len(conts) == 0 {
+				return err
+			}
+			klog.Errorf("Error calling GetRequestedContainersInfo: %v", err)
+		}
+		pods := v2.PodsFromV1(conts)
+		if len(request) > 0 {
+			// Return only the pod with the requested UID.
+			pod, ok := pods[request[0]]
+			if !ok {
+				return unknownResource("unknown pod %q", request[0])
+			}
+			return writeResult(pod, w)
+		}
+		return writeResult(pods, w)
+	case storageApi:
+		if len(request) == 0 {
+			return api.baseVersion.HandleRequest(requestType, request, m, w, r)
+		}
+		// Break down the usage of the writable layer of the requested container.
+		name := getContainerName(request)
+		depth := 1
+		if val := r.URL.Query().Get("depth"); val != "" {
+			depth, err = strconv.Atoi(val)
+			if err != nil || depth < 0 || depth > maxStorageBreakdownDepth {
+				return badRequest("'depth' must be an integer between 0 and %d, got %q", maxStorageBreakdownDepth, val)
+			}
+		}
+		klog.V(4).Infof("Api - Storage breakdown for container %q, depth %d, options %+v", name, depth, opt)
+		breakdown, err := m.GetStorageBreakdown(name, depth, opt)
+		if err != nil {
+			return err
+		}
+		return writeResult(breakdown, w)
+	case streamApi:
+		return handleStreamRequest(request, opt, m, w, r)
+	case collectorsApi:
+		return handleCollectorsRequest(request, opt, m, w, r)
+	case configApi:
+		return handleConfigRequest(w, r)
+	case loggingApi:
+		return handleLoggingRequest(w, r)
+	case watchApi:
+		return handleWatchRequest(request, m, w, r)
+	case statusApi:
+		klog.V(4).Infof("Api - Status")
+		return writeResult(m.GetStatus(), w)
+	case queryApi:
+		return handleQueryRequest(request, opt, m, w, r)
+	case debugApi:
+		return handleDebugRequest(request, opt, m, w)
 	default:
 		return api.baseVersion.HandleRequest(requestType, request, m, w, r)
 	}
@@ -541,7 +673,7 @@ func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	idType := r.URL.Query().Get("type")
 	if len(idType) != 0 {
 		if !supportedTypes[idType] {
-			return opt, fmt.Errorf("unknown 'type' %q", idType)
+			return opt, badRequest("unknown 'type' %q", idType)
 		}
 		opt.IdType = idType
 	}
@@ -549,7 +681,7 @@ func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	if len(count) != 0 {
 		n, err := strconv.ParseUint(count, 10, 32)
 		if err != nil {
-			return opt, fmt.Errorf("failed to parse 'count' option: %v", count)
+			return opt, badRequest("failed to parse 'count' option: %v", count)
 		}
 		opt.Count = int(n)
 	}
@@ -560,9 +692,37 @@ func GetRequestOptions(r *http.Request) (v2.RequestOptions, error) {
 	if maxAgeString := r.URL.Query().Get("max_age"); len(maxAgeString) > 0 {
 		maxAge, err := time.ParseDuration(maxAgeString)
 		if err != nil {
-			return opt, fmt.Errorf("failed to parse 'max_age' option: %v", err)
+			return opt, badRequest("failed to parse 'max_age' option: %v", err)
 		}
 		opt.MaxAge = &maxAge
 	}
+	filter := v2.ContainerFilter{
+		Container: r.URL.Query().Get("container"),
+		Namespace: r.URL.Query().Get("namespace"),
+	}
+	if selector := r.URL.Query().Get("selector"); len(selector) > 0 {
+		requirements, err := v2.ParseLabelSelector(selector)
+		if err != nil {
+			return opt, badRequest("failed to parse 'selector' option: %v", err)
+		}
+		filter.Selector = requirements
+	}
+	if filter.Container != "" || filter.Namespace != "" || len(filter.Selector) > 0 {
+		opt.Filter = &filter
+	}
+	switch resolution := r.URL.Query().Get("resolution"); resolution {
+	case "", v2.ResolutionRaw:
+	case v2.ResolutionDownsampled:
+		opt.Resolution = resolution
+	default:
+		return opt, badRequest("unknown 'resolution' %q, expected %q or %q", resolution, v2.ResolutionRaw, v2.ResolutionDownsampled)
+	}
+	switch derived := r.URL.Query().Get("derived"); derived {
+	case "":
+	case v2.DerivedRate:
+		opt.Derived = derived
+	default:
+		return opt, badRequest("unknown 'derived' %q, expected %q", derived, v2.DerivedRate)
+	}
 	return opt, nil
 }
@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/cadvisor/events"
 	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +80,82 @@ func TestGetEventRequestDoubleArgument(t *testing.T) {
 	assert.True(t, stream)
 	assert.Nil(t, err)
 }
+
+func TestGetRequestOptionsFilter(t *testing.T) {
+	r := makeHTTPRequest("http://localhost:8080/metrics?container=web-1&namespace=default&selector=app%3Dweb,!canary", t)
+	opt, err := GetRequestOptions(r)
+	assert.Nil(t, err)
+	assert.Equal(t, &v2.ContainerFilter{
+		Container: "web-1",
+		Namespace: "default",
+		Selector: []v2.LabelRequirement{
+			{Key: "app", Operator: v2.LabelEquals, Value: "web"},
+			{Key: "canary", Operator: v2.LabelNotExists},
+		},
+	}, opt.Filter)
+
+	opt, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/metrics", t))
+	assert.Nil(t, err)
+	assert.Nil(t, opt.Filter)
+
+	_, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/metrics?selector=%3Dweb", t))
+	assert.NotNil(t, err)
+}
+
+func TestGetRequestOptionsResolution(t *testing.T) {
+	opt, err := GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.0/stats?resolution=downsampled", t))
+	assert.Nil(t, err)
+	assert.Equal(t, v2.ResolutionDownsampled, opt.Resolution)
+
+	opt, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.0/stats?resolution=raw", t))
+	assert.Nil(t, err)
+	assert.Equal(t, "", opt.Resolution)
+
+	_, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.0/stats?resolution=1h", t))
+	assert.NotNil(t, err)
+}
+
+func TestGetRequestOptionsDerived(t *testing.T) {
+	opt, err := GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.1/stats?derived=rate", t))
+	assert.Nil(t, err)
+	assert.Equal(t, v2.DerivedRate, opt.Derived)
+
+	opt, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.1/stats", t))
+	assert.Nil(t, err)
+	assert.Equal(t, "", opt.Derived)
+
+	_, err = GetRequestOptions(makeHTTPRequest("http://localhost:8080/api/v2.1/stats?derived=delta", t))
+	assert.NotNil(t, err)
+}
+
+func TestSortProcessList(t *testing.T) {
+	ps := []v2.ProcessInfo{
+		{Pid: 1, RSS: 10, ReadBytes: 300},
+		{Pid: 2, RSS: 30, ReadBytes: 100},
+		{Pid: 3, RSS: 20, ReadBytes: 200},
+	}
+	pids := func(ps []v2.ProcessInfo) []int {
+		var pids []int
+		for _, p := range ps {
+			pids = append(pids, p.Pid)
+		}
+		return pids
+	}
+
+	sorted, err := sortProcessList(ps, "rss", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 3, 1}, pids(sorted))
+
+	sorted, err = sortProcessList(ps, "read_bytes", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 3}, pids(sorted))
+
+	sorted, err = sortProcessList(ps, "pid", "5")
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2, 3}, pids(sorted))
+
+	_, err = sortProcessList(ps, "size", "")
+	assert.NotNil(t, err)
+	_, err = sortProcessList(ps, "", "-1")
+	assert.NotNil(t, err)
+}
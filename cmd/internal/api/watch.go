@@ -0,0 +1,68 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/cadvisor/manager"
+
+	"k8s.io/klog/v2"
+)
+
+// handleWatchRequest lists the cgroups monitored on request on GET, monitors
+// the requested cgroup for the duration of the ttl parameter on POST, and
+// stops monitoring it on DELETE.
+func handleWatchRequest(request []string, m manager.Manager, w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		klog.V(4).Infof("Api - Watches")
+		return writeResult(m.GetAdHocWatches(), w)
+	case http.MethodPost:
+		if len(request) == 0 {
+			return badRequest("missing cgroup")
+		}
+		val := r.URL.Query().Get("ttl")
+		if val == "" {
+			return badRequest("missing 'ttl' parameter")
+		}
+		ttl, err := time.ParseDuration(val)
+		if err != nil {
+			return badRequest("invalid 'ttl' %q: %v", val, err)
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Watch cgroup %q for %v", name, ttl)
+		watch, err := m.WatchCgroup(name, ttl)
+		if err != nil {
+			return err
+		}
+		return writeResult(watch, w)
+	case http.MethodDelete:
+		if len(request) == 0 {
+			return badRequest("missing cgroup")
+		}
+		name := getContainerName(request)
+		klog.V(4).Infof("Api - Unwatch cgroup %q", name)
+		if err := m.UnwatchCgroup(name); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return &requestError{code: http.StatusMethodNotAllowed, reason: ReasonBadRequest, err: fmt.Errorf("method %s is not allowed", r.Method)}
+	}
+}
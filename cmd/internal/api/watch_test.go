@@ -0,0 +1,102 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchManager keeps the cgroups monitored on request.
+type watchManager struct {
+	manager.Manager
+	watches map[string]time.Time
+}
+
+func (m *watchManager) WatchCgroup(containerName string, ttl time.Duration) (v2.AdHocWatch, error) {
+	if containerName == "/system.slice" {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: %q", manager.ErrContainerMonitored, containerName)
+	}
+	m.watches[containerName] = time.Unix(1600000000, 0).Add(ttl)
+	return v2.AdHocWatch{Name: containerName, Expires: m.watches[containerName]}, nil
+}
+
+func (m *watchManager) UnwatchCgroup(containerName string) error {
+	if _, ok := m.watches[containerName]; !ok {
+		return fmt.Errorf("%w: %q", manager.ErrUnknownContainer, containerName)
+	}
+	delete(m.watches, containerName)
+	return nil
+}
+
+func (m *watchManager) GetAdHocWatches() []v2.AdHocWatch {
+	var watches []v2.AdHocWatch
+	for name, expires := range m.watches {
+		watches = append(watches, v2.AdHocWatch{Name: name, Expires: expires})
+	}
+	return watches
+}
+
+func TestHandleWatchRequest(t *testing.T) {
+	versions := map[string]ApiVersion{}
+	for _, v := range getApiVersions() {
+		versions[v.Version()] = v
+	}
+	m := &watchManager{watches: make(map[string]time.Time)}
+	do := func(method, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "http://localhost:8080/api/v2.1/watch"+path, nil)
+		w := httptest.NewRecorder()
+		if err := handleRequest(versions, m, w, r); err != nil {
+			WriteError(w, err)
+		}
+		return w
+	}
+
+	w := do(http.MethodPost, "/system.slice/run-r1.scope?ttl=10m")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var watch v2.AdHocWatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &watch))
+	assert.Equal(t, "/system.slice/run-r1.scope", watch.Name)
+	assert.Equal(t, time.Unix(1600000600, 0), watch.Expires.Local())
+
+	w = do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var watches []v2.AdHocWatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &watches))
+	assert.Len(t, watches, 1)
+
+	for path, code := range map[string]int{
+		"/system.slice/run-r2.scope":          http.StatusBadRequest,
+		"/system.slice/run-r2.scope?ttl=soon": http.StatusBadRequest,
+		"?ttl=1m":                             http.StatusBadRequest,
+		"/system.slice?ttl=1m":                http.StatusConflict,
+	} {
+		assert.Equal(t, code, do(http.MethodPost, path).Code, path)
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/system.slice/run-r1.scope").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/system.slice/run-r1.scope").Code)
+	assert.Empty(t, m.watches)
+}
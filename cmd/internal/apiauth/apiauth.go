@@ -0,0 +1,195 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apiauth authenticates the clients of the APIs of cAdvisor by
+// bearer tokens, and authorizes them by role.
+package apiauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/cadvisor/cmd/internal/api"
+	"github.com/google/cadvisor/cmd/internal/audit"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// Role is the access granted to a client.
+type Role int
+
+const (
+	// RoleRead grants access to the metrics and to the endpoints which do
+	// not change the state of cAdvisor.
+	RoleRead Role = iota
+	// RoleControl additionally grants access to the endpoints changing the
+	// state of cAdvisor, such as the collectors of containers.
+	RoleControl
+)
+
+// ParseRole parses the name of a role, "read" or "control".
+func ParseRole(name string) (Role, error) {
+	switch name {
+	case "read":
+		return RoleRead, nil
+	case "control":
+		return RoleControl, nil
+	default:
+		return RoleRead, fmt.Errorf("unknown role %q, expected \"read\" or \"control\"", name)
+	}
+}
+
+func (r Role) String() string {
+	if r == RoleControl {
+		return "control"
+	}
+	return "read"
+}
+
+// Identity is an authenticated client.
+type Identity struct {
+	Name string
+	Role Role
+}
+
+// Authenticator identifies the client holding a bearer token.
+type Authenticator interface {
+	// Authenticate returns the identity of the client holding token, or nil
+	// if the token is not recognized.
+	Authenticate(token string) (*Identity, error)
+}
+
+// Authenticators tries each authenticator in order.
+type Authenticators []Authenticator
+
+func (a Authenticators) Authenticate(token string) (*Identity, error) {
+	var errs []string
+	for _, authenticator := range a {
+		identity, err := authenticator.Authenticate(token)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil, nil
+}
+
+// requiredRole returns the role needed for a request. Only the endpoints
+// changing the state of cAdvisor require the control role.
+func requiredRole(r *http.Request) Role {
+	if api.ChangesState(r) {
+		return RoleControl
+	}
+	return RoleRead
+}
+
+// authorize authenticates the token and checks that it grants role.
+func authorize(a Authenticator, token string, role Role) (*Identity, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: missing bearer token", api.ErrUnauthenticated)
+	}
+	identity, err := a.Authenticate(token)
+	if err != nil {
+		klog.V(2).Infof("Rejected bearer token: %v", err)
+		return nil, fmt.Errorf("%w: invalid bearer token", api.ErrUnauthenticated)
+	}
+	if identity == nil {
+		return nil, fmt.Errorf("%w: invalid bearer token", api.ErrUnauthenticated)
+	}
+	if identity.Role < role {
+		return nil, fmt.Errorf("%w: %q has the %s role, %s is required", api.ErrForbidden, identity.Name, identity.Role, role)
+	}
+	return identity, nil
+}
+
+func bearerToken(authorization string) string {
+	const prefix = "bearer "
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(authorization[len(prefix):])
+}
+
+// Handler serves the requests carrying a bearer token which grants the read
+// role, and the control role for the requests changing the state of cAdvisor.
+// Requests for the exempt paths are served without authentication.
+func Handler(a Authenticator, h http.Handler, exemptPaths ...string) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exempt[r.URL.Path] {
+			h.ServeHTTP(w, r)
+			return
+		}
+		identity, err := authorize(a, bearerToken(r.Header.Get("Authorization")), requiredRole(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cadvisor"`)
+			api.WriteError(w, err)
+			return
+		}
+		klog.V(4).Infof("Request %s %s authenticated as %q", r.Method, r.URL.Path, identity.Name)
+		audit.SetIdentity(r.Context(), identity.Name, identity.Role.String())
+		h.ServeHTTP(w, r)
+	})
+}
+
+// authorizeGRPC authenticates the client of a gRPC call. The gRPC API is
+// read-only, so every authenticated client is granted access.
+func authorizeGRPC(a Authenticator, ctx context.Context) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = bearerToken(values[0])
+		}
+	}
+	identity, err := authorize(a, token, RoleRead)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	audit.SetIdentity(ctx, identity.Name, identity.Role.String())
+	return nil
+}
+
+// GRPCServerOptions return the options of gRPC servers authenticating their
+// clients by the bearer token in the authorization metadata.
+func GRPCServerOptions(a Authenticator) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := authorizeGRPC(a, ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := authorizeGRPC(a, ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
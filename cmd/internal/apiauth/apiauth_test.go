@@ -0,0 +1,115 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiauth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newTokenFile returns the authenticator of a token file with the given
+// content.
+func newTokenFile(t *testing.T, content string) (Authenticator, error) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return NewTokenFile(path)
+}
+
+func TestNewTokenFile(t *testing.T) {
+	tokens, err := newTokenFile(t, "# Scrapers\nr34d,prometheus,read\n\nc0ntr0l, admin, control\n")
+	require.NoError(t, err)
+
+	identity, err := tokens.Authenticate("r34d")
+	assert.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "prometheus", Role: RoleRead}, identity)
+	identity, err = tokens.Authenticate("c0ntr0l")
+	assert.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "admin", Role: RoleControl}, identity)
+	identity, err = tokens.Authenticate("r34")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	for _, content := range []string{"", "token,name", "token,name,admin", ",name,read"} {
+		_, err := newTokenFile(t, content)
+		assert.Error(t, err, content)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tokens, err := newTokenFile(t, "r34d,prometheus,read\nc0ntr0l,admin,control\n")
+	require.NoError(t, err)
+	handler := Handler(tokens, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "/healthz")
+
+	for _, tc := range []struct {
+		method        string
+		path          string
+		authorization string
+		code          int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/metrics", "", http.StatusUnauthorized},
+		{http.MethodGet, "/metrics", "Basic cmVhZA==", http.StatusUnauthorized},
+		{http.MethodGet, "/metrics", "Bearer wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/metrics", "Bearer r34d", http.StatusOK},
+		{http.MethodGet, "/api/v2.1/collectors/", "bearer r34d", http.StatusOK},
+		{http.MethodPost, "/api/v2.1/collectors/?name=nginx", "Bearer r34d", http.StatusForbidden},
+		{http.MethodPost, "/api/v2.1/collectors/?name=nginx", "Bearer c0ntr0l", http.StatusOK},
+		{http.MethodDelete, "/api/v2.1/collectors/?name=nginx", "Bearer c0ntr0l", http.StatusOK},
+		{http.MethodPut, "/api/v2.1/logging", "Bearer r34d", http.StatusForbidden},
+		{http.MethodPost, "/api/v2.1/watch/a", "Bearer r34d", http.StatusForbidden},
+		// The v1 API is read with POST requests.
+		{http.MethodPost, "/api/v1.3/containers/docker", "Bearer r34d", http.StatusOK},
+		{http.MethodPost, "/api/v1.3/subcontainers/", "Bearer r34d", http.StatusOK},
+		{http.MethodPost, "/validate/", "Bearer r34d", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(tc.method, "http://localhost:8080"+tc.path, nil)
+		if tc.authorization != "" {
+			r.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, tc.code, w.Code, "%s %s %s", tc.method, tc.path, tc.authorization)
+		if tc.code == http.StatusUnauthorized {
+			assert.Equal(t, `Bearer realm="cadvisor"`, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAuthorizeGRPC(t *testing.T) {
+	tokens, err := newTokenFile(t, "r34d,prometheus,read\n")
+	require.NoError(t, err)
+
+	assert.NoError(t, authorizeGRPC(tokens, metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer r34d"))))
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer wrong")),
+	} {
+		assert.Equal(t, codes.Unauthenticated, status.Code(authorizeGRPC(tokens, ctx)))
+	}
+}
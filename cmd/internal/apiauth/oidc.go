@@ -0,0 +1,368 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Minimum interval between fetches of the keys of the issuer, which are
+// fetched again when a token is signed by an unknown key.
+var keysRefreshInterval = time.Minute
+
+// Tolerated clock skew with the issuer.
+const clockSkew = time.Minute
+
+// OIDCConfig configures the validation of OpenID Connect ID tokens.
+type OIDCConfig struct {
+	// URL of the issuer, which must serve its discovery document under
+	// /.well-known/openid-configuration.
+	IssuerURL string
+	// Client ID the tokens must be issued for.
+	ClientID string
+	// Claim holding the groups of the client.
+	GroupsClaim string
+	// Groups granted the control role, other clients are granted the read
+	// role.
+	ControlGroups []string
+}
+
+// oidc authenticates the ID tokens signed by an OpenID Connect issuer with
+// RS256 or ES256.
+type oidc struct {
+	config OIDCConfig
+	client *http.Client
+
+	lock        sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastFetched time.Time
+	// Closed when the keys being fetched are stored, nil when they are not
+	// being fetched.
+	fetching chan struct{}
+}
+
+// Minimum size of the RSA keys of the issuer, smaller keys are ignored.
+const minRSAKeyBits = 2048
+
+// NewOIDC returns an authenticator accepting the ID tokens issued by the
+// issuer of config. The keys of the issuer are fetched when the first token
+// is authenticated, so that cAdvisor starts while the issuer is unavailable.
+func NewOIDC(config OIDCConfig, client *http.Client) (Authenticator, error) {
+	if config.IssuerURL == "" || config.ClientID == "" {
+		return nil, fmt.Errorf("both an issuer URL and a client ID are required")
+	}
+	if !strings.HasPrefix(config.IssuerURL, "https://") {
+		return nil, fmt.Errorf("the issuer URL %q must use https", config.IssuerURL)
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	return &oidc{config: config, client: client}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+type jwtClaims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	Expiry    *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+func (o *oidc) Authenticate(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		// Not a JWT, possibly a token for another authenticator.
+		return nil, nil
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid JWT header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signature: %v", err)
+	}
+	key, err := o.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %v", err)
+	}
+	var rawClaims map[string]interface{}
+	if err := decodeSegment(parts[1], &rawClaims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %v", err)
+	}
+	if claims.Issuer != o.config.IssuerURL {
+		return nil, fmt.Errorf("token issued by %q instead of %q", claims.Issuer, o.config.IssuerURL)
+	}
+	if !containsString(claims.Audience, o.config.ClientID) {
+		return nil, fmt.Errorf("token not issued for client %q", o.config.ClientID)
+	}
+	now := time.Now()
+	if claims.Expiry == nil || now.After(time.Unix(*claims.Expiry, 0).Add(clockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+
+	identity := &Identity{Name: claims.Subject, Role: RoleRead}
+	groups, _ := rawClaims[o.config.GroupsClaim].([]interface{})
+	for _, group := range groups {
+		if name, ok := group.(string); ok && containsString(o.config.ControlGroups, name) {
+			identity.Role = RoleControl
+			break
+		}
+	}
+	return identity, nil
+}
+
+// key returns the key of the issuer with the given ID, fetching the keys of
+// the issuer if it is unknown. The keys are fetched without holding the lock,
+// concurrent callers wait for them.
+func (o *oidc) key(kid string) (crypto.PublicKey, error) {
+	o.lock.Lock()
+	if key, ok := o.keys[kid]; ok {
+		o.lock.Unlock()
+		return key, nil
+	}
+	if fetching := o.fetching; fetching != nil {
+		o.lock.Unlock()
+		<-fetching
+		return o.knownKey(kid)
+	}
+	if time.Since(o.lastFetched) < keysRefreshInterval {
+		o.lock.Unlock()
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	o.lastFetched = time.Now()
+	fetching := make(chan struct{})
+	o.fetching = fetching
+	o.lock.Unlock()
+
+	keys, err := o.fetchKeys()
+
+	o.lock.Lock()
+	if err == nil {
+		o.keys = keys
+	}
+	o.fetching = nil
+	close(fetching)
+	o.lock.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the keys of %q: %v", o.config.IssuerURL, err)
+	}
+	return o.knownKey(kid)
+}
+
+func (o *oidc) knownKey(kid string) (crypto.PublicKey, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if key, ok := o.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (o *oidc) getJSON(url string, v interface{}) error {
+	resp, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (o *oidc) fetchKeys() (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(strings.TrimSuffix(o.config.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != o.config.IssuerURL {
+		return nil, fmt.Errorf("discovery document of issuer %q instead of %q", discovery.Issuer, o.config.IssuerURL)
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if errors.Is(err, errWeakKey) {
+			klog.Warningf("Ignoring key %q of OIDC issuer %q: %v", jwk.Kid, o.config.IssuerURL, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", jwk.Kid, err)
+		}
+		if key != nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+var errWeakKey = errors.New("weak key")
+
+// publicKey returns the key, or nil if its type is not supported.
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case jwk.Kty == "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		if n.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%w: RSA key of %d bits, at least %d are required", errWeakKey, n.BitLen(), minRSAKeyBits)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case jwk.Kty == "EC" && jwk.Crv == "P-256":
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("RS256 token signed by a non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid token signature: %v", err)
+		}
+		return nil
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return errors.New("invalid ES256 token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
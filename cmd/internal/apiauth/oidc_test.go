@@ -0,0 +1,185 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(t, jwtHeader{Alg: "RS256", Kid: kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(t, jwtHeader{Alg: "ES256", Kid: kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	signature := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rBytes):32], rBytes)
+	copy(signature[64-len(sBytes):], sBytes)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDC(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var issuer string
+	keysFetched := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		keysFetched++
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {
+			{Kty: "RSA", Kid: "rsa", Use: "sig", N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec", Crv: "P-256", X: base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()), Y: base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes())},
+			{Kty: "RSA", Kid: "weak", Use: "sig", N: base64.RawURLEncoding.EncodeToString(weakKey.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(weakKey.E)).Bytes())},
+			{Kty: "oct", Kid: "symmetric"},
+		}})
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	authenticator, err := NewOIDC(OIDCConfig{IssuerURL: issuer, ClientID: "cadvisor", ControlGroups: []string{"admins"}}, server.Client())
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	identity, err := authenticator.Authenticate(signRS256(t, rsaKey, "rsa", map[string]interface{}{
+		"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp, "groups": []string{"devs", "admins"},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "alice", Role: RoleControl}, identity)
+
+	identity, err = authenticator.Authenticate(signES256(t, ecKey, "ec", map[string]interface{}{
+		"iss": issuer, "sub": "bob", "aud": []string{"other", "cadvisor"}, "exp": exp,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Name: "bob", Role: RoleRead}, identity)
+
+	// Tokens which are not JWTs are left to other authenticators.
+	identity, err = authenticator.Authenticate("s3cr3t")
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	for name, token := range map[string]string{
+		"wrong issuer":   signRS256(t, rsaKey, "rsa", map[string]interface{}{"iss": "https://example.com", "sub": "alice", "aud": "cadvisor", "exp": exp}),
+		"wrong audience": signRS256(t, rsaKey, "rsa", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "other", "exp": exp}),
+		"expired":        signRS256(t, rsaKey, "rsa", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"no expiry":      signRS256(t, rsaKey, "rsa", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor"}),
+		"not yet valid":  signRS256(t, rsaKey, "rsa", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp, "nbf": exp}),
+		"wrong key":      signRS256(t, otherKey, "rsa", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp}),
+		"wrong key type": signRS256(t, rsaKey, "ec", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp}),
+		"unknown key":    signRS256(t, rsaKey, "unknown", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp}),
+		"weak key":       signRS256(t, weakKey, "weak", map[string]interface{}{"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": exp}),
+	} {
+		identity, err := authenticator.Authenticate(token)
+		assert.Error(t, err, name)
+		assert.Nil(t, identity, name)
+	}
+	// The keys are not fetched again for each unknown key.
+	assert.Equal(t, 1, keysFetched)
+}
+
+func TestOIDCConcurrentFetch(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var issuer string
+	var keysFetched int32
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&keysFetched, 1)
+		<-release
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {
+			{Kty: "RSA", Kid: "rsa", N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		}})
+	})
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+	issuer = server.URL
+
+	authenticator, err := NewOIDC(OIDCConfig{IssuerURL: issuer, ClientID: "cadvisor"}, server.Client())
+	require.NoError(t, err)
+	token := signRS256(t, rsaKey, "rsa", map[string]interface{}{
+		"iss": issuer, "sub": "alice", "aud": "cadvisor", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	// Clients authenticated while the keys are fetched wait for them.
+	errs := make(chan error, 3)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := authenticator.Authenticate(token)
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&keysFetched))
+}
+
+func TestNewOIDCInvalidConfig(t *testing.T) {
+	for _, config := range []OIDCConfig{
+		{ClientID: "cadvisor"},
+		{IssuerURL: "https://accounts.example.com"},
+		{IssuerURL: "http://accounts.example.com", ClientID: "cadvisor"},
+	} {
+		_, err := NewOIDC(config, http.DefaultClient)
+		assert.Error(t, err, config.IssuerURL)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiauth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+type staticToken struct {
+	token    []byte
+	identity Identity
+}
+
+// staticTokens authenticates the tokens listed in a file.
+type staticTokens []staticToken
+
+// NewTokenFile returns an authenticator accepting the tokens of the given
+// file. Each line of the file holds a token, the name of its client and its
+// role, separated by commas, e.g. "s3cr3t,prometheus,read". Empty lines and
+// lines starting with # are ignored.
+func NewTokenFile(path string) (Authenticator, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens staticTokens
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"<token>,<name>,<role>\"", path, i+1)
+		}
+		role, err := ParseRole(strings.TrimSpace(fields[2]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		tokens = append(tokens, staticToken{
+			token:    []byte(strings.TrimSpace(fields[0])),
+			identity: Identity{Name: strings.TrimSpace(fields[1]), Role: role},
+		})
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no token found in %s", path)
+	}
+	return tokens, nil
+}
+
+func (t staticTokens) Authenticate(token string) (*Identity, error) {
+	var found *Identity
+	for i := range t {
+		// Compare all tokens in constant time so that their contents cannot
+		// be guessed from response times.
+		if subtle.ConstantTimeCompare(t[i].token, []byte(token)) == 1 && found == nil {
+			identity := t[i].identity
+			found = &identity
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,267 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit logs the requests served by the APIs of cAdvisor as JSON
+// lines.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/grpcapi"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// Entry is the audit record of a request.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// "http" or "grpc".
+	Protocol string `json:"protocol"`
+	// Address of the client, without port.
+	RemoteAddr string `json:"remote_addr"`
+	// Name and role of the authenticated client, empty if authentication is
+	// disabled or failed.
+	Identity string `json:"identity,omitempty"`
+	Role     string `json:"role,omitempty"`
+	// HTTP method, or "grpc" for gRPC calls.
+	Method string `json:"method"`
+	// Path of HTTP requests, or full method name of gRPC calls.
+	Endpoint string `json:"endpoint"`
+	Query    string `json:"query,omitempty"`
+	// Container queried, if any.
+	Container string `json:"container,omitempty"`
+	// HTTP status code, or gRPC status code name.
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	UserAgent string  `json:"user_agent,omitempty"`
+}
+
+// Logger writes audit entries to a sink.
+type Logger struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+// NewLogger returns a logger writing to the file at path, created if needed
+// and appended to, or to stdout if path is "-".
+func NewLogger(path string) (*Logger, error) {
+	if path == "-" {
+		return &Logger{out: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{out: f}, nil
+}
+
+func (l *Logger) log(entry *Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		klog.Errorf("Failed to marshal audit entry %+v: %v", entry, err)
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, err := l.out.Write(append(line, '\n')); err != nil {
+		klog.Errorf("Failed to write audit entry: %v", err)
+	}
+}
+
+type contextKey struct{}
+
+// SetIdentity records the authenticated client of the request of ctx, if
+// it is audited.
+func SetIdentity(ctx context.Context, name, role string) {
+	if entry, ok := ctx.Value(contextKey{}).(*Entry); ok {
+		entry.Identity = name
+		entry.Role = role
+	}
+}
+
+// containerOf returns the container queried by an HTTP request: the
+// container parameter of the metrics endpoints, or the path following the
+// request type of the REST API.
+func containerOf(r *http.Request) string {
+	if container := r.URL.Query().Get("container"); container != "" {
+		return container
+	}
+	i := strings.Index(r.URL.Path, "/api/")
+	if i < 0 {
+		return ""
+	}
+	// /api/<version>/<request type>/<container>
+	elements := strings.SplitN(strings.Trim(r.URL.Path[i+len("/api/"):], "/"), "/", 3)
+	if len(elements) < 3 || elements[2] == "" {
+		return ""
+	}
+	return path.Join("/", elements[2])
+}
+
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// statusRecorder records the status code of a response. It implements the
+// optional interfaces of response writers used by the streaming endpoints.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) CloseNotify() <-chan bool {
+	if notifier, ok := r.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// Handler logs the requests served by h once they complete.
+func (l *Logger) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &Entry{
+			Time:       start,
+			Protocol:   "http",
+			RemoteAddr: remoteHost(r.RemoteAddr),
+			Method:     r.Method,
+			Endpoint:   r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Container:  containerOf(r),
+			UserAgent:  r.UserAgent(),
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+			entry.Status = strconv.Itoa(recorder.status)
+			entry.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+			l.log(entry)
+		}()
+		h.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), contextKey{}, entry)))
+	})
+}
+
+func (l *Logger) grpcEntry(ctx context.Context, method string) *Entry {
+	entry := &Entry{
+		Time:     time.Now(),
+		Protocol: "grpc",
+		Method:   "grpc",
+		Endpoint: method,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		entry.RemoteAddr = remoteHost(p.Addr.String())
+	}
+	return entry
+}
+
+func (l *Logger) logGRPC(entry *Entry, err error) {
+	entry.Status = status.Code(err).String()
+	entry.LatencyMs = float64(time.Since(entry.Time)) / float64(time.Millisecond)
+	l.log(entry)
+}
+
+// grpcContainer returns the container queried by a gRPC request, if any.
+func grpcContainer(req interface{}) string {
+	switch r := req.(type) {
+	case *grpcapi.ContainerInfoRequest:
+		return path.Join("/", r.Name)
+	case *grpcapi.WatchStatsRequest:
+		return path.Join("/", r.Name)
+	default:
+		return ""
+	}
+}
+
+// auditedStream passes the context holding the audit entry to the handlers
+// of streaming calls.
+type auditedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *auditedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *auditedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if entry, ok := s.ctx.Value(contextKey{}).(*Entry); ok && err == nil && entry.Container == "" {
+		entry.Container = grpcContainer(m)
+	}
+	return err
+}
+
+func (l *Logger) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	entry := l.grpcEntry(ctx, info.FullMethod)
+	entry.Container = grpcContainer(req)
+	resp, err := handler(context.WithValue(ctx, contextKey{}, entry), req)
+	l.logGRPC(entry, err)
+	return resp, err
+}
+
+func (l *Logger) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	entry := l.grpcEntry(ss.Context(), info.FullMethod)
+	err := handler(srv, &auditedStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), contextKey{}, entry)})
+	l.logGRPC(entry, err)
+	return err
+}
+
+// GRPCServerOptions return the options of gRPC servers logging their calls
+// once they complete. They must precede the options of other interceptors.
+func (l *Logger) GRPCServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(l.unaryInterceptor),
+		grpc.ChainStreamInterceptor(l.streamInterceptor),
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/grpcapi"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func decodeEntries(t *testing.T, out *bytes.Buffer) []Entry {
+	var entries []Entry
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var entry Entry
+		require.NoError(t, decoder.Decode(&entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestHandler(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := &Logger{out: out}
+	handler := logger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2.1/collectors/docker/abc" {
+			SetIdentity(r.Context(), "admin", "control")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, target := range []string{
+		"/api/v2.1/collectors/docker/abc",
+		"/metrics?container=/system.slice",
+		"/healthz",
+	} {
+		method := http.MethodGet
+		if target == "/api/v2.1/collectors/docker/abc" {
+			method = http.MethodDelete
+		}
+		r := httptest.NewRequest(method, target, nil)
+		r.RemoteAddr = "10.0.0.1:54321"
+		r.Header.Set("User-Agent", "test")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	entries := decodeEntries(t, out)
+	require.Len(t, entries, 3)
+	for i := range entries {
+		assert.False(t, entries[i].Time.IsZero())
+		assert.True(t, entries[i].LatencyMs >= 0)
+		entries[i].Time, entries[i].LatencyMs = time.Time{}, 0
+	}
+	assert.Equal(t, []Entry{
+		{Protocol: "http", RemoteAddr: "10.0.0.1", Identity: "admin", Role: "control", Method: "DELETE", Endpoint: "/api/v2.1/collectors/docker/abc", Container: "/docker/abc", Status: "204", UserAgent: "test"},
+		{Protocol: "http", RemoteAddr: "10.0.0.1", Method: "GET", Endpoint: "/metrics", Query: "container=/system.slice", Container: "/system.slice", Status: "200", UserAgent: "test"},
+		{Protocol: "http", RemoteAddr: "10.0.0.1", Method: "GET", Endpoint: "/healthz", Status: "200", UserAgent: "test"},
+	}, entries)
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := &Logger{out: out}
+	info := &grpc.UnaryServerInfo{FullMethod: "/cadvisor.ContainerService/GetContainerInfo"}
+	_, err := logger.unaryInterceptor(context.Background(), &grpcapi.ContainerInfoRequest{Name: "docker/abc"}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		SetIdentity(ctx, "prometheus", "read")
+		return nil, status.Error(codes.NotFound, "unknown container")
+	})
+	assert.Error(t, err)
+
+	entries := decodeEntries(t, out)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "grpc", entries[0].Protocol)
+	assert.Equal(t, "/cadvisor.ContainerService/GetContainerInfo", entries[0].Endpoint)
+	assert.Equal(t, "/docker/abc", entries[0].Container)
+	assert.Equal(t, "prometheus", entries[0].Identity)
+	assert.Equal(t, "read", entries[0].Role)
+	assert.Equal(t, "NotFound", entries[0].Status)
+}
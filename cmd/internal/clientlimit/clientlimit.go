@@ -0,0 +1,198 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clientlimit restricts the clients of the HTTP server of cAdvisor
+// by address and by request rate.
+package clientlimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/cmd/internal/api"
+)
+
+// clientIP returns the address of the client of r. Forwarding headers are
+// ignored since they are set by the clients themselves.
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func exemptSet(paths []string) map[string]bool {
+	exempt := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exempt[path] = true
+	}
+	return exempt
+}
+
+// Allowlist is a list of networks allowed to reach the server.
+type Allowlist []*net.IPNet
+
+// ParseAllowlist parses a comma-separated list of CIDRs or IP addresses.
+func ParseAllowlist(list string) (Allowlist, error) {
+	var allowlist Allowlist
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			allowlist = append(allowlist, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		allowlist = append(allowlist, network)
+	}
+	return allowlist, nil
+}
+
+// Allows returns whether ip belongs to one of the networks of the list.
+func (a Allowlist) Allows(ip net.IP) bool {
+	for _, network := range a {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the requests of the clients in the list, and rejects the
+// others with a 403 error. Requests for the exempt paths are always served.
+func (a Allowlist) Handler(h http.Handler, exemptPaths ...string) http.Handler {
+	exempt := exemptSet(exemptPaths)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !exempt[r.URL.Path] {
+			if ip := clientIP(r); ip == nil || !a.Allows(ip) {
+				api.WriteError(w, fmt.Errorf("%w: address %s not allowed", api.ErrForbidden, r.RemoteAddr))
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// bucket holds the tokens of a client, one token being taken per request.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter limits the rate of requests of each client by a token bucket
+// refilled at a steady rate.
+type Limiter struct {
+	rate  float64
+	burst float64
+	// Returns the current time, overridden by tests.
+	now func() time.Time
+
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// NewLimiter returns a limiter allowing each client rate requests per second
+// on average, and bursts of up to burst requests.
+func NewLimiter(rate float64, burst int) (*Limiter, error) {
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid rate %v, must be positive", rate)
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("invalid burst %d, must be at least 1", burst)
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}, nil
+}
+
+// Allow takes a token from the bucket of client. If the bucket is empty, it
+// returns false and the time until a token is available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.prune(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune forgets the clients whose buckets are full again, so that the
+// memory used does not grow with the number of clients ever seen.
+func (l *Limiter) prune(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastPrune) < refill {
+		return
+	}
+	l.lastPrune = now
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Handler serves the requests of clients within their rate, and rejects the
+// others with a 429 error. Requests for the exempt paths are always served.
+func (l *Limiter) Handler(h http.Handler, exemptPaths ...string) http.Handler {
+	exempt := exemptSet(exemptPaths)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !exempt[r.URL.Path] {
+			client := r.RemoteAddr
+			if ip := clientIP(r); ip != nil {
+				client = ip.String()
+			}
+			if ok, wait := l.Allow(client); !ok {
+				seconds := int(math.Ceil(wait.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				api.WriteError(w, fmt.Errorf("%w: retry in %ds", api.ErrRateLimited, seconds))
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
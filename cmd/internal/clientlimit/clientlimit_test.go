@@ -0,0 +1,112 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clientlimit
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func serve(h http.Handler, remoteAddr, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAllowlist(t *testing.T) {
+	allowlist, err := ParseAllowlist("10.0.0.0/8, 192.168.1.1,fd00::/8")
+	require.NoError(t, err)
+	for ip, allowed := range map[string]bool{
+		"10.1.2.3":        true,
+		"192.168.1.1":     true,
+		"192.168.1.2":     false,
+		"fd00::1":         true,
+		"2001:db8::1":     false,
+		"::ffff:10.0.0.1": true,
+	} {
+		assert.Equal(t, allowed, allowlist.Allows(net.ParseIP(ip)), ip)
+	}
+
+	h := allowlist.Handler(okHandler, "/healthz")
+	assert.Equal(t, http.StatusOK, serve(h, "10.1.2.3:1234", "/metrics").Code)
+	assert.Equal(t, http.StatusForbidden, serve(h, "172.16.0.1:1234", "/metrics").Code)
+	assert.Equal(t, http.StatusOK, serve(h, "172.16.0.1:1234", "/healthz").Code)
+
+	for _, list := range []string{"10.0.0.0/33", "10.0.0.256", "example.com"} {
+		_, err := ParseAllowlist(list)
+		assert.Error(t, err, list)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	limiter, err := NewLimiter(2, 3)
+	require.NoError(t, err)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := limiter.Allow("10.0.0.1")
+		assert.True(t, ok, i)
+	}
+	ok, wait := limiter.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	// Other clients have their own bucket.
+	ok, _ = limiter.Allow("10.0.0.2")
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.Allow("10.0.0.1")
+	assert.True(t, ok)
+	ok, _ = limiter.Allow("10.0.0.1")
+	assert.False(t, ok)
+
+	// Idle clients are forgotten once their buckets are full.
+	now = now.Add(time.Minute)
+	limiter.Allow("10.0.0.3")
+	assert.Len(t, limiter.buckets, 1)
+
+	for _, tc := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {1, 0}} {
+		_, err := NewLimiter(tc.rate, tc.burst)
+		assert.Error(t, err)
+	}
+}
+
+func TestLimiterHandler(t *testing.T) {
+	limiter, err := NewLimiter(1, 1)
+	require.NoError(t, err)
+	h := limiter.Handler(okHandler, "/healthz")
+
+	assert.Equal(t, http.StatusOK, serve(h, "10.0.0.1:1234", "/api/v2.1/storage").Code)
+	// The port is ignored.
+	w := serve(h, "10.0.0.1:5678", "/api/v2.1/storage")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve(h, "10.0.0.1:1234", "/healthz").Code)
+	assert.Equal(t, http.StatusOK, serve(h, "10.0.0.2:1234", "/api/v2.1/storage").Code)
+}
@@ -0,0 +1,351 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config sets the flags of cAdvisor from a YAML file, which is
+// reloaded when it changes or on SIGHUP.
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+)
+
+// Flags applied when the file is reloaded. The other flags, e.g. the
+// metrics toggles, housekeeping intervals, storage drivers and filters, are
+// only read when cAdvisor starts and reloads changing them are rejected.
+var reloadable = map[string]bool{
+	"v":       true,
+	"vmodule": true,
+}
+
+// Flags whose values are not shown in the effective configuration.
+var secretFlag = regexp.MustCompile(`(password|secret|_token|api_key)$`)
+
+// Sources of the value of a flag.
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceFile    = "file"
+)
+
+// Setting is the effective value of a flag.
+type Setting struct {
+	Value   string `json:"value"`
+	Default string `json:"default"`
+	// One of the Source constants.
+	Source string `json:"source"`
+	// Value set by a rejected reload of the file, which is only applied
+	// after a restart.
+	PendingValue *string `json:"pending_value,omitempty"`
+}
+
+// Effective is the configuration cAdvisor runs with.
+type Effective struct {
+	// Path of the configuration file, if any.
+	File string `json:"file,omitempty"`
+	// Time the file was last loaded successfully.
+	LoadTime time.Time `json:"load_time,omitempty"`
+	// Error of the last reload of the file, if it failed.
+	LastError string `json:"last_error,omitempty"`
+	// Settings by flag name.
+	Settings map[string]Setting `json:"settings"`
+	// Flags with a pending value.
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// Config tracks the flags set by a configuration file.
+type Config struct {
+	flags *flag.FlagSet
+	path  string
+	// Flags set on the command line, which take precedence over the file.
+	cmdline map[string]bool
+
+	lock sync.Mutex
+	// Content of the file when it was last loaded.
+	content []byte
+	// Values of the flags set by the file, as written in the file.
+	fromFile map[string]string
+	// Values of the file not applied until restart, by flag name.
+	pending   map[string]string
+	loadTime  time.Time
+	lastError error
+}
+
+// Load sets the flags of flags which are not set on the command line from
+// the file at path. It must be called once the command line is parsed. The
+// file is a YAML map of flag names to values, lists being joined by commas,
+// e.g.:
+//
+//	housekeeping_interval: 10s
+//	disable_metrics: [tcp, udp]
+//
+// If path is empty, only the effective configuration is tracked.
+func Load(flags *flag.FlagSet, path string) (*Config, error) {
+	c := &Config{
+		flags:    flags,
+		path:     path,
+		cmdline:  make(map[string]bool),
+		fromFile: make(map[string]string),
+		pending:  make(map[string]string),
+	}
+	flags.Visit(func(f *flag.Flag) {
+		c.cmdline[f.Name] = true
+	})
+	if path == "" {
+		return c, nil
+	}
+	content, values, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range values {
+		if c.cmdline[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: invalid value %q for %q: %v", path, value, name, err)
+		}
+		c.fromFile[name] = value
+	}
+	c.content, c.loadTime = content, time.Now()
+	return c, nil
+}
+
+// read reads the file and returns its content and the flag values it sets.
+func (c *Config) read() ([]byte, map[string]string, error) {
+	content, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", c.path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		f := c.flags.Lookup(name)
+		if f == nil {
+			return nil, nil, fmt.Errorf("%s: unknown flag %q", c.path, name)
+		}
+		if name == "config" {
+			return nil, nil, fmt.Errorf("%s: the config flag can only be set on the command line", c.path)
+		}
+		s, err := flagValue(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid value for %q: %v", c.path, name, err)
+		}
+		values[name] = s
+	}
+	return content, values, nil
+}
+
+// flagValue formats a YAML value as the value of a flag.
+func flagValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := flagValue(item)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(s, ",") {
+				return "", fmt.Errorf("list item %q contains a comma", s)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("expected a scalar or a list, got %T", value)
+	}
+}
+
+// Reload reads the file again. The values of the reloadable flags are
+// applied. Files changing other flags are rejected, their values are recorded
+// as pending a restart.
+func (c *Config) Reload() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	content, values, err := c.read()
+	if err == nil {
+		err = c.apply(content, values)
+	}
+	c.lastError = err
+	return err
+}
+
+func (c *Config) apply(content []byte, values map[string]string) error {
+	if bytes.Equal(content, c.content) {
+		return nil
+	}
+	// The flags set by the file, and those it no longer sets which go back
+	// to their defaults.
+	names := make(map[string]bool, len(values)+len(c.fromFile))
+	for name := range values {
+		names[name] = true
+	}
+	for name := range c.fromFile {
+		names[name] = true
+	}
+
+	fromFile := make(map[string]string)
+	pending := make(map[string]string)
+	// New values of the flags to reload.
+	changed := make(map[string]string)
+	for name := range names {
+		if c.cmdline[name] {
+			continue
+		}
+		f := c.flags.Lookup(name)
+		value, inFile := values[name]
+		if !inFile {
+			value = f.DefValue
+		}
+		current, wasFromFile := c.fromFile[name]
+		if !wasFromFile {
+			current = f.Value.String()
+		}
+		switch {
+		case value == current:
+		case !reloadable[name]:
+			pending[name] = value
+		default:
+			changed[name] = value
+		}
+		if inFile {
+			fromFile[name] = value
+		}
+	}
+	if len(pending) > 0 {
+		c.pending = pending
+		names := make([]string, 0, len(pending))
+		for name := range pending {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%s: %s can only be changed by restarting cAdvisor", c.path, strings.Join(names, ", "))
+	}
+
+	// Values of the flags reloaded, restored if a value is invalid.
+	previous := make(map[string]string, len(changed))
+	for name, value := range changed {
+		previous[name] = c.flags.Lookup(name).Value.String()
+		if err := c.flags.Set(name, value); err != nil {
+			for name, value := range previous {
+				c.flags.Set(name, value)
+			}
+			return fmt.Errorf("%s: invalid value %q for %q: %v", c.path, value, name, err)
+		}
+	}
+	for name, value := range previous {
+		klog.Infof("Reloaded %s from %s: %q -> %q", name, c.path, value, c.flags.Lookup(name).Value.String())
+	}
+	c.content, c.fromFile, c.pending, c.loadTime = content, fromFile, pending, time.Now()
+	return nil
+}
+
+func (c *Config) isFromFile(name string) bool {
+	_, ok := c.fromFile[name]
+	return ok
+}
+
+// Effective returns the configuration cAdvisor runs with.
+func (c *Config) Effective() Effective {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := Effective{
+		File:     c.path,
+		LoadTime: c.loadTime,
+		Settings: make(map[string]Setting),
+	}
+	if c.lastError != nil {
+		e.LastError = c.lastError.Error()
+	}
+	c.flags.VisitAll(func(f *flag.Flag) {
+		s := Setting{Value: f.Value.String(), Default: f.DefValue, Source: SourceDefault}
+		switch {
+		case c.cmdline[f.Name]:
+			s.Source = SourceFlag
+		case c.isFromFile(f.Name):
+			s.Source = SourceFile
+		}
+		if value, ok := c.pending[f.Name]; ok {
+			s.PendingValue = &value
+			e.RestartRequired = append(e.RestartRequired, f.Name)
+		}
+		if secretFlag.MatchString(f.Name) {
+			if s.Value != "" {
+				s.Value = "<redacted>"
+			}
+			if s.PendingValue != nil {
+				redacted := "<redacted>"
+				s.PendingValue = &redacted
+			}
+		}
+		e.Settings[f.Name] = s
+	})
+	sort.Strings(e.RestartRequired)
+	return e
+}
+
+// Watch reloads the file on SIGHUP, and when the file changes on the
+// platforms supporting inotify.
+func (c *Config) Watch() {
+	if c.path == "" {
+		return
+	}
+	changed := make(chan struct{}, 1)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	// The directory is watched since the file may be replaced, e.g. by the
+	// symlink swap of Kubernetes config maps.
+	if err := watchDir(filepath.Dir(c.path), changed); err != nil {
+		klog.Warningf("Failed to watch %s, it is only reloaded on SIGHUP: %v", c.path, err)
+	}
+	go func() {
+		for range changed {
+			if err := c.Reload(); err != nil {
+				klog.Errorf("Failed to reload %s: %v", c.path, err)
+			}
+		}
+	}()
+}
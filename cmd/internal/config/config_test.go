@@ -0,0 +1,159 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFlags struct {
+	flags      *flag.FlagSet
+	v          *int
+	interval   *time.Duration
+	metrics    *string
+	port       *int
+	password   *string
+	dockerOnly *bool
+}
+
+func newTestFlags(t *testing.T, args ...string) *testFlags {
+	f := &testFlags{flags: flag.NewFlagSet("test", flag.ContinueOnError)}
+	f.v = f.flags.Int("v", 0, "")
+	f.interval = f.flags.Duration("housekeeping_interval", time.Second, "")
+	f.metrics = f.flags.String("disable_metrics", "tcp", "")
+	f.port = f.flags.Int("port", 8080, "")
+	f.password = f.flags.String("storage_driver_password", "", "")
+	f.dockerOnly = f.flags.Bool("docker_only", false, "")
+	f.flags.String("config", "", "")
+	require.NoError(t, f.flags.Parse(args))
+	return f
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path, `
+housekeeping_interval: 10s
+disable_metrics: [tcp, udp]
+port: 9090
+docker_only: true
+storage_driver_password: s3cr3t
+`)
+
+	f := newTestFlags(t, "--port=8081")
+	c, err := Load(f.flags, path)
+	require.NoError(t, err)
+	assert.Equal(t, 10*time.Second, *f.interval)
+	assert.Equal(t, "tcp,udp", *f.metrics)
+	assert.True(t, *f.dockerOnly)
+	// The command line takes precedence.
+	assert.Equal(t, 8081, *f.port)
+
+	e := c.Effective()
+	assert.Equal(t, path, e.File)
+	assert.Equal(t, Setting{Value: "10s", Default: "1s", Source: SourceFile}, e.Settings["housekeeping_interval"])
+	assert.Equal(t, Setting{Value: "8081", Default: "8080", Source: SourceFlag}, e.Settings["port"])
+	assert.Equal(t, Setting{Value: "0", Default: "0", Source: SourceDefault}, e.Settings["v"])
+	assert.Equal(t, Setting{Value: "<redacted>", Default: "", Source: SourceFile}, e.Settings["storage_driver_password"])
+	assert.Empty(t, e.RestartRequired)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+
+	for _, content := range []string{
+		"unknown_flag: 1",
+		"config: other.yaml",
+		"port: eighty",
+		"housekeeping_interval: {seconds: 10}",
+		"disable_metrics: ['tcp,udp']",
+		"- port",
+	} {
+		writeConfig(t, path, content)
+		_, err := Load(newTestFlags(t).flags, path)
+		assert.Error(t, err, content)
+	}
+
+	_, err = Load(newTestFlags(t).flags, filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path, "v: 2\nhousekeeping_interval: 10s\n")
+
+	f := newTestFlags(t)
+	c, err := Load(f.flags, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *f.v)
+
+	// Files changing flags only read at start are rejected, the changes
+	// wait for a restart.
+	writeConfig(t, path, "v: 4\nhousekeeping_interval: 1m\nport: 9090\n")
+	err = c.Reload()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "housekeeping_interval, port can only be changed by restarting cAdvisor")
+	assert.Equal(t, 2, *f.v)
+	assert.Equal(t, 10*time.Second, *f.interval)
+	assert.Equal(t, 8080, *f.port)
+	e := c.Effective()
+	assert.Equal(t, []string{"housekeeping_interval", "port"}, e.RestartRequired)
+	assert.Equal(t, err.Error(), e.LastError)
+	pending := "1m"
+	assert.Equal(t, Setting{Value: "10s", Default: "1s", Source: SourceFile, PendingValue: &pending}, e.Settings["housekeeping_interval"])
+
+	// Reloadable flags are applied.
+	writeConfig(t, path, "v: 4\nhousekeeping_interval: 10s\n")
+	require.NoError(t, c.Reload())
+	assert.Equal(t, 4, *f.v)
+	e = c.Effective()
+	assert.Empty(t, e.RestartRequired)
+	assert.Equal(t, Setting{Value: "4", Default: "0", Source: SourceFile}, e.Settings["v"])
+
+	// Invalid files are not applied.
+	writeConfig(t, path, "v: four\n")
+	assert.Error(t, c.Reload())
+	assert.Equal(t, 4, *f.v)
+	assert.NotEmpty(t, c.Effective().LastError)
+
+	// Flags removed from the file go back to their defaults.
+	writeConfig(t, path, "housekeeping_interval: 10s\n")
+	require.NoError(t, c.Reload())
+	assert.Equal(t, 0, *f.v)
+	e = c.Effective()
+	assert.Empty(t, e.RestartRequired)
+	assert.Empty(t, e.LastError)
+	assert.Equal(t, Setting{Value: "0", Default: "0", Source: SourceDefault}, e.Settings["v"])
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package config
+
+import (
+	"k8s.io/klog/v2"
+	inotify "k8s.io/utils/inotify"
+)
+
+// watchDir notifies changed when the entries of dir change. Accesses are not
+// watched, since reading the file would trigger a reload.
+func watchDir(dir string, changed chan<- struct{}) error {
+	w, err := inotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.AddWatch(dir, inotify.InCreate|inotify.InDelete|inotify.InModify|inotify.InMove|inotify.InAttrib); err != nil {
+		w.Close()
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case <-w.Event:
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			case err := <-w.Error:
+				klog.Errorf("Error watching %s: %v", dir, err)
+			}
+		}
+	}()
+	return nil
+}
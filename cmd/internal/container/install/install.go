@@ -18,7 +18,13 @@ package install
 import (
 	_ "github.com/google/cadvisor/cmd/internal/container/mesos/install"
 	_ "github.com/google/cadvisor/container/containerd/install"
+	_ "github.com/google/cadvisor/container/cri/install"
 	_ "github.com/google/cadvisor/container/crio/install"
 	_ "github.com/google/cadvisor/container/docker/install"
+	_ "github.com/google/cadvisor/container/firecracker/install"
+	_ "github.com/google/cadvisor/container/gvisor/install"
+	_ "github.com/google/cadvisor/container/kata/install"
+	_ "github.com/google/cadvisor/container/lxd/install"
+	_ "github.com/google/cadvisor/container/podman/install"
 	_ "github.com/google/cadvisor/container/systemd/install"
 )
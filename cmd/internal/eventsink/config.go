@@ -0,0 +1,185 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"text/template"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 3
+	defaultQueueSize  = 100
+)
+
+var eventTypes = map[info.EventType]bool{
+	info.EventOom:                true,
+	info.EventOomKill:            true,
+	info.EventContainerCreation:  true,
+	info.EventContainerDeletion:  true,
+	info.EventMachineInfoChanged: true,
+	info.EventMdArrayChanged:     true,
+	info.EventContainerRestored:  true,
+}
+
+// Config lists the webhooks events are delivered to.
+type Config struct {
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+}
+
+// WebhookConfig configures a webhook receiving events as HTTP POST requests.
+type WebhookConfig struct {
+	// URL the events are POSTed to.
+	URL string `yaml:"url"`
+	// Types of the events delivered, all types if empty.
+	EventTypes []info.EventType `yaml:"event_types"`
+	// Regular expression matched against the whole container name. Events
+	// of all containers are delivered if empty.
+	ContainerName string `yaml:"container_name"`
+	// Go template rendering the request body from the event. The event is
+	// POSTed as JSON if empty.
+	Template string `yaml:"template"`
+	// Content type of the request body, application/json by default.
+	ContentType string `yaml:"content_type"`
+	// Extra headers of the request, e.g. Authorization.
+	Headers map[string]string `yaml:"headers"`
+	// Key used to sign the request body with HMAC-SHA256, the signature
+	// is sent in the X-Cadvisor-Signature header.
+	Secret string `yaml:"secret"`
+	// Timeout of a single request.
+	Timeout time.Duration `yaml:"timeout"`
+	// Number of times a failed request is retried.
+	MaxRetries *int `yaml:"max_retries"`
+	// Number of events waiting for delivery above which new events are
+	// dropped.
+	QueueSize int `yaml:"queue_size"`
+
+	eventTypes    map[info.EventType]bool
+	containerName *regexp.Regexp
+	template      *template.Template
+}
+
+// ReadConfig reads and validates the event sink config stored in file.
+func ReadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read event sink config %q: %v", file, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event sink config %q: %v", file, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates a YAML event sink config, filling in the
+// defaults.
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	for i := range config.Webhooks {
+		if err := config.Webhooks[i].init(); err != nil {
+			return nil, fmt.Errorf("webhooks[%d]: %v", i, err)
+		}
+	}
+	return config, nil
+}
+
+func (c *WebhookConfig) init() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url %q must use http or https", c.URL)
+	}
+
+	c.eventTypes = make(map[info.EventType]bool, len(eventTypes))
+	for _, eventType := range c.EventTypes {
+		if !eventTypes[eventType] {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+		c.eventTypes[eventType] = true
+	}
+	if len(c.eventTypes) == 0 {
+		for eventType := range eventTypes {
+			c.eventTypes[eventType] = true
+		}
+	}
+
+	if c.ContainerName != "" {
+		c.containerName, err = regexp.Compile("^(?:" + c.ContainerName + ")$")
+		if err != nil {
+			return fmt.Errorf("container_name: %v", err)
+		}
+	}
+	if c.Template != "" {
+		c.template, err = template.New(c.URL).Funcs(templateFuncs).Parse(c.Template)
+		if err != nil {
+			return fmt.Errorf("template: %v", err)
+		}
+	}
+
+	if c.ContentType == "" {
+		c.ContentType = "application/json"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MaxRetries == nil {
+		maxRetries := defaultMaxRetries
+		c.MaxRetries = &maxRetries
+	}
+	if *c.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = defaultQueueSize
+	}
+	return nil
+}
+
+// matches returns whether event passes the filters of the webhook.
+func (c *WebhookConfig) matches(event *info.Event) bool {
+	if !c.eventTypes[event.EventType] {
+		return false
+	}
+	return c.containerName == nil || c.containerName.MatchString(event.ContainerName)
+}
+
+// render returns the request body of event.
+func (c *WebhookConfig) render(p *payload) ([]byte, error) {
+	if c.template == nil {
+		return json.Marshal(p)
+	}
+	var buf bytes.Buffer
+	if err := c.template.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
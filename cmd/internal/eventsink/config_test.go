@@ -0,0 +1,67 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventsink
+
+import (
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig([]byte(`
+webhooks:
+- url: https://alerts.example.com/hook
+  event_types: [oom, oomKill]
+  container_name: /kubepods/.*
+  timeout: 5s
+  max_retries: 0
+- url: http://localhost:9000/
+`))
+	require.NoError(t, err)
+	require.Len(t, config.Webhooks, 2)
+
+	hook := config.Webhooks[0]
+	assert.Equal(t, 5*time.Second, hook.Timeout)
+	assert.Equal(t, 0, *hook.MaxRetries)
+	assert.Equal(t, "application/json", hook.ContentType)
+	assert.True(t, hook.matches(&info.Event{EventType: info.EventOomKill, ContainerName: "/kubepods/pod1"}))
+	assert.False(t, hook.matches(&info.Event{EventType: info.EventOomKill, ContainerName: "/system.slice/kubepods/pod1"}))
+	assert.False(t, hook.matches(&info.Event{EventType: info.EventContainerCreation, ContainerName: "/kubepods/pod1"}))
+
+	hook = config.Webhooks[1]
+	assert.Equal(t, defaultTimeout, hook.Timeout)
+	assert.Equal(t, defaultMaxRetries, *hook.MaxRetries)
+	assert.Equal(t, defaultQueueSize, hook.QueueSize)
+	assert.True(t, hook.matches(&info.Event{EventType: info.EventContainerDeletion, ContainerName: "/"}))
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		"webhooks:\n- url: ftp://example.com/\n",
+		"webhooks:\n- url: http://example.com/\n  event_types: [restart]\n",
+		"webhooks:\n- url: http://example.com/\n  container_name: \"[\"\n",
+		"webhooks:\n- url: http://example.com/\n  template: \"{{.Missing\"\n",
+		"webhooks:\n- url: http://example.com/\n  max_retries: -1\n",
+		"webhooks:\n- url: http://example.com/\n  unknown: true\n",
+	} {
+		_, err := ParseConfig([]byte(config))
+		assert.Error(t, err, config)
+	}
+}
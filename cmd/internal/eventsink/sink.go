@@ -0,0 +1,246 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventsink delivers container events to webhooks.
+package eventsink
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	signatureHeader = "X-Cadvisor-Signature"
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// EventWatcher is implemented by the manager.
+type EventWatcher interface {
+	WatchForEvents(request *events.Request) (*events.EventChannel, error)
+	CloseEventChannel(watchID int)
+}
+
+// payload is the value the templates are executed with.
+type payload struct {
+	*info.Event
+	Hostname string `json:"hostname"`
+}
+
+// Sink delivers the events of an EventWatcher to the configured webhooks.
+type Sink struct {
+	watcher  EventWatcher
+	webhooks []*webhook
+	hostname string
+	watchID  int
+	wg       sync.WaitGroup
+}
+
+type webhook struct {
+	config *WebhookConfig
+	client *http.Client
+	queue  chan *info.Event
+	stop   chan struct{}
+	// Overridden in tests.
+	sleep func(time.Duration, <-chan struct{}) bool
+}
+
+// New returns a sink delivering the events to the webhooks of config.
+func New(config *Config) *Sink {
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.Warningf("Unable to get hostname for event sink: %v", err)
+	}
+	s := &Sink{hostname: hostname}
+	for i := range config.Webhooks {
+		c := &config.Webhooks[i]
+		s.webhooks = append(s.webhooks, &webhook{
+			config: c,
+			client: &http.Client{Timeout: c.Timeout},
+			queue:  make(chan *info.Event, c.QueueSize),
+			stop:   make(chan struct{}),
+			sleep:  sleep,
+		})
+	}
+	return s
+}
+
+// Start watches the events of watcher and starts delivering them.
+func (s *Sink) Start(watcher EventWatcher) error {
+	request := events.NewRequest()
+	request.ContainerName = "/"
+	request.IncludeSubcontainers = true
+	for _, w := range s.webhooks {
+		for eventType := range w.config.eventTypes {
+			request.EventType[eventType] = true
+		}
+	}
+	ch, err := watcher.WatchForEvents(request)
+	if err != nil {
+		return fmt.Errorf("unable to watch events: %v", err)
+	}
+	s.watcher = watcher
+	s.watchID = ch.GetWatchId()
+
+	for _, w := range s.webhooks {
+		s.wg.Add(1)
+		go func(w *webhook) {
+			defer s.wg.Done()
+			w.run(s.hostname)
+		}(w)
+	}
+	go s.dispatch(ch.GetChannel())
+	return nil
+}
+
+// Stop stops watching events and waits for the webhooks to finish the
+// delivery in progress. Queued events are dropped.
+func (s *Sink) Stop() {
+	if s.watcher != nil {
+		s.watcher.CloseEventChannel(s.watchID)
+	}
+	for _, w := range s.webhooks {
+		close(w.stop)
+	}
+	s.wg.Wait()
+}
+
+// dispatch queues the events of ch for the matching webhooks. The event
+// manager blocks until the events are received, so they are never waited on
+// the webhooks.
+func (s *Sink) dispatch(ch <-chan *info.Event) {
+	for event := range ch {
+		for _, w := range s.webhooks {
+			if !w.config.matches(event) {
+				continue
+			}
+			select {
+			case w.queue <- event:
+			default:
+				klog.Warningf("Event sink queue of %s is full, dropping %s event of %q", w.config.URL, event.EventType, event.ContainerName)
+			}
+		}
+	}
+}
+
+func (w *webhook) run(hostname string) {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case event := <-w.queue:
+			body, err := w.config.render(&payload{Event: event, Hostname: hostname})
+			if err != nil {
+				klog.Errorf("Unable to render %s event of %q for %s: %v", event.EventType, event.ContainerName, w.config.URL, err)
+				continue
+			}
+			if err := w.deliver(body); err != nil {
+				klog.Errorf("Unable to deliver %s event of %q to %s: %v", event.EventType, event.ContainerName, w.config.URL, err)
+			}
+		}
+	}
+}
+
+// deliver POSTs body, retrying on connection errors, 429 and 5xx
+// responses with an exponential backoff.
+func (w *webhook) deliver(body []byte) error {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= *w.config.MaxRetries {
+			return err
+		}
+		klog.V(4).Infof("Retrying event delivery to %s in %v: %v", w.config.URL, backoff, err)
+		if !w.sleep(backoff, w.stop) {
+			return err
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// post sends a single request and returns whether a failure may be retried.
+func (w *webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", w.config.ContentType)
+	for name, value := range w.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if w.config.Secret != "" {
+		req.Header.Set(signatureHeader, "sha256="+sign(body, w.config.Secret))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body.
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sleep waits for d and returns false if stop is closed first.
+func sleep(d time.Duration, stop <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventsink
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventManager adapts an events.EventManager to EventWatcher.
+type eventManager struct {
+	events.EventManager
+}
+
+func (m eventManager) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return m.WatchEvents(request)
+}
+
+func (m eventManager) CloseEventChannel(watchID int) {
+	m.StopWatch(watchID)
+}
+
+type receiver struct {
+	lock     sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+	received chan struct{}
+}
+
+func newReceiver(statuses ...int) (*receiver, *httptest.Server) {
+	r := &receiver{statuses: statuses, received: make(chan struct{}, 10)}
+	return r, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		r.lock.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, string(body))
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.lock.Unlock()
+		w.WriteHeader(status)
+		r.received <- struct{}{}
+	}))
+}
+
+func (r *receiver) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for request %d", i+1)
+		}
+	}
+}
+
+func startSink(t *testing.T, config string) (*Sink, events.EventManager) {
+	c, err := ParseConfig([]byte(config))
+	require.NoError(t, err)
+	s := New(c)
+	s.hostname = "node1"
+	for _, w := range s.webhooks {
+		w.sleep = func(time.Duration, <-chan struct{}) bool { return true }
+	}
+	m := events.NewEventManager(events.DefaultStoragePolicy())
+	require.NoError(t, s.Start(eventManager{m}))
+	return s, m
+}
+
+func TestSinkDeliversJSON(t *testing.T) {
+	r, server := newReceiver()
+	defer server.Close()
+	s, m := startSink(t, "webhooks:\n- url: "+server.URL+"\n  secret: s3cr3t\n  headers:\n    Authorization: Bearer token\n")
+	defer s.Stop()
+
+	timestamp := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, m.AddEvent(&info.Event{
+		ContainerName: "/docker/abc",
+		Timestamp:     timestamp,
+		EventType:     info.EventOomKill,
+		EventData:     info.EventData{OomKill: &info.OomKillEventData{Pid: 42, ProcessName: "java"}},
+	}))
+	r.wait(t, 1)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	req, body := r.requests[0], r.bodies[0]
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	assert.Equal(t, "sha256="+sign([]byte(body), "s3cr3t"), req.Header.Get(signatureHeader))
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	assert.Equal(t, "/docker/abc", got["container_name"])
+	assert.Equal(t, "oomKill", got["event_type"])
+	assert.Equal(t, "node1", got["hostname"])
+	assert.Equal(t, "java", got["event_data"].(map[string]interface{})["oom"].(map[string]interface{})["process_name"])
+}
+
+func TestSinkFiltersAndTemplates(t *testing.T) {
+	r, server := newReceiver()
+	defer server.Close()
+	s, m := startSink(t, `
+webhooks:
+- url: `+server.URL+`
+  event_types: [containerDeletion]
+  container_name: /docker/.*
+  content_type: text/plain
+  template: '{{.Hostname}}: {{.ContainerName}} {{.EventType}} {{json .EventType}}'
+`)
+	defer s.Stop()
+
+	require.NoError(t, m.AddEvent(&info.Event{ContainerName: "/docker/abc", Timestamp: time.Now(), EventType: info.EventContainerCreation}))
+	require.NoError(t, m.AddEvent(&info.Event{ContainerName: "/system.slice", Timestamp: time.Now(), EventType: info.EventContainerDeletion}))
+	require.NoError(t, m.AddEvent(&info.Event{ContainerName: "/docker/abc", Timestamp: time.Now(), EventType: info.EventContainerDeletion}))
+	r.wait(t, 1)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	require.Len(t, r.bodies, 1)
+	assert.Equal(t, `node1: /docker/abc containerDeletion "containerDeletion"`, r.bodies[0])
+	assert.Equal(t, "text/plain", r.requests[0].Header.Get("Content-Type"))
+}
+
+func TestSinkRetries(t *testing.T) {
+	r, server := newReceiver(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+	s, m := startSink(t, "webhooks:\n- url: "+server.URL+"\n")
+	defer s.Stop()
+
+	require.NoError(t, m.AddEvent(&info.Event{ContainerName: "/", Timestamp: time.Now(), EventType: info.EventOom}))
+	r.wait(t, 3)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	assert.Len(t, r.bodies, 3)
+	assert.Equal(t, r.bodies[0], r.bodies[2])
+}
+
+func TestSinkDoesNotRetryClientErrors(t *testing.T) {
+	r, server := newReceiver(http.StatusBadRequest)
+	defer server.Close()
+	hook := &webhook{
+		config: &WebhookConfig{URL: server.URL},
+		client: server.Client(),
+		stop:   make(chan struct{}),
+		sleep: func(time.Duration, <-chan struct{}) bool {
+			t.Fatal("client error was retried")
+			return false
+		},
+	}
+	require.NoError(t, hook.config.init())
+
+	assert.Error(t, hook.deliver([]byte("{}")))
+	r.wait(t, 1)
+}
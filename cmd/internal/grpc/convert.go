@@ -0,0 +1,189 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/cadvisor/grpcapi"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+func sortedNames(infos map[string]v2.ContainerInfo) []string {
+	names := make([]string, 0, len(infos))
+	for name := range infos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func timestampToProto(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+func machineInfoToProto(machineInfo *info.MachineInfo) *grpcapi.MachineInfo {
+	out := &grpcapi.MachineInfo{
+		Timestamp:        timestampToProto(machineInfo.Timestamp),
+		NumCores:         int32(machineInfo.NumCores),
+		NumPhysicalCores: int32(machineInfo.NumPhysicalCores),
+		NumSockets:       int32(machineInfo.NumSockets),
+		CpuFrequencyKhz:  machineInfo.CpuFrequency,
+		MemoryCapacity:   machineInfo.MemoryCapacity,
+		MachineID:        machineInfo.MachineID,
+		SystemUUID:       machineInfo.SystemUUID,
+		BootID:           machineInfo.BootID,
+	}
+	for _, fs := range machineInfo.Filesystems {
+		out.Filesystems = append(out.Filesystems, &grpcapi.Filesystem{
+			Device:   fs.Device,
+			Type:     fs.Type,
+			Capacity: fs.Capacity,
+			Inodes:   fs.Inodes,
+		})
+	}
+	for _, dev := range machineInfo.NetworkDevices {
+		out.NetworkDevices = append(out.NetworkDevices, &grpcapi.NetworkDevice{
+			Name:       dev.Name,
+			MacAddress: dev.MacAddress,
+			Speed:      dev.Speed,
+			Mtu:        dev.Mtu,
+		})
+	}
+	return out
+}
+
+func containerInfoToProto(name string, cinfo v2.ContainerInfo) *grpcapi.ContainerInfo {
+	out := &grpcapi.ContainerInfo{
+		Name: name,
+		Spec: containerSpecToProto(cinfo.Spec),
+	}
+	for _, stats := range cinfo.Stats {
+		out.Stats = append(out.Stats, containerStatsToProto(stats))
+	}
+	return out
+}
+
+func containerSpecToProto(spec v2.ContainerSpec) *grpcapi.ContainerSpec {
+	out := &grpcapi.ContainerSpec{
+		CreationTime:  timestampToProto(spec.CreationTime),
+		Namespace:     spec.Namespace,
+		Aliases:       spec.Aliases,
+		Labels:        spec.Labels,
+		Image:         spec.Image,
+		HasCpu:        spec.HasCpu,
+		HasMemory:     spec.HasMemory,
+		HasNetwork:    spec.HasNetwork,
+		HasFilesystem: spec.HasFilesystem,
+		HasDiskIo:     spec.HasDiskIo,
+	}
+	if spec.HasCpu {
+		out.Cpu = &grpcapi.CpuSpec{
+			Limit:    spec.Cpu.Limit,
+			MaxLimit: spec.Cpu.MaxLimit,
+			Mask:     spec.Cpu.Mask,
+			Quota:    spec.Cpu.Quota,
+			Period:   spec.Cpu.Period,
+		}
+	}
+	if spec.HasMemory {
+		out.Memory = &grpcapi.MemorySpec{
+			Limit:       spec.Memory.Limit,
+			Reservation: spec.Memory.Reservation,
+			SwapLimit:   spec.Memory.SwapLimit,
+		}
+	}
+	return out
+}
+
+func containerStatsToProto(stats *v2.ContainerStats) *grpcapi.ContainerStats {
+	out := &grpcapi.ContainerStats{
+		Timestamp: timestampToProto(stats.Timestamp),
+	}
+	if stats.Cpu != nil {
+		out.Cpu = &grpcapi.CpuStats{
+			Total:            stats.Cpu.Usage.Total,
+			PerCpu:           stats.Cpu.Usage.PerCpu,
+			User:             stats.Cpu.Usage.User,
+			System:           stats.Cpu.Usage.System,
+			ThrottledPeriods: stats.Cpu.CFS.ThrottledPeriods,
+			ThrottledTime:    stats.Cpu.CFS.ThrottledTime,
+			LoadAverage:      stats.Cpu.LoadAverage,
+		}
+	}
+	if stats.Memory != nil {
+		out.Memory = &grpcapi.MemoryStats{
+			Usage:      stats.Memory.Usage,
+			MaxUsage:   stats.Memory.MaxUsage,
+			Cache:      stats.Memory.Cache,
+			Rss:        stats.Memory.RSS,
+			Swap:       stats.Memory.Swap,
+			MappedFile: stats.Memory.MappedFile,
+			WorkingSet: stats.Memory.WorkingSet,
+			Failcnt:    stats.Memory.Failcnt,
+		}
+	}
+	if stats.Network != nil {
+		for _, iface := range stats.Network.Interfaces {
+			out.Network = append(out.Network, &grpcapi.InterfaceStats{
+				Name:      iface.Name,
+				RxBytes:   iface.RxBytes,
+				RxPackets: iface.RxPackets,
+				RxErrors:  iface.RxErrors,
+				RxDropped: iface.RxDropped,
+				TxBytes:   iface.TxBytes,
+				TxPackets: iface.TxPackets,
+				TxErrors:  iface.TxErrors,
+				TxDropped: iface.TxDropped,
+			})
+		}
+	}
+	if stats.Filesystem != nil {
+		out.Filesystem = &grpcapi.FilesystemStats{
+			TotalUsageBytes: valueOrZero(stats.Filesystem.TotalUsageBytes),
+			BaseUsageBytes:  valueOrZero(stats.Filesystem.BaseUsageBytes),
+			InodeUsage:      valueOrZero(stats.Filesystem.InodeUsage),
+		}
+	}
+	if stats.Processes != nil {
+		out.Processes = &grpcapi.ProcessStats{
+			ProcessCount:   stats.Processes.ProcessCount,
+			FdCount:        stats.Processes.FdCount,
+			SocketCount:    stats.Processes.SocketCount,
+			ThreadsCurrent: stats.Processes.ThreadsCurrent,
+			ThreadsMax:     stats.Processes.ThreadsMax,
+		}
+	}
+	return out
+}
+
+func valueOrZero(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
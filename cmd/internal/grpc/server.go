@@ -0,0 +1,174 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpc serves the cadvisor.v1.Cadvisor gRPC API.
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/google/cadvisor/grpcapi"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// defaultCount is the number of stats returned by GetContainerInfo when the
+// request does not set it, as for the REST API.
+const defaultCount = 64
+
+type server struct {
+	manager manager.Manager
+	// Default interval between the lookups of new stats of WatchStats.
+	interval time.Duration
+}
+
+// NewServer returns a cadvisor.v1.Cadvisor server backed by m, looking up
+// new stats every interval unless requested otherwise.
+func NewServer(m manager.Manager, interval time.Duration) grpcapi.CadvisorServer {
+	return &server{manager: m, interval: interval}
+}
+
+// ListenAndServe serves the gRPC API on the TCP address addr, over TLS if
+// tlsConfig is not nil.
+func ListenAndServe(addr string, m manager.Manager, interval time.Duration, tlsConfig *tls.Config, opts ...grpc.ServerOption) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	s := grpc.NewServer(opts...)
+	grpcapi.RegisterCadvisorServer(s, NewServer(m, interval))
+	klog.V(1).Infof("Starting gRPC API on %s", addr)
+	return s.Serve(listener)
+}
+
+func (s *server) GetMachineInfo(ctx context.Context, req *grpcapi.MachineInfoRequest) (*grpcapi.MachineInfo, error) {
+	machineInfo, err := s.manager.GetMachineInfo()
+	if err != nil {
+		return nil, err
+	}
+	return machineInfoToProto(machineInfo), nil
+}
+
+func (s *server) GetContainerInfo(ctx context.Context, req *grpcapi.ContainerInfoRequest) (*grpcapi.ContainerInfoResponse, error) {
+	opts, err := requestOptions(req.IdType, req.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	opts.Count = int(req.Count)
+	if opts.Count == 0 {
+		opts.Count = defaultCount
+	}
+	if req.MaxAge != nil {
+		maxAge, err := ptypes.Duration(req.MaxAge)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid max_age: %v", err)
+		}
+		opts.MaxAge = &maxAge
+	}
+
+	infos, err := s.manager.GetContainerInfoV2(containerName(req.Name), opts)
+	if err != nil {
+		if len(infos) == 0 {
+			return nil, err
+		}
+		// Return the containers whose info could be gathered.
+		klog.V(4).Infof("Error getting container info over gRPC: %v", err)
+	}
+	resp := &grpcapi.ContainerInfoResponse{}
+	for _, name := range sortedNames(infos) {
+		resp.Containers = append(resp.Containers, containerInfoToProto(name, infos[name]))
+	}
+	return resp, nil
+}
+
+func (s *server) WatchStats(req *grpcapi.WatchStatsRequest, stream grpcapi.WatchStatsServer) error {
+	opts, err := requestOptions(req.IdType, req.Recursive)
+	if err != nil {
+		return err
+	}
+	opts.Count = 1
+	interval := s.interval
+	if req.Interval != nil {
+		interval, err = ptypes.Duration(req.Interval)
+		if err != nil || interval <= 0 {
+			return status.Errorf(codes.InvalidArgument, "invalid interval %v", req.Interval)
+		}
+	}
+
+	name := containerName(req.Name)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// Timestamp of the last stats sent for each container.
+	sent := map[string]time.Time{}
+	for {
+		infos, err := s.manager.GetContainerInfoV2(name, opts)
+		if err != nil && len(infos) == 0 {
+			return err
+		}
+		seen := make(map[string]time.Time, len(infos))
+		for _, name := range sortedNames(infos) {
+			stats := infos[name].Stats
+			if len(stats) == 0 {
+				continue
+			}
+			latest := stats[len(stats)-1]
+			seen[name] = latest.Timestamp
+			if !latest.Timestamp.After(sent[name]) {
+				continue
+			}
+			if err := stream.Send(&grpcapi.ContainerStatsUpdate{Name: name, Stats: containerStatsToProto(latest)}); err != nil {
+				return err
+			}
+		}
+		// Forget the containers which are gone.
+		sent = seen
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func requestOptions(idType string, recursive bool) (v2.RequestOptions, error) {
+	switch idType {
+	case "":
+		idType = v2.TypeName
+	case v2.TypeName, v2.TypeDocker:
+	default:
+		return v2.RequestOptions{}, status.Errorf(codes.InvalidArgument, "unknown id_type %q", idType)
+	}
+	return v2.RequestOptions{IdType: idType, Recursive: recursive}, nil
+}
+
+func containerName(name string) string {
+	if name == "" {
+		return "/"
+	}
+	return name
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/grpcapi"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/manager"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeManager struct {
+	manager.Manager
+
+	lock  sync.Mutex
+	opts  []v2.RequestOptions
+	infos map[string]v2.ContainerInfo
+}
+
+func (m *fakeManager) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{
+		NumCores:       4,
+		MemoryCapacity: 1024,
+		MachineID:      "machine",
+		Filesystems:    []info.FsInfo{{Device: "/dev/sda1", Type: "vfs", Capacity: 100}},
+	}, nil
+}
+
+func (m *fakeManager) GetContainerInfoV2(containerName string, options v2.RequestOptions) (map[string]v2.ContainerInfo, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.opts = append(m.opts, options)
+	return m.infos, nil
+}
+
+func (m *fakeManager) setStats(name string, stats ...*v2.ContainerStats) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.infos[name] = v2.ContainerInfo{
+		Spec:  v2.ContainerSpec{HasCpu: true, Cpu: v2.CpuSpec{Limit: 1024}, Labels: map[string]string{"app": "web"}},
+		Stats: stats,
+	}
+}
+
+func cpuStats(ts time.Time, total uint64) *v2.ContainerStats {
+	return &v2.ContainerStats{
+		Timestamp: ts,
+		Cpu:       &info.CpuStats{Usage: info.CpuUsage{Total: total, PerCpu: []uint64{total}}},
+	}
+}
+
+func startServer(t *testing.T, m manager.Manager) grpcapi.CadvisorClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	grpcapi.RegisterCadvisorServer(s, NewServer(m, time.Second))
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpcapi.NewCadvisorClient(conn)
+}
+
+func TestGetMachineInfo(t *testing.T) {
+	client := startServer(t, &fakeManager{})
+
+	machineInfo, err := client.GetMachineInfo(context.Background(), &grpcapi.MachineInfoRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), machineInfo.NumCores)
+	assert.Equal(t, uint64(1024), machineInfo.MemoryCapacity)
+	assert.Equal(t, "machine", machineInfo.MachineID)
+	require.Len(t, machineInfo.Filesystems, 1)
+	assert.Equal(t, "/dev/sda1", machineInfo.Filesystems[0].Device)
+}
+
+func TestGetContainerInfo(t *testing.T) {
+	m := &fakeManager{infos: map[string]v2.ContainerInfo{}}
+	now := time.Unix(1600000000, 0)
+	m.setStats("/b", cpuStats(now, 2))
+	m.setStats("/a", cpuStats(now.Add(-time.Second), 1), cpuStats(now, 3))
+	client := startServer(t, m)
+
+	resp, err := client.GetContainerInfo(context.Background(), &grpcapi.ContainerInfoRequest{
+		Name:      "/",
+		Recursive: true,
+		MaxAge:    ptypes.DurationProto(time.Second),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Containers, 2)
+	a := resp.Containers[0]
+	assert.Equal(t, "/a", a.Name)
+	assert.Equal(t, uint64(1024), a.Spec.Cpu.Limit)
+	assert.Equal(t, map[string]string{"app": "web"}, a.Spec.Labels)
+	require.Len(t, a.Stats, 2)
+	assert.Equal(t, uint64(3), a.Stats[1].Cpu.Total)
+	assert.Equal(t, []uint64{3}, a.Stats[1].Cpu.PerCpu)
+	ts, err := ptypes.Timestamp(a.Stats[1].Timestamp)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(ts))
+	assert.Equal(t, "/b", resp.Containers[1].Name)
+
+	require.Len(t, m.opts, 1)
+	assert.Equal(t, v2.TypeName, m.opts[0].IdType)
+	assert.Equal(t, defaultCount, m.opts[0].Count)
+	assert.True(t, m.opts[0].Recursive)
+	assert.Equal(t, time.Second, *m.opts[0].MaxAge)
+
+	_, err = client.GetContainerInfo(context.Background(), &grpcapi.ContainerInfoRequest{IdType: "pod"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchStats(t *testing.T) {
+	m := &fakeManager{infos: map[string]v2.ContainerInfo{}}
+	now := time.Unix(1600000000, 0)
+	m.setStats("/a", cpuStats(now, 1))
+	client := startServer(t, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchStats(ctx, &grpcapi.WatchStatsRequest{
+		Name:      "/",
+		Recursive: true,
+		Interval:  ptypes.DurationProto(10 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "/a", update.Name)
+	assert.Equal(t, uint64(1), update.Stats.Cpu.Total)
+
+	// Stats are only sent again once they are updated.
+	m.setStats("/a", cpuStats(now.Add(time.Second), 2))
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "/a", update.Name)
+	assert.Equal(t, uint64(2), update.Stats.Cpu.Total)
+
+	m.lock.Lock()
+	assert.Equal(t, 1, m.opts[0].Count)
+	m.lock.Unlock()
+}
@@ -17,6 +17,7 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/google/cadvisor/cmd/internal/api"
 	"github.com/google/cadvisor/cmd/internal/healthz"
@@ -26,11 +27,13 @@ import (
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/metrics"
+	"github.com/google/cadvisor/storage"
 	"github.com/google/cadvisor/validate"
 
 	auth "github.com/abbot/go-http-auth"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
@@ -45,7 +48,7 @@ func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAut
 	mux.HandleFunc(validate.ValidatePage, func(w http.ResponseWriter, r *http.Request) {
 		err := validate.HandleRequest(w, containerManager)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			api.WriteError(w, err)
 		}
 	})
 
@@ -92,32 +95,91 @@ func RegisterHandlers(mux httpmux.Mux, containerManager manager.Manager, httpAut
 	return nil
 }
 
+// prometheusScope selects the metrics served by a Prometheus endpoint.
+type prometheusScope struct {
+	// Machine metrics and the metrics of cAdvisor itself.
+	machine bool
+	// Resource usage and spec of containers.
+	containers bool
+	// Application metrics of containers.
+	app bool
+}
+
 // RegisterPrometheusHandler creates a new PrometheusCollector and configures
-// the provided HTTP mux to handle the given Prometheus endpoint.
+// the provided HTTP mux to handle the given Prometheus endpoint. The machine,
+// container and application metrics are also served separately under
+// <endpoint>/machine, <endpoint>/containers and <endpoint>/app, so that they
+// can be scraped at different intervals.
 func RegisterPrometheusHandler(mux httpmux.Mux, resourceManager manager.Manager, prometheusEndpoint string,
-	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet) {
+	f metrics.ContainerLabelsFunc, includedMetrics container.MetricSet, exemplarLabel string, relabelConfig *metrics.RelabelConfig) {
 	goCollector := prometheus.NewGoCollector()
 	processCollector := prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})
 	machineCollector := metrics.NewPrometheusMachineCollector(resourceManager, includedMetrics)
 
-	mux.Handle(prometheusEndpoint, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		opts, err := api.GetRequestOptions(req)
-		if err != nil {
-			http.Error(w, "No metrics gathered, last error:\n\n"+err.Error(), http.StatusInternalServerError)
-			return
+	handler := func(scope prometheusScope) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			opts, err := api.GetRequestOptions(req)
+			if err != nil {
+				api.WriteError(w, err)
+				return
+			}
+			opts.Count = 1        // we only want the latest datapoint
+			opts.Recursive = true // get all child containers
+
+			r := prometheus.NewRegistry()
+			var collector *metrics.PrometheusCollector
+			if scope.containers || scope.app {
+				containerMetrics := includedMetrics
+				if !scope.app {
+					containerMetrics = includedMetrics.Difference(container.MetricSet{container.AppMetrics: struct{}{}})
+				}
+				collector = metrics.NewPrometheusCollector(resourceManager, f, containerMetrics, clock.RealClock{}, opts)
+				if !scope.containers {
+					collector.SetAppMetricsOnly()
+				}
+				r.MustRegister(collector)
+			}
+			if scope.machine {
+				r.MustRegister(
+					machineCollector,
+					goCollector,
+					processCollector,
+				)
+				r.MustRegister(storage.Metrics()...)
+				r.MustRegister(resourceManager.InternalMetrics()...)
+			}
+			g := metrics.NewRelabelingGatherer(r, relabelConfig)
+
+			// OpenMetrics responses carry exemplars and the created timestamps of
+			// counters, which the Prometheus text format cannot express.
+			format := expfmt.NegotiateIncludingOpenMetrics(req.Header)
+			if format != expfmt.FmtOpenMetrics {
+				promhttp.HandlerFor(g, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
+				return
+			}
+			created := metrics.NewCreatedTimestamps()
+			if collector != nil {
+				collector.SetOpenMetricsOptions(metrics.OpenMetricsOptions{
+					ExemplarLabel: exemplarLabel,
+					Created:       created,
+				})
+			}
+			families, err := g.Gather()
+			if err != nil {
+				klog.V(4).Infof("Error gathering metrics: %v", err)
+			}
+			w.Header().Set("Content-Type", string(format))
+			if err := metrics.WriteOpenMetrics(w, families, created); err != nil {
+				klog.V(4).Infof("Error writing metrics: %v", err)
+			}
 		}
-		opts.Count = 1        // we only want the latest datapoint
-		opts.Recursive = true // get all child containers
-
-		r := prometheus.NewRegistry()
-		r.MustRegister(
-			metrics.NewPrometheusCollector(resourceManager, f, includedMetrics, clock.RealClock{}, opts),
-			machineCollector,
-			goCollector,
-			processCollector,
-		)
-		promhttp.HandlerFor(r, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, req)
-	}))
+	}
+
+	endpoint := strings.TrimSuffix(prometheusEndpoint, "/")
+	mux.Handle(prometheusEndpoint, handler(prometheusScope{machine: true, containers: true, app: true}))
+	mux.Handle(endpoint+"/machine", handler(prometheusScope{machine: true}))
+	mux.Handle(endpoint+"/containers", handler(prometheusScope{containers: true}))
+	mux.Handle(endpoint+"/app", handler(prometheusScope{app: true}))
 }
 
 func staticHandlerNoAuth(w http.ResponseWriter, r *http.Request) {
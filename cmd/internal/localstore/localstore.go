@@ -0,0 +1,418 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package localstore persists the recent stats and events of the containers
+// in a bolt database, so that they survive restarts of cAdvisor.
+package localstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// Container references by container name.
+	containersBucket = []byte("containers")
+	// A bucket of stats by timestamp for each container name.
+	statsBucket = []byte("stats")
+	// Events by timestamp and sequence number.
+	eventsBucket = []byte("events")
+)
+
+const (
+	// The database is compacted once its free pages take more than half of
+	// it, and it is larger than this.
+	minCompactSize = 4 << 20
+	// Maximum size of the transactions copying the database when compacting.
+	compactTxMaxSize = 64 << 20
+)
+
+// EventRetention limits the events kept by a Store.
+type EventRetention struct {
+	// Events older than MaxAge are deleted.
+	MaxAge time.Duration
+	// The oldest events are deleted once there are more than MaxEvents.
+	MaxEvents int
+}
+
+// Store buffers the stats and events it is given and writes them
+// periodically, keeping the stats of the last maxAge. It implements
+// storage.StorageDriver and events.Store.
+type Store struct {
+	path          string
+	maxAge        time.Duration
+	events        EventRetention
+	db            *bolt.DB
+	lock          sync.Mutex
+	pending       map[string]*pendingStats
+	pendingEvents []*info.Event
+	// Serializes the writes and the compactions of db.
+	dbLock sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+type pendingStats struct {
+	ref   info.ContainerReference
+	stats []*info.ContainerStats
+}
+
+// Open opens or creates the store at path, writing the stats and events
+// given to it every flushInterval.
+func Open(path string, maxAge time.Duration, events EventRetention, flushInterval time.Duration) (*Store, error) {
+	if flushInterval <= 0 {
+		return nil, fmt.Errorf("invalid local store flush interval %v", flushInterval)
+	}
+	if events.MaxEvents < 0 {
+		return nil, fmt.Errorf("invalid local store event limit %d", events.MaxEvents)
+	}
+	s := &Store{
+		path:    path,
+		maxAge:  maxAge,
+		events:  events,
+		pending: map[string]*pendingStats{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	if err := s.expire(time.Now()); err != nil {
+		s.db.Close()
+		return nil, err
+	}
+	go s.loop(flushInterval)
+	return s, nil
+}
+
+func (s *Store) open() error {
+	// The timeout fails instead of blocking when another process has the
+	// database open.
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("failed to open local store %s: %v", s.path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{containersBucket, statsBucket, eventsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to initialize local store %s: %v", s.path, err)
+	}
+	s.db = db
+	return nil
+}
+
+func (s *Store) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	p, ok := s.pending[cInfo.Name]
+	if !ok {
+		p = &pendingStats{ref: cInfo.ContainerReference}
+		s.pending[cInfo.Name] = p
+	}
+	p.stats = append(p.stats, stats)
+	return nil
+}
+
+func (s *Store) AddEvent(event *info.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pendingEvents = append(s.pendingEvents, event)
+	return nil
+}
+
+func (s *Store) loop(flushInterval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		if err := s.flush(); err != nil {
+			klog.Errorf("Failed to write local store %s: %v", s.path, err)
+		}
+		if err := s.expire(time.Now()); err != nil {
+			klog.Errorf("Failed to expire stats and events of local store %s: %v", s.path, err)
+		}
+		if err := s.maybeCompact(); err != nil {
+			klog.Errorf("Failed to compact local store %s: %v", s.path, err)
+		}
+	}
+}
+
+// Returns the key of stats taken at timestamp, which sort by time.
+func key(timestamp time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(timestamp.UnixNano()))
+	return k
+}
+
+// Returns the key of an event, which sorts by time and keeps the events
+// taken at the same time apart.
+func eventKey(timestamp time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(timestamp.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
+
+// Writes the pending stats and events in a single transaction.
+func (s *Store) flush() error {
+	s.lock.Lock()
+	pending, pendingEvents := s.pending, s.pendingEvents
+	s.pending, s.pendingEvents = map[string]*pendingStats{}, nil
+	s.lock.Unlock()
+	if len(pending) == 0 && len(pendingEvents) == 0 {
+		return nil
+	}
+
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		containers := tx.Bucket(containersBucket)
+		stats := tx.Bucket(statsBucket)
+		for name, p := range pending {
+			ref, err := json.Marshal(p.ref)
+			if err != nil {
+				return err
+			}
+			if err := containers.Put([]byte(name), ref); err != nil {
+				return err
+			}
+			b, err := stats.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+			for _, st := range p.stats {
+				value, err := json.Marshal(st)
+				if err != nil {
+					return err
+				}
+				if err := b.Put(key(st.Timestamp), value); err != nil {
+					return err
+				}
+			}
+		}
+		events := tx.Bucket(eventsBucket)
+		for _, event := range pendingEvents {
+			value, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			seq, err := events.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := events.Put(eventKey(event.Timestamp, seq), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Deletes the stats older than maxAge, the containers left without stats,
+// and the events beyond the event retention.
+func (s *Store) expire(now time.Time) error {
+	cutoff := key(now.Add(-s.maxAge))
+	eventCutoff := key(now.Add(-s.events.MaxAge))
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+		excess := events.Stats().KeyN - s.events.MaxEvents
+		c := events.Cursor()
+		for k, _ := c.First(); k != nil && (excess > 0 || bytes.Compare(k, eventCutoff) < 0); k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			excess--
+		}
+
+		containers := tx.Bucket(containersBucket)
+		stats := tx.Bucket(statsBucket)
+		// Buckets must not be modified while iterating over them.
+		var names [][]byte
+		err := stats.ForEach(func(name, _ []byte) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			b := stats.Bucket(name)
+			if b == nil {
+				continue
+			}
+			c := b.Cursor()
+			k, _ := c.First()
+			for ; k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+			if k != nil {
+				continue
+			}
+			if err := stats.DeleteBucket(name); err != nil {
+				return err
+			}
+			if err := containers.Delete(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Compacts the database once most of it is free pages, which bolt reuses but
+// never gives back to the file system.
+func (s *Store) maybeCompact() error {
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	stats := s.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(s.db.Info().PageSize)
+	if fi.Size() < minCompactSize || free*2 < fi.Size() {
+		return nil
+	}
+	return s.compact()
+}
+
+// Copies the database to a new file replacing it. Must be called with dbLock
+// held.
+func (s *Store) compact() error {
+	tmp := s.path + ".compact"
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, s.db, compactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		// Keep using the uncompacted database.
+		os.Remove(tmp)
+		if openErr := s.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	klog.V(2).Infof("Compacted local store %s", s.path)
+	return s.open()
+}
+
+// Restore calls f with the stored stats of each container, oldest first.
+func (s *Store) Restore(f func(ref info.ContainerReference, stats []*info.ContainerStats)) error {
+	cutoff := key(time.Now().Add(-s.maxAge))
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	return s.db.View(func(tx *bolt.Tx) error {
+		containers := tx.Bucket(containersBucket)
+		statsByName := tx.Bucket(statsBucket)
+		return containers.ForEach(func(name, value []byte) error {
+			var ref info.ContainerReference
+			if err := json.Unmarshal(value, &ref); err != nil {
+				klog.Warningf("Skipping container %q of local store %s: %v", name, s.path, err)
+				return nil
+			}
+			b := statsByName.Bucket(name)
+			if b == nil {
+				return nil
+			}
+			var stats []*info.ContainerStats
+			c := b.Cursor()
+			for k, v := c.Seek(cutoff); k != nil; k, v = c.Next() {
+				st := &info.ContainerStats{}
+				if err := json.Unmarshal(v, st); err != nil {
+					klog.Warningf("Skipping stats of container %q of local store %s: %v", name, s.path, err)
+					continue
+				}
+				stats = append(stats, st)
+			}
+			if len(stats) > 0 {
+				f(ref, stats)
+			}
+			return nil
+		})
+	})
+}
+
+// RestoreEvents calls f with the stored events, oldest first.
+func (s *Store) RestoreEvents(f func(event *info.Event)) error {
+	cutoff := key(time.Now().Add(-s.events.MaxAge))
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(eventsBucket).Cursor()
+		for k, v := c.Seek(cutoff); k != nil; k, v = c.Next() {
+			event := &info.Event{}
+			if err := json.Unmarshal(v, event); err != nil {
+				klog.Warningf("Skipping event of local store %s: %v", s.path, err)
+				continue
+			}
+			f(event)
+		}
+		return nil
+	})
+}
+
+// Close writes the pending stats and events and closes the database.
+func (s *Store) Close() error {
+	close(s.stop)
+	<-s.done
+	err := s.flush()
+	s.dbLock.Lock()
+	defer s.dbLock.Unlock()
+	if closeErr := s.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package localstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+var testRetention = EventRetention{MaxAge: time.Hour, MaxEvents: 3}
+
+func testContainer(name string) *info.ContainerInfo {
+	return &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: name, Aliases: []string{"alias"}}}
+}
+
+func testStats(timestamp time.Time, usage uint64) *info.ContainerStats {
+	return &info.ContainerStats{Timestamp: timestamp, Memory: info.MemoryStats{Usage: usage}}
+}
+
+// Returns the restored stats by container name.
+func restore(t *testing.T, s *Store) map[string][]uint64 {
+	restored := map[string][]uint64{}
+	require.NoError(t, s.Restore(func(ref info.ContainerReference, stats []*info.ContainerStats) {
+		assert.Equal(t, []string{"alias"}, ref.Aliases)
+		for _, st := range stats {
+			restored[ref.Name] = append(restored[ref.Name], st.Memory.Usage)
+		}
+	}))
+	return restored
+}
+
+func testPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "localstore")
+	require.NoError(t, err)
+	return filepath.Join(dir, "stats.db"), func() { os.RemoveAll(dir) }
+}
+
+func TestRestoreAfterReopen(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, s.AddStats(testContainer("/a"), testStats(now.Add(-2*time.Second), 1)))
+	require.NoError(t, s.AddStats(testContainer("/a"), testStats(now.Add(-time.Second), 2)))
+	require.NoError(t, s.AddStats(testContainer("/b"), testStats(now, 3)))
+	// Stats are only written when flushed.
+	assert.Empty(t, restore(t, s))
+	require.NoError(t, s.Close())
+
+	s, err = Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, map[string][]uint64{"/a": {1, 2}, "/b": {3}}, restore(t, s))
+}
+
+func TestExpire(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	require.NoError(t, s.AddStats(testContainer("/a"), testStats(now.Add(-2*time.Minute), 1)))
+	require.NoError(t, s.AddStats(testContainer("/a"), testStats(now, 2)))
+	require.NoError(t, s.AddStats(testContainer("/gone"), testStats(now.Add(-2*time.Minute), 3)))
+	require.NoError(t, s.flush())
+	// Old stats are not restored, even before they expire.
+	assert.Equal(t, map[string][]uint64{"/a": {2}}, restore(t, s))
+
+	require.NoError(t, s.expire(now))
+	require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, tx.Bucket(containersBucket).Get([]byte("/gone")))
+		assert.Nil(t, tx.Bucket(statsBucket).Bucket([]byte("/gone")))
+		assert.Equal(t, 1, tx.Bucket(statsBucket).Bucket([]byte("/a")).Stats().KeyN)
+		return nil
+	}))
+}
+
+func testEvent(timestamp time.Time, name string) *info.Event {
+	return &info.Event{ContainerName: name, Timestamp: timestamp, EventType: info.EventOomKill}
+}
+
+// Returns the container names of the restored events.
+func restoreEvents(t *testing.T, s *Store) []string {
+	var restored []string
+	require.NoError(t, s.RestoreEvents(func(event *info.Event) {
+		assert.Equal(t, info.EventOomKill, event.EventType)
+		restored = append(restored, event.ContainerName)
+	}))
+	return restored
+}
+
+func TestRestoreEventsAfterReopen(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, s.AddEvent(testEvent(now.Add(-2*time.Hour), "/expired")))
+	require.NoError(t, s.AddEvent(testEvent(now.Add(-time.Minute), "/a")))
+	// Events taken at the same time are all kept.
+	require.NoError(t, s.AddEvent(testEvent(now, "/b")))
+	require.NoError(t, s.AddEvent(testEvent(now, "/c")))
+	assert.Empty(t, restoreEvents(t, s))
+	require.NoError(t, s.Close())
+
+	s, err = Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, []string{"/a", "/b", "/c"}, restoreEvents(t, s))
+}
+
+func TestExpireEvents(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	require.NoError(t, s.AddEvent(testEvent(now.Add(-2*time.Hour), "/expired")))
+	for i, name := range []string{"/a", "/b", "/c", "/d"} {
+		require.NoError(t, s.AddEvent(testEvent(now.Add(time.Duration(i)*time.Second), name)))
+	}
+	require.NoError(t, s.flush())
+	require.NoError(t, s.expire(now))
+	require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, testRetention.MaxEvents, tx.Bucket(eventsBucket).Stats().KeyN)
+		return nil
+	}))
+	assert.Equal(t, []string{"/b", "/c", "/d"}, restoreEvents(t, s))
+}
+
+func TestCompact(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	for i := 0; i < 5000; i++ {
+		require.NoError(t, s.AddStats(testContainer("/a"), testStats(now.Add(-2*time.Minute).Add(time.Duration(i)), uint64(i))))
+	}
+	require.NoError(t, s.AddStats(testContainer("/a"), testStats(now, 1)))
+	require.NoError(t, s.flush())
+	require.NoError(t, s.expire(now))
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+	require.True(t, before.Size() >= minCompactSize)
+
+	require.NoError(t, s.maybeCompact())
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, after.Size() < before.Size())
+	assert.Equal(t, map[string][]uint64{"/a": {1}}, restore(t, s))
+}
+
+func TestOpenLocked(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+	s, err := Open(path, time.Minute, testRetention, time.Hour)
+	require.NoError(t, err)
+	defer s.Close()
+
+	_, err = Open(path, time.Minute, testRetention, time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var severities = map[byte]string{
+	'I': "INFO",
+	'W': "WARNING",
+	'E': "ERROR",
+	'F': "FATAL",
+}
+
+// jsonEntry is a log line in the JSON format.
+type jsonEntry struct {
+	Time     time.Time `json:"time"`
+	Severity string    `json:"severity"`
+	File     string    `json:"file,omitempty"`
+	Line     int       `json:"line,omitempty"`
+	Message  string    `json:"msg"`
+}
+
+// jsonWriter converts the lines of klog, e.g.
+// "I0102 15:04:05.000000   1234 manager.go:42] message", to JSON.
+type jsonWriter struct {
+	out io.Writer
+	now func() time.Time
+}
+
+func (w *jsonWriter) Write(data []byte) (int, error) {
+	entry := jsonEntry{Time: w.now(), Severity: "INFO", Message: string(bytes.TrimSuffix(data, []byte("\n")))}
+	if i := bytes.Index(data, []byte("] ")); i > 0 {
+		header := bytes.Fields(data[:i])
+		if len(header) == 4 && len(header[0]) > 0 {
+			if severity, ok := severities[header[0][0]]; ok {
+				entry.Severity = severity
+			}
+			location := header[3]
+			if j := bytes.LastIndexByte(location, ':'); j > 0 {
+				entry.File = string(location[:j])
+				entry.Line, _ = strconv.Atoi(string(location[j+1:]))
+			}
+			entry.Message = string(bytes.TrimSuffix(data[i+2:], []byte("\n")))
+		}
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.out.Write(append(line, '\n')); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// SetJSONOutput makes klog write its logs to out as JSON lines, e.g.
+// {"time":"...","severity":"INFO","file":"manager.go","line":42,"msg":"..."}.
+// flags are the klog flags.
+func SetJSONOutput(flags *flag.FlagSet, out io.Writer) error {
+	// klog writes each line to the output of its severity and those of the
+	// lower severities, so only the info output is kept.
+	for name, value := range map[string]string{
+		"logtostderr":     "false",
+		"alsologtostderr": "false",
+		"stderrthreshold": "FATAL",
+	} {
+		if err := flags.Set(name, value); err != nil {
+			return err
+		}
+	}
+	klog.SetOutputBySeverity("INFO", &jsonWriter{out: out, now: time.Now})
+	for _, severity := range []string{"WARNING", "ERROR", "FATAL"} {
+		klog.SetOutputBySeverity(severity, ioutil.Discard)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging changes the verbosity of the logs of cAdvisor at runtime,
+// globally or for some of its components, and formats them as JSON.
+package logging
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// Maximum verbosity accepted.
+const maxLevel = 10
+
+// Components maps the components whose verbosity can be set to the patterns
+// of their source file names, as matched by --vmodule. klog matches the
+// patterns against the base names of the files only, the names of files
+// outside of a component must not match them.
+var Components = map[string][]string{
+	"discovery": {"factory", "watcher", "inotify_watcher", "manager"},
+	"fs":        {"fs", "fsHandler", "breakdown"},
+	"resctrl":   {"resctrl_*"},
+	"perf":      {"*_libpfm"},
+}
+
+// ErrInvalidLevels is wrapped by the errors of invalid updates.
+var ErrInvalidLevels = errors.New("invalid log levels")
+
+// Levels are the verbosities of the logs.
+type Levels struct {
+	// Global verbosity, as set by -v.
+	Verbosity int `json:"verbosity"`
+	// Verbosity of source files, as set by --vmodule, in addition to the
+	// components.
+	VModule string `json:"vmodule"`
+	// Verbosity of components, by component name.
+	Components map[string]int `json:"components"`
+}
+
+// Update changes the verbosities set.
+type Update struct {
+	Verbosity *int    `json:"verbosity"`
+	VModule   *string `json:"vmodule"`
+	// Verbosity of components, 0 resets a component to the global
+	// verbosity.
+	Components map[string]int `json:"components"`
+}
+
+// Controller sets the verbosity flags of klog.
+type Controller struct {
+	flags *flag.FlagSet
+
+	lock sync.Mutex
+	// vmodule patterns set in addition to the components.
+	base       string
+	components map[string]int
+	// Last vmodule set, to detect the changes made outside of the
+	// controller, e.g. by reloading the config file.
+	vmodule string
+}
+
+// NewController returns a controller of the klog flags registered in flags.
+func NewController(flags *flag.FlagSet) (*Controller, error) {
+	if flags.Lookup("v") == nil || flags.Lookup("vmodule") == nil {
+		return nil, errors.New("klog flags not registered")
+	}
+	vmodule := flags.Lookup("vmodule").Value.String()
+	return &Controller{flags: flags, base: vmodule, components: make(map[string]int), vmodule: vmodule}, nil
+}
+
+// sync forgets the components if vmodule was set by someone else.
+func (c *Controller) sync() {
+	if vmodule := c.flags.Lookup("vmodule").Value.String(); vmodule != c.vmodule {
+		c.base, c.components, c.vmodule = vmodule, make(map[string]int), vmodule
+	}
+}
+
+// Levels returns the current verbosities.
+func (c *Controller) Levels() Levels {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sync()
+	return c.levels()
+}
+
+func (c *Controller) levels() Levels {
+	verbosity, _ := strconv.Atoi(c.flags.Lookup("v").Value.String())
+	levels := Levels{Verbosity: verbosity, VModule: c.base, Components: make(map[string]int, len(c.components))}
+	for name, level := range c.components {
+		levels.Components[name] = level
+	}
+	return levels
+}
+
+func checkLevel(level int) error {
+	if level < 0 || level > maxLevel {
+		return fmt.Errorf("%w: level %d not between 0 and %d", ErrInvalidLevels, level, maxLevel)
+	}
+	return nil
+}
+
+// Set applies u and returns the new verbosities.
+func (c *Controller) Set(u Update) (Levels, error) {
+	if u.Verbosity != nil {
+		if err := checkLevel(*u.Verbosity); err != nil {
+			return Levels{}, err
+		}
+	}
+	for name, level := range u.Components {
+		if _, ok := Components[name]; !ok {
+			return Levels{}, fmt.Errorf("%w: unknown component %q", ErrInvalidLevels, name)
+		}
+		if err := checkLevel(level); err != nil {
+			return Levels{}, err
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.sync()
+	base := c.base
+	if u.VModule != nil {
+		base = *u.VModule
+	}
+	components := make(map[string]int, len(c.components))
+	for name, level := range c.components {
+		components[name] = level
+	}
+	for name, level := range u.Components {
+		if level == 0 {
+			delete(components, name)
+		} else {
+			components[name] = level
+		}
+	}
+	vmodule := joinVModule(components, base)
+	if err := c.flags.Set("vmodule", vmodule); err != nil {
+		return Levels{}, fmt.Errorf("%w: %v", ErrInvalidLevels, err)
+	}
+	c.base, c.components, c.vmodule = base, components, vmodule
+	if u.Verbosity != nil {
+		if err := c.flags.Set("v", strconv.Itoa(*u.Verbosity)); err != nil {
+			return Levels{}, err
+		}
+	}
+	levels := c.levels()
+	klog.Infof("Log levels set to %+v", levels)
+	return levels, nil
+}
+
+// joinVModule returns the vmodule flag setting the components, which take
+// precedence over the base patterns since klog applies the first match.
+func joinVModule(components map[string]int, base string) string {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var patterns []string
+	for _, name := range names {
+		for _, pattern := range Components[name] {
+			patterns = append(patterns, fmt.Sprintf("%s=%d", pattern, components[name]))
+		}
+	}
+	if base != "" {
+		patterns = append(patterns, base)
+	}
+	return strings.Join(patterns, ",")
+}
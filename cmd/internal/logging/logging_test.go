@@ -0,0 +1,153 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/klog/v2"
+)
+
+func newFlags(t *testing.T) *flag.FlagSet {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	require.NoError(t, flags.Set("v", "2"))
+	require.NoError(t, flags.Set("vmodule", "housekeeping=3"))
+	return flags
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestController(t *testing.T) {
+	flags := newFlags(t)
+	defer flags.Set("vmodule", "")
+	c, err := NewController(flags)
+	require.NoError(t, err)
+	assert.Equal(t, Levels{Verbosity: 2, VModule: "housekeeping=3", Components: map[string]int{}}, c.Levels())
+
+	levels, err := c.Set(Update{Verbosity: intPtr(3), Components: map[string]int{"fs": 5, "perf": 4}})
+	require.NoError(t, err)
+	assert.Equal(t, Levels{Verbosity: 3, VModule: "housekeeping=3", Components: map[string]int{"fs": 5, "perf": 4}}, levels)
+	assert.Equal(t, "3", flags.Lookup("v").Value.String())
+	assert.Equal(t, "fs=5,fsHandler=5,breakdown=5,*_libpfm=4,housekeeping=3", flags.Lookup("vmodule").Value.String())
+
+	// Level 0 resets a component.
+	vmodule := ""
+	levels, err = c.Set(Update{VModule: &vmodule, Components: map[string]int{"perf": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, Levels{Verbosity: 3, Components: map[string]int{"fs": 5}}, levels)
+	assert.Equal(t, "fs=5,fsHandler=5,breakdown=5", flags.Lookup("vmodule").Value.String())
+
+	for _, u := range []Update{
+		{Verbosity: intPtr(-1)},
+		{Verbosity: intPtr(11)},
+		{Components: map[string]int{"gpu": 2}},
+		{Components: map[string]int{"fs": 20}},
+	} {
+		_, err := c.Set(u)
+		assert.True(t, errors.Is(err, ErrInvalidLevels), "%+v: %v", u, err)
+	}
+	assert.Equal(t, "3", flags.Lookup("v").Value.String())
+
+	// Changes of vmodule made elsewhere, e.g. by a config reload, replace
+	// the components.
+	require.NoError(t, flags.Set("vmodule", "manager=4"))
+	assert.Equal(t, Levels{Verbosity: 3, VModule: "manager=4", Components: map[string]int{}}, c.Levels())
+}
+
+// The directories of the source files of each component, relative to the
+// root of the repository.
+var componentDirs = map[string][]string{
+	"discovery": {"container/", "cmd/internal/container/", "manager/", "watcher/"},
+	"fs":        {"fs/", "container/common/"},
+	"resctrl":   {"resctrl/"},
+	"perf":      {"perf/"},
+}
+
+func TestComponentPatterns(t *testing.T) {
+	const root = "../../.."
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && (info.Name() == "vendor" || info.Name() == "testdata" || info.Name() == ".git") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".go") {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, componentDirs, len(Components))
+
+	for name, patterns := range Components {
+		for _, pattern := range patterns {
+			matched := 0
+			for _, file := range files {
+				// klog matches the base name without the extension.
+				ok, err := filepath.Match(pattern, strings.TrimSuffix(filepath.Base(file), ".go"))
+				require.NoError(t, err)
+				if !ok {
+					continue
+				}
+				matched++
+				assert.True(t, inDirs(file, componentDirs[name]), "pattern %q of %s matches %s", pattern, name, file)
+			}
+			assert.NotZero(t, matched, "pattern %q of %s matches no file", pattern, name)
+		}
+	}
+}
+
+func inDirs(file string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(file, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJSONWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := &jsonWriter{out: out, now: func() time.Time { return time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC) }}
+	for _, line := range []string{
+		"W0102 15:04:05.000000   1234 manager.go:42] container \"/foo\" gone\n",
+		"not a klog line\n",
+	} {
+		n, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+	assert.Equal(t, `{"time":"2021-01-02T15:04:05Z","severity":"WARNING","file":"manager.go","line":42,"msg":"container \"/foo\" gone"}
+{"time":"2021-01-02T15:04:05Z","severity":"INFO","msg":"not a klog line"}
+`, out.String())
+}
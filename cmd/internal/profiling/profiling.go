@@ -0,0 +1,196 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profiling periodically captures CPU and heap profiles of cAdvisor,
+// and saves them to disk or pushes them to a profile collector.
+package profiling
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Profile types captured.
+const (
+	cpuProfile  = "cpu"
+	heapProfile = "heap"
+)
+
+// Suffix of the files of the profiles, which are gzipped protocol buffers.
+const profileSuffix = ".pb.gz"
+
+// Config configures the capture of profiles.
+type Config struct {
+	// Interval between the starts of two captures.
+	Interval time.Duration
+	// Duration of the CPU profiles.
+	CPUDuration time.Duration
+	// Directory the profiles are saved to, not saved if empty.
+	Dir string
+	// Number of profiles of each type kept in Dir.
+	Keep int
+	// URL the profiles are POSTed to, not pushed if empty.
+	PushURL string
+	// Name of the application the pushed profiles are reported for.
+	AppName string
+	Client  *http.Client
+}
+
+// Capturer captures profiles.
+type Capturer struct {
+	config Config
+}
+
+// New validates config and returns a capturer.
+func New(config Config) (*Capturer, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("invalid capture interval %v", config.Interval)
+	}
+	if config.CPUDuration <= 0 || config.CPUDuration > config.Interval {
+		return nil, fmt.Errorf("the CPU profile duration %v must be positive and at most the capture interval %v", config.CPUDuration, config.Interval)
+	}
+	if config.Dir == "" && config.PushURL == "" {
+		return nil, fmt.Errorf("neither a directory nor a push URL is set")
+	}
+	if config.Dir != "" {
+		if config.Keep < 1 {
+			return nil, fmt.Errorf("invalid number of profiles kept %d", config.Keep)
+		}
+		if err := os.MkdirAll(config.Dir, 0700); err != nil {
+			return nil, err
+		}
+	}
+	if config.PushURL != "" {
+		if _, err := url.Parse(config.PushURL); err != nil {
+			return nil, fmt.Errorf("invalid push URL: %v", err)
+		}
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &Capturer{config: config}, nil
+}
+
+// Start captures profiles every interval until the process exits.
+func (c *Capturer) Start() {
+	go func() {
+		ticker := time.NewTicker(c.config.Interval)
+		defer ticker.Stop()
+		for {
+			c.Capture()
+			<-ticker.C
+		}
+	}()
+}
+
+// Capture captures a CPU profile, which takes the CPU profile duration, and
+// a heap profile, and saves or pushes them.
+func (c *Capturer) Capture() {
+	start := time.Now()
+	var cpu bytes.Buffer
+	if err := pprof.StartCPUProfile(&cpu); err != nil {
+		// A CPU profile may be running through /debug/pprof.
+		klog.Warningf("Failed to capture a CPU profile: %v", err)
+	} else {
+		time.Sleep(c.config.CPUDuration)
+		pprof.StopCPUProfile()
+		c.output(cpuProfile, start, time.Now(), cpu.Bytes())
+	}
+
+	now := time.Now()
+	var heap bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+		klog.Warningf("Failed to capture a heap profile: %v", err)
+		return
+	}
+	c.output(heapProfile, now, now, heap.Bytes())
+}
+
+func (c *Capturer) output(profileType string, start, end time.Time, profile []byte) {
+	if c.config.Dir != "" {
+		if err := c.save(profileType, start, profile); err != nil {
+			klog.Errorf("Failed to save %s profile: %v", profileType, err)
+		}
+	}
+	if c.config.PushURL != "" {
+		if err := c.push(profileType, start, end, profile); err != nil {
+			klog.Errorf("Failed to push %s profile: %v", profileType, err)
+		}
+	}
+}
+
+// save writes the profile to <dir>/<type>-<time>.pb.gz, and removes the
+// oldest profiles of the same type beyond the number kept.
+func (c *Capturer) save(profileType string, start time.Time, profile []byte) error {
+	name := fmt.Sprintf("%s-%s%s", profileType, start.UTC().Format("20060102T150405.000Z"), profileSuffix)
+	if err := ioutil.WriteFile(filepath.Join(c.config.Dir, name), profile, 0600); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(c.config.Dir)
+	if err != nil {
+		return err
+	}
+	var profiles []string
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), profileType+"-") && strings.HasSuffix(f.Name(), profileSuffix) {
+			profiles = append(profiles, f.Name())
+		}
+	}
+	// The names sort by time.
+	sort.Strings(profiles)
+	for len(profiles) > c.config.Keep {
+		if err := os.Remove(filepath.Join(c.config.Dir, profiles[0])); err != nil {
+			return err
+		}
+		profiles = profiles[1:]
+	}
+	return nil
+}
+
+// push POSTs the profile with the query parameters of the ingestion API of
+// Pyroscope: the name of the application and of the profile type, the
+// period profiled and the format.
+func (c *Capturer) push(profileType string, start, end time.Time, profile []byte) error {
+	u, err := url.Parse(c.config.PushURL)
+	if err != nil {
+		return err
+	}
+	query := u.Query()
+	query.Set("name", c.config.AppName+"."+profileType)
+	query.Set("from", strconv.FormatInt(start.Unix(), 10))
+	query.Set("until", strconv.FormatInt(end.Unix(), 10))
+	query.Set("format", "pprof")
+	u.RawQuery = query.Encode()
+	resp, err := c.config.Client.Post(u.String(), "application/octet-stream", bytes.NewReader(profile))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", c.config.PushURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profiling
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var lock sync.Mutex
+	var pushed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, body)
+		assert.Equal(t, "pprof", r.URL.Query().Get("format"))
+		assert.NotEmpty(t, r.URL.Query().Get("from"))
+		assert.NotEmpty(t, r.URL.Query().Get("until"))
+		lock.Lock()
+		pushed = append(pushed, r.URL.Query().Get("name"))
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	c, err := New(Config{
+		Interval:    time.Minute,
+		CPUDuration: 10 * time.Millisecond,
+		Dir:         dir,
+		Keep:        2,
+		PushURL:     server.URL + "/ingest",
+		AppName:     "cadvisor",
+	})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		c.Capture()
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+	// Only the last two profiles of each type are kept.
+	require.Len(t, names, 4, "%v", names)
+	assert.True(t, strings.HasPrefix(names[0], "cpu-"))
+	assert.True(t, strings.HasPrefix(names[2], "heap-"))
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"cadvisor.cpu", "cadvisor.heap", "cadvisor.cpu", "cadvisor.heap", "cadvisor.cpu", "cadvisor.heap"}, pushed)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{CPUDuration: time.Second, Dir: "/tmp", Keep: 1},
+		{Interval: time.Minute, Dir: "/tmp", Keep: 1},
+		{Interval: time.Minute, CPUDuration: 2 * time.Minute, Dir: "/tmp", Keep: 1},
+		{Interval: time.Minute, CPUDuration: time.Second},
+		{Interval: time.Minute, CPUDuration: time.Second, Dir: "/tmp"},
+		{Interval: time.Minute, CPUDuration: time.Second, PushURL: "http://[::1"},
+	} {
+		_, err := New(config)
+		assert.Error(t, err, "%+v", config)
+	}
+}
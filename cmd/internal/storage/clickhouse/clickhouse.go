@@ -0,0 +1,272 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/utils/container"
+)
+
+func init() {
+	storage.RegisterStorageDriver("clickhouse", new)
+}
+
+var argAsyncInsert = flag.Bool("storage_driver_clickhouse_async_insert", true, "Use ClickHouse asynchronous inserts, batching the rows of several cAdvisor instances on the server")
+
+// Timeout of a single insert.
+const insertTimeout = 30 * time.Second
+
+// Layout of DateTime64(9) values.
+const timestampLayout = "2006-01-02 15:04:05.000000000"
+
+type clickhouseStorage struct {
+	client         *http.Client
+	insertURL      string
+	username       string
+	password       string
+	machineName    string
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	rows           []*row
+	lock           sync.Mutex
+	readyToFlush   func() bool
+}
+
+// row is a row of the stats table, see docs/storage/clickhouse.md for its
+// definition.
+type row struct {
+	Timestamp        string            `json:"timestamp"`
+	MachineName      string            `json:"machine_name"`
+	ContainerName    string            `json:"container_name"`
+	ContainerID      string            `json:"container_id"`
+	Labels           map[string]string `json:"labels"`
+	CpuUsageTotal    uint64            `json:"cpu_usage_total"`
+	CpuUsageUser     uint64            `json:"cpu_usage_user"`
+	CpuUsageSystem   uint64            `json:"cpu_usage_system"`
+	CpuLoadAverage   int32             `json:"cpu_load_average"`
+	MemoryUsage      uint64            `json:"memory_usage"`
+	MemoryWorkingSet uint64            `json:"memory_working_set"`
+	MemoryRss        uint64            `json:"memory_rss"`
+	MemoryCache      uint64            `json:"memory_cache"`
+	MemorySwap       uint64            `json:"memory_swap"`
+	MemoryFailcnt    uint64            `json:"memory_failcnt"`
+	RxBytes          uint64            `json:"rx_bytes"`
+	RxErrors         uint64            `json:"rx_errors"`
+	TxBytes          uint64            `json:"tx_bytes"`
+	TxErrors         uint64            `json:"tx_errors"`
+	FsDevice         []string          `json:"fs_device"`
+	FsLimit          []uint64          `json:"fs_limit"`
+	FsUsage          []uint64          `json:"fs_usage"`
+	ProcessCount     uint64            `json:"process_count"`
+	FdCount          uint64            `json:"fd_count"`
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(
+		hostname,
+		*storage.ArgDbTable,
+		*storage.ArgDbName,
+		*storage.ArgDbUsername,
+		*storage.ArgDbPassword,
+		*storage.ArgDbHost,
+		*storage.ArgDbIsSecure,
+		*storage.ArgDbBufferDuration,
+		*argAsyncInsert,
+	)
+}
+
+func (s *clickhouseStorage) containerStatsToRow(cInfo *info.ContainerInfo, stats *info.ContainerStats) *row {
+	r := &row{
+		Timestamp:        stats.Timestamp.UTC().Format(timestampLayout),
+		MachineName:      s.machineName,
+		ContainerName:    container.GetPreferredName(cInfo.ContainerReference),
+		ContainerID:      cInfo.ContainerReference.Id,
+		Labels:           cInfo.Spec.Labels,
+		CpuUsageTotal:    stats.Cpu.Usage.Total,
+		CpuUsageUser:     stats.Cpu.Usage.User,
+		CpuUsageSystem:   stats.Cpu.Usage.System,
+		CpuLoadAverage:   stats.Cpu.LoadAverage,
+		MemoryUsage:      stats.Memory.Usage,
+		MemoryWorkingSet: stats.Memory.WorkingSet,
+		MemoryRss:        stats.Memory.RSS,
+		MemoryCache:      stats.Memory.Cache,
+		MemorySwap:       stats.Memory.Swap,
+		MemoryFailcnt:    stats.Memory.Failcnt,
+		RxBytes:          stats.Network.RxBytes,
+		RxErrors:         stats.Network.RxErrors,
+		TxBytes:          stats.Network.TxBytes,
+		TxErrors:         stats.Network.TxErrors,
+		FsDevice:         []string{},
+		FsLimit:          []uint64{},
+		FsUsage:          []uint64{},
+		ProcessCount:     stats.Processes.ProcessCount,
+		FdCount:          stats.Processes.FdCount,
+	}
+	if r.Labels == nil {
+		r.Labels = map[string]string{}
+	}
+	for _, fs := range stats.Filesystem {
+		r.FsDevice = append(r.FsDevice, fs.Device)
+		r.FsLimit = append(r.FsLimit, fs.Limit)
+		r.FsUsage = append(r.FsUsage, fs.Usage)
+	}
+	return r
+}
+
+func (s *clickhouseStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+func (s *clickhouseStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	var rowsToFlush []*row
+	func() {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.rows = append(s.rows, s.containerStatsToRow(cInfo, stats))
+		if s.readyToFlush() {
+			rowsToFlush = s.rows
+			s.rows = make([]*row, 0)
+			s.lastWrite = time.Now()
+		}
+	}()
+	if len(rowsToFlush) > 0 {
+		return s.insert(rowsToFlush)
+	}
+	return nil
+}
+
+// WriteBatch inserts the rows of samples at once, bypassing the buffer.
+func (s *clickhouseStorage) WriteBatch(samples []storage.Sample) error {
+	rows := make([]*row, 0, len(samples))
+	for _, sample := range samples {
+		rows = append(rows, s.containerStatsToRow(sample.ContainerInfo, sample.Stats))
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return s.insert(rows)
+}
+
+// Inserts rows in a single JSONEachRow insert.
+func (s *clickhouseStorage) insert(rows []*row) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, r := range rows {
+		if err := encoder.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.insertURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-ClickHouse-User", s.username)
+	req.Header.Set("X-ClickHouse-Key", s.password)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write stats to ClickHouse: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write stats to ClickHouse: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+func (s *clickhouseStorage) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Quotes a ClickHouse identifier.
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(strings.Replace(name, `\`, `\\`, -1), "`", "\\`", -1) + "`"
+}
+
+// machineName: A unique identifier to identify the host that current cAdvisor
+// instance is running on.
+// clickhouseHost: The host:port of the ClickHouse HTTP interface.
+func newStorage(
+	machineName,
+	tableName,
+	database,
+	username,
+	password,
+	clickhouseHost string,
+	isSecure bool,
+	bufferDuration time.Duration,
+	asyncInsert bool,
+) (*clickhouseStorage, error) {
+	if tableName == "" {
+		return nil, fmt.Errorf("ClickHouse table is not set")
+	}
+	table := quoteIdentifier(tableName)
+	if database != "" {
+		table = quoteIdentifier(database) + "." + table
+	}
+	query := url.Values{}
+	query.Set("query", fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table))
+	if asyncInsert {
+		query.Set("async_insert", "1")
+	}
+	insertURL := &url.URL{
+		Scheme:   "http",
+		Host:     clickhouseHost,
+		Path:     "/",
+		RawQuery: query.Encode(),
+	}
+	if isSecure {
+		insertURL.Scheme = "https"
+	}
+
+	ret := &clickhouseStorage{
+		client:         &http.Client{Timeout: insertTimeout},
+		insertURL:      insertURL.String(),
+		username:       username,
+		password:       password,
+		machineName:    machineName,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+		rows:           make([]*row, 0),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clickhouse
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type clickhouseServer struct {
+	queries []string
+	rows    [][]map[string]interface{}
+}
+
+func (s *clickhouseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-ClickHouse-User") != "default" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+		http.Error(w, "Code: 516. DB::Exception: default: Authentication failed", http.StatusUnauthorized)
+		return
+	}
+	s.queries = append(s.queries, r.URL.RawQuery)
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var rows []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var row map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows = append(rows, row)
+	}
+	s.rows = append(s.rows, rows)
+}
+
+func newTestStorage(t *testing.T, ts *httptest.Server, password string, buffer int) *clickhouseStorage {
+	s, err := newStorage("machine", "stats", "cadvisor", "default", password, strings.TrimPrefix(ts.URL, "http://"), false, time.Minute, true)
+	require.NoError(t, err)
+	count := 0
+	s.readyToFlush = func() bool {
+		count++
+		return count%buffer == 0
+	}
+	return s
+}
+
+func testStats(timestamp time.Time) (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Id: "abc", Aliases: []string{"web"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"app": "web"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp:  timestamp,
+		Cpu:        info.CpuStats{Usage: info.CpuUsage{Total: 1000}},
+		Memory:     info.MemoryStats{WorkingSet: 1024},
+		Filesystem: []info.FsStats{{Device: "/dev/sda1", Limit: 100, Usage: 50}},
+	}
+	return cInfo, stats
+}
+
+func TestBatchedInsert(t *testing.T) {
+	server := &clickhouseServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	s := newTestStorage(t, ts, "secret", 2)
+	defer s.Close()
+	start := time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.AddStats(testStats(start.Add(time.Duration(i)*time.Second))))
+	}
+
+	// The third row is buffered.
+	require.Len(t, server.rows, 1)
+	require.Len(t, server.rows[0], 2)
+	assert.Equal(t, "async_insert=1&query=INSERT+INTO+%60cadvisor%60.%60stats%60+FORMAT+JSONEachRow", server.queries[0])
+	row := server.rows[0][1]
+	assert.Equal(t, "2021-01-02 03:04:06.000000006", row["timestamp"])
+	assert.Equal(t, "machine", row["machine_name"])
+	assert.Equal(t, "web", row["container_name"])
+	assert.Equal(t, map[string]interface{}{"app": "web"}, row["labels"])
+	assert.Equal(t, float64(1000), row["cpu_usage_total"])
+	assert.Equal(t, float64(1024), row["memory_working_set"])
+	assert.Equal(t, []interface{}{"/dev/sda1"}, row["fs_device"])
+	assert.Equal(t, []interface{}{float64(50)}, row["fs_usage"])
+}
+
+func TestInsertError(t *testing.T) {
+	ts := httptest.NewServer(&clickhouseServer{})
+	defer ts.Close()
+
+	s := newTestStorage(t, ts, "wrong", 1)
+	err := s.AddStats(testStats(time.Now()))
+	assert.EqualError(t, err, "failed to write stats to ClickHouse: 401 Unauthorized: Code: 516. DB::Exception: default: Authentication failed")
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, "`stats`", quoteIdentifier("stats"))
+	assert.Equal(t, "`st\\`ats`", quoteIdentifier("st`ats"))
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/container"
+	"github.com/google/cadvisor/version"
+)
+
+// Version of the Elastic Common Schema the documents follow.
+const ecsVersion = "8.2.0"
+
+// Memory limits above this value mean that the container is not limited.
+const unlimitedMemory = uint64(1) << 62
+
+// document is a single container stats sample, with its fields named after
+// the Elastic Common Schema (https://www.elastic.co/guide/en/ecs/current/).
+// Fields without an ECS equivalent are kept under the custom cadvisor
+// namespace.
+type document struct {
+	Timestamp  string       `json:"@timestamp"`
+	ECS        ecsInfo      `json:"ecs"`
+	Agent      agent        `json:"agent"`
+	Host       host         `json:"host"`
+	Event      event        `json:"event"`
+	DataStream *dataStream  `json:"data_stream,omitempty"`
+	Container  ecsContainer `json:"container"`
+	Cadvisor   cadvisor     `json:"cadvisor"`
+}
+
+type ecsInfo struct {
+	Version string `json:"version"`
+}
+
+type agent struct {
+	Type    string `json:"type"`
+	Version string `json:"version,omitempty"`
+}
+
+type host struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+}
+
+type event struct {
+	Kind    string `json:"kind"`
+	Module  string `json:"module"`
+	Dataset string `json:"dataset"`
+}
+
+type dataStream struct {
+	Type      string `json:"type"`
+	Dataset   string `json:"dataset"`
+	Namespace string `json:"namespace"`
+}
+
+type ecsContainer struct {
+	ID      string            `json:"id,omitempty"`
+	Name    string            `json:"name"`
+	Runtime string            `json:"runtime,omitempty"`
+	Image   *image            `json:"image,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	CPU     *usage            `json:"cpu,omitempty"`
+	Memory  *usage            `json:"memory,omitempty"`
+	Network network           `json:"network"`
+	Disk    disk              `json:"disk"`
+}
+
+type image struct {
+	Name string   `json:"name"`
+	Tag  []string `json:"tag,omitempty"`
+}
+
+// usage is a fraction between 0 and 1.
+type usage struct {
+	Usage float64 `json:"usage"`
+}
+
+type bytesCount struct {
+	Bytes uint64 `json:"bytes"`
+}
+
+type network struct {
+	Ingress bytesCount `json:"ingress"`
+	Egress  bytesCount `json:"egress"`
+}
+
+type disk struct {
+	Read  bytesCount `json:"read"`
+	Write bytesCount `json:"write"`
+}
+
+type cadvisor struct {
+	Stats *info.ContainerStats `json:"stats"`
+}
+
+// cpuSample is the previous cumulative CPU usage of a container, needed to
+// compute container.cpu.usage.
+type cpuSample struct {
+	timestamp time.Time
+	total     uint64
+}
+
+// Splits the data stream name into its type, dataset and namespace following
+// the data stream naming scheme, or returns nil if the name does not follow it.
+func parseDataStream(name string) *dataStream {
+	parts := strings.Split(name, "-")
+	if len(parts) != 3 {
+		return nil
+	}
+	for _, part := range parts {
+		if part == "" {
+			return nil
+		}
+	}
+	return &dataStream{Type: parts[0], Dataset: parts[1], Namespace: parts[2]}
+}
+
+// Splits an image reference into its name and tag.
+func parseImage(ref string) *image {
+	if ref == "" {
+		return nil
+	}
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return &image{Name: ref[:i]}
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return &image{Name: ref[:i], Tag: []string{ref[i+1:]}}
+	}
+	return &image{Name: ref}
+}
+
+// Sums the bytes of the given operation over all the block devices.
+func diskBytes(stats *info.ContainerStats, op string) uint64 {
+	var total uint64
+	for _, entry := range stats.DiskIo.IoServiceBytes {
+		total += entry.Stats[op]
+	}
+	return total
+}
+
+// Builds the ECS document of stats. prev is the previous CPU sample of the
+// container, if any, and cores the number of cores of the machine.
+func newDocument(machineName string, ds *dataStream, cInfo *info.ContainerInfo, stats *info.ContainerStats, prev *cpuSample, cores int) *document {
+	doc := &document{
+		Timestamp:  stats.Timestamp.UTC().Format(time.RFC3339Nano),
+		ECS:        ecsInfo{Version: ecsVersion},
+		Agent:      agent{Type: "cadvisor", Version: version.Info["version"]},
+		Host:       host{Name: machineName, Hostname: machineName},
+		Event:      event{Kind: "metric", Module: "cadvisor", Dataset: "cadvisor.container"},
+		DataStream: ds,
+		Container: ecsContainer{
+			ID:      cInfo.ContainerReference.Id,
+			Name:    container.GetPreferredName(cInfo.ContainerReference),
+			Runtime: cInfo.ContainerReference.Namespace,
+			Image:   parseImage(cInfo.Spec.Image),
+			Labels:  cInfo.Spec.Labels,
+			Network: network{
+				Ingress: bytesCount{Bytes: stats.Network.RxBytes},
+				Egress:  bytesCount{Bytes: stats.Network.TxBytes},
+			},
+			Disk: disk{
+				Read:  bytesCount{Bytes: diskBytes(stats, "Read")},
+				Write: bytesCount{Bytes: diskBytes(stats, "Write")},
+			},
+		},
+		Cadvisor: cadvisor{Stats: stats},
+	}
+	if prev != nil && cores > 0 && stats.Cpu.Usage.Total >= prev.total {
+		if elapsed := stats.Timestamp.Sub(prev.timestamp); elapsed > 0 {
+			used := float64(stats.Cpu.Usage.Total-prev.total) / float64(elapsed.Nanoseconds())
+			doc.Container.CPU = &usage{Usage: used / float64(cores)}
+		}
+	}
+	if limit := cInfo.Spec.Memory.Limit; cInfo.Spec.HasMemory && limit > 0 && limit < unlimitedMemory {
+		doc.Container.Memory = &usage{Usage: float64(stats.Memory.Usage) / float64(limit)}
+	}
+	return doc
+}
@@ -15,144 +15,433 @@
 package elasticsearch
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
 	info "github.com/google/cadvisor/info/v1"
 	storage "github.com/google/cadvisor/storage"
 
-	"gopkg.in/olivere/elastic.v2"
+	"k8s.io/klog/v2"
 )
 
 func init() {
 	storage.RegisterStorageDriver("elasticsearch", new)
 }
 
+var (
+	argElasticHost = flag.String("storage_driver_es_host", "http://localhost:9200", "Comma separated ElasticSearch URLs, tried in turn when one fails")
+	argIndexName   = flag.String("storage_driver_es_index", "cadvisor", "ElasticSearch index name, ignored when --storage_driver_es_data_stream is set")
+	argDataStream  = flag.String("storage_driver_es_data_stream", "", "ElasticSearch data stream to write to instead of an index, e.g. metrics-cadvisor-default")
+	argILMPolicy   = flag.String("storage_driver_es_ilm_policy", "", "ILM policy of the data stream. When set, an index template of the data stream using the policy is installed on startup")
+	argAPIKey      = flag.String("storage_driver_es_api_key", "", "ElasticSearch API key, base64 encoded id:api_key")
+	argUsername    = flag.String("storage_driver_es_user", "", "ElasticSearch basic auth user")
+	argPassword    = flag.String("storage_driver_es_password", "", "ElasticSearch basic auth password")
+	argBulkSize    = flag.Int("storage_driver_es_bulk_size", 1000, "Maximum number of documents in a single ElasticSearch bulk request")
+	argMaxRetries  = flag.Int("storage_driver_es_max_retries", 3, "Number of times a rejected ElasticSearch bulk request is retried, with exponential backoff")
+
+	// Deprecated: mapping types were removed in ElasticSearch 7 and nodes are
+	// listed in --storage_driver_es_host.
+	_ = flag.String("storage_driver_es_type", "stats", "Deprecated: ignored, ElasticSearch mapping types no longer exist")
+	_ = flag.Bool("storage_driver_es_enable_sniffer", false, "Deprecated: ignored, list the ElasticSearch nodes in --storage_driver_es_host instead")
+)
+
+const (
+	// Timeout of a single request.
+	requestTimeout = 30 * time.Second
+	// Delay before the first retry of a bulk request, doubled on each retry.
+	initialBackoff = 100 * time.Millisecond
+	// CPU samples older than this are dropped.
+	cpuSampleExpiry = 10 * time.Minute
+)
+
 type elasticStorage struct {
-	client      *elastic.Client
-	machineName string
-	indexName   string
-	typeName    string
-	lock        sync.Mutex
+	client         *http.Client
+	hosts          []string
+	host           int
+	apiKey         string
+	username       string
+	password       string
+	machineName    string
+	target         string
+	useDataStream  bool
+	dataStream     *dataStream
+	bulkSize       int
+	maxRetries     int
+	backoff        time.Duration
+	cores          int
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	docs           []*document
+	cpuSamples     map[string]cpuSample
+	lock           sync.Mutex
+	hostLock       sync.Mutex
+	readyToFlush   func() bool
 }
 
-type detailSpec struct {
-	Timestamp      int64                `json:"timestamp"`
-	MachineName    string               `json:"machine_name,omitempty"`
-	ContainerName  string               `json:"container_Name,omitempty"`
-	ContainerStats *info.ContainerStats `json:"container_stats,omitempty"`
+// Options of the ElasticSearch storage.
+type options struct {
+	hosts          []string
+	index          string
+	dataStream     string
+	ilmPolicy      string
+	apiKey         string
+	username       string
+	password       string
+	bulkSize       int
+	maxRetries     int
+	bufferDuration time.Duration
 }
 
-var (
-	argElasticHost   = flag.String("storage_driver_es_host", "http://localhost:9200", "ElasticSearch host:port")
-	argIndexName     = flag.String("storage_driver_es_index", "cadvisor", "ElasticSearch index name")
-	argTypeName      = flag.String("storage_driver_es_type", "stats", "ElasticSearch type name")
-	argEnableSniffer = flag.Bool("storage_driver_es_enable_sniffer", false, "ElasticSearch uses a sniffing process to find all nodes of your cluster by default, automatically")
-)
-
 func new() (storage.StorageDriver, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
 		return nil, err
 	}
-	return newStorage(
-		hostname,
-		*argIndexName,
-		*argTypeName,
-		*argElasticHost,
-		*argEnableSniffer,
-	)
+	return newStorage(hostname, options{
+		hosts:          strings.Split(*argElasticHost, ","),
+		index:          *argIndexName,
+		dataStream:     *argDataStream,
+		ilmPolicy:      *argILMPolicy,
+		apiKey:         *argAPIKey,
+		username:       *argUsername,
+		password:       *argPassword,
+		bulkSize:       *argBulkSize,
+		maxRetries:     *argMaxRetries,
+		bufferDuration: *storage.ArgDbBufferDuration,
+	})
 }
 
-func (s *elasticStorage) containerStatsAndDefaultValues(
-	cInfo *info.ContainerInfo, stats *info.ContainerStats) *detailSpec {
-	timestamp := stats.Timestamp.UnixNano() / 1e3
-	var containerName string
-	if len(cInfo.ContainerReference.Aliases) > 0 {
-		containerName = cInfo.ContainerReference.Aliases[0]
-	} else {
-		containerName = cInfo.ContainerReference.Name
-	}
-	detail := &detailSpec{
-		Timestamp:      timestamp,
-		MachineName:    s.machineName,
-		ContainerName:  containerName,
-		ContainerStats: stats,
-	}
-	return detail
+func (s *elasticStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
 }
 
 func (s *elasticStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
 	if stats == nil {
 		return nil
 	}
+	var docsToFlush []*document
 	func() {
 		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
 		s.lock.Lock()
 		defer s.lock.Unlock()
-		// Add some default params based on ContainerStats
-		detail := s.containerStatsAndDefaultValues(cInfo, stats)
-		// Index a cadvisor (using JSON serialization)
-		_, err := s.client.Index().
-			Index(s.indexName).
-			Type(s.typeName).
-			BodyJson(detail).
-			Do()
-		if err != nil {
-			// Handle error
-			fmt.Printf("failed to write stats to ElasticSearch - %s", err)
-			return
+
+		s.docs = append(s.docs, s.newDocument(cInfo, stats))
+		if s.readyToFlush() {
+			docsToFlush = s.docs
+			s.docs = make([]*document, 0)
+			s.lastWrite = time.Now()
+			for name, sample := range s.cpuSamples {
+				if time.Since(sample.timestamp) > cpuSampleExpiry {
+					delete(s.cpuSamples, name)
+				}
+			}
+		}
+	}()
+	return s.write(docsToFlush)
+}
+
+// Builds the document of stats. Must be called with the lock held.
+func (s *elasticStorage) newDocument(cInfo *info.ContainerInfo, stats *info.ContainerStats) *document {
+	name := cInfo.ContainerReference.Name
+	var prev *cpuSample
+	if sample, ok := s.cpuSamples[name]; ok {
+		prev = &sample
+	}
+	s.cpuSamples[name] = cpuSample{timestamp: stats.Timestamp, total: stats.Cpu.Usage.Total}
+	return newDocument(s.machineName, s.dataStream, cInfo, stats, prev, s.cores)
+}
+
+// WriteBatch writes the documents of samples, bypassing the buffer.
+func (s *elasticStorage) WriteBatch(samples []storage.Sample) error {
+	docs := make([]*document, 0, len(samples))
+	func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		for _, sample := range samples {
+			docs = append(docs, s.newDocument(sample.ContainerInfo, sample.Stats))
 		}
 	}()
+	return s.write(docs)
+}
+
+// Writes docs in bulk requests of at most bulkSize documents.
+func (s *elasticStorage) write(docs []*document) error {
+	for len(docs) > 0 {
+		n := len(docs)
+		if n > s.bulkSize {
+			n = s.bulkSize
+		}
+		if err := s.bulk(docs[:n]); err != nil {
+			return err
+		}
+		docs = docs[n:]
+	}
+	return nil
+}
+
+// bulkResponse is the response of the _bulk API.
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkItemResponse `json:"items"`
+}
+
+type bulkItemResponse struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+// Returns whether a request or document rejected with status may succeed
+// when retried.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// Writes docs with the _bulk API, retrying the documents rejected because
+// the cluster is overloaded with exponential backoff.
+func (s *elasticStorage) bulk(docs []*document) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		rejected, err := s.tryBulk(docs)
+		if err == nil {
+			return nil
+		}
+		if len(rejected) == 0 || attempt >= s.maxRetries {
+			return fmt.Errorf("failed to write stats to ElasticSearch: %v", err)
+		}
+		klog.V(4).Infof("Retrying %d documents rejected by ElasticSearch in %v: %v", len(rejected), backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		docs = rejected
+	}
+}
+
+// Sends a single bulk request. On failure it returns the documents worth
+// retrying along with the error.
+func (s *elasticStorage) tryBulk(docs []*document) ([]*document, error) {
+	// Data streams only accept the create action.
+	action := "index"
+	if s.useDataStream {
+		action = "create"
+	}
+	meta, err := json.Marshal(map[string]interface{}{action: map[string]string{"_index": s.target}})
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		body.Write(meta)
+		body.WriteByte('\n')
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := s.do(http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return docs, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := responseError(resp)
+		if retryable(resp.StatusCode) {
+			return docs, err
+		}
+		return nil, err
+	}
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid bulk response: %v", err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+
+	var rejected []*document
+	failed := 0
+	var firstErr string
+	for i, item := range result.Items {
+		for _, r := range item {
+			if r.Error == nil {
+				continue
+			}
+			failed++
+			if firstErr == "" {
+				firstErr = fmt.Sprintf("%s: %s", r.Error.Type, r.Error.Reason)
+			}
+			if retryable(r.Status) && i < len(docs) {
+				rejected = append(rejected, docs[i])
+			}
+		}
+	}
+	if failed == 0 {
+		return nil, nil
+	}
+	return rejected, fmt.Errorf("%d of %d documents failed, first error: %s", failed, len(docs), firstErr)
+}
+
+// Sends a request to the current host, moving on to the next host when it
+// can't be reached or is unavailable.
+func (s *elasticStorage) do(method, path, contentType string, body []byte) (*http.Response, error) {
+	s.hostLock.Lock()
+	host := s.host
+	s.hostLock.Unlock()
+
+	var lastErr error
+	for i := 0; i < len(s.hosts); i++ {
+		current := (host + i) % len(s.hosts)
+		req, err := http.NewRequest(method, s.hosts[current]+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if s.apiKey != "" {
+			req.Header.Set("Authorization", "ApiKey "+s.apiKey)
+		} else if s.username != "" {
+			req.SetBasicAuth(s.username, s.password)
+		}
+		resp, err := s.client.Do(req)
+		if err == nil && resp.StatusCode != http.StatusServiceUnavailable {
+			s.hostLock.Lock()
+			s.host = current
+			s.hostLock.Unlock()
+			return resp, nil
+		}
+		if err != nil {
+			lastErr = err
+		} else {
+			lastErr = responseError(resp)
+			resp.Body.Close()
+		}
+		klog.V(4).Infof("ElasticSearch host %s failed: %v", s.hosts[current], lastErr)
+	}
+	return nil, lastErr
+}
+
+// Reads the error of a failed request.
+func responseError(resp *http.Response) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+// Installs an index template creating the data stream with the given ILM
+// policy. It builds on the metrics mappings and settings that ElasticSearch
+// ships for data streams.
+func (s *elasticStorage) installIndexTemplate(policy string) error {
+	template := map[string]interface{}{
+		"index_patterns": []string{s.target},
+		"data_stream":    map[string]interface{}{},
+		"priority":       200,
+		"composed_of":    []string{"metrics-mappings", "metrics-settings"},
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"index.lifecycle.name": policy,
+			},
+		},
+		"_meta": map[string]interface{}{
+			"managed_by": "cadvisor",
+		},
+	}
+	body, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, "/_index_template/"+s.target, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
 	return nil
 }
 
 func (s *elasticStorage) Close() error {
-	s.client = nil
+	s.client.CloseIdleConnections()
 	return nil
 }
 
 // machineName: A unique identifier to identify the host that current cAdvisor
 // instance is running on.
-// ElasticHost: The host which runs ElasticSearch.
-func newStorage(
-	machineName,
-	indexName,
-	typeName,
-	elasticHost string,
-	enableSniffer bool,
-) (storage.StorageDriver, error) {
-	// Obtain a client and connect to the default Elasticsearch installation
-	// on 127.0.0.1:9200. Of course you can configure your client to connect
-	// to other hosts and configure it in various other ways.
-	client, err := elastic.NewClient(
-		elastic.SetHealthcheck(true),
-		elastic.SetSniff(enableSniffer),
-		elastic.SetHealthcheckInterval(30*time.Second),
-		elastic.SetURL(elasticHost),
-	)
-	if err != nil {
-		// Handle error
-		return nil, fmt.Errorf("failed to create the elasticsearch client - %s", err)
+func newStorage(machineName string, opts options) (*elasticStorage, error) {
+	var hosts []string
+	for _, host := range opts.hosts {
+		if host = strings.TrimRight(strings.TrimSpace(host), "/"); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("no ElasticSearch host is set")
+	}
+	if opts.bulkSize <= 0 {
+		return nil, fmt.Errorf("invalid ElasticSearch bulk size %d", opts.bulkSize)
+	}
+	ret := &elasticStorage{
+		client:         &http.Client{Timeout: requestTimeout},
+		hosts:          hosts,
+		apiKey:         opts.apiKey,
+		username:       opts.username,
+		password:       opts.password,
+		machineName:    machineName,
+		target:         opts.index,
+		bulkSize:       opts.bulkSize,
+		maxRetries:     opts.maxRetries,
+		backoff:        initialBackoff,
+		cores:          runtime.NumCPU(),
+		bufferDuration: opts.bufferDuration,
+		lastWrite:      time.Now(),
+		docs:           make([]*document, 0),
+		cpuSamples:     make(map[string]cpuSample),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	if opts.dataStream != "" {
+		ret.target = opts.dataStream
+		ret.useDataStream = true
+		// The data_stream fields are only set when the name follows the
+		// <type>-<dataset>-<namespace> naming scheme.
+		ret.dataStream = parseDataStream(opts.dataStream)
+	} else if opts.ilmPolicy != "" {
+		return nil, fmt.Errorf("an ElasticSearch ILM policy requires a data stream")
 	}
 
-	// Ping the Elasticsearch server to get e.g. the version number
-	info, code, err := client.Ping().URL(elasticHost).Do()
+	// Ping the cluster to fail early on a wrong address or credentials.
+	resp, err := ret.do(http.MethodGet, "/", "", nil)
 	if err != nil {
-		// Handle error
-		return nil, fmt.Errorf("failed to ping the elasticsearch - %s", err)
-
+		return nil, fmt.Errorf("failed to ping ElasticSearch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to ping ElasticSearch: %v", responseError(resp))
+	}
+	var ping struct {
+		Version struct {
+			Number string `json:"number"`
+		} `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ping); err == nil {
+		klog.Infof("Connected to ElasticSearch %s", ping.Version.Number)
 	}
-	fmt.Printf("Elasticsearch returned with code %d and version %s", code, info.Version.Number)
 
-	ret := &elasticStorage{
-		client:      client,
-		machineName: machineName,
-		indexName:   indexName,
-		typeName:    typeName,
+	if opts.ilmPolicy != "" {
+		if err := ret.installIndexTemplate(opts.ilmPolicy); err != nil {
+			return nil, fmt.Errorf("failed to install the ElasticSearch index template: %v", err)
+		}
 	}
 	return ret, nil
 }
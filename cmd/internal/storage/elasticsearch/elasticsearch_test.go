@@ -0,0 +1,332 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// elasticServer fakes the parts of the ElasticSearch REST API used by the
+// driver.
+type elasticServer struct {
+	lock      sync.Mutex
+	actions   []map[string]map[string]string
+	docs      []map[string]interface{}
+	templates map[string]map[string]interface{}
+	// Statuses of the documents of the next bulk requests, 0 for success.
+	statuses [][]int
+	// Status of the next bulk requests, 0 for success.
+	bulkStatuses []int
+	bulkRequests int
+}
+
+func (s *elasticServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if r.Header.Get("Authorization") != "ApiKey a2V5OnNlY3JldA==" {
+		http.Error(w, `{"error":"missing authentication credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/":
+		w.Write([]byte(`{"version":{"number":"7.12.0"}}`))
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/_index_template/"):
+		var template map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.templates[strings.TrimPrefix(r.URL.Path, "/_index_template/")] = template
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/_bulk":
+		s.bulk(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *elasticServer) bulk(w http.ResponseWriter, r *http.Request) {
+	s.bulkRequests++
+	if len(s.bulkStatuses) > 0 {
+		status := s.bulkStatuses[0]
+		s.bulkStatuses = s.bulkStatuses[1:]
+		if status != 0 {
+			http.Error(w, `{"error":"rejected"}`, status)
+			return
+		}
+	}
+	if r.Header.Get("Content-Type") != "application/x-ndjson" {
+		http.Error(w, "wrong content type", http.StatusBadRequest)
+		return
+	}
+	var statuses []int
+	if len(s.statuses) > 0 {
+		statuses = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	type item struct {
+		Status int                    `json:"status"`
+		Error  map[string]interface{} `json:"error,omitempty"`
+	}
+	var resp struct {
+		Errors bool              `json:"errors"`
+		Items  []map[string]item `json:"items"`
+	}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1<<20)
+	for i := 0; scanner.Scan(); i++ {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			http.Error(w, "invalid action", http.StatusBadRequest)
+			return
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := item{Status: http.StatusCreated}
+		if i < len(statuses) && statuses[i] != 0 {
+			result = item{Status: statuses[i], Error: map[string]interface{}{"type": "es_rejected_execution_exception", "reason": "rejected"}}
+			resp.Errors = true
+		} else {
+			s.actions = append(s.actions, action)
+			s.docs = append(s.docs, doc)
+		}
+		for op := range action {
+			resp.Items = append(resp.Items, map[string]item{op: result})
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newTestServer() (*elasticServer, *httptest.Server) {
+	es := &elasticServer{templates: map[string]map[string]interface{}{}}
+	return es, httptest.NewServer(es)
+}
+
+func testOptions(hosts ...string) options {
+	return options{
+		hosts:          hosts,
+		index:          "cadvisor",
+		apiKey:         "a2V5OnNlY3JldA==",
+		bulkSize:       1000,
+		maxRetries:     3,
+		bufferDuration: time.Minute,
+	}
+}
+
+func newTestStorage(t *testing.T, opts options, buffer int) *elasticStorage {
+	s, err := newStorage("machine", opts)
+	require.NoError(t, err)
+	s.backoff = time.Millisecond
+	count := 0
+	s.readyToFlush = func() bool {
+		count++
+		return count%buffer == 0
+	}
+	return s
+}
+
+func testStats(timestamp time.Time, cpu uint64) (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Id: "abc", Aliases: []string{"web"}, Namespace: "docker"},
+		Spec: info.ContainerSpec{
+			Image:     "registry:5000/nginx:1.19",
+			Labels:    map[string]string{"app": "web"},
+			HasMemory: true,
+			Memory:    info.MemorySpec{Limit: 4096},
+		},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: timestamp,
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: cpu}},
+		Memory:    info.MemoryStats{Usage: 1024},
+		Network:   info.NetworkStats{InterfaceStats: info.InterfaceStats{RxBytes: 10, TxBytes: 20}},
+		DiskIo: info.DiskIoStats{IoServiceBytes: []info.PerDiskStats{
+			{Device: "/dev/sda", Stats: map[string]uint64{"Read": 1, "Write": 2}},
+			{Device: "/dev/sdb", Stats: map[string]uint64{"Read": 3, "Write": 4}},
+		}},
+	}
+	return cInfo, stats
+}
+
+func TestECSDocument(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	s := newTestStorage(t, testOptions(ts.URL), 2)
+	s.cores = 2
+
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.AddStats(testStats(now, 1000000000)))
+	require.NoError(t, s.AddStats(testStats(now.Add(time.Second), 2000000000)))
+
+	require.Len(t, es.docs, 2)
+	assert.Equal(t, map[string]map[string]string{"index": {"_index": "cadvisor"}}, es.actions[0])
+	doc := es.docs[1]
+	assert.Equal(t, "2021-03-01T12:00:01Z", doc["@timestamp"])
+	assert.Equal(t, map[string]interface{}{"name": "machine", "hostname": "machine"}, doc["host"])
+	assert.NotContains(t, doc, "data_stream")
+	container := doc["container"].(map[string]interface{})
+	assert.Equal(t, "abc", container["id"])
+	assert.Equal(t, "web", container["name"])
+	assert.Equal(t, "docker", container["runtime"])
+	assert.Equal(t, map[string]interface{}{"name": "registry:5000/nginx", "tag": []interface{}{"1.19"}}, container["image"])
+	assert.Equal(t, map[string]interface{}{"app": "web"}, container["labels"])
+	// One second of CPU time used in one second, on two cores.
+	assert.Equal(t, map[string]interface{}{"usage": 0.5}, container["cpu"])
+	assert.Equal(t, map[string]interface{}{"usage": 0.25}, container["memory"])
+	assert.Equal(t, map[string]interface{}{
+		"ingress": map[string]interface{}{"bytes": 10.0},
+		"egress":  map[string]interface{}{"bytes": 20.0},
+	}, container["network"])
+	assert.Equal(t, map[string]interface{}{
+		"read":  map[string]interface{}{"bytes": 4.0},
+		"write": map[string]interface{}{"bytes": 6.0},
+	}, container["disk"])
+	stats := doc["cadvisor"].(map[string]interface{})["stats"].(map[string]interface{})
+	assert.Equal(t, 2000000000.0, stats["cpu"].(map[string]interface{})["usage"].(map[string]interface{})["total"])
+	// No previous sample to compute the CPU usage of the first document.
+	assert.NotContains(t, es.docs[0]["container"], "cpu")
+}
+
+func TestDataStream(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	opts := testOptions(ts.URL)
+	opts.dataStream = "metrics-cadvisor-default"
+	opts.ilmPolicy = "cadvisor"
+	s := newTestStorage(t, opts, 1)
+
+	require.Contains(t, es.templates, "metrics-cadvisor-default")
+	template := es.templates["metrics-cadvisor-default"]
+	assert.Equal(t, []interface{}{"metrics-cadvisor-default"}, template["index_patterns"])
+	assert.Equal(t, map[string]interface{}{"index.lifecycle.name": "cadvisor"}, template["template"].(map[string]interface{})["settings"])
+
+	require.NoError(t, s.AddStats(testStats(time.Now(), 0)))
+	require.Len(t, es.docs, 1)
+	assert.Equal(t, map[string]map[string]string{"create": {"_index": "metrics-cadvisor-default"}}, es.actions[0])
+	assert.Equal(t, map[string]interface{}{"type": "metrics", "dataset": "cadvisor", "namespace": "default"}, es.docs[0]["data_stream"])
+}
+
+func TestILMPolicyRequiresDataStream(t *testing.T) {
+	_, ts := newTestServer()
+	defer ts.Close()
+	opts := testOptions(ts.URL)
+	opts.ilmPolicy = "cadvisor"
+	_, err := newStorage("machine", opts)
+	assert.Error(t, err)
+}
+
+func TestAuthentication(t *testing.T) {
+	_, ts := newTestServer()
+	defer ts.Close()
+	opts := testOptions(ts.URL)
+	opts.apiKey = "wrong"
+	_, err := newStorage("machine", opts)
+	assert.Error(t, err)
+}
+
+func TestBulkSize(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	opts := testOptions(ts.URL)
+	opts.bulkSize = 2
+	s := newTestStorage(t, opts, 5)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.AddStats(testStats(time.Now(), 0)))
+	}
+	assert.Len(t, es.docs, 5)
+	assert.Equal(t, 3, es.bulkRequests)
+}
+
+func TestRetryRejectedDocuments(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	s := newTestStorage(t, testOptions(ts.URL), 3)
+	es.bulkStatuses = []int{http.StatusTooManyRequests}
+	es.statuses = [][]int{{0, http.StatusTooManyRequests, 0}}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.AddStats(testStats(time.Now(), 0)))
+	}
+	// The whole request is retried first, then only the rejected document.
+	assert.Equal(t, 3, es.bulkRequests)
+	assert.Len(t, es.docs, 3)
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	s := newTestStorage(t, testOptions(ts.URL), 1)
+	es.bulkStatuses = []int{503, 503, 503, 503}
+
+	assert.Error(t, s.AddStats(testStats(time.Now(), 0)))
+	assert.Equal(t, 4, es.bulkRequests)
+	assert.Empty(t, es.docs)
+}
+
+func TestDocumentErrorNotRetried(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	s := newTestStorage(t, testOptions(ts.URL), 1)
+	es.statuses = [][]int{{http.StatusBadRequest}}
+
+	err := s.AddStats(testStats(time.Now(), 0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 1 documents failed")
+	assert.Equal(t, 1, es.bulkRequests)
+}
+
+func TestHostFailover(t *testing.T) {
+	es, ts := newTestServer()
+	defer ts.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	s := newTestStorage(t, testOptions(down.URL, ts.URL), 1)
+
+	require.NoError(t, s.AddStats(testStats(time.Now(), 0)))
+	assert.Len(t, es.docs, 1)
+	assert.Equal(t, 1, s.host)
+}
+
+func TestParseImage(t *testing.T) {
+	assert.Nil(t, parseImage(""))
+	assert.Equal(t, &image{Name: "nginx"}, parseImage("nginx"))
+	assert.Equal(t, &image{Name: "nginx", Tag: []string{"latest"}}, parseImage("nginx:latest"))
+	assert.Equal(t, &image{Name: "localhost:5000/nginx"}, parseImage("localhost:5000/nginx"))
+	assert.Equal(t, &image{Name: "nginx"}, parseImage("nginx@sha256:abcd"))
+}
+
+func TestParseDataStream(t *testing.T) {
+	assert.Equal(t, &dataStream{Type: "metrics", Dataset: "cadvisor", Namespace: "prod"}, parseDataStream("metrics-cadvisor-prod"))
+	assert.Nil(t, parseDataStream("cadvisor"))
+	assert.Nil(t, parseDataStream("metrics--prod"))
+}
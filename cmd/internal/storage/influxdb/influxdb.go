@@ -45,6 +45,8 @@ type influxdbStorage struct {
 	points          []*influxdb.Point
 	lock            sync.Mutex
 	readyToFlush    func() bool
+	// Writes a batch of points, set by the constructor of each driver.
+	writePoints func(points []*influxdb.Point) error
 }
 
 // Series names
@@ -383,6 +385,18 @@ func (s *influxdbStorage) defaultReadyToFlush() bool {
 	return time.Since(s.lastWrite) >= s.bufferDuration
 }
 
+// Returns all the points of stats.
+func (s *influxdbStorage) statsToPoints(cInfo *info.ContainerInfo, stats *info.ContainerStats) []*influxdb.Point {
+	var points []*influxdb.Point
+	points = append(points, s.containerStatsToPoints(cInfo, stats)...)
+	points = append(points, s.memoryStatsToPoints(cInfo, stats)...)
+	points = append(points, s.hugetlbStatsToPoints(cInfo, stats)...)
+	points = append(points, s.perfStatsToPoints(cInfo, stats)...)
+	points = append(points, s.resctrlStatsToPoints(cInfo, stats)...)
+	points = append(points, s.containerFilesystemStatsToPoints(cInfo, stats)...)
+	return points
+}
+
 func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
 	if stats == nil {
 		return nil
@@ -393,12 +407,7 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 		s.lock.Lock()
 		defer s.lock.Unlock()
 
-		s.points = append(s.points, s.containerStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.memoryStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.hugetlbStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.perfStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.resctrlStatsToPoints(cInfo, stats)...)
-		s.points = append(s.points, s.containerFilesystemStatsToPoints(cInfo, stats)...)
+		s.points = append(s.points, s.statsToPoints(cInfo, stats)...)
 		if s.readyToFlush() {
 			pointsToFlush = s.points
 			s.points = make([]*influxdb.Point, 0)
@@ -406,23 +415,40 @@ func (s *influxdbStorage) AddStats(cInfo *info.ContainerInfo, stats *info.Contai
 		}
 	}()
 	if len(pointsToFlush) > 0 {
-		points := make([]influxdb.Point, len(pointsToFlush))
-		for i, p := range pointsToFlush {
-			points[i] = *p
-		}
+		return s.writePoints(pointsToFlush)
+	}
+	return nil
+}
 
-		batchTags := map[string]string{tagMachineName: s.machineName}
-		bp := influxdb.BatchPoints{
-			Points:          points,
-			Database:        s.database,
-			RetentionPolicy: s.retentionPolicy,
-			Tags:            batchTags,
-			Time:            stats.Timestamp,
-		}
-		response, err := s.client.Write(bp)
-		if err != nil || checkResponseForErrors(response) != nil {
-			return fmt.Errorf("failed to write stats to influxDb - %s", err)
-		}
+// WriteBatch writes the points of samples at once, bypassing the buffer.
+func (s *influxdbStorage) WriteBatch(samples []storage.Sample) error {
+	var points []*influxdb.Point
+	for _, sample := range samples {
+		points = append(points, s.statsToPoints(sample.ContainerInfo, sample.Stats)...)
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return s.writePoints(points)
+}
+
+// Writes points to InfluxDB 1.x.
+func (s *influxdbStorage) writeBatchPoints(pointsToFlush []*influxdb.Point) error {
+	points := make([]influxdb.Point, len(pointsToFlush))
+	for i, p := range pointsToFlush {
+		points[i] = *p
+	}
+
+	batchTags := map[string]string{tagMachineName: s.machineName}
+	bp := influxdb.BatchPoints{
+		Points:          points,
+		Database:        s.database,
+		RetentionPolicy: s.retentionPolicy,
+		Tags:            batchTags,
+	}
+	response, err := s.client.Write(bp)
+	if err != nil || checkResponseForErrors(response) != nil {
+		return fmt.Errorf("failed to write stats to influxDb - %s", err)
 	}
 	return nil
 }
@@ -475,6 +501,7 @@ func newStorage(
 		points:          make([]*influxdb.Point, 0),
 	}
 	ret.readyToFlush = ret.defaultReadyToFlush
+	ret.writePoints = ret.writeBatchPoints
 	return ret, nil
 }
 
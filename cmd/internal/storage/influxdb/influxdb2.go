@@ -0,0 +1,203 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
+
+	influxdb "github.com/influxdb/influxdb/client"
+)
+
+func init() {
+	storage.RegisterStorageDriver("influxdb2", newV2)
+}
+
+var (
+	argOrg       = flag.String("storage_driver_influxdb2_org", "", "InfluxDB 2.x organization")
+	argBucket    = flag.String("storage_driver_influxdb2_bucket", "cadvisor", "InfluxDB 2.x bucket")
+	argToken     = flag.String("storage_driver_influxdb2_token", "", "InfluxDB 2.x API token, defaults to the INFLUX_TOKEN environment variable")
+	argBatchSize = flag.Int("storage_driver_influxdb2_batch_size", 5000, "Maximum number of points in a single InfluxDB 2.x write request")
+	argGzip      = flag.Bool("storage_driver_influxdb2_gzip", true, "Compress InfluxDB 2.x write requests with gzip")
+)
+
+// Timeout of a single write request.
+const writeTimeout = 30 * time.Second
+
+// Maximum length of an error response body included in errors.
+const maxErrorBodySize = 1024
+
+func newV2() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	token := *argToken
+	if token == "" {
+		token = os.Getenv("INFLUX_TOKEN")
+	}
+	return newStorageV2(
+		hostname,
+		*argOrg,
+		*argBucket,
+		token,
+		*storage.ArgDbHost,
+		*storage.ArgDbIsSecure,
+		*storage.ArgDbBufferDuration,
+		*argBatchSize,
+		*argGzip,
+	)
+}
+
+// influxdb2Writer writes points to the InfluxDB 2.x write API in line
+// protocol.
+type influxdb2Writer struct {
+	client    *http.Client
+	writeURL  string
+	token     string
+	userAgent string
+	batchSize int
+	gzip      bool
+}
+
+// Writes points in batches of at most batchSize points.
+func (w *influxdb2Writer) writePoints(points []*influxdb.Point) error {
+	for len(points) > 0 {
+		n := w.batchSize
+		if n > len(points) {
+			n = len(points)
+		}
+		if err := w.writeBatch(points[:n]); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
+
+func (w *influxdb2Writer) writeBatch(points []*influxdb.Point) error {
+	var body bytes.Buffer
+	var out io.Writer = &body
+	var gz *gzip.Writer
+	if w.gzip {
+		gz = gzip.NewWriter(&body)
+		out = gz
+	}
+	for _, p := range points {
+		if _, err := io.WriteString(out, p.MarshalString()+"\n"); err != nil {
+			return err
+		}
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+w.token)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("User-Agent", w.userAgent)
+	if w.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write stats to InfluxDB: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("failed to write stats to InfluxDB: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	// Drain the body to reuse the connection.
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// machineName: A unique identifier to identify the host that current cAdvisor
+// instance is running on.
+// influxdbHost: The host which runs InfluxDB 2.x (host:port)
+func newStorageV2(
+	machineName,
+	org,
+	bucket,
+	token,
+	influxdbHost string,
+	isSecure bool,
+	bufferDuration time.Duration,
+	batchSize int,
+	gzip bool,
+) (*influxdbStorage, error) {
+	if org == "" {
+		return nil, fmt.Errorf("InfluxDB 2.x organization is not set")
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("InfluxDB 2.x bucket is not set")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("InfluxDB 2.x token is not set")
+	}
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid InfluxDB 2.x batch size %d", batchSize)
+	}
+
+	query := url.Values{}
+	query.Set("org", org)
+	query.Set("bucket", bucket)
+	query.Set("precision", "ns")
+	writeURL := &url.URL{
+		Scheme:   "http",
+		Host:     influxdbHost,
+		Path:     "/api/v2/write",
+		RawQuery: query.Encode(),
+	}
+	if isSecure {
+		writeURL.Scheme = "https"
+	}
+	writer := &influxdb2Writer{
+		client:    &http.Client{Timeout: writeTimeout},
+		writeURL:  writeURL.String(),
+		token:     token,
+		userAgent: fmt.Sprintf("%v/%v", "cAdvisor", version.Info["version"]),
+		batchSize: batchSize,
+		gzip:      gzip,
+	}
+
+	ret := &influxdbStorage{
+		machineName:    machineName,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+		points:         make([]*influxdb.Point, 0),
+		writePoints:    writer.writePoints,
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret, nil
+}
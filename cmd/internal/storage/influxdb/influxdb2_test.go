@@ -0,0 +1,135 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package influxdb
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type influxdb2Server struct {
+	lock     sync.Mutex
+	requests [][]string
+	status   int
+}
+
+func (s *influxdb2Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if r.URL.Path != "/api/v2/write" || r.Header.Get("Authorization") != "Token secret" {
+		http.Error(w, `{"code":"unauthorized","message":"unauthorized access"}`, http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("org") != "cadvisor-org" || query.Get("bucket") != "stats" || query.Get("precision") != "ns" {
+		http.Error(w, `{"code":"not found","message":"bucket not found"}`, http.StatusNotFound)
+		return
+	}
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, strings.Split(strings.TrimSpace(string(data)), "\n"))
+	if s.status != 0 {
+		http.Error(w, `{"code":"invalid","message":"partial write"}`, s.status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newTestStorageV2(t *testing.T, server *httptest.Server, token string, batchSize int, gzip bool) *influxdbStorage {
+	host := strings.TrimPrefix(server.URL, "http://")
+	s, err := newStorageV2("testMachine", "cadvisor-org", "stats", token, host, false, 0, batchSize, gzip)
+	require.NoError(t, err)
+	return s
+}
+
+func testContainerInfo() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Aliases: []string{"web"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1600000000, 0),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1000, PerCpu: []uint64{400, 600}}},
+		Memory:    info.MemoryStats{Usage: 2048},
+	}
+	return cInfo, stats
+}
+
+func TestInfluxdb2Write(t *testing.T) {
+	for _, gzip := range []bool{true, false} {
+		server := &influxdb2Server{}
+		ts := httptest.NewServer(server)
+		defer ts.Close()
+
+		s := newTestStorageV2(t, ts, "secret", 10, gzip)
+		cInfo, stats := testContainerInfo()
+		require.NoError(t, s.AddStats(cInfo, stats))
+
+		var lines []string
+		for _, request := range server.requests {
+			assert.True(t, len(request) <= 10)
+			lines = append(lines, request...)
+		}
+		assert.Len(t, server.requests, (len(lines)+9)/10)
+		assert.Contains(t, lines, "cpu_usage_total,container_name=web,machine=testMachine value=1000i 1600000000000000000")
+		assert.Contains(t, lines, "cpu_usage_per_cpu,container_name=web,instance=1,machine=testMachine value=600i 1600000000000000000")
+		assert.Contains(t, lines, "memory_usage,container_name=web,machine=testMachine value=2048i 1600000000000000000")
+	}
+}
+
+func TestInfluxdb2WriteErrors(t *testing.T) {
+	server := &influxdb2Server{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cInfo, stats := testContainerInfo()
+	err := newTestStorageV2(t, ts, "wrong", 5000, true).AddStats(cInfo, stats)
+	assert.EqualError(t, err, `failed to write stats to InfluxDB: 401 Unauthorized: {"code":"unauthorized","message":"unauthorized access"}`)
+
+	server.status = http.StatusBadRequest
+	err = newTestStorageV2(t, ts, "secret", 5000, true).AddStats(cInfo, stats)
+	assert.EqualError(t, err, `failed to write stats to InfluxDB: 400 Bad Request: {"code":"invalid","message":"partial write"}`)
+}
+
+func TestNewStorageV2Validation(t *testing.T) {
+	_, err := newStorageV2("m", "", "stats", "secret", "localhost:8086", false, time.Minute, 5000, true)
+	assert.Error(t, err)
+	_, err = newStorageV2("m", "org", "stats", "", "localhost:8086", false, time.Minute, 5000, true)
+	assert.Error(t, err)
+	_, err = newStorageV2("m", "org", "stats", "secret", "localhost:8086", false, time.Minute, 0, true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Encodings of the stats.
+const (
+	encodingJSON     = "json"
+	encodingAvro     = "avro"
+	encodingProtobuf = "protobuf"
+)
+
+// Schema types of the schema registry.
+const (
+	schemaTypeAvro     = "AVRO"
+	schemaTypeProtobuf = "PROTOBUF"
+)
+
+// First byte of messages in the Confluent wire format.
+const wireFormatMagicByte = 0
+
+// Timeout of schema registry requests.
+const schemaRegistryTimeout = 10 * time.Second
+
+// schemaRegistry is a client of the Confluent Schema Registry.
+type schemaRegistry struct {
+	client   *http.Client
+	url      string
+	user     string
+	password string
+}
+
+func newSchemaRegistry(url, user, password string) *schemaRegistry {
+	return &schemaRegistry{
+		client:   &http.Client{Timeout: schemaRegistryTimeout},
+		url:      strings.TrimSuffix(url, "/"),
+		user:     user,
+		password: password,
+	}
+}
+
+// Registers schema under subject and returns its ID. Registering a schema
+// which is already registered returns the existing ID.
+func (r *schemaRegistry) register(subject, schemaType, schema string) (int, error) {
+	request := struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType,omitempty"`
+	}{Schema: schema}
+	// Registries which predate protobuf support only accept Avro schemas
+	// without a type.
+	if schemaType != schemaTypeAvro {
+		request.SchemaType = schemaType
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/subjects/%s/versions", r.url, url.PathEscape(subject)), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.user != "" {
+		req.SetBasicAuth(r.user, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema of subject %q: %v", subject, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf("failed to register schema of subject %q: %s: %s", subject, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var response struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode schema registry response: %v", err)
+	}
+	return response.ID, nil
+}
+
+// Prefixes payload with the Confluent wire format header of schemaID.
+// messageIndexes locate the message in protobuf schemas and are nil for other
+// schema types.
+func wireFormat(schemaID int, messageIndexes []int, payload []byte) []byte {
+	out := make([]byte, 5, 5+len(payload)+binary.MaxVarintLen64*(len(messageIndexes)+1))
+	out[0] = wireFormatMagicByte
+	binary.BigEndian.PutUint32(out[1:5], uint32(schemaID))
+	if messageIndexes != nil {
+		var buf [binary.MaxVarintLen64]byte
+		if len(messageIndexes) == 1 && messageIndexes[0] == 0 {
+			// The first message is encoded as a single zero.
+			out = append(out, 0)
+		} else {
+			out = append(out, buf[:binary.PutVarint(buf[:], int64(len(messageIndexes)))]...)
+			for _, index := range messageIndexes {
+				out = append(out, buf[:binary.PutVarint(buf[:], int64(index))]...)
+			}
+		}
+	}
+	return append(out, payload...)
+}
+
+// Sets up the encoding of stats, registering its schema under subject if a
+// registry is given.
+func (s *kafkaStorage) setEncoding(encoding string, registry *schemaRegistry, subject string) error {
+	switch encoding {
+	case encodingJSON:
+		s.encode = s.encodeJSON
+	case encodingAvro:
+		codec, err := goavro.NewCodec(avroSchema)
+		if err != nil {
+			return err
+		}
+		s.avroCodec = codec
+		s.encode = s.encodeAvro
+	case encodingProtobuf:
+		s.encode = s.encodeProtobuf
+	default:
+		return fmt.Errorf("unknown encoding %q, expected %q, %q or %q", encoding, encodingJSON, encodingAvro, encodingProtobuf)
+	}
+	if registry == nil || encoding == encodingJSON {
+		return nil
+	}
+	schemaType, schema := schemaTypeAvro, avroSchema
+	if encoding == encodingProtobuf {
+		schemaType, schema = schemaTypeProtobuf, protobufSchema
+	}
+	id, err := registry.register(subject, schemaType, schema)
+	if err != nil {
+		return err
+	}
+	s.schemaID = &id
+	return nil
+}
+
+func (s *kafkaStorage) encodeJSON(cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error) {
+	return json.Marshal(s.infoToDetailSpec(cInfo, stats))
+}
+
+func (s *kafkaStorage) encodeAvro(cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error) {
+	out, err := s.avroCodec.BinaryFromNative(nil, newRecord(s.machineName, cInfo, stats).avroNative())
+	if err != nil {
+		return nil, err
+	}
+	if s.schemaID != nil {
+		out = wireFormat(*s.schemaID, nil, out)
+	}
+	return out, nil
+}
+
+func (s *kafkaStorage) encodeProtobuf(cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error) {
+	out, err := proto.Marshal(newRecord(s.machineName, cInfo, stats))
+	if err != nil {
+		return nil, err
+	}
+	if s.schemaID != nil {
+		out = wireFormat(*s.schemaID, []int{protobufRecordIndex}, out)
+	}
+	return out, nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/storage"
+
+	kafka "github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStats() (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Id: "abc", Aliases: []string{"web", "abc"}},
+		Spec:               info.ContainerSpec{Labels: map[string]string{"app": "web"}},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: time.Unix(1600000000, 5000),
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: 1000, PerCpu: []uint64{400, 600}}, LoadAverage: 3},
+		Memory:    info.MemoryStats{Usage: 2048, WorkingSet: 1024},
+		Network: info.NetworkStats{Interfaces: []info.InterfaceStats{
+			{Name: "eth0", RxBytes: 10, TxBytes: 20},
+		}},
+		Filesystem: []info.FsStats{{Device: "/dev/sda1", Limit: 100, Usage: 50}},
+		Processes:  info.ProcessStats{ProcessCount: 2},
+	}
+	return cInfo, stats
+}
+
+type registryServer struct {
+	subject    string
+	schemaType string
+	user       string
+}
+
+func (s *registryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Schema == "" {
+		http.Error(w, `{"error_code":42201,"message":"Invalid schema"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	s.subject = r.URL.Path
+	s.schemaType = request.SchemaType
+	s.user, _, _ = r.BasicAuth()
+	w.Write([]byte(`{"id":42}`))
+}
+
+func TestAvroEncoding(t *testing.T) {
+	server := &registryServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	s := &kafkaStorage{machineName: "machine"}
+	require.NoError(t, s.setEncoding(encodingAvro, newSchemaRegistry(ts.URL, "user", "secret"), "stats-value"))
+	assert.Equal(t, "/subjects/stats-value/versions", server.subject)
+	assert.Empty(t, server.schemaType)
+	assert.Equal(t, "user", server.user)
+
+	out, err := s.encode(testStats())
+	require.NoError(t, err)
+	require.True(t, len(out) > 5)
+	assert.Equal(t, byte(wireFormatMagicByte), out[0])
+	assert.Equal(t, uint32(42), binary.BigEndian.Uint32(out[1:5]))
+
+	native, rest, err := s.avroCodec.NativeFromBinary(out[5:])
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	record := native.(map[string]interface{})
+	assert.Equal(t, "web", record["container_name"])
+	assert.Equal(t, "abc", record["container_id"])
+	assert.Equal(t, map[string]interface{}{"app": "web"}, record["container_labels"])
+	assert.Equal(t, time.Unix(1600000000, 5000).UTC(), record["timestamp"].(time.Time).UTC())
+	cpu := record["cpu"].(map[string]interface{})
+	assert.Equal(t, int64(1000), cpu["total"])
+	assert.Equal(t, []interface{}{int64(400), int64(600)}, cpu["per_cpu"])
+	network := record["network"].([]interface{})
+	require.Len(t, network, 1)
+	assert.Equal(t, "eth0", network[0].(map[string]interface{})["name"])
+}
+
+func TestProtobufEncoding(t *testing.T) {
+	server := &registryServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	s := &kafkaStorage{machineName: "machine"}
+	require.NoError(t, s.setEncoding(encodingProtobuf, newSchemaRegistry(ts.URL, "", ""), "stats-value"))
+	assert.Equal(t, schemaTypeProtobuf, server.schemaType)
+
+	out, err := s.encode(testStats())
+	require.NoError(t, err)
+	// Magic byte, schema ID and the index of the first message.
+	assert.Equal(t, []byte{0, 0, 0, 0, 42, 0}, out[:6])
+
+	record := &ContainerStatsRecord{}
+	require.NoError(t, proto.Unmarshal(out[6:], record))
+	assert.Equal(t, "machine", record.MachineName)
+	assert.Equal(t, "web", record.ContainerName)
+	assert.Equal(t, int64(1600000000), record.Timestamp.Seconds)
+	assert.Equal(t, int32(5000), record.Timestamp.Nanos)
+	assert.Equal(t, []uint64{400, 600}, record.Cpu.PerCpu)
+	assert.Equal(t, uint64(1024), record.Memory.WorkingSet)
+	require.Len(t, record.Filesystem, 1)
+	assert.Equal(t, uint64(50), record.Filesystem[0].Usage)
+	assert.Equal(t, uint64(2), record.Processes.ProcessCount)
+}
+
+func TestEncodingWithoutRegistry(t *testing.T) {
+	s := &kafkaStorage{machineName: "machine"}
+	require.NoError(t, s.setEncoding(encodingProtobuf, nil, "stats-value"))
+	out, err := s.encode(testStats())
+	require.NoError(t, err)
+	record := &ContainerStatsRecord{}
+	require.NoError(t, proto.Unmarshal(out, record))
+	assert.Equal(t, "web", record.ContainerName)
+
+	assert.Error(t, s.setEncoding("xml", nil, "stats-value"))
+}
+
+func TestSchemaRegistryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error_code":40101,"message":"Unauthorized"}`, http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	s := &kafkaStorage{}
+	err := s.setEncoding(encodingAvro, newSchemaRegistry(ts.URL, "", ""), "stats-value")
+	assert.EqualError(t, err, `failed to register schema of subject "stats-value": 401 Unauthorized: {"error_code":40101,"message":"Unauthorized"}`)
+}
+
+func TestWireFormat(t *testing.T) {
+	assert.Equal(t, []byte{0, 0, 0, 1, 0, 'a'}, wireFormat(256, nil, []byte("a")))
+	assert.Equal(t, []byte{0, 0, 0, 0, 7, 0, 'a'}, wireFormat(7, []int{0}, []byte("a")))
+	// Zigzag encoded count and indexes.
+	assert.Equal(t, []byte{0, 0, 0, 0, 7, 4, 2, 6, 'a'}, wireFormat(7, []int{1, 3}, []byte("a")))
+}
+
+func TestPartitionKey(t *testing.T) {
+	config := kafka.NewConfig()
+	config.Producer.Return.Successes = true
+	producer := mocks.NewAsyncProducer(t, config)
+	defer producer.Close()
+
+	s := &kafkaStorage{producer: producer, topic: "stats", machineName: "machine", keyByContainer: true}
+	require.NoError(t, s.setEncoding(encodingJSON, nil, "stats-value"))
+
+	producer.ExpectInputAndSucceed()
+	require.NoError(t, s.AddStats(testStats()))
+	message := <-producer.Successes()
+	key, err := message.Key.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, "web", string(key))
+
+	value, err := message.Value.Encode()
+	require.NoError(t, err)
+	var detail detailSpec
+	require.NoError(t, json.Unmarshal(value, &detail))
+	assert.Equal(t, "machine", detail.MachineName)
+	assert.Equal(t, uint64(2048), detail.ContainerStats.Memory.Usage)
+}
+
+func TestWriteBatch(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	s := &kafkaStorage{
+		topic:           "stats",
+		machineName:     "machine",
+		newSyncProducer: func() (kafka.SyncProducer, error) { return producer, nil },
+	}
+	require.NoError(t, s.setEncoding(encodingJSON, nil, "stats-value"))
+	cInfo, stats := testStats()
+	samples := []storage.Sample{{ContainerInfo: cInfo, Stats: stats}, {ContainerInfo: cInfo, Stats: stats}}
+
+	producer.ExpectSendMessageAndSucceed()
+	producer.ExpectSendMessageAndSucceed()
+	require.NoError(t, s.WriteBatch(samples))
+
+	producer.ExpectSendMessageAndFail(kafka.ErrOutOfBrokers)
+	producer.ExpectSendMessageAndSucceed()
+	assert.Error(t, s.WriteBatch(samples))
+	require.NoError(t, producer.Close())
+}
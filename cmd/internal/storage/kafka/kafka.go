@@ -17,12 +17,13 @@ package kafka
 import (
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	info "github.com/google/cadvisor/info/v1"
@@ -30,6 +31,7 @@ import (
 	"github.com/google/cadvisor/utils/container"
 
 	kafka "github.com/Shopify/sarama"
+	"github.com/linkedin/goavro/v2"
 	"k8s.io/klog/v2"
 )
 
@@ -45,12 +47,41 @@ var (
 	keyFile   = flag.String("storage_driver_kafka_ssl_key", "", "optional key file for TLS client authentication")
 	caFile    = flag.String("storage_driver_kafka_ssl_ca", "", "optional certificate authority file for TLS client authentication")
 	verifySSL = flag.Bool("storage_driver_kafka_ssl_verify", true, "verify ssl certificate chain")
+
+	encoding               = flag.String("storage_driver_kafka_encoding", encodingJSON, "encoding of the stats, one of json, avro or protobuf")
+	schemaRegistryURL      = flag.String("storage_driver_kafka_schema_registry_url", "", "optional URL of the Confluent Schema Registry the avro and protobuf schemas are registered with")
+	schemaRegistryUser     = flag.String("storage_driver_kafka_schema_registry_user", "", "optional schema registry basic auth user")
+	schemaRegistryPassword = flag.String("storage_driver_kafka_schema_registry_password", "", "optional schema registry basic auth password")
+	partitionKey           = flag.String("storage_driver_kafka_partition_key", partitionKeyNone, "key of the messages, none or container_name to keep the stats of a container in order on a single partition")
+
+	saslMechanism     = flag.String("storage_driver_kafka_sasl_mechanism", "", "optional SASL mechanism, one of PLAIN, SCRAM-SHA-256, SCRAM-SHA-512 or OAUTHBEARER")
+	saslUser          = flag.String("storage_driver_kafka_sasl_user", "", "SASL user of the PLAIN and SCRAM mechanisms")
+	saslPassword      = flag.String("storage_driver_kafka_sasl_password", "", "SASL password of the PLAIN and SCRAM mechanisms")
+	oauthTokenURL     = flag.String("storage_driver_kafka_oauth_token_url", "", "OAuth 2.0 token endpoint of the OAUTHBEARER mechanism")
+	oauthClientID     = flag.String("storage_driver_kafka_oauth_client_id", "", "OAuth 2.0 client ID of the OAUTHBEARER mechanism")
+	oauthClientSecret = flag.String("storage_driver_kafka_oauth_client_secret", "", "OAuth 2.0 client secret of the OAUTHBEARER mechanism")
+	oauthScopes       = flag.String("storage_driver_kafka_oauth_scopes", "", "optional comma separated OAuth 2.0 scopes of the OAUTHBEARER mechanism")
+)
+
+// Message keys.
+const (
+	partitionKeyNone          = "none"
+	partitionKeyContainerName = "container_name"
 )
 
 type kafkaStorage struct {
-	producer    kafka.AsyncProducer
-	topic       string
-	machineName string
+	producer kafka.AsyncProducer
+	// Producer of WriteBatch, created on its first call.
+	syncProducer    kafka.SyncProducer
+	newSyncProducer func() (kafka.SyncProducer, error)
+	syncLock        sync.Mutex
+	topic           string
+	machineName     string
+	keyByContainer  bool
+	encode          func(cInfo *info.ContainerInfo, stats *info.ContainerStats) ([]byte, error)
+	avroCodec       *goavro.Codec
+	// ID of the schema in the schema registry, nil without a registry.
+	schemaID *int
 }
 
 type detailSpec struct {
@@ -80,18 +111,64 @@ func (s *kafkaStorage) infoToDetailSpec(cInfo *info.ContainerInfo, stats *info.C
 }
 
 func (s *kafkaStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
-	detail := s.infoToDetailSpec(cInfo, stats)
-	b, err := json.Marshal(detail)
+	message, err := s.newMessage(cInfo, stats)
+	if err != nil {
+		return err
+	}
+	s.producer.Input() <- message
+
+	return nil
+}
 
-	s.producer.Input() <- &kafka.ProducerMessage{
+func (s *kafkaStorage) newMessage(cInfo *info.ContainerInfo, stats *info.ContainerStats) (*kafka.ProducerMessage, error) {
+	b, err := s.encode(cInfo, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &kafka.ProducerMessage{
 		Topic: s.topic,
-		Value: kafka.StringEncoder(b),
+		Value: kafka.ByteEncoder(b),
+	}
+	if s.keyByContainer {
+		// Messages with the same key are sent to the same partition.
+		message.Key = kafka.StringEncoder(container.GetPreferredName(cInfo.ContainerReference))
 	}
+	return message, nil
+}
 
-	return err
+// WriteBatch sends the messages of samples and waits for their
+// acknowledgement, unlike AddStats.
+func (s *kafkaStorage) WriteBatch(samples []storage.Sample) error {
+	messages := make([]*kafka.ProducerMessage, 0, len(samples))
+	for _, sample := range samples {
+		message, err := s.newMessage(sample.ContainerInfo, sample.Stats)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+	if s.syncProducer == nil {
+		producer, err := s.newSyncProducer()
+		if err != nil {
+			return err
+		}
+		s.syncProducer = producer
+	}
+	return s.syncProducer.SendMessages(messages)
 }
 
 func (s *kafkaStorage) Close() error {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+	if s.syncProducer != nil {
+		if err := s.syncProducer.Close(); err != nil {
+			return err
+		}
+	}
 	return s.producer.Close()
 }
 
@@ -140,8 +217,32 @@ func newStorage(machineName string) (storage.StorageDriver, error) {
 		config.Net.TLS.Config = tlsConfig
 	}
 
+	if err := configureSASL(config, *saslMechanism); err != nil {
+		return nil, err
+	}
+
 	config.Producer.RequiredAcks = kafka.WaitForAll
 
+	ret := &kafkaStorage{
+		topic:       *topic,
+		machineName: machineName,
+	}
+	switch *partitionKey {
+	case partitionKeyNone:
+	case partitionKeyContainerName:
+		ret.keyByContainer = true
+	default:
+		return nil, fmt.Errorf("unknown partition key %q, expected %q or %q", *partitionKey, partitionKeyNone, partitionKeyContainerName)
+	}
+	var registry *schemaRegistry
+	if *schemaRegistryURL != "" {
+		registry = newSchemaRegistry(*schemaRegistryURL, *schemaRegistryUser, *schemaRegistryPassword)
+	}
+	// Schemas are registered with the default topic name strategy.
+	if err := ret.setEncoding(*encoding, registry, *topic+"-value"); err != nil {
+		return nil, err
+	}
+
 	brokerList := strings.Split(*brokers, ",")
 	klog.V(4).Infof("Kafka brokers:%q", *brokers)
 
@@ -149,10 +250,11 @@ func newStorage(machineName string) (storage.StorageDriver, error) {
 	if err != nil {
 		return nil, err
 	}
-	ret := &kafkaStorage{
-		producer:    producer,
-		topic:       *topic,
-		machineName: machineName,
+	ret.producer = producer
+	ret.newSyncProducer = func() (kafka.SyncProducer, error) {
+		syncConfig := *config
+		syncConfig.Producer.Return.Successes = true
+		return kafka.NewSyncProducer(brokerList, &syncConfig)
 	}
 	return ret, nil
 }
@@ -0,0 +1,423 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/container"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// Protobuf schema of the records of the protobuf encoding.
+const protobufSchema = `syntax = "proto3";
+
+package cadvisor.kafka.v1;
+
+import "google/protobuf/timestamp.proto";
+
+// Stats of a container at a point in time.
+message ContainerStatsRecord {
+  google.protobuf.Timestamp timestamp = 1;
+  string machine_name = 2;
+  string container_name = 3;
+  string container_id = 4;
+  map<string, string> container_labels = 5;
+  CpuStats cpu = 6;
+  MemoryStats memory = 7;
+  repeated InterfaceStats network = 8;
+  repeated FilesystemStats filesystem = 9;
+  ProcessStats processes = 10;
+}
+
+message CpuStats {
+  // Cumulative CPU time, in nanoseconds.
+  uint64 total = 1;
+  repeated uint64 per_cpu = 2;
+  uint64 user = 3;
+  uint64 system = 4;
+  uint64 throttled_periods = 5;
+  // In nanoseconds.
+  uint64 throttled_time = 6;
+  // Smoothed average of runnable threads, times 1000.
+  int32 load_average = 7;
+}
+
+message MemoryStats {
+  // In bytes.
+  uint64 usage = 1;
+  uint64 max_usage = 2;
+  uint64 cache = 3;
+  uint64 rss = 4;
+  uint64 swap = 5;
+  uint64 mapped_file = 6;
+  uint64 working_set = 7;
+  uint64 failcnt = 8;
+}
+
+message InterfaceStats {
+  string name = 1;
+  uint64 rx_bytes = 2;
+  uint64 rx_packets = 3;
+  uint64 rx_errors = 4;
+  uint64 rx_dropped = 5;
+  uint64 tx_bytes = 6;
+  uint64 tx_packets = 7;
+  uint64 tx_errors = 8;
+  uint64 tx_dropped = 9;
+}
+
+message FilesystemStats {
+  string device = 1;
+  string type = 2;
+  // In bytes.
+  uint64 limit = 3;
+  uint64 usage = 4;
+  uint64 base_usage = 5;
+  uint64 available = 6;
+  uint64 inodes_free = 7;
+  uint64 reads_completed = 8;
+  uint64 writes_completed = 9;
+}
+
+message ProcessStats {
+  uint64 process_count = 1;
+  uint64 fd_count = 2;
+  uint64 socket_count = 3;
+  uint64 threads_current = 4;
+  uint64 threads_max = 5;
+}
+`
+
+// Index of ContainerStatsRecord among the messages of protobufSchema.
+const protobufRecordIndex = 0
+
+// Avro schema of the records of the avro encoding, the same fields as
+// protobufSchema. Counters are signed longs.
+const avroSchema = `{
+  "type": "record",
+  "name": "ContainerStatsRecord",
+  "namespace": "cadvisor.kafka.v1",
+  "doc": "Stats of a container at a point in time.",
+  "fields": [
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "machine_name", "type": "string"},
+    {"name": "container_name", "type": "string"},
+    {"name": "container_id", "type": "string"},
+    {"name": "container_labels", "type": {"type": "map", "values": "string"}},
+    {"name": "cpu", "type": {
+      "type": "record",
+      "name": "CpuStats",
+      "fields": [
+        {"name": "total", "type": "long"},
+        {"name": "per_cpu", "type": {"type": "array", "items": "long"}},
+        {"name": "user", "type": "long"},
+        {"name": "system", "type": "long"},
+        {"name": "throttled_periods", "type": "long"},
+        {"name": "throttled_time", "type": "long"},
+        {"name": "load_average", "type": "int"}
+      ]
+    }},
+    {"name": "memory", "type": {
+      "type": "record",
+      "name": "MemoryStats",
+      "fields": [
+        {"name": "usage", "type": "long"},
+        {"name": "max_usage", "type": "long"},
+        {"name": "cache", "type": "long"},
+        {"name": "rss", "type": "long"},
+        {"name": "swap", "type": "long"},
+        {"name": "mapped_file", "type": "long"},
+        {"name": "working_set", "type": "long"},
+        {"name": "failcnt", "type": "long"}
+      ]
+    }},
+    {"name": "network", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "InterfaceStats",
+      "fields": [
+        {"name": "name", "type": "string"},
+        {"name": "rx_bytes", "type": "long"},
+        {"name": "rx_packets", "type": "long"},
+        {"name": "rx_errors", "type": "long"},
+        {"name": "rx_dropped", "type": "long"},
+        {"name": "tx_bytes", "type": "long"},
+        {"name": "tx_packets", "type": "long"},
+        {"name": "tx_errors", "type": "long"},
+        {"name": "tx_dropped", "type": "long"}
+      ]
+    }}},
+    {"name": "filesystem", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "FilesystemStats",
+      "fields": [
+        {"name": "device", "type": "string"},
+        {"name": "type", "type": "string"},
+        {"name": "limit", "type": "long"},
+        {"name": "usage", "type": "long"},
+        {"name": "base_usage", "type": "long"},
+        {"name": "available", "type": "long"},
+        {"name": "inodes_free", "type": "long"},
+        {"name": "reads_completed", "type": "long"},
+        {"name": "writes_completed", "type": "long"}
+      ]
+    }}},
+    {"name": "processes", "type": {
+      "type": "record",
+      "name": "ProcessStats",
+      "fields": [
+        {"name": "process_count", "type": "long"},
+        {"name": "fd_count", "type": "long"},
+        {"name": "socket_count", "type": "long"},
+        {"name": "threads_current", "type": "long"},
+        {"name": "threads_max", "type": "long"}
+      ]
+    }}
+  ]
+}
+`
+
+// Messages of protobufSchema.
+
+type ContainerStatsRecord struct {
+	Timestamp       *timestamp.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	MachineName     string               `protobuf:"bytes,2,opt,name=machine_name,proto3" json:"machine_name,omitempty"`
+	ContainerName   string               `protobuf:"bytes,3,opt,name=container_name,proto3" json:"container_name,omitempty"`
+	ContainerID     string               `protobuf:"bytes,4,opt,name=container_id,proto3" json:"container_id,omitempty"`
+	ContainerLabels map[string]string    `protobuf:"bytes,5,rep,name=container_labels,proto3" json:"container_labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Cpu             *CpuStats            `protobuf:"bytes,6,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory          *MemoryStats         `protobuf:"bytes,7,opt,name=memory,proto3" json:"memory,omitempty"`
+	Network         []*InterfaceStats    `protobuf:"bytes,8,rep,name=network,proto3" json:"network,omitempty"`
+	Filesystem      []*FilesystemStats   `protobuf:"bytes,9,rep,name=filesystem,proto3" json:"filesystem,omitempty"`
+	Processes       *ProcessStats        `protobuf:"bytes,10,opt,name=processes,proto3" json:"processes,omitempty"`
+}
+
+func (m *ContainerStatsRecord) Reset()         { *m = ContainerStatsRecord{} }
+func (m *ContainerStatsRecord) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatsRecord) ProtoMessage()    {}
+
+type CpuStats struct {
+	Total            uint64   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	PerCpu           []uint64 `protobuf:"varint,2,rep,packed,name=per_cpu,proto3" json:"per_cpu,omitempty"`
+	User             uint64   `protobuf:"varint,3,opt,name=user,proto3" json:"user,omitempty"`
+	System           uint64   `protobuf:"varint,4,opt,name=system,proto3" json:"system,omitempty"`
+	ThrottledPeriods uint64   `protobuf:"varint,5,opt,name=throttled_periods,proto3" json:"throttled_periods,omitempty"`
+	ThrottledTime    uint64   `protobuf:"varint,6,opt,name=throttled_time,proto3" json:"throttled_time,omitempty"`
+	LoadAverage      int32    `protobuf:"varint,7,opt,name=load_average,proto3" json:"load_average,omitempty"`
+}
+
+func (m *CpuStats) Reset()         { *m = CpuStats{} }
+func (m *CpuStats) String() string { return proto.CompactTextString(m) }
+func (*CpuStats) ProtoMessage()    {}
+
+type MemoryStats struct {
+	Usage      uint64 `protobuf:"varint,1,opt,name=usage,proto3" json:"usage,omitempty"`
+	MaxUsage   uint64 `protobuf:"varint,2,opt,name=max_usage,proto3" json:"max_usage,omitempty"`
+	Cache      uint64 `protobuf:"varint,3,opt,name=cache,proto3" json:"cache,omitempty"`
+	Rss        uint64 `protobuf:"varint,4,opt,name=rss,proto3" json:"rss,omitempty"`
+	Swap       uint64 `protobuf:"varint,5,opt,name=swap,proto3" json:"swap,omitempty"`
+	MappedFile uint64 `protobuf:"varint,6,opt,name=mapped_file,proto3" json:"mapped_file,omitempty"`
+	WorkingSet uint64 `protobuf:"varint,7,opt,name=working_set,proto3" json:"working_set,omitempty"`
+	Failcnt    uint64 `protobuf:"varint,8,opt,name=failcnt,proto3" json:"failcnt,omitempty"`
+}
+
+func (m *MemoryStats) Reset()         { *m = MemoryStats{} }
+func (m *MemoryStats) String() string { return proto.CompactTextString(m) }
+func (*MemoryStats) ProtoMessage()    {}
+
+type InterfaceStats struct {
+	Name      string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,2,opt,name=rx_bytes,proto3" json:"rx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,3,opt,name=rx_packets,proto3" json:"rx_packets,omitempty"`
+	RxErrors  uint64 `protobuf:"varint,4,opt,name=rx_errors,proto3" json:"rx_errors,omitempty"`
+	RxDropped uint64 `protobuf:"varint,5,opt,name=rx_dropped,proto3" json:"rx_dropped,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,6,opt,name=tx_bytes,proto3" json:"tx_bytes,omitempty"`
+	TxPackets uint64 `protobuf:"varint,7,opt,name=tx_packets,proto3" json:"tx_packets,omitempty"`
+	TxErrors  uint64 `protobuf:"varint,8,opt,name=tx_errors,proto3" json:"tx_errors,omitempty"`
+	TxDropped uint64 `protobuf:"varint,9,opt,name=tx_dropped,proto3" json:"tx_dropped,omitempty"`
+}
+
+func (m *InterfaceStats) Reset()         { *m = InterfaceStats{} }
+func (m *InterfaceStats) String() string { return proto.CompactTextString(m) }
+func (*InterfaceStats) ProtoMessage()    {}
+
+type FilesystemStats struct {
+	Device          string `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Type            string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Limit           uint64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Usage           uint64 `protobuf:"varint,4,opt,name=usage,proto3" json:"usage,omitempty"`
+	BaseUsage       uint64 `protobuf:"varint,5,opt,name=base_usage,proto3" json:"base_usage,omitempty"`
+	Available       uint64 `protobuf:"varint,6,opt,name=available,proto3" json:"available,omitempty"`
+	InodesFree      uint64 `protobuf:"varint,7,opt,name=inodes_free,proto3" json:"inodes_free,omitempty"`
+	ReadsCompleted  uint64 `protobuf:"varint,8,opt,name=reads_completed,proto3" json:"reads_completed,omitempty"`
+	WritesCompleted uint64 `protobuf:"varint,9,opt,name=writes_completed,proto3" json:"writes_completed,omitempty"`
+}
+
+func (m *FilesystemStats) Reset()         { *m = FilesystemStats{} }
+func (m *FilesystemStats) String() string { return proto.CompactTextString(m) }
+func (*FilesystemStats) ProtoMessage()    {}
+
+type ProcessStats struct {
+	ProcessCount   uint64 `protobuf:"varint,1,opt,name=process_count,proto3" json:"process_count,omitempty"`
+	FdCount        uint64 `protobuf:"varint,2,opt,name=fd_count,proto3" json:"fd_count,omitempty"`
+	SocketCount    uint64 `protobuf:"varint,3,opt,name=socket_count,proto3" json:"socket_count,omitempty"`
+	ThreadsCurrent uint64 `protobuf:"varint,4,opt,name=threads_current,proto3" json:"threads_current,omitempty"`
+	ThreadsMax     uint64 `protobuf:"varint,5,opt,name=threads_max,proto3" json:"threads_max,omitempty"`
+}
+
+func (m *ProcessStats) Reset()         { *m = ProcessStats{} }
+func (m *ProcessStats) String() string { return proto.CompactTextString(m) }
+func (*ProcessStats) ProtoMessage()    {}
+
+func newRecord(machineName string, cInfo *info.ContainerInfo, stats *info.ContainerStats) *ContainerStatsRecord {
+	record := &ContainerStatsRecord{
+		Timestamp:       &timestamp.Timestamp{Seconds: stats.Timestamp.Unix(), Nanos: int32(stats.Timestamp.Nanosecond())},
+		MachineName:     machineName,
+		ContainerName:   container.GetPreferredName(cInfo.ContainerReference),
+		ContainerID:     cInfo.ContainerReference.Id,
+		ContainerLabels: cInfo.Spec.Labels,
+		Cpu: &CpuStats{
+			Total:            stats.Cpu.Usage.Total,
+			PerCpu:           stats.Cpu.Usage.PerCpu,
+			User:             stats.Cpu.Usage.User,
+			System:           stats.Cpu.Usage.System,
+			ThrottledPeriods: stats.Cpu.CFS.ThrottledPeriods,
+			ThrottledTime:    stats.Cpu.CFS.ThrottledTime,
+			LoadAverage:      stats.Cpu.LoadAverage,
+		},
+		Memory: &MemoryStats{
+			Usage:      stats.Memory.Usage,
+			MaxUsage:   stats.Memory.MaxUsage,
+			Cache:      stats.Memory.Cache,
+			Rss:        stats.Memory.RSS,
+			Swap:       stats.Memory.Swap,
+			MappedFile: stats.Memory.MappedFile,
+			WorkingSet: stats.Memory.WorkingSet,
+			Failcnt:    stats.Memory.Failcnt,
+		},
+		Processes: &ProcessStats{
+			ProcessCount:   stats.Processes.ProcessCount,
+			FdCount:        stats.Processes.FdCount,
+			SocketCount:    stats.Processes.SocketCount,
+			ThreadsCurrent: stats.Processes.ThreadsCurrent,
+			ThreadsMax:     stats.Processes.ThreadsMax,
+		},
+	}
+	for _, iface := range stats.Network.Interfaces {
+		record.Network = append(record.Network, &InterfaceStats{
+			Name:      iface.Name,
+			RxBytes:   iface.RxBytes,
+			RxPackets: iface.RxPackets,
+			RxErrors:  iface.RxErrors,
+			RxDropped: iface.RxDropped,
+			TxBytes:   iface.TxBytes,
+			TxPackets: iface.TxPackets,
+			TxErrors:  iface.TxErrors,
+			TxDropped: iface.TxDropped,
+		})
+	}
+	for _, fs := range stats.Filesystem {
+		record.Filesystem = append(record.Filesystem, &FilesystemStats{
+			Device:          fs.Device,
+			Type:            fs.Type,
+			Limit:           fs.Limit,
+			Usage:           fs.Usage,
+			BaseUsage:       fs.BaseUsage,
+			Available:       fs.Available,
+			InodesFree:      fs.InodesFree,
+			ReadsCompleted:  fs.ReadsCompleted,
+			WritesCompleted: fs.WritesCompleted,
+		})
+	}
+	return record
+}
+
+// Converts record to the native form of avroSchema.
+func (m *ContainerStatsRecord) avroNative() map[string]interface{} {
+	labels := make(map[string]interface{}, len(m.ContainerLabels))
+	for k, v := range m.ContainerLabels {
+		labels[k] = v
+	}
+	perCPU := make([]interface{}, len(m.Cpu.PerCpu))
+	for i, usage := range m.Cpu.PerCpu {
+		perCPU[i] = int64(usage)
+	}
+	network := make([]interface{}, len(m.Network))
+	for i, iface := range m.Network {
+		network[i] = map[string]interface{}{
+			"name":       iface.Name,
+			"rx_bytes":   int64(iface.RxBytes),
+			"rx_packets": int64(iface.RxPackets),
+			"rx_errors":  int64(iface.RxErrors),
+			"rx_dropped": int64(iface.RxDropped),
+			"tx_bytes":   int64(iface.TxBytes),
+			"tx_packets": int64(iface.TxPackets),
+			"tx_errors":  int64(iface.TxErrors),
+			"tx_dropped": int64(iface.TxDropped),
+		}
+	}
+	filesystem := make([]interface{}, len(m.Filesystem))
+	for i, fs := range m.Filesystem {
+		filesystem[i] = map[string]interface{}{
+			"device":           fs.Device,
+			"type":             fs.Type,
+			"limit":            int64(fs.Limit),
+			"usage":            int64(fs.Usage),
+			"base_usage":       int64(fs.BaseUsage),
+			"available":        int64(fs.Available),
+			"inodes_free":      int64(fs.InodesFree),
+			"reads_completed":  int64(fs.ReadsCompleted),
+			"writes_completed": int64(fs.WritesCompleted),
+		}
+	}
+	return map[string]interface{}{
+		"timestamp":        time.Unix(m.Timestamp.Seconds, int64(m.Timestamp.Nanos)),
+		"machine_name":     m.MachineName,
+		"container_name":   m.ContainerName,
+		"container_id":     m.ContainerID,
+		"container_labels": labels,
+		"cpu": map[string]interface{}{
+			"total":             int64(m.Cpu.Total),
+			"per_cpu":           perCPU,
+			"user":              int64(m.Cpu.User),
+			"system":            int64(m.Cpu.System),
+			"throttled_periods": int64(m.Cpu.ThrottledPeriods),
+			"throttled_time":    int64(m.Cpu.ThrottledTime),
+			"load_average":      m.Cpu.LoadAverage,
+		},
+		"memory": map[string]interface{}{
+			"usage":       int64(m.Memory.Usage),
+			"max_usage":   int64(m.Memory.MaxUsage),
+			"cache":       int64(m.Memory.Cache),
+			"rss":         int64(m.Memory.Rss),
+			"swap":        int64(m.Memory.Swap),
+			"mapped_file": int64(m.Memory.MappedFile),
+			"working_set": int64(m.Memory.WorkingSet),
+			"failcnt":     int64(m.Memory.Failcnt),
+		},
+		"network":    network,
+		"filesystem": filesystem,
+		"processes": map[string]interface{}{
+			"process_count":   int64(m.Processes.ProcessCount),
+			"fd_count":        int64(m.Processes.FdCount),
+			"socket_count":    int64(m.Processes.SocketCount),
+			"threads_current": int64(m.Processes.ThreadsCurrent),
+			"threads_max":     int64(m.Processes.ThreadsMax),
+		},
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	kafka "github.com/Shopify/sarama"
+	"github.com/xdg/scram"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// scramClient implements kafka.SCRAMClient with github.com/xdg/scram.
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
+
+// tokenProvider implements kafka.AccessTokenProvider with tokens of an OAuth
+// 2.0 client credentials grant. Tokens are cached until they expire.
+type tokenProvider struct {
+	tokenSource oauth2.TokenSource
+}
+
+func (p *tokenProvider) Token() (*kafka.AccessToken, error) {
+	token, err := p.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OAuth token: %v", err)
+	}
+	return &kafka.AccessToken{Token: token.AccessToken}, nil
+}
+
+func newTokenProvider(tokenURL, clientID, clientSecret, scopes string) *tokenProvider {
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+	}
+	if scopes != "" {
+		config.Scopes = strings.Split(scopes, ",")
+	}
+	return &tokenProvider{tokenSource: config.TokenSource(context.Background())}
+}
+
+// Configures SASL authentication with the given mechanism, disabled when
+// mechanism is empty.
+func configureSASL(config *kafka.Config, mechanism string) error {
+	if mechanism == "" {
+		return nil
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Mechanism = kafka.SASLMechanism(mechanism)
+	config.Net.SASL.User = *saslUser
+	config.Net.SASL.Password = *saslPassword
+	switch config.Net.SASL.Mechanism {
+	case kafka.SASLTypePlaintext:
+	case kafka.SASLTypeSCRAMSHA256:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() kafka.SCRAMClient {
+			return &scramClient{hashGenerator: sha256.New}
+		}
+	case kafka.SASLTypeSCRAMSHA512:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() kafka.SCRAMClient {
+			return &scramClient{hashGenerator: sha512.New}
+		}
+	case kafka.SASLTypeOAuth:
+		if *oauthTokenURL == "" {
+			return fmt.Errorf("--storage_driver_kafka_oauth_token_url is required by SASL mechanism %s", mechanism)
+		}
+		config.Net.SASL.TokenProvider = newTokenProvider(*oauthTokenURL, *oauthClientID, *oauthClientSecret, *oauthScopes)
+	default:
+		return fmt.Errorf("unsupported SASL mechanism %q, expected one of %s, %s, %s or %s",
+			mechanism, kafka.SASLTypePlaintext, kafka.SASLTypeSCRAMSHA256, kafka.SASLTypeSCRAMSHA512, kafka.SASLTypeOAuth)
+	}
+	return nil
+}
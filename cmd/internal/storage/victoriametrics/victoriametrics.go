@@ -0,0 +1,370 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package victoriametrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/metrics"
+	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/utils/clock"
+)
+
+func init() {
+	storage.RegisterStorageDriver("victoriametrics", new)
+}
+
+var (
+	argURL         = flag.String("storage_driver_victoriametrics_url", "http://localhost:8428/api/v1/import", "VictoriaMetrics or vmagent JSON line import URL")
+	argUser        = flag.String("storage_driver_victoriametrics_user", "", "optional VictoriaMetrics basic auth user")
+	argPassword    = flag.String("storage_driver_victoriametrics_password", "", "optional VictoriaMetrics basic auth password")
+	argExtraLabels = flag.String("storage_driver_victoriametrics_extra_labels", "job=cadvisor", "comma separated name=value labels added to all the samples, besides an instance label of the host name")
+)
+
+// Timeout of a single import.
+const importTimeout = 30 * time.Second
+
+// Metrics of the Prometheus collector which describe the collector itself
+// rather than the containers, and are not pushed.
+var skippedMetrics = map[string]bool{
+	"cadvisor_version_info":  true,
+	"container_scrape_error": true,
+}
+
+var (
+	includedMetrics     = container.AllMetrics
+	containerLabelsFunc = metrics.DefaultContainerLabels
+	relabelConfig       *metrics.RelabelConfig
+)
+
+// SetMetricsConfig sets the metrics pushed by the driver, which are the same
+// as those of the Prometheus endpoint. It must be called before the driver is
+// created.
+func SetMetricsConfig(metricSet container.MetricSet, f metrics.ContainerLabelsFunc, config *metrics.RelabelConfig) {
+	includedMetrics = metricSet
+	containerLabelsFunc = f
+	relabelConfig = config
+}
+
+type victoriametricsStorage struct {
+	client         *http.Client
+	importURL      string
+	username       string
+	password       string
+	bufferDuration time.Duration
+	lastWrite      time.Time
+	// Gathers the metrics of provider.
+	gatherer prometheus.Gatherer
+	provider *containerProvider
+	// Buffered series by their labels.
+	series       map[string]*series
+	lock         sync.Mutex
+	readyToFlush func() bool
+}
+
+// series is a line of the JSON line import format.
+type series struct {
+	Metric     map[string]string `json:"metric"`
+	Values     []float64         `json:"values"`
+	Timestamps []int64           `json:"timestamps"`
+}
+
+// containerProvider provides the Prometheus collector with a single container.
+type containerProvider struct {
+	cInfo *info.ContainerInfo
+}
+
+func (p *containerProvider) GetRequestedContainersInfo(string, v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	return map[string]*info.ContainerInfo{p.cInfo.Name: p.cInfo}, nil
+}
+
+func (p *containerProvider) GetVersionInfo() (*info.VersionInfo, error) {
+	return &info.VersionInfo{CadvisorVersion: version.Info["version"], CadvisorRevision: version.Info["revision"]}, nil
+}
+
+func (p *containerProvider) GetMachineInfo() (*info.MachineInfo, error) {
+	return &info.MachineInfo{}, nil
+}
+
+func new() (storage.StorageDriver, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return newStorage(
+		hostname,
+		*argURL,
+		*argUser,
+		*argPassword,
+		*argExtraLabels,
+		*storage.ArgDbBufferDuration,
+	)
+}
+
+func (s *victoriametricsStorage) defaultReadyToFlush() bool {
+	return time.Since(s.lastWrite) >= s.bufferDuration
+}
+
+// Adds the samples of stats to seriesByKey. Must be called with the lock
+// held.
+func (s *victoriametricsStorage) addSamples(seriesByKey map[string]*series, cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	sample := *cInfo
+	sample.Stats = []*info.ContainerStats{stats}
+	s.provider.cInfo = &sample
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	defaultTimestamp := stats.Timestamp.UnixNano() / int64(time.Millisecond)
+	for _, family := range families {
+		if skippedMetrics[family.GetName()] {
+			continue
+		}
+		for _, m := range family.Metric {
+			var value float64
+			switch {
+			case m.Counter != nil:
+				value = m.Counter.GetValue()
+			case m.Gauge != nil:
+				value = m.Gauge.GetValue()
+			case m.Untyped != nil:
+				value = m.Untyped.GetValue()
+			default:
+				continue
+			}
+			timestamp := defaultTimestamp
+			if m.TimestampMs != nil {
+				timestamp = m.GetTimestampMs()
+			}
+			key := seriesKey(family.GetName(), m.Label)
+			ser, ok := seriesByKey[key]
+			if !ok {
+				ser = &series{Metric: map[string]string{"__name__": family.GetName()}}
+				for _, label := range m.Label {
+					// Like Prometheus, drop the labels without value.
+					if label.GetValue() != "" {
+						ser.Metric[label.GetName()] = label.GetValue()
+					}
+				}
+				seriesByKey[key] = ser
+			}
+			ser.Values = append(ser.Values, value)
+			ser.Timestamps = append(ser.Timestamps, timestamp)
+		}
+	}
+	return nil
+}
+
+// Returns a key identifying the series of name and labels.
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var key strings.Builder
+	key.WriteString(name)
+	for _, label := range labels {
+		if label.GetValue() == "" {
+			continue
+		}
+		key.WriteByte(0)
+		key.WriteString(label.GetName())
+		key.WriteByte(0)
+		key.WriteString(label.GetValue())
+	}
+	return key.String()
+}
+
+func (s *victoriametricsStorage) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	var seriesToFlush map[string]*series
+	err := func() error {
+		// AddStats will be invoked simultaneously from multiple threads and only one of them will perform a write.
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		if err := s.addSamples(s.series, cInfo, stats); err != nil {
+			return err
+		}
+		if s.readyToFlush() {
+			seriesToFlush = s.series
+			s.series = make(map[string]*series)
+			s.lastWrite = time.Now()
+		}
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	if len(seriesToFlush) > 0 {
+		return s.importSeries(seriesToFlush)
+	}
+	return nil
+}
+
+// WriteBatch imports the series of samples at once, bypassing the buffer.
+func (s *victoriametricsStorage) WriteBatch(samples []storage.Sample) error {
+	seriesByKey := make(map[string]*series)
+	err := func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		for _, sample := range samples {
+			if err := s.addSamples(seriesByKey, sample.ContainerInfo, sample.Stats); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil || len(seriesByKey) == 0 {
+		return err
+	}
+	return s.importSeries(seriesByKey)
+}
+
+// Imports series with a single gzipped request.
+func (s *victoriametricsStorage) importSeries(seriesByKey map[string]*series) error {
+	keys := make([]string, 0, len(seriesByKey))
+	for key := range seriesByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	encoder := json.NewEncoder(gz)
+	for _, key := range keys {
+		if err := encoder.Encode(seriesByKey[key]); err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.importURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to write stats to VictoriaMetrics: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to write stats to VictoriaMetrics: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+func (s *victoriametricsStorage) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Parses comma separated name=value labels.
+func parseLabels(labels string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, label := range strings.Split(labels, ",") {
+		if label == "" {
+			continue
+		}
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid label %q, expected name=value", label)
+		}
+		parsed[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return parsed, nil
+}
+
+// machineName: A unique identifier to identify the host that current cAdvisor
+// instance is running on, the instance label of the samples.
+// importURL: The JSON line import URL of VictoriaMetrics or vmagent.
+func newStorage(
+	machineName,
+	importURL,
+	username,
+	password,
+	extraLabels string,
+	bufferDuration time.Duration,
+) (*victoriametricsStorage, error) {
+	u, err := url.Parse(importURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VictoriaMetrics URL %q: %v", importURL, err)
+	}
+	labels, err := parseLabels(extraLabels)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := labels["instance"]; !ok {
+		labels["instance"] = machineName
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// VictoriaMetrics adds the extra labels to all the imported samples.
+	query := u.Query()
+	for _, name := range names {
+		query.Add("extra_label", name+"="+labels[name])
+	}
+	u.RawQuery = query.Encode()
+
+	provider := &containerProvider{}
+	collector := metrics.NewPrometheusCollector(provider, containerLabelsFunc, includedMetrics, clock.RealClock{}, v2.RequestOptions{})
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		return nil, err
+	}
+
+	ret := &victoriametricsStorage{
+		client:         &http.Client{Timeout: importTimeout},
+		importURL:      u.String(),
+		username:       username,
+		password:       password,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+		gatherer:       metrics.NewRelabelingGatherer(registry, relabelConfig),
+		provider:       provider,
+		series:         make(map[string]*series),
+	}
+	ret.readyToFlush = ret.defaultReadyToFlush
+	return ret, nil
+}
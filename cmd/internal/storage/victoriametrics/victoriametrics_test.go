@@ -0,0 +1,148 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package victoriametrics
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type importServer struct {
+	extraLabels []string
+	series      []series
+}
+
+func (s *importServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/v1/import" || r.Header.Get("Content-Encoding") != "gzip" {
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+		return
+	}
+	s.extraLabels = r.URL.Query()["extra_label"]
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	decoder := json.NewDecoder(gz)
+	for decoder.More() {
+		var line series
+		if err := decoder.Decode(&line); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.series = append(s.series, line)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testStats(timestamp time.Time, cpu uint64) (*info.ContainerInfo, *info.ContainerStats) {
+	cInfo := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Name: "/docker/abc", Aliases: []string{"web", "abc"}},
+		Spec: info.ContainerSpec{
+			Image:  "nginx",
+			Labels: map[string]string{"app": "web"},
+			HasCpu: true,
+		},
+	}
+	stats := &info.ContainerStats{
+		Timestamp: timestamp,
+		Cpu:       info.CpuStats{Usage: info.CpuUsage{Total: cpu}},
+	}
+	return cInfo, stats
+}
+
+func findSeries(all []series, name string) *series {
+	for i := range all {
+		if all[i].Metric["__name__"] == name {
+			return &all[i]
+		}
+	}
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	SetMetricsConfig(container.MetricSet{container.CpuUsageMetrics: struct{}{}}, containerLabelsFunc, nil)
+
+	server := &importServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	s, err := newStorage("machine", ts.URL+"/api/v1/import", "", "", "job=cadvisor,dc=eu", time.Minute)
+	require.NoError(t, err)
+	defer s.Close()
+	count := 0
+	s.readyToFlush = func() bool {
+		count++
+		return count == 2
+	}
+
+	start := time.Unix(1600000000, 0)
+	require.NoError(t, s.AddStats(testStats(start, 1e9)))
+	assert.Empty(t, server.series)
+	require.NoError(t, s.AddStats(testStats(start.Add(10*time.Second), 3e9)))
+
+	assert.Equal(t, []string{"dc=eu", "instance=machine", "job=cadvisor"}, server.extraLabels)
+	cpu := findSeries(server.series, "container_cpu_usage_seconds_total")
+	require.NotNil(t, cpu)
+	assert.Equal(t, map[string]string{
+		"__name__":            "container_cpu_usage_seconds_total",
+		"cpu":                 "total",
+		"id":                  "/docker/abc",
+		"image":               "nginx",
+		"name":                "web",
+		"container_label_app": "web",
+	}, cpu.Metric)
+	assert.Equal(t, []float64{1, 3}, cpu.Values)
+	assert.Equal(t, []int64{1600000000000, 1600000010000}, cpu.Timestamps)
+
+	// Samples without a timestamp take the one of the stats.
+	startTime := findSeries(server.series, "container_start_time_seconds")
+	require.NotNil(t, startTime)
+	assert.Equal(t, []int64{1600000000000, 1600000010000}, startTime.Timestamps)
+
+	assert.Nil(t, findSeries(server.series, "container_scrape_error"))
+	assert.Nil(t, findSeries(server.series, "cadvisor_version_info"))
+}
+
+func TestImportError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "cannot parse json line", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	s, err := newStorage("machine", ts.URL+"/api/v1/import", "", "", "", 0)
+	require.NoError(t, err)
+	err = s.AddStats(testStats(time.Now(), 1))
+	assert.EqualError(t, err, "failed to write stats to VictoriaMetrics: 400 Bad Request: cannot parse json line")
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels("job=cadvisor, env = prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"job": "cadvisor", "env": "prod"}, labels)
+
+	_, err = parseLabels("job")
+	assert.Error(t, err)
+}
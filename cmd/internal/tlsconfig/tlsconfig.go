@@ -0,0 +1,189 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tlsconfig builds the TLS configuration of the servers of cAdvisor,
+// reloading the certificates when their files change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Minimum interval between checks of the files for changes, made during
+// TLS handshakes.
+var reloadInterval = 10 * time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloader holds the certificate and the client CAs loaded from files.
+type reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	lock      sync.Mutex
+	lastCheck time.Time
+	files     map[string]fileState
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// New returns a TLS configuration serving the certificate and key of the
+// given files. If clientCAFile is set, clients must present a certificate
+// signed by one of its CAs. The files are checked for changes at most every
+// 10 seconds during handshakes and reloaded when they change; the previous
+// certificates are kept if the new ones are invalid.
+func New(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	}
+	r := &reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if clientCAFile != "" {
+		// The chain is verified by verifyClientCertificate rather than
+		// against ClientCAs, which cannot be replaced once the config is in
+		// use.
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyPeerCertificate = r.verifyClientCertificate
+	}
+	return config, nil
+}
+
+func (r *reloader) paths() []string {
+	paths := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		paths = append(paths, r.clientCAFile)
+	}
+	return paths
+}
+
+// load reads all the files, replacing the certificates only if they are all
+// valid.
+func (r *reloader) load() error {
+	files := make(map[string]fileState, 3)
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load the certificate %q and key %q: %v", r.certFile, r.keyFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in client CA file %q", r.clientCAFile)
+		}
+	}
+
+	r.files = files
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+// changed returns whether any file changed since it was loaded.
+func (r *reloader) changed() bool {
+	for _, path := range r.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			// The file may be in the middle of being replaced.
+			continue
+		}
+		if state := r.files[path]; !info.ModTime().Equal(state.modTime) || info.Size() != state.size {
+			return true
+		}
+	}
+	return false
+}
+
+// current returns the certificate and client CAs, reloading them first if
+// their files changed.
+func (r *reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if time.Since(r.lastCheck) >= reloadInterval {
+		r.lastCheck = time.Now()
+		if r.changed() {
+			if err := r.load(); err != nil {
+				klog.Errorf("Failed to reload TLS certificates, serving the previous ones: %v", err)
+			} else {
+				klog.V(1).Infof("Reloaded TLS certificate %q", r.certFile)
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+func (r *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := r.current()
+	return cert, nil
+}
+
+func (r *reloader) verifyClientCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no client certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("invalid client certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	_, clientCAs := r.current()
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	tls  tls.Certificate
+}
+
+// newTestCert returns a certificate signed by parent, or self-signed if
+// parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, tls: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0644))
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, "ca", nil, x509.ExtKeyUsageClientAuth)
+	ca.write(t, caFile, "")
+	first := newTestCert(t, "first", nil, x509.ExtKeyUsageServerAuth)
+	first.write(t, certFile, keyFile)
+
+	config, err := New(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	// httptest.Server.StartTLS would serve its own certificate.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), TLSConfig: config}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+	url := "https://" + listener.Addr().String()
+
+	get := func(client *testCert) (*http.Response, error) {
+		clientConfig := &tls.Config{InsecureSkipVerify: true}
+		if client != nil {
+			clientConfig.Certificates = []tls.Certificate{client.tls}
+		}
+		return (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(url)
+	}
+
+	// Clients must present a certificate signed by the CA.
+	_, err = get(nil)
+	assert.Error(t, err)
+	_, err = get(newTestCert(t, "self-signed", nil, x509.ExtKeyUsageClientAuth))
+	assert.Error(t, err)
+	client := newTestCert(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	resp, err := get(client)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// The certificate is reloaded when its files change.
+	reloadInterval = 0
+	defer func() { reloadInterval = 10 * time.Second }()
+	second := newTestCert(t, "second", nil, x509.ExtKeyUsageServerAuth)
+	second.write(t, certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	resp, err = get(client)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	// Invalid files do not replace the certificate being served.
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+	resp, err = get(client)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestNewInvalidFiles(t *testing.T) {
+	_, err := New("", "", "")
+	assert.Error(t, err)
+	_, err = New("/nonexistent/tls.crt", "/nonexistent/tls.key", "")
+	assert.Error(t, err)
+}
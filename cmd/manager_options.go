@@ -0,0 +1,234 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/machine"
+	"github.com/google/cadvisor/manager"
+	"github.com/google/cadvisor/summary"
+	"github.com/google/cadvisor/utils/cloudinfo"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	periodicHousekeepingMode = "periodic"
+	onDemandHousekeepingMode = "on_demand"
+)
+
+var (
+	housekeepingInterval       = flag.Duration("housekeeping_interval", 1*time.Second, "Interval between container housekeepings")
+	maxHousekeepingInterval    = flag.Duration("max_housekeeping_interval", 60*time.Second, "Largest interval to allow between container housekeepings")
+	allowDynamicHousekeeping   = flag.Bool("allow_dynamic_housekeeping", true, "Whether to allow the housekeeping interval to be dynamic")
+	housekeepingMode           = flag.String("housekeeping_mode", periodicHousekeepingMode, "When container stats are collected: 'periodic' collects them at every container housekeeping, 'on_demand' collects them only when they are requested through the API or the Prometheus endpoint")
+	onDemandStatsTTL           = flag.Duration("on_demand_stats_ttl", 5*time.Second, "Maximum age of container stats served without collecting them again when housekeeping_mode is 'on_demand'")
+	globalHousekeepingInterval = flag.Duration("global_housekeeping_interval", 1*time.Minute, "Interval between global housekeepings")
+	updateMachineInfoInterval  = flag.Duration("update_machine_info_interval", 5*time.Minute, "Interval between machine info updates.")
+	perfInterval               = flag.Duration("perf_interval", 0, "Interval between perf event measurements of a container, 0 measures at every container housekeeping")
+	resctrlInterval            = flag.Duration("resctrl_interval", 0, "Interval between resctrl measurements of a container, 0 measures at every container housekeeping")
+	enableLoadReader           = flag.Bool("enable_load_reader", false, "Whether to enable cpu load reader")
+	logCadvisorUsage           = flag.Bool("log_cadvisor_usage", false, "Whether to log the usage of the cAdvisor container")
+
+	summaryWindows   = flag.String("summary_windows", "", "Comma-separated list of windows, in whole minutes, over which the usage percentiles of the summary API are computed in addition to the last minute, hour and day, e.g. '5m,6h'")
+	summaryQuantiles = flag.String("summary_quantiles", "", "Comma-separated list of quantiles of the usage computed by the summary API in addition to the 50th, 90th, 95th, 99th and 99.9th percentiles, e.g. '0.75,0.8'")
+
+	eventStorageAgeLimit   = flag.String("event_storage_age_limit", "default=24h", "Max length of time for which to store events (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is a duration. Default is applied to all non-specified event types")
+	eventStorageEventLimit = flag.String("event_storage_event_limit", "default=100000", "Max number of events to store (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is an integer. Default is applied to all non-specified event types")
+
+	applicationMetricsCountLimit = flag.Int("application_metrics_count_limit", 100, "Max number of application metrics to store (per container)")
+	statsdListenAddress          = flag.String("statsd_listen_address", "", "Address of the statsd listener receiving application metrics from containers, udp://<host>:<port> or unix://<path>; disabled if empty")
+	enableCollectorAPI           = flag.Bool("enable_collector_api", false, "Whether application metrics collectors can be added to and removed from containers through the API. Only enable it if the API is not reachable by untrusted clients")
+
+	statsPlugins       = flag.String("stats_plugins", "", "Comma-separated list of the addresses of the plugins collecting container stats, unix://<path> or <host>:<port>")
+	statsPluginTimeout = flag.Duration("stats_plugin_timeout", time.Second, "Max duration of the calls to the stats plugins")
+
+	extraFsMounts   = flag.String("extra_fs_mounts", "", "Comma-separated list of mount points of the host whose filesystems are tracked in the machine stats in addition to the partitions of the supported filesystem types, e.g. /data,/var/lib/etcd")
+	fsUsageInterval = flag.Duration("fs_usage_interval", common.DefaultPeriod, "Interval between disk usage measurements of container filesystems")
+
+	machineIDFilePath         = flag.String("machine_id_file", "/etc/machine-id,/var/lib/dbus/machine-id", "Comma-separated list of files to check for machine-id. Use the first one that exists.")
+	bootIDFilePath            = flag.String("boot_id_file", "/proc/sys/kernel/random/boot_id", "Comma-separated list of files to check for boot-id. Use the first one that exists.")
+	machineInfoSectionTimeout = flag.Duration("machine_info_section_timeout", 10*time.Second, "Maximum time to wait for a section of the machine information (e.g. topology, filesystems) to be gathered. Sections which fail or time out are reported in the machine info errors.")
+	cloudMetadataTimeout      = flag.Duration("cloud_metadata_timeout", cloudinfo.DefaultTimeout, "Maximum time to wait for the metadata service of the detected cloud provider to describe the instance. Machines outside of the cloud are detected from DMI data and never query metadata services.")
+	memoryCalibration         = flag.Bool("memory_calibration", false, "Measure memory bandwidth and latency of every NUMA node once on startup and report the results in machine info. Takes a few seconds and allocates 64MiB per node, one node at a time.")
+
+	storageBreakdownMaxFiles       = flag.Int("storage_breakdown_max_files", 100000, "Max number of files visited to break down the usage of a container's writable layer, the breakdown is incomplete once reached")
+	storageBreakdownFilesPerSecond = flag.Int("storage_breakdown_files_per_second", 10000, "Max number of files visited per second to break down the usage of a container's writable layer")
+
+	containerInclude        = flag.String("container_include", "", "Regular expression matching the names of the containers monitored, e.g. ^/kubepods. Empty value monitors all containers.")
+	containerExclude        = flag.String("container_exclude", "", "Regular expression matching the names of the containers not monitored, e.g. ^/system.slice/. It takes precedence over container_include.")
+	containerLabelSelector  = flag.String("container_label_selector", "", "Comma-separated requirements on the labels of the containers monitored: key=value, key!=value, key or !key, e.g. io.kubernetes.container.name!=POD")
+	containerNamespaces     = flag.String("container_namespaces", "", "Comma-separated Kubernetes namespaces whose containers are monitored, a namespace prefixed by ! being excluded instead, e.g. !kube-system. Containers outside of pods are not filtered by namespace.")
+	nestedCgroupsContainers = flag.String("nested_cgroups_containers", "", "Regular expression matching the names of the containers whose child cgroups are monitored as nested containers, in addition to the containers labeled "+manager.NestedCgroupsLabel+"=true")
+
+	adHocWatchMaxTTL = flag.Duration("adhoc_watch_max_ttl", 24*time.Hour, "Longest time a cgroup may be monitored on request through the watch API before the request is renewed")
+	adHocWatchLimit  = flag.Int("adhoc_watch_limit", 100, "Max number of cgroups monitored on request through the watch API at once")
+)
+
+// managerOptions returns the options of the manager set by the flags.
+func managerOptions(includedMetrics container.MetricSet, collectorHTTPClient *http.Client) (manager.Options, error) {
+	options := manager.Options{
+		HousekeepingInterval:                  *housekeepingInterval,
+		MaxHousekeepingInterval:               *maxHousekeepingInterval,
+		AllowDynamicHousekeeping:              *allowDynamicHousekeeping,
+		OnDemandStatsTTL:                      *onDemandStatsTTL,
+		GlobalHousekeepingInterval:            *globalHousekeepingInterval,
+		UpdateMachineInfoInterval:             *updateMachineInfoInterval,
+		PerfInterval:                          *perfInterval,
+		ResctrlInterval:                       *resctrlInterval,
+		PerfEventsFile:                        *perfEvents,
+		IncludedMetrics:                       includedMetrics,
+		RawContainerCgroupPathPrefixWhiteList: strings.Split(*rawCgroupPrefixWhiteList, ","),
+		EnableLoadReader:                      *enableLoadReader,
+		LogCadvisorUsage:                      *logCadvisorUsage,
+		CollectorHTTPClient:                   collectorHTTPClient,
+		ApplicationMetricsCountLimit:          *applicationMetricsCountLimit,
+		StatsdListenAddress:                   *statsdListenAddress,
+		EnableCollectorAPI:                    *enableCollectorAPI,
+		FsUsageInterval:                       *fsUsageInterval,
+		StorageBreakdownMaxFiles:              *storageBreakdownMaxFiles,
+		StorageBreakdownFilesPerSecond:        *storageBreakdownFilesPerSecond,
+		StatsPluginTimeout:                    *statsPluginTimeout,
+		ContainerInclude:                      *containerInclude,
+		ContainerExclude:                      *containerExclude,
+		ContainerLabelSelector:                *containerLabelSelector,
+		ContainerNamespaces:                   *containerNamespaces,
+		NestedCgroupsContainers:               *nestedCgroupsContainers,
+		EventStoragePolicy:                    parseEventsStoragePolicy(),
+		AdHocWatchMaxTTL:                      *adHocWatchMaxTTL,
+		AdHocWatchLimit:                       *adHocWatchLimit,
+	}
+	options.MachineInfo = machine.InfoOptions{
+		MachineIDFiles:       *machineIDFilePath,
+		BootIDFiles:          *bootIDFilePath,
+		SectionTimeout:       *machineInfoSectionTimeout,
+		CloudMetadataTimeout: *cloudMetadataTimeout,
+		MemoryCalibration:    *memoryCalibration,
+	}
+	switch *housekeepingMode {
+	case periodicHousekeepingMode:
+	case onDemandHousekeepingMode:
+		options.OnDemandHousekeeping = true
+	default:
+		return manager.Options{}, fmt.Errorf("unknown housekeeping mode %q, expected %q or %q", *housekeepingMode, periodicHousekeepingMode, onDemandHousekeepingMode)
+	}
+	for _, mountpoint := range strings.Split(*extraFsMounts, ",") {
+		if mountpoint = strings.TrimSpace(mountpoint); mountpoint != "" {
+			options.ExtraFsMounts = append(options.ExtraFsMounts, mountpoint)
+		}
+	}
+	for _, address := range strings.Split(*statsPlugins, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			options.StatsPlugins = append(options.StatsPlugins, address)
+		}
+	}
+	var err error
+	options.Summary, err = parseSummaryConfig()
+	if err != nil {
+		return manager.Options{}, err
+	}
+	if localStore != nil && *localStoreEventLimit > 0 {
+		// The manager restores the events when it is created.
+		options.EventStore = localStore
+	}
+	return options, nil
+}
+
+// parseSummaryConfig returns the windows and quantiles of the summary API
+// selected with --summary_windows and --summary_quantiles.
+func parseSummaryConfig() (summary.Config, error) {
+	var config summary.Config
+	for _, w := range strings.Split(*summaryWindows, ",") {
+		if w = strings.TrimSpace(w); w == "" {
+			continue
+		}
+		d, err := time.ParseDuration(w)
+		if err != nil {
+			return summary.Config{}, fmt.Errorf("invalid summary_windows: %v", err)
+		}
+		config.Windows = append(config.Windows, d)
+	}
+	for _, q := range strings.Split(*summaryQuantiles, ",") {
+		if q = strings.TrimSpace(q); q == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(q, 64)
+		if err != nil {
+			return summary.Config{}, fmt.Errorf("invalid summary_quantiles: %v", err)
+		}
+		config.Quantiles = append(config.Quantiles, f)
+	}
+	if err := config.Validate(); err != nil {
+		return summary.Config{}, fmt.Errorf("invalid summary configuration: %v", err)
+	}
+	return config, nil
+}
+
+// Parses the events StoragePolicy from the flags.
+func parseEventsStoragePolicy() events.StoragePolicy {
+	policy := events.DefaultStoragePolicy()
+
+	// Parse max age.
+	parts := strings.Split(*eventStorageAgeLimit, ",")
+	for _, part := range parts {
+		items := strings.Split(part, "=")
+		if len(items) != 2 {
+			klog.Warningf("Unknown event storage policy %q when parsing max age", part)
+			continue
+		}
+		dur, err := time.ParseDuration(items[1])
+		if err != nil {
+			klog.Warningf("Unable to parse event max age duration %q: %v", items[1], err)
+			continue
+		}
+		if items[0] == "default" {
+			policy.DefaultMaxAge = dur
+			continue
+		}
+		policy.PerTypeMaxAge[info.EventType(items[0])] = dur
+	}
+
+	// Parse max number.
+	parts = strings.Split(*eventStorageEventLimit, ",")
+	for _, part := range parts {
+		items := strings.Split(part, "=")
+		if len(items) != 2 {
+			klog.Warningf("Unknown event storage policy %q when parsing max event limit", part)
+			continue
+		}
+		val, err := strconv.Atoi(items[1])
+		if err != nil {
+			klog.Warningf("Unable to parse integer from %q: %v", items[1], err)
+			continue
+		}
+		if items[0] == "default" {
+			policy.DefaultMaxNumEvents = val
+			continue
+		}
+		policy.PerTypeMaxNumEvents[info.EventType(items[0])] = val
+	}
+
+	return policy
+}
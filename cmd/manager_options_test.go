@@ -0,0 +1,50 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/cadvisor/manager"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerOptionsDefaults(t *testing.T) {
+	options, err := managerOptions(manager.DefaultOptions().IncludedMetrics, http.DefaultClient)
+	require.NoError(t, err)
+	// The default flags configure the default manager.
+	assert.Equal(t, manager.DefaultOptions(), options)
+}
+
+func TestManagerOptionsFlags(t *testing.T) {
+	mode := *housekeepingMode
+	defer func() { *housekeepingMode = mode }()
+	*housekeepingMode = "never"
+	_, err := managerOptions(manager.DefaultOptions().IncludedMetrics, http.DefaultClient)
+	assert.Error(t, err)
+	*housekeepingMode = onDemandHousekeepingMode
+	options, err := managerOptions(manager.DefaultOptions().IncludedMetrics, http.DefaultClient)
+	require.NoError(t, err)
+	assert.True(t, options.OnDemandHousekeeping)
+
+	windows := *summaryWindows
+	defer func() { *summaryWindows = windows }()
+	*summaryWindows = "5 minutes"
+	_, err = managerOptions(manager.DefaultOptions().IncludedMetrics, http.DefaultClient)
+	assert.Error(t, err)
+}
@@ -21,13 +21,17 @@ import (
 	"time"
 
 	"github.com/google/cadvisor/cache/memory"
+	"github.com/google/cadvisor/cmd/internal/localstore"
 	_ "github.com/google/cadvisor/cmd/internal/storage/bigquery"
+	_ "github.com/google/cadvisor/cmd/internal/storage/clickhouse"
 	_ "github.com/google/cadvisor/cmd/internal/storage/elasticsearch"
 	_ "github.com/google/cadvisor/cmd/internal/storage/influxdb"
 	_ "github.com/google/cadvisor/cmd/internal/storage/kafka"
 	_ "github.com/google/cadvisor/cmd/internal/storage/redis"
 	_ "github.com/google/cadvisor/cmd/internal/storage/statsd"
 	_ "github.com/google/cadvisor/cmd/internal/storage/stdout"
+	_ "github.com/google/cadvisor/cmd/internal/storage/victoriametrics"
+	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/storage"
 
 	"k8s.io/klog/v2"
@@ -36,22 +40,82 @@ import (
 var (
 	storageDriver   = flag.String("storage_driver", "", fmt.Sprintf("Storage `driver` to use. Data is always cached shortly in memory, this controls where data is pushed besides the local cache. Empty means none, multiple separated by commas. Options are: <empty>, %s", strings.Join(storage.ListDrivers(), ", ")))
 	storageDuration = flag.Duration("storage_duration", 2*time.Minute, "How long to keep data stored (Default: 2min).")
+
+	localStorePath          = flag.String("local_store_path", "", "Path of a database keeping the stats of the last --storage_duration and the events across restarts of cAdvisor. Empty disables it")
+	localStoreFlushInterval = flag.Duration("local_store_flush_interval", 10*time.Second, "Interval between writes of the stats and events to --local_store_path")
+	localStoreEventAgeLimit = flag.Duration("local_store_event_age_limit", 24*time.Hour, "Max length of time for which events are kept in --local_store_path")
+	localStoreEventLimit    = flag.Int("local_store_event_limit", 100000, "Max number of events kept in --local_store_path, 0 disables keeping events")
+
+	downsampleInterval = flag.Duration("storage_downsample_interval", time.Minute, "Interval over which stats are downsampled, see --storage_downsample_duration")
+	downsampleDuration = flag.Duration("storage_downsample_duration", 0, "How long to keep stats downsampled over --storage_downsample_interval in memory, besides the stats of the last --storage_duration. 0 disables downsampling")
 )
 
+// localStore keeps the stats across restarts, nil when disabled.
+var localStore *localstore.Store
+
+// storageDrivers are the queued storage drivers, flushed and closed on exit.
+var storageDrivers []storage.StorageDriver
+
 // NewMemoryStorage creates a memory storage with an optional backend storage option.
+// When --local_store_path is set, it holds the stats saved by the previous run.
 func NewMemoryStorage() (*memory.InMemoryCache, error) {
 	backendStorages := []storage.StorageDriver{}
+	seen := map[string]bool{}
 	for _, driver := range strings.Split(*storageDriver, ",") {
+		driver = strings.TrimSpace(driver)
 		if driver == "" {
 			continue
 		}
-		storage, err := storage.New(driver)
+		if seen[driver] {
+			return nil, fmt.Errorf("storage driver %q is given more than once", driver)
+		}
+		seen[driver] = true
+		backend, err := storage.New(driver)
 		if err != nil {
 			return nil, err
 		}
-		backendStorages = append(backendStorages, storage)
+		// Each driver writes from its own queue, independently of the others.
+		queued, err := storage.NewQueuedDriver(driver, backend, *storage.ArgQueueSize)
+		if err != nil {
+			return nil, err
+		}
+		backendStorages = append(backendStorages, queued)
+		storageDrivers = append(storageDrivers, queued)
 		klog.V(1).Infof("Using backend storage type %q", driver)
 	}
+	if *localStorePath != "" {
+		retention := localstore.EventRetention{
+			MaxAge:    *localStoreEventAgeLimit,
+			MaxEvents: *localStoreEventLimit,
+		}
+		store, err := localstore.Open(*localStorePath, *storageDuration, retention, *localStoreFlushInterval)
+		if err != nil {
+			return nil, err
+		}
+		localStore = store
+		backendStorages = append(backendStorages, store)
+	}
 	klog.V(1).Infof("Caching stats in memory for %v", *storageDuration)
-	return memory.New(*storageDuration, backendStorages), nil
+	var memoryStorage *memory.InMemoryCache
+	if *downsampleDuration > 0 {
+		if *downsampleInterval <= 0 {
+			return nil, fmt.Errorf("invalid --storage_downsample_interval %v", *downsampleInterval)
+		}
+		klog.V(1).Infof("Caching stats downsampled over %v in memory for %v", *downsampleInterval, *downsampleDuration)
+		memoryStorage = memory.NewWithDownsampling(*storageDuration, *downsampleInterval, *downsampleDuration, backendStorages)
+	} else {
+		memoryStorage = memory.New(*storageDuration, backendStorages)
+	}
+	if localStore != nil {
+		restored := 0
+		err := localStore.Restore(func(ref info.ContainerReference, stats []*info.ContainerStats) {
+			memoryStorage.RestoreStats(ref, stats)
+			restored++
+		})
+		if err != nil {
+			return nil, err
+		}
+		klog.V(1).Infof("Restored the stats of %d containers from %s", restored, *localStorePath)
+	}
+	return memoryStorage, nil
 }
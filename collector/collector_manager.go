@@ -15,8 +15,11 @@
 package collector
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/cadvisor/info/v1"
@@ -24,7 +27,18 @@ import (
 
 const metricLabelPrefix = "io.cadvisor.metric."
 
+// Labels announcing the Prometheus endpoint of a container, scraped without
+// a config file.
+const (
+	metricsPortLabel   = "cadvisor.metrics.port"
+	metricsPathLabel   = "cadvisor.metrics.path"
+	metricsSchemeLabel = "cadvisor.metrics.scheme"
+)
+
 type GenericCollectorManager struct {
+	// Protects the collectors, which may be registered and unregistered
+	// while they are collected from.
+	lock               sync.Mutex
 	Collectors         []*collectorData
 	NextCollectionTime time.Time
 }
@@ -34,7 +48,7 @@ type collectorData struct {
 	nextCollectionTime time.Time
 }
 
-// Returns a new CollectorManager that is thread-compatible.
+// Returns a new CollectorManager that is thread-safe.
 func NewCollectorManager() (CollectorManager, error) {
 	return &GenericCollectorManager{
 		Collectors:         []*collectorData{},
@@ -53,15 +67,89 @@ func GetCollectorConfigs(labels map[string]string) map[string]string {
 	return configs
 }
 
+// GetDiscoveredPrometheusConfig returns the config scraping the Prometheus
+// endpoint announced by the cadvisor.metrics.* labels, if any.
+func GetDiscoveredPrometheusConfig(labels map[string]string) (Prometheus, bool, error) {
+	port, ok := labels[metricsPortLabel]
+	if !ok {
+		return Prometheus{}, false, nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return Prometheus{}, false, fmt.Errorf("invalid %s label %q", metricsPortLabel, port)
+	}
+	config := URLConfig{
+		Protocol: "http",
+		Port:     json.Number(port),
+		Path:     "/metrics",
+	}
+	if scheme, ok := labels[metricsSchemeLabel]; ok {
+		if scheme != "http" && scheme != "https" {
+			return Prometheus{}, false, fmt.Errorf("invalid %s label %q", metricsSchemeLabel, scheme)
+		}
+		config.Protocol = scheme
+	}
+	if path, ok := labels[metricsPathLabel]; ok {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		config.Path = path
+	}
+	return Prometheus{Endpoint: EndpointConfig{URLConfig: config}}, true, nil
+}
+
 func (cm *GenericCollectorManager) RegisterCollector(collector Collector) error {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	now := time.Now()
 	cm.Collectors = append(cm.Collectors, &collectorData{
 		collector:          collector,
-		nextCollectionTime: time.Now(),
+		nextCollectionTime: now,
 	})
+	// Collect from the new collector at the next housekeeping.
+	cm.NextCollectionTime = now
 	return nil
 }
 
+func (cm *GenericCollectorManager) UnregisterCollector(name string) error {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	collectors := cm.Collectors[:0]
+	for _, c := range cm.Collectors {
+		if c.collector.Name() != name {
+			collectors = append(collectors, c)
+		}
+	}
+	if len(collectors) == len(cm.Collectors) {
+		return fmt.Errorf("%w %q", ErrUnknownCollector, name)
+	}
+	for i := len(collectors); i < len(cm.Collectors); i++ {
+		cm.Collectors[i] = nil
+	}
+	cm.Collectors = collectors
+	return nil
+}
+
+func (cm *GenericCollectorManager) CollectorNames() []string {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	names := make([]string, 0, len(cm.Collectors))
+	for _, c := range cm.Collectors {
+		names = append(names, c.collector.Name())
+	}
+	return names
+}
+
+// NextCollection returns the next time at which a collector will be ready
+// to collect from, and false if no collector is registered.
+func (cm *GenericCollectorManager) NextCollection() (time.Time, bool) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	return cm.NextCollectionTime, len(cm.Collectors) > 0
+}
+
 func (cm *GenericCollectorManager) GetSpec() ([]v1.MetricSpec, error) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
 	metricSpec := []v1.MetricSpec{}
 	for _, c := range cm.Collectors {
 		specs := c.collector.GetSpec()
@@ -72,6 +160,8 @@ func (cm *GenericCollectorManager) GetSpec() ([]v1.MetricSpec, error) {
 }
 
 func (cm *GenericCollectorManager) Collect() (time.Time, map[string][]v1.MetricVal, error) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
 	var errors []error
 
 	// Collect from all collectors that are ready.
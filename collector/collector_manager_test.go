@@ -15,6 +15,7 @@
 package collector
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -74,3 +75,57 @@ func TestCollect(t *testing.T) {
 	assert.Equal(2, f1.collectedFrom)
 	assert.Equal(1, f2.collectedFrom)
 }
+
+func TestGetDiscoveredPrometheusConfig(t *testing.T) {
+	_, ok, err := GetDiscoveredPrometheusConfig(map[string]string{"io.cadvisor.metric.prometheus": "/config.json"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	config, ok, err := GetDiscoveredPrometheusConfig(map[string]string{"cadvisor.metrics.port": "9090"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, URLConfig{Protocol: "http", Port: "9090", Path: "/metrics"}, config.Endpoint.URLConfig)
+
+	config, ok, err = GetDiscoveredPrometheusConfig(map[string]string{
+		"cadvisor.metrics.port":   "8443",
+		"cadvisor.metrics.path":   "stats/prometheus",
+		"cadvisor.metrics.scheme": "https",
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, URLConfig{Protocol: "https", Port: "8443", Path: "/stats/prometheus"}, config.Endpoint.URLConfig)
+
+	_, _, err = GetDiscoveredPrometheusConfig(map[string]string{"cadvisor.metrics.port": "http"})
+	assert.Error(t, err)
+	_, _, err = GetDiscoveredPrometheusConfig(map[string]string{"cadvisor.metrics.port": "80", "cadvisor.metrics.scheme": "ftp"})
+	assert.Error(t, err)
+}
+
+type namedCollector struct {
+	fakeCollector
+	name string
+}
+
+func (nc *namedCollector) Name() string {
+	return nc.name
+}
+
+func TestUnregisterCollector(t *testing.T) {
+	cm := &GenericCollectorManager{}
+	assert := assert.New(t)
+	assert.NoError(cm.RegisterCollector(&namedCollector{name: "first"}))
+	assert.NoError(cm.RegisterCollector(&namedCollector{name: "second"}))
+	assert.Equal([]string{"first", "second"}, cm.CollectorNames())
+
+	next, ok := cm.NextCollection()
+	assert.True(ok)
+	assert.False(next.After(time.Now()))
+
+	assert.NoError(cm.UnregisterCollector("first"))
+	assert.Equal([]string{"second"}, cm.CollectorNames())
+	assert.True(errors.Is(cm.UnregisterCollector("first"), ErrUnknownCollector))
+
+	assert.NoError(cm.UnregisterCollector("second"))
+	_, ok = cm.NextCollection()
+	assert.False(ok)
+}
@@ -60,6 +60,64 @@ type Prometheus struct {
 
 	// holds names of different metrics that can be collected
 	MetricsConfig []string `json:"metrics_config"`
+
+	// rules applied to the labels of the scraped samples, in order
+	MetricRelabelConfigs []RelabelConfig `json:"metric_relabel_configs,omitempty"`
+
+	// whether the scraped labels are exported as is, overriding container
+	// labels of the same name, rather than with an app_ prefix
+	HonorLabels bool `json:"honor_labels,omitempty"`
+
+	// the token sent as a bearer token to the endpoint
+	BearerToken string `json:"bearer_token,omitempty"`
+
+	// the file, read before each scrape, holding the bearer token
+	BearerTokenFile string `json:"bearer_token_file,omitempty"`
+
+	// the TLS settings used to connect to https endpoints
+	TLSConfig *TLSConfig `json:"tls_config,omitempty"`
+}
+
+// RelabelConfig is a rule rewriting or filtering the labels of the samples,
+// following Prometheus' metric_relabel_configs.
+type RelabelConfig struct {
+	// the labels whose values, joined by the separator, are matched
+	SourceLabels []string `json:"source_labels,omitempty"`
+
+	// the separator of the source label values, ";" by default
+	Separator string `json:"separator,omitempty"`
+
+	// the regular expression matched against the whole joined value, "(.*)"
+	// by default
+	Regex string `json:"regex,omitempty"`
+
+	// the label written by the replace action
+	TargetLabel string `json:"target_label,omitempty"`
+
+	// the value written by the replace action, may refer to the regex
+	// groups, "$1" by default
+	Replacement *string `json:"replacement,omitempty"`
+
+	// one of replace (the default), keep, drop, labeldrop or labelkeep
+	Action string `json:"action,omitempty"`
+}
+
+// TLSConfig holds the TLS settings of a Prometheus endpoint. The files are
+// read from the filesystem of cAdvisor.
+type TLSConfig struct {
+	// the CA certificate to verify the endpoint with, the endpoint is not
+	// verified if unset
+	CAFile string `json:"ca_file,omitempty"`
+
+	// the client certificate and key presented to the endpoint
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+
+	// the name the certificate of the endpoint is verified against
+	ServerName string `json:"server_name,omitempty"`
+
+	// skips the verification of the endpoint even if a CA is set
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
 }
 
 type EndpointConfig struct {
@@ -27,6 +27,14 @@ func (fkm *FakeCollectorManager) RegisterCollector(collector Collector) error {
 	return nil
 }
 
+func (fkm *FakeCollectorManager) UnregisterCollector(name string) error {
+	return nil
+}
+
+func (fkm *FakeCollectorManager) CollectorNames() []string {
+	return []string{}
+}
+
 func (fkm *FakeCollectorManager) GetSpec() ([]v1.MetricSpec, error) {
 	return []v1.MetricSpec{}, nil
 }
@@ -16,11 +16,15 @@ package collector
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"sort"
+	"strings"
 	"time"
 
 	rawmodel "github.com/prometheus/client_model/go"
@@ -44,6 +48,9 @@ type PrometheusCollector struct {
 	// the metrics to gather (uses a map as a set)
 	metricsSet map[string]bool
 
+	// rules rewriting or filtering the scraped samples
+	relabelRules []relabelRule
+
 	// Limit for the number of scaped metrics. If the count is higher,
 	// no metrics will be returned.
 	metricCountLimit int
@@ -59,9 +66,23 @@ func NewPrometheusCollector(collectorName string, configFile []byte, metricCount
 	if err != nil {
 		return nil, err
 	}
+	return NewPrometheusCollectorFromConfig(collectorName, configInJSON, metricCountLimit, containerHandler, httpClient)
+}
 
+// Returns a new collector scraping the endpoint described by configInJSON,
+// e.g. one discovered from the labels of the container
+func NewPrometheusCollectorFromConfig(collectorName string, configInJSON Prometheus, metricCountLimit int, containerHandler container.ContainerHandler, httpClient *http.Client) (*PrometheusCollector, error) {
 	configInJSON.Endpoint.configure(containerHandler)
 
+	relabelRules, err := newRelabelRules(configInJSON.MetricRelabelConfigs)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err = newPrometheusHTTPClient(configInJSON, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
 	minPollingFrequency := configInJSON.PollingFrequency
 
 	// Minimum supported frequency is 1s
@@ -93,11 +114,99 @@ func NewPrometheusCollector(collectorName string, configFile []byte, metricCount
 		pollingFrequency: minPollingFrequency,
 		configFile:       configInJSON,
 		metricsSet:       metricsSet,
+		relabelRules:     relabelRules,
 		metricCountLimit: metricCountLimit,
 		httpClient:       httpClient,
 	}, nil
 }
 
+// newPrometheusHTTPClient returns the client authenticating to the endpoint
+// as configured, httpClient if no authentication is configured.
+func newPrometheusHTTPClient(config Prometheus, httpClient *http.Client) (*http.Client, error) {
+	if config.TLSConfig == nil && config.BearerToken == "" && config.BearerTokenFile == "" {
+		return httpClient, nil
+	}
+	if config.BearerToken != "" && config.BearerTokenFile != "" {
+		return nil, fmt.Errorf("at most one of bearer_token and bearer_token_file must be set")
+	}
+
+	var transport http.RoundTripper = httpClient.Transport
+	if config.TLSConfig != nil {
+		tlsConfig, err := newTLSConfig(config.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		}
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if config.BearerToken != "" || config.BearerTokenFile != "" {
+		transport = &bearerAuthRoundTripper{
+			token:     config.BearerToken,
+			tokenFile: config.BearerTokenFile,
+			next:      transport,
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: httpClient.Timeout}, nil
+}
+
+func newTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	// Like the shared collector client, endpoints are not verified unless a
+	// CA is given.
+	tlsConfig := &tls.Config{
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify || config.CAFile == "",
+	}
+	if config.CAFile != "" {
+		ca, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %q: %v", config.CAFile, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in CA file %q", config.CAFile)
+		}
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return nil, fmt.Errorf("both cert_file and key_file must be set for client certificate authentication")
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %q: %v", config.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// bearerAuthRoundTripper authenticates requests with a bearer token, read
+// from tokenFile on each request if set so that rotated tokens are used.
+type bearerAuthRoundTripper struct {
+	token     string
+	tokenFile string
+	next      http.RoundTripper
+}
+
+func (rt *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := rt.token
+	if rt.tokenFile != "" {
+		content, err := ioutil.ReadFile(rt.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read bearer token file %q: %v", rt.tokenFile, err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+	// RoundTrippers must not modify the request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return rt.next.RoundTrip(req)
+}
+
 // Returns name of the collector
 func (collector *PrometheusCollector) Name() string {
 	return collector.name
@@ -132,11 +241,18 @@ func (collector *PrometheusCollector) GetSpec() []v1.MetricSpec {
 		if _, ok := collector.metricsSet[name]; collector.metricsSet != nil && !ok {
 			continue
 		}
+		// Rules may rename or drop the metric, those matching on other
+		// labels are only applied when collecting.
+		labels, keep := relabel(model.Metric{model.MetricNameLabel: model.LabelValue(name)}, collector.relabelRules)
+		if !keep {
+			continue
+		}
 
 		spec := v1.MetricSpec{
-			Name:   name,
-			Type:   metricType(d.GetType()),
-			Format: v1.FloatType,
+			Name:        string(labels[model.MetricNameLabel]),
+			Type:        metricType(d.GetType()),
+			Format:      v1.FloatType,
+			HonorLabels: collector.configFile.HonorLabels,
 		}
 		specs = append(specs, spec)
 	}
@@ -255,6 +371,12 @@ func (collector *PrometheusCollector) Collect(metrics map[string][]v1.MetricVal)
 			if _, ok := collector.metricsSet[metName]; collector.metricsSet != nil && !ok {
 				continue
 			}
+			relabeled, keep := relabel(sample.Metric, collector.relabelRules)
+			if !keep {
+				continue
+			}
+			sample.Metric = relabeled
+			metName = string(relabeled[model.MetricNameLabel])
 			// TODO Handle multiple labels nicer. Prometheus metrics can have multiple
 			// labels, cadvisor only accepts a single string for the metric label.
 			label := prometheusLabelSetToCadvisorLabel(sample.Metric)
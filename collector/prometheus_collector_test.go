@@ -233,3 +233,57 @@ func TestPrometheusFiltersMetricsCountLimit(t *testing.T) {
 	_, err = NewPrometheusCollector("Prometheus", configFile, 1, containerHandler, http.DefaultClient)
 	assert.Error(err)
 }
+
+func TestPrometheusRelabelAndBearerToken(t *testing.T) {
+	assert := assert.New(t)
+
+	configFile, err := ioutil.ReadFile("config/sample_config_prometheus_relabel.json")
+	assert.NoError(err)
+	containerHandler := containertest.NewMockContainerHandler("mockContainer")
+	collector, err := NewPrometheusCollector("Prometheus", configFile, 100, containerHandler, http.DefaultClient)
+	assert.NoError(err)
+
+	tempServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		text := `# HELP go_gc_duration_seconds A summary of the GC invocation durations.
+# TYPE go_gc_duration_seconds summary
+go_gc_duration_seconds{quantile="0"} 5.8348000000000004e-05
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines{instance="a",job="web"} 16
+`
+		fmt.Fprintln(w, text)
+	}))
+	defer tempServer.Close()
+
+	collector.configFile.Endpoint.URL = tempServer.URL
+
+	spec := collector.GetSpec()
+	assert.Equal([]v1.MetricSpec{{Name: "app_goroutines", Type: v1.MetricGauge, Format: v1.FloatType, HonorLabels: true}}, spec)
+
+	metrics := map[string][]v1.MetricVal{}
+	_, metrics, errMetric := collector.Collect(metrics)
+	assert.NoError(errMetric)
+	assert.Len(metrics, 1)
+	goRoutines := metrics["app_goroutines"]
+	assert.Equal(float64(16), goRoutines[0].FloatValue)
+	assert.Equal(map[string]string{"job": "web"}, goRoutines[0].Labels)
+}
+
+func TestPrometheusInvalidRelabelConfig(t *testing.T) {
+	containerHandler := containertest.NewMockContainerHandler("mockContainer")
+	for _, config := range []string{
+		`{"endpoint": "http://localhost:8080/metrics", "metric_relabel_configs": [{"regex": "("}]}`,
+		`{"endpoint": "http://localhost:8080/metrics", "metric_relabel_configs": [{"action": "drop"}]}`,
+		`{"endpoint": "http://localhost:8080/metrics", "metric_relabel_configs": [{"source_labels": ["a"]}]}`,
+		`{"endpoint": "http://localhost:8080/metrics", "metric_relabel_configs": [{"action": "hashmod"}]}`,
+		`{"endpoint": "http://localhost:8080/metrics", "bearer_token": "a", "bearer_token_file": "b"}`,
+		`{"endpoint": "http://localhost:8080/metrics", "tls_config": {"cert_file": "cert.pem"}}`,
+	} {
+		_, err := NewPrometheusCollector("Prometheus", []byte(config), 100, containerHandler, http.DefaultClient)
+		assert.Error(t, err, config)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	relabelReplace   = "replace"
+	relabelKeep      = "keep"
+	relabelDrop      = "drop"
+	relabelLabelDrop = "labeldrop"
+	relabelLabelKeep = "labelkeep"
+)
+
+// relabelRule is a validated RelabelConfig.
+type relabelRule struct {
+	sourceLabels []model.LabelName
+	separator    string
+	regex        *regexp.Regexp
+	targetLabel  model.LabelName
+	replacement  string
+	action       string
+}
+
+func newRelabelRules(configs []RelabelConfig) ([]relabelRule, error) {
+	rules := make([]relabelRule, 0, len(configs))
+	for i, config := range configs {
+		rule := relabelRule{
+			separator:   ";",
+			replacement: "$1",
+			action:      relabelReplace,
+			targetLabel: model.LabelName(config.TargetLabel),
+		}
+		for _, label := range config.SourceLabels {
+			rule.sourceLabels = append(rule.sourceLabels, model.LabelName(label))
+		}
+		if config.Separator != "" {
+			rule.separator = config.Separator
+		}
+		if config.Replacement != nil {
+			rule.replacement = *config.Replacement
+		}
+		if config.Action != "" {
+			rule.action = strings.ToLower(config.Action)
+		}
+		regex := "(.*)"
+		if config.Regex != "" {
+			regex = config.Regex
+		}
+		var err error
+		rule.regex, err = regexp.Compile("^(?:" + regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q of relabel config %d: %v", regex, i, err)
+		}
+
+		switch rule.action {
+		case relabelReplace:
+			if config.TargetLabel == "" {
+				return nil, fmt.Errorf("relabel config %d: target_label is required by the replace action", i)
+			}
+		case relabelKeep, relabelDrop:
+			if len(config.SourceLabels) == 0 {
+				return nil, fmt.Errorf("relabel config %d: source_labels are required by the %s action", i, rule.action)
+			}
+		case relabelLabelDrop, relabelLabelKeep:
+		default:
+			return nil, fmt.Errorf("relabel config %d: unknown action %q", i, config.Action)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// relabel applies the rules in order to the labels of a sample, including
+// its name, and returns the new labels or false if the sample is dropped.
+func relabel(metric model.Metric, rules []relabelRule) (model.Metric, bool) {
+	if len(rules) == 0 {
+		return metric, true
+	}
+	labels := metric.Clone()
+	for _, rule := range rules {
+		values := make([]string, 0, len(rule.sourceLabels))
+		for _, label := range rule.sourceLabels {
+			values = append(values, string(labels[label]))
+		}
+		value := strings.Join(values, rule.separator)
+
+		switch rule.action {
+		case relabelKeep:
+			if !rule.regex.MatchString(value) {
+				return nil, false
+			}
+		case relabelDrop:
+			if rule.regex.MatchString(value) {
+				return nil, false
+			}
+		case relabelReplace:
+			indexes := rule.regex.FindStringSubmatchIndex(value)
+			if indexes == nil {
+				continue
+			}
+			target := model.LabelName(rule.regex.ExpandString(nil, string(rule.targetLabel), value, indexes))
+			if !target.IsValid() {
+				continue
+			}
+			replacement := string(rule.regex.ExpandString(nil, rule.replacement, value, indexes))
+			if replacement == "" {
+				delete(labels, target)
+			} else {
+				labels[target] = model.LabelValue(replacement)
+			}
+		case relabelLabelDrop:
+			for name := range labels {
+				if rule.regex.MatchString(string(name)) {
+					delete(labels, name)
+				}
+			}
+		case relabelLabelKeep:
+			for name := range labels {
+				if name != model.MetricNameLabel && !rule.regex.MatchString(string(name)) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+	if labels[model.MetricNameLabel] == "" {
+		return nil, false
+	}
+	return labels, true
+}
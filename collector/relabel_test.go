@@ -0,0 +1,103 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelabel(t *testing.T) {
+	empty := ""
+	metric := model.Metric{"__name__": "requests_total", "method": "GET", "code": "200", "pod": "web-1"}
+	tests := []struct {
+		name     string
+		configs  []RelabelConfig
+		expected model.Metric
+	}{
+		{
+			name:     "no rules",
+			expected: metric,
+		},
+		{
+			name: "replace joined source labels",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"method", "code"}, Separator: "_", Regex: "(.*)_2..", TargetLabel: "ok_method"},
+			},
+			expected: model.Metric{"__name__": "requests_total", "method": "GET", "code": "200", "pod": "web-1", "ok_method": "GET"},
+		},
+		{
+			name: "replace with empty value removes the label",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"pod"}, TargetLabel: "pod", Replacement: &empty},
+			},
+			expected: model.Metric{"__name__": "requests_total", "method": "GET", "code": "200"},
+		},
+		{
+			name: "replace not matching",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"code"}, Regex: "5..", TargetLabel: "error", Replacement: &empty},
+			},
+			expected: metric,
+		},
+		{
+			name: "keep matching",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"__name__"}, Regex: "requests_.*", Action: "keep"},
+			},
+			expected: metric,
+		},
+		{
+			name: "keep not matching",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"__name__"}, Regex: "requests", Action: "keep"},
+			},
+		},
+		{
+			name: "drop",
+			configs: []RelabelConfig{
+				{SourceLabels: []string{"code"}, Regex: "2..", Action: "drop"},
+			},
+		},
+		{
+			name: "labelkeep keeps the name",
+			configs: []RelabelConfig{
+				{Regex: "method|code", Action: "labelkeep"},
+			},
+			expected: model.Metric{"__name__": "requests_total", "method": "GET", "code": "200"},
+		},
+		{
+			name: "labeldrop",
+			configs: []RelabelConfig{
+				{Regex: "p.d", Action: "LabelDrop"},
+			},
+			expected: model.Metric{"__name__": "requests_total", "method": "GET", "code": "200"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := newRelabelRules(test.configs)
+			assert.NoError(t, err)
+			labels, keep := relabel(metric, rules)
+			assert.Equal(t, test.expected != nil, keep)
+			assert.Equal(t, test.expected, labels)
+		})
+	}
+	// The sample is not modified.
+	assert.Len(t, metric, 4)
+}
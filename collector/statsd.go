@@ -0,0 +1,474 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/klog/v2"
+
+	v1 "github.com/google/cadvisor/info/v1"
+)
+
+// Maximum size of a statsd datagram.
+const statsdMaxPacketSize = 65535
+
+var invalidStatsdNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// StatsdListener receives statsd and DogStatsD metrics and attributes them
+// to the container which sent them: by the credentials of the peer on unix
+// sockets, by the IP address of the peer on UDP.
+type StatsdListener struct {
+	conn     net.PacketConn
+	procRoot string
+
+	// Limit for the number of metrics per container, metrics received
+	// once it is reached are dropped.
+	metricCountLimit int
+
+	lock sync.Mutex
+	// Metrics of the registered containers by container name.
+	containers map[string]*statsdMetrics
+	// Registered containers by IP address.
+	containerIPs map[string]string
+
+	stop chan struct{}
+}
+
+type statsdMetrics struct {
+	metrics map[string]*statsdMetric
+}
+
+type statsdMetric struct {
+	metricType v1.MetricType
+	// Series of the metric by label string.
+	series map[string]*statsdSeries
+}
+
+type statsdSeries struct {
+	labels    map[string]string
+	value     float64
+	timestamp time.Time
+}
+
+// statsdSample is a value parsed from a statsd line.
+type statsdSample struct {
+	name   string
+	value  float64
+	kind   string
+	rate   float64
+	labels map[string]string
+}
+
+// NewStatsdListener listens on address, either udp://<host>:<port> or
+// unix://<path> for a unix datagram socket. The cgroup of the peers of the
+// unix socket is read from procRoot.
+func NewStatsdListener(address string, metricCountLimit int, procRoot string) (*StatsdListener, error) {
+	var conn net.PacketConn
+	switch {
+	case strings.HasPrefix(address, "udp://"):
+		udpConn, err := net.ListenPacket("udp", strings.TrimPrefix(address, "udp://"))
+		if err != nil {
+			return nil, err
+		}
+		conn = udpConn
+	case strings.HasPrefix(address, "unix://"):
+		socketPath := strings.TrimPrefix(address, "unix://")
+		// Remove the socket of a previous run.
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		unixConn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		if err != nil {
+			return nil, err
+		}
+		if err := enablePassCred(unixConn); err != nil {
+			unixConn.Close()
+			return nil, err
+		}
+		conn = unixConn
+	default:
+		return nil, fmt.Errorf("invalid statsd address %q, expected udp://<host>:<port> or unix://<path>", address)
+	}
+	return &StatsdListener{
+		conn:             conn,
+		procRoot:         procRoot,
+		metricCountLimit: metricCountLimit,
+		containers:       map[string]*statsdMetrics{},
+		containerIPs:     map[string]string{},
+		stop:             make(chan struct{}),
+	}, nil
+}
+
+func enablePassCred(conn *net.UnixConn) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sockErr error
+	err = rawConn.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
+
+// Addr returns the address the listener receives metrics on.
+func (l *StatsdListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Start receives metrics until Stop is called.
+func (l *StatsdListener) Start() {
+	go func() {
+		buf := make([]byte, statsdMaxPacketSize)
+		oob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+		for {
+			n, containerName, err := l.read(buf, oob)
+			if err != nil {
+				select {
+				case <-l.stop:
+					return
+				default:
+				}
+				klog.V(4).Infof("Failed to read statsd packet: %v", err)
+				continue
+			}
+			if containerName == "" {
+				continue
+			}
+			l.add(containerName, buf[:n])
+		}
+	}()
+}
+
+// Stop closes the listener.
+func (l *StatsdListener) Stop() error {
+	close(l.stop)
+	return l.conn.Close()
+}
+
+// read returns the next packet and the name of the container which sent it,
+// empty if it is not a registered container.
+func (l *StatsdListener) read(buf, oob []byte) (int, string, error) {
+	if unixConn, ok := l.conn.(*net.UnixConn); ok {
+		n, oobn, _, _, err := unixConn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return 0, "", err
+		}
+		pid, err := peerPid(oob[:oobn])
+		if err != nil {
+			klog.V(4).Infof("Unable to get the peer of a statsd packet: %v", err)
+			return n, "", nil
+		}
+		return n, l.containerOfPid(pid), nil
+	}
+	n, addr, err := l.conn.ReadFrom(buf)
+	if err != nil {
+		return 0, "", err
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return n, "", nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return n, l.containerIPs[udpAddr.IP.String()], nil
+}
+
+func peerPid(oob []byte) (int, error) {
+	messages, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, err
+	}
+	for i := range messages {
+		cred, err := syscall.ParseUnixCredentials(&messages[i])
+		if err == nil {
+			return int(cred.Pid), nil
+		}
+	}
+	return 0, fmt.Errorf("no credentials")
+}
+
+// containerOfPid returns the innermost registered container holding the
+// process, according to its cgroups.
+func (l *StatsdListener) containerOfPid(pid int) string {
+	content, err := ioutil.ReadFile(path.Join(l.procRoot, "proc", strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		klog.V(4).Infof("Unable to get the cgroup of statsd peer %d: %v", pid, err)
+		return ""
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	containerName := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		// Lines are formatted as hierarchy-ID:controller-list:cgroup-path.
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for name := parts[2]; ; name = path.Dir(name) {
+			if _, ok := l.containers[name]; ok {
+				if len(name) > len(containerName) {
+					containerName = name
+				}
+				break
+			}
+			if name == "/" || name == "." {
+				break
+			}
+		}
+	}
+	return containerName
+}
+
+// Register starts accepting the metrics of a container and returns the
+// collector reporting them. The IP address of the container, if any,
+// attributes the metrics received over UDP.
+func (l *StatsdListener) Register(containerName, ipAddress string) Collector {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.containers[containerName] = &statsdMetrics{metrics: map[string]*statsdMetric{}}
+	if ipAddress != "" {
+		l.containerIPs[ipAddress] = containerName
+	}
+	return &statsdCollector{listener: l, containerName: containerName}
+}
+
+// Unregister drops the metrics of a container.
+func (l *StatsdListener) Unregister(containerName string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.containers, containerName)
+	for ip, name := range l.containerIPs {
+		if name == containerName {
+			delete(l.containerIPs, ip)
+		}
+	}
+}
+
+func (l *StatsdListener) add(containerName string, packet []byte) {
+	now := time.Now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	metrics, ok := l.containers[containerName]
+	if !ok {
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(packet))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		samples, err := parseStatsdLine(line)
+		if err != nil {
+			klog.V(4).Infof("Invalid statsd line %q from container %q: %v", line, containerName, err)
+			continue
+		}
+		for _, sample := range samples {
+			metrics.add(sample, now, l.metricCountLimit)
+		}
+	}
+}
+
+func (m *statsdMetrics) add(sample statsdSample, now time.Time, metricCountLimit int) {
+	switch sample.kind {
+	case "c":
+		m.addValue(sample.name, v1.MetricCumulative, sample.labels, sample.value/sample.rate, true, now, metricCountLimit)
+	case "g":
+		m.addValue(sample.name, v1.MetricGauge, sample.labels, sample.value, false, now, metricCountLimit)
+	case "g+":
+		m.addValue(sample.name, v1.MetricGauge, sample.labels, sample.value, true, now, metricCountLimit)
+	case "ms", "h", "d":
+		// Distributions are summarized by their count and sum.
+		m.addValue(sample.name+"_count", v1.MetricCumulative, sample.labels, 1/sample.rate, true, now, metricCountLimit)
+		m.addValue(sample.name+"_sum", v1.MetricCumulative, sample.labels, sample.value/sample.rate, true, now, metricCountLimit)
+	}
+}
+
+func (m *statsdMetrics) addValue(name string, metricType v1.MetricType, labels map[string]string, value float64, increment bool, now time.Time, metricCountLimit int) {
+	metric, ok := m.metrics[name]
+	if !ok {
+		if len(m.metrics) >= metricCountLimit {
+			return
+		}
+		metric = &statsdMetric{metricType: metricType, series: map[string]*statsdSeries{}}
+		m.metrics[name] = metric
+	}
+	key := statsdLabelString(name, labels)
+	series, ok := metric.series[key]
+	if !ok {
+		series = &statsdSeries{labels: labels}
+		metric.series[key] = series
+	}
+	if increment {
+		series.value += value
+	} else {
+		series.value = value
+	}
+	series.timestamp = now
+}
+
+// statsdLabelString formats the labels of a series like the Prometheus
+// collector does.
+func statsdLabelString(name string, labels map[string]string) string {
+	metric := model.Metric{model.MetricNameLabel: model.LabelValue(name)}
+	for k, v := range labels {
+		metric[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return prometheusLabelSetToCadvisorLabel(metric)
+}
+
+// parseStatsdLine parses a statsd line, name:value[:value...]|type[|@rate],
+// and the DogStatsD tags extension, |#tag:value,tag.
+func parseStatsdLine(line string) ([]statsdSample, error) {
+	sections := strings.Split(line, "|")
+	if len(sections) < 2 {
+		return nil, fmt.Errorf("missing metric type")
+	}
+	colon := strings.Index(sections[0], ":")
+	if colon <= 0 {
+		return nil, fmt.Errorf("missing metric value")
+	}
+	name := invalidStatsdNameCharRE.ReplaceAllString(sections[0][:colon], "_")
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	kind := sections[1]
+	switch kind {
+	case "c", "g", "ms", "h", "d":
+	default:
+		return nil, fmt.Errorf("unsupported metric type %q", kind)
+	}
+
+	rate := 1.0
+	labels := map[string]string{}
+	for _, section := range sections[2:] {
+		switch {
+		case strings.HasPrefix(section, "@"):
+			var err error
+			rate, err = strconv.ParseFloat(section[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid sample rate %q", section)
+			}
+		case strings.HasPrefix(section, "#"):
+			for _, tag := range strings.Split(section[1:], ",") {
+				if tag == "" {
+					continue
+				}
+				parts := strings.SplitN(tag, ":", 2)
+				if parts[0] == "" {
+					continue
+				}
+				value := ""
+				if len(parts) == 2 {
+					value = parts[1]
+				}
+				labels[parts[0]] = value
+			}
+		}
+	}
+
+	var samples []statsdSample
+	for _, rawValue := range strings.Split(sections[0][colon+1:], ":") {
+		sampleKind := kind
+		// Signed gauge values are relative to the current value.
+		if kind == "g" && (strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-")) {
+			sampleKind = "g+"
+		}
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", rawValue)
+		}
+		samples = append(samples, statsdSample{name: name, value: value, kind: sampleKind, rate: rate, labels: labels})
+	}
+	return samples, nil
+}
+
+// statsdCollector reports the statsd metrics received from a container.
+type statsdCollector struct {
+	listener      *StatsdListener
+	containerName string
+}
+
+func (c *statsdCollector) Name() string {
+	return "statsd"
+}
+
+func (c *statsdCollector) GetSpec() []v1.MetricSpec {
+	c.listener.lock.Lock()
+	defer c.listener.lock.Unlock()
+	metrics, ok := c.listener.containers[c.containerName]
+	if !ok {
+		return nil
+	}
+	specs := make([]v1.MetricSpec, 0, len(metrics.metrics))
+	for name, metric := range metrics.metrics {
+		specs = append(specs, v1.MetricSpec{
+			Name:   name,
+			Type:   metric.metricType,
+			Format: v1.FloatType,
+		})
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+func (c *statsdCollector) Collect(metrics map[string][]v1.MetricVal) (time.Time, map[string][]v1.MetricVal, error) {
+	// Metrics are received as they are sent, report them at every
+	// housekeeping.
+	nextCollectionTime := time.Now().Add(time.Second)
+	c.listener.lock.Lock()
+	defer c.listener.lock.Unlock()
+	containerMetrics, ok := c.listener.containers[c.containerName]
+	if !ok {
+		return nextCollectionTime, metrics, nil
+	}
+	for name, metric := range containerMetrics.metrics {
+		for label, series := range metric.series {
+			labels := make(map[string]string, len(series.labels))
+			for k, v := range series.labels {
+				labels[k] = v
+			}
+			metrics[name] = append(metrics[name], v1.MetricVal{
+				FloatValue: series.value,
+				Timestamp:  series.timestamp,
+				Label:      label,
+				Labels:     labels,
+			})
+		}
+	}
+	return nextCollectionTime, metrics, nil
+}
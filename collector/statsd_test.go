@@ -0,0 +1,139 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package collector
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatsdLine(t *testing.T) {
+	samples, err := parseStatsdLine("page.views:1|c|@0.5|#env:prod,canary")
+	assert.NoError(t, err)
+	assert.Equal(t, []statsdSample{
+		{name: "page_views", value: 1, kind: "c", rate: 0.5, labels: map[string]string{"env": "prod", "canary": ""}},
+	}, samples)
+
+	samples, err = parseStatsdLine("queue.size:-3|g")
+	assert.NoError(t, err)
+	assert.Equal(t, []statsdSample{{name: "queue_size", value: -3, kind: "g+", rate: 1, labels: map[string]string{}}}, samples)
+
+	samples, err = parseStatsdLine("latency:10:20|ms")
+	assert.NoError(t, err)
+	assert.Len(t, samples, 2)
+
+	for _, line := range []string{"noType", ":1|c", "a:1|s", "a:x|c", "a:1|c|@2"} {
+		_, err := parseStatsdLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func collectStatsd(t *testing.T, collector Collector) map[string][]v1.MetricVal {
+	_, metrics, err := collector.Collect(map[string][]v1.MetricVal{})
+	require.NoError(t, err)
+	return metrics
+}
+
+func TestStatsdListenerUDP(t *testing.T) {
+	listener, err := NewStatsdListener("udp://127.0.0.1:0", 3, "/")
+	require.NoError(t, err)
+	listener.Start()
+	defer listener.Stop()
+	collector := listener.Register("/docker/app", "127.0.0.1")
+
+	conn, err := net.Dial("udp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("requests:1|c|#code:200\nrequests:2|c|#code:200\nrequests:1|c|#code:500\ntemperature:20|g\ntemperature:+2|g\nlatency:30|ms\n"))
+	require.NoError(t, err)
+
+	var metrics map[string][]v1.MetricVal
+	assert.Eventually(t, func() bool {
+		metrics = collectStatsd(t, collector)
+		return len(metrics) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	values := map[string]float64{}
+	for name, vals := range metrics {
+		for _, val := range vals {
+			values[name+" "+val.Labels["code"]] = val.FloatValue
+		}
+	}
+	// The fourth metric, latency_sum, is over the limit.
+	assert.Equal(t, map[string]float64{
+		"requests 200":   3,
+		"requests 500":   1,
+		"temperature ":   22,
+		"latency_count ": 1,
+	}, values)
+	assert.Equal(t, []v1.MetricSpec{
+		{Name: "latency_count", Type: v1.MetricCumulative, Format: v1.FloatType},
+		{Name: "requests", Type: v1.MetricCumulative, Format: v1.FloatType},
+		{Name: "temperature", Type: v1.MetricGauge, Format: v1.FloatType},
+	}, collector.GetSpec())
+
+	listener.Unregister("/docker/app")
+	assert.Empty(t, collectStatsd(t, collector))
+	assert.Empty(t, collector.GetSpec())
+}
+
+func TestStatsdListenerUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The peer is attributed to the innermost registered container of its
+	// cgroups.
+	procDir := filepath.Join(dir, "proc", strconv.Itoa(os.Getpid()))
+	require.NoError(t, os.MkdirAll(procDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procDir, "cgroup"), []byte("4:memory:/kubepods/pod1/app/init\n1:cpu:/kubepods\n"), 0644))
+
+	socket := filepath.Join(dir, "statsd.sock")
+	listener, err := NewStatsdListener("unix://"+socket, 10, dir)
+	require.NoError(t, err)
+	listener.Start()
+	defer listener.Stop()
+	root := listener.Register("/", "")
+	app := listener.Register("/kubepods/pod1/app", "")
+
+	conn, err := net.Dial("unixgram", socket)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("jobs:4|g"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(collectStatsd(t, app)["jobs"]) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4.0, collectStatsd(t, app)["jobs"][0].FloatValue)
+	assert.Empty(t, collectStatsd(t, root))
+}
+
+func TestNewStatsdListenerInvalidAddress(t *testing.T) {
+	_, err := NewStatsdListener("tcp://127.0.0.1:8125", 10, "/")
+	assert.Error(t, err)
+}
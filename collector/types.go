@@ -42,6 +42,13 @@ type CollectorManager interface {
 	// Register a collector.
 	RegisterCollector(collector Collector) error
 
+	// Unregister the collectors with the given name, returns an error
+	// wrapping ErrUnknownCollector if there is none.
+	UnregisterCollector(name string) error
+
+	// Names of the registered collectors, in registration order.
+	CollectorNames() []string
+
 	// Collect from collectors that are ready and return the next time
 	// at which a collector will be ready to collect from.
 	// Next collection time is always returned, even when an error occurs.
@@ -0,0 +1,95 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+)
+
+var (
+	// ErrInvalidConfig is wrapped by the errors of configs rejected by
+	// NewValidatedCollector.
+	ErrInvalidConfig = errors.New("invalid collector config")
+	// ErrUnknownCollector is wrapped by the errors of requests for
+	// collectors which are not registered.
+	ErrUnknownCollector = errors.New("unknown collector")
+)
+
+var collectorNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// IsPrometheusConfig returns whether the config of the named collector is a
+// Prometheus config rather than a generic one.
+func IsPrometheusConfig(collectorName string) bool {
+	return strings.HasPrefix(collectorName, "prometheus") || strings.HasPrefix(collectorName, "Prometheus")
+}
+
+// NewValidatedCollector returns a new collector for a config submitted at
+// runtime rather than read from the container. Unlike config files, the
+// config is rejected if it has unknown fields, or if it refers to files of
+// cAdvisor such as bearer tokens or TLS keys.
+func NewValidatedCollector(collectorName string, configFile []byte, metricCountLimit int, containerHandler container.ContainerHandler, httpClient *http.Client) (Collector, error) {
+	if !collectorNameRegexp.MatchString(collectorName) {
+		return nil, fmt.Errorf("%w: name %q must only contain letters, digits, '_', '.' and '-'", ErrInvalidConfig, collectorName)
+	}
+	if IsPrometheusConfig(collectorName) {
+		var config Prometheus
+		if err := decodeStrict(configFile, &config); err != nil {
+			return nil, err
+		}
+		if config.BearerTokenFile != "" {
+			return nil, fmt.Errorf("%w: bearer_token_file is not allowed", ErrInvalidConfig)
+		}
+		if config.TLSConfig != nil && (config.TLSConfig.CAFile != "" || config.TLSConfig.CertFile != "" || config.TLSConfig.KeyFile != "") {
+			return nil, fmt.Errorf("%w: files of tls_config are not allowed", ErrInvalidConfig)
+		}
+		newCollector, err := NewPrometheusCollectorFromConfig(collectorName, config, metricCountLimit, containerHandler, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		return newCollector, nil
+	}
+
+	var config Config
+	if err := decodeStrict(configFile, &config); err != nil {
+		return nil, err
+	}
+	newCollector, err := NewCollector(collectorName, configFile, metricCountLimit, containerHandler, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return newCollector, nil
+}
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown fields.
+func decodeStrict(configFile []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(configFile))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("%w: unexpected data after the config", ErrInvalidConfig)
+	}
+	return nil
+}
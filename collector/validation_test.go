@@ -0,0 +1,54 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	containertest "github.com/google/cadvisor/container/testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidatedCollector(t *testing.T) {
+	containerHandler := containertest.NewMockContainerHandler("mockContainer")
+
+	collector, err := NewValidatedCollector("prometheus_app", []byte(`{"endpoint": "http://localhost:8080/metrics", "metrics_config": ["requests"]}`), 10, containerHandler, http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Equal(t, "prometheus_app", collector.Name())
+
+	collector, err = NewValidatedCollector("nginx", []byte(`{"endpoint": "http://localhost:8080/status", "metrics_config": [{"name": "activeConnections", "metric_type": "gauge", "data_type": "int", "regex": "Active connections: ([0-9]+)"}]}`), 10, containerHandler, http.DefaultClient)
+	assert.NoError(t, err)
+	assert.Len(t, collector.GetSpec(), 1)
+
+	for name, tc := range map[string]struct {
+		collectorName string
+		config        string
+	}{
+		"invalid name":       {"../app", `{"endpoint": "http://localhost:8080/status"}`},
+		"unknown field":      {"prometheus", `{"endpoint": "http://localhost:8080/metrics", "metric_config": ["requests"]}`},
+		"trailing data":      {"prometheus", `{"endpoint": "http://localhost:8080/metrics"} {}`},
+		"bearer token file":  {"prometheus", `{"endpoint": "http://localhost:8080/metrics", "bearer_token_file": "/var/run/secrets/token"}`},
+		"tls key file":       {"prometheus", `{"endpoint": "https://localhost:8443/metrics", "tls_config": {"key_file": "/etc/ssl/private/key.pem"}}`},
+		"invalid relabeling": {"prometheus", `{"endpoint": "http://localhost:8080/metrics", "metric_relabel_configs": [{"action": "replace"}]}`},
+		"no generic metrics": {"nginx", `{"endpoint": "http://localhost:8080/status"}`},
+		"invalid regex":      {"nginx", `{"endpoint": "http://localhost:8080/status", "metrics_config": [{"name": "a", "regex": "("}]}`},
+	} {
+		_, err := NewValidatedCollector(tc.collectorName, []byte(tc.config), 10, containerHandler, http.DefaultClient)
+		assert.True(t, errors.Is(err, ErrInvalidConfig), "%s: %v", name, err)
+	}
+}
@@ -56,6 +56,22 @@ const (
 
 const DefaultPeriod = time.Minute
 
+// fsUsageInterval is the period between disk usage measurements of container
+// filesystems. Slow measurements back off up to maxBackoffFactor times it.
+var fsUsageInterval = DefaultPeriod
+
+// FsUsageInterval returns the period between disk usage measurements of
+// container filesystems.
+func FsUsageInterval() time.Duration {
+	return fsUsageInterval
+}
+
+// SetFsUsageInterval sets the period between disk usage measurements of the
+// container filesystems handled afterwards.
+func SetFsUsageInterval(interval time.Duration) {
+	fsUsageInterval = interval
+}
+
 var _ FsHandler = &realFsHandler{}
 
 func NewFsHandler(period time.Duration, rootfs, extraDir string, fsInfo fs.FsInfo) FsHandler {
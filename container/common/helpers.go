@@ -20,8 +20,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/cadvisor/container"
@@ -34,6 +36,18 @@ import (
 	"k8s.io/klog/v2"
 )
 
+const cpuSysfsDir = "/sys/devices/system/cpu"
+
+// RestoredFromAnnotation is the annotation, or the label for runtimes without
+// annotations, holding the ID of the container a container was restored from
+// with CRIU. It is set by the tooling restoring the checkpoint.
+const RestoredFromAnnotation = "io.cadvisor.restored-from"
+
+var (
+	hostIsolatedCpusOnce sync.Once
+	hostIsolatedCpus     []int
+)
+
 func DebugInfo(watches map[string][]string) map[string][]string {
 	out := make(map[string][]string)
 
@@ -125,10 +139,18 @@ func GetSpec(cgroupPaths map[string]string, machineInfoFactory info.MachineInfoF
 			mask := ""
 			if cgroups.IsCgroup2UnifiedMode() {
 				mask = readString(cpusetRoot, "cpuset.cpus.effective")
+				spec.Cpu.EffectiveMask = mask
+				spec.Cpu.MemoryNodes = readString(cpusetRoot, "cpuset.mems.effective")
 			} else {
 				mask = readString(cpusetRoot, "cpuset.cpus")
+				spec.Cpu.EffectiveMask = readString(cpusetRoot, "cpuset.effective_cpus")
+				spec.Cpu.MemoryNodes = readString(cpusetRoot, "cpuset.effective_mems")
 			}
 			spec.Cpu.Mask = utils.FixCpuMask(mask, mi.NumCores)
+			if spec.Cpu.EffectiveMask == "" {
+				spec.Cpu.EffectiveMask = spec.Cpu.Mask
+			}
+			spec.Cpu.IsolatedCpus = isolatedCpus(spec.Cpu.EffectiveMask, getHostIsolatedCpus())
 		}
 	}
 
@@ -187,6 +209,62 @@ func GetSpec(cgroupPaths map[string]string, machineInfoFactory info.MachineInfoF
 	return spec, nil
 }
 
+// getHostIsolatedCpus returns the CPUs of the host which are isolated from
+// the scheduler (isolcpus) or run without scheduling-clock ticks (nohz_full).
+// Both are boot parameters, so they are only read once.
+func getHostIsolatedCpus() []int {
+	hostIsolatedCpusOnce.Do(func() {
+		hostIsolatedCpus = readIsolatedCpus(cpuSysfsDir)
+	})
+	return hostIsolatedCpus
+}
+
+func readIsolatedCpus(dir string) []int {
+	isolated := map[int]struct{}{}
+	for _, file := range []string{"isolated", "nohz_full"} {
+		list := readString(dir, file)
+		cpus, err := utils.ParseCpuList(list)
+		if err != nil {
+			// Some kernels report "(null)" when nohz_full is not configured.
+			klog.V(4).Infof("Unable to parse %q from %s: %v", list, path.Join(dir, file), err)
+			continue
+		}
+		for _, cpu := range cpus {
+			isolated[cpu] = struct{}{}
+		}
+	}
+	result := make([]int, 0, len(isolated))
+	for cpu := range isolated {
+		result = append(result, cpu)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// isolatedCpus returns the CPUs of the mask which are isolated, in the
+// kernel's list format.
+func isolatedCpus(mask string, isolated []int) string {
+	if len(isolated) == 0 {
+		return ""
+	}
+	cpus, err := utils.ParseCpuList(mask)
+	if err != nil {
+		klog.V(4).Infof("Unable to parse cpu mask %q: %v", mask, err)
+		return ""
+	}
+	isolatedSet := make(map[int]struct{}, len(isolated))
+	for _, cpu := range isolated {
+		isolatedSet[cpu] = struct{}{}
+	}
+	result := []int{}
+	for _, cpu := range cpus {
+		if _, ok := isolatedSet[cpu]; ok {
+			result = append(result, cpu)
+		}
+	}
+	return utils.FormatCpuList(result)
+}
+
 func readString(dirpath string, file string) string {
 	cgroupFile := path.Join(dirpath, file)
 
@@ -314,7 +392,9 @@ func assignDeviceNamesToPerDiskStats(namer DeviceNamer, diskStats ...[]info.PerD
 	devices := make(deviceIdentifierMap)
 	for _, stats := range diskStats {
 		for i, stat := range stats {
-			stats[i].Device = devices.Find(stat.Major, stat.Minor, namer)
+			device := devices.Find(stat.Major, stat.Minor, namer)
+			stats[i].Device = device.name
+			stats[i].PhysicalDevices = device.physical
 		}
 	}
 }
@@ -326,8 +406,33 @@ type DeviceNamer interface {
 	DeviceName(major, minor uint64) (string, bool)
 }
 
+// PhysicalDeviceNamer is implemented by DeviceNamers which know the physical disks stacked
+// devices, e.g. LVM volumes or multipath devices, are built on.
+type PhysicalDeviceNamer interface {
+	// PhysicalDeviceNames returns the names of the physical disks below the device by its
+	// major and minor ids, nil for physical disks and unknown devices.
+	PhysicalDeviceNames(major, minor uint64) []string
+}
+
 type MachineInfoNamer info.MachineInfo
 
+func (n *MachineInfoNamer) PhysicalDeviceNames(major, minor uint64) []string {
+	for _, info := range n.DiskMap {
+		if info.Major != major || info.Minor != minor {
+			continue
+		}
+		if len(info.PhysicalDisks) == 0 {
+			return nil
+		}
+		names := make([]string, 0, len(info.PhysicalDisks))
+		for _, disk := range info.PhysicalDisks {
+			names = append(names, "/dev/"+disk)
+		}
+		return names
+	}
+	return nil
+}
+
 func (n *MachineInfoNamer) DeviceName(major, minor uint64) (string, bool) {
 	for _, info := range n.DiskMap {
 		if info.Major == major && info.Minor == minor {
@@ -347,15 +452,24 @@ type deviceIdentifier struct {
 	minor uint64
 }
 
-type deviceIdentifierMap map[deviceIdentifier]string
+type deviceNames struct {
+	name     string
+	physical []string
+}
+
+type deviceIdentifierMap map[deviceIdentifier]deviceNames
 
 // Find locates the device name by device identifier out of from, caching the result as necessary.
-func (m deviceIdentifierMap) Find(major, minor uint64, namer DeviceNamer) string {
+func (m deviceIdentifierMap) Find(major, minor uint64, namer DeviceNamer) deviceNames {
 	d := deviceIdentifier{major, minor}
 	if s, ok := m[d]; ok {
 		return s
 	}
-	s, _ := namer.DeviceName(major, minor)
+	var s deviceNames
+	s.name, _ = namer.DeviceName(major, minor)
+	if physicalNamer, ok := namer.(PhysicalDeviceNamer); ok {
+		s.physical = physicalNamer.PhysicalDeviceNames(major, minor)
+	}
 	m[d] = s
 	return s
 }
@@ -16,6 +16,9 @@ package common
 
 import (
 	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkListDirectories(b *testing.B) {
@@ -26,3 +29,38 @@ func BenchmarkListDirectories(b *testing.B) {
 		}
 	}
 }
+
+func TestReadIsolatedCpus(t *testing.T) {
+	assert.Equal(t, []int{2, 3, 6, 7}, readIsolatedCpus("test_resources/cpu_isolated"))
+	assert.Equal(t, []int{}, readIsolatedCpus("test_resources/cpu_nohz_null"))
+}
+
+func TestIsolatedCpus(t *testing.T) {
+	isolated := []int{2, 3, 6, 7}
+	assert.Equal(t, "2-3,6", isolatedCpus("0-6", isolated))
+	assert.Equal(t, "", isolatedCpus("0-1,4", isolated))
+	assert.Equal(t, "", isolatedCpus("0-7", nil))
+	assert.Equal(t, "", isolatedCpus("invalid", isolated))
+}
+
+func TestAssignDeviceNamesToDiskStats(t *testing.T) {
+	namer := &MachineInfoNamer{
+		DiskMap: map[string]info.DiskInfo{
+			"8:0":   {Name: "sda", Major: 8, Minor: 0},
+			"8:16":  {Name: "sdb", Major: 8, Minor: 16},
+			"253:0": {Name: "dm-0", Major: 253, Minor: 0, Slaves: []string{"sda2", "sdb"}, PhysicalDisks: []string{"sda", "sdb"}},
+		},
+	}
+	stats := &info.DiskIoStats{
+		IoServiceBytes: []info.PerDiskStats{{Major: 253, Minor: 0}, {Major: 8, Minor: 0}, {Major: 7, Minor: 0}},
+		IoServiced:     []info.PerDiskStats{{Major: 253, Minor: 0}},
+	}
+	AssignDeviceNamesToDiskStats(namer, stats)
+
+	assert.Equal(t, []info.PerDiskStats{
+		{Device: "/dev/dm-0", Major: 253, Minor: 0, PhysicalDevices: []string{"/dev/sda", "/dev/sdb"}},
+		{Device: "/dev/sda", Major: 8, Minor: 0},
+		{Device: "", Major: 7, Minor: 0},
+	}, stats.IoServiceBytes)
+	assert.Equal(t, []string{"/dev/sda", "/dev/sdb"}, stats.IoServiced[0].PhysicalDevices)
+}
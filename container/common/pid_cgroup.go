@@ -0,0 +1,57 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+// ProcRoot returns the path under which the host's procfs is visible to
+// cAdvisor. It follows the same convention as the manager: if the host's root
+// is mounted at /rootfs, cAdvisor is assumed to run in its own namespaces.
+func ProcRoot() string {
+	if _, err := os.Stat("/rootfs/proc"); err == nil {
+		return "/rootfs/proc"
+	}
+	return "/proc"
+}
+
+// CgroupNameForPid returns the name of the container (i.e. the cgroup path
+// relative to the hierarchy root) that the process with the given pid belongs
+// to. procRoot is where the host's procfs is mounted.
+func CgroupNameForPid(procRoot string, pid int) (string, error) {
+	if pid <= 0 {
+		return "", fmt.Errorf("invalid pid %d", pid)
+	}
+	paths, err := cgroups.ParseCgroupFile(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	// On the unified hierarchy the only entry has an empty controller list.
+	if name, ok := paths[""]; ok && cgroups.IsCgroup2UnifiedMode() {
+		return name, nil
+	}
+	for _, subsystem := range []string{"cpu", "memory", "pids"} {
+		if name, ok := paths[subsystem]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no cgroup found for pid %d", pid)
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCgroupNameForPid(t *testing.T) {
+	expected := "/docker/abc"
+	if cgroups.IsCgroup2UnifiedMode() {
+		expected = "/system.slice/containerd.service"
+	}
+	name, err := CgroupNameForPid("test_resources/proc", 42)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, name)
+}
+
+func TestCgroupNameForPidErrors(t *testing.T) {
+	_, err := CgroupNameForPid("test_resources/proc", 0)
+	assert.NotNil(t, err)
+
+	_, err = CgroupNameForPid("test_resources/proc", 44)
+	assert.NotNil(t, err)
+
+	if !cgroups.IsCgroup2UnifiedMode() {
+		_, err = CgroupNameForPid("test_resources/proc", 43)
+		assert.NotNil(t, err)
+	}
+}
@@ -35,6 +35,12 @@ const (
 	ContainerTypeCrio
 	ContainerTypeContainerd
 	ContainerTypeMesos
+	ContainerTypePodman
+	ContainerTypeCRI
+	ContainerTypeKata
+	ContainerTypeGvisor
+	ContainerTypeLxd
+	ContainerTypeFirecracker
 )
 
 // Interface for container operation handlers.
@@ -76,3 +82,10 @@ type ContainerHandler interface {
 	// Type of handler
 	Type() ContainerType
 }
+
+// StorageDirHandler is implemented by the handlers of containers with a
+// writable layer on the host filesystem.
+type StorageDirHandler interface {
+	// Returns the directory of the writable layer, empty if it is unknown.
+	GetRootfsStorageDir() string
+}
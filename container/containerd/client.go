@@ -21,9 +21,14 @@ import (
 	"sync"
 	"time"
 
+	apievents "github.com/containerd/containerd/api/events"
 	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	eventsapi "github.com/containerd/containerd/api/services/events/v1"
+	imagesapi "github.com/containerd/containerd/api/services/images/v1"
+	snapshotsapi "github.com/containerd/containerd/api/services/snapshots/v1"
 	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
 	versionapi "github.com/containerd/containerd/api/services/version/v1"
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
 	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/pkg/dialer"
@@ -35,13 +40,22 @@ import (
 type client struct {
 	containerService containersapi.ContainersClient
 	taskService      tasksapi.TasksClient
+	snapshotService  snapshotsapi.SnapshotsClient
+	imageService     imagesapi.ImagesClient
 	versionService   versionapi.VersionClient
+	eventService     eventsapi.EventsClient
+	namespace        string
 }
 
 type ContainerdClient interface {
 	LoadContainer(ctx context.Context, id string) (*containers.Container, error)
 	TaskPid(ctx context.Context, id string) (uint32, error)
+	SnapshotMounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error)
+	ImageDigest(ctx context.Context, name string) (string, error)
 	Version(ctx context.Context) (string, error)
+	// WatchTaskStarts calls handle for every task started in the namespace
+	// until ctx is done or the event stream fails.
+	WatchTaskStarts(ctx context.Context, handle func(*apievents.TaskStart)) error
 }
 
 var once sync.Once
@@ -91,7 +105,11 @@ func Client(address, namespace string) (ContainerdClient, error) {
 		ctrdClient = &client{
 			containerService: containersapi.NewContainersClient(conn),
 			taskService:      tasksapi.NewTasksClient(conn),
+			snapshotService:  snapshotsapi.NewSnapshotsClient(conn),
+			imageService:     imagesapi.NewImagesClient(conn),
 			versionService:   versionapi.NewVersionClient(conn),
+			eventService:     eventsapi.NewEventsClient(conn),
+			namespace:        namespace,
 		}
 	})
 	return ctrdClient, retErr
@@ -117,6 +135,30 @@ func (c *client) TaskPid(ctx context.Context, id string) (uint32, error) {
 	return response.Process.Pid, nil
 }
 
+func (c *client) SnapshotMounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error) {
+	response, err := c.snapshotService.Mounts(ctx, &snapshotsapi.MountsRequest{
+		Snapshotter: snapshotter,
+		Key:         key,
+	})
+	if err != nil {
+		return nil, errdefs.FromGRPC(err)
+	}
+	return response.Mounts, nil
+}
+
+func (c *client) ImageDigest(ctx context.Context, name string) (string, error) {
+	response, err := c.imageService.Get(ctx, &imagesapi.GetImageRequest{
+		Name: name,
+	})
+	if err != nil {
+		return "", errdefs.FromGRPC(err)
+	}
+	if response.Image == nil {
+		return "", fmt.Errorf("image %q not found", name)
+	}
+	return response.Image.Target.Digest.String(), nil
+}
+
 func (c *client) Version(ctx context.Context) (string, error) {
 	response, err := c.versionService.Version(ctx, &ptypes.Empty{})
 	if err != nil {
@@ -125,6 +167,29 @@ func (c *client) Version(ctx context.Context) (string, error) {
 	return response.Version, nil
 }
 
+func (c *client) WatchTaskStarts(ctx context.Context, handle func(*apievents.TaskStart)) error {
+	stream, err := c.eventService.Subscribe(ctx, &eventsapi.SubscribeRequest{
+		Filters: []string{fmt.Sprintf(`topic=="/tasks/start",namespace==%q`, c.namespace)},
+	})
+	if err != nil {
+		return errdefs.FromGRPC(err)
+	}
+	for {
+		envelope, err := stream.Recv()
+		if err != nil {
+			return errdefs.FromGRPC(err)
+		}
+		if envelope.Event == nil {
+			continue
+		}
+		event := &apievents.TaskStart{}
+		if err := event.Unmarshal(envelope.Event.Value); err != nil {
+			return fmt.Errorf("failed to decode %s event: %v", envelope.Topic, err)
+		}
+		handle(event)
+	}
+}
+
 func containerFromProto(containerpb containersapi.Container) *containers.Container {
 	var runtime containers.RuntimeInfo
 	if containerpb.Runtime != nil {
@@ -18,12 +18,17 @@ import (
 	"context"
 	"fmt"
 
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
 )
 
 type containerdClientMock struct {
-	cntrs     map[string]*containers.Container
-	returnErr error
+	cntrs      map[string]*containers.Container
+	mounts     map[string][]*types.Mount
+	digests    map[string]string
+	taskStarts []*apievents.TaskStart
+	returnErr  error
 }
 
 func (c *containerdClientMock) LoadContainer(ctx context.Context, id string) (*containers.Container, error) {
@@ -45,6 +50,33 @@ func (c *containerdClientMock) TaskPid(ctx context.Context, id string) (uint32,
 	return 2389, nil
 }
 
+func (c *containerdClientMock) SnapshotMounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error) {
+	mounts, ok := c.mounts[key]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %q does not exist", key)
+	}
+	return mounts, nil
+}
+
+func (c *containerdClientMock) ImageDigest(ctx context.Context, name string) (string, error) {
+	digest, ok := c.digests[name]
+	if !ok {
+		return "", fmt.Errorf("image %q not found", name)
+	}
+	return digest, nil
+}
+
+func (c *containerdClientMock) WatchTaskStarts(ctx context.Context, handle func(*apievents.TaskStart)) error {
+	if c.returnErr != nil {
+		return c.returnErr
+	}
+	for _, event := range c.taskStarts {
+		handle(event)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
 func mockcontainerdClient(cntrs map[string]*containers.Container, returnErr error) ContainerdClient {
 	return &containerdClientMock{
 		cntrs:     cntrs,
@@ -18,11 +18,14 @@ package containerd
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/errdefs"
 	"golang.org/x/net/context"
+	"k8s.io/klog/v2"
 
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/common"
@@ -32,6 +35,19 @@ import (
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
+const (
+	// Labels and annotations set by the containerd CRI plugin.
+	criContainerKindLabel      = "io.cri-containerd.kind"
+	criContainerTypeAnnotation = "io.kubernetes.cri.container-type"
+	criSandboxIDAnnotation     = "io.kubernetes.cri.sandbox-id"
+	criImageNameAnnotation     = "io.kubernetes.cri.image-name"
+
+	criSandboxContainerType = "sandbox"
+
+	// Label holding the digest the container image resolved to.
+	imageDigestLabel = "io.containerd.image.digest"
+)
+
 type containerdContainerHandler struct {
 	machineInfoFactory info.MachineInfoFactory
 	// Absolute path to the cgroup hierarchies of this container.
@@ -44,13 +60,22 @@ type containerdContainerHandler struct {
 	labels    map[string]string
 	// Image name used for this container.
 	image string
+	// ID of the container this container was restored from, if any.
+	restoredFrom string
+	// Whether the container is the sandbox (infrastructure) container of a pod.
+	sandbox bool
+	// Directory holding the writable layer of the container rootfs.
+	rootfsStorageDir string
 	// Filesystem handler.
+	fsHandler common.FsHandler
+
 	includedMetrics container.MetricSet
 
 	libcontainerHandler *containerlibcontainer.Handler
 }
 
 var _ container.ContainerHandler = &containerdContainerHandler{}
+var _ container.StorageDirHandler = &containerdContainerHandler{}
 
 // newContainerdContainerHandler returns a new container.ContainerHandler
 func newContainerdContainerHandler(
@@ -126,25 +151,80 @@ func newContainerdContainerHandler(
 		cgroupPaths:         cgroupPaths,
 		fsInfo:              fsInfo,
 		envs:                make(map[string]string),
-		labels:              cntr.Labels,
+		labels:              make(map[string]string, len(cntr.Labels)),
 		includedMetrics:     includedMetrics,
 		reference:           containerReference,
 		libcontainerHandler: libcontainerHandler,
 	}
-	// Add the name and bare ID as aliases of the container.
+	for k, v := range cntr.Labels {
+		handler.labels[k] = v
+	}
+	handler.sandbox = cntr.Labels[criContainerKindLabel] == criSandboxContainerType ||
+		spec.Annotations[criContainerTypeAnnotation] == criSandboxContainerType
+	if sandboxID, ok := spec.Annotations[criSandboxIDAnnotation]; ok && !handler.sandbox {
+		handler.labels[criSandboxIDAnnotation] = sandboxID
+	}
+
+	handler.restoredFrom = spec.Annotations[common.RestoredFromAnnotation]
+
+	// The CRI plugin records the image by its ID, prefer the name the user asked for.
 	handler.image = cntr.Image
-	for _, envVar := range spec.Process.Env {
-		if envVar != "" {
-			splits := strings.SplitN(envVar, "=", 2)
-			if len(splits) == 2 {
-				handler.envs[splits[0]] = splits[1]
+	if imageName, ok := spec.Annotations[criImageNameAnnotation]; ok && imageName != "" {
+		handler.image = imageName
+	}
+	if cntr.Image != "" {
+		digest, err := client.ImageDigest(ctx, cntr.Image)
+		if err != nil {
+			klog.V(4).Infof("Unable to resolve digest of image %q for container %q: %v", cntr.Image, id, err)
+		} else {
+			handler.labels[imageDigestLabel] = digest
+		}
+	}
+
+	if spec.Process != nil {
+		for _, envVar := range spec.Process.Env {
+			if envVar != "" {
+				splits := strings.SplitN(envVar, "=", 2)
+				if len(splits) == 2 {
+					handler.envs[splits[0]] = splits[1]
+				}
 			}
 		}
 	}
 
+	// we optionally collect disk usage metrics
+	if includedMetrics.Has(container.DiskUsageMetrics) && cntr.SnapshotKey != "" {
+		mounts, err := client.SnapshotMounts(ctx, cntr.Snapshotter, cntr.SnapshotKey)
+		if err != nil {
+			klog.V(4).Infof("Unable to get rootfs mounts of container %q: %v", id, err)
+		} else if dir := SnapshotUpperDir(mounts); dir != "" {
+			handler.rootfsStorageDir = filepath.Join(rootfs, dir)
+			handler.fsHandler = common.NewFsHandler(common.FsUsageInterval(), handler.rootfsStorageDir, "", fsInfo)
+		}
+	}
+
 	return handler, nil
 }
 
+// SnapshotUpperDir returns the directory holding the writable layer of a
+// container rootfs described by the mounts of its active snapshot, empty if
+// there is none.
+func SnapshotUpperDir(mounts []*types.Mount) string {
+	for _, m := range mounts {
+		switch m.Type {
+		case "overlay":
+			for _, option := range m.Options {
+				if strings.HasPrefix(option, "upperdir=") {
+					return strings.TrimPrefix(option, "upperdir=")
+				}
+			}
+		case "bind", "rbind":
+			return m.Source
+		}
+	}
+	return ""
+}
+
 func (h *containerdContainerHandler) ContainerReference() (info.ContainerReference, error) {
 	return h.reference, nil
 }
@@ -154,20 +234,24 @@ func (h *containerdContainerHandler) needNet() bool {
 	// on includedMetrics list. Here the assumption is the presence of cri-containerd
 	// label
 	if h.includedMetrics.Has(container.NetworkUsageMetrics) {
-		//TODO change it to exported cri-containerd constants
-		return h.labels["io.cri-containerd.kind"] == "sandbox"
+		return h.sandbox
 	}
 	return false
 }
 
 func (h *containerdContainerHandler) GetSpec() (info.ContainerSpec, error) {
-	// TODO: Since we dont collect disk usage stats for containerd, we set hasFilesystem
-	// to false. Revisit when we support disk usage stats for containerd
-	hasFilesystem := false
+	hasFilesystem := h.fsHandler != nil
 	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
 	spec.Labels = h.labels
 	spec.Envs = h.envs
 	spec.Image = h.image
+	spec.RestoredFrom = h.restoredFrom
+
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
 
 	return spec, err
 }
@@ -181,6 +265,40 @@ func (h *containerdContainerHandler) getFsStats(stats *info.ContainerStats) erro
 	if h.includedMetrics.Has(container.DiskIOMetrics) {
 		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
 	}
+
+	if !h.includedMetrics.Has(container.DiskUsageMetrics) || h.fsHandler == nil {
+		return nil
+	}
+	deviceInfo, err := h.fsInfo.GetDirFsDevice(h.rootfsStorageDir)
+	if err != nil {
+		return fmt.Errorf("unable to determine device info for dir: %v: %v", h.rootfsStorageDir, err)
+	}
+
+	var (
+		limit  uint64
+		fsType string
+	)
+
+	// containerd does not impose any filesystem limits for containers. So use capacity as limit.
+	for _, fs := range mi.Filesystems {
+		if fs.Device == deviceInfo.Device {
+			limit = fs.Capacity
+			fsType = fs.Type
+			break
+		}
+	}
+
+	if fsType == "" {
+		return fmt.Errorf("unable to determine fs type for device: %v", deviceInfo.Device)
+	}
+	fsStat := info.FsStats{Device: deviceInfo.Device, Type: fsType, Limit: limit}
+	usage := h.fsHandler.Usage()
+	fsStat.BaseUsage = usage.BaseUsageBytes
+	fsStat.Usage = usage.TotalUsageBytes
+	fsStat.Inodes = usage.InodeUsage
+
+	stats.Filesystem = append(stats.Filesystem, fsStat)
+
 	return nil
 }
 
@@ -230,10 +348,20 @@ func (h *containerdContainerHandler) Type() container.ContainerType {
 	return container.ContainerTypeContainerd
 }
 
+func (h *containerdContainerHandler) GetRootfsStorageDir() string {
+	return h.rootfsStorageDir
+}
+
 func (h *containerdContainerHandler) Start() {
+	if h.fsHandler != nil {
+		h.fsHandler.Start()
+	}
 }
 
 func (h *containerdContainerHandler) Cleanup() {
+	if h.fsHandler != nil {
+		h.fsHandler.Stop()
+	}
 }
 
 func (h *containerdContainerHandler) GetContainerIPAddress() string {
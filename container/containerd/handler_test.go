@@ -18,9 +18,11 @@ package containerd
 import (
 	"testing"
 
+	"github.com/containerd/containerd/api/types"
 	"github.com/containerd/containerd/containers"
 	"github.com/containerd/typeurl"
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
 	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
@@ -103,3 +105,62 @@ func TestHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestHandlerResolvesCRIMetadata(t *testing.T) {
+	as := assert.New(t)
+	id := "40af7cdcbe507acad47a5a62025743ad3ddc6ab93b77b21363aa1c1d641047c9"
+	testContainer := &containers.Container{
+		ID:          id,
+		Labels:      map[string]string{"io.cri-containerd.kind": "container"},
+		Image:       "sha256:4e5d1f2a",
+		Snapshotter: "overlayfs",
+		SnapshotKey: id,
+	}
+	spec := &specs.Spec{
+		Root:    &specs.Root{Path: "/test/"},
+		Process: &specs.Process{Env: []string{"FOO=bar"}},
+		Annotations: map[string]string{
+			criContainerTypeAnnotation:    "container",
+			criSandboxIDAnnotation:        "sandbox-id",
+			criImageNameAnnotation:        "docker.io/library/nginx:latest",
+			common.RestoredFromAnnotation: "3b8fd2a4c4a1",
+		},
+	}
+	testContainer.Spec, _ = typeurl.MarshalAny(spec)
+	client := &containerdClientMock{
+		cntrs: map[string]*containers.Container{id: testContainer},
+		mounts: map[string][]*types.Mount{
+			id: {{
+				Type:    "overlay",
+				Source:  "overlay",
+				Options: []string{"lowerdir=/var/lib/containerd/snapshots/1/fs", "upperdir=/var/lib/containerd/snapshots/2/fs", "workdir=/var/lib/containerd/snapshots/2/work"},
+			}},
+		},
+		digests: map[string]string{"sha256:4e5d1f2a": "sha256:0123"},
+	}
+	includedMetrics := container.MetricSet{container.DiskUsageMetrics: struct{}{}}
+
+	handler, err := newContainerdContainerHandler(client, "/kubepods/pod068e8fa0/"+id, nil, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil, includedMetrics)
+	as.Nil(err)
+
+	h := handler.(*containerdContainerHandler)
+	as.False(h.sandbox)
+	as.Equal("docker.io/library/nginx:latest", h.image)
+	as.Equal("3b8fd2a4c4a1", h.restoredFrom)
+	as.Equal("/var/lib/containerd/snapshots/2/fs", h.rootfsStorageDir)
+	as.NotNil(h.fsHandler)
+	as.Equal(map[string]string{
+		"io.cri-containerd.kind": "container",
+		criSandboxIDAnnotation:   "sandbox-id",
+		imageDigestLabel:         "sha256:0123",
+	}, h.GetContainerLabels())
+	as.Equal(map[string]string{"FOO": "bar"}, h.envs)
+}
+
+func TestSnapshotUpperDir(t *testing.T) {
+	as := assert.New(t)
+	as.Equal("/snapshots/2/fs", SnapshotUpperDir([]*types.Mount{{Type: "overlay", Options: []string{"lowerdir=/snapshots/1/fs", "upperdir=/snapshots/2/fs"}}}))
+	as.Equal("/snapshots/3/fs", SnapshotUpperDir([]*types.Mount{{Type: "bind", Source: "/snapshots/3/fs", Options: []string{"rbind", "rw"}}}))
+	as.Equal("", SnapshotUpperDir([]*types.Mount{{Type: "overlay", Options: []string{"lowerdir=/snapshots/1/fs"}}}))
+	as.Equal("", SnapshotUpperDir(nil))
+}
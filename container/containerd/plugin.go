@@ -33,6 +33,12 @@ func (p *plugin) InitializeFSContext(context *fs.Context) error {
 }
 
 func (p *plugin) Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) (watcher.ContainerWatcher, error) {
-	err := Register(factory, fsInfo, includedMetrics)
-	return nil, err
+	if err := Register(factory, fsInfo, includedMetrics); err != nil {
+		return nil, err
+	}
+	client, err := Client(*ArgContainerdEndpoint, *ArgContainerdNamespace)
+	if err != nil {
+		return nil, err
+	}
+	return newContainerdWatcher(client), nil
 }
@@ -0,0 +1,104 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerd
+
+import (
+	"context"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	minEventsRetryDelay = time.Second
+	maxEventsRetryDelay = 30 * time.Second
+)
+
+// containerdWatcher subscribes to containerd's task start events and reports
+// containers as soon as their task runs, instead of waiting for the cgroup to
+// be picked up by the next housekeeping pass. Container removal is left to the
+// raw watcher.
+type containerdWatcher struct {
+	client   ContainerdClient
+	procRoot string
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+var _ watcher.ContainerWatcher = &containerdWatcher{}
+
+func newContainerdWatcher(client ContainerdClient) *containerdWatcher {
+	return &containerdWatcher{
+		client:   client,
+		procRoot: common.ProcRoot(),
+	}
+}
+
+func (w *containerdWatcher) Start(events chan watcher.ContainerEvent) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		delay := minEventsRetryDelay
+		for {
+			err := w.client.WatchTaskStarts(ctx, func(event *apievents.TaskStart) {
+				w.handleTaskStart(ctx, events, event)
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			klog.Warningf("containerd event stream closed, retrying in %v: %v", delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxEventsRetryDelay {
+				delay = maxEventsRetryDelay
+			}
+		}
+	}()
+	return nil
+}
+
+func (w *containerdWatcher) Stop() error {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+	return nil
+}
+
+func (w *containerdWatcher) handleTaskStart(ctx context.Context, events chan watcher.ContainerEvent, event *apievents.TaskStart) {
+	name, err := common.CgroupNameForPid(w.procRoot, int(event.Pid))
+	if err != nil {
+		klog.V(4).Infof("Unable to find cgroup of started containerd task %q: %v", event.ContainerID, err)
+		return
+	}
+	select {
+	case events <- watcher.ContainerEvent{
+		EventType:   watcher.ContainerAdd,
+		Name:        name,
+		WatchSource: watcher.Raw,
+	}:
+	case <-ctx.Done():
+	}
+}
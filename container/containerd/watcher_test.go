@@ -0,0 +1,68 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containerd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apievents "github.com/containerd/containerd/api/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/google/cadvisor/watcher"
+)
+
+func TestContainerdWatcherReportsStartedTasks(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(procRoot, "2389"), 0755))
+	cgroup := "4:cpu,cpuacct:/kubepods/pod1/abc\n0::/kubepods/pod1/abc\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "2389", "cgroup"), []byte(cgroup), 0644))
+
+	client := &containerdClientMock{
+		taskStarts: []*apievents.TaskStart{
+			{ContainerID: "gone", Pid: 2390},
+			{ContainerID: "abc", Pid: 2389},
+		},
+	}
+	w := newContainerdWatcher(client)
+	w.procRoot = procRoot
+
+	events := make(chan watcher.ContainerEvent, 1)
+	require.NoError(t, w.Start(events))
+	defer w.Stop()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, watcher.ContainerEvent{
+			EventType:   watcher.ContainerAdd,
+			Name:        "/kubepods/pod1/abc",
+			WatchSource: watcher.Raw,
+		}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for container event")
+	}
+}
+
+func TestContainerdWatcherStop(t *testing.T) {
+	w := newContainerdWatcher(&containerdClientMock{})
+	require.NoError(t, w.Start(make(chan watcher.ContainerEvent)))
+	assert.NoError(t, w.Stop())
+}
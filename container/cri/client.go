@@ -0,0 +1,149 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// CRIClient is the subset of the CRI runtime service used by cAdvisor.
+type CRIClient interface {
+	// Container returns the container with the given id, nil if unknown.
+	Container(ctx context.Context, id string) (*criapi.Container, error)
+	// PodSandbox returns the pod sandbox with the given id, nil if unknown.
+	PodSandbox(ctx context.Context, id string) (*criapi.PodSandbox, error)
+	// ContainerStatus returns the verbose status of a container.
+	ContainerStatus(ctx context.Context, id string) (*criapi.ContainerStatusResponse, error)
+	// PodSandboxStatus returns the verbose status of a pod sandbox.
+	PodSandboxStatus(ctx context.Context, id string) (*criapi.PodSandboxStatusResponse, error)
+	// Version returns the name and version of the runtime.
+	Version(ctx context.Context) (string, error)
+}
+
+type client struct {
+	runtimeService criapi.RuntimeServiceClient
+}
+
+var once sync.Once
+var criClient CRIClient = nil
+
+const (
+	maxBackoffDelay   = 3 * time.Second
+	baseBackoffDelay  = 100 * time.Millisecond
+	connectionTimeout = 2 * time.Second
+)
+
+// Client creates a client of the CRI runtime service listening on the given
+// unix socket, e.g. "/run/containerd/containerd.sock" or
+// "unix:///var/run/crio/crio.sock".
+func Client(endpoint string) (CRIClient, error) {
+	var retErr error
+	once.Do(func() {
+		address := strings.TrimPrefix(endpoint, "unix://")
+		tryConn, err := net.DialTimeout("unix", address, connectionTimeout)
+		if err != nil {
+			retErr = fmt.Errorf("cri: cannot unix dial runtime service: %v", err)
+			return
+		}
+		tryConn.Close()
+
+		connParams := grpc.ConnectParams{
+			Backoff: backoff.DefaultConfig,
+		}
+		connParams.Backoff.BaseDelay = baseBackoffDelay
+		connParams.Backoff.MaxDelay = maxBackoffDelay
+		gopts := []grpc.DialOption{
+			grpc.WithInsecure(),
+			grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+			}),
+			grpc.WithBlock(),
+			grpc.WithConnectParams(connParams),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), connectionTimeout)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, address, gopts...)
+		if err != nil {
+			retErr = err
+			return
+		}
+		criClient = &client{
+			runtimeService: criapi.NewRuntimeServiceClient(conn),
+		}
+	})
+	return criClient, retErr
+}
+
+func (c *client) Container(ctx context.Context, id string) (*criapi.Container, error) {
+	response, err := c.runtimeService.ListContainers(ctx, &criapi.ListContainersRequest{
+		Filter: &criapi.ContainerFilter{Id: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range response.Containers {
+		if c.Id == id {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *client) PodSandbox(ctx context.Context, id string) (*criapi.PodSandbox, error) {
+	response, err := c.runtimeService.ListPodSandbox(ctx, &criapi.ListPodSandboxRequest{
+		Filter: &criapi.PodSandboxFilter{Id: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range response.Items {
+		if s.Id == id {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *client) ContainerStatus(ctx context.Context, id string) (*criapi.ContainerStatusResponse, error) {
+	return c.runtimeService.ContainerStatus(ctx, &criapi.ContainerStatusRequest{
+		ContainerId: id,
+		Verbose:     true,
+	})
+}
+
+func (c *client) PodSandboxStatus(ctx context.Context, id string) (*criapi.PodSandboxStatusResponse, error) {
+	return c.runtimeService.PodSandboxStatus(ctx, &criapi.PodSandboxStatusRequest{
+		PodSandboxId: id,
+		Verbose:      true,
+	})
+}
+
+func (c *client) Version(ctx context.Context) (string, error) {
+	response, err := c.runtimeService.Version(ctx, &criapi.VersionRequest{})
+	if err != nil {
+		return "", err
+	}
+	return response.RuntimeName + " " + response.RuntimeVersion, nil
+}
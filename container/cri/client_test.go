@@ -0,0 +1,107 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"fmt"
+
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+type criClientMock struct {
+	containers map[string]*criapi.Container
+	sandboxes  map[string]*criapi.PodSandbox
+	pids       map[string]int
+	returnErr  error
+}
+
+func (c *criClientMock) Container(ctx context.Context, id string) (*criapi.Container, error) {
+	if c.returnErr != nil {
+		return nil, c.returnErr
+	}
+	return c.containers[id], nil
+}
+
+func (c *criClientMock) PodSandbox(ctx context.Context, id string) (*criapi.PodSandbox, error) {
+	if c.returnErr != nil {
+		return nil, c.returnErr
+	}
+	return c.sandboxes[id], nil
+}
+
+func (c *criClientMock) statusInfo(id string) map[string]string {
+	pid, ok := c.pids[id]
+	if !ok {
+		return nil
+	}
+	return map[string]string{"info": fmt.Sprintf(`{"pid": %d}`, pid)}
+}
+
+func (c *criClientMock) ContainerStatus(ctx context.Context, id string) (*criapi.ContainerStatusResponse, error) {
+	if _, ok := c.containers[id]; !ok {
+		return nil, fmt.Errorf("unable to find container %q", id)
+	}
+	return &criapi.ContainerStatusResponse{Info: c.statusInfo(id)}, nil
+}
+
+func (c *criClientMock) PodSandboxStatus(ctx context.Context, id string) (*criapi.PodSandboxStatusResponse, error) {
+	if _, ok := c.sandboxes[id]; !ok {
+		return nil, fmt.Errorf("unable to find pod sandbox %q", id)
+	}
+	return &criapi.PodSandboxStatusResponse{Info: c.statusInfo(id)}, nil
+}
+
+func (c *criClientMock) Version(ctx context.Context) (string, error) {
+	return "mock 1.0.0", nil
+}
+
+const (
+	testContainerID = "3f7c6ae1b95a0f1e86d5ea4e6e9f2d1c8f4c7c0cbb6e6a0f3d8a5d3c1e0b9a87"
+	testSandboxID   = "9a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789abcdef"
+)
+
+func newTestClient() *criClientMock {
+	podLabels := map[string]string{
+		"io.kubernetes.pod.name":      "web-0",
+		"io.kubernetes.pod.namespace": "default",
+	}
+	containerLabels := map[string]string{
+		"io.kubernetes.container.name": "nginx",
+		"io.kubernetes.pod.name":       "web-0",
+		"io.kubernetes.pod.namespace":  "default",
+	}
+	return &criClientMock{
+		containers: map[string]*criapi.Container{
+			testContainerID: {
+				Id:           testContainerID,
+				PodSandboxId: testSandboxID,
+				Metadata:     &criapi.ContainerMetadata{Name: "nginx"},
+				Image:        &criapi.ImageSpec{Image: "docker.io/library/nginx:latest"},
+				CreatedAt:    1611000000000000000,
+				Labels:       containerLabels,
+			},
+		},
+		sandboxes: map[string]*criapi.PodSandbox{
+			testSandboxID: {
+				Id:        testSandboxID,
+				Metadata:  &criapi.PodSandboxMetadata{Name: "web-0", Namespace: "default"},
+				CreatedAt: 1610999999000000000,
+				Labels:    podLabels,
+			},
+		},
+		pids: map[string]int{testContainerID: 4242},
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+)
+
+var ArgCRIEndpoint = flag.String("cri_endpoint", "", "CRI runtime service endpoint, e.g. /run/containerd/containerd.sock. Containers not claimed by a runtime specific factory are looked up there. Empty value disables the generic CRI factory.")
+
+// The namespace under which CRI aliases are unique.
+const CRINamespace = "cri"
+
+const (
+	// Timeout of the lookups of containers in the runtime.
+	lookupTimeout = 2 * time.Second
+	// Time the result of a lookup of a container is cached.
+	lookupCacheTTL = 30 * time.Second
+)
+
+var (
+	// Regexp that identifies the id of CRI containers and sandboxes in
+	// cgroup names, e.g. "cri-containerd-<id>.scope", "crio-<id>.scope" or
+	// "<id>" with the cgroupfs driver.
+	criCgroupRegexp = regexp.MustCompile(`([a-f0-9]{64})`)
+)
+
+type criFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+	client             CRIClient
+	version            string
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+	// Information about mounted filesystems.
+	fsInfo          fs.FsInfo
+	includedMetrics container.MetricSet
+
+	lookupsLock sync.Mutex
+	// Results of the lookups of container and sandbox ids in the runtime.
+	lookups map[string]criLookup
+}
+
+type criLookup struct {
+	known   bool
+	expires time.Time
+}
+
+func (f *criFactory) String() string {
+	return CRINamespace
+}
+
+func (f *criFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	return newCRIContainerHandler(
+		f.client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToCRIID returns the CRI container or sandbox id from the full
+// container name.
+func ContainerNameToCRIID(name string) string {
+	id := path.Base(name)
+	if matches := criCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+func isContainerName(name string) bool {
+	base := path.Base(name)
+	// Mounts of the container rootfs and the monitor process of cri-o
+	// share the id of the container.
+	if strings.HasSuffix(base, ".mount") || strings.Contains(base, "conmon") {
+		return false
+	}
+	return criCgroupRegexp.MatchString(base)
+}
+
+// cri handles all containers and pod sandboxes known to the runtime behind
+// the CRI endpoint.
+func (f *criFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !isContainerName(name) {
+		return false, false, nil
+	}
+	known, err := f.lookup(ContainerNameToCRIID(name), time.Now())
+	return known, known, err
+}
+
+// lookup returns whether the runtime knows a container or pod sandbox with
+// the given id. Results are cached for lookupCacheTTL, errors are not.
+func (f *criFactory) lookup(id string, now time.Time) (bool, error) {
+	f.lookupsLock.Lock()
+	if l, ok := f.lookups[id]; ok && now.Before(l.expires) {
+		f.lookupsLock.Unlock()
+		return l.known, nil
+	}
+	f.lookupsLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	known := false
+	c, err := f.client.Container(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to list container %q: %v", id, err)
+	}
+	if c != nil {
+		known = true
+	} else {
+		s, err := f.client.PodSandbox(ctx, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to list pod sandbox %q: %v", id, err)
+		}
+		known = s != nil
+	}
+
+	f.lookupsLock.Lock()
+	defer f.lookupsLock.Unlock()
+	if f.lookups == nil {
+		f.lookups = make(map[string]criLookup)
+	}
+	for k, l := range f.lookups {
+		if !now.Before(l.expires) {
+			delete(f.lookups, k)
+		}
+	}
+	f.lookups[id] = criLookup{known: known, expires: now.Add(lookupCacheTTL)}
+	return known, nil
+}
+
+func (f *criFactory) DebugInfo() map[string][]string {
+	return map[string][]string{
+		"endpoint": {*ArgCRIEndpoint},
+		"version":  {f.version},
+	}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	if *ArgCRIEndpoint == "" {
+		return fmt.Errorf("no CRI endpoint configured")
+	}
+	client, err := Client(*ArgCRIEndpoint)
+	if err != nil {
+		return fmt.Errorf("unable to create CRI client: %v", err)
+	}
+
+	version, err := client.Version(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to fetch CRI runtime version: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering CRI factory for %s", version)
+	f := &criFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		client:             client,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		version:            version,
+		includedMetrics:    includedMetrics,
+	}
+
+	// Asked after the runtime specific factories, so that it only handles
+	// the containers they do not claim.
+	container.RegisterContainerHandlerFactoryWithPriority(f, container.FallbackFactoryPriority, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
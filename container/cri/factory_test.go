@@ -0,0 +1,84 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerNameToCRIID(t *testing.T) {
+	for _, name := range []string{
+		"/kubepods/besteffort/pod1234/" + testContainerID,
+		"/kubepods.slice/kubepods-besteffort.slice/cri-containerd-" + testContainerID + ".scope",
+		"/kubepods.slice/kubepods-besteffort.slice/crio-" + testContainerID + ".scope",
+	} {
+		assert.Equal(t, testContainerID, ContainerNameToCRIID(name), name)
+	}
+}
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	f := &criFactory{client: newTestClient()}
+
+	for _, test := range []struct {
+		name   string
+		handle bool
+	}{
+		{"/kubepods/besteffort/pod1234/" + testContainerID, true},
+		{"/kubepods.slice/crio-" + testSandboxID + ".scope", true},
+		{"/kubepods.slice/crio-conmon-" + testContainerID + ".scope", false},
+		{"/system.slice/run-containerd-" + testContainerID + "-rootfs.mount", false},
+		{"/kubepods/besteffort/pod1234/0000000000000000000000000000000000000000000000000000000000000000", false},
+		{"/system.slice/docker.service", false},
+	} {
+		canHandle, canAccept, err := f.CanHandleAndAccept(test.name)
+		as.Nil(err, test.name)
+		as.Equal(test.handle, canHandle, test.name)
+		as.Equal(test.handle, canAccept, test.name)
+	}
+
+	// Lookups are cached.
+	f.client = &criClientMock{returnErr: fmt.Errorf("connection refused")}
+	canHandle, _, err := f.CanHandleAndAccept("/kubepods/besteffort/pod1234/" + testContainerID)
+	as.Nil(err)
+	as.True(canHandle)
+
+	f.lookups = nil
+	canHandle, _, err = f.CanHandleAndAccept("/kubepods/besteffort/pod1234/" + testContainerID)
+	as.NotNil(err)
+	as.False(canHandle)
+}
+
+func TestLookupCacheExpires(t *testing.T) {
+	f := &criFactory{client: newTestClient()}
+	now := time.Now()
+	known, err := f.lookup(testContainerID, now)
+	assert.NoError(t, err)
+	assert.True(t, known)
+
+	f.client = &criClientMock{}
+	known, err = f.lookup(testContainerID, now.Add(lookupCacheTTL-time.Second))
+	assert.NoError(t, err)
+	assert.True(t, known)
+
+	known, err = f.lookup(testContainerID, now.Add(lookupCacheTTL))
+	assert.NoError(t, err)
+	assert.False(t, known)
+	assert.Len(t, f.lookups, 1)
+}
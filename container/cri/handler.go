@@ -0,0 +1,266 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for containers managed by any CRI runtime.
+package cri
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// verboseInfo is the part of the verbose status info shared by the CRI
+// runtimes, it is stored as JSON under the "info" key.
+type verboseInfo struct {
+	Pid int `json:"pid"`
+}
+
+type criContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+	// Metadata associated with the container.
+	reference    info.ContainerReference
+	labels       map[string]string
+	creationTime time.Time
+	// Image name used for this container.
+	image string
+	// Whether this is a pod sandbox, which owns the network namespace of the pod.
+	sandbox bool
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &criContainerHandler{}
+
+// newCRIContainerHandler returns a new container.ContainerHandler
+func newCRIContainerHandler(
+	client CRIClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ContainerNameToCRIID(name)
+	handler := &criContainerHandler{
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		includedMetrics:    includedMetrics,
+	}
+
+	ctx := context.Background()
+	var (
+		metadataName string
+		labels       map[string]string
+		statusInfo   map[string]string
+	)
+	c, err := client.Container(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list container %q: %v", id, err)
+	}
+	if c != nil {
+		if c.Metadata != nil {
+			metadataName = c.Metadata.Name
+		}
+		labels = c.Labels
+		handler.creationTime = time.Unix(0, c.CreatedAt)
+		if c.Image != nil {
+			handler.image = c.Image.Image
+		}
+		if handler.image == "" {
+			handler.image = c.ImageRef
+		}
+		status, err := client.ContainerStatus(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of container %q: %v", id, err)
+		}
+		statusInfo = status.Info
+	} else {
+		s, err := client.PodSandbox(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pod sandbox %q: %v", id, err)
+		}
+		if s == nil {
+			return nil, fmt.Errorf("container or pod sandbox %q is unknown to the CRI runtime", id)
+		}
+		if s.Metadata != nil {
+			metadataName = s.Metadata.Name
+		}
+		labels = s.Labels
+		handler.creationTime = time.Unix(0, s.CreatedAt)
+		handler.sandbox = true
+		status, err := client.PodSandboxStatus(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of pod sandbox %q: %v", id, err)
+		}
+		statusInfo = status.Info
+	}
+
+	handler.labels = make(map[string]string, len(labels))
+	for k, v := range labels {
+		handler.labels[k] = v
+	}
+
+	aliases := []string{id}
+	if metadataName != "" {
+		aliases = []string{metadataName, id}
+	}
+	handler.reference = info.ContainerReference{
+		Id:        id,
+		Name:      name,
+		Aliases:   aliases,
+		Namespace: CRINamespace,
+	}
+
+	pid := statusPid(statusInfo)
+	if pid == 0 {
+		// Not every runtime reports the pid, any process of the container
+		// shares its namespaces.
+		if pids, err := cgroupManager.GetPids(); err == nil && len(pids) > 0 {
+			pid = pids[0]
+		} else {
+			klog.V(4).Infof("Unable to determine pid of container %q", id)
+		}
+	}
+
+	rootfs := "/"
+	if !inHostNamespace {
+		rootfs = "/rootfs"
+	}
+	handler.libcontainerHandler = containerlibcontainer.NewHandler(cgroupManager, rootfs, pid, includedMetrics)
+
+	return handler, nil
+}
+
+// statusPid returns the pid from the verbose status info of a container or
+// pod sandbox, 0 if it is not reported.
+func statusPid(statusInfo map[string]string) int {
+	raw, ok := statusInfo["info"]
+	if !ok {
+		return 0
+	}
+	var v verboseInfo
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return 0
+	}
+	return v.Pid
+}
+
+func (h *criContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *criContainerHandler) needNet() bool {
+	// Containers of a pod share the network namespace of its sandbox, so
+	// only the sandbox reports network stats.
+	if h.includedMetrics.Has(container.NetworkUsageMetrics) {
+		return h.sandbox
+	}
+	return false
+}
+
+func (h *criContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasFilesystem := false
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
+	spec.Labels = h.labels
+	spec.Image = h.image
+	if !h.creationTime.IsZero() {
+		spec.CreationTime = h.creationTime
+	}
+
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
+
+	return spec, err
+}
+
+func (h *criContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+	if !h.needNet() {
+		stats.Network = info.NetworkStats{}
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *criContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return []info.ContainerReference{}, nil
+}
+
+func (h *criContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *criContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *criContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *criContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *criContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeCRI
+}
+
+func (h *criContainerHandler) Start() {}
+
+func (h *criContainerHandler) Cleanup() {}
+
+func (h *criContainerHandler) GetContainerIPAddress() string {
+	return ""
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cri
+
+import (
+	"testing"
+	"time"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	as := assert.New(t)
+	client := newTestClient()
+
+	name := "/kubepods/besteffort/pod1234/" + testContainerID
+	handler, err := newCRIContainerHandler(client, name, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.Nil(err)
+
+	ref, err := handler.ContainerReference()
+	as.Nil(err)
+	as.Equal(info.ContainerReference{
+		Id:        testContainerID,
+		Name:      name,
+		Aliases:   []string{"nginx", testContainerID},
+		Namespace: CRINamespace,
+	}, ref)
+	as.Equal("web-0", handler.GetContainerLabels()["io.kubernetes.pod.name"])
+	as.Equal("nginx", handler.GetContainerLabels()["io.kubernetes.container.name"])
+
+	h := handler.(*criContainerHandler)
+	as.Equal("docker.io/library/nginx:latest", h.image)
+	as.Equal(time.Unix(0, 1611000000000000000), h.creationTime)
+	as.False(h.sandbox)
+}
+
+func TestSandboxHandler(t *testing.T) {
+	as := assert.New(t)
+	client := newTestClient()
+
+	name := "/kubepods/besteffort/pod1234/" + testSandboxID
+	handler, err := newCRIContainerHandler(client, name, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.Nil(err)
+
+	ref, err := handler.ContainerReference()
+	as.Nil(err)
+	as.Equal([]string{"web-0", testSandboxID}, ref.Aliases)
+	as.Equal("default", handler.GetContainerLabels()["io.kubernetes.pod.namespace"])
+	as.True(handler.(*criContainerHandler).sandbox)
+
+	_, err = newCRIContainerHandler(client, "/kubepods/besteffort/pod1234/0000000000000000000000000000000000000000000000000000000000000000", nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.NotNil(err)
+}
+
+func TestStatusPid(t *testing.T) {
+	as := assert.New(t)
+	as.Equal(4242, statusPid(map[string]string{"info": `{"pid": 4242, "sandboxID": "abc"}`}))
+	as.Equal(0, statusPid(map[string]string{"info": "not json"}))
+	as.Equal(0, statusPid(nil))
+}
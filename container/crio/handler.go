@@ -27,6 +27,8 @@ import (
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
+
+	"k8s.io/klog/v2"
 )
 
 type crioContainerHandler struct {
@@ -53,6 +55,8 @@ type crioContainerHandler struct {
 
 	// Image name used for this container.
 	image string
+	// ID of the container this container was restored from, if any.
+	restoredFrom string
 
 	// The network mode of the container
 	// TODO
@@ -74,6 +78,7 @@ type crioContainerHandler struct {
 }
 
 var _ container.ContainerHandler = &crioContainerHandler{}
+var _ container.StorageDirHandler = &crioContainerHandler{}
 
 // newCrioContainerHandler returns a new container.ContainerHandler
 func newCrioContainerHandler(
@@ -169,6 +174,7 @@ func newCrioContainerHandler(
 	}
 
 	handler.image = cInfo.Image
+	handler.restoredFrom = cInfo.Annotations[common.RestoredFromAnnotation]
 	// TODO: we wantd to know graph driver DeviceId (dont think this is needed now)
 
 	// ignore err and get zero as default, this happens with sandboxes, not sure why...
@@ -183,7 +189,7 @@ func newCrioContainerHandler(
 
 	// we optionally collect disk usage metrics
 	if includedMetrics.Has(container.DiskUsageMetrics) {
-		handler.fsHandler = common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, storageLogDir, fsInfo)
+		handler.fsHandler = common.NewFsHandler(common.FsUsageInterval(), rootfsStorageDir, storageLogDir, fsInfo)
 	}
 	// TODO for env vars we wanted to show from container.Config.Env from whitelist
 	//for _, exposedEnv := range metadataEnvs {
@@ -223,6 +229,13 @@ func (h *crioContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	spec.Labels = h.labels
 	spec.Envs = h.envs
 	spec.Image = h.image
+	spec.RestoredFrom = h.restoredFrom
+
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
 
 	return spec, err
 }
@@ -353,3 +366,7 @@ func (h *crioContainerHandler) Exists() bool {
 func (h *crioContainerHandler) Type() container.ContainerType {
 	return container.ContainerTypeCrio
 }
+
+func (h *crioContainerHandler) GetRootfsStorageDir() string {
+	return h.rootfsStorageDir
+}
@@ -22,9 +22,11 @@ import (
 
 	dockertypes "github.com/docker/docker/api/types"
 	"golang.org/x/net/context"
+	"k8s.io/klog/v2"
 
 	"time"
 
+	dockerutil "github.com/google/cadvisor/container/docker/utils"
 	"github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/machine"
 )
@@ -93,6 +95,12 @@ func Images() ([]v1.DockerImage, error) {
 		return nil, err
 	}
 
+	// With the containerd image store, sizes are read from the layer snapshots.
+	var snapshotter string
+	if dockerInfo, err := client.Info(defaultContext()); err == nil && dockerutil.IsContainerdSnapshotter(dockerInfo) {
+		snapshotter = dockerInfo.Driver
+	}
+
 	out := []v1.DockerImage{}
 	const unknownTag = "<none>:<none>"
 	for _, image := range images {
@@ -107,12 +115,42 @@ func Images() ([]v1.DockerImage, error) {
 			VirtualSize: image.VirtualSize,
 			Size:        image.Size,
 		}
+		if snapshotter != "" && image.Size == 0 {
+			if err := setImageSnapshotSize(&di, snapshotter); err != nil {
+				klog.V(4).Infof("Unable to get snapshot size of image %q: %v", image.ID, err)
+			}
+		}
 		out = append(out, di)
 	}
+	if snapshotter != "" {
+		layerUsage.sweep()
+	}
 	return out, nil
 
 }
 
+func setImageSnapshotSize(image *v1.DockerImage, snapshotter string) error {
+	client, err := Client()
+	if err != nil {
+		return err
+	}
+	snapshots, err := containerdSnapshots()
+	if err != nil {
+		return err
+	}
+	inspect, _, err := client.ImageInspectWithRaw(defaultContext(), image.ID)
+	if err != nil {
+		return err
+	}
+	size, err := imageSnapshotSize(defaultContext(), snapshots, layerUsage, snapshotter, inspect.RootFS.Layers)
+	if err != nil {
+		return err
+	}
+	image.Size = size
+	image.VirtualSize = size
+	return nil
+}
+
 // Checks whether the dockerInfo reflects a valid docker setup, and returns it if it does, or an
 // error otherwise.
 func ValidateInfo() (*dockertypes.Info, error) {
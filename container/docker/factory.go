@@ -111,6 +111,8 @@ type dockerFactory struct {
 
 	storageDriver storageDriver
 	storageDir    string
+	// Name of the containerd snapshotter when docker uses the containerd image store.
+	snapshotter string
 
 	client *docker.Client
 
@@ -151,6 +153,7 @@ func (f *dockerFactory) NewContainerHandler(name string, inHostNamespace bool) (
 		f.fsInfo,
 		f.storageDriver,
 		f.storageDir,
+		f.snapshotter,
 		&f.cgroupSubsystems,
 		inHostNamespace,
 		metadataEnvs,
@@ -367,6 +370,14 @@ func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics
 		}
 	}
 
+	sd := storageDriver(dockerInfo.Driver)
+	var snapshotter string
+	if dockerutil.IsContainerdSnapshotter(*dockerInfo) {
+		klog.V(1).Infof("Docker uses the containerd image store with snapshotter %q", dockerInfo.Driver)
+		sd = containerdSnapshotterStorageDriver
+		snapshotter = dockerInfo.Driver
+	}
+
 	klog.V(1).Infof("Registering Docker factory")
 	f := &dockerFactory{
 		cgroupSubsystems:   cgroupSubsystems,
@@ -375,8 +386,9 @@ func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics
 		dockerAPIVersion:   dockerAPIVersion,
 		fsInfo:             fsInfo,
 		machineInfoFactory: factory,
-		storageDriver:      storageDriver(dockerInfo.Driver),
+		storageDriver:      sd,
 		storageDir:         RootDir(),
+		snapshotter:        snapshotter,
 		includedMetrics:    includedMetrics,
 		thinPoolName:       thinPoolName,
 		thinPoolWatcher:    thinPoolWatcher,
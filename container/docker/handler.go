@@ -21,10 +21,12 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/cadvisor/container"
 	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/container/containerd"
 	dockerutil "github.com/google/cadvisor/container/docker/utils"
 	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
 	"github.com/google/cadvisor/devicemapper"
@@ -32,6 +34,7 @@ import (
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/zfs"
 
+	dockertypes "github.com/docker/docker/api/types"
 	dockercontainer "github.com/docker/docker/api/types/container"
 	docker "github.com/docker/docker/client"
 	"golang.org/x/net/context"
@@ -46,6 +49,9 @@ const (
 
 	// Path to the directory where docker stores log files if the json logging driver is enabled.
 	pathToContainersDir = "containers"
+
+	// Interval after which the cached state of a container is inspected again.
+	stateRefreshInterval = 10 * time.Second
 )
 
 type dockerContainerHandler struct {
@@ -70,6 +76,8 @@ type dockerContainerHandler struct {
 
 	// Image name used for this container.
 	image string
+	// ID of the container this container was restored from, if any.
+	restoredFrom string
 
 	// The network mode of the container
 	networkMode dockercontainer.NetworkMode
@@ -91,10 +99,16 @@ type dockerContainerHandler struct {
 	// Reference to the container
 	reference info.ContainerReference
 
+	// Client used to refresh the state of the container.
+	client *docker.Client
+	// Last inspected state of the container.
+	state stateCache
+
 	libcontainerHandler *containerlibcontainer.Handler
 }
 
 var _ container.ContainerHandler = &dockerContainerHandler{}
+var _ container.StorageDirHandler = &dockerContainerHandler{}
 
 func getRwLayerID(containerID, storageDir string, sd storageDriver, dockerVersion []int) (string, error) {
 	const (
@@ -113,6 +127,22 @@ func getRwLayerID(containerID, storageDir string, sd storageDriver, dockerVersio
 	return string(bytes), err
 }
 
+func getSnapshotRootfsDir(containerID, snapshotter string) (string, error) {
+	snapshots, err := containerdSnapshots()
+	if err != nil {
+		return "", err
+	}
+	mounts, err := snapshots.Mounts(context.Background(), snapshotter, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get snapshot mounts for container %q: %v", containerID, err)
+	}
+	dir := containerd.SnapshotUpperDir(mounts)
+	if dir == "" {
+		return "", fmt.Errorf("no writable layer found in snapshot mounts of container %q", containerID)
+	}
+	return dir, nil
+}
+
 // newDockerContainerHandler returns a new container.ContainerHandler
 func newDockerContainerHandler(
 	client *docker.Client,
@@ -121,6 +151,7 @@ func newDockerContainerHandler(
 	fsInfo fs.FsInfo,
 	storageDriver storageDriver,
 	storageDir string,
+	snapshotter string,
 	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
 	inHostNamespace bool,
 	metadataEnvs []string,
@@ -151,9 +182,14 @@ func newDockerContainerHandler(
 	// FIXME: Give `otherStorageDir` a more descriptive name.
 	otherStorageDir := path.Join(storageDir, pathToContainersDir, id)
 
-	rwLayerID, err := getRwLayerID(id, storageDir, storageDriver, dockerVersion)
-	if err != nil {
-		return nil, err
+	// The containerd image store keeps no layerdb, the writable layer is found
+	// through the container snapshot instead.
+	var rwLayerID string
+	if storageDriver != containerdSnapshotterStorageDriver {
+		rwLayerID, err = getRwLayerID(id, storageDir, storageDriver, dockerVersion)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Determine the rootfs storage dir OR the pool name to determine the device.
@@ -172,6 +208,12 @@ func newDockerContainerHandler(
 		rootfsStorageDir = path.Join(storageDir, string(storageDriver), rwLayerID, overlay2RWLayer)
 	case vfsStorageDriver:
 		rootfsStorageDir = path.Join(storageDir)
+	case containerdSnapshotterStorageDriver:
+		rootfsStorageDir, err = getSnapshotRootfsDir(id, snapshotter)
+		if err != nil {
+			return nil, err
+		}
+		rootfsStorageDir = path.Join(rootFs, rootfsStorageDir)
 	case zfsStorageDriver:
 		status, err := Status()
 		if err != nil {
@@ -182,7 +224,9 @@ func newDockerContainerHandler(
 	}
 
 	// We assume that if Inspect fails then the container is not known to docker.
-	ctnr, err := client.ContainerInspect(context.Background(), id)
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+	ctnr, err := client.ContainerInspect(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inspect container %q: %v", id, err)
 	}
@@ -198,8 +242,12 @@ func newDockerContainerHandler(
 		envs:               make(map[string]string),
 		labels:             ctnr.Config.Labels,
 		includedMetrics:    includedMetrics,
+		client:             client,
 		zfsParent:          zfsParent,
 	}
+	// Docker has no annotations, a restored container is labeled.
+	handler.restoredFrom = ctnr.Config.Labels[common.RestoredFromAnnotation]
+
 	// Timestamp returned by Docker is in time.RFC3339Nano format.
 	handler.creationTime, err = time.Parse(time.RFC3339Nano, ctnr.Created)
 	if err != nil {
@@ -207,6 +255,8 @@ func newDockerContainerHandler(
 		return nil, fmt.Errorf("failed to parse the create timestamp %q for container %q: %v", ctnr.Created, id, err)
 	}
 	handler.libcontainerHandler = containerlibcontainer.NewHandler(cgroupManager, rootFs, ctnr.State.Pid, includedMetrics)
+	handler.state.state = containerState(ctnr)
+	handler.state.updated = time.Now()
 
 	// Add the name and bare ID as aliases of the container.
 	handler.reference = info.ContainerReference{
@@ -229,7 +279,7 @@ func newDockerContainerHandler(
 	networkMode := string(ctnr.HostConfig.NetworkMode)
 	if ipAddress == "" && strings.HasPrefix(networkMode, "container:") {
 		containerID := strings.TrimPrefix(networkMode, "container:")
-		c, err := client.ContainerInspect(context.Background(), containerID)
+		c, err := client.ContainerInspect(ctx, containerID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to inspect container %q: %v", id, err)
 		}
@@ -240,7 +290,7 @@ func newDockerContainerHandler(
 
 	if includedMetrics.Has(container.DiskUsageMetrics) {
 		handler.fsHandler = &dockerFsHandler{
-			fsHandler:       common.NewFsHandler(common.DefaultPeriod, rootfsStorageDir, otherStorageDir, fsInfo),
+			fsHandler:       common.NewFsHandler(common.FsUsageInterval(), rootfsStorageDir, otherStorageDir, fsInfo),
 			thinPoolWatcher: thinPoolWatcher,
 			zfsWatcher:      zfsWatcher,
 			deviceID:        ctnr.GraphDriver.Data["DeviceId"],
@@ -358,10 +408,84 @@ func (h *dockerContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	spec.Envs = h.envs
 	spec.Image = h.image
 	spec.CreationTime = h.creationTime
+	spec.RestoredFrom = h.restoredFrom
+
+	// Mounts are audited through the spec, e.g. for hostPath volumes. They
+	// are missing once the container stopped.
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
+
+	// Restarts and health checks don't recreate the handler, so the state
+	// is inspected again in the background once it is stale.
+	spec.State = h.state.get(time.Now(), h.reference.Id, h.client.ContainerInspect)
 
 	return spec, err
 }
 
+// inspectFunc inspects the container with the given ID.
+type inspectFunc func(ctx context.Context, id string) (dockertypes.ContainerJSON, error)
+
+// stateCache holds the last inspected state of a container so that a slow
+// or hung dockerd doesn't block spec updates.
+type stateCache struct {
+	lock       sync.Mutex
+	state      *info.ContainerState
+	updated    time.Time
+	refreshing bool
+}
+
+// get returns the cached state and starts refreshing it in the background if
+// it is older than stateRefreshInterval.
+func (c *stateCache) get(now time.Time, id string, inspect inspectFunc) *info.ContainerState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.refreshing && now.Sub(c.updated) >= stateRefreshInterval {
+		c.refreshing = true
+		go c.refresh(id, inspect)
+	}
+	return c.state
+}
+
+func (c *stateCache) refresh(id string, inspect inspectFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+	ctnr, err := inspect(ctx, id)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.refreshing = false
+	if err != nil {
+		klog.V(4).Infof("Unable to inspect state of container %q: %v", id, err)
+		return
+	}
+	c.state = containerState(ctnr)
+	c.updated = time.Now()
+}
+
+// containerState returns the restart count, OOM kill and health check state
+// of an inspected container.
+func containerState(ctnr dockertypes.ContainerJSON) *info.ContainerState {
+	state := &info.ContainerState{}
+	if ctnr.ContainerJSONBase == nil {
+		return state
+	}
+	state.RestartCount = ctnr.RestartCount
+	if ctnr.State == nil {
+		return state
+	}
+	state.OOMKilled = ctnr.State.OOMKilled
+	if ctnr.State.Health != nil {
+		state.Health = &info.ContainerHealth{
+			Status:        ctnr.State.Health.Status,
+			FailingStreak: ctnr.State.Health.FailingStreak,
+		}
+	}
+	return state
+}
+
 func (h *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error {
 	mi, err := h.machineInfoFactory.GetMachineInfo()
 	if err != nil {
@@ -381,7 +505,7 @@ func (h *dockerContainerHandler) getFsStats(stats *info.ContainerStats) error {
 		// Device has to be the pool name to correlate with the device name as
 		// set in the machine info filesystems.
 		device = h.poolName
-	case aufsStorageDriver, overlayStorageDriver, overlay2StorageDriver, vfsStorageDriver:
+	case aufsStorageDriver, overlayStorageDriver, overlay2StorageDriver, vfsStorageDriver, containerdSnapshotterStorageDriver:
 		deviceInfo, err := h.fsInfo.GetDirFsDevice(h.rootfsStorageDir)
 		if err != nil {
 			return fmt.Errorf("unable to determine device info for dir: %v: %v", h.rootfsStorageDir, err)
@@ -473,3 +597,7 @@ func (h *dockerContainerHandler) Exists() bool {
 func (h *dockerContainerHandler) Type() container.ContainerType {
 	return container.ContainerTypeDocker
 }
+
+func (h *dockerContainerHandler) GetRootfsStorageDir() string {
+	return h.rootfsStorageDir
+}
@@ -21,9 +21,14 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
+	dockertypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+
+	info "github.com/google/cadvisor/info/v1"
 )
 
 func TestStorageDirDetectionWithOldVersions(t *testing.T) {
@@ -144,3 +149,54 @@ func TestDockerEnvWhitelist(t *testing.T) {
 	as.Equal(rawEnvsMatchWithEmptyWhitelist, emptyExpected)
 
 }
+
+func TestContainerState(t *testing.T) {
+	ctnr := dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			RestartCount: 2,
+			State: &dockertypes.ContainerState{
+				OOMKilled: true,
+				Health: &dockertypes.Health{
+					Status:        "unhealthy",
+					FailingStreak: 4,
+				},
+			},
+		},
+	}
+	assert.Equal(t, &info.ContainerState{
+		RestartCount: 2,
+		OOMKilled:    true,
+		Health: &info.ContainerHealth{
+			Status:        "unhealthy",
+			FailingStreak: 4,
+		},
+	}, containerState(ctnr))
+
+	ctnr.State.Health = nil
+	assert.Nil(t, containerState(ctnr).Health)
+	assert.Equal(t, &info.ContainerState{}, containerState(dockertypes.ContainerJSON{}))
+}
+
+func TestStateCache(t *testing.T) {
+	inspected := make(chan string, 1)
+	inspect := func(ctx context.Context, id string) (dockertypes.ContainerJSON, error) {
+		inspected <- id
+		return dockertypes.ContainerJSON{
+			ContainerJSONBase: &dockertypes.ContainerJSONBase{RestartCount: 1},
+		}, nil
+	}
+	now := time.Now()
+	cache := stateCache{state: &info.ContainerState{}, updated: now}
+
+	// A fresh state is returned without inspecting the container.
+	assert.Equal(t, &info.ContainerState{}, cache.get(now, "a", inspect))
+	assert.Empty(t, inspected)
+
+	// A stale state is still returned while it is refreshed.
+	assert.Equal(t, &info.ContainerState{}, cache.get(now.Add(stateRefreshInterval), "a", inspect))
+	assert.Equal(t, "a", <-inspected)
+	assert.Eventually(t, func() bool {
+		return cache.get(time.Now(), "a", inspect).RestartCount == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, inspected)
+}
@@ -39,16 +39,23 @@ func (p *plugin) InitializeFSContext(context *fs.Context) error {
 	// Try to connect to docker indefinitely on startup.
 	dockerStatus := retryDockerStatus()
 	context.Docker = fs.DockerContext{
-		Root:         RootDir(),
-		Driver:       dockerStatus.Driver,
-		DriverStatus: dockerStatus.DriverStatus,
+		Root:           RootDir(),
+		Driver:         dockerStatus.Driver,
+		DriverStatus:   dockerStatus.DriverStatus,
+		ContainerdRoot: *dockerContainerdRoot,
 	}
 	return nil
 }
 
 func (p *plugin) Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) (watcher.ContainerWatcher, error) {
-	err := Register(factory, fsInfo, includedMetrics)
-	return nil, err
+	if err := Register(factory, fsInfo, includedMetrics); err != nil {
+		return nil, err
+	}
+	client, err := Client()
+	if err != nil {
+		return nil, err
+	}
+	return newDockerWatcher(client), nil
 }
 
 func retryDockerStatus() info.DockerStatus {
@@ -0,0 +1,195 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	snapshotsapi "github.com/containerd/containerd/api/services/snapshots/v1"
+	"github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/pkg/dialer"
+	"google.golang.org/grpc"
+)
+
+var (
+	dockerContainerdEndpoint = flag.String("docker_containerd", "/run/containerd/containerd.sock", "containerd endpoint docker uses when it runs with the containerd image store")
+	dockerContainerdRoot     = flag.String("docker_containerd_root", "/var/lib/containerd", "containerd root directory docker uses when it runs with the containerd image store")
+)
+
+const (
+	// The containerd namespace docker keeps its images and snapshots in.
+	dockerContainerdNamespace = "moby"
+
+	snapshotsConnectionTimeout = 2 * time.Second
+)
+
+// containerdSnapshotterStorageDriver is used in place of a graph driver when
+// docker stores images and container filesystems in containerd snapshots.
+const containerdSnapshotterStorageDriver storageDriver = "containerd-snapshotter"
+
+type snapshotsClient interface {
+	// Usage returns the disk usage in bytes of the snapshot with the given key.
+	Usage(ctx context.Context, snapshotter, key string) (int64, error)
+	// Mounts returns the mounts of the active snapshot with the given key.
+	Mounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error)
+}
+
+type grpcSnapshotsClient struct {
+	service snapshotsapi.SnapshotsClient
+}
+
+var (
+	snapshotsOnce      sync.Once
+	snapshotsClientErr error
+	snapshots          snapshotsClient
+)
+
+// containerdSnapshots returns a client for the snapshots service of the
+// containerd instance backing docker.
+func containerdSnapshots() (snapshotsClient, error) {
+	snapshotsOnce.Do(func() {
+		tryConn, err := net.DialTimeout("unix", *dockerContainerdEndpoint, snapshotsConnectionTimeout)
+		if err != nil {
+			snapshotsClientErr = fmt.Errorf("cannot unix dial containerd api service: %v", err)
+			return
+		}
+		tryConn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), snapshotsConnectionTimeout)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, dialer.DialAddress(*dockerContainerdEndpoint),
+			grpc.WithInsecure(),
+			grpc.WithContextDialer(dialer.ContextDialer),
+			grpc.WithBlock(),
+		)
+		if err != nil {
+			snapshotsClientErr = err
+			return
+		}
+		snapshots = &grpcSnapshotsClient{service: snapshotsapi.NewSnapshotsClient(conn)}
+	})
+	return snapshots, snapshotsClientErr
+}
+
+func (c *grpcSnapshotsClient) Usage(ctx context.Context, snapshotter, key string) (int64, error) {
+	ctx = namespaces.WithNamespace(ctx, dockerContainerdNamespace)
+	response, err := c.service.Usage(ctx, &snapshotsapi.UsageRequest{
+		Snapshotter: snapshotter,
+		Key:         key,
+	})
+	if err != nil {
+		return 0, errdefs.FromGRPC(err)
+	}
+	return response.Size_, nil
+}
+
+func (c *grpcSnapshotsClient) Mounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error) {
+	ctx = namespaces.WithNamespace(ctx, dockerContainerdNamespace)
+	response, err := c.service.Mounts(ctx, &snapshotsapi.MountsRequest{
+		Snapshotter: snapshotter,
+		Key:         key,
+	})
+	if err != nil {
+		return nil, errdefs.FromGRPC(err)
+	}
+	return response.Mounts, nil
+}
+
+// chainIDs returns the chain IDs of the layers with the given diff IDs. Unpacked
+// layers are committed as snapshots named after their chain ID.
+func chainIDs(diffIDs []string) []string {
+	out := make([]string, 0, len(diffIDs))
+	for i, diffID := range diffIDs {
+		if i == 0 {
+			out = append(out, diffID)
+			continue
+		}
+		sum := sha256.Sum256([]byte(out[i-1] + " " + diffID))
+		out = append(out, "sha256:"+hex.EncodeToString(sum[:]))
+	}
+	return out
+}
+
+// snapshotUsageCache keeps the disk usage of committed layer snapshots, which
+// never changes, by snapshotter and key.
+type snapshotUsageCache struct {
+	lock  sync.Mutex
+	usage map[string]int64
+	// Keys looked up since the last sweep.
+	used map[string]bool
+}
+
+func newSnapshotUsageCache() *snapshotUsageCache {
+	return &snapshotUsageCache{usage: map[string]int64{}, used: map[string]bool{}}
+}
+
+// Disk usage of the layer snapshots of the images of docker.
+var layerUsage = newSnapshotUsageCache()
+
+// get returns the disk usage of a snapshot, only asking containerd for the
+// snapshots not looked up before.
+func (c *snapshotUsageCache) get(ctx context.Context, client snapshotsClient, snapshotter, key string) (int64, error) {
+	cacheKey := snapshotter + "/" + key
+	c.lock.Lock()
+	usage, ok := c.usage[cacheKey]
+	c.used[cacheKey] = true
+	c.lock.Unlock()
+	if ok {
+		return usage, nil
+	}
+	usage, err := client.Usage(ctx, snapshotter, key)
+	if err != nil {
+		return 0, err
+	}
+	c.lock.Lock()
+	c.usage[cacheKey] = usage
+	c.lock.Unlock()
+	return usage, nil
+}
+
+// sweep drops the snapshots not looked up since the previous sweep, e.g.
+// those of deleted images.
+func (c *snapshotUsageCache) sweep() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.usage {
+		if !c.used[key] {
+			delete(c.usage, key)
+		}
+	}
+	c.used = map[string]bool{}
+}
+
+// imageSnapshotSize returns the disk usage of the layer snapshots of an image.
+func imageSnapshotSize(ctx context.Context, client snapshotsClient, cache *snapshotUsageCache, snapshotter string, diffIDs []string) (int64, error) {
+	var size int64
+	for _, chainID := range chainIDs(diffIDs) {
+		usage, err := cache.get(ctx, client, snapshotter, chainID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get usage of layer snapshot %q: %v", chainID, err)
+		}
+		size += usage
+	}
+	return size, nil
+}
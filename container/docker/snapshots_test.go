@@ -0,0 +1,87 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/containerd/containerd/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSnapshotsClient struct {
+	usage map[string]int64
+	calls int
+}
+
+func (c *fakeSnapshotsClient) Usage(ctx context.Context, snapshotter, key string) (int64, error) {
+	c.calls++
+	size, ok := c.usage[snapshotter+"/"+key]
+	if !ok {
+		return 0, fmt.Errorf("snapshot %q not found", key)
+	}
+	return size, nil
+}
+
+func (c *fakeSnapshotsClient) Mounts(ctx context.Context, snapshotter, key string) ([]*types.Mount, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func TestChainIDs(t *testing.T) {
+	diffIDs := []string{"sha256:aaaa", "sha256:bbbb", "sha256:cccc"}
+	sum := sha256.Sum256([]byte("sha256:aaaa sha256:bbbb"))
+	second := "sha256:" + hex.EncodeToString(sum[:])
+	sum = sha256.Sum256([]byte(second + " sha256:cccc"))
+	third := "sha256:" + hex.EncodeToString(sum[:])
+
+	assert.Equal(t, []string{"sha256:aaaa", second, third}, chainIDs(diffIDs))
+	assert.Empty(t, chainIDs(nil))
+}
+
+func TestImageSnapshotSize(t *testing.T) {
+	diffIDs := []string{"sha256:aaaa", "sha256:bbbb"}
+	chain := chainIDs(diffIDs)
+	client := &fakeSnapshotsClient{usage: map[string]int64{
+		"overlayfs/" + chain[0]: 1000,
+		"overlayfs/" + chain[1]: 234,
+	}}
+
+	cache := newSnapshotUsageCache()
+	size, err := imageSnapshotSize(context.Background(), client, cache, "overlayfs", diffIDs)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), size)
+
+	_, err = imageSnapshotSize(context.Background(), client, cache, "native", diffIDs)
+	assert.NotNil(t, err)
+
+	// The usage of the layers is only asked once, layers shared by images
+	// included.
+	client.calls = 0
+	size, err = imageSnapshotSize(context.Background(), client, cache, "overlayfs", diffIDs[:1])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), size)
+	assert.Equal(t, 0, client.calls)
+
+	// Layers not looked up since the previous sweep are dropped.
+	cache.sweep()
+	cache.sweep()
+	_, err = imageSnapshotSize(context.Background(), client, cache, "overlayfs", diffIDs)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, client.calls)
+}
@@ -26,6 +26,11 @@ const (
 	DriverStatusPoolName      = "Pool Name"
 	DriverStatusMetadataFile  = "Metadata file"
 	DriverStatusParentDataset = "Parent Dataset"
+	DriverStatusDriverType    = "driver-type"
+
+	// ContainerdSnapshotterDriverType is the driver type reported by docker
+	// when images are stored in containerd snapshots instead of a graph driver.
+	ContainerdSnapshotterDriverType = "io.containerd.snapshotter.v1"
 )
 
 func DriverStatusValue(status [][2]string, target string) string {
@@ -75,3 +80,8 @@ func DockerZfsFilesystem(info dockertypes.Info) (string, error) {
 
 	return filesystem, nil
 }
+
+// IsContainerdSnapshotter reports whether docker runs with the containerd image store.
+func IsContainerdSnapshotter(info dockertypes.Info) bool {
+	return DriverStatusValue(info.DriverStatus, DriverStatusDriverType) == ContainerdSnapshotterDriverType
+}
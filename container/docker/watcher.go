@@ -0,0 +1,138 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockerevents "github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	minEventsRetryDelay = time.Second
+	maxEventsRetryDelay = 30 * time.Second
+)
+
+// eventsClient is the subset of the Docker API client used by the watcher.
+type eventsClient interface {
+	Events(ctx context.Context, options dockertypes.EventsOptions) (<-chan dockerevents.Message, <-chan error)
+	ContainerInspect(ctx context.Context, id string) (dockertypes.ContainerJSON, error)
+}
+
+// dockerWatcher subscribes to Docker's event stream and reports containers as
+// soon as they start, instead of waiting for the cgroup to be picked up by the
+// next housekeeping pass. Container removal is left to the raw watcher.
+type dockerWatcher struct {
+	client   eventsClient
+	procRoot string
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+var _ watcher.ContainerWatcher = &dockerWatcher{}
+
+func newDockerWatcher(client eventsClient) *dockerWatcher {
+	return &dockerWatcher{
+		client:   client,
+		procRoot: common.ProcRoot(),
+	}
+}
+
+func (w *dockerWatcher) Start(events chan watcher.ContainerEvent) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		delay := minEventsRetryDelay
+		for {
+			err := w.watch(ctx, events)
+			if ctx.Err() != nil {
+				return
+			}
+			klog.Warningf("Docker event stream closed, retrying in %v: %v", delay, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxEventsRetryDelay {
+				delay = maxEventsRetryDelay
+			}
+		}
+	}()
+	return nil
+}
+
+func (w *dockerWatcher) Stop() error {
+	if w.cancel != nil {
+		w.cancel()
+		<-w.done
+	}
+	return nil
+}
+
+// watch consumes container start events until the stream fails or ctx is done.
+func (w *dockerWatcher) watch(ctx context.Context, events chan watcher.ContainerEvent) error {
+	msgs, errs := w.client.Events(ctx, dockertypes.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", dockerevents.ContainerEventType),
+			filters.Arg("event", "start"),
+		),
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case msg := <-msgs:
+			name, err := w.cgroupName(ctx, msg.Actor.ID)
+			if err != nil {
+				klog.V(4).Infof("Unable to find cgroup of started docker container %q: %v", msg.Actor.ID, err)
+				continue
+			}
+			select {
+			case events <- watcher.ContainerEvent{
+				EventType:   watcher.ContainerAdd,
+				Name:        name,
+				WatchSource: watcher.Raw,
+			}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (w *dockerWatcher) cgroupName(ctx context.Context, id string) (string, error) {
+	ctnr, err := w.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if ctnr.State == nil || !ctnr.State.Running {
+		return "", fmt.Errorf("container is not running")
+	}
+	return common.CgroupNameForPid(w.procRoot, ctnr.State.Pid)
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	dockerevents "github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/google/cadvisor/watcher"
+)
+
+type fakeEventsClient struct {
+	msgs       chan dockerevents.Message
+	errs       chan error
+	containers map[string]dockertypes.ContainerJSON
+}
+
+func (c *fakeEventsClient) Events(ctx context.Context, options dockertypes.EventsOptions) (<-chan dockerevents.Message, <-chan error) {
+	return c.msgs, c.errs
+}
+
+func (c *fakeEventsClient) ContainerInspect(ctx context.Context, id string) (dockertypes.ContainerJSON, error) {
+	ctnr, ok := c.containers[id]
+	if !ok {
+		return dockertypes.ContainerJSON{}, fmt.Errorf("no such container: %s", id)
+	}
+	return ctnr, nil
+}
+
+func containerWithPid(pid int, running bool) dockertypes.ContainerJSON {
+	return dockertypes.ContainerJSON{
+		ContainerJSONBase: &dockertypes.ContainerJSONBase{
+			State: &dockertypes.ContainerState{Running: running, Pid: pid},
+		},
+	}
+}
+
+func TestDockerWatcherReportsStartedContainers(t *testing.T) {
+	procRoot, err := ioutil.TempDir("", "proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(procRoot)
+	require.NoError(t, os.Mkdir(filepath.Join(procRoot, "1234"), 0755))
+	cgroup := "4:cpu,cpuacct:/docker/abc\n0::/docker/abc\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(procRoot, "1234", "cgroup"), []byte(cgroup), 0644))
+
+	client := &fakeEventsClient{
+		msgs: make(chan dockerevents.Message),
+		errs: make(chan error),
+		containers: map[string]dockertypes.ContainerJSON{
+			"abc":     containerWithPid(1234, true),
+			"stopped": containerWithPid(0, false),
+		},
+	}
+	w := newDockerWatcher(client)
+	w.procRoot = procRoot
+
+	events := make(chan watcher.ContainerEvent, 1)
+	require.NoError(t, w.Start(events))
+	defer w.Stop()
+
+	client.msgs <- dockerevents.Message{Actor: dockerevents.Actor{ID: "unknown"}}
+	client.msgs <- dockerevents.Message{Actor: dockerevents.Actor{ID: "stopped"}}
+	client.msgs <- dockerevents.Message{Actor: dockerevents.Actor{ID: "abc"}}
+
+	select {
+	case event := <-events:
+		assert.Equal(t, watcher.ContainerEvent{
+			EventType:   watcher.ContainerAdd,
+			Name:        "/docker/abc",
+			WatchSource: watcher.Raw,
+		}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for container event")
+	}
+}
+
+func TestDockerWatcherStop(t *testing.T) {
+	client := &fakeEventsClient{
+		msgs: make(chan dockerevents.Message),
+		errs: make(chan error, 1),
+	}
+	w := newDockerWatcher(client)
+	require.NoError(t, w.Start(make(chan watcher.ContainerEvent)))
+
+	// A failing stream is retried until the watcher is stopped.
+	client.errs <- fmt.Errorf("connection reset")
+	assert.NoError(t, w.Stop())
+}
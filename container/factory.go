@@ -16,6 +16,7 @@ package container
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/google/cadvisor/fs"
@@ -48,6 +49,7 @@ const (
 	PerCpuUsageMetrics             MetricKind = "percpu"
 	MemoryUsageMetrics             MetricKind = "memory"
 	MemoryNumaMetrics              MetricKind = "memory_numa"
+	MemoryStatMetrics              MetricKind = "memory_stat"
 	CpuLoadMetrics                 MetricKind = "cpuLoad"
 	DiskIOMetrics                  MetricKind = "diskIO"
 	DiskUsageMetrics               MetricKind = "disk"
@@ -55,6 +57,7 @@ const (
 	NetworkTcpUsageMetrics         MetricKind = "tcp"
 	NetworkAdvancedTcpUsageMetrics MetricKind = "advtcp"
 	NetworkUdpUsageMetrics         MetricKind = "udp"
+	NetworkQueueMetrics            MetricKind = "nic_queues"
 	AcceleratorUsageMetrics        MetricKind = "accelerator"
 	AppMetrics                     MetricKind = "app"
 	ProcessMetrics                 MetricKind = "process"
@@ -63,6 +66,17 @@ const (
 	ReferencedMemoryMetrics        MetricKind = "referenced_memory"
 	CPUTopologyMetrics             MetricKind = "cpu_topology"
 	ResctrlMetrics                 MetricKind = "resctrl"
+	GvisorMetrics                  MetricKind = "gvisor"
+	KsmMetrics                     MetricKind = "ksm"
+	CpuStealMetrics                MetricKind = "cpu_steal"
+	CpuFrequencyMetrics            MetricKind = "cpu_frequency"
+	PowerMetrics                   MetricKind = "power"
+	ThermalMetrics                 MetricKind = "thermal"
+	ThermalThrottleMetrics         MetricKind = "thermal_throttle"
+	CpuUsageQuantileMetrics        MetricKind = "cpu_usage_quantile"
+	MdRaidMetrics                  MetricKind = "md_raid"
+	StorageSessionMetrics          MetricKind = "storage_sessions"
+	SlabMetrics                    MetricKind = "slab"
 )
 
 // AllMetrics represents all kinds of metrics that cAdvisor supported.
@@ -72,6 +86,7 @@ var AllMetrics = MetricSet{
 	PerCpuUsageMetrics:             struct{}{},
 	MemoryUsageMetrics:             struct{}{},
 	MemoryNumaMetrics:              struct{}{},
+	MemoryStatMetrics:              struct{}{},
 	CpuLoadMetrics:                 struct{}{},
 	DiskIOMetrics:                  struct{}{},
 	AcceleratorUsageMetrics:        struct{}{},
@@ -80,6 +95,7 @@ var AllMetrics = MetricSet{
 	NetworkTcpUsageMetrics:         struct{}{},
 	NetworkAdvancedTcpUsageMetrics: struct{}{},
 	NetworkUdpUsageMetrics:         struct{}{},
+	NetworkQueueMetrics:            struct{}{},
 	ProcessMetrics:                 struct{}{},
 	AppMetrics:                     struct{}{},
 	HugetlbUsageMetrics:            struct{}{},
@@ -87,6 +103,46 @@ var AllMetrics = MetricSet{
 	ReferencedMemoryMetrics:        struct{}{},
 	CPUTopologyMetrics:             struct{}{},
 	ResctrlMetrics:                 struct{}{},
+	GvisorMetrics:                  struct{}{},
+	KsmMetrics:                     struct{}{},
+	CpuStealMetrics:                struct{}{},
+	CpuFrequencyMetrics:            struct{}{},
+	PowerMetrics:                   struct{}{},
+	ThermalMetrics:                 struct{}{},
+	ThermalThrottleMetrics:         struct{}{},
+	CpuUsageQuantileMetrics:        struct{}{},
+	MdRaidMetrics:                  struct{}{},
+	StorageSessionMetrics:          struct{}{},
+	SlabMetrics:                    struct{}{},
+}
+
+// DefaultDisabledMetrics returns the kinds of metrics which are not collected
+// unless they are enabled, as they are expensive or of little use.
+func DefaultDisabledMetrics() MetricSet {
+	return MetricSet{
+		MemoryNumaMetrics:              struct{}{},
+		MemoryStatMetrics:              struct{}{},
+		NetworkTcpUsageMetrics:         struct{}{},
+		NetworkUdpUsageMetrics:         struct{}{},
+		NetworkAdvancedTcpUsageMetrics: struct{}{},
+		ProcessSchedulerMetrics:        struct{}{},
+		ProcessMetrics:                 struct{}{},
+		HugetlbUsageMetrics:            struct{}{},
+		ReferencedMemoryMetrics:        struct{}{},
+		CPUTopologyMetrics:             struct{}{},
+		ResctrlMetrics:                 struct{}{},
+		NetworkQueueMetrics:            struct{}{},
+		KsmMetrics:                     struct{}{},
+		CpuStealMetrics:                struct{}{},
+		CpuFrequencyMetrics:            struct{}{},
+		PowerMetrics:                   struct{}{},
+		ThermalMetrics:                 struct{}{},
+		ThermalThrottleMetrics:         struct{}{},
+		CpuUsageQuantileMetrics:        struct{}{},
+		MdRaidMetrics:                  struct{}{},
+		StorageSessionMetrics:          struct{}{},
+		SlabMetrics:                    struct{}{},
+	}
 }
 
 func (mk MetricKind) String() string {
@@ -156,8 +212,17 @@ func InitializePlugins(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includ
 	pluginsLock.Lock()
 	defer pluginsLock.Unlock()
 
+	// Register the plugins in a stable order; the order of their factories is
+	// set by their priority.
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	containerWatchers := []watcher.ContainerWatcher{}
-	for name, plugin := range plugins {
+	for _, name := range names {
+		plugin := plugins[name]
 		watcher, err := plugin.Register(factory, fsInfo, includedMetrics)
 		if err != nil {
 			klog.V(5).Infof("Registration of the %s container factory failed: %v", name, err)
@@ -169,21 +234,58 @@ func InitializePlugins(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includ
 	return containerWatchers
 }
 
+// FactoryPriority orders the factories asked whether they can handle a
+// container: factories of lower priority are asked first, factories of the
+// same priority in the order they were registered.
+type FactoryPriority int
+
+const (
+	// Factories of sandboxed runtimes, whose containers have cgroups a
+	// runtime specific factory would claim as well, e.g. kata and gVisor.
+	SandboxFactoryPriority FactoryPriority = 10
+	// Runtime specific factories, e.g. docker, containerd and cri-o.
+	RuntimeFactoryPriority FactoryPriority = 20
+	// Factories of containers not claimed by a runtime specific factory, e.g.
+	// the generic CRI factory.
+	FallbackFactoryPriority FactoryPriority = 30
+	// The raw factory, which handles all cgroups.
+	RawFactoryPriority FactoryPriority = 40
+)
+
+type registeredFactory struct {
+	factory  ContainerHandlerFactory
+	priority FactoryPriority
+}
+
 // TODO(vmarmol): Consider not making this global.
-// Global list of factories.
+// Global list of factories, sorted by priority.
 var (
-	factories     = map[watcher.ContainerWatchSource][]ContainerHandlerFactory{}
+	factories     = map[watcher.ContainerWatchSource][]registeredFactory{}
 	factoriesLock sync.RWMutex
 )
 
-// Register a ContainerHandlerFactory. These should be registered from least general to most general
+// Register a runtime specific ContainerHandlerFactory. These should be registered from least general to most general
 // as they will be asked in order whether they can handle a particular container.
 func RegisterContainerHandlerFactory(factory ContainerHandlerFactory, watchTypes []watcher.ContainerWatchSource) {
+	RegisterContainerHandlerFactoryWithPriority(factory, RuntimeFactoryPriority, watchTypes)
+}
+
+// RegisterContainerHandlerFactoryWithPriority registers a ContainerHandlerFactory
+// asked after the factories of lower priority and the factories of the same
+// priority registered before it.
+func RegisterContainerHandlerFactoryWithPriority(factory ContainerHandlerFactory, priority FactoryPriority, watchTypes []watcher.ContainerWatchSource) {
 	factoriesLock.Lock()
 	defer factoriesLock.Unlock()
 
 	for _, watchType := range watchTypes {
-		factories[watchType] = append(factories[watchType], factory)
+		registered := factories[watchType]
+		i := sort.Search(len(registered), func(i int) bool {
+			return registered[i].priority > priority
+		})
+		registered = append(registered, registeredFactory{})
+		copy(registered[i+1:], registered[i:])
+		registered[i] = registeredFactory{factory: factory, priority: priority}
+		factories[watchType] = registered
 	}
 }
 
@@ -201,7 +303,8 @@ func NewContainerHandler(name string, watchType watcher.ContainerWatchSource, in
 	defer factoriesLock.RUnlock()
 
 	// Create the ContainerHandler with the first factory that supports it.
-	for _, factory := range factories[watchType] {
+	for _, registered := range factories[watchType] {
+		factory := registered.factory
 		canHandle, canAccept, err := factory.CanHandleAndAccept(name)
 		if err != nil {
 			klog.V(4).Infof("Error trying to work out if we can handle %s: %v", name, err)
@@ -226,7 +329,7 @@ func ClearContainerHandlerFactories() {
 	factoriesLock.Lock()
 	defer factoriesLock.Unlock()
 
-	factories = map[watcher.ContainerWatchSource][]ContainerHandlerFactory{}
+	factories = map[watcher.ContainerWatchSource][]registeredFactory{}
 }
 
 func DebugInfo() map[string][]string {
@@ -236,8 +339,8 @@ func DebugInfo() map[string][]string {
 	// Get debug information for all factories.
 	out := make(map[string][]string)
 	for _, factoriesSlice := range factories {
-		for _, factory := range factoriesSlice {
-			for k, v := range factory.DebugInfo() {
+		for _, registered := range factoriesSlice {
+			for k, v := range registered.factory.DebugInfo() {
 				out[k] = v
 			}
 		}
@@ -160,3 +160,43 @@ func TestNewContainerHandler_Accept(t *testing.T) {
 		t.Error("Expected NewContainerHandler to ignore the container.")
 	}
 }
+
+func TestNewContainerHandler_Priority(t *testing.T) {
+	container.ClearContainerHandlerFactories()
+
+	// Register the raw factory first, then a fallback and a runtime factory.
+	raw := &mockContainerHandlerFactory{
+		Name:           "raw",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactoryWithPriority(raw, container.RawFactoryPriority, []watcher.ContainerWatchSource{watcher.Raw})
+	fallback := &mockContainerHandlerFactory{
+		Name:           "fallback",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactoryWithPriority(fallback, container.FallbackFactoryPriority, []watcher.ContainerWatchSource{watcher.Raw})
+	runtime := &mockContainerHandlerFactory{
+		Name:           "runtime",
+		CanHandleValue: true,
+		CanAcceptValue: true,
+	}
+	container.RegisterContainerHandlerFactory(runtime, []watcher.ContainerWatchSource{watcher.Raw})
+
+	// The runtime factory should be asked to create the ContainerHandler.
+	mockContainer, err := mockFactory.NewContainerHandler(testContainerName, true)
+	if err != nil {
+		t.Error(err)
+	}
+	runtime.On("NewContainerHandler", testContainerName).Return(mockContainer, nil)
+
+	cont, _, err := container.NewContainerHandler(testContainerName, watcher.Raw, true)
+	if err != nil {
+		t.Error(err)
+	}
+	if cont == nil {
+		t.Error("Expected container to not be nil")
+	}
+	runtime.AssertExpectations(t)
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firecracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	maxUnixSocketPathSize = len(syscall.RawSockaddrUnix{}.Path)
+	connectionTimeout     = 2 * time.Second
+)
+
+// MachineConfig is the configuration of a microVM as returned by the
+// Firecracker API.
+type MachineConfig struct {
+	VcpuCount  int    `json:"vcpu_count"`
+	MemSizeMib uint64 `json:"mem_size_mib"`
+	Smt        bool   `json:"smt"`
+}
+
+// FirecrackerClient talks to the API socket of a single Firecracker process.
+type FirecrackerClient interface {
+	MachineConfig() (*MachineConfig, error)
+}
+
+type firecrackerClientImpl struct {
+	socket string
+	client *http.Client
+}
+
+// NewClient returns a client of the Firecracker API listening on the socket.
+func NewClient(socket string) (FirecrackerClient, error) {
+	if len(socket) > maxUnixSocketPathSize {
+		return nil, fmt.Errorf("Unix socket path %q is too long", socket)
+	}
+	tr := &http.Transport{
+		// No need for compression in local communications.
+		DisableCompression: true,
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, connectionTimeout)
+		},
+	}
+	return &firecrackerClientImpl{
+		socket: socket,
+		client: &http.Client{Transport: tr, Timeout: connectionTimeout},
+	}, nil
+}
+
+// MachineConfig returns the vCPUs and memory of the microVM.
+func (c *firecrackerClientImpl) MachineConfig() (*MachineConfig, error) {
+	resp, err := c.client.Get("http://firecracker/machine-config")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request for machine config to firecracker at %q failed with status %d", c.socket, resp.StatusCode)
+	}
+	config := MachineConfig{}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firecracker
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	ArgCgroupParent = flag.String("firecracker_cgroup_parent", "firecracker", "parent cgroup of the microVMs started by the Firecracker jailer, shell patterns such as firecracker/* are accepted for per tenant parents")
+	ArgChrootBase   = flag.String("firecracker_chroot_base", "/srv/jailer/firecracker", "directory holding the chroots of the microVMs started by the Firecracker jailer")
+	ArgAPISocket    = flag.String("firecracker_api_socket", "run/firecracker.socket", "path of the Firecracker API socket relative to the root of the jail")
+)
+
+// The namespace under which Firecracker aliases are unique.
+const FirecrackerNamespace = "firecracker"
+
+// Regexp that matches the ids of microVMs accepted by the jailer.
+var vmIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9-]{1,64}$`)
+
+type firecrackerFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *firecrackerFactory) String() string {
+	return FirecrackerNamespace
+}
+
+func (f *firecrackerFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	client, err := NewClient(apiSocketPath(path.Base(name), inHostNamespace))
+	if err != nil {
+		return
+	}
+	return newFirecrackerContainerHandler(
+		client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// apiSocketPath returns the path of the API socket of the given microVM.
+func apiSocketPath(id string, inHostNamespace bool) string {
+	socket := filepath.Join(*ArgChrootBase, id, "root", *ArgAPISocket)
+	if !inHostNamespace {
+		socket = filepath.Join("/rootfs", socket)
+	}
+	return socket
+}
+
+// isJailerCgroup reports whether the cgroup was created by the jailer for a
+// microVM, i.e. it is named after the VM id below the parent cgroup.
+func isJailerCgroup(name string) bool {
+	matched, err := path.Match(path.Join("/", *ArgCgroupParent), path.Dir(name))
+	if err != nil || !matched {
+		return false
+	}
+	return vmIDRegexp.MatchString(path.Base(name))
+}
+
+// firecracker handles the cgroups of all microVMs started by the jailer.
+func (f *firecrackerFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !isJailerCgroup(name) {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+func (f *firecrackerFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering firecracker factory")
+	f := &firecrackerFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	container.RegisterContainerHandlerFactory(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
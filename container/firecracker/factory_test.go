@@ -0,0 +1,50 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firecracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanHandleAndAccept(t *testing.T) {
+	defer func(old string) { *ArgCgroupParent = old }(*ArgCgroupParent)
+	f := &firecrackerFactory{}
+
+	for _, test := range []struct {
+		parent string
+		name   string
+		handle bool
+	}{
+		{"firecracker", "/firecracker/551e7604-e35c-42b3-b825-416853441234", true},
+		{"firecracker", "/firecracker/vm_1", false},
+		{"firecracker", "/firecracker", false},
+		{"firecracker", "/tenants/a/551e7604-e35c-42b3-b825-416853441234", false},
+		{"tenants/*", "/tenants/a/551e7604-e35c-42b3-b825-416853441234", true},
+		{"tenants/*", "/tenants/551e7604-e35c-42b3-b825-416853441234", false},
+	} {
+		*ArgCgroupParent = test.parent
+		canHandle, canAccept, err := f.CanHandleAndAccept(test.name)
+		assert.Nil(t, err)
+		assert.Equal(t, test.handle, canHandle, test.name)
+		assert.Equal(t, test.handle, canAccept, test.name)
+	}
+}
+
+func TestAPISocketPath(t *testing.T) {
+	assert.Equal(t, "/srv/jailer/firecracker/vm-1/root/run/firecracker.socket", apiSocketPath("vm-1", true))
+	assert.Equal(t, "/rootfs/srv/jailer/firecracker/vm-1/root/run/firecracker.socket", apiSocketPath("vm-1", false))
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for Firecracker microVMs started by the jailer.
+package firecracker
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Labels describing a microVM.
+	VMIDLabel         = "firecracker.vm_id"
+	CgroupParentLabel = "firecracker.cgroup_parent"
+	VcpuCountLabel    = "firecracker.vcpu_count"
+	MemSizeLabel      = "firecracker.mem_size_mib"
+
+	// Period used to express the vCPUs of a microVM as CFS quota when the
+	// cgroup sets none.
+	defaultCPUPeriod = 100000
+)
+
+type firecrackerContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	reference info.ContainerReference
+	labels    map[string]string
+
+	client FirecrackerClient
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &firecrackerContainerHandler{}
+
+func newFirecrackerContainerHandler(
+	client FirecrackerClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	// The jailer moves Firecracker into the network namespace of the microVM.
+	pid := 0
+	if pids, err := cgroupManager.GetPids(); err == nil && len(pids) > 0 {
+		pid = pids[0]
+	}
+
+	id := path.Base(name)
+	return &firecrackerContainerHandler{
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		reference: info.ContainerReference{
+			Id:        id,
+			Name:      name,
+			Aliases:   []string{id},
+			Namespace: FirecrackerNamespace,
+		},
+		labels: map[string]string{
+			VMIDLabel:         id,
+			CgroupParentLabel: strings.TrimPrefix(path.Dir(name), "/"),
+		},
+		client:              client,
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, pid, includedMetrics),
+	}, nil
+}
+
+func (h *firecrackerContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *firecrackerContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasNetwork := h.includedMetrics.Has(container.NetworkUsageMetrics)
+	hasFilesystem := false
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
+	if err != nil {
+		return spec, err
+	}
+
+	// The machine config can be changed until the microVM boots, so it is
+	// fetched whenever the spec is updated.
+	config, err := h.client.MachineConfig()
+	if err != nil {
+		klog.V(4).Infof("Unable to get machine config of microVM %q: %v", h.reference.Id, err)
+		spec.Labels = h.labels
+		return spec, nil
+	}
+	spec.Labels = make(map[string]string, len(h.labels)+2)
+	for k, v := range h.labels {
+		spec.Labels[k] = v
+	}
+	spec.Labels[VcpuCountLabel] = strconv.Itoa(config.VcpuCount)
+	spec.Labels[MemSizeLabel] = strconv.FormatUint(config.MemSizeMib, 10)
+	applyMachineConfig(&spec, config)
+	return spec, nil
+}
+
+// applyMachineConfig limits the spec to the resources of the microVM where
+// the cgroup is more permissive than the microVM itself.
+func applyMachineConfig(spec *info.ContainerSpec, config *MachineConfig) {
+	if config.VcpuCount > 0 && spec.Cpu.Quota == 0 {
+		spec.HasCpu = true
+		if spec.Cpu.Period == 0 {
+			spec.Cpu.Period = defaultCPUPeriod
+		}
+		spec.Cpu.Quota = uint64(config.VcpuCount) * spec.Cpu.Period
+	}
+	memory := config.MemSizeMib << 20
+	if memory > 0 && (spec.Memory.Limit == 0 || spec.Memory.Limit > memory) {
+		spec.HasMemory = true
+		spec.Memory.Limit = memory
+	}
+}
+
+func (h *firecrackerContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *firecrackerContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return []info.ContainerReference{}, nil
+}
+
+func (h *firecrackerContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *firecrackerContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *firecrackerContainerHandler) GetContainerIPAddress() string {
+	return ""
+}
+
+func (h *firecrackerContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *firecrackerContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *firecrackerContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeFirecracker
+}
+
+func (h *firecrackerContainerHandler) Start() {}
+
+func (h *firecrackerContainerHandler) Cleanup() {}
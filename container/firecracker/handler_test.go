@@ -0,0 +1,48 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package firecracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestApplyMachineConfig(t *testing.T) {
+	config := &MachineConfig{VcpuCount: 2, MemSizeMib: 512}
+
+	spec := info.ContainerSpec{}
+	applyMachineConfig(&spec, config)
+	assert.Equal(t, info.CpuSpec{Quota: 200000, Period: 100000}, spec.Cpu)
+	assert.Equal(t, uint64(512<<20), spec.Memory.Limit)
+	assert.True(t, spec.HasCpu)
+	assert.True(t, spec.HasMemory)
+
+	// Limits of the cgroup tighter than the microVM are kept.
+	spec = info.ContainerSpec{
+		Cpu:    info.CpuSpec{Quota: 50000, Period: 100000},
+		Memory: info.MemorySpec{Limit: 256 << 20},
+	}
+	applyMachineConfig(&spec, config)
+	assert.Equal(t, uint64(50000), spec.Cpu.Quota)
+	assert.Equal(t, uint64(256<<20), spec.Memory.Limit)
+
+	// Unlimited memory is reported as a huge limit.
+	spec = info.ContainerSpec{Memory: info.MemorySpec{Limit: 9223372036854771712}}
+	applyMachineConfig(&spec, config)
+	assert.Equal(t, uint64(512<<20), spec.Memory.Limit)
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gvisor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	// Label carrying the id of the sandbox on metrics of the metric server.
+	sandboxLabel = "sandbox"
+
+	connectionTimeout = 2 * time.Second
+
+	// Scrapes of the metric server cover all sandboxes, the result is shared
+	// by all handlers for this long.
+	scrapeCacheDuration = time.Second
+)
+
+// SandboxMetrics holds the values of the metrics exported for a sandbox,
+// summed over all other labels.
+type SandboxMetrics map[string]float64
+
+// MetricServerClient fetches the metrics of the runsc metric server.
+type MetricServerClient interface {
+	// Sandboxes returns the metrics of all sandboxes, keyed by sandbox id.
+	Sandboxes() (map[string]SandboxMetrics, error)
+}
+
+type metricServerClient struct {
+	address string
+	client  *http.Client
+
+	lock       sync.Mutex
+	lastScrape time.Time
+	sandboxes  map[string]SandboxMetrics
+}
+
+var (
+	clientOnce sync.Once
+	client     MetricServerClient
+)
+
+// Client returns the client of the metric server listening on the address,
+// either "unix://<path>" or "<host>:<port>".
+func Client(address string) MetricServerClient {
+	clientOnce.Do(func() {
+		network, addr := "tcp", address
+		if strings.HasPrefix(address, "unix://") {
+			network, addr = "unix", strings.TrimPrefix(address, "unix://")
+		}
+		tr := &http.Transport{
+			DisableCompression: true,
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return net.DialTimeout(network, addr, connectionTimeout)
+			},
+		}
+		client = &metricServerClient{
+			address: address,
+			client:  &http.Client{Transport: tr, Timeout: connectionTimeout},
+		}
+	})
+	return client
+}
+
+func (c *metricServerClient) Sandboxes() (map[string]SandboxMetrics, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sandboxes != nil && time.Since(c.lastScrape) < scrapeCacheDuration {
+		return c.sandboxes, nil
+	}
+	families, err := c.scrape()
+	if err != nil {
+		return nil, err
+	}
+	c.sandboxes = sandboxMetrics(families)
+	c.lastScrape = time.Now()
+	return c.sandboxes, nil
+}
+
+func (c *metricServerClient) scrape() (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequest("GET", "http://runsc/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request for metrics to runsc metric server at %q failed with status %d", c.address, resp.StatusCode)
+	}
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
+
+// sandboxMetrics groups the metric values by sandbox.
+func sandboxMetrics(families map[string]*dto.MetricFamily) map[string]SandboxMetrics {
+	sandboxes := map[string]SandboxMetrics{}
+	for name, family := range families {
+		for _, m := range family.GetMetric() {
+			id := ""
+			for _, l := range m.GetLabel() {
+				if l.GetName() == sandboxLabel {
+					id = l.GetValue()
+					break
+				}
+			}
+			if id == "" {
+				continue
+			}
+			if sandboxes[id] == nil {
+				sandboxes[id] = SandboxMetrics{}
+			}
+			switch {
+			case m.GetCounter() != nil:
+				sandboxes[id][name] += m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				sandboxes[id][name] += m.GetGauge().GetValue()
+			case m.GetUntyped() != nil:
+				sandboxes[id][name] += m.GetUntyped().GetValue()
+			}
+		}
+	}
+	return sandboxes
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gvisor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+)
+
+const testID = "9a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789abcdef"
+
+type metricServerClientMock struct {
+	sandboxes map[string]SandboxMetrics
+	err       error
+}
+
+func (c *metricServerClientMock) Sandboxes() (map[string]SandboxMetrics, error) {
+	return c.sandboxes, c.err
+}
+
+func TestSandboxMetrics(t *testing.T) {
+	as := assert.New(t)
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(`# TYPE meta_sandbox_running gauge
+meta_sandbox_running{sandbox="` + testID + `"} 1
+meta_sandbox_running{sandbox="other"} 1
+# TYPE runsc_syscalls_total counter
+runsc_syscalls_total{sandbox="` + testID + `",syscall="read"} 100
+runsc_syscalls_total{sandbox="` + testID + `",syscall="write"} 50
+runsc_syscalls_total{sandbox="other",syscall="read"} 7
+# TYPE meta_total_num_sandboxes counter
+meta_total_num_sandboxes 2
+`))
+	as.Nil(err)
+
+	sandboxes := sandboxMetrics(families)
+	as.Len(sandboxes, 2)
+	as.Equal(SandboxMetrics{"meta_sandbox_running": 1, "runsc_syscalls_total": 150}, sandboxes[testID])
+	as.Equal(SandboxMetrics{"meta_sandbox_running": 1, "runsc_syscalls_total": 7}, sandboxes["other"])
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gvisor
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgMetricServer = flag.String("gvisor_metric_server", "", "address of the runsc metric server, either unix://<path> or <host>:<port>. Empty value disables the gVisor factory.")
+
+// The namespace under which gVisor aliases are unique.
+const GvisorNamespace = "gvisor"
+
+// Regexp that identifies the sandbox id in cgroup names.
+var gvisorCgroupRegexp = regexp.MustCompile(`([a-f0-9]{64})`)
+
+// Minimum interval between two fetches of the sandboxes from the runsc metric
+// server. Cgroups of unknown sandboxes trigger a fetch once the list of
+// sandboxes is older than this.
+const sandboxesRefreshInterval = time.Second
+
+type gvisorFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+	client             MetricServerClient
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+
+	sandboxesLock sync.Mutex
+	// Ids of the sandboxes exported by the metric server when last fetched.
+	sandboxes map[string]struct{}
+	refreshed time.Time
+}
+
+func (f *gvisorFactory) String() string {
+	return GvisorNamespace
+}
+
+func (f *gvisorFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	return newGvisorContainerHandler(
+		f.client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToGvisorID returns the sandbox id from the full container name.
+func ContainerNameToGvisorID(name string) string {
+	id := path.Base(name)
+	if matches := gvisorCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+// gvisor handles the cgroups of sandboxes the runsc metric server exports
+// metrics for.
+func (f *gvisorFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	base := path.Base(name)
+	if strings.HasSuffix(base, ".mount") || !gvisorCgroupRegexp.MatchString(base) {
+		return false, false, nil
+	}
+	known, err := f.isSandbox(ContainerNameToGvisorID(name), time.Now())
+	if err != nil {
+		return false, false, err
+	}
+	return known, known, nil
+}
+
+// isSandbox returns whether the metric server exports the sandbox with the
+// given id. The sandboxes are fetched again when the id is unknown and they
+// were fetched more than sandboxesRefreshInterval ago.
+func (f *gvisorFactory) isSandbox(id string, now time.Time) (bool, error) {
+	f.sandboxesLock.Lock()
+	defer f.sandboxesLock.Unlock()
+	if _, ok := f.sandboxes[id]; ok {
+		return true, nil
+	}
+	if now.Sub(f.refreshed) < sandboxesRefreshInterval {
+		return false, nil
+	}
+
+	sandboxes, err := f.client.Sandboxes()
+	if err != nil {
+		return false, fmt.Errorf("failed to get sandboxes from runsc metric server: %v", err)
+	}
+	f.sandboxes = make(map[string]struct{}, len(sandboxes))
+	for id := range sandboxes {
+		f.sandboxes[id] = struct{}{}
+	}
+	f.refreshed = now
+	_, ok := f.sandboxes[id]
+	return ok, nil
+}
+
+func (f *gvisorFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	if *ArgMetricServer == "" {
+		return fmt.Errorf("no runsc metric server configured")
+	}
+	client := Client(*ArgMetricServer)
+	if _, err := client.Sandboxes(); err != nil {
+		return fmt.Errorf("unable to reach runsc metric server: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering gvisor factory")
+	f := &gvisorFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		client:             client,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	// Asked before the docker and containerd factories, which would claim the
+	// cgroups of the sandboxes as well.
+	container.RegisterContainerHandlerFactoryWithPriority(f, container.SandboxFactoryPriority, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for gVisor (runsc) sandboxes.
+package gvisor
+
+import (
+	"fmt"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Metrics of the Sentry exported by the runsc metric server.
+	syscallsMetric         = "runsc_syscalls_total"
+	platformSwitchesMetric = "runsc_platform_switches_total"
+)
+
+type gvisorContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	reference info.ContainerReference
+
+	client MetricServerClient
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &gvisorContainerHandler{}
+
+func newGvisorContainerHandler(
+	client MetricServerClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	// The Sentry and the gofer run in the network namespace of the sandbox.
+	pid := 0
+	if pids, err := cgroupManager.GetPids(); err == nil && len(pids) > 0 {
+		pid = pids[0]
+	}
+
+	id := ContainerNameToGvisorID(name)
+	return &gvisorContainerHandler{
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		reference: info.ContainerReference{
+			Id:        id,
+			Name:      name,
+			Aliases:   []string{id},
+			Namespace: GvisorNamespace,
+		},
+		client:              client,
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, pid, includedMetrics),
+	}, nil
+}
+
+func (h *gvisorContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *gvisorContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasNetwork := h.includedMetrics.Has(container.NetworkUsageMetrics)
+	hasFilesystem := false
+	return common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
+}
+
+func (h *gvisorContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+
+	if h.includedMetrics.Has(container.GvisorMetrics) {
+		h.getSentryStats(stats)
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+// getSentryStats adds the counters of the Sentry, the host cgroup stats
+// remain valid as the Sentry runs the application on the host.
+func (h *gvisorContainerHandler) getSentryStats(stats *info.ContainerStats) {
+	sandboxes, err := h.client.Sandboxes()
+	if err != nil {
+		klog.V(4).Infof("Unable to get metrics of gVisor sandbox %q: %v", h.reference.Id, err)
+		return
+	}
+	metrics, ok := sandboxes[h.reference.Id]
+	if !ok {
+		return
+	}
+	stats.Gvisor = &info.GvisorStats{
+		Syscalls:         uint64(metrics[syscallsMetric]),
+		PlatformSwitches: uint64(metrics[platformSwitchesMetric]),
+	}
+}
+
+func (h *gvisorContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return []info.ContainerReference{}, nil
+}
+
+func (h *gvisorContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *gvisorContainerHandler) GetContainerLabels() map[string]string {
+	return map[string]string{}
+}
+
+func (h *gvisorContainerHandler) GetContainerIPAddress() string {
+	return ""
+}
+
+func (h *gvisorContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *gvisorContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *gvisorContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeGvisor
+}
+
+func (h *gvisorContainerHandler) Start() {}
+
+func (h *gvisorContainerHandler) Cleanup() {}
@@ -0,0 +1,95 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gvisor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/google/cadvisor/container"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	f := &gvisorFactory{client: &metricServerClientMock{sandboxes: map[string]SandboxMetrics{testID: {}}}}
+
+	for _, test := range []struct {
+		name   string
+		handle bool
+	}{
+		{"/kubepods/besteffort/pod1234/" + testID, true},
+		{"/docker/" + testID, true},
+		{"/system.slice/run-containerd-" + testID + "-rootfs.mount", false},
+		{"/kubepods/besteffort/pod1234/0000000000000000000000000000000000000000000000000000000000000000", false},
+		{"/system.slice/docker.service", false},
+	} {
+		canHandle, canAccept, err := f.CanHandleAndAccept(test.name)
+		as.Nil(err, test.name)
+		as.Equal(test.handle, canHandle, test.name)
+		as.Equal(test.handle, canAccept, test.name)
+	}
+
+	// Known sandboxes are served from the cache.
+	f.client = &metricServerClientMock{err: fmt.Errorf("connection refused")}
+	canHandle, _, err := f.CanHandleAndAccept("/docker/" + testID)
+	as.Nil(err)
+	as.True(canHandle)
+
+	f.refreshed = time.Time{}
+	_, _, err = f.CanHandleAndAccept("/docker/0000000000000000000000000000000000000000000000000000000000000000")
+	as.NotNil(err)
+}
+
+func TestIsSandboxRefresh(t *testing.T) {
+	client := &metricServerClientMock{sandboxes: map[string]SandboxMetrics{}}
+	f := &gvisorFactory{client: client}
+	now := time.Now()
+	known, err := f.isSandbox(testID, now)
+	assert.NoError(t, err)
+	assert.False(t, known)
+
+	// The sandbox is started; it is only seen once the list is stale.
+	client.sandboxes = map[string]SandboxMetrics{testID: {}}
+	known, err = f.isSandbox(testID, now.Add(sandboxesRefreshInterval/2))
+	assert.NoError(t, err)
+	assert.False(t, known)
+	known, err = f.isSandbox(testID, now.Add(sandboxesRefreshInterval))
+	assert.NoError(t, err)
+	assert.True(t, known)
+}
+
+func TestSentryStats(t *testing.T) {
+	as := assert.New(t)
+	client := &metricServerClientMock{sandboxes: map[string]SandboxMetrics{
+		testID: {syscallsMetric: 150, platformSwitchesMetric: 42},
+	}}
+	handler, err := newGvisorContainerHandler(client, "/docker/"+testID, nil, &containerlibcontainer.CgroupSubsystems{}, true, container.MetricSet{})
+	as.Nil(err)
+	h := handler.(*gvisorContainerHandler)
+
+	stats := &info.ContainerStats{}
+	h.getSentryStats(stats)
+	as.Equal(&info.GvisorStats{Syscalls: 150, PlatformSwitches: 42}, stats.Gvisor)
+
+	client.sandboxes = map[string]SandboxMetrics{}
+	stats = &info.ContainerStats{}
+	h.getSentryStats(stats)
+	as.Nil(stats.Gvisor)
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kata
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	maxUnixSocketPathSize = len(syscall.RawSockaddrUnix{}.Path)
+	connectionTimeout     = 2 * time.Second
+)
+
+// ShimClient fetches the metrics exposed by the Kata shim of a sandbox, which
+// include the metrics of the agent running inside the guest.
+type ShimClient interface {
+	Metrics() (map[string]*dto.MetricFamily, error)
+}
+
+type shimClientImpl struct {
+	socket string
+	client *http.Client
+}
+
+// NewShimClient returns a client of the monitor socket of a Kata shim.
+func NewShimClient(socket string) (ShimClient, error) {
+	if len(socket) > maxUnixSocketPathSize {
+		return nil, fmt.Errorf("Unix socket path %q is too long", socket)
+	}
+	tr := &http.Transport{
+		DisableCompression: true,
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, connectionTimeout)
+		},
+	}
+	return &shimClientImpl{
+		socket: socket,
+		client: &http.Client{Transport: tr, Timeout: connectionTimeout},
+	}, nil
+}
+
+// Metrics returns the metric families in the text exposition format served
+// by the shim.
+func (c *shimClientImpl) Metrics() (map[string]*dto.MetricFamily, error) {
+	req, err := http.NewRequest("GET", "http://shim/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request for metrics to kata shim at %q failed with status %d", c.socket, resp.StatusCode)
+	}
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(resp.Body)
+}
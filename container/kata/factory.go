@@ -0,0 +1,154 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kata
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgKataSandboxesDir = flag.String("kata_sandboxes_dir", "/run/vc/sbs", "directory holding the state of Kata Containers sandboxes, including the monitor sockets of their shims")
+
+// The namespace under which Kata aliases are unique.
+const KataNamespace = "kata"
+
+// Name of the socket on which the shim of a sandbox serves its metrics.
+const shimMonitorSocket = "shim-monitor.sock"
+
+// Regexp that identifies the sandbox id in cgroup names, "kata_<id>" when
+// only the sandbox is placed in a cgroup and "<id>" or "cri-containerd-<id>.scope"
+// when the pod cgroup is created by the CRI runtime.
+var kataCgroupRegexp = regexp.MustCompile(`([a-f0-9]{64})(?:\.scope)?$`)
+
+type kataFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *kataFactory) String() string {
+	return KataNamespace
+}
+
+func (f *kataFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	client, err := NewShimClient(shimSocketPath(ContainerNameToKataID(name), inHostNamespace))
+	if err != nil {
+		return
+	}
+	return newKataContainerHandler(
+		client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToKataID returns the Kata sandbox id from the full container name.
+func ContainerNameToKataID(name string) string {
+	id := path.Base(name)
+	if matches := kataCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+// shimSocketPath returns the path of the monitor socket of the shim running
+// the given sandbox.
+func shimSocketPath(id string, inHostNamespace bool) string {
+	socket := filepath.Join(*ArgKataSandboxesDir, id, shimMonitorSocket)
+	if !inHostNamespace {
+		socket = filepath.Join("/rootfs", socket)
+	}
+	return socket
+}
+
+// kata handles the cgroups of sandboxes whose shim serves on a monitor
+// socket. The cgroups of other containers match kataCgroupRegexp as well, so
+// the socket is dialed rather than only looked up.
+func (f *kataFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !kataCgroupRegexp.MatchString(path.Base(name)) {
+		return false, false, nil
+	}
+	// The factory doesn't know whether it runs in the host namespace, the
+	// socket is looked up at both places.
+	id := ContainerNameToKataID(name)
+	for _, inHostNamespace := range []bool{true, false} {
+		if shimListening(shimSocketPath(id, inHostNamespace)) {
+			return true, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+// shimListening returns whether a shim accepts connections on the socket.
+func shimListening(socket string) bool {
+	fi, err := os.Lstat(socket)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", socket, connectionTimeout)
+	if err != nil {
+		klog.V(4).Infof("Kata shim socket %q is not served: %v", socket, err)
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func (f *kataFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering kata factory")
+	f := &kataFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	// Asked before the containerd and cri-o factories, which would claim the
+	// cgroups of the sandboxes as well.
+	container.RegisterContainerHandlerFactoryWithPriority(f, container.SandboxFactoryPriority, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
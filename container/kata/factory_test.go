@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kata
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testID      = "9a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789abcdef"
+	testStaleID = "1a1b2c3d4e5f60718293a4b5c6d7e8f90123456789abcdef0123456789abcdef"
+)
+
+func TestContainerNameToKataID(t *testing.T) {
+	for _, name := range []string{
+		"/kubepods/besteffort/pod1234/kata_" + testID,
+		"/kubepods/besteffort/pod1234/" + testID,
+		"/kubepods.slice/kubepods-besteffort.slice/cri-containerd-" + testID + ".scope",
+	} {
+		assert.Equal(t, testID, ContainerNameToKataID(name), name)
+	}
+}
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	dir, err := ioutil.TempDir("", "kata")
+	as.Nil(err)
+	defer os.RemoveAll(dir)
+	defer func(old string) { *ArgKataSandboxesDir = old }(*ArgKataSandboxesDir)
+	*ArgKataSandboxesDir = dir
+
+	as.Nil(os.MkdirAll(filepath.Join(dir, testID), 0755))
+	l, err := net.Listen("unix", filepath.Join(dir, testID, shimMonitorSocket))
+	as.Nil(err)
+	defer l.Close()
+	// The socket of a sandbox whose shim exited.
+	as.Nil(os.MkdirAll(filepath.Join(dir, testStaleID), 0755))
+	as.Nil(ioutil.WriteFile(filepath.Join(dir, testStaleID, shimMonitorSocket), nil, 0600))
+
+	f := &kataFactory{}
+	for _, test := range []struct {
+		name   string
+		handle bool
+	}{
+		{"/kubepods/besteffort/pod1234/kata_" + testID, true},
+		{"/kubepods.slice/cri-containerd-" + testID + ".scope", true},
+		{"/kubepods/besteffort/pod1234/" + testStaleID, false},
+		{"/kubepods/besteffort/pod1234/0000000000000000000000000000000000000000000000000000000000000000", false},
+		{"/system.slice/docker.service", false},
+	} {
+		canHandle, canAccept, err := f.CanHandleAndAccept(test.name)
+		as.Nil(err, test.name)
+		as.Equal(test.handle, canHandle, test.name)
+		as.Equal(test.handle, canAccept, test.name)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kata
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	// CPU times of the guest as read from /proc/stat by the agent, in ticks.
+	guestCPUTimeMetric = "kata_guest_cpu_time"
+	// Memory of the guest as read from /proc/meminfo by the agent, in bytes.
+	guestMeminfoMetric = "kata_guest_meminfo"
+
+	// The agent reports the total of all CPUs with this cpu label.
+	totalCPU = "total"
+
+	// USER_HZ, the unit of /proc/stat, which is 100 on all architectures
+	// supported by Kata.
+	userHZ = 100
+)
+
+// guestValues indexes the values of a metric family by two of its labels.
+func guestValues(family *dto.MetricFamily, outer, inner string) map[string]map[string]float64 {
+	values := map[string]map[string]float64{}
+	for _, m := range family.GetMetric() {
+		var o, i string
+		for _, l := range m.GetLabel() {
+			switch l.GetName() {
+			case outer:
+				o = l.GetValue()
+			case inner:
+				i = l.GetValue()
+			}
+		}
+		if values[o] == nil {
+			values[o] = map[string]float64{}
+		}
+		switch {
+		case m.GetGauge() != nil:
+			values[o][i] = m.GetGauge().GetValue()
+		case m.GetCounter() != nil:
+			values[o][i] = m.GetCounter().GetValue()
+		case m.GetUntyped() != nil:
+			values[o][i] = m.GetUntyped().GetValue()
+		}
+	}
+	return values
+}
+
+func ticksToNanoseconds(ticks float64) uint64 {
+	return uint64(ticks * float64(time.Second/userHZ))
+}
+
+// mergeGuestStats replaces the CPU and memory stats of the VMM seen on the
+// host with the ones of the workload running in the guest.
+func mergeGuestStats(families map[string]*dto.MetricFamily, stats *info.ContainerStats, includedMetrics container.MetricSet) error {
+	cpuFamily, ok := families[guestCPUTimeMetric]
+	if !ok {
+		return fmt.Errorf("metric %q not reported by the kata shim", guestCPUTimeMetric)
+	}
+	memFamily, ok := families[guestMeminfoMetric]
+	if !ok {
+		return fmt.Errorf("metric %q not reported by the kata shim", guestMeminfoMetric)
+	}
+
+	cpus := guestValues(cpuFamily, "cpu", "item")
+	total, ok := cpus[totalCPU]
+	if !ok {
+		return fmt.Errorf("total CPU time not reported by the kata shim")
+	}
+	user := total["user"] + total["nice"]
+	system := total["system"] + total["irq"] + total["softirq"]
+	stats.Cpu.Usage.User = ticksToNanoseconds(user)
+	stats.Cpu.Usage.System = ticksToNanoseconds(system)
+	stats.Cpu.Usage.Total = ticksToNanoseconds(user + system)
+	if includedMetrics.Has(container.CpuStealMetrics) {
+		// The guest kernel accounts the time the hypervisor did not run its
+		// vCPUs, which is the steal time of this container.
+		stats.Cpu.Usage.Steal = ticksToNanoseconds(total["steal"])
+	}
+	if includedMetrics.Has(container.PerCpuUsageMetrics) {
+		var perCPU []uint64
+		for cpu := 0; ; cpu++ {
+			times, ok := cpus[strconv.Itoa(cpu)]
+			if !ok {
+				break
+			}
+			perCPU = append(perCPU, ticksToNanoseconds(times["user"]+times["nice"]+times["system"]+times["irq"]+times["softirq"]))
+		}
+		stats.Cpu.Usage.PerCpu = perCPU
+	}
+
+	mem := guestValues(memFamily, "", "item")[""]
+	usage := mem["mem_total"] - mem["mem_free"]
+	stats.Memory.Usage = uint64(usage)
+	stats.Memory.Cache = uint64(mem["cached"] + mem["buffers"])
+	stats.Memory.RSS = uint64(mem["active_anon"] + mem["inactive_anon"])
+	stats.Memory.Swap = uint64(mem["swap_total"] - mem["swap_free"])
+	stats.Memory.MappedFile = uint64(mem["mapped"])
+	stats.Memory.WorkingSet = 0
+	if usage > mem["inactive_file"] {
+		stats.Memory.WorkingSet = uint64(usage - mem["inactive_file"])
+	}
+	return nil
+}
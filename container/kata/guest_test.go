@@ -0,0 +1,98 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kata
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/google/cadvisor/container"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const shimMetrics = `# HELP kata_guest_cpu_time Guest CPU stat.
+# TYPE kata_guest_cpu_time gauge
+kata_guest_cpu_time{cpu="total",item="user"} 300
+kata_guest_cpu_time{cpu="total",item="nice"} 20
+kata_guest_cpu_time{cpu="total",item="system"} 150
+kata_guest_cpu_time{cpu="total",item="irq"} 5
+kata_guest_cpu_time{cpu="total",item="softirq"} 5
+kata_guest_cpu_time{cpu="total",item="idle"} 10000
+kata_guest_cpu_time{cpu="total",item="steal"} 40
+kata_guest_cpu_time{cpu="0",item="user"} 200
+kata_guest_cpu_time{cpu="0",item="system"} 100
+kata_guest_cpu_time{cpu="1",item="user"} 100
+kata_guest_cpu_time{cpu="1",item="nice"} 20
+kata_guest_cpu_time{cpu="1",item="system"} 50
+kata_guest_cpu_time{cpu="1",item="irq"} 5
+kata_guest_cpu_time{cpu="1",item="softirq"} 5
+# HELP kata_guest_meminfo Statistics about memory usage in the system.
+# TYPE kata_guest_meminfo gauge
+kata_guest_meminfo{item="mem_total"} 2.147483648e+09
+kata_guest_meminfo{item="mem_free"} 1.073741824e+09
+kata_guest_meminfo{item="buffers"} 1.048576e+06
+kata_guest_meminfo{item="cached"} 5.24288e+08
+kata_guest_meminfo{item="active_anon"} 3.3554432e+08
+kata_guest_meminfo{item="inactive_anon"} 3.3554432e+07
+kata_guest_meminfo{item="inactive_file"} 2.68435456e+08
+kata_guest_meminfo{item="mapped"} 4.194304e+06
+kata_guest_meminfo{item="swap_total"} 0
+kata_guest_meminfo{item="swap_free"} 0
+`
+
+func TestMergeGuestStats(t *testing.T) {
+	as := assert.New(t)
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(shimMetrics))
+	as.Nil(err)
+
+	stats := &info.ContainerStats{}
+	stats.Cpu.Usage.Total = 1
+	stats.Memory.Usage = 1
+	err = mergeGuestStats(families, stats, container.MetricSet{
+		container.PerCpuUsageMetrics: struct{}{},
+		container.CpuStealMetrics:    struct{}{},
+	})
+	as.Nil(err)
+
+	as.Equal(uint64(3200000000), stats.Cpu.Usage.User)
+	as.Equal(uint64(1600000000), stats.Cpu.Usage.System)
+	as.Equal(uint64(4800000000), stats.Cpu.Usage.Total)
+	as.Equal([]uint64{3000000000, 1800000000}, stats.Cpu.Usage.PerCpu)
+	as.Equal(uint64(400000000), stats.Cpu.Usage.Steal)
+
+	as.Equal(uint64(1073741824), stats.Memory.Usage)
+	as.Equal(uint64(525336576), stats.Memory.Cache)
+	as.Equal(uint64(369098752), stats.Memory.RSS)
+	as.Equal(uint64(805306368), stats.Memory.WorkingSet)
+	as.Equal(uint64(4194304), stats.Memory.MappedFile)
+	as.Equal(uint64(0), stats.Memory.Swap)
+}
+
+func TestMergeGuestStatsMissingMetrics(t *testing.T) {
+	as := assert.New(t)
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(`kata_shim_threads 12
+`))
+	as.Nil(err)
+
+	stats := &info.ContainerStats{}
+	stats.Cpu.Usage.Total = 42
+	as.NotNil(mergeGuestStats(families, stats, container.MetricSet{}))
+	as.Equal(uint64(42), stats.Cpu.Usage.Total)
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for Kata Containers sandboxes.
+package kata
+
+import (
+	"fmt"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// VirtualizedLabel marks containers whose workload runs in a virtual
+// machine, its value is the technology used.
+const VirtualizedLabel = "io.cadvisor.virtualized"
+
+type kataContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	reference info.ContainerReference
+	labels    map[string]string
+
+	client ShimClient
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &kataContainerHandler{}
+
+func newKataContainerHandler(
+	client ShimClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	// The VMM runs in the network namespace of the pod, so its processes
+	// are a valid source of network stats.
+	pid := 0
+	if pids, err := cgroupManager.GetPids(); err == nil && len(pids) > 0 {
+		pid = pids[0]
+	}
+
+	id := ContainerNameToKataID(name)
+	return &kataContainerHandler{
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		reference: info.ContainerReference{
+			Id:        id,
+			Name:      name,
+			Aliases:   []string{id},
+			Namespace: KataNamespace,
+		},
+		labels:              map[string]string{VirtualizedLabel: "kata"},
+		client:              client,
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, pid, includedMetrics),
+	}, nil
+}
+
+func (h *kataContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *kataContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasNetwork := h.includedMetrics.Has(container.NetworkUsageMetrics)
+	hasFilesystem := false
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, hasNetwork, hasFilesystem)
+	spec.Labels = h.labels
+	return spec, err
+}
+
+func (h *kataContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+
+	// Without the guest stats the ones of the VMM are still meaningful, if
+	// less precise.
+	families, err := h.client.Metrics()
+	if err != nil {
+		klog.V(4).Infof("Unable to get metrics of kata sandbox %q: %v", h.reference.Id, err)
+	} else if err := mergeGuestStats(families, stats, h.includedMetrics); err != nil {
+		klog.V(4).Infof("Unable to merge guest stats of kata sandbox %q: %v", h.reference.Id, err)
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *kataContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	return []info.ContainerReference{}, nil
+}
+
+func (h *kataContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *kataContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *kataContainerHandler) GetContainerIPAddress() string {
+	return ""
+}
+
+func (h *kataContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *kataContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *kataContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeKata
+}
+
+func (h *kataContainerHandler) Start() {}
+
+func (h *kataContainerHandler) Cleanup() {}
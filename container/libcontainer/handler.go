@@ -31,11 +31,12 @@ import (
 
 	"github.com/google/cadvisor/container"
 	info "github.com/google/cadvisor/info/v1"
-	"golang.org/x/sys/unix"
+	"github.com/google/cadvisor/utils"
 
 	"github.com/opencontainers/runc/libcontainer"
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	fs2 "github.com/opencontainers/runc/libcontainer/cgroups/fs2"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fscommon"
 	"k8s.io/klog/v2"
 )
 
@@ -50,12 +51,17 @@ var (
 	referencedRegexp = regexp.MustCompile(`Referenced:\s*([0-9]+)\s*kB`)
 )
 
+// USER_HZ, the unit of CPU times in /proc/<pid>/stat, which is 100 on all
+// architectures supported by cAdvisor.
+const userHZ = 100
+
 type Handler struct {
 	cgroupManager   cgroups.Manager
 	rootFs          string
 	pid             int
 	includedMetrics container.MetricSet
 	pidMetricsCache map[int]*info.CpuSchedstat
+	pidGuestCache   map[int]uint64
 	cycles          uint64
 }
 
@@ -66,6 +72,7 @@ func NewHandler(cgroupManager cgroups.Manager, rootFs string, pid int, includedM
 		pid:             pid,
 		includedMetrics: includedMetrics,
 		pidMetricsCache: make(map[int]*info.CpuSchedstat),
+		pidGuestCache:   make(map[int]uint64),
 	}
 }
 
@@ -92,6 +99,36 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 	}
 	stats := newContainerStats(libcontainerStats, h.includedMetrics)
 
+	if readCgroupStats && cgroups.IsCgroup2UnifiedMode() && h.includedMetrics.Has(container.MemoryUsageMetrics) {
+		path := h.cgroupManager.Path("")
+		stats.Memory.Events, err = memoryEventsFromCgroup(path, "memory.events")
+		if err != nil {
+			klog.V(4).Infof("Unable to get memory events of %q: %v", path, err)
+		}
+		stats.Memory.LocalEvents, err = memoryEventsFromCgroup(path, "memory.events.local")
+		if err != nil {
+			klog.V(5).Infof("Unable to get local memory events of %q: %v", path, err)
+		}
+	}
+
+	if readCgroupStats && !cgroups.IsCgroup2UnifiedMode() && h.includedMetrics.Has(container.SlabMetrics) {
+		path := h.cgroupManager.Path("memory")
+		stats.Memory.Slab, err = slabStatsFromCgroup(path, uint64(os.Getpagesize()))
+		if err != nil {
+			klog.V(4).Infof("Unable to get slab caches of %q: %v", path, err)
+		}
+	}
+
+	if readCgroupStats && h.includedMetrics.Has(container.CpuUsageMetrics) {
+		path := h.cgroupManager.Path("cpu")
+		if cgroups.IsCgroup2UnifiedMode() {
+			path = h.cgroupManager.Path("")
+		}
+		if err := cpuStatFromCgroup(path, &stats.Cpu); err != nil {
+			klog.V(4).Infof("Unable to get cpu.stat of %q: %v", path, err)
+		}
+	}
+
 	if h.includedMetrics.Has(container.ProcessSchedulerMetrics) {
 		pids, err := h.cgroupManager.GetAllPids()
 		if err != nil {
@@ -104,6 +141,18 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 		}
 	}
 
+	if h.includedMetrics.Has(container.CpuStealMetrics) {
+		pids, err := h.cgroupManager.GetAllPids()
+		if err != nil {
+			klog.V(4).Infof("Could not get PIDs for container %d: %v", h.pid, err)
+		} else {
+			stats.Cpu.Usage.Guest, err = guestTimeFromProcs(h.rootFs, pids, h.pidGuestCache)
+			if err != nil {
+				klog.V(4).Infof("Unable to get guest CPU time: %v", err)
+			}
+		}
+	}
+
 	if h.includedMetrics.Has(container.ReferencedMemoryMetrics) {
 		h.cycles++
 		pids, err := h.cgroupManager.GetPids()
@@ -180,6 +229,12 @@ func (h *Handler) GetStats() (*info.ContainerStats, error) {
 			if err != nil {
 				klog.V(4).Infof("Unable to get Process Stats: %v", err)
 			}
+			if h.includedMetrics.Has(container.CpuLoadMetrics) {
+				stats.TaskStats, err = taskStatsFromProcs(h.rootFs, path)
+				if err != nil {
+					klog.V(4).Infof("Unable to get Task Stats: %v", err)
+				}
+			}
 		}
 
 		// if include processes metrics, just set threads metrics if exist, and has no relationship with cpu path
@@ -263,12 +318,12 @@ func processRootProcUlimits(rootFs string, rootPid int) []info.UlimitSpec {
 	return processLimitsFile(string(out))
 }
 
-func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.ProcessStats, error) {
-	var fdCount, socketCount uint64
+// cgroupProcs returns the pids listed in the cgroup.procs file of cgroupPath.
+func cgroupProcs(cgroupPath string) ([]string, error) {
 	filePath := path.Join(cgroupPath, "cgroup.procs")
 	out, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return info.ProcessStats{}, fmt.Errorf("couldn't open cpu cgroup procs file %v : %v", filePath, err)
+		return nil, fmt.Errorf("couldn't open cpu cgroup procs file %v : %v", filePath, err)
 	}
 
 	pids := strings.Split(string(out), "\n")
@@ -279,6 +334,15 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 	if len(pids) != 0 && pids[len(pids)-1] == "" {
 		pids = pids[:len(pids)-1]
 	}
+	return pids, nil
+}
+
+func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.ProcessStats, error) {
+	var fdCount, maxProcessFdCount, socketCount, inotifyInstances, inotifyWatches uint64
+	pids, err := cgroupProcs(cgroupPath)
+	if err != nil {
+		return info.ProcessStats{}, err
+	}
 
 	for _, pid := range pids {
 		dirPath := path.Join(rootFs, "/proc", pid, "fd")
@@ -288,6 +352,9 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 			continue
 		}
 		fdCount += uint64(len(fds))
+		if uint64(len(fds)) > maxProcessFdCount {
+			maxProcessFdCount = uint64(len(fds))
+		}
 		for _, fd := range fds {
 			fdPath := path.Join(dirPath, fd.Name())
 			linkName, err := os.Readlink(fdPath)
@@ -297,16 +364,34 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 			}
 			if strings.HasPrefix(linkName, "socket") {
 				socketCount++
+			} else if linkName == "anon_inode:inotify" {
+				inotifyInstances++
+				infoPath := path.Join(rootFs, "/proc", pid, "fdinfo", fd.Name())
+				watches, err := inotifyWatchesFromFdInfo(infoPath)
+				if err != nil {
+					klog.V(4).Infof("error while reading %q to measure inotify watches: %v", infoPath, err)
+					continue
+				}
+				inotifyWatches += watches
 			}
 		}
 	}
 
 	processStats := info.ProcessStats{
-		ProcessCount: uint64(len(pids)),
-		FdCount:      fdCount,
-		SocketCount:  socketCount,
+		ProcessCount:      uint64(len(pids)),
+		FdCount:           fdCount,
+		MaxProcessFdCount: maxProcessFdCount,
+		SocketCount:       socketCount,
+		InotifyInstances:  inotifyInstances,
+		InotifyWatches:    inotifyWatches,
 	}
 
+	// Not every runtime reports the root process of the container, fall
+	// back to the first process of the cgroup, which usually shares its
+	// ulimits.
+	if rootPid <= 0 && len(pids) > 0 {
+		rootPid, _ = strconv.Atoi(pids[0])
+	}
 	if rootPid > 0 {
 		processStats.Ulimits = processRootProcUlimits(rootFs, rootPid)
 	}
@@ -314,6 +399,69 @@ func processStatsFromProcs(rootFs string, cgroupPath string, rootPid int) (info.
 	return processStats, nil
 }
 
+// taskStatsFromProcs counts the threads of the processes of the cgroup by
+// their state in /proc/<pid>/task/<tid>/stat.
+func taskStatsFromProcs(rootFs string, cgroupPath string) (info.LoadStats, error) {
+	pids, err := cgroupProcs(cgroupPath)
+	if err != nil {
+		return info.LoadStats{}, err
+	}
+	var stats info.LoadStats
+	for _, pid := range pids {
+		dirPath := path.Join(rootFs, "/proc", pid, "task")
+		tasks, err := ioutil.ReadDir(dirPath)
+		if err != nil {
+			klog.V(4).Infof("error while listing directory %q to measure task states: %v", dirPath, err)
+			continue
+		}
+		for _, task := range tasks {
+			statPath := path.Join(dirPath, task.Name(), "stat")
+			out, err := ioutil.ReadFile(statPath)
+			if err != nil {
+				klog.V(4).Infof("error while reading %q to measure task state: %v", statPath, err)
+				continue
+			}
+			// The state follows the command name, which is in parentheses
+			// and may contain spaces: 42 (my command) S 1 ...
+			stat := string(out)
+			i := strings.LastIndex(stat, ")")
+			if i < 0 || i+2 >= len(stat) {
+				klog.V(4).Infof("unexpected format of %q: %q", statPath, stat)
+				continue
+			}
+			switch stat[i+2] {
+			case 'R':
+				stats.NrRunning++
+			case 'S':
+				stats.NrSleeping++
+			case 'D':
+				stats.NrUninterruptible++
+			case 'T', 't':
+				stats.NrStopped++
+			case 'Z':
+				stats.NrZombie++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// inotifyWatchesFromFdInfo returns the number of watches of an inotify
+// instance, its fdinfo file has one "inotify wd:" line per watch.
+func inotifyWatchesFromFdInfo(fdInfoPath string) (uint64, error) {
+	out, err := ioutil.ReadFile(fdInfoPath)
+	if err != nil {
+		return 0, err
+	}
+	var watches uint64
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "inotify wd:") {
+			watches++
+		}
+	}
+	return watches, nil
+}
+
 func schedulerStatsFromProcs(rootFs string, pids []int, pidMetricsCache map[int]*info.CpuSchedstat) (info.CpuSchedstat, error) {
 	for _, pid := range pids {
 		f, err := os.Open(path.Join(rootFs, "proc", strconv.Itoa(pid), "schedstat"))
@@ -358,6 +506,43 @@ func schedulerStatsFromProcs(rootFs string, pids []int, pidMetricsCache map[int]
 	return schedstats, nil
 }
 
+// guestTimeFromProcs sums the guest_time of the given processes, read from
+// /proc/<pid>/stat. Like schedulerStatsFromProcs, the last value seen for every
+// process is kept in pidGuestCache so that the total does not drop when a
+// process exits.
+func guestTimeFromProcs(rootFs string, pids []int, pidGuestCache map[int]uint64) (uint64, error) {
+	for _, pid := range pids {
+		contents, err := ioutil.ReadFile(path.Join(rootFs, "proc", strconv.Itoa(pid), "stat"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, fmt.Errorf("couldn't read stat of process %d: %v", pid, err)
+		}
+		// The command name may contain spaces, fields are counted after it.
+		end := bytes.LastIndexByte(contents, ')')
+		if end < 0 {
+			return 0, fmt.Errorf("unexpected format of stat file for process %d", pid)
+		}
+		fields := strings.Fields(string(contents[end+1:]))
+		// guest_time is the 43rd field of the file, the first one after the
+		// command name being the 3rd.
+		if len(fields) < 41 {
+			return 0, fmt.Errorf("unexpected number of fields in stat file for process %d", pid)
+		}
+		ticks, err := strconv.ParseUint(fields[40], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parsing error while reading guest time of process %d: %v", pid, err)
+		}
+		pidGuestCache[pid] = ticks * uint64(time.Second/userHZ)
+	}
+	var guest uint64
+	for _, v := range pidGuestCache {
+		guest += v
+	}
+	return guest, nil
+}
+
 // referencedBytesStat gets and clears referenced bytes
 // see: https://github.com/brendangregg/wss#wsspl-referenced-page-flag
 func referencedBytesStat(pids []int, cycles uint64, resetInterval uint64) (uint64, error) {
@@ -758,15 +943,8 @@ func (h *Handler) GetProcesses() ([]int, error) {
 	return pids, nil
 }
 
-func minUint32(x, y uint32) uint32 {
-	if x < y {
-		return x
-	}
-	return y
-}
-
 // var to allow unit tests to stub it out
-var numCpusFunc = getNumberOnlineCPUs
+var onlineCPUsFunc = getOnlineCPUs
 
 // Convert libcontainer stats to info.ContainerStats.
 func setCPUStats(s *cgroups.Stats, ret *info.ContainerStats, withPerCPU bool) {
@@ -786,36 +964,43 @@ func setCPUStats(s *cgroups.Stats, ret *info.ContainerStats, withPerCPU bool) {
 		return
 	}
 
-	numPossible := uint32(len(s.CpuStats.CpuUsage.PercpuUsage))
+	numPossible := len(s.CpuStats.CpuUsage.PercpuUsage)
 	// Note that as of https://patchwork.kernel.org/patch/8607101/ (kernel v4.7),
 	// the percpu usage information includes extra zero values for all additional
 	// possible CPUs. This is to allow statistic collection after CPU-hotplug.
-	// We intentionally ignore these extra zeroes.
-	numActual, err := numCpusFunc()
-	if err != nil {
-		klog.Errorf("unable to determine number of actual cpus; defaulting to maximum possible number: errno %v", err)
-		numActual = numPossible
+	// We intentionally ignore these extra zeroes. The usage stays indexed by
+	// CPU id, offline CPUs in between online ones are reported as zero.
+	online, err := onlineCPUsFunc()
+	if err != nil || len(online) == 0 {
+		klog.Errorf("unable to determine online cpus; defaulting to maximum possible number: %v", err)
+		ret.Cpu.Usage.PerCpu = make([]uint64, numPossible)
+		copy(ret.Cpu.Usage.PerCpu, s.CpuStats.CpuUsage.PercpuUsage)
+		return
 	}
+	numActual := online[len(online)-1] + 1
 	if numActual > numPossible {
-		// The real number of cores should never be greater than the number of
-		// datapoints reported in cpu usage.
-		klog.Errorf("PercpuUsage had %v cpus, but the actual number is %v; ignoring extra CPUs", numPossible, numActual)
+		// The highest online CPU should always be among the datapoints reported
+		// in cpu usage.
+		klog.Errorf("PercpuUsage had %v cpus, but the highest online cpu is %v; ignoring extra CPUs", numPossible, numActual-1)
+		numActual = numPossible
 	}
-	numActual = minUint32(numPossible, numActual)
 	ret.Cpu.Usage.PerCpu = make([]uint64, numActual)
-
-	for i := uint32(0); i < numActual; i++ {
-		ret.Cpu.Usage.PerCpu[i] = s.CpuStats.CpuUsage.PercpuUsage[i]
+	for _, cpu := range online {
+		if cpu < numActual {
+			ret.Cpu.Usage.PerCpu[cpu] = s.CpuStats.CpuUsage.PercpuUsage[cpu]
+		}
 	}
-
 }
 
-func getNumberOnlineCPUs() (uint32, error) {
-	var availableCPUs unix.CPUSet
-	if err := unix.SchedGetaffinity(0, &availableCPUs); err != nil {
-		return 0, err
+const onlineCPUsFile = "/sys/devices/system/cpu/online"
+
+// getOnlineCPUs returns the ids of the CPUs currently online on the host.
+func getOnlineCPUs() ([]int, error) {
+	content, err := ioutil.ReadFile(onlineCPUsFile)
+	if err != nil {
+		return nil, err
 	}
-	return uint32(availableCPUs.Count()), nil
+	return utils.ParseCpuList(string(content))
 }
 
 func setDiskIoStats(s *cgroups.Stats, ret *info.ContainerStats) {
@@ -870,6 +1055,150 @@ func setMemoryStats(s *cgroups.Stats, ret *info.ContainerStats) {
 	ret.Memory.WorkingSet = workingSet
 }
 
+// newMemoryStatBreakdown maps the keys of a cgroup v2 memory.stat file.
+// Kernels before 5.9 report workingset events for file pages only, without
+// the _anon/_file suffix.
+func newMemoryStatBreakdown(stats map[string]uint64) *info.MemoryStatBreakdown {
+	ret := &info.MemoryStatBreakdown{
+		Anon:                   stats["anon"],
+		File:                   stats["file"],
+		KernelStack:            stats["kernel_stack"],
+		Pagetables:             stats["pagetables"],
+		Percpu:                 stats["percpu"],
+		Sock:                   stats["sock"],
+		Shmem:                  stats["shmem"],
+		FileMapped:             stats["file_mapped"],
+		FileDirty:              stats["file_dirty"],
+		FileWriteback:          stats["file_writeback"],
+		AnonThp:                stats["anon_thp"],
+		InactiveAnon:           stats["inactive_anon"],
+		ActiveAnon:             stats["active_anon"],
+		InactiveFile:           stats["inactive_file"],
+		ActiveFile:             stats["active_file"],
+		Unevictable:            stats["unevictable"],
+		SlabReclaimable:        stats["slab_reclaimable"],
+		SlabUnreclaimable:      stats["slab_unreclaimable"],
+		WorkingsetRefaultAnon:  stats["workingset_refault_anon"],
+		WorkingsetRefaultFile:  stats["workingset_refault_file"],
+		WorkingsetActivateAnon: stats["workingset_activate_anon"],
+		WorkingsetActivateFile: stats["workingset_activate_file"],
+		WorkingsetRestoreAnon:  stats["workingset_restore_anon"],
+		WorkingsetRestoreFile:  stats["workingset_restore_file"],
+		WorkingsetNodereclaim:  stats["workingset_nodereclaim"],
+		Pgscan:                 stats["pgscan"],
+		Pgsteal:                stats["pgsteal"],
+	}
+	if v, ok := stats["workingset_refault"]; ok {
+		ret.WorkingsetRefaultFile = v
+	}
+	if v, ok := stats["workingset_activate"]; ok {
+		ret.WorkingsetActivateFile = v
+	}
+	if v, ok := stats["workingset_restore"]; ok {
+		ret.WorkingsetRestoreFile = v
+	}
+	return ret
+}
+
+// memoryEventsFromCgroup parses a memory.events file of a cgroup v2 path.
+// Events not known to the running kernel are reported as zero.
+func memoryEventsFromCgroup(path, file string) (*info.MemoryEvents, error) {
+	content, err := fscommon.ReadFile(path, file)
+	if err != nil {
+		return nil, err
+	}
+	events := &info.MemoryEvents{}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		key, value, err := fscommon.GetCgroupParamKeyValue(line)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "low":
+			events.Low = value
+		case "high":
+			events.High = value
+		case "max":
+			events.Max = value
+		case "oom":
+			events.Oom = value
+		case "oom_kill":
+			events.OomKill = value
+		case "oom_group_kill":
+			events.OomGroupKill = value
+		}
+	}
+	return events, nil
+}
+
+// slabStatsFromCgroup sums the slab caches of a cgroup v1 path from its
+// memory.kmem.slabinfo file, which has the format of /proc/slabinfo. The size
+// of a cache is the size of its slabs, including the space of free objects,
+// as the pages of the slabs are charged to the cgroup.
+func slabStatsFromCgroup(path string, pageSize uint64) (*info.MemorySlabStats, error) {
+	content, err := fscommon.ReadFile(path, "memory.kmem.slabinfo")
+	if err != nil {
+		return nil, err
+	}
+	stats := &info.MemorySlabStats{Caches: map[string]uint64{"dentry": 0, "inode": 0}}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		// dentry 1285 1344 192 21 1 : tunables 0 0 0 : slabdata 64 64 0
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "slabinfo" || fields[0] == "#" {
+			continue
+		}
+		if len(fields) < 15 || fields[6] != ":" || fields[11] != ":" {
+			return nil, fmt.Errorf("invalid slabinfo line %q", line)
+		}
+		var kind string
+		switch name := fields[0]; {
+		case name == "dentry":
+			kind = "dentry"
+		case strings.HasSuffix(name, "inode_cache") || strings.HasSuffix(name, "_inode"):
+			// ext4_inode_cache, proc_inode_cache, xfs_inode, ...
+			kind = "inode"
+		default:
+			continue
+		}
+		pagesPerSlab, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse pagesperslab of %q: %v", fields[0], err)
+		}
+		slabs, err := strconv.ParseUint(fields[13], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse num_slabs of %q: %v", fields[0], err)
+		}
+		stats.Caches[kind] += slabs * pagesPerSlab * pageSize
+	}
+	return stats, nil
+}
+
+// cpuStatFromCgroup fills in the cpu.stat entries that libcontainer does not
+// report: CFS burst statistics and the cgroup's runqueue wait time.
+func cpuStatFromCgroup(path string, stats *info.CpuStats) error {
+	content, err := fscommon.ReadFile(path, "cpu.stat")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		key, value, err := fscommon.GetCgroupParamKeyValue(line)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "nr_bursts":
+			stats.CFS.Bursts = value
+		case "burst_usec":
+			stats.CFS.BurstTime = value * uint64(time.Microsecond)
+		case "burst_time":
+			stats.CFS.BurstTime = value
+		case "wait_sum":
+			stats.WaitTime = value
+		}
+	}
+	return nil
+}
+
 func getNumaStats(memoryStats map[uint8]uint64) map[uint8]uint64 {
 	stats := make(map[uint8]uint64, len(memoryStats))
 	for node, usage := range memoryStats {
@@ -944,6 +1273,15 @@ func newContainerStats(libcontainerStats *libcontainer.Stats, includedMetrics co
 		if includedMetrics.Has(container.MemoryNumaMetrics) {
 			setMemoryNumaStats(s, ret)
 		}
+		if includedMetrics.Has(container.MemoryStatMetrics) && cgroups.IsCgroup2UnifiedMode() {
+			ret.Memory.Breakdown = newMemoryStatBreakdown(s.MemoryStats.Stats)
+		}
+		if includedMetrics.Has(container.SlabMetrics) && cgroups.IsCgroup2UnifiedMode() {
+			ret.Memory.Slab = &info.MemorySlabStats{
+				Reclaimable:   s.MemoryStats.Stats["slab_reclaimable"],
+				Unreclaimable: s.MemoryStats.Stats["slab_unreclaimable"],
+			}
+		}
 		if includedMetrics.Has(container.HugetlbUsageMetrics) {
 			setHugepageStats(s, ret)
 		}
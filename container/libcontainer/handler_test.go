@@ -15,7 +15,9 @@
 package libcontainer
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -98,8 +100,8 @@ const clockTicks = 100
 
 func TestMorePossibleCPUs(t *testing.T) {
 	realNumCPUs := uint32(8)
-	numCpusFunc = func() (uint32, error) {
-		return realNumCPUs, nil
+	onlineCPUsFunc = func() ([]int, error) {
+		return []int{0, 1, 2, 3, 4, 5, 6, 7}, nil
 	}
 	possibleCPUs := uint32(31)
 
@@ -137,6 +139,28 @@ func TestMorePossibleCPUs(t *testing.T) {
 	}
 }
 
+func TestOfflineCPUKeepsPerCPUAligned(t *testing.T) {
+	onlineCPUsFunc = func() ([]int, error) {
+		return []int{0, 1, 3}, nil
+	}
+
+	s := &cgroups.Stats{
+		CpuStats: cgroups.CpuStats{
+			CpuUsage: cgroups.CpuUsage{
+				PercpuUsage: []uint64{10, 20, 30, 40, 0, 0, 0, 0},
+				TotalUsage:  100,
+			},
+		},
+	}
+	var ret info.ContainerStats
+	setCPUStats(s, &ret, true)
+
+	expected := []uint64{10, 20, 0, 40}
+	if !reflect.DeepEqual(ret.Cpu.Usage.PerCpu, expected) {
+		t.Fatalf("expected per cpu usage %v, got %v", expected, ret.Cpu.Usage.PerCpu)
+	}
+}
+
 func TestSetProcessesStats(t *testing.T) {
 	ret := info.ContainerStats{
 		Processes: info.ProcessStats{
@@ -178,6 +202,217 @@ func TestSetProcessesStats(t *testing.T) {
 
 }
 
+func TestNewMemoryStatBreakdown(t *testing.T) {
+	stats := map[string]uint64{
+		"anon":                     1 << 20,
+		"file":                     2 << 20,
+		"kernel_stack":             16384,
+		"pagetables":               8192,
+		"percpu":                   512,
+		"sock":                     4096,
+		"shmem":                    1024,
+		"file_mapped":              3 << 10,
+		"file_dirty":               4 << 10,
+		"inactive_file":            5 << 10,
+		"slab_unreclaimable":       6 << 10,
+		"workingset_refault_anon":  7,
+		"workingset_refault_file":  8,
+		"workingset_activate_file": 9,
+		"pgscan":                   10,
+		"pgsteal":                  11,
+	}
+
+	breakdown := newMemoryStatBreakdown(stats)
+	assert.Equal(t, &info.MemoryStatBreakdown{
+		Anon:                   1 << 20,
+		File:                   2 << 20,
+		KernelStack:            16384,
+		Pagetables:             8192,
+		Percpu:                 512,
+		Sock:                   4096,
+		Shmem:                  1024,
+		FileMapped:             3 << 10,
+		FileDirty:              4 << 10,
+		InactiveFile:           5 << 10,
+		SlabUnreclaimable:      6 << 10,
+		WorkingsetRefaultAnon:  7,
+		WorkingsetRefaultFile:  8,
+		WorkingsetActivateFile: 9,
+		Pgscan:                 10,
+		Pgsteal:                11,
+	}, breakdown)
+}
+
+func TestNewMemoryStatBreakdownBeforeLinux59(t *testing.T) {
+	stats := map[string]uint64{
+		"anon":                12,
+		"workingset_refault":  13,
+		"workingset_activate": 14,
+		"workingset_restore":  15,
+	}
+
+	breakdown := newMemoryStatBreakdown(stats)
+	assert.Equal(t, uint64(12), breakdown.Anon)
+	assert.Equal(t, uint64(0), breakdown.WorkingsetRefaultAnon)
+	assert.Equal(t, uint64(13), breakdown.WorkingsetRefaultFile)
+	assert.Equal(t, uint64(14), breakdown.WorkingsetActivateFile)
+	assert.Equal(t, uint64(15), breakdown.WorkingsetRestoreFile)
+}
+
+func TestMemoryEventsFromCgroup(t *testing.T) {
+	events, err := memoryEventsFromCgroup("testdata/memory_events", "memory.events")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.MemoryEvents{
+		Low:          1,
+		High:         27,
+		Max:          5,
+		Oom:          2,
+		OomKill:      2,
+		OomGroupKill: 1,
+	}, events)
+
+	// Kernels before 5.17 do not report oom_group_kill.
+	events, err = memoryEventsFromCgroup("testdata/memory_events", "memory.events.local")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.MemoryEvents{
+		High:    12,
+		Max:     3,
+		Oom:     1,
+		OomKill: 1,
+	}, events)
+
+	_, err = memoryEventsFromCgroup("testdata/memory_events", "memory.missing")
+	assert.NotNil(t, err)
+}
+
+func TestSlabStatsFromCgroup(t *testing.T) {
+	stats, err := slabStatsFromCgroup("testdata/slabinfo", 4096)
+	assert.Nil(t, err)
+	assert.Equal(t, &info.MemorySlabStats{
+		Caches: map[string]uint64{
+			"dentry": 64 * 4096,
+			"inode":  (12*8 + 12*4 + 1*8) * 4096,
+		},
+	}, stats)
+
+	_, err = slabStatsFromCgroup("testdata/slabinfo/missing", 4096)
+	assert.NotNil(t, err)
+}
+
+func TestCpuStatFromCgroup(t *testing.T) {
+	stats := info.CpuStats{}
+	assert.Nil(t, cpuStatFromCgroup("testdata/cpu_stat/v1", &stats))
+	assert.Equal(t, uint64(7), stats.CFS.Bursts)
+	assert.Equal(t, uint64(150000000), stats.CFS.BurstTime)
+	assert.Equal(t, uint64(923456789), stats.WaitTime)
+
+	// cgroup v2 reports burst time in microseconds and has no wait_sum.
+	stats = info.CpuStats{}
+	assert.Nil(t, cpuStatFromCgroup("testdata/cpu_stat/v2", &stats))
+	assert.Equal(t, uint64(7), stats.CFS.Bursts)
+	assert.Equal(t, uint64(150000000), stats.CFS.BurstTime)
+	assert.Equal(t, uint64(0), stats.WaitTime)
+
+	assert.NotNil(t, cpuStatFromCgroup("testdata/cpu_stat/missing", &stats))
+}
+
+func TestGuestTimeFromProcs(t *testing.T) {
+	cache := map[int]uint64{}
+	// Process 1003 has already exited and is skipped.
+	guest, err := guestTimeFromProcs("testdata/guest_time", []int{1001, 1002, 1003}, cache)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2500000000), guest)
+
+	// The guest time of exited processes is kept.
+	guest, err = guestTimeFromProcs("testdata/guest_time", []int{1002}, cache)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2500000000), guest)
+}
+
+func TestProcessStatsFromProcs(t *testing.T) {
+	rootFs, err := ioutil.TempDir(os.TempDir(), "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootFs)
+
+	cgroupPath := filepath.Join(rootFs, "cgroup")
+	assert.Nil(t, os.MkdirAll(cgroupPath, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(cgroupPath, "cgroup.procs"), []byte("10\n20\n"), 0644))
+
+	fds := map[string]map[string]string{
+		"10": {"0": "/dev/null", "1": "socket:[1234]", "2": "anon_inode:inotify", "3": "anon_inode:inotify"},
+		"20": {"0": "/dev/null", "1": "anon_inode:[eventfd]"},
+	}
+	for pid, links := range fds {
+		assert.Nil(t, os.MkdirAll(filepath.Join(rootFs, "proc", pid, "fd"), 0755))
+		assert.Nil(t, os.MkdirAll(filepath.Join(rootFs, "proc", pid, "fdinfo"), 0755))
+		for fd, link := range links {
+			assert.Nil(t, os.Symlink(link, filepath.Join(rootFs, "proc", pid, "fd", fd)))
+		}
+	}
+	fdinfo := "pos:\t0\nflags:\t02004000\nmnt_id:\t15\n" +
+		"inotify wd:2 ino:1a sdev:801 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:1a00000000000000\n" +
+		"inotify wd:1 ino:1b sdev:801 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:1b00000000000000\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(rootFs, "proc", "10", "fdinfo", "2"), []byte(fdinfo), 0644))
+	// The fdinfo of the second instance is gone, its watches are not counted.
+	limits := "Limit                     Soft Limit           Hard Limit           Units     \n" +
+		"Max open files            1024                 4096                 files     \n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(rootFs, "proc", "10", "limits"), []byte(limits), 0644))
+
+	stats, err := processStatsFromProcs(rootFs, cgroupPath, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, info.ProcessStats{
+		ProcessCount:      2,
+		FdCount:           6,
+		MaxProcessFdCount: 4,
+		SocketCount:       1,
+		InotifyInstances:  2,
+		InotifyWatches:    2,
+		// Without a root pid the ulimits are those of the first process.
+		Ulimits: []info.UlimitSpec{{Name: "max_open_files", SoftLimit: 1024, HardLimit: 4096}},
+	}, stats)
+}
+
+func TestTaskStatsFromProcs(t *testing.T) {
+	rootFs, err := ioutil.TempDir(os.TempDir(), "")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootFs)
+
+	cgroupPath := filepath.Join(rootFs, "cgroup")
+	assert.Nil(t, os.MkdirAll(cgroupPath, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(cgroupPath, "cgroup.procs"), []byte("10\n20\n30\n"), 0644))
+
+	tasks := map[string]map[string]string{
+		"10": {
+			"10": "10 (web server) S 1 10 10 0 -1 4194560",
+			"11": "11 (worker (1)) R 1 10 10 0 -1 4194624",
+			"12": "12 (worker (2)) D 1 10 10 0 -1 4194624",
+		},
+		"20": {
+			"20": "20 (defunct) Z 10 20 20 0 -1 4227084",
+		},
+		"30": {
+			"30": "30 (gdb target) t 1 30 30 0 -1 4194560",
+		},
+	}
+	for pid, stats := range tasks {
+		for tid, stat := range stats {
+			dir := filepath.Join(rootFs, "proc", pid, "task", tid)
+			assert.Nil(t, os.MkdirAll(dir, 0755))
+			assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "stat"), []byte(stat+"\n"), 0644))
+		}
+	}
+
+	stats, err := taskStatsFromProcs(rootFs, cgroupPath)
+	assert.Nil(t, err)
+	assert.Equal(t, info.LoadStats{
+		NrSleeping:        1,
+		NrRunning:         1,
+		NrStopped:         1,
+		NrUninterruptible: 1,
+		NrZombie:          1,
+	}, stats)
+}
+
 func TestParseLimitsFile(t *testing.T) {
 	var testData = []struct {
 		limitLine string
@@ -300,3 +535,25 @@ func TestClearReferencedBytesWhenClearRefsMissing(t *testing.T) {
 	err := clearReferencedBytes(pids, 0, 1)
 	assert.Nil(t, err)
 }
+
+func TestMountsFromProc(t *testing.T) {
+	mounts, err := mountsFromProc("testdata/mounts", 1234)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.MountSpec{
+		{Destination: "/", Source: "/var/lib/docker/overlay2/4f2a/merged", Device: "overlay", FsType: "overlay", Propagation: "private"},
+		{Destination: "/proc", Source: "/", Device: "proc", FsType: "proc", Propagation: "private"},
+		{Destination: "/dev", Source: "/", Device: "tmpfs", FsType: "tmpfs", Propagation: "private"},
+		{Destination: "/etc/app", Source: "/etc/app", Device: "/dev/sda1", FsType: "ext4", ReadOnly: true, Propagation: "slave"},
+		{Destination: "/data", Source: "/var/lib/kubelet/pods/abc/volumes/data/db", Device: "/dev/sdb1", FsType: "xfs", Propagation: "shared,slave"},
+		{Destination: "/var/log/my app", Source: "/var/lib/kubelet/pods/abc/volumes/logs", Device: "/dev/sdb1", FsType: "xfs", Propagation: "unbindable"},
+	}, mounts)
+
+	// Without the host mounts, sources are paths within their filesystem.
+	mounts, err = mountsFromProc("testdata/mounts", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "/", mounts[0].Source)
+	assert.Equal(t, "shared", mounts[0].Propagation)
+
+	_, err = mountsFromProc("testdata/mounts", 1003)
+	assert.NotNil(t, err)
+}
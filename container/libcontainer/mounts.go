@@ -0,0 +1,199 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcontainer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// GetMounts returns the mounts of the mount namespace of the container's
+// init process, nil if the container has none.
+func (h *Handler) GetMounts() ([]info.MountSpec, error) {
+	if h.pid == 0 {
+		return nil, nil
+	}
+	return mountsFromProc(h.rootFs, h.pid)
+}
+
+// mountsFromProc reads the mounts of a process. The mounted directories are
+// resolved to host paths with the mounts of the host's init process.
+func mountsFromProc(rootFs string, pid int) ([]info.MountSpec, error) {
+	mounts, err := readMountinfo(path.Join(rootFs, "proc", strconv.Itoa(pid), "mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	hostMounts, err := readMountinfo(path.Join(rootFs, "proc", "1", "mountinfo"))
+	if err != nil {
+		klog.V(4).Infof("Unable to read host mounts, sources of the mounts of process %d are not resolved: %v", pid, err)
+	}
+
+	specs := make([]info.MountSpec, 0, len(mounts))
+	for i := range mounts {
+		mount := &mounts[i]
+		specs = append(specs, info.MountSpec{
+			Destination: mount.mountpoint,
+			Source:      hostSource(mount, hostMounts),
+			Device:      mount.source,
+			FsType:      mount.fsType,
+			ReadOnly:    hasOption(mount.options, "ro"),
+			Propagation: propagation(mount.optional),
+		})
+	}
+	return specs, nil
+}
+
+// mountEntry is an entry of a mountinfo file.
+type mountEntry struct {
+	major, minor string
+	root         string
+	mountpoint   string
+	options      string
+	// Optional fields, e.g. "shared:1 master:2".
+	optional string
+	fsType   string
+	source   string
+}
+
+// readMountinfo parses a mountinfo file, see proc(5). It is not parsed with
+// the mountinfo package, whose version in use drops the last optional field.
+func readMountinfo(file string) ([]mountEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 0 || separator+2 >= len(fields) {
+			return nil, fmt.Errorf("invalid mountinfo entry in %s: %q", file, scanner.Text())
+		}
+		device := strings.SplitN(fields[2], ":", 2)
+		if len(device) != 2 {
+			return nil, fmt.Errorf("invalid device %q in %s", fields[2], file)
+		}
+		mounts = append(mounts, mountEntry{
+			major:      device[0],
+			minor:      device[1],
+			root:       unescapeMountPath(fields[3]),
+			mountpoint: unescapeMountPath(fields[4]),
+			options:    fields[5],
+			optional:   strings.Join(fields[6:separator], " "),
+			fsType:     fields[separator+1],
+			source:     unescapeMountPath(fields[separator+2]),
+		})
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes of spaces, tabs, newlines and
+// backslashes in the paths of mountinfo, e.g. \040 for a space.
+func unescapeMountPath(p string) string {
+	if !strings.Contains(p, "\\") {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+4 <= len(p) {
+			if c, err := strconv.ParseUint(p[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// hostSource returns the host path of the directory mounted by a mount of a
+// container: the directory's path below the host mount of the same
+// filesystem whose root contains it. The host mount with the deepest root is
+// used, the first one if several mount the same directory.
+func hostSource(mount *mountEntry, hostMounts []mountEntry) string {
+	var best *mountEntry
+	for i := range hostMounts {
+		hostMount := &hostMounts[i]
+		if hostMount.major != mount.major || hostMount.minor != mount.minor || !isPathPrefix(hostMount.root, mount.root) {
+			continue
+		}
+		if best == nil || len(hostMount.root) > len(best.root) {
+			best = hostMount
+		}
+	}
+	if best == nil {
+		return mount.root
+	}
+	return path.Join(best.mountpoint, strings.TrimPrefix(mount.root, best.root))
+}
+
+func isPathPrefix(prefix, p string) bool {
+	return prefix == "/" || p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// propagation returns the propagation type of a mount from the optional
+// fields of its mountinfo entry, e.g. "shared:1 master:2".
+func propagation(optional string) string {
+	var shared, slave, unbindable bool
+	for _, field := range strings.Fields(optional) {
+		switch {
+		case strings.HasPrefix(field, "shared:"):
+			shared = true
+		case strings.HasPrefix(field, "master:"):
+			slave = true
+		case field == "unbindable":
+			unbindable = true
+		}
+	}
+	switch {
+	case shared && slave:
+		return "shared,slave"
+	case shared:
+		return "shared"
+	case slave:
+		return "slave"
+	case unbindable:
+		return "unbindable"
+	}
+	return "private"
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	maxUnixSocketPathSize = len(syscall.RawSockaddrUnix{}.Path)
+	connectionTimeout     = 2 * time.Second
+)
+
+// Instance represents the subset of an LXD instance used by cAdvisor.
+type Instance struct {
+	Name      string            `json:"name"`
+	Project   string            `json:"project"`
+	Type      string            `json:"type"`
+	Profiles  []string          `json:"profiles"`
+	Config    map[string]string `json:"config"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// response is the envelope of synchronous LXD API responses.
+type response struct {
+	Type       string          `json:"type"`
+	StatusCode int             `json:"status_code"`
+	Error      string          `json:"error"`
+	Metadata   json.RawMessage `json:"metadata"`
+}
+
+// LxdClient talks to the REST API of the LXD daemon.
+type LxdClient interface {
+	Ping() error
+	Instance(name, project string) (*Instance, error)
+}
+
+type lxdClientImpl struct {
+	socket string
+	client *http.Client
+}
+
+var (
+	clientOnce sync.Once
+	lxdClient  LxdClient
+	clientErr  error
+)
+
+// Client returns the client of the LXD daemon listening on the socket.
+func Client(socket string) (LxdClient, error) {
+	clientOnce.Do(func() {
+		if len(socket) > maxUnixSocketPathSize {
+			clientErr = fmt.Errorf("Unix socket path %q is too long", socket)
+			return
+		}
+		tr := &http.Transport{
+			// No need for compression in local communications.
+			DisableCompression: true,
+			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+				return net.DialTimeout("unix", socket, connectionTimeout)
+			},
+		}
+		lxdClient = &lxdClientImpl{
+			socket: socket,
+			client: &http.Client{Transport: tr},
+		}
+	})
+	return lxdClient, clientErr
+}
+
+func (c *lxdClientImpl) get(path string, v interface{}) error {
+	resp, err := c.client.Get("http://lxd" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("unable to decode response of LXD at %q to %q: %v", c.socket, path, err)
+	}
+	if r.Type == "error" || resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %q to LXD at %q failed with status %d: %s", path, c.socket, resp.StatusCode, r.Error)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(r.Metadata, v)
+}
+
+// Ping checks whether the LXD daemon is reachable.
+func (c *lxdClientImpl) Ping() error {
+	return c.get("/1.0", nil)
+}
+
+// Instance returns the instance with the given name in the project.
+func (c *lxdClientImpl) Instance(name, project string) (*Instance, error) {
+	path := "/1.0/instances/" + url.PathEscape(name)
+	if project != "" {
+		path += "?project=" + url.QueryEscape(project)
+	}
+	instance := Instance{}
+	if err := c.get(path, &instance); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lxd
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgLxdEndpoint = flag.String("lxd", "/var/snap/lxd/common/lxd/unix.socket", "lxd endpoint, /var/lib/lxd/unix.socket for non-snap installations")
+
+// The namespace under which LXD aliases are unique.
+const LxdNamespace = "lxd"
+
+// Project of instances whose cgroup name carries no project.
+const defaultProject = "default"
+
+// Regexp that identifies the cgroups of LXD instances, "lxc.payload.<name>"
+// or "lxc.payload.<project>_<name>" for instances of other projects than
+// the default one. The cgroups of the monitor processes, "lxc.monitor.<name>",
+// are not matched.
+var lxdCgroupRegexp = regexp.MustCompile(`^lxc\.payload\.([a-zA-Z0-9_-]+)$`)
+
+type lxdFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *lxdFactory) String() string {
+	return LxdNamespace
+}
+
+func (f *lxdFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	socket := *ArgLxdEndpoint
+	if !inHostNamespace {
+		socket = filepath.Join("/rootfs", socket)
+	}
+	client, err := Client(socket)
+	if err != nil {
+		return
+	}
+	return newLxdContainerHandler(
+		client,
+		name,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToLxdInstance returns the name and project of the LXD
+// instance from the full container name.
+func ContainerNameToLxdInstance(name string) (string, string) {
+	instance := path.Base(name)
+	if matches := lxdCgroupRegexp.FindStringSubmatch(instance); matches != nil {
+		instance = matches[1]
+	}
+	// Instance names can't contain underscores, which separate the project.
+	if i := strings.Index(instance, "_"); i >= 0 {
+		return instance[i+1:], instance[:i]
+	}
+	return instance, defaultProject
+}
+
+// lxd handles the payload cgroups of all LXD instances.
+func (f *lxdFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !lxdCgroupRegexp.MatchString(path.Base(name)) {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+func (f *lxdFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	client, err := Client(*ArgLxdEndpoint)
+	if err != nil {
+		return fmt.Errorf("unable to create lxd client: %v", err)
+	}
+	if err := client.Ping(); err != nil {
+		return fmt.Errorf("unable to communicate with lxd: %v", err)
+	}
+
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	klog.V(1).Infof("Registering lxd factory")
+	f := &lxdFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	container.RegisterContainerHandlerFactory(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lxd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerNameToLxdInstance(t *testing.T) {
+	for name, expected := range map[string][2]string{
+		"/lxc.payload.web":                   {"web", "default"},
+		"/lxc.payload.staging_web-01":        {"web-01", "staging"},
+		"/lxc.payload.web/init.scope":        {"init.scope", "default"},
+		"/sys/fs/cgroup/lxc.payload.db-main": {"db-main", "default"},
+	} {
+		instance, project := ContainerNameToLxdInstance(name)
+		assert.Equal(t, expected, [2]string{instance, project}, name)
+	}
+}
+
+func TestCanHandleAndAccept(t *testing.T) {
+	f := &lxdFactory{}
+	for name, expected := range map[string]bool{
+		"/lxc.payload.web":            true,
+		"/lxc.payload.staging_web-01": true,
+		"/lxc.monitor.web":            false,
+		"/lxc.payload.web/init.scope": false,
+		"/system.slice/lxd.service":   false,
+	} {
+		canHandle, canAccept, err := f.CanHandleAndAccept(name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, canHandle, name)
+		assert.Equal(t, expected, canAccept, name)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for LXD instances.
+package lxd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Labels describing the LXD configuration of an instance.
+	ProjectLabel  = "lxd.project"
+	ProfilesLabel = "lxd.profiles"
+	TypeLabel     = "lxd.type"
+
+	// Prefix of the user defined configuration keys, which are reported as labels.
+	userConfigPrefix = "user."
+)
+
+type lxdContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	// Metadata associated with the container.
+	reference    info.ContainerReference
+	labels       map[string]string
+	creationTime time.Time
+
+	// Image the instance was created from.
+	image string
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &lxdContainerHandler{}
+
+func newLxdContainerHandler(
+	client LxdClient,
+	name string,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceName, project := ContainerNameToLxdInstance(name)
+	instance, err := client.Instance(instanceName, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lxd instance %q of project %q: %v", instanceName, project, err)
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	// Any process of the instance shares its network namespace.
+	pid := 0
+	if pids, err := cgroupManager.GetPids(); err == nil && len(pids) > 0 {
+		pid = pids[0]
+	}
+
+	handler := &lxdContainerHandler{
+		machineInfoFactory: machineInfoFactory,
+		cgroupPaths:        cgroupPaths,
+		reference: info.ContainerReference{
+			Id:        instanceName,
+			Name:      name,
+			Aliases:   []string{instanceName},
+			Namespace: LxdNamespace,
+		},
+		labels: map[string]string{
+			ProjectLabel:  project,
+			ProfilesLabel: strings.Join(instance.Profiles, ","),
+		},
+		creationTime:        instance.CreatedAt,
+		image:               instance.Config["image.description"],
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, pid, includedMetrics),
+	}
+	if instance.Type != "" {
+		handler.labels[TypeLabel] = instance.Type
+	}
+	if handler.image == "" {
+		handler.image = instance.Config["volatile.base_image"]
+	}
+	for k, v := range instance.Config {
+		if strings.HasPrefix(k, userConfigPrefix) {
+			handler.labels[k] = v
+		}
+	}
+
+	return handler, nil
+}
+
+func (h *lxdContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *lxdContainerHandler) needNet() bool {
+	return h.includedMetrics.Has(container.NetworkUsageMetrics)
+}
+
+func (h *lxdContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasFilesystem := false
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
+	spec.Labels = h.labels
+	spec.Image = h.image
+	if !h.creationTime.IsZero() {
+		spec.CreationTime = h.creationTime
+	}
+
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
+
+	return spec, err
+}
+
+func (h *lxdContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+	if !h.needNet() {
+		stats.Network = info.NetworkStats{}
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *lxdContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	// No-op for lxd driver.
+	return []info.ContainerReference{}, nil
+}
+
+func (h *lxdContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *lxdContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *lxdContainerHandler) GetContainerIPAddress() string {
+	return ""
+}
+
+func (h *lxdContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *lxdContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *lxdContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypeLxd
+}
+
+func (h *lxdContainerHandler) Start() {}
+
+func (h *lxdContainerHandler) Cleanup() {}
@@ -0,0 +1,82 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lxd
+
+import (
+	"fmt"
+	"testing"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+type lxdClientMock struct {
+	instances map[string]*Instance
+}
+
+func (c *lxdClientMock) Ping() error {
+	return nil
+}
+
+func (c *lxdClientMock) Instance(name, project string) (*Instance, error) {
+	instance, ok := c.instances[project+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("instance %q not found in project %q", name, project)
+	}
+	return instance, nil
+}
+
+func TestHandler(t *testing.T) {
+	as := assert.New(t)
+
+	client := &lxdClientMock{instances: map[string]*Instance{
+		"staging/web-01": {
+			Name:     "web-01",
+			Project:  "staging",
+			Type:     "container",
+			Profiles: []string{"default", "web"},
+			Config: map[string]string{
+				"image.description":   "Ubuntu focal amd64 (20210115_07:42)",
+				"volatile.base_image": "4e1e1dfb0f8a3eb1f5bd7e1e0e0fbb3a3d3a6a0e",
+				"user.team":           "storefront",
+				"limits.cpu":          "2",
+			},
+		},
+	}}
+
+	name := "/lxc.payload.staging_web-01"
+	handler, err := newLxdContainerHandler(client, name, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.Nil(err)
+
+	ref, err := handler.ContainerReference()
+	as.Nil(err)
+	as.Equal(info.ContainerReference{
+		Id:        "web-01",
+		Name:      name,
+		Aliases:   []string{"web-01"},
+		Namespace: LxdNamespace,
+	}, ref)
+	as.Equal(map[string]string{
+		ProjectLabel:  "staging",
+		ProfilesLabel: "default,web",
+		TypeLabel:     "container",
+		"user.team":   "storefront",
+	}, handler.GetContainerLabels())
+	as.Equal("Ubuntu focal amd64 (20210115_07:42)", handler.(*lxdContainerHandler).image)
+
+	_, err = newLxdContainerHandler(client, "/lxc.payload.web-01", nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.NotNil(err)
+}
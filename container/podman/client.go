@@ -0,0 +1,137 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// Version of the libpod REST API used for requests.
+	apiVersion = "v3.0.0"
+
+	maxUnixSocketPathSize = len(syscall.RawSockaddrUnix{}.Path)
+	connectionTimeout     = 2 * time.Second
+)
+
+// ContainerInfo represents the subset of the libpod container inspect
+// response used by cAdvisor.
+type ContainerInfo struct {
+	ID        string    `json:"Id"`
+	Name      string    `json:"Name"`
+	Created   time.Time `json:"Created"`
+	Image     string    `json:"Image"`
+	ImageName string    `json:"ImageName"`
+	State     struct {
+		Pid int `json:"Pid"`
+		// Whether the container was restored from a checkpoint.
+		Restored bool `json:"Restored"`
+	} `json:"State"`
+	Config struct {
+		Labels      map[string]string `json:"Labels"`
+		Annotations map[string]string `json:"Annotations"`
+		Env         []string          `json:"Env"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		IPAddress string `json:"IPAddress"`
+	} `json:"NetworkSettings"`
+}
+
+// PodmanClient talks to the REST API of a single podman service.
+type PodmanClient interface {
+	Ping() error
+	ContainerInfo(id string) (*ContainerInfo, error)
+}
+
+type podmanClientImpl struct {
+	socket string
+	client *http.Client
+}
+
+var (
+	clientsLock sync.Mutex
+	clients     = map[string]PodmanClient{}
+)
+
+// Client returns the client for the podman service listening on the socket.
+// Clients are cached, there is one per user running podman.
+func Client(socket string) (PodmanClient, error) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	if client, ok := clients[socket]; ok {
+		return client, nil
+	}
+	if len(socket) > maxUnixSocketPathSize {
+		return nil, fmt.Errorf("Unix socket path %q is too long", socket)
+	}
+	tr := &http.Transport{
+		// No need for compression in local communications.
+		DisableCompression: true,
+		DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
+			return net.DialTimeout("unix", socket, connectionTimeout)
+		},
+	}
+	client := &podmanClientImpl{
+		socket: socket,
+		client: &http.Client{Transport: tr},
+	}
+	clients[socket] = client
+	return client, nil
+}
+
+func (c *podmanClientImpl) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", "http://d/"+apiVersion+path, nil)
+	if err != nil {
+		return err
+	}
+	// For local communications over a unix socket, it doesn't matter what
+	// the host is. We just need a valid and meaningful host name.
+	req.Host = "podman"
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %q to podman at %q failed with status %d", path, c.socket, resp.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Ping checks whether the podman service is reachable.
+func (c *podmanClientImpl) Ping() error {
+	return c.get("/libpod/_ping", nil)
+}
+
+// ContainerInfo returns information about a given container
+func (c *podmanClientImpl) ContainerInfo(id string) (*ContainerInfo, error) {
+	cInfo := ContainerInfo{}
+	if err := c.get("/libpod/containers/"+id+"/json", &cInfo); err != nil {
+		return nil, err
+	}
+	return &cInfo, nil
+}
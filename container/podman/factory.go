@@ -0,0 +1,192 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/libcontainer"
+	"github.com/google/cadvisor/fs"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+var ArgPodmanEndpoint = flag.String("podman", "/run/podman/podman.sock", "podman endpoint of the system (rootful) service")
+var ArgPodmanUserEndpoint = flag.String("podman_user", "/run/user/%d/podman/podman.sock", "podman endpoint of rootless services, %d is replaced by the uid of the user running the container")
+
+// The namespace under which podman aliases are unique.
+const PodmanNamespace = "podman"
+
+var (
+	// Regexp that identifies podman cgroups, "libpod-<id>.scope" with the
+	// systemd cgroup manager and "libpod-<id>" with cgroupfs.
+	podmanCgroupRegexp = regexp.MustCompile(`^libpod-([a-z0-9]{64})(?:\.scope)?$`)
+
+	// Regexp that extracts the uid of the user manager a rootless container
+	// runs under, e.g. "/user.slice/user-1000.slice/user@1000.service/...".
+	userServiceRegexp = regexp.MustCompile(`/user@(\d+)\.service/`)
+)
+
+type podmanFactory struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Information about the mounted cgroup subsystems.
+	cgroupSubsystems libcontainer.CgroupSubsystems
+
+	// Information about mounted filesystems.
+	fsInfo fs.FsInfo
+
+	includedMetrics container.MetricSet
+}
+
+func (f *podmanFactory) String() string {
+	return PodmanNamespace
+}
+
+func (f *podmanFactory) NewContainerHandler(name string, inHostNamespace bool) (handler container.ContainerHandler, err error) {
+	uid, rootless := containerNameToUID(name)
+	client, err := Client(socketPath(uid, rootless, inHostNamespace))
+	if err != nil {
+		return
+	}
+	return newPodmanContainerHandler(
+		client,
+		name,
+		uid,
+		rootless,
+		f.machineInfoFactory,
+		&f.cgroupSubsystems,
+		inHostNamespace,
+		f.includedMetrics,
+	)
+}
+
+// ContainerNameToPodmanID returns the podman ID from the full container name.
+func ContainerNameToPodmanID(name string) string {
+	id := path.Base(name)
+	if matches := podmanCgroupRegexp.FindStringSubmatch(id); matches != nil {
+		return matches[1]
+	}
+	return id
+}
+
+// containerNameToUID returns the uid of the user running a rootless
+// container, the second return value is false for rootful containers.
+func containerNameToUID(name string) (int, bool) {
+	matches := userServiceRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return 0, false
+	}
+	uid, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	return uid, true
+}
+
+// socketPath returns the path of the podman service socket responsible for
+// containers of the given user.
+func socketPath(uid int, rootless bool, inHostNamespace bool) string {
+	socket := *ArgPodmanEndpoint
+	if rootless {
+		socket = fmt.Sprintf(*ArgPodmanUserEndpoint, uid)
+	}
+	if !inHostNamespace {
+		socket = filepath.Join("/rootfs", socket)
+	}
+	return socket
+}
+
+// serviceSockets returns the socket of the system service followed by the
+// existing sockets of the rootless services.
+func serviceSockets(inHostNamespace bool) []string {
+	sockets := []string{socketPath(0, false, inHostNamespace)}
+	pattern := strings.Replace(*ArgPodmanUserEndpoint, "%d", "*", 1)
+	if !inHostNamespace {
+		pattern = filepath.Join("/rootfs", pattern)
+	}
+	userSockets, err := filepath.Glob(pattern)
+	if err != nil {
+		klog.Warningf("Invalid podman endpoint of rootless services %q: %v", *ArgPodmanUserEndpoint, err)
+	}
+	return append(sockets, userSockets...)
+}
+
+// pingAny returns an error if none of the podman services listening on the
+// sockets is reachable.
+func pingAny(sockets []string) error {
+	var errs []string
+	for _, socket := range sockets {
+		client, err := Client(socket)
+		if err == nil {
+			err = client.Ping()
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", socket, err))
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// podman handles all cgroups of libpod containers, both rootful ones and
+// rootless ones running under a user manager.
+func (f *podmanFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	if !podmanCgroupRegexp.MatchString(path.Base(name)) {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+func (f *podmanFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
+
+// Register root container before running this function!
+func Register(factory info.MachineInfoFactory, fsInfo fs.FsInfo, includedMetrics container.MetricSet) error {
+	cgroupSubsystems, err := libcontainer.GetCgroupSubsystems(includedMetrics)
+	if err != nil {
+		return fmt.Errorf("failed to get cgroup subsystems: %v", err)
+	}
+
+	// The sockets of rootless services exist while they are socket activated,
+	// so one reachable service of any user is enough.
+	_, err = os.Stat("/rootfs/proc")
+	inHostNamespace := os.IsNotExist(err)
+	if err := pingAny(serviceSockets(inHostNamespace)); err != nil {
+		return fmt.Errorf("unable to communicate with podman service: %v", err)
+	}
+
+	klog.V(1).Infof("Registering podman factory")
+	f := &podmanFactory{
+		cgroupSubsystems:   cgroupSubsystems,
+		fsInfo:             fsInfo,
+		machineInfoFactory: factory,
+		includedMetrics:    includedMetrics,
+	}
+
+	container.RegisterContainerHandlerFactory(f, []watcher.ContainerWatchSource{watcher.Raw})
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testID = "81e5c2990803c383229c9680ce964738d5e566d97f5bd436ac34808d2ec75d5f"
+
+func TestCanHandleAndAccept(t *testing.T) {
+	as := assert.New(t)
+	f := &podmanFactory{
+		cgroupSubsystems: containerlibcontainer.CgroupSubsystems{},
+	}
+	for k, v := range map[string]bool{
+		"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testID + ".scope":        true,
+		"/machine.slice/libpod-" + testID + ".scope":                                                  true,
+		"/libpod_parent/libpod-" + testID:                                                             true,
+		"/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-conmon-" + testID + ".scope": false,
+		"/machine.slice/libpod-" + testID + ".scope/container":                                        false,
+		"/system.slice/docker-" + testID + ".scope":                                                   false,
+	} {
+		b1, b2, err := f.CanHandleAndAccept(k)
+		as.Nil(err)
+		as.Equal(v, b1, k)
+		as.Equal(v, b2, k)
+	}
+}
+
+func TestContainerNameToUID(t *testing.T) {
+	as := assert.New(t)
+
+	uid, rootless := containerNameToUID("/user.slice/user-1000.slice/user@1000.service/user.slice/libpod-" + testID + ".scope")
+	as.True(rootless)
+	as.Equal(1000, uid)
+
+	_, rootless = containerNameToUID("/machine.slice/libpod-" + testID + ".scope")
+	as.False(rootless)
+}
+
+func TestSocketPath(t *testing.T) {
+	as := assert.New(t)
+	as.Equal("/run/podman/podman.sock", socketPath(0, false, true))
+	as.Equal("/run/user/1000/podman/podman.sock", socketPath(1000, true, true))
+	as.Equal("/rootfs/run/user/1000/podman/podman.sock", socketPath(1000, true, false))
+}
+
+func TestContainerNameToPodmanID(t *testing.T) {
+	assert.Equal(t, testID, ContainerNameToPodmanID("/machine.slice/libpod-"+testID+".scope"))
+	assert.Equal(t, testID, ContainerNameToPodmanID("/libpod_parent/libpod-"+testID))
+}
+
+func TestPingAny(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podman")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing.sock")
+	assert.Error(t, pingAny([]string{missing}))
+
+	socket := filepath.Join(dir, "podman.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+apiVersion+"/libpod/_ping" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+	assert.NoError(t, pingAny([]string{missing, socket}))
+}
+
+func TestServiceSockets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "podman")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer func(old string) { *ArgPodmanUserEndpoint = old }(*ArgPodmanUserEndpoint)
+	*ArgPodmanUserEndpoint = filepath.Join(dir, "%d", "podman.sock")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1000"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1000", "podman.sock"), nil, 0644))
+
+	assert.Equal(t, []string{*ArgPodmanEndpoint, filepath.Join(dir, "1000", "podman.sock")}, serviceSockets(true))
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Handler for podman containers.
+package podman
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Labels identifying the user running a container.
+	UserLabel = "podman.user"
+	UIDLabel  = "podman.uid"
+)
+
+type podmanContainerHandler struct {
+	machineInfoFactory info.MachineInfoFactory
+
+	// Absolute path to the cgroup hierarchies of this container.
+	// (e.g.: "cpu" -> "/sys/fs/cgroup/cpu/test")
+	cgroupPaths map[string]string
+
+	// Metadata associated with the container.
+	reference    info.ContainerReference
+	envs         map[string]string
+	labels       map[string]string
+	creationTime time.Time
+
+	// Image name used for this container.
+	image string
+	// ID of the container this container was restored from, if any.
+	restoredFrom string
+
+	ipAddress string
+
+	includedMetrics container.MetricSet
+
+	libcontainerHandler *containerlibcontainer.Handler
+}
+
+var _ container.ContainerHandler = &podmanContainerHandler{}
+
+func newPodmanContainerHandler(
+	client PodmanClient,
+	name string,
+	uid int,
+	rootless bool,
+	machineInfoFactory info.MachineInfoFactory,
+	cgroupSubsystems *containerlibcontainer.CgroupSubsystems,
+	inHostNamespace bool,
+	includedMetrics container.MetricSet,
+) (container.ContainerHandler, error) {
+	// Create the cgroup paths.
+	cgroupPaths := common.MakeCgroupPaths(cgroupSubsystems.MountPoints, name)
+
+	// Generate the equivalent cgroup manager for this container.
+	cgroupManager, err := containerlibcontainer.NewCgroupManager(name, cgroupPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ContainerNameToPodmanID(name)
+	cInfo, err := client.ContainerInfo(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect podman container %q: %v", id, err)
+	}
+
+	rootFs := "/"
+	if !inHostNamespace {
+		rootFs = "/rootfs"
+	}
+
+	aliases := []string{id}
+	if cInfo.Name != "" {
+		aliases = []string{strings.TrimPrefix(cInfo.Name, "/"), id}
+	}
+	containerReference := info.ContainerReference{
+		Id:        id,
+		Name:      name,
+		Aliases:   aliases,
+		Namespace: PodmanNamespace,
+	}
+
+	handler := &podmanContainerHandler{
+		machineInfoFactory:  machineInfoFactory,
+		cgroupPaths:         cgroupPaths,
+		reference:           containerReference,
+		envs:                make(map[string]string),
+		labels:              make(map[string]string, len(cInfo.Config.Labels)+2),
+		creationTime:        cInfo.Created,
+		image:               cInfo.ImageName,
+		ipAddress:           cInfo.NetworkSettings.IPAddress,
+		includedMetrics:     includedMetrics,
+		libcontainerHandler: containerlibcontainer.NewHandler(cgroupManager, rootFs, cInfo.State.Pid, includedMetrics),
+	}
+	if handler.image == "" {
+		handler.image = cInfo.Image
+	}
+	handler.restoredFrom = cInfo.Config.Annotations[common.RestoredFromAnnotation]
+	if handler.restoredFrom == "" && cInfo.State.Restored {
+		// Podman restores a checkpoint into a container with the same ID,
+		// unless it is imported under another name.
+		handler.restoredFrom = id
+	}
+	for k, v := range cInfo.Config.Labels {
+		handler.labels[k] = v
+	}
+	if rootless {
+		handler.labels[UIDLabel] = strconv.Itoa(uid)
+		if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+			handler.labels[UserLabel] = u.Username
+		}
+	}
+	for _, envVar := range cInfo.Config.Env {
+		if envVar != "" {
+			splits := strings.SplitN(envVar, "=", 2)
+			if len(splits) == 2 {
+				handler.envs[splits[0]] = splits[1]
+			}
+		}
+	}
+
+	return handler, nil
+}
+
+func (h *podmanContainerHandler) ContainerReference() (info.ContainerReference, error) {
+	return h.reference, nil
+}
+
+func (h *podmanContainerHandler) needNet() bool {
+	// Every podman container has its own network namespace, pods excepted
+	// which are not distinguished here.
+	return h.includedMetrics.Has(container.NetworkUsageMetrics)
+}
+
+func (h *podmanContainerHandler) GetSpec() (info.ContainerSpec, error) {
+	hasFilesystem := false
+	spec, err := common.GetSpec(h.cgroupPaths, h.machineInfoFactory, h.needNet(), hasFilesystem)
+	spec.Labels = h.labels
+	spec.Envs = h.envs
+	spec.Image = h.image
+	spec.RestoredFrom = h.restoredFrom
+	if !h.creationTime.IsZero() {
+		spec.CreationTime = h.creationTime
+	}
+
+	mounts, mountsErr := h.libcontainerHandler.GetMounts()
+	if mountsErr != nil {
+		klog.V(4).Infof("Unable to get mounts of container %q: %v", h.reference.Name, mountsErr)
+	}
+	spec.Mounts = mounts
+
+	return spec, err
+}
+
+func (h *podmanContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats, err := h.libcontainerHandler.GetStats()
+	if err != nil {
+		return stats, err
+	}
+	if !h.needNet() {
+		stats.Network = info.NetworkStats{}
+	}
+
+	if h.includedMetrics.Has(container.DiskIOMetrics) {
+		mi, err := h.machineInfoFactory.GetMachineInfo()
+		if err != nil {
+			return stats, err
+		}
+		common.AssignDeviceNamesToDiskStats((*common.MachineInfoNamer)(mi), &stats.DiskIo)
+	}
+	return stats, nil
+}
+
+func (h *podmanContainerHandler) ListContainers(listType container.ListType) ([]info.ContainerReference, error) {
+	// No-op for podman driver.
+	return []info.ContainerReference{}, nil
+}
+
+func (h *podmanContainerHandler) GetCgroupPath(resource string) (string, error) {
+	path, ok := h.cgroupPaths[resource]
+	if !ok {
+		return "", fmt.Errorf("could not find path for resource %q for container %q", resource, h.reference.Name)
+	}
+	return path, nil
+}
+
+func (h *podmanContainerHandler) GetContainerLabels() map[string]string {
+	return h.labels
+}
+
+func (h *podmanContainerHandler) GetContainerIPAddress() string {
+	return h.ipAddress
+}
+
+func (h *podmanContainerHandler) ListProcesses(listType container.ListType) ([]int, error) {
+	return h.libcontainerHandler.GetProcesses()
+}
+
+func (h *podmanContainerHandler) Exists() bool {
+	return common.CgroupExists(h.cgroupPaths)
+}
+
+func (h *podmanContainerHandler) Type() container.ContainerType {
+	return container.ContainerTypePodman
+}
+
+func (h *podmanContainerHandler) Start() {}
+
+func (h *podmanContainerHandler) Cleanup() {}
@@ -0,0 +1,76 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"fmt"
+	"testing"
+
+	containerlibcontainer "github.com/google/cadvisor/container/libcontainer"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+type podmanClientMock struct {
+	containers map[string]*ContainerInfo
+}
+
+func (c *podmanClientMock) Ping() error {
+	return nil
+}
+
+func (c *podmanClientMock) ContainerInfo(id string) (*ContainerInfo, error) {
+	cInfo, ok := c.containers[id]
+	if !ok {
+		return nil, fmt.Errorf("no such container %q", id)
+	}
+	return cInfo, nil
+}
+
+func TestHandler(t *testing.T) {
+	as := assert.New(t)
+
+	cInfo := &ContainerInfo{
+		ID:        testID,
+		Name:      "web",
+		ImageName: "docker.io/library/nginx:latest",
+	}
+	cInfo.State.Pid = 2389
+	cInfo.Config.Labels = map[string]string{"app": "web"}
+	cInfo.Config.Env = []string{"FOO=bar", "EMPTY="}
+	client := &podmanClientMock{containers: map[string]*ContainerInfo{testID: cInfo}}
+
+	name := "/user.slice/user-4242.slice/user@4242.service/user.slice/libpod-" + testID + ".scope"
+	handler, err := newPodmanContainerHandler(client, name, 4242, true, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.Nil(err)
+
+	ref, err := handler.ContainerReference()
+	as.Nil(err)
+	as.Equal(info.ContainerReference{
+		Id:        testID,
+		Name:      name,
+		Aliases:   []string{"web", testID},
+		Namespace: PodmanNamespace,
+	}, ref)
+	as.Equal("web", handler.GetContainerLabels()["app"])
+	as.Equal("4242", handler.GetContainerLabels()[UIDLabel])
+
+	h := handler.(*podmanContainerHandler)
+	as.Equal("docker.io/library/nginx:latest", h.image)
+	as.Equal(map[string]string{"FOO": "bar", "EMPTY": ""}, h.envs)
+
+	_, err = newPodmanContainerHandler(client, "/machine.slice/libpod-0000000000000000000000000000000000000000000000000000000000000000.scope", 0, false, nil, &containerlibcontainer.CgroupSubsystems{}, true, nil)
+	as.NotNil(err)
+}
@@ -31,6 +31,8 @@ import (
 
 var dockerOnly = flag.Bool("docker_only", false, "Only report docker containers in addition to root stats")
 var disableRootCgroupStats = flag.Bool("disable_root_cgroup_stats", false, "Disable collecting root Cgroup stats")
+var systemdUnits = flag.String("systemd_units", "", "comma-separated list of systemd units, e.g. nginx.service,sshd.service, to be monitored even when -docker_only is specified. Their stats are labeled with unit metadata. Shell patterns such as getty@*.service are accepted.")
+var kubernetesQosCgroups = flag.Bool("kubernetes_qos_cgroups", false, "Monitor the Kubernetes QoS tier and pod cgroups even when -docker_only is specified. Their stats are labeled with qos_class and pod_uid.")
 
 type rawFactory struct {
 	// Factory for machine information.
@@ -50,6 +52,9 @@ type rawFactory struct {
 
 	// List of raw container cgroup path prefix whitelist.
 	rawPrefixWhiteList []string
+
+	// Patterns of the systemd units monitored with unit metadata.
+	systemdUnits []string
 }
 
 func (f *rawFactory) String() string {
@@ -61,14 +66,34 @@ func (f *rawFactory) NewContainerHandler(name string, inHostNamespace bool) (con
 	if !inHostNamespace {
 		rootFs = "/rootfs"
 	}
-	return newRawContainerHandler(name, f.cgroupSubsystems, f.machineInfoFactory, f.fsInfo, f.watcher, rootFs, f.includedMetrics)
+	return newRawContainerHandler(name, f.cgroupSubsystems, f.machineInfoFactory, f.fsInfo, f.watcher, rootFs, f.includedMetrics, f.containerLabels(name))
+}
+
+// containerLabels returns the labels of the systemd units and, if
+// kubernetes_qos_cgroups flag is set, of the Kubernetes QoS tier and pod
+// cgroups.
+func (f *rawFactory) containerLabels(name string) map[string]string {
+	if matchesSystemdUnit(name, f.systemdUnits) {
+		return systemdUnitLabels(name)
+	}
+	if !*kubernetesQosCgroups {
+		return nil
+	}
+	labels, _ := kubepodsLabels(name)
+	return labels
 }
 
-// The raw factory can handle any container. If --docker_only is set to true, non-docker containers are ignored except for "/" and those whitelisted by raw_cgroup_prefix_whitelist flag.
+// The raw factory can handle any container. If --docker_only is set to true, non-docker containers are ignored except for "/", the units listed by systemd_units flag, the Kubernetes QoS tier and pod cgroups if kubernetes_qos_cgroups flag is set and those whitelisted by raw_cgroup_prefix_whitelist flag.
 func (f *rawFactory) CanHandleAndAccept(name string) (bool, bool, error) {
 	if name == "/" {
 		return true, true, nil
 	}
+	if matchesSystemdUnit(name, f.systemdUnits) {
+		return true, true, nil
+	}
+	if _, ok := kubepodsLabels(name); ok && *kubernetesQosCgroups {
+		return true, true, nil
+	}
 	if *dockerOnly && f.rawPrefixWhiteList[0] == "" {
 		return true, false, nil
 	}
@@ -106,7 +131,29 @@ func Register(machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, incl
 		watcher:            watcher,
 		includedMetrics:    includedMetrics,
 		rawPrefixWhiteList: rawPrefixWhiteList,
+		systemdUnits:       parseSystemdUnits(*systemdUnits),
 	}
-	container.RegisterContainerHandlerFactory(factory, []watch.ContainerWatchSource{watch.Raw})
+	container.RegisterContainerHandlerFactoryWithPriority(factory, container.RawFactoryPriority, []watch.ContainerWatchSource{watch.Raw})
+	container.RegisterContainerHandlerFactoryWithPriority(adHocFactory{factory}, container.RawFactoryPriority, []watch.ContainerWatchSource{watch.AdHoc})
 	return nil
 }
+
+// adHocFactory creates the handlers of the cgroups monitored on request and
+// of nested cgroups, which are accepted as long as they exist, whatever the
+// flags limiting the raw containers.
+type adHocFactory struct {
+	*rawFactory
+}
+
+func (f adHocFactory) String() string {
+	return "ad hoc"
+}
+
+func (f adHocFactory) CanHandleAndAccept(name string) (bool, bool, error) {
+	return true, common.CgroupExists(common.MakeCgroupPaths(f.cgroupSubsystems.MountPoints, name)), nil
+}
+
+// DebugInfo is empty since the cgroups are watched by the raw factory.
+func (f adHocFactory) DebugInfo() map[string][]string {
+	return map[string][]string{}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/machine"
+	"github.com/google/cadvisor/utils/ethtool"
 
 	"k8s.io/klog/v2"
 )
@@ -41,14 +42,28 @@ type rawContainerHandler struct {
 	externalMounts  []common.Mount
 	includedMetrics container.MetricSet
 
+	// Labels of the container, only set for systemd units.
+	labels map[string]string
+
 	libcontainerHandler *libcontainer.Handler
+
+	// Previous CPU frequency sample of the root container, used to derive
+	// effective frequencies.
+	lastCpuFreq *info.CpuFreqStats
+
+	// Energy counters of the host, only used by the root container.
+	energyMeter *machine.EnergyMeter
+
+	// Reconnect counters of the host's storage sessions, only used by the
+	// root container.
+	storageSessions *machine.StorageSessionMonitor
 }
 
 func isRootCgroup(name string) bool {
 	return name == "/"
 }
 
-func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSubsystems, machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, watcher *common.InotifyWatcher, rootFs string, includedMetrics container.MetricSet) (container.ContainerHandler, error) {
+func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSubsystems, machineInfoFactory info.MachineInfoFactory, fsInfo fs.FsInfo, watcher *common.InotifyWatcher, rootFs string, includedMetrics container.MetricSet, labels map[string]string) (container.ContainerHandler, error) {
 	cHints, err := common.GetContainerHintsFromFile(*common.ArgContainerHints)
 	if err != nil {
 		return nil, err
@@ -86,6 +101,7 @@ func newRawContainerHandler(name string, cgroupSubsystems *libcontainer.CgroupSu
 		fsInfo:              fsInfo,
 		externalMounts:      externalMounts,
 		includedMetrics:     includedMetrics,
+		labels:              labels,
 		libcontainerHandler: handler,
 	}, nil
 }
@@ -122,6 +138,7 @@ func (h *rawContainerHandler) GetSpec() (info.ContainerSpec, error) {
 	if err != nil {
 		return spec, err
 	}
+	spec.Labels = h.labels
 
 	if isRootCgroup(h.name) {
 		// Check physical network devices for root container.
@@ -163,6 +180,7 @@ func fsToFsStats(fs *fs.Fs) info.FsStats {
 	}
 	return info.FsStats{
 		Device:          fs.Device,
+		Mountpoint:      fs.Mountpoint,
 		Type:            fs.Type.String(),
 		Limit:           fs.Capacity,
 		Usage:           fs.Capacity - fs.Free,
@@ -240,9 +258,121 @@ func (h *rawContainerHandler) GetStats() (*info.ContainerStats, error) {
 		return stats, err
 	}
 
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.NetworkQueueMetrics) {
+		h.getNetworkQueueStats(stats)
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.KsmMetrics) {
+		ksm, err := machine.GetKsmStats()
+		if err != nil {
+			klog.V(4).Infof("Unable to get KSM stats: %v", err)
+		} else {
+			stats.Ksm = ksm
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.CpuStealMetrics) {
+		steal, guest, err := machine.GetStealAndGuestTime()
+		if err != nil {
+			klog.V(4).Infof("Unable to get steal and guest CPU time: %v", err)
+		} else {
+			stats.Cpu.Usage.Steal = steal
+			stats.Cpu.Usage.Guest = guest
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.CpuFrequencyMetrics) {
+		cpuFreq, err := machine.GetCpuFreqStats(h.lastCpuFreq)
+		if err != nil {
+			klog.V(4).Infof("Unable to get CPU frequency stats: %v", err)
+		} else {
+			stats.CpuFreq = cpuFreq
+			h.lastCpuFreq = cpuFreq
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.PowerMetrics) {
+		if h.energyMeter == nil {
+			h.energyMeter = machine.NewEnergyMeter()
+		}
+		energy, err := h.energyMeter.Read()
+		if err != nil {
+			klog.V(4).Infof("Unable to get energy stats: %v", err)
+		} else {
+			stats.Energy = energy
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.ThermalMetrics) {
+		thermal, err := machine.GetThermalStats()
+		if err != nil {
+			klog.V(4).Infof("Unable to get thermal stats: %v", err)
+		} else if len(thermal.Zones) > 0 || len(thermal.Fans) > 0 {
+			stats.Thermal = thermal
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.ThermalThrottleMetrics) {
+		throttle, err := machine.GetThermalThrottleStats()
+		if err != nil {
+			klog.V(4).Infof("Unable to get thermal throttling stats: %v", err)
+		} else {
+			stats.ThermalThrottle = throttle
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.MdRaidMetrics) {
+		arrays, err := machine.GetMdArrayStats()
+		if err != nil {
+			klog.V(4).Infof("Unable to get md array stats: %v", err)
+		} else {
+			stats.MdArrays = arrays
+		}
+	}
+
+	if isRootCgroup(h.name) && h.includedMetrics.Has(container.StorageSessionMetrics) {
+		if h.storageSessions == nil {
+			h.storageSessions = machine.NewStorageSessionMonitor()
+		}
+		sessions, err := h.storageSessions.Read()
+		if err != nil {
+			klog.V(4).Infof("Unable to get storage session stats: %v", err)
+		} else {
+			stats.StorageSessions = sessions
+		}
+	}
+
 	return stats, nil
 }
 
+// getNetworkQueueStats collects per-queue statistics of the physical network
+// devices. Devices whose driver does not report queue statistics are skipped.
+func (h *rawContainerHandler) getNetworkQueueStats(stats *info.ContainerStats) {
+	devices, err := h.GetRootNetworkDevices()
+	if err != nil {
+		klog.V(4).Infof("Unable to get network devices for queue stats: %v", err)
+		return
+	}
+	if len(devices) == 0 {
+		return
+	}
+	client, err := ethtool.NewClient()
+	if err != nil {
+		klog.V(4).Infof("Unable to collect network queue stats: %v", err)
+		return
+	}
+	defer client.Close()
+
+	for _, device := range devices {
+		queues, err := client.QueueStats(device.Name)
+		if err != nil {
+			klog.V(5).Infof("Unable to get queue stats of %q: %v", device.Name, err)
+			continue
+		}
+		stats.Network.Queues = append(stats.Network.Queues, queues...)
+	}
+}
+
 func (h *rawContainerHandler) GetCgroupPath(resource string) (string, error) {
 	path, ok := h.cgroupPaths[resource]
 	if !ok {
@@ -252,7 +382,10 @@ func (h *rawContainerHandler) GetCgroupPath(resource string) (string, error) {
 }
 
 func (h *rawContainerHandler) GetContainerLabels() map[string]string {
-	return map[string]string{}
+	if h.labels == nil {
+		return map[string]string{}
+	}
+	return h.labels
 }
 
 func (h *rawContainerHandler) GetContainerIPAddress() string {
@@ -288,12 +421,29 @@ func (n *fsNamer) DeviceName(major, minor uint64) (string, bool) {
 			return info.Device, true
 		}
 	}
+	if !n.loadMachineInfo() {
+		return "", false
+	}
+	return n.info.DeviceName(major, minor)
+}
+
+func (n *fsNamer) PhysicalDeviceNames(major, minor uint64) []string {
+	if !n.loadMachineInfo() {
+		return nil
+	}
+	if physicalNamer, ok := n.info.(common.PhysicalDeviceNamer); ok {
+		return physicalNamer.PhysicalDeviceNames(major, minor)
+	}
+	return nil
+}
+
+func (n *fsNamer) loadMachineInfo() bool {
 	if n.info == nil {
 		mi, err := n.factory.GetMachineInfo()
 		if err != nil {
-			return "", false
+			return false
 		}
 		n.info = (*common.MachineInfoNamer)(mi)
 	}
-	return n.info.DeviceName(major, minor)
+	return true
 }
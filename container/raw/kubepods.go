@@ -0,0 +1,97 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"strings"
+)
+
+const (
+	// Labels describing the Kubernetes QoS tier and pod cgroups.
+	QosClassLabel = "qos_class"
+	PodUIDLabel   = "pod_uid"
+)
+
+const (
+	qosGuaranteed = "guaranteed"
+	qosBurstable  = "burstable"
+	qosBestEffort = "besteffort"
+)
+
+// kubepodsLabels returns the labels of a cgroup created by the kubelet for a
+// QoS tier or a pod, with both the cgroupfs and the systemd cgroup driver, e.g.
+// "/kubepods/burstable/pod<uid>" or
+// "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice".
+// The root kubepods cgroup spans all tiers and is reported without a QoS class.
+// The second return value is false for any other cgroup, including the
+// containers within a pod.
+func kubepodsLabels(name string) (map[string]string, bool) {
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	root := -1
+	for i, part := range parts {
+		if part == "kubepods" || part == "kubepods.slice" {
+			root = i
+			break
+		}
+	}
+	if root < 0 {
+		return nil, false
+	}
+	rest := parts[root+1:]
+	if len(rest) == 0 {
+		return map[string]string{}, true
+	}
+
+	// Guaranteed pods live directly below the root, there is no tier cgroup.
+	qos := qosGuaranteed
+	switch rest[0] {
+	case qosBurstable, "kubepods-burstable.slice":
+		qos = qosBurstable
+		rest = rest[1:]
+	case qosBestEffort, "kubepods-besteffort.slice":
+		qos = qosBestEffort
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		return map[string]string{QosClassLabel: qos}, true
+	}
+	if len(rest) > 1 {
+		return nil, false
+	}
+
+	uid, ok := podUID(rest[0])
+	if !ok {
+		return nil, false
+	}
+	return map[string]string{QosClassLabel: qos, PodUIDLabel: uid}, true
+}
+
+// podUID extracts the pod UID from the name of a pod cgroup, e.g. "pod<uid>"
+// or "kubepods-burstable-pod<uid>.slice" where dashes in the UID are escaped
+// as underscores.
+func podUID(cgroup string) (string, bool) {
+	if strings.HasSuffix(cgroup, ".slice") {
+		i := strings.LastIndex(cgroup, "-pod")
+		if i < 0 {
+			return "", false
+		}
+		uid := strings.TrimSuffix(cgroup[i+len("-pod"):], ".slice")
+		return strings.Replace(uid, "_", "-", -1), uid != ""
+	}
+	if strings.HasPrefix(cgroup, "pod") && len(cgroup) > len("pod") {
+		return strings.TrimPrefix(cgroup, "pod"), true
+	}
+	return "", false
+}
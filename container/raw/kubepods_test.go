@@ -0,0 +1,86 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubepodsLabels(t *testing.T) {
+	const uid = "0f5a6b2c-1d3e-4f5a-8b7c-9d0e1f2a3b4c"
+	for name, expected := range map[string]map[string]string{
+		"/kubepods":                                 {},
+		"/kubepods/burstable":                       {QosClassLabel: "burstable"},
+		"/kubepods/besteffort":                      {QosClassLabel: "besteffort"},
+		"/kubepods/pod" + uid:                       {QosClassLabel: "guaranteed", PodUIDLabel: uid},
+		"/kubepods/burstable/pod" + uid:             {QosClassLabel: "burstable", PodUIDLabel: uid},
+		"/kubepods.slice":                           {},
+		"/kubepods.slice/kubepods-burstable.slice":  {QosClassLabel: "burstable"},
+		"/kubepods.slice/kubepods-besteffort.slice": {QosClassLabel: "besteffort"},
+		"/kubepods.slice/kubepods-pod0f5a6b2c_1d3e_4f5a_8b7c_9d0e1f2a3b4c.slice":                                      {QosClassLabel: "guaranteed", PodUIDLabel: uid},
+		"/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod0f5a6b2c_1d3e_4f5a_8b7c_9d0e1f2a3b4c.slice": {QosClassLabel: "besteffort", PodUIDLabel: uid},
+	} {
+		labels, ok := kubepodsLabels(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, labels, name)
+	}
+
+	for _, name := range []string{
+		"/",
+		"/system.slice/kubelet.service",
+		"/kubepods/burstable/pod" + uid + "/0123456789abcdef",
+		"/kubepods/burstable/unknown",
+		"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod.slice",
+	} {
+		_, ok := kubepodsLabels(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestCanHandleAndAcceptKubepods(t *testing.T) {
+	defer func(old bool) { *dockerOnly = old }(*dockerOnly)
+	defer func(old bool) { *kubernetesQosCgroups = old }(*kubernetesQosCgroups)
+	*dockerOnly = true
+
+	f := &rawFactory{
+		rawPrefixWhiteList: []string{""},
+	}
+	_, canAccept, err := f.CanHandleAndAccept("/kubepods/burstable")
+	assert.Nil(t, err)
+	assert.False(t, canAccept)
+
+	*kubernetesQosCgroups = true
+	_, canAccept, err = f.CanHandleAndAccept("/kubepods/burstable")
+	assert.Nil(t, err)
+	assert.True(t, canAccept)
+
+	_, canAccept, err = f.CanHandleAndAccept("/kubepods/burstable/pod1234/0123456789abcdef")
+	assert.Nil(t, err)
+	assert.False(t, canAccept)
+}
+
+func TestContainerLabelsKubepods(t *testing.T) {
+	defer func(old bool) { *kubernetesQosCgroups = old }(*kubernetesQosCgroups)
+	f := &rawFactory{}
+
+	*kubernetesQosCgroups = false
+	assert.Nil(t, f.containerLabels("/kubepods/burstable"))
+
+	*kubernetesQosCgroups = true
+	assert.Equal(t, map[string]string{QosClassLabel: "burstable"}, f.containerLabels("/kubepods/burstable"))
+	assert.Nil(t, f.containerLabels("/system.slice"))
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	// Labels describing systemd units monitored through --systemd_units.
+	SystemdUnitLabel         = "systemd.unit"
+	SystemdUnitTypeLabel     = "systemd.unit_type"
+	SystemdSliceLabel        = "systemd.slice"
+	SystemdUnitTemplateLabel = "systemd.unit_template"
+	SystemdUnitInstanceLabel = "systemd.unit_instance"
+)
+
+// parseSystemdUnits splits the value of --systemd_units into unit patterns.
+func parseSystemdUnits(value string) []string {
+	var units []string
+	for _, unit := range strings.Split(value, ",") {
+		if unit = strings.TrimSpace(unit); unit != "" {
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// matchesSystemdUnit reports whether the cgroup belongs to one of the units,
+// which may be shell patterns, e.g. "getty@*.service".
+func matchesSystemdUnit(name string, units []string) bool {
+	unit := path.Base(name)
+	for _, pattern := range units {
+		if matched, err := path.Match(pattern, unit); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// systemdUnitLabels returns the metadata of the unit owning the cgroup, as
+// far as it can be derived from the unit name and its position in the
+// hierarchy, e.g. "/system.slice/system-getty.slice/getty@tty1.service".
+func systemdUnitLabels(name string) map[string]string {
+	unit := path.Base(name)
+	labels := map[string]string{
+		SystemdUnitLabel: unit,
+	}
+	ext := path.Ext(unit)
+	if ext != "" {
+		labels[SystemdUnitTypeLabel] = strings.TrimPrefix(ext, ".")
+	}
+	if slice := path.Base(path.Dir(name)); strings.HasSuffix(slice, ".slice") {
+		labels[SystemdSliceLabel] = slice
+	}
+	if i := strings.Index(unit, "@"); i >= 0 {
+		labels[SystemdUnitTemplateLabel] = unit[:i+1] + ext
+		if instance := strings.TrimSuffix(unit[i+1:], ext); instance != "" {
+			labels[SystemdUnitInstanceLabel] = instance
+		}
+	}
+	return labels
+}
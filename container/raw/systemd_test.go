@@ -0,0 +1,76 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSystemdUnits(t *testing.T) {
+	assert.Nil(t, parseSystemdUnits(""))
+	assert.Equal(t, []string{"nginx.service", "getty@*.service"}, parseSystemdUnits("nginx.service, getty@*.service,"))
+}
+
+func TestMatchesSystemdUnit(t *testing.T) {
+	units := []string{"nginx.service", "getty@*.service"}
+	for name, expected := range map[string]bool{
+		"/system.slice/nginx.service":                          true,
+		"/system.slice/system-getty.slice/getty@tty1.service":  true,
+		"/system.slice/sshd.service":                           false,
+		"/system.slice/nginx.service/child":                    false,
+		"/system.slice/docker-0123456789abcdef.scope":          false,
+		"/system.slice/system-serial.slice/getty@ttyS0.socket": false,
+	} {
+		assert.Equal(t, expected, matchesSystemdUnit(name, units), name)
+	}
+	assert.False(t, matchesSystemdUnit("/system.slice/nginx.service", nil))
+}
+
+func TestSystemdUnitLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		SystemdUnitLabel:     "nginx.service",
+		SystemdUnitTypeLabel: "service",
+		SystemdSliceLabel:    "system.slice",
+	}, systemdUnitLabels("/system.slice/nginx.service"))
+
+	assert.Equal(t, map[string]string{
+		SystemdUnitLabel:         "getty@tty1.service",
+		SystemdUnitTypeLabel:     "service",
+		SystemdSliceLabel:        "system-getty.slice",
+		SystemdUnitTemplateLabel: "getty@.service",
+		SystemdUnitInstanceLabel: "tty1",
+	}, systemdUnitLabels("/system.slice/system-getty.slice/getty@tty1.service"))
+}
+
+func TestCanHandleAndAcceptSystemdUnits(t *testing.T) {
+	defer func(old bool) { *dockerOnly = old }(*dockerOnly)
+	*dockerOnly = true
+
+	f := &rawFactory{
+		rawPrefixWhiteList: []string{""},
+		systemdUnits:       []string{"nginx.service"},
+	}
+	canHandle, canAccept, err := f.CanHandleAndAccept("/system.slice/nginx.service")
+	assert.Nil(t, err)
+	assert.True(t, canHandle)
+	assert.True(t, canAccept)
+
+	canHandle, canAccept, err = f.CanHandleAndAccept("/system.slice/sshd.service")
+	assert.Nil(t, err)
+	assert.True(t, canHandle)
+	assert.False(t, canAccept)
+}
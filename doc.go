@@ -12,4 +12,18 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
+// Package cadvisor runs cAdvisor as a library, monitoring the containers of
+// the machine from within another program:
+//
+//	c, err := cadvisor.New(ctx,
+//		cadvisor.WithIntervals(cadvisor.Intervals{Housekeeping: 10 * time.Second}),
+//		cadvisor.WithCollectors(container.CpuUsageMetrics, container.MemoryUsageMetrics))
+//	if err != nil {
+//		return err
+//	}
+//	infos, err := c.GetContainerInfoV2("/", v2.RequestOptions{Recursive: true, Count: 1})
+//
+// The flags of the cadvisor command are not used by the library. Some
+// packages of container runtimes and storage drivers still register flags
+// when they are imported.
 package cadvisor
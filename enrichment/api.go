@@ -0,0 +1,41 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import "github.com/golang/protobuf/proto"
+
+// Messages of the cadvisor.enrichment.v1.Enricher service, see api.proto.
+
+// EnrichRequest describes a container to enrich.
+type EnrichRequest struct {
+	Name      string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace string            `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Aliases   []string          `protobuf:"bytes,3,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	Labels    map[string]string `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Image     string            `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+}
+
+func (m *EnrichRequest) Reset()         { *m = EnrichRequest{} }
+func (m *EnrichRequest) String() string { return proto.CompactTextString(m) }
+func (*EnrichRequest) ProtoMessage()    {}
+
+// EnrichResponse holds the labels to attach to a container.
+type EnrichResponse struct {
+	Labels map[string]string `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *EnrichResponse) Reset()         { *m = EnrichResponse{} }
+func (m *EnrichResponse) String() string { return proto.CompactTextString(m) }
+func (*EnrichResponse) ProtoMessage()    {}
@@ -0,0 +1,101 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enrichment lets plugins attach extra labels to discovered containers.
+package enrichment
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Enricher attaches extra labels to containers, e.g. the team or cost center
+// owning them, before their metrics are exported.
+type Enricher interface {
+	// Name identifies the enricher in logs and errors.
+	Name() string
+	// Labels returns the labels to attach to the container described by ref and spec.
+	Labels(ref info.ContainerReference, spec info.ContainerSpec) (map[string]string, error)
+}
+
+var (
+	enrichersLock sync.Mutex
+	enrichers     []Enricher
+)
+
+// RegisterEnricher registers a compiled-in enricher. It is meant to be called
+// from the init function of the package implementing the enricher.
+func RegisterEnricher(e Enricher) {
+	enrichersLock.Lock()
+	defer enrichersLock.Unlock()
+	enrichers = append(enrichers, e)
+}
+
+// Enrichers returns the registered enrichers followed by the sidecar enricher
+// when one is configured.
+func Enrichers() ([]Enricher, error) {
+	enrichersLock.Lock()
+	out := append([]Enricher{}, enrichers...)
+	enrichersLock.Unlock()
+
+	if *sidecarEndpoint != "" {
+		sidecar, err := NewSidecarEnricher(*sidecarEndpoint)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, sidecar)
+	}
+	return out, nil
+}
+
+// Labels collects the labels of all enrichers, later enrichers overriding
+// earlier ones. Labels of enrichers that succeeded are returned even when
+// others failed.
+func Labels(enrichers []Enricher, ref info.ContainerReference, spec info.ContainerSpec) (map[string]string, error) {
+	labels := map[string]string{}
+	var errs []string
+	for _, e := range enrichers {
+		l, err := e.Labels(ref, spec)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", e.Name(), err))
+			continue
+		}
+		for k, v := range l {
+			labels[k] = v
+		}
+	}
+	if len(errs) > 0 {
+		return labels, fmt.Errorf("failed to enrich container %q: %s", ref.Name, strings.Join(errs, "; "))
+	}
+	return labels, nil
+}
+
+// MergeLabels adds the enriched labels to the labels reported by the container
+// runtime. Labels set by the runtime are never overridden.
+func MergeLabels(labels, enriched map[string]string) map[string]string {
+	if len(enriched) == 0 {
+		return labels
+	}
+	out := make(map[string]string, len(labels)+len(enriched))
+	for k, v := range enriched {
+		out[k] = v
+	}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import (
+	"fmt"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticEnricher struct {
+	name   string
+	labels map[string]string
+	err    error
+}
+
+func (e *staticEnricher) Name() string { return e.name }
+
+func (e *staticEnricher) Labels(ref info.ContainerReference, spec info.ContainerSpec) (map[string]string, error) {
+	return e.labels, e.err
+}
+
+func TestLabels(t *testing.T) {
+	enrichers := []Enricher{
+		&staticEnricher{name: "inventory", labels: map[string]string{"team": "storage", "cost_center": "1234"}},
+		&staticEnricher{name: "broken", err: fmt.Errorf("unavailable")},
+		&staticEnricher{name: "override", labels: map[string]string{"team": "runtime"}},
+	}
+
+	labels, err := Labels(enrichers, info.ContainerReference{Name: "/docker/abc"}, info.ContainerSpec{})
+	assert.EqualError(t, err, `failed to enrich container "/docker/abc": broken: unavailable`)
+	assert.Equal(t, map[string]string{"team": "runtime", "cost_center": "1234"}, labels)
+
+	labels, err = Labels(nil, info.ContainerReference{}, info.ContainerSpec{})
+	assert.Nil(t, err)
+	assert.Empty(t, labels)
+}
+
+func TestMergeLabels(t *testing.T) {
+	runtime := map[string]string{"app": "web", "team": "runtime"}
+
+	assert.Equal(t, runtime, MergeLabels(runtime, nil))
+	assert.Equal(t,
+		map[string]string{"app": "web", "team": "runtime", "cost_center": "1234"},
+		MergeLabels(runtime, map[string]string{"team": "storage", "cost_center": "1234"}))
+	assert.Equal(t, map[string]string{"team": "storage"}, MergeLabels(nil, map[string]string{"team": "storage"}))
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"google.golang.org/grpc"
+)
+
+var (
+	sidecarEndpoint = flag.String("enrichment_sidecar", "", "unix socket of a gRPC sidecar implementing the cadvisor.enrichment.v1.Enricher service, used to attach extra labels to containers")
+	sidecarTimeout  = flag.Duration("enrichment_sidecar_timeout", 2*time.Second, "timeout of requests to the enrichment sidecar")
+)
+
+const enrichMethod = "/cadvisor.enrichment.v1.Enricher/Enrich"
+
+type sidecarEnricher struct {
+	endpoint string
+	conn     *grpc.ClientConn
+}
+
+// NewSidecarEnricher returns an enricher asking the gRPC sidecar listening on
+// the given unix socket for labels. The connection is established lazily, so
+// the sidecar may start after cAdvisor.
+func NewSidecarEnricher(endpoint string) (Enricher, error) {
+	endpoint = strings.TrimPrefix(endpoint, "unix://")
+	conn, err := grpc.Dial(endpoint,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to enrichment sidecar %q: %v", endpoint, err)
+	}
+	return &sidecarEnricher{endpoint: endpoint, conn: conn}, nil
+}
+
+func (s *sidecarEnricher) Name() string {
+	return "sidecar " + s.endpoint
+}
+
+func (s *sidecarEnricher) Labels(ref info.ContainerReference, spec info.ContainerSpec) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *sidecarTimeout)
+	defer cancel()
+
+	req := &EnrichRequest{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Aliases:   ref.Aliases,
+		Labels:    spec.Labels,
+		Image:     spec.Image,
+	}
+	resp := &EnrichResponse{}
+	if err := s.conn.Invoke(ctx, enrichMethod, req, resp); err != nil {
+		return nil, err
+	}
+	return resp.Labels, nil
+}
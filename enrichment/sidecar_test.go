@@ -0,0 +1,88 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrichment
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type enricherServer interface {
+	Enrich(context.Context, *EnrichRequest) (*EnrichResponse, error)
+}
+
+type testSidecar struct {
+	requests []*EnrichRequest
+}
+
+func (s *testSidecar) Enrich(ctx context.Context, req *EnrichRequest) (*EnrichResponse, error) {
+	s.requests = append(s.requests, req)
+	return &EnrichResponse{Labels: map[string]string{"team": req.Labels["app"] + "-team"}}, nil
+}
+
+var testServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cadvisor.enrichment.v1.Enricher",
+	HandlerType: (*enricherServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Enrich",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := &EnrichRequest{}
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			return srv.(enricherServer).Enrich(ctx, req)
+		},
+	}},
+}
+
+func TestSidecarEnricher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enrichment")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "enricher.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	sidecar := &testSidecar{}
+	server.RegisterService(&testServiceDesc, sidecar)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	enricher, err := NewSidecarEnricher("unix://" + socket)
+	require.NoError(t, err)
+
+	labels, err := enricher.Labels(
+		info.ContainerReference{Name: "/docker/abc", Namespace: "docker", Aliases: []string{"web", "abc"}},
+		info.ContainerSpec{Labels: map[string]string{"app": "web"}, Image: "nginx"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "web-team"}, labels)
+
+	require.Len(t, sidecar.requests, 1)
+	assert.Equal(t, "/docker/abc", sidecar.requests[0].Name)
+	assert.Equal(t, "docker", sidecar.requests[0].Namespace)
+	assert.Equal(t, []string{"web", "abc"}, sidecar.requests[0].Aliases)
+	assert.Equal(t, "nginx", sidecar.requests[0].Image)
+}
@@ -103,6 +103,16 @@ type events struct {
 	lastID int
 	// Event storage policy.
 	storagePolicy StoragePolicy
+	// Persistent store of the events, nil if events are kept in memory only.
+	store Store
+}
+
+// Store persists events so that they survive restarts.
+type Store interface {
+	// AddEvent persists event.
+	AddEvent(event *info.Event) error
+	// RestoreEvents calls f with the persisted events, oldest first.
+	RestoreEvents(f func(event *info.Event)) error
 }
 
 // initialized by a call to WatchEvents(), a watch struct will then be added
@@ -156,6 +166,28 @@ func NewEventManager(storagePolicy StoragePolicy) EventManager {
 	}
 }
 
+// NewPersistentEventManager returns an EventManager writing the events added
+// to it to store, and holding the events restored from store. The storage
+// policy applies to the restored events too.
+func NewPersistentEventManager(storagePolicy StoragePolicy, store Store) (EventManager, error) {
+	e := &events{
+		eventStore:    make(map[info.EventType]*utils.TimedStore),
+		watchers:      make(map[int]*watch),
+		storagePolicy: storagePolicy,
+		store:         store,
+	}
+	restored := 0
+	err := store.RestoreEvents(func(event *info.Event) {
+		e.updateEventStore(event)
+		restored++
+	})
+	if err != nil {
+		return nil, err
+	}
+	klog.V(1).Infof("Restored %d events", restored)
+	return e, nil
+}
+
 // returns a pointer to an initialized Request object
 func NewRequest() *Request {
 	return &Request{
@@ -316,6 +348,11 @@ func (e *events) findValidWatchers(event *info.Event) []*watch {
 // held by the manager if it satisfies the request keys of the channels
 func (e *events) AddEvent(event *info.Event) error {
 	e.updateEventStore(event)
+	if e.store != nil {
+		if err := e.store.AddEvent(event); err != nil {
+			klog.Warningf("Failed to persist event %v: %v", event, err)
+		}
+	}
 	e.watcherLock.RLock()
 	defer e.watcherLock.RUnlock()
 	watchesToSend := e.findValidWatchers(event)
@@ -196,3 +196,42 @@ func TestGetEventsForNoTypeRequested(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, receivedEvents, 0)
 }
+
+type fakeStore struct {
+	events []*info.Event
+}
+
+func (s *fakeStore) AddEvent(event *info.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeStore) RestoreEvents(f func(event *info.Event)) error {
+	for _, event := range s.events {
+		f(event)
+	}
+	return nil
+}
+
+func TestPersistentEventManagerRestoresEvents(t *testing.T) {
+	store := &fakeStore{}
+	fakeEvent := makeEvent(time.Now().Add(-time.Hour), "/")
+	fakeEvent2 := makeEvent(time.Now(), "/docker")
+
+	manager, err := NewPersistentEventManager(DefaultStoragePolicy(), store)
+	assert.NoError(t, err)
+	assert.NoError(t, manager.AddEvent(fakeEvent))
+	assert.NoError(t, manager.AddEvent(fakeEvent2))
+	assert.Equal(t, []*info.Event{fakeEvent, fakeEvent2}, store.events)
+
+	// A restarted manager answers with the persisted events.
+	restarted, err := NewPersistentEventManager(DefaultStoragePolicy(), store)
+	assert.NoError(t, err)
+	events, err := restarted.GetEvents(&Request{
+		EventType:         map[info.EventType]bool{info.EventOom: true},
+		MaxEventsReturned: -1,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*info.Event{fakeEvent, fakeEvent2}, events)
+	assert.Len(t, store.events, 2)
+}
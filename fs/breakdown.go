@@ -0,0 +1,175 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package fs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DirUsage is the usage of a directory and of its subdirectories.
+type DirUsage struct {
+	// Path relative to the walked directory, "" for the directory itself.
+	Path     string
+	Usage    UsageInfo
+	Children []DirUsage
+}
+
+// BreakdownLimits bound the cost of a directory usage breakdown.
+type BreakdownLimits struct {
+	// Maximum number of files visited, the usage is incomplete once reached.
+	MaxFiles int
+	// Maximum number of files visited per second.
+	FilesPerSecond int
+}
+
+var errBreakdownLimitReached = errors.New("file limit reached")
+
+// GetDirUsageBreakdown returns the usage of dir broken down by its
+// subdirectories up to depth levels below it. The walk stays on the device of
+// dir and is throttled and bounded by limits, the returned bool is true if
+// the limit on files was reached and the usage is incomplete.
+func GetDirUsageBreakdown(dir string, depth int, limits BreakdownLimits) (DirUsage, bool, error) {
+	if dir == "" {
+		return DirUsage{}, false, fmt.Errorf("invalid directory")
+	}
+	claimToken()
+	defer releaseToken()
+
+	rootInfo, err := os.Stat(dir)
+	if err != nil {
+		return DirUsage{}, false, fmt.Errorf("could not stat %q to get usage breakdown: %v", dir, err)
+	}
+	rootStat, ok := rootInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return DirUsage{}, false, fmt.Errorf("unsupported fileinfo for getting usage breakdown of %q", dir)
+	}
+
+	usages := map[string]*DirUsage{"": {}}
+	dedupedInodes := make(map[uint64]struct{})
+	files := 0
+	batchStart := time.Now()
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			// expected if files appear/vanish
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to get usage of part of dir %s: %s", dir, err)
+		}
+		s, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("unsupported fileinfo; could not convert to stat_t")
+		}
+		if s.Dev != rootStat.Dev {
+			// don't descend into directories on other devices
+			return filepath.SkipDir
+		}
+
+		files++
+		if limits.MaxFiles > 0 && files > limits.MaxFiles {
+			return errBreakdownLimitReached
+		}
+		if limits.FilesPerSecond > 0 && files%limits.FilesPerSecond == 0 {
+			time.Sleep(time.Until(batchStart.Add(time.Second)))
+			batchStart = time.Now()
+		}
+
+		if s.Nlink > 1 && !info.IsDir() {
+			if _, ok := dedupedInodes[s.Ino]; ok {
+				return nil
+			}
+			// Dedupe things that could be hardlinks
+			dedupedInodes[s.Ino] = struct{}{}
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		addUsage(usages, relPath, info.IsDir(), depth, UsageInfo{Bytes: uint64(s.Blocks) * statBlockSize, Inodes: 1})
+		return nil
+	})
+	truncated := err == errBreakdownLimitReached
+	if err != nil && !truncated {
+		return DirUsage{}, false, err
+	}
+	return buildDirUsageTree(usages), truncated, nil
+}
+
+// addUsage adds the usage of the file at relPath to all its ancestors up to
+// depth levels below the root, and to the file itself if it is such a
+// directory.
+func addUsage(usages map[string]*DirUsage, relPath string, isDir bool, depth int, usage UsageInfo) {
+	var parts []string
+	if relPath != "." {
+		parts = strings.Split(relPath, string(filepath.Separator))
+	}
+	if !isDir && len(parts) > 0 {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	for i := 0; i <= len(parts); i++ {
+		key := strings.Join(parts[:i], "/")
+		dirUsage, ok := usages[key]
+		if !ok {
+			dirUsage = &DirUsage{Path: key}
+			usages[key] = dirUsage
+		}
+		dirUsage.Usage.Bytes += usage.Bytes
+		dirUsage.Usage.Inodes += usage.Inodes
+	}
+}
+
+// buildDirUsageTree returns the usage of the root directory with the
+// subdirectories of every directory ordered by decreasing size.
+func buildDirUsageTree(usages map[string]*DirUsage) DirUsage {
+	children := make(map[string][]string, len(usages))
+	for path := range usages {
+		if path == "" {
+			continue
+		}
+		parent := ""
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			parent = path[:i]
+		}
+		children[parent] = append(children[parent], path)
+	}
+
+	var build func(path string) DirUsage
+	build = func(path string) DirUsage {
+		tree := *usages[path]
+		for _, child := range children[path] {
+			tree.Children = append(tree.Children, build(child))
+		}
+		sort.Slice(tree.Children, func(i, j int) bool {
+			if tree.Children[i].Usage.Bytes != tree.Children[j].Usage.Bytes {
+				return tree.Children[i].Usage.Bytes > tree.Children[j].Usage.Bytes
+			}
+			return tree.Children[i].Path < tree.Children[j].Path
+		})
+		return tree
+	}
+	return build("")
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build linux
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDirUsageBreakdown(t *testing.T) {
+	as := assert.New(t)
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// var/log/app holds 3 files, etc 1 file and the root 1 file.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "var", "log", "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "etc"), 0755))
+	for _, file := range []string{"var/log/app/a", "var/log/app/b", "var/log/app/c", "etc/hosts", "root"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), make([]byte, 8192), 0644))
+	}
+
+	usage, truncated, err := GetDirUsageBreakdown(dir, 2, BreakdownLimits{})
+	as.NoError(err)
+	as.False(truncated)
+	as.Equal("", usage.Path)
+	as.Equal(uint64(10), usage.Usage.Inodes)
+	as.Len(usage.Children, 2)
+	as.Equal("var", usage.Children[0].Path)
+	as.Equal(uint64(6), usage.Children[0].Usage.Inodes)
+	as.Equal("etc", usage.Children[1].Path)
+	as.Equal(uint64(2), usage.Children[1].Usage.Inodes)
+	// Directories deeper than depth are accounted to their ancestors.
+	as.Len(usage.Children[0].Children, 1)
+	as.Equal("var/log", usage.Children[0].Children[0].Path)
+	as.Equal(uint64(5), usage.Children[0].Children[0].Usage.Inodes)
+	as.Empty(usage.Children[0].Children[0].Children)
+	as.True(usage.Usage.Bytes >= usage.Children[0].Usage.Bytes+usage.Children[1].Usage.Bytes)
+}
+
+func TestGetDirUsageBreakdownStopsAtMaxFiles(t *testing.T) {
+	as := assert.New(t)
+	dir, err := ioutil.TempDir(os.TempDir(), "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for i := 0; i < 10; i++ {
+		_, err := ioutil.TempFile(dir, "")
+		require.NoError(t, err)
+	}
+
+	usage, truncated, err := GetDirUsageBreakdown(dir, 1, BreakdownLimits{MaxFiles: 5})
+	as.NoError(err)
+	as.True(truncated)
+	as.Equal(uint64(5), usage.Usage.Inodes)
+}
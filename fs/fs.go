@@ -44,6 +44,9 @@ const (
 	LabelCrioImages          = "crio-images"
 	DriverStatusPoolName     = "Pool Name"
 	DriverStatusDataLoopFile = "Data loop file"
+	DriverStatusDriverType   = "driver-type"
+
+	containerdSnapshotterDriverType = "io.containerd.snapshotter.v1"
 )
 
 const (
@@ -112,7 +115,7 @@ func NewFsInfo(context Context) (FsInfo, error) {
 	// Avoid devicemapper container mounts - these are tracked by the ThinPoolWatcher
 	excluded := []string{fmt.Sprintf("%s/devicemapper/mnt", context.Docker.Root)}
 	fsInfo := &RealFsInfo{
-		partitions:         processMounts(mounts, excluded),
+		partitions:         addExtraMounts(processMounts(mounts, excluded), mounts, context.ExtraMounts),
 		labels:             make(map[string]string),
 		mounts:             make(map[string]mount.Info),
 		dmsetup:            devicemapper.NewDmsetupClient(),
@@ -134,6 +137,43 @@ func NewFsInfo(context Context) (FsInfo, error) {
 	return fsInfo, nil
 }
 
+// addExtraMounts adds the partitions mounted at the extra mount points,
+// whatever their filesystem type. Mount points of partitions which are
+// already tracked, e.g. bind mounts of a tracked partition, are skipped.
+func addExtraMounts(partitions map[string]partition, mounts []*mount.Info, extraMounts []string) map[string]partition {
+	for _, mountpoint := range extraMounts {
+		mountpoint = filepath.Clean(mountpoint)
+		var mnt *mount.Info
+		for _, m := range mounts {
+			// The last mount at a mount point hides the others.
+			if m.Mountpoint == mountpoint {
+				mnt = m
+			}
+		}
+		if mnt == nil {
+			klog.Warningf("Extra filesystem mount point %q is not mounted", mountpoint)
+			continue
+		}
+
+		source := mnt.Source
+		if existing, ok := partitions[source]; ok {
+			if existing.major == uint(mnt.Major) && existing.minor == uint(mnt.Minor) {
+				klog.V(2).Infof("Extra filesystem mount point %q is already tracked at %q", mountpoint, existing.mountpoint)
+				continue
+			}
+			// Sources of pseudo filesystems, e.g. fuse, are not unique.
+			source = mountpoint
+		}
+		partitions[source] = partition{
+			fsType:     mnt.FSType,
+			mountpoint: mnt.Mountpoint,
+			major:      uint(mnt.Major),
+			minor:      uint(mnt.Minor),
+		}
+	}
+	return partitions
+}
+
 // getFsUUIDToDeviceNameMap creates the filesystem uuid to device name map
 // using the information in /dev/disk/by-uuid. If the directory does not exist,
 // this function will return an empty map.
@@ -325,6 +365,15 @@ func getDockerImagePaths(context Context) map[string]struct{} {
 		dockerImagePaths[dockerRoot] = struct{}{}
 		dockerRoot = filepath.Dir(dockerRoot)
 	}
+
+	// With the containerd image store, images live in the snapshotter directory.
+	if context.Docker.DriverStatus[DriverStatusDriverType] == containerdSnapshotterDriverType && context.Docker.ContainerdRoot != "" {
+		snapshotterRoot := path.Join(context.Docker.ContainerdRoot, containerdSnapshotterDriverType+"."+context.Docker.Driver)
+		for snapshotterRoot != "/" && snapshotterRoot != "." {
+			dockerImagePaths[snapshotterRoot] = struct{}{}
+			snapshotterRoot = filepath.Dir(snapshotterRoot)
+		}
+	}
 	return dockerImagePaths
 }
 
@@ -419,6 +468,7 @@ func (i *RealFsInfo) GetFsInfoForPath(mountSet map[string]struct{}) ([]Fs, error
 				klog.V(4).Infof("Stat fs failed. Error: %v", err)
 			} else {
 				deviceSet[device] = struct{}{}
+				fs.Mountpoint = partition.mountpoint
 				fs.DeviceInfo = DeviceInfo{
 					Device: device,
 					Major:  uint(partition.major),
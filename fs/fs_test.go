@@ -484,6 +484,24 @@ func TestAddDockerImagesLabel(t *testing.T) {
 			},
 			expectedDockerDevice: "/dev/sdb2",
 		},
+		{
+			name:         "containerd image store on dedicated partition",
+			driver:       "overlayfs",
+			driverStatus: map[string]string{"driver-type": "io.containerd.snapshotter.v1"},
+			mounts: []*mount.Info{
+				{
+					Source:     "/dev/sda1",
+					Mountpoint: "/",
+					FSType:     "ext4",
+				},
+				{
+					Source:     "/dev/sdb1",
+					Mountpoint: "/var/lib/containerd",
+					FSType:     "ext4",
+				},
+			},
+			expectedDockerDevice: "/dev/sdb1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -497,9 +515,10 @@ func TestAddDockerImagesLabel(t *testing.T) {
 
 		context := Context{
 			Docker: DockerContext{
-				Root:         "/var/lib/docker",
-				Driver:       tt.driver,
-				DriverStatus: tt.driverStatus,
+				Root:           "/var/lib/docker",
+				Driver:         tt.driver,
+				DriverStatus:   tt.driverStatus,
+				ContainerdRoot: "/var/lib/containerd",
 			},
 		}
 
@@ -588,3 +607,21 @@ func TestProcessMounts(t *testing.T) {
 		}
 	}
 }
+
+func TestAddExtraMounts(t *testing.T) {
+	mounts := []*mount.Info{
+		{Root: "/", Mountpoint: "/", Source: "/dev/sda1", FSType: "xfs", Major: 253, Minor: 0},
+		{Root: "/etcd", Mountpoint: "/var/lib/etcd", Source: "/dev/sda1", FSType: "xfs", Major: 253, Minor: 0},
+		{Root: "/", Mountpoint: "/data", Source: "/dev/sdb1", FSType: "f2fs", Major: 8, Minor: 17},
+		{Root: "/", Mountpoint: "/mnt/a", Source: "sshfs", FSType: "fuse.sshfs", Major: 0, Minor: 50},
+		{Root: "/", Mountpoint: "/mnt/b", Source: "sshfs", FSType: "fuse.sshfs", Major: 0, Minor: 51},
+	}
+	partitions := processMounts(mounts, nil)
+	actual := addExtraMounts(partitions, mounts, []string{"/var/lib/etcd", "/data/", "/mnt/a", "/mnt/b", "/missing"})
+	assert.Equal(t, map[string]partition{
+		"/dev/sda1": {fsType: "xfs", mountpoint: "/", major: 253, minor: 0},
+		"/dev/sdb1": {fsType: "f2fs", mountpoint: "/data", major: 8, minor: 17},
+		"sshfs":     {fsType: "fuse.sshfs", mountpoint: "/mnt/a", major: 0, minor: 50},
+		"/mnt/b":    {fsType: "fuse.sshfs", mountpoint: "/mnt/b", major: 0, minor: 51},
+	}, actual)
+}
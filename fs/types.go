@@ -22,12 +22,17 @@ type Context struct {
 	// docker root directory.
 	Docker DockerContext
 	Crio   CrioContext
+	// Mount points of the host tracked in addition to the partitions of the
+	// supported filesystem types, e.g. /var/lib/etcd.
+	ExtraMounts []string
 }
 
 type DockerContext struct {
 	Root         string
 	Driver       string
 	DriverStatus map[string]string
+	// containerd root directory, used when docker runs with the containerd image store.
+	ContainerdRoot string
 }
 
 type CrioContext struct {
@@ -54,6 +59,7 @@ const (
 
 type Fs struct {
 	DeviceInfo
+	Mountpoint string
 	Type       FsType
 	Capacity   uint64
 	Free       uint64
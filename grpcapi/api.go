@@ -0,0 +1,242 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcapi
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// Messages of the cadvisor.v1.Cadvisor service, see api.proto for their
+// documentation.
+
+type MachineInfoRequest struct {
+}
+
+func (m *MachineInfoRequest) Reset()         { *m = MachineInfoRequest{} }
+func (m *MachineInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*MachineInfoRequest) ProtoMessage()    {}
+
+type MachineInfo struct {
+	Timestamp        *timestamp.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	NumCores         int32                `protobuf:"varint,2,opt,name=num_cores,proto3" json:"num_cores,omitempty"`
+	NumPhysicalCores int32                `protobuf:"varint,3,opt,name=num_physical_cores,proto3" json:"num_physical_cores,omitempty"`
+	NumSockets       int32                `protobuf:"varint,4,opt,name=num_sockets,proto3" json:"num_sockets,omitempty"`
+	CpuFrequencyKhz  uint64               `protobuf:"varint,5,opt,name=cpu_frequency_khz,proto3" json:"cpu_frequency_khz,omitempty"`
+	MemoryCapacity   uint64               `protobuf:"varint,6,opt,name=memory_capacity,proto3" json:"memory_capacity,omitempty"`
+	MachineID        string               `protobuf:"bytes,7,opt,name=machine_id,proto3" json:"machine_id,omitempty"`
+	SystemUUID       string               `protobuf:"bytes,8,opt,name=system_uuid,proto3" json:"system_uuid,omitempty"`
+	BootID           string               `protobuf:"bytes,9,opt,name=boot_id,proto3" json:"boot_id,omitempty"`
+	Filesystems      []*Filesystem        `protobuf:"bytes,10,rep,name=filesystems,proto3" json:"filesystems,omitempty"`
+	NetworkDevices   []*NetworkDevice     `protobuf:"bytes,11,rep,name=network_devices,proto3" json:"network_devices,omitempty"`
+}
+
+func (m *MachineInfo) Reset()         { *m = MachineInfo{} }
+func (m *MachineInfo) String() string { return proto.CompactTextString(m) }
+func (*MachineInfo) ProtoMessage()    {}
+
+type Filesystem struct {
+	Device   string `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Type     string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Capacity uint64 `protobuf:"varint,3,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Inodes   uint64 `protobuf:"varint,4,opt,name=inodes,proto3" json:"inodes,omitempty"`
+}
+
+func (m *Filesystem) Reset()         { *m = Filesystem{} }
+func (m *Filesystem) String() string { return proto.CompactTextString(m) }
+func (*Filesystem) ProtoMessage()    {}
+
+type NetworkDevice struct {
+	Name       string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	MacAddress string `protobuf:"bytes,2,opt,name=mac_address,proto3" json:"mac_address,omitempty"`
+	Speed      int64  `protobuf:"varint,3,opt,name=speed,proto3" json:"speed,omitempty"`
+	Mtu        int64  `protobuf:"varint,4,opt,name=mtu,proto3" json:"mtu,omitempty"`
+}
+
+func (m *NetworkDevice) Reset()         { *m = NetworkDevice{} }
+func (m *NetworkDevice) String() string { return proto.CompactTextString(m) }
+func (*NetworkDevice) ProtoMessage()    {}
+
+type ContainerInfoRequest struct {
+	Name      string             `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IdType    string             `protobuf:"bytes,2,opt,name=id_type,proto3" json:"id_type,omitempty"`
+	Count     int32              `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Recursive bool               `protobuf:"varint,4,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	MaxAge    *duration.Duration `protobuf:"bytes,5,opt,name=max_age,proto3" json:"max_age,omitempty"`
+}
+
+func (m *ContainerInfoRequest) Reset()         { *m = ContainerInfoRequest{} }
+func (m *ContainerInfoRequest) String() string { return proto.CompactTextString(m) }
+func (*ContainerInfoRequest) ProtoMessage()    {}
+
+type ContainerInfoResponse struct {
+	Containers []*ContainerInfo `protobuf:"bytes,1,rep,name=containers,proto3" json:"containers,omitempty"`
+}
+
+func (m *ContainerInfoResponse) Reset()         { *m = ContainerInfoResponse{} }
+func (m *ContainerInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*ContainerInfoResponse) ProtoMessage()    {}
+
+type ContainerInfo struct {
+	Name  string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Spec  *ContainerSpec    `protobuf:"bytes,2,opt,name=spec,proto3" json:"spec,omitempty"`
+	Stats []*ContainerStats `protobuf:"bytes,3,rep,name=stats,proto3" json:"stats,omitempty"`
+}
+
+func (m *ContainerInfo) Reset()         { *m = ContainerInfo{} }
+func (m *ContainerInfo) String() string { return proto.CompactTextString(m) }
+func (*ContainerInfo) ProtoMessage()    {}
+
+type ContainerSpec struct {
+	CreationTime  *timestamp.Timestamp `protobuf:"bytes,1,opt,name=creation_time,proto3" json:"creation_time,omitempty"`
+	Namespace     string               `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Aliases       []string             `protobuf:"bytes,3,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	Labels        map[string]string    `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Image         string               `protobuf:"bytes,5,opt,name=image,proto3" json:"image,omitempty"`
+	HasCpu        bool                 `protobuf:"varint,6,opt,name=has_cpu,proto3" json:"has_cpu,omitempty"`
+	Cpu           *CpuSpec             `protobuf:"bytes,7,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	HasMemory     bool                 `protobuf:"varint,8,opt,name=has_memory,proto3" json:"has_memory,omitempty"`
+	Memory        *MemorySpec          `protobuf:"bytes,9,opt,name=memory,proto3" json:"memory,omitempty"`
+	HasNetwork    bool                 `protobuf:"varint,10,opt,name=has_network,proto3" json:"has_network,omitempty"`
+	HasFilesystem bool                 `protobuf:"varint,11,opt,name=has_filesystem,proto3" json:"has_filesystem,omitempty"`
+	HasDiskIo     bool                 `protobuf:"varint,12,opt,name=has_disk_io,proto3" json:"has_disk_io,omitempty"`
+}
+
+func (m *ContainerSpec) Reset()         { *m = ContainerSpec{} }
+func (m *ContainerSpec) String() string { return proto.CompactTextString(m) }
+func (*ContainerSpec) ProtoMessage()    {}
+
+type CpuSpec struct {
+	Limit    uint64 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	MaxLimit uint64 `protobuf:"varint,2,opt,name=max_limit,proto3" json:"max_limit,omitempty"`
+	Mask     string `protobuf:"bytes,3,opt,name=mask,proto3" json:"mask,omitempty"`
+	Quota    uint64 `protobuf:"varint,4,opt,name=quota,proto3" json:"quota,omitempty"`
+	Period   uint64 `protobuf:"varint,5,opt,name=period,proto3" json:"period,omitempty"`
+}
+
+func (m *CpuSpec) Reset()         { *m = CpuSpec{} }
+func (m *CpuSpec) String() string { return proto.CompactTextString(m) }
+func (*CpuSpec) ProtoMessage()    {}
+
+type MemorySpec struct {
+	Limit       uint64 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Reservation uint64 `protobuf:"varint,2,opt,name=reservation,proto3" json:"reservation,omitempty"`
+	SwapLimit   uint64 `protobuf:"varint,3,opt,name=swap_limit,proto3" json:"swap_limit,omitempty"`
+}
+
+func (m *MemorySpec) Reset()         { *m = MemorySpec{} }
+func (m *MemorySpec) String() string { return proto.CompactTextString(m) }
+func (*MemorySpec) ProtoMessage()    {}
+
+type ContainerStats struct {
+	Timestamp  *timestamp.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Cpu        *CpuStats            `protobuf:"bytes,2,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory     *MemoryStats         `protobuf:"bytes,3,opt,name=memory,proto3" json:"memory,omitempty"`
+	Network    []*InterfaceStats    `protobuf:"bytes,4,rep,name=network,proto3" json:"network,omitempty"`
+	Filesystem *FilesystemStats     `protobuf:"bytes,5,opt,name=filesystem,proto3" json:"filesystem,omitempty"`
+	Processes  *ProcessStats        `protobuf:"bytes,6,opt,name=processes,proto3" json:"processes,omitempty"`
+}
+
+func (m *ContainerStats) Reset()         { *m = ContainerStats{} }
+func (m *ContainerStats) String() string { return proto.CompactTextString(m) }
+func (*ContainerStats) ProtoMessage()    {}
+
+type CpuStats struct {
+	Total            uint64   `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	PerCpu           []uint64 `protobuf:"varint,2,rep,packed,name=per_cpu,proto3" json:"per_cpu,omitempty"`
+	User             uint64   `protobuf:"varint,3,opt,name=user,proto3" json:"user,omitempty"`
+	System           uint64   `protobuf:"varint,4,opt,name=system,proto3" json:"system,omitempty"`
+	ThrottledPeriods uint64   `protobuf:"varint,5,opt,name=throttled_periods,proto3" json:"throttled_periods,omitempty"`
+	ThrottledTime    uint64   `protobuf:"varint,6,opt,name=throttled_time,proto3" json:"throttled_time,omitempty"`
+	LoadAverage      int32    `protobuf:"varint,7,opt,name=load_average,proto3" json:"load_average,omitempty"`
+}
+
+func (m *CpuStats) Reset()         { *m = CpuStats{} }
+func (m *CpuStats) String() string { return proto.CompactTextString(m) }
+func (*CpuStats) ProtoMessage()    {}
+
+type MemoryStats struct {
+	Usage      uint64 `protobuf:"varint,1,opt,name=usage,proto3" json:"usage,omitempty"`
+	MaxUsage   uint64 `protobuf:"varint,2,opt,name=max_usage,proto3" json:"max_usage,omitempty"`
+	Cache      uint64 `protobuf:"varint,3,opt,name=cache,proto3" json:"cache,omitempty"`
+	Rss        uint64 `protobuf:"varint,4,opt,name=rss,proto3" json:"rss,omitempty"`
+	Swap       uint64 `protobuf:"varint,5,opt,name=swap,proto3" json:"swap,omitempty"`
+	MappedFile uint64 `protobuf:"varint,6,opt,name=mapped_file,proto3" json:"mapped_file,omitempty"`
+	WorkingSet uint64 `protobuf:"varint,7,opt,name=working_set,proto3" json:"working_set,omitempty"`
+	Failcnt    uint64 `protobuf:"varint,8,opt,name=failcnt,proto3" json:"failcnt,omitempty"`
+}
+
+func (m *MemoryStats) Reset()         { *m = MemoryStats{} }
+func (m *MemoryStats) String() string { return proto.CompactTextString(m) }
+func (*MemoryStats) ProtoMessage()    {}
+
+type InterfaceStats struct {
+	Name      string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	RxBytes   uint64 `protobuf:"varint,2,opt,name=rx_bytes,proto3" json:"rx_bytes,omitempty"`
+	RxPackets uint64 `protobuf:"varint,3,opt,name=rx_packets,proto3" json:"rx_packets,omitempty"`
+	RxErrors  uint64 `protobuf:"varint,4,opt,name=rx_errors,proto3" json:"rx_errors,omitempty"`
+	RxDropped uint64 `protobuf:"varint,5,opt,name=rx_dropped,proto3" json:"rx_dropped,omitempty"`
+	TxBytes   uint64 `protobuf:"varint,6,opt,name=tx_bytes,proto3" json:"tx_bytes,omitempty"`
+	TxPackets uint64 `protobuf:"varint,7,opt,name=tx_packets,proto3" json:"tx_packets,omitempty"`
+	TxErrors  uint64 `protobuf:"varint,8,opt,name=tx_errors,proto3" json:"tx_errors,omitempty"`
+	TxDropped uint64 `protobuf:"varint,9,opt,name=tx_dropped,proto3" json:"tx_dropped,omitempty"`
+}
+
+func (m *InterfaceStats) Reset()         { *m = InterfaceStats{} }
+func (m *InterfaceStats) String() string { return proto.CompactTextString(m) }
+func (*InterfaceStats) ProtoMessage()    {}
+
+type FilesystemStats struct {
+	TotalUsageBytes uint64 `protobuf:"varint,1,opt,name=total_usage_bytes,proto3" json:"total_usage_bytes,omitempty"`
+	BaseUsageBytes  uint64 `protobuf:"varint,2,opt,name=base_usage_bytes,proto3" json:"base_usage_bytes,omitempty"`
+	InodeUsage      uint64 `protobuf:"varint,3,opt,name=inode_usage,proto3" json:"inode_usage,omitempty"`
+}
+
+func (m *FilesystemStats) Reset()         { *m = FilesystemStats{} }
+func (m *FilesystemStats) String() string { return proto.CompactTextString(m) }
+func (*FilesystemStats) ProtoMessage()    {}
+
+type ProcessStats struct {
+	ProcessCount   uint64 `protobuf:"varint,1,opt,name=process_count,proto3" json:"process_count,omitempty"`
+	FdCount        uint64 `protobuf:"varint,2,opt,name=fd_count,proto3" json:"fd_count,omitempty"`
+	SocketCount    uint64 `protobuf:"varint,3,opt,name=socket_count,proto3" json:"socket_count,omitempty"`
+	ThreadsCurrent uint64 `protobuf:"varint,4,opt,name=threads_current,proto3" json:"threads_current,omitempty"`
+	ThreadsMax     uint64 `protobuf:"varint,5,opt,name=threads_max,proto3" json:"threads_max,omitempty"`
+}
+
+func (m *ProcessStats) Reset()         { *m = ProcessStats{} }
+func (m *ProcessStats) String() string { return proto.CompactTextString(m) }
+func (*ProcessStats) ProtoMessage()    {}
+
+type WatchStatsRequest struct {
+	Name      string             `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	IdType    string             `protobuf:"bytes,2,opt,name=id_type,proto3" json:"id_type,omitempty"`
+	Recursive bool               `protobuf:"varint,3,opt,name=recursive,proto3" json:"recursive,omitempty"`
+	Interval  *duration.Duration `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+}
+
+func (m *WatchStatsRequest) Reset()         { *m = WatchStatsRequest{} }
+func (m *WatchStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchStatsRequest) ProtoMessage()    {}
+
+type ContainerStatsUpdate struct {
+	Name  string          `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Stats *ContainerStats `protobuf:"bytes,2,opt,name=stats,proto3" json:"stats,omitempty"`
+}
+
+func (m *ContainerStatsUpdate) Reset()         { *m = ContainerStatsUpdate{} }
+func (m *ContainerStatsUpdate) String() string { return proto.CompactTextString(m) }
+func (*ContainerStatsUpdate) ProtoMessage()    {}
@@ -0,0 +1,174 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grpcapi defines the gRPC API of cAdvisor, the cadvisor.v1.Cadvisor
+// service, along with its client.
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	serviceName = "cadvisor.v1.Cadvisor"
+
+	getMachineInfoMethod   = "/" + serviceName + "/GetMachineInfo"
+	getContainerInfoMethod = "/" + serviceName + "/GetContainerInfo"
+	watchStatsMethod       = "/" + serviceName + "/WatchStats"
+)
+
+// CadvisorServer is implemented by servers of the cadvisor.v1.Cadvisor
+// service.
+type CadvisorServer interface {
+	GetMachineInfo(context.Context, *MachineInfoRequest) (*MachineInfo, error)
+	GetContainerInfo(context.Context, *ContainerInfoRequest) (*ContainerInfoResponse, error)
+	WatchStats(*WatchStatsRequest, WatchStatsServer) error
+}
+
+// WatchStatsServer is the server side of a WatchStats stream.
+type WatchStatsServer interface {
+	Send(*ContainerStatsUpdate) error
+	grpc.ServerStream
+}
+
+// RegisterCadvisorServer registers srv as the cadvisor.v1.Cadvisor service
+// of s.
+func RegisterCadvisorServer(s *grpc.Server, srv CadvisorServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*CadvisorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetMachineInfo", Handler: getMachineInfoHandler},
+		{MethodName: "GetContainerInfo", Handler: getContainerInfoHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "WatchStats", Handler: watchStatsHandler, ServerStreams: true},
+	},
+	Metadata: "api.proto",
+}
+
+func getMachineInfoHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MachineInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CadvisorServer).GetMachineInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: getMachineInfoMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CadvisorServer).GetMachineInfo(ctx, req.(*MachineInfoRequest))
+	})
+}
+
+func getContainerInfoHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainerInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CadvisorServer).GetContainerInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: getContainerInfoMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CadvisorServer).GetContainerInfo(ctx, req.(*ContainerInfoRequest))
+	})
+}
+
+func watchStatsHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(WatchStatsRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(CadvisorServer).WatchStats(in, &watchStatsServer{stream})
+}
+
+type watchStatsServer struct {
+	grpc.ServerStream
+}
+
+func (s *watchStatsServer) Send(update *ContainerStatsUpdate) error {
+	return s.ServerStream.SendMsg(update)
+}
+
+// CadvisorClient is a client of the cadvisor.v1.Cadvisor service.
+type CadvisorClient interface {
+	GetMachineInfo(ctx context.Context, in *MachineInfoRequest, opts ...grpc.CallOption) (*MachineInfo, error)
+	GetContainerInfo(ctx context.Context, in *ContainerInfoRequest, opts ...grpc.CallOption) (*ContainerInfoResponse, error)
+	WatchStats(ctx context.Context, in *WatchStatsRequest, opts ...grpc.CallOption) (WatchStatsClient, error)
+}
+
+// WatchStatsClient is the client side of a WatchStats stream.
+type WatchStatsClient interface {
+	Recv() (*ContainerStatsUpdate, error)
+	grpc.ClientStream
+}
+
+type cadvisorClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewCadvisorClient returns a client of the cadvisor.v1.Cadvisor service
+// served on cc.
+func NewCadvisorClient(cc *grpc.ClientConn) CadvisorClient {
+	return &cadvisorClient{cc: cc}
+}
+
+func (c *cadvisorClient) GetMachineInfo(ctx context.Context, in *MachineInfoRequest, opts ...grpc.CallOption) (*MachineInfo, error) {
+	out := new(MachineInfo)
+	if err := c.cc.Invoke(ctx, getMachineInfoMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cadvisorClient) GetContainerInfo(ctx context.Context, in *ContainerInfoRequest, opts ...grpc.CallOption) (*ContainerInfoResponse, error) {
+	out := new(ContainerInfoResponse)
+	if err := c.cc.Invoke(ctx, getContainerInfoMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cadvisorClient) WatchStats(ctx context.Context, in *WatchStatsRequest, opts ...grpc.CallOption) (WatchStatsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], watchStatsMethod, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client := &watchStatsClient{stream}
+	if err := client.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := client.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+type watchStatsClient struct {
+	grpc.ClientStream
+}
+
+func (c *watchStatsClient) Recv() (*ContainerStatsUpdate, error) {
+	update := new(ContainerStatsUpdate)
+	if err := c.ClientStream.RecvMsg(update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
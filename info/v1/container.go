@@ -25,6 +25,14 @@ type CpuSpec struct {
 	Mask     string `json:"mask,omitempty"`
 	Quota    uint64 `json:"quota,omitempty"`
 	Period   uint64 `json:"period,omitempty"`
+	// CPUs the container can actually run on, i.e. the cpuset restricted by
+	// its ancestors and by CPU hotplug.
+	EffectiveMask string `json:"effective_mask,omitempty"`
+	// Memory nodes the container can allocate memory from.
+	MemoryNodes string `json:"memory_nodes,omitempty"`
+	// CPUs of the effective mask isolated from the scheduler (isolcpus) or
+	// running without scheduling-clock ticks (nohz_full).
+	IsolatedCpus string `json:"isolated_cpus,omitempty"`
 }
 
 type MemorySpec struct {
@@ -77,6 +85,59 @@ type ContainerSpec struct {
 
 	// Image name used for this container.
 	Image string `json:"image,omitempty"`
+
+	// Runtime state of the container, only set by runtimes reporting it.
+	State *ContainerState `json:"state,omitempty"`
+
+	// Mounts visible to the processes of the container, in mount order.
+	// Only set for containers with their own mount namespace.
+	Mounts []MountSpec `json:"mounts,omitempty"`
+
+	// ID of the container this container was restored from with CRIU, from
+	// the io.cadvisor.restored-from annotation of the container.
+	RestoredFrom string `json:"restored_from,omitempty"`
+	// ID of the first container of a chain of containers restored from
+	// checkpoints of each other, which identifies the workload across
+	// restores. Only set for restored containers.
+	ContinuityId string `json:"continuity_id,omitempty"`
+}
+
+// MountSpec describes a mount of a container, from the mountinfo of its init
+// process.
+type MountSpec struct {
+	// Path of the mount in the container.
+	Destination string `json:"destination"`
+	// Host path of the mounted directory, e.g. the directory of a bind mount,
+	// if the filesystem is mounted on the host. Otherwise the path of the
+	// mounted directory within its filesystem.
+	Source string `json:"source"`
+	// Mount source as reported by the kernel, e.g. /dev/sda1, tmpfs or overlay.
+	Device string `json:"device,omitempty"`
+	// Type of the filesystem, e.g. ext4, tmpfs or overlay.
+	FsType string `json:"fs_type"`
+	// Whether the mount is read only.
+	ReadOnly bool `json:"read_only"`
+	// Propagation of the mount: private, shared, slave, "shared,slave" for a
+	// slave mount which is also shared, or unbindable.
+	Propagation string `json:"propagation"`
+}
+
+// ContainerState describes the lifecycle of a container as tracked by its runtime.
+type ContainerState struct {
+	// Number of times the runtime restarted the container.
+	RestartCount int `json:"restart_count"`
+	// Whether the last exit of the container was caused by the OOM killer.
+	OOMKilled bool `json:"oom_killed"`
+	// Result of the health checks, nil if the container has none.
+	Health *ContainerHealth `json:"health,omitempty"`
+}
+
+// ContainerHealth is the result of the health checks of a container.
+type ContainerHealth struct {
+	// Health status, one of "starting", "healthy" or "unhealthy".
+	Status string `json:"status"`
+	// Number of consecutive failed health checks.
+	FailingStreak int `json:"failing_streak"`
 }
 
 // Container reference contains enough information to uniquely identify a container
@@ -277,6 +338,9 @@ type LoadStats struct {
 
 	// Number of tasks waiting on IO
 	NrIoWait uint64 `json:"nr_io_wait"`
+
+	// Number of tasks that exited and were not reaped by their parent yet
+	NrZombie uint64 `json:"nr_zombie"`
 }
 
 // CPU usage time statistics.
@@ -296,6 +360,15 @@ type CpuUsage struct {
 	// Time spent in kernel space.
 	// Unit: nanoseconds.
 	System uint64 `json:"system"`
+
+	// Time spent running virtual CPUs of guests (including niced guests).
+	// Unit: nanoseconds.
+	Guest uint64 `json:"guest,omitempty"`
+
+	// Time stolen by the hypervisor while running other virtual machines.
+	// Only reported for the root container and for VM-isolated containers.
+	// Unit: nanoseconds.
+	Steal uint64 `json:"steal,omitempty"`
 }
 
 // Cpu Completely Fair Scheduler statistics.
@@ -309,6 +382,14 @@ type CpuCFS struct {
 	// Total time duration for which tasks in the cgroup have been throttled.
 	// Unit: nanoseconds.
 	ThrottledTime uint64 `json:"throttled_time"`
+
+	// Total number of periods in which the cgroup used burst capacity
+	// (cpu.max.burst / cpu.cfs_burst_us). Requires Linux 5.14+.
+	Bursts uint64 `json:"bursts,omitempty"`
+
+	// Total time the cgroup spent running on burst capacity beyond its quota.
+	// Unit: nanoseconds.
+	BurstTime uint64 `json:"burst_time,omitempty"`
 }
 
 // Cpu Aggregated scheduler statistics
@@ -328,6 +409,11 @@ type CpuStats struct {
 	Usage     CpuUsage     `json:"usage"`
 	CFS       CpuCFS       `json:"cfs"`
 	Schedstat CpuSchedstat `json:"schedstat"`
+	// Total time tasks of the cgroup spent waiting on a runqueue, as reported
+	// by wait_sum in the cgroup's cpu.stat. Only available on cgroup v1 with
+	// kernel.sched_schedstats enabled.
+	// Unit: nanoseconds.
+	WaitTime uint64 `json:"wait_time,omitempty"`
 	// Smoothed average of number of runnable threads x 1000.
 	// We multiply by thousand to avoid using floats, but preserving precision.
 	// Load is smoothed over the last 10 seconds. Instantaneous value can be read
@@ -340,6 +426,9 @@ type PerDiskStats struct {
 	Major  uint64            `json:"major"`
 	Minor  uint64            `json:"minor"`
 	Stats  map[string]uint64 `json:"stats"`
+	// Physical disks the device is built on, e.g. /dev/sda for an LVM volume
+	// on /dev/sda2. Empty for physical disks.
+	PhysicalDevices []string `json:"physical_devices,omitempty"`
 }
 
 type DiskIoStats struct {
@@ -397,6 +486,125 @@ type MemoryStats struct {
 
 	ContainerData    MemoryStatsMemoryData `json:"container_data,omitempty"`
 	HierarchicalData MemoryStatsMemoryData `json:"hierarchical_data,omitempty"`
+
+	// Breakdown of the memory usage as reported by memory.stat. Only set on
+	// cgroup v2 hosts when memory_stat metrics are enabled.
+	Breakdown *MemoryStatBreakdown `json:"breakdown,omitempty"`
+
+	// Memory events of the cgroup and its descendants, from memory.events.
+	// Only set on cgroup v2 hosts.
+	Events *MemoryEvents `json:"events,omitempty"`
+	// Memory events of the cgroup itself, from memory.events.local.
+	// Only set on cgroup v2 hosts running Linux 5.2 or newer.
+	LocalEvents *MemoryEvents `json:"local_events,omitempty"`
+
+	// Kernel memory of the slab allocator charged to the cgroup. Only set
+	// when slab metrics are enabled.
+	Slab *MemorySlabStats `json:"slab,omitempty"`
+}
+
+// MemorySlabStats holds the slab memory charged to a cgroup, which includes
+// the dentry and inode caches of the files the container accessed.
+type MemorySlabStats struct {
+	// Size of the slab memory which can be reclaimed under memory pressure,
+	// such as dentries and inodes, from memory.stat. Only set on cgroup v2
+	// hosts.
+	// Units: Bytes.
+	Reclaimable uint64 `json:"reclaimable"`
+	// Size of the slab memory which cannot be reclaimed, from memory.stat.
+	// Only set on cgroup v2 hosts.
+	// Units: Bytes.
+	Unreclaimable uint64 `json:"unreclaimable"`
+	// Size of the slab caches of the cgroup by kind, "dentry" for the
+	// dentry cache and "inode" for the inode caches of all filesystems, from
+	// memory.kmem.slabinfo. Only set on cgroup v1 hosts with kernel memory
+	// accounting, cgroup v2 does not attribute slab caches to cgroups.
+	// Units: Bytes.
+	Caches map[string]uint64 `json:"caches,omitempty"`
+}
+
+// MemoryEvents holds the cumulative counts of the cgroup v2 memory events.
+type MemoryEvents struct {
+	// Number of times the cgroup was reclaimed below its memory.low boundary.
+	Low uint64 `json:"low"`
+	// Number of times processes were throttled and forced into direct reclaim
+	// because the memory.high boundary was exceeded.
+	High uint64 `json:"high"`
+	// Number of times the memory usage was about to go over memory.max.
+	Max uint64 `json:"max"`
+	// Number of times the memory usage reached the limit and allocation failed.
+	Oom uint64 `json:"oom"`
+	// Number of processes killed by the OOM killer.
+	OomKill uint64 `json:"oom_kill"`
+	// Number of times the whole cgroup was killed by the OOM killer because
+	// of memory.oom.group.
+	OomGroupKill uint64 `json:"oom_group_kill"`
+}
+
+// MemoryStatBreakdown holds the cgroup v2 memory.stat keys. Field names follow
+// the kernel keys so they stay stable across kernel versions; keys missing on
+// older kernels are reported as zero.
+type MemoryStatBreakdown struct {
+	// Anonymous memory, including tmpfs-backed shared memory.
+	// Units: Bytes.
+	Anon uint64 `json:"anon"`
+	// Page cache memory, including tmpfs/shmem.
+	// Units: Bytes.
+	File uint64 `json:"file"`
+	// Memory allocated to kernel stacks.
+	// Units: Bytes.
+	KernelStack uint64 `json:"kernel_stack"`
+	// Memory allocated for page tables.
+	// Units: Bytes.
+	Pagetables uint64 `json:"pagetables"`
+	// Memory used by per-cpu kernel data structures.
+	// Units: Bytes.
+	Percpu uint64 `json:"percpu"`
+	// Memory used in network transmission buffers.
+	// Units: Bytes.
+	Sock uint64 `json:"sock"`
+	// Swap-backed cached memory, such as tmpfs and shm segments.
+	// Units: Bytes.
+	Shmem uint64 `json:"shmem"`
+	// Page cache mapped into process address spaces.
+	// Units: Bytes.
+	FileMapped uint64 `json:"file_mapped"`
+	// Page cache that is modified but not yet written back to disk.
+	// Units: Bytes.
+	FileDirty uint64 `json:"file_dirty"`
+	// Page cache that is being written back to disk.
+	// Units: Bytes.
+	FileWriteback uint64 `json:"file_writeback"`
+	// Anonymous memory backed by transparent hugepages.
+	// Units: Bytes.
+	AnonThp uint64 `json:"anon_thp"`
+	// Memory on the internal memory management LRU lists.
+	// Units: Bytes.
+	InactiveAnon uint64 `json:"inactive_anon"`
+	ActiveAnon   uint64 `json:"active_anon"`
+	InactiveFile uint64 `json:"inactive_file"`
+	ActiveFile   uint64 `json:"active_file"`
+	Unevictable  uint64 `json:"unevictable"`
+	// Slab memory that might and might not be reclaimed.
+	// Units: Bytes.
+	SlabReclaimable   uint64 `json:"slab_reclaimable"`
+	SlabUnreclaimable uint64 `json:"slab_unreclaimable"`
+
+	// Number of refaults of previously evicted pages.
+	WorkingsetRefaultAnon uint64 `json:"workingset_refault_anon"`
+	WorkingsetRefaultFile uint64 `json:"workingset_refault_file"`
+	// Number of refaulted pages that were immediately activated.
+	WorkingsetActivateAnon uint64 `json:"workingset_activate_anon"`
+	WorkingsetActivateFile uint64 `json:"workingset_activate_file"`
+	// Number of restored pages which had been detected as a workingset before
+	// they got reclaimed.
+	WorkingsetRestoreAnon uint64 `json:"workingset_restore_anon"`
+	WorkingsetRestoreFile uint64 `json:"workingset_restore_file"`
+	// Number of times a shadow node has been reclaimed.
+	WorkingsetNodereclaim uint64 `json:"workingset_nodereclaim"`
+	// Number of pages scanned and reclaimed by the page reclaim code.
+	Pgscan  uint64 `json:"pgscan"`
+	Pgsteal uint64 `json:"pgsteal"`
 }
 
 type MemoryNumaStats struct {
@@ -445,6 +653,26 @@ type NetworkStats struct {
 	Udp6 UdpStat `json:"udp6"`
 	// TCP advanced stats
 	TcpAdvanced TcpAdvancedStat `json:"tcp_advanced"`
+	// Per-queue statistics of physical network devices.
+	// Applies only for root container.
+	Queues []InterfaceQueueStats `json:"queues,omitempty"`
+}
+
+type InterfaceQueueStats struct {
+	// The name of the interface the queue belongs to.
+	Interface string `json:"interface"`
+	// Direction of the queue, either "rx" or "tx".
+	Direction string `json:"direction"`
+	// Index of the queue on the interface.
+	Queue int `json:"queue"`
+	// Cumulative count of packets handled by the queue.
+	Packets uint64 `json:"packets"`
+	// Cumulative count of bytes handled by the queue.
+	Bytes uint64 `json:"bytes"`
+	// Cumulative count of packets dropped by the queue.
+	Drops uint64 `json:"drops"`
+	// Cumulative XDP counters of the queue keyed by action (e.g. "drop", "redirect").
+	Xdp map[string]uint64 `json:"xdp,omitempty"`
 }
 
 type TcpStat struct {
@@ -729,6 +957,10 @@ type FsStats struct {
 	// The block device name associated with the filesystem.
 	Device string `json:"device,omitempty"`
 
+	// Mount point of the filesystem, only set for the filesystems of the
+	// root container.
+	Mountpoint string `json:"mountpoint,omitempty"`
+
 	// Type of the filesytem.
 	Type string `json:"type"`
 
@@ -885,6 +1117,14 @@ type ResctrlStats struct {
 	Cache           []CacheStats           `json:"cache,omitempty"`
 }
 
+// GvisorStats are counters of the gVisor Sentry running a sandbox.
+type GvisorStats struct {
+	// Number of system calls of the application intercepted by the Sentry.
+	Syscalls uint64 `json:"syscalls"`
+	// Number of switches between the application and the Sentry done by the platform.
+	PlatformSwitches uint64 `json:"platform_switches"`
+}
+
 // PerfUncoreStat represents value of a single monitored perf uncore event.
 type PerfUncoreStat struct {
 	PerfValue
@@ -909,9 +1149,19 @@ type ProcessStats struct {
 	// Number of open file descriptors
 	FdCount uint64 `json:"fd_count"`
 
+	// Largest number of file descriptors opened by a single process, to be
+	// compared with its max_open_files ulimit
+	MaxProcessFdCount uint64 `json:"max_process_fd_count,omitempty"`
+
 	// Number of sockets
 	SocketCount uint64 `json:"socket_count"`
 
+	// Number of inotify instances
+	InotifyInstances uint64 `json:"inotify_instances,omitempty"`
+
+	// Number of inotify watches of all inotify instances
+	InotifyWatches uint64 `json:"inotify_watches,omitempty"`
+
 	// Number of threads currently in container
 	ThreadsCurrent uint64 `json:"threads_current,omitempty"`
 
@@ -957,6 +1207,251 @@ type ContainerStats struct {
 
 	// Resource Control (resctrl) statistics
 	Resctrl ResctrlStats `json:"resctrl,omitempty"`
+
+	// Statistics of the gVisor Sentry, only set for gVisor sandboxes
+	Gvisor *GvisorStats `json:"gvisor,omitempty"`
+
+	// Kernel samepage merging statistics.
+	// Applies only for root container.
+	Ksm *KsmStats `json:"ksm,omitempty"`
+
+	// CPU frequency scaling statistics.
+	// Applies only for root container.
+	CpuFreq *CpuFreqStats `json:"cpu_freq,omitempty"`
+
+	// Energy consumption statistics.
+	Energy *EnergyStats `json:"energy,omitempty"`
+
+	// Temperatures and fan speeds of the host.
+	// Applies only for root container.
+	Thermal *ThermalStats `json:"thermal,omitempty"`
+
+	// Thermal throttling counters of the host's CPUs.
+	// Applies only for root container.
+	ThermalThrottle *ThermalThrottleStats `json:"thermal_throttle,omitempty"`
+
+	// Software RAID (md) arrays of the host.
+	// Applies only for root container.
+	MdArrays []MdArrayStats `json:"md_arrays,omitempty"`
+
+	// Sessions of the host with network attached block storage, i.e. iSCSI
+	// and NVMe over Fabrics.
+	// Applies only for root container.
+	StorageSessions []StorageSessionStats `json:"storage_sessions,omitempty"`
+}
+
+// StorageSessionStats holds the state of a session with a network attached
+// storage target: an iSCSI session or an NVMe over Fabrics controller.
+type StorageSessionStats struct {
+	// Name of the session, e.g. session1 for iSCSI or nvme0 for NVMe.
+	Name string `json:"name"`
+	// Transport of the session: iscsi, nvme-tcp, nvme-rdma, nvme-fc or
+	// nvme-loop.
+	Transport string `json:"transport"`
+	// Target of the session: the IQN of an iSCSI target or the NQN of an
+	// NVMe subsystem.
+	Target string `json:"target"`
+	// Address of the target, e.g. 10.0.0.1:3260 for iSCSI or
+	// traddr=10.0.0.1,trsvcid=4420 for NVMe.
+	Address string `json:"address,omitempty"`
+	// State of the session as reported by the kernel, e.g. LOGGED_IN or
+	// FAILED for iSCSI, live, connecting or resetting for NVMe.
+	State string `json:"state"`
+	// Whether the session is able to carry IO.
+	Connected bool `json:"connected"`
+	// Number of sessions of the host with the same target, i.e. the paths to
+	// it, and the number of those which are connected.
+	Paths          uint64 `json:"paths"`
+	ConnectedPaths uint64 `json:"connected_paths"`
+	// Number of times the session was seen leaving the connected state, i.e.
+	// starting to reconnect, since cAdvisor started. Reconnects completing
+	// between two housekeepings are not seen.
+	Reconnects uint64 `json:"reconnects"`
+	// Block devices backed by the session, e.g. sdb or nvme1n1.
+	Devices []string `json:"devices,omitempty"`
+}
+
+// MdArrayStats holds the state of a software RAID (md) array, from
+// /proc/mdstat and /sys/block/<array>/md.
+type MdArrayStats struct {
+	// Name of the array, e.g. md0.
+	Name string `json:"name"`
+	// RAID level, e.g. raid1. Empty for inactive arrays.
+	Level string `json:"level,omitempty"`
+	// State of the array, e.g. clean, active, read-auto or inactive.
+	State string `json:"state"`
+	// Number of devices of the complete array.
+	Disks uint64 `json:"disks"`
+	// Number of devices missing from the array, non-zero if it is degraded.
+	Degraded uint64 `json:"degraded"`
+	// Member devices of the array.
+	Members []MdMember `json:"members,omitempty"`
+	// Sync action in progress, e.g. resync, recover, check or reshape, idle
+	// if there is none.
+	SyncAction string `json:"sync_action,omitempty"`
+	// Progress of the sync action between 0 and 1, 0 when idle.
+	SyncCompleted float64 `json:"sync_completed"`
+	// Speed of the sync action.
+	// Units: KiB per second.
+	SyncSpeed uint64 `json:"sync_speed"`
+}
+
+// MdMember is a member device of an md array.
+type MdMember struct {
+	// Name of the device, e.g. sda1.
+	Device string `json:"device"`
+	// Whether the device failed and was removed from the array.
+	Faulty bool `json:"faulty,omitempty"`
+	// Whether the device is a spare, e.g. waiting to replace a failed member.
+	Spare bool `json:"spare,omitempty"`
+}
+
+// KsmStats holds the kernel samepage merging counters of /sys/kernel/mm/ksm.
+type KsmStats struct {
+	// Number of shared pages in use.
+	PagesShared uint64 `json:"pages_shared"`
+	// Number of sites sharing the shared pages, i.e. how much memory is saved.
+	PagesSharing uint64 `json:"pages_sharing"`
+	// Number of times all mergeable areas have been scanned.
+	FullScans uint64 `json:"full_scans"`
+	// Memory saved by KSM minus the cost of its metadata, reported by kernels
+	// 6.1 and newer. It is negative when KSM costs more than it saves.
+	// Units: Bytes.
+	GeneralProfit int64 `json:"general_profit"`
+}
+
+// CpuFreqStats holds the frequency scaling state of the host's CPUs.
+type CpuFreqStats struct {
+	// Per logical CPU frequencies, for CPUs exposing cpufreq.
+	PerCpu []CpuFreq `json:"per_cpu"`
+	// State of the intel_pstate driver, if it is in use.
+	IntelPstate *IntelPstateStats `json:"intel_pstate,omitempty"`
+}
+
+// CpuFreq holds the frequencies of a logical CPU.
+// Units: kHz, except for the APERF/MPERF counters.
+type CpuFreq struct {
+	Cpu int `json:"cpu"`
+	// Current frequency as reported by the cpufreq driver.
+	Current uint64 `json:"current"`
+	// Frequency limits currently enforced by the cpufreq policy.
+	ScalingMin uint64 `json:"scaling_min"`
+	ScalingMax uint64 `json:"scaling_max"`
+	// Highest frequency the CPU supports, including turbo.
+	HardwareMax uint64 `json:"hardware_max"`
+	// Base (non-turbo) frequency, only reported by some drivers.
+	Base uint64 `json:"base,omitempty"`
+	// Raw values of the APERF and MPERF model specific registers. They are
+	// only available when cAdvisor can read /dev/cpu/<cpu>/msr.
+	Aperf uint64 `json:"aperf,omitempty"`
+	Mperf uint64 `json:"mperf,omitempty"`
+	// Average frequency the CPU actually ran at since the previous sample,
+	// derived from APERF/MPERF and the base frequency.
+	Effective uint64 `json:"effective,omitempty"`
+}
+
+// EnergyStats holds the energy consumed by the host's RAPL domains or, for
+// containers other than the root, an estimate of the share they consumed.
+// Units: microjoules, counted since cAdvisor started.
+type EnergyStats struct {
+	// Energy consumed by every RAPL domain of the host.
+	// Applies only for root container.
+	Domains []EnergyDomainStats `json:"domains,omitempty"`
+	// CPU time used on the host when the domains were read, used to attribute
+	// the energy to containers.
+	// Applies only for root container.
+	// Unit: nanoseconds.
+	CpuTime uint64 `json:"cpu_time,omitempty"`
+	// Energy attributed to the container according to its share of the CPU
+	// time used on the host. For the root container, this is the energy of
+	// all package and DRAM domains.
+	Estimated uint64 `json:"estimated,omitempty"`
+}
+
+// EnergyDomainStats holds the energy consumed by a RAPL domain.
+type EnergyDomainStats struct {
+	// Socket the domain belongs to.
+	Socket int `json:"socket"`
+	// Name of the domain, e.g. "package", "core", "uncore" or "dram".
+	Domain string `json:"domain"`
+	// Unit: microjoules.
+	Energy uint64 `json:"energy"`
+}
+
+// ThermalStats holds the temperatures of the thermal zones of the host and the
+// speed of its fans.
+type ThermalStats struct {
+	Zones []ThermalZoneStats `json:"zones,omitempty"`
+	Fans  []FanStats         `json:"fans,omitempty"`
+}
+
+// ThermalZoneStats holds the temperature of a thermal zone of
+// /sys/class/thermal.
+type ThermalZoneStats struct {
+	// Name of the zone, e.g. "thermal_zone0".
+	Zone string `json:"zone"`
+	// Type of the sensor, e.g. "x86_pkg_temp" or "acpitz".
+	Type string `json:"type"`
+	// Units: millidegree Celsius.
+	Temperature int64 `json:"temperature"`
+	// Temperatures at which the kernel starts cooling the zone.
+	TripPoints []ThermalTripPoint `json:"trip_points,omitempty"`
+}
+
+// ThermalTripPoint is a temperature threshold of a thermal zone.
+type ThermalTripPoint struct {
+	Index int `json:"index"`
+	// Type of the trip point: "active", "passive", "hot" or "critical".
+	Type string `json:"type"`
+	// Units: millidegree Celsius.
+	Temperature int64 `json:"temperature"`
+}
+
+// ThermalThrottleStats holds the number of times the CPUs of the host were
+// throttled because they were too hot.
+type ThermalThrottleStats struct {
+	Cores    []CoreThrottleStats    `json:"cores,omitempty"`
+	Packages []PackageThrottleStats `json:"packages,omitempty"`
+}
+
+// CoreThrottleStats holds the thermal throttling counters of a logical CPU.
+type CoreThrottleStats struct {
+	Cpu   int    `json:"cpu"`
+	Count uint64 `json:"count"`
+	// Units: nanoseconds, only reported by Linux 5.7+.
+	Time uint64 `json:"time,omitempty"`
+}
+
+// PackageThrottleStats holds the thermal throttling counters of a CPU
+// package.
+type PackageThrottleStats struct {
+	Package int    `json:"package"`
+	Count   uint64 `json:"count"`
+	// Units: nanoseconds, only reported by Linux 5.7+.
+	Time uint64 `json:"time,omitempty"`
+}
+
+// FanStats holds the speed of a fan reported by a hwmon driver.
+type FanStats struct {
+	// Name of the hwmon device, e.g. "hwmon2".
+	Device string `json:"device"`
+	// Name of the chip driving the fan, e.g. "nct6775".
+	Chip string `json:"chip"`
+	// Label of the fan, or "fan<n>" if the driver does not label it.
+	Fan string `json:"fan"`
+	// Units: revolutions per minute.
+	Speed uint64 `json:"speed"`
+}
+
+// IntelPstateStats holds the global settings of the intel_pstate driver.
+type IntelPstateStats struct {
+	// Operation mode of the driver: "active", "passive" or "off".
+	Status string `json:"status"`
+	// Whether turbo frequencies are disabled.
+	NoTurbo bool `json:"no_turbo"`
+	// Performance limits in percent of the maximum supported performance.
+	MinPerfPct uint64 `json:"min_perf_pct"`
+	MaxPerfPct uint64 `json:"max_perf_pct"`
 }
 
 func timeEq(t1, t2 time.Time, tolerance time.Duration) bool {
@@ -1041,16 +1536,28 @@ type Event struct {
 type EventType string
 
 const (
-	EventOom               EventType = "oom"
-	EventOomKill           EventType = "oomKill"
-	EventContainerCreation EventType = "containerCreation"
-	EventContainerDeletion EventType = "containerDeletion"
+	EventOom                EventType = "oom"
+	EventOomKill            EventType = "oomKill"
+	EventContainerCreation  EventType = "containerCreation"
+	EventContainerDeletion  EventType = "containerDeletion"
+	EventMachineInfoChanged EventType = "machineInfoChanged"
+	EventMdArrayChanged     EventType = "mdArrayChanged"
+	EventContainerRestored  EventType = "containerRestored"
 )
 
 // Extra information about an event. Only one type will be set.
 type EventData struct {
 	// Information about an OOM kill event.
 	OomKill *OomKillEventData `json:"oom,omitempty"`
+
+	// Information about a change of the machine info.
+	MachineInfoChanged *MachineInfoChangedEventData `json:"machine_info_changed,omitempty"`
+
+	// Information about a state transition of an md array.
+	MdArrayChanged *MdArrayChangedEventData `json:"md_array_changed,omitempty"`
+
+	// Information about a container restored from a checkpoint.
+	ContainerRestored *ContainerRestoredEventData `json:"container_restored,omitempty"`
 }
 
 // Information related to an OOM kill instance
@@ -1061,3 +1568,52 @@ type OomKillEventData struct {
 	// The name of the killed process
 	ProcessName string `json:"process_name"`
 }
+
+// Information related to a change of the machine info
+type MachineInfoChangedEventData struct {
+	// The machine info fields that changed
+	Changes []MachineInfoChange `json:"changes"`
+}
+
+// Information related to a state transition of an md array, e.g. a member
+// failing or a resync finishing
+type MdArrayChangedEventData struct {
+	// Name of the array, ex. md0
+	Array string `json:"array"`
+
+	// State before the transition, nil if the array was just assembled
+	Old *MdArrayState `json:"old,omitempty"`
+
+	// State after the transition, nil if the array was stopped
+	New *MdArrayState `json:"new,omitempty"`
+}
+
+// Information related to a container restored from a checkpoint, linking it
+// to the container the checkpoint was taken of
+type ContainerRestoredEventData struct {
+	// ID of the checkpointed container
+	RestoredFrom string `json:"restored_from"`
+
+	// ID of the first container of the chain of restores
+	ContinuityId string `json:"continuity_id"`
+}
+
+// The part of the state of an md array whose transitions are reported
+type MdArrayState struct {
+	State         string   `json:"state"`
+	Degraded      uint64   `json:"degraded"`
+	FailedMembers []string `json:"failed_members,omitempty"`
+	SyncAction    string   `json:"sync_action,omitempty"`
+}
+
+// A single changed machine info field
+type MachineInfoChange struct {
+	// JSON name of the changed MachineInfo field, ex. num_cores
+	Field string `json:"field"`
+
+	// Value before the change
+	Old string `json:"old"`
+
+	// Value after the change
+	New string `json:"new"`
+}
@@ -14,7 +14,10 @@
 
 package v1
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type FsInfo struct {
 	// Block device associated with the filesystem.
@@ -44,6 +47,12 @@ type Node struct {
 	HugePages []HugePagesInfo `json:"hugepages"`
 	Cores     []Core          `json:"cores"`
 	Caches    []Cache         `json:"caches"`
+	// Measured memory copy bandwidth in bytes per second.
+	// Only set when memory calibration is enabled.
+	MemoryBandwidth uint64 `json:"memory_bandwidth,omitempty"`
+	// Measured memory access latency in nanoseconds.
+	// Only set when memory calibration is enabled.
+	MemoryLatency uint64 `json:"memory_latency_ns,omitempty"`
 }
 
 type Core struct {
@@ -51,8 +60,28 @@ type Core struct {
 	Threads  []int   `json:"thread_ids"`
 	Caches   []Cache `json:"caches"`
 	SocketID int     `json:"socket_id"`
+	// Cluster of cores sharing a DSU/L3 within the socket, only reported on
+	// platforms exposing it (e.g. arm64).
+	ClusterID int `json:"cluster_id,omitempty"`
+	// Book and drawer of the socket on s390x, where socket ids are only unique
+	// within a book and books within a drawer.
+	BookID   int `json:"book_id,omitempty"`
+	DrawerID int `json:"drawer_id,omitempty"`
+	// Compute capacity relative to the fastest core of the machine, scaled to
+	// 1024. Only reported on platforms exposing it (e.g. arm64).
+	Capacity uint64 `json:"cpu_capacity,omitempty"`
+	// Core microarchitecture derived from the arm64 MIDR, e.g. neoverse-n1.
+	Model string `json:"model,omitempty"`
+	// Core type on heterogeneous (big.LITTLE) machines: performance or
+	// efficiency. Empty when all cores have the same capacity.
+	Type string `json:"core_type,omitempty"`
 }
 
+const (
+	CoreTypePerformance = "performance"
+	CoreTypeEfficiency  = "efficiency"
+)
+
 type Cache struct {
 	// Size of memory cache in bytes.
 	Size uint64 `json:"size"`
@@ -134,6 +163,42 @@ type DiskInfo struct {
 
 	// I/O Scheduler - one of "none", "noop", "cfq", "deadline"
 	Scheduler string `json:"scheduler"`
+
+	// Whether the device is rotational, i.e. a spinning disk.
+	Rotational bool `json:"rotational"`
+
+	// Maximum number of requests queued by the block layer.
+	NrRequests uint64 `json:"nr_requests,omitempty"`
+
+	// Maximum number of commands queued by the device, e.g. the SCSI or SATA
+	// NCQ queue depth. Zero if the device does not report it, as NVMe
+	// devices do not.
+	QueueDepth uint64 `json:"queue_depth,omitempty"`
+
+	// Write cache mode, "write back" or "write through".
+	WriteCache string `json:"write_cache,omitempty"`
+
+	// Whether the device supports discard (TRIM/UNMAP).
+	Discard bool `json:"discard"`
+
+	// Address of the PCI device backing the disk, e.g. the NVMe controller.
+	// Empty for virtual devices.
+	PCIAddress string `json:"pci_address,omitempty"`
+
+	// Name of the device mapper device, e.g. vg0-root, empty for other disks.
+	DmName string `json:"dm_name,omitempty"`
+
+	// Subsystem which created the device mapper device, from the prefix of
+	// its UUID, e.g. "LVM", "CRYPT" or "mpath".
+	DmType string `json:"dm_type,omitempty"`
+
+	// Devices or partitions the disk is built on, e.g. the physical volumes
+	// of an LVM volume or the paths of a multipath device.
+	Slaves []string `json:"slaves,omitempty"`
+
+	// Physical disks at the bottom of the device stack, e.g. sda for an LVM
+	// volume on a LUKS device on sda2. Empty for physical disks.
+	PhysicalDisks []string `json:"physical_disks,omitempty"`
 }
 
 type NetInfo struct {
@@ -148,6 +213,10 @@ type NetInfo struct {
 
 	// Maximum Transmission Unit
 	Mtu int64 `json:"mtu"`
+
+	// Address of the PCI device backing the interface. Empty for virtual
+	// devices.
+	PCIAddress string `json:"pci_address,omitempty"`
 }
 
 type CloudProvider string
@@ -156,6 +225,7 @@ const (
 	GCE             CloudProvider = "GCE"
 	AWS             CloudProvider = "AWS"
 	Azure           CloudProvider = "Azure"
+	OpenStack       CloudProvider = "OpenStack"
 	UnknownProvider CloudProvider = "Unknown"
 )
 
@@ -207,6 +277,33 @@ type MachineInfo struct {
 	// The boot id
 	BootID string `json:"boot_id"`
 
+	// The command line the kernel was booted with.
+	KernelCmdline string `json:"kernel_cmdline,omitempty"`
+
+	// Values of sysctls and transparent hugepage settings commonly tuned for
+	// containers, keyed by sysctl name, e.g. "vm.swappiness" or
+	// "transparent_hugepage.enabled".
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+
+	// PRETTY_NAME of the host's os-release.
+	OSPrettyName string `json:"os_pretty_name,omitempty"`
+
+	// Flags of the kernel taint, e.g. "O" for a loaded out-of-tree module.
+	// Empty if the kernel is not tainted.
+	KernelTaintFlags []string `json:"kernel_taint_flags,omitempty"`
+
+	// Active Linux security modules in the order they were initialized.
+	SecurityModules []string `json:"security_modules,omitempty"`
+
+	// SELinux mode: enforcing, permissive or disabled.
+	SELinuxMode string `json:"selinux_mode,omitempty"`
+
+	// AppArmor mode: enforce, complain or disabled.
+	AppArmorMode string `json:"apparmor_mode,omitempty"`
+
+	// Mode of the cgroup hierarchy: legacy, hybrid or unified.
+	CgroupMode string `json:"cgroup_mode,omitempty"`
+
 	// Filesystems on this machine.
 	Filesystems []FsInfo `json:"filesystems"`
 
@@ -220,6 +317,14 @@ type MachineInfo struct {
 	// Describes cpu/memory layout and hierarchy.
 	Topology []Node `json:"topology"`
 
+	// PCI devices of the machine, sorted by address. Disks and network
+	// devices refer to them by their PCI address.
+	PCIDevices []PCIDevice `json:"pci_devices,omitempty"`
+
+	// Hardware inventory reported by the firmware, nil if the platform has
+	// no DMI (e.g. most ARM machines).
+	Hardware *HardwareInfo `json:"hardware,omitempty"`
+
 	// Cloud provider the machine belongs to.
 	CloudProvider CloudProvider `json:"cloud_provider"`
 
@@ -228,6 +333,66 @@ type MachineInfo struct {
 
 	// ID of cloud instance (e.g. instance-1) given to it by the cloud provider.
 	InstanceID InstanceID `json:"instance_id"`
+
+	// Additional information registered by machine info providers, keyed by
+	// the name of the provider.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+
+	// Sections of the machine information that could not be gathered.
+	Errors []MachineInfoError `json:"errors,omitempty"`
+}
+
+type PCIDevice struct {
+	// Address in domain:bus:device.function notation, e.g. 0000:3b:00.0.
+	Address string `json:"address"`
+
+	// Class code, e.g. 0x020000 for Ethernet controllers or 0x030200 for 3D
+	// controllers such as GPUs.
+	Class string `json:"class"`
+
+	// Vendor ID, e.g. 0x8086.
+	Vendor string `json:"vendor_id"`
+
+	// Device ID, e.g. 0x1572.
+	Device string `json:"device_id"`
+
+	// NUMA node the device is attached to, -1 if the platform does not
+	// report it.
+	NumaNode int `json:"numa_node"`
+
+	// IOMMU group of the device, -1 if the IOMMU is disabled.
+	IommuGroup int `json:"iommu_group"`
+
+	// Kernel driver bound to the device, empty if there is none.
+	Driver string `json:"driver,omitempty"`
+}
+
+// HardwareInfo is the inventory of the machine from its DMI (SMBIOS) tables.
+// Fields the firmware leaves unset or filled with placeholders such as
+// "To Be Filled By O.E.M." are empty. Serial numbers are only readable by
+// root.
+type HardwareInfo struct {
+	SystemVendor    string `json:"system_vendor,omitempty"`
+	ProductName     string `json:"product_name,omitempty"`
+	ProductVersion  string `json:"product_version,omitempty"`
+	SerialNumber    string `json:"serial_number,omitempty"`
+	BoardVendor     string `json:"board_vendor,omitempty"`
+	BoardName       string `json:"board_name,omitempty"`
+	BoardSerial     string `json:"board_serial,omitempty"`
+	ChassisAssetTag string `json:"chassis_asset_tag,omitempty"`
+	BIOSVendor      string `json:"bios_vendor,omitempty"`
+	BIOSVersion     string `json:"bios_version,omitempty"`
+	// Release date of the BIOS as reported, usually MM/DD/YYYY.
+	BIOSDate string `json:"bios_date,omitempty"`
+}
+
+// MachineInfoError describes a section of the machine information that failed
+// to be gathered. The remaining sections are still reported.
+type MachineInfoError struct {
+	// Name of the section, e.g. "topology".
+	Section string `json:"section"`
+	// Description of the failure.
+	Message string `json:"message"`
 }
 
 func (m *MachineInfo) Clone() *MachineInfo {
@@ -245,6 +410,20 @@ func (m *MachineInfo) Clone() *MachineInfo {
 			diskMap[k] = info
 		}
 	}
+	sysctls := m.Sysctls
+	if len(m.Sysctls) > 0 {
+		sysctls = make(map[string]string, len(m.Sysctls))
+		for k, v := range m.Sysctls {
+			sysctls[k] = v
+		}
+	}
+	extensions := m.Extensions
+	if len(m.Extensions) > 0 {
+		extensions = make(map[string]json.RawMessage, len(m.Extensions))
+		for k, v := range m.Extensions {
+			extensions[k] = v
+		}
+	}
 	copy := MachineInfo{
 		Timestamp:        m.Timestamp,
 		NumCores:         m.NumCores,
@@ -258,13 +437,25 @@ func (m *MachineInfo) Clone() *MachineInfo {
 		MachineID:        m.MachineID,
 		SystemUUID:       m.SystemUUID,
 		BootID:           m.BootID,
+		KernelCmdline:    m.KernelCmdline,
+		Sysctls:          sysctls,
+		OSPrettyName:     m.OSPrettyName,
+		KernelTaintFlags: m.KernelTaintFlags,
+		SecurityModules:  m.SecurityModules,
+		SELinuxMode:      m.SELinuxMode,
+		AppArmorMode:     m.AppArmorMode,
+		CgroupMode:       m.CgroupMode,
 		Filesystems:      m.Filesystems,
 		DiskMap:          diskMap,
 		NetworkDevices:   m.NetworkDevices,
 		Topology:         m.Topology,
+		PCIDevices:       m.PCIDevices,
+		Hardware:         m.Hardware,
 		CloudProvider:    m.CloudProvider,
 		InstanceType:     m.InstanceType,
 		InstanceID:       m.InstanceID,
+		Extensions:       extensions,
+		Errors:           m.Errors,
 	}
 	return &copy
 }
@@ -286,6 +477,45 @@ type NVMInfo struct {
 
 	// Average power budget in watts for NVM devices configured in BIOS.
 	AvgPowerBudget uint `json:"avg_power_budget"`
+
+	// Persistent memory regions, i.e. App Direct capacity, and the namespaces
+	// configured in them as reported by the kernel.
+	Regions []PmemRegion `json:"regions,omitempty"`
+}
+
+type PmemRegion struct {
+	// Region id, e.g. 0 for region0.
+	Id int `json:"id"`
+
+	// Capacity of the region in bytes.
+	Size uint64 `json:"size"`
+
+	// Capacity of the region in bytes not yet allocated to namespaces.
+	AvailableSize uint64 `json:"available_size"`
+
+	// NUMA node the region is attached to, -1 if unknown.
+	NumaNode int `json:"numa_node"`
+
+	// Namespaces allocated in the region.
+	Namespaces []PmemNamespace `json:"namespaces,omitempty"`
+}
+
+type PmemNamespace struct {
+	// Namespace name, e.g. namespace0.0.
+	Name string `json:"name"`
+
+	// Mode of the namespace - one of "fsdax", "devdax", "sector", "raw".
+	Mode string `json:"mode"`
+
+	// Capacity of the namespace in bytes.
+	Size uint64 `json:"size"`
+
+	// UUID of the namespace, empty for label-less namespaces.
+	UUID string `json:"uuid,omitempty"`
+
+	// Device exposing the namespace, e.g. /dev/pmem0 or /dev/dax0.0. Filesystem
+	// stats of mounted fsdax namespaces are reported for this device.
+	Device string `json:"device,omitempty"`
 }
 
 type VersionInfo struct {
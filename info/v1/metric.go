@@ -50,6 +50,10 @@ type MetricSpec struct {
 
 	// Display Units for the stats.
 	Units string `json:"units"`
+
+	// Whether the labels of the metric are exported as is rather than
+	// prefixed, overriding container labels of the same name.
+	HonorLabels bool `json:"honor_labels,omitempty"`
 }
 
 // An exported metric.
@@ -40,6 +40,12 @@ type CpuSpec struct {
 	Quota uint64 `json:"quota,omitempty"`
 	// Period is the CPU reference time in ns e.g the quota is compared against this.
 	Period uint64 `json:"period,omitempty"`
+	// CPUs the container can actually run on.
+	EffectiveMask string `json:"effective_mask,omitempty"`
+	// Memory nodes the container can allocate memory from.
+	MemoryNodes string `json:"memory_nodes,omitempty"`
+	// CPUs of the effective mask isolated by isolcpus or nohz_full.
+	IsolatedCpus string `json:"isolated_cpus,omitempty"`
 }
 
 type MemorySpec struct {
@@ -102,6 +108,17 @@ type ContainerSpec struct {
 
 	// Image name used for this container.
 	Image string `json:"image,omitempty"`
+
+	// Runtime state of the container, only set by runtimes reporting it.
+	State *v1.ContainerState `json:"state,omitempty"`
+
+	// Mounts visible to the processes of the container.
+	Mounts []v1.MountSpec `json:"mounts,omitempty"`
+
+	// ID of the container this container was restored from with CRIU.
+	RestoredFrom string `json:"restored_from,omitempty"`
+	// ID of the first container of the chain of restores of this container.
+	ContinuityId string `json:"continuity_id,omitempty"`
 }
 
 type DeprecatedContainerStats struct {
@@ -146,6 +163,8 @@ type DeprecatedContainerStats struct {
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
 	// Resource Control (resctrl) statistics
 	Resctrl v1.ResctrlStats `json:"resctrl,omitempty"`
+	// Statistics of the gVisor Sentry, only set for gVisor sandboxes
+	Gvisor *v1.GvisorStats `json:"gvisor,omitempty"`
 }
 
 type ContainerStats struct {
@@ -183,6 +202,72 @@ type ContainerStats struct {
 	ReferencedMemory uint64 `json:"referenced_memory,omitempty"`
 	// Resource Control (resctrl) statistics
 	Resctrl v1.ResctrlStats `json:"resctrl,omitempty"`
+	// Statistics of the gVisor Sentry, only set for gVisor sandboxes
+	Gvisor *v1.GvisorStats `json:"gvisor,omitempty"`
+	// Only set for downsampled stats.
+	Aggregation *StatsAggregation `json:"aggregation,omitempty"`
+	// Rates since the previous sample, only set with the DerivedRate option.
+	Rates *RateStats `json:"rates,omitempty"`
+}
+
+// RateStats are the rates of the counters of a sample since the previous
+// one. Resources whose counters decreased, e.g. because a network interface
+// was removed, are left out.
+type RateStats struct {
+	// Time since the previous sample.
+	Interval time.Duration `json:"interval"`
+	// CPU usage, in cores.
+	CpuUsage *float64 `json:"cpu_usage,omitempty"`
+	// Network throughput summed over the interfaces.
+	Network *NetworkRates `json:"network,omitempty"`
+	// Disk throughput summed over the devices.
+	DiskIo *DiskIoRates `json:"diskio,omitempty"`
+}
+
+type NetworkRates struct {
+	// In bytes per second.
+	RxBytes float64 `json:"rx_bytes"`
+	TxBytes float64 `json:"tx_bytes"`
+	// In packets per second.
+	RxPackets float64 `json:"rx_packets"`
+	TxPackets float64 `json:"tx_packets"`
+}
+
+type DiskIoRates struct {
+	// In bytes per second.
+	ReadBytes  float64 `json:"read_bytes"`
+	WriteBytes float64 `json:"write_bytes"`
+	// In operations per second.
+	ReadOps  float64 `json:"read_ops"`
+	WriteOps float64 `json:"write_ops"`
+}
+
+// StatsAggregation describes stats downsampled over an interval. Their
+// memory, load average and process counts are averaged over the samples of
+// the interval, while the other stats are those of its last sample.
+type StatsAggregation struct {
+	// Start of the interval.
+	Start time.Time `json:"start"`
+	// Length of the interval.
+	Interval time.Duration `json:"interval"`
+	// Number of samples in the interval.
+	Samples int `json:"samples"`
+	// Maximum values over the interval.
+	Max MaxStats `json:"max"`
+}
+
+type MaxStats struct {
+	// CPU usage between two samples, in nanocores.
+	CpuUsage uint64 `json:"cpu_usage"`
+	// In bytes.
+	MemoryUsage uint64 `json:"memory_usage"`
+	// In bytes.
+	MemoryWorkingSet uint64 `json:"memory_working_set"`
+	// In bytes.
+	MemoryRSS           uint64 `json:"memory_rss"`
+	LoadAverage         int32  `json:"load_average"`
+	ProcessCount        uint64 `json:"process_count"`
+	FileDescriptorCount uint64 `json:"fd_count"`
 }
 
 type Percentiles struct {
@@ -199,6 +284,13 @@ type Percentiles struct {
 	Ninety uint64 `json:"ninety"`
 	// 95th percentile over the collected sample.
 	NinetyFive uint64 `json:"ninetyfive"`
+	// 99th percentile over the collected sample.
+	NinetyNine uint64 `json:"ninetynine"`
+	// 99.9th percentile over the collected sample.
+	NinetyNineNine uint64 `json:"ninetyninenine"`
+	// Additional quantiles configured with --summary_quantiles, keyed by
+	// quantile (e.g. "0.75").
+	Quantiles map[string]uint64 `json:"quantiles,omitempty"`
 }
 
 type Usage struct {
@@ -231,6 +323,9 @@ type DerivedStats struct {
 	HourUsage Usage `json:"hour_usage"`
 	// Percentile in last day.
 	DayUsage Usage `json:"day_usage"`
+	// Percentiles in the windows configured with --summary_windows, keyed by
+	// window (e.g. "5m", "6h").
+	Windows map[string]Usage `json:"windows,omitempty"`
 }
 
 type FsInfo struct {
@@ -262,6 +357,34 @@ type FsInfo struct {
 	InodesFree *uint64 `json:"inodes_free,omitempty"`
 }
 
+// StorageBreakdown is the usage of the writable layer of a container broken
+// down by directory.
+type StorageBreakdown struct {
+	// Time the breakdown was computed.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Usage of the root directory of the writable layer.
+	Usage DirUsage `json:"usage"`
+
+	// Whether the walk stopped at the limit on visited files, the usage is
+	// incomplete if set.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type DirUsage struct {
+	// Path relative to the root of the container filesystem, "/" for the root.
+	Path string `json:"path"`
+
+	// Number of bytes used by the directory and its contents.
+	Bytes uint64 `json:"bytes"`
+
+	// Number of inodes used by the directory and its contents.
+	Inodes uint64 `json:"inodes"`
+
+	// Usage of the subdirectories, ordered by decreasing size.
+	Children []DirUsage `json:"children,omitempty"`
+}
+
 type RequestOptions struct {
 	// Type of container identifier specified - "name", "dockerid", dockeralias"
 	IdType string `json:"type"`
@@ -272,8 +395,25 @@ type RequestOptions struct {
 	// Update stats if they are older than MaxAge
 	// nil indicates no update, and 0 will always trigger an update.
 	MaxAge *time.Duration `json:"max_age"`
+	// Only return the containers matching the filter, nil matches all containers.
+	Filter *ContainerFilter `json:"filter,omitempty"`
+	// Resolution of the stats, ResolutionRaw or ResolutionDownsampled.
+	// Empty means ResolutionRaw.
+	Resolution string `json:"resolution,omitempty"`
+	// Values derived from consecutive stats, DerivedRate or empty for none.
+	Derived string `json:"derived,omitempty"`
 }
 
+const (
+	// Stats as collected.
+	ResolutionRaw = "raw"
+	// Stats downsampled over the downsampling interval.
+	ResolutionDownsampled = "downsampled"
+)
+
+// Rates of the counters since the previous stats.
+const DerivedRate = "rate"
+
 type ProcessInfo struct {
 	User          string  `json:"user"`
 	Pid           int     `json:"pid"`
@@ -289,6 +429,15 @@ type ProcessInfo struct {
 	Cmd           string  `json:"cmd"`
 	FdCount       int     `json:"fd_count"`
 	Psr           int     `json:"psr"`
+	// Cumulative CPU time of the process.
+	CpuTimeSeconds uint64 `json:"cpu_time_seconds"`
+	ThreadCount    int    `json:"thread_count"`
+	// Scheduling class as reported by ps, e.g. TS, FF or RR.
+	SchedulingPolicy string `json:"scheduling_policy"`
+	// Bytes read from and written to storage by the process, from
+	// /proc/<pid>/io.
+	ReadBytes  uint64 `json:"read_bytes"`
+	WriteBytes uint64 `json:"write_bytes"`
 }
 
 type TcpStat struct {
@@ -318,6 +467,8 @@ type NetworkStats struct {
 	Udp6 v1.UdpStat `json:"udp6"`
 	// TCP advanced stats
 	TcpAdvanced v1.TcpAdvancedStat `json:"tcp_advanced"`
+	// Per-queue statistics of physical network devices
+	Queues []v1.InterfaceQueueStats `json:"queues,omitempty"`
 }
 
 // Instantaneous CPU stats
@@ -355,3 +506,11 @@ type FilesystemStats struct {
 	// and does not include inodes used in mounted directories.
 	InodeUsage *uint64 `json:"containter_inode_usage,omitempty"`
 }
+
+// AdHocWatch is a cgroup monitored on request until it expires.
+type AdHocWatch struct {
+	// Name of the cgroup, e.g. /system.slice/run-r1234.scope.
+	Name string `json:"name"`
+	// Time the cgroup stops being monitored unless the watch is renewed.
+	Expires time.Time `json:"expires"`
+}
@@ -31,11 +31,12 @@ func machineFsStatsFromV1(fsStats []v1.FsStats) []MachineFsStats {
 		ioDuration := time.Millisecond * time.Duration(stat.IoTime)
 		weightedDuration := time.Millisecond * time.Duration(stat.WeightedIoTime)
 		machineFsStat := MachineFsStats{
-			Device:    stat.Device,
-			Type:      stat.Type,
-			Capacity:  &stat.Limit,
-			Usage:     &stat.Usage,
-			Available: &stat.Available,
+			Device:     stat.Device,
+			Mountpoint: stat.Mountpoint,
+			Type:       stat.Type,
+			Capacity:   &stat.Limit,
+			Usage:      &stat.Usage,
+			Available:  &stat.Available,
 			DiskStats: DiskStats{
 				ReadsCompleted:     &stat.ReadsCompleted,
 				ReadsMerged:        &stat.ReadsMerged,
@@ -85,11 +86,17 @@ func MachineStatsFromV1(cont *v1.ContainerInfo) []MachineStats {
 				Tcp:        TcpStat(val.Network.Tcp),
 				Tcp6:       TcpStat(val.Network.Tcp6),
 				Interfaces: val.Network.Interfaces,
+				Queues:     val.Network.Queues,
 			}
 		}
 		if cont.Spec.HasFilesystem {
 			stat.Filesystem = machineFsStatsFromV1(val.Filesystem)
 		}
+		stat.Ksm = val.Ksm
+		stat.CpuFreq = val.CpuFreq
+		stat.Energy = val.Energy
+		stat.Thermal = val.Thermal
+		stat.ThermalThrottle = val.ThermalThrottle
 		// TODO(rjnagal): Handle load stats.
 		stats = append(stats, stat)
 	}
@@ -126,6 +133,7 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 				Tcp:        TcpStat(val.Network.Tcp),
 				Tcp6:       TcpStat(val.Network.Tcp6),
 				Interfaces: val.Network.Interfaces,
+				Queues:     val.Network.Queues,
 			}
 		}
 		if spec.HasProcesses {
@@ -161,6 +169,7 @@ func ContainerStatsFromV1(containerName string, spec *v1.ContainerSpec, stats []
 		if len(val.Resctrl.MemoryBandwidth) > 0 || len(val.Resctrl.Cache) > 0 {
 			stat.Resctrl = val.Resctrl
 		}
+		stat.Gvisor = val.Gvisor
 		// TODO(rjnagal): Handle load stats.
 		newStats = append(newStats, stat)
 	}
@@ -222,6 +231,7 @@ func DeprecatedStatsFromV1(cont *v1.ContainerInfo) []DeprecatedContainerStats {
 		if len(val.Resctrl.MemoryBandwidth) > 0 || len(val.Resctrl.Cache) > 0 {
 			stat.Resctrl = val.Resctrl
 		}
+		stat.Gvisor = val.Gvisor
 		// TODO(rjnagal): Handle load stats.
 		stats = append(stats, stat)
 	}
@@ -280,6 +290,80 @@ func InstCpuStats(last, cur *v1.ContainerStats) (*CpuInstStats, error) {
 	}, nil
 }
 
+// AddRateStats sets the rates of the counters of stats, sorted oldest
+// first, since the previous stats. The first stats have none.
+func AddRateStats(stats []*ContainerStats) {
+	for i := 1; i < len(stats); i++ {
+		stats[i].Rates = InstRateStats(stats[i-1], stats[i])
+	}
+}
+
+// InstRateStats returns the rates of the counters of cur since last, nil if
+// cur is not more recent than last.
+func InstRateStats(last, cur *ContainerStats) *RateStats {
+	if !cur.Timestamp.After(last.Timestamp) {
+		return nil
+	}
+	interval := cur.Timestamp.Sub(last.Timestamp)
+	rate := func(lastValue, curValue uint64) (float64, bool) {
+		if curValue < lastValue {
+			return 0, false
+		}
+		return float64(curValue-lastValue) / interval.Seconds(), true
+	}
+	rates := &RateStats{Interval: interval}
+	if last.Cpu != nil && cur.Cpu != nil {
+		if r, ok := rate(last.Cpu.Usage.Total, cur.Cpu.Usage.Total); ok {
+			// Nanoseconds of CPU time per second.
+			r /= 1e9
+			rates.CpuUsage = &r
+		}
+	}
+	if last.Network != nil && cur.Network != nil {
+		lastNet, curNet := sumInterfaces(last.Network.Interfaces), sumInterfaces(cur.Network.Interfaces)
+		var net NetworkRates
+		var rxBytes, txBytes, rxPackets, txPackets bool
+		net.RxBytes, rxBytes = rate(lastNet.RxBytes, curNet.RxBytes)
+		net.TxBytes, txBytes = rate(lastNet.TxBytes, curNet.TxBytes)
+		net.RxPackets, rxPackets = rate(lastNet.RxPackets, curNet.RxPackets)
+		net.TxPackets, txPackets = rate(lastNet.TxPackets, curNet.TxPackets)
+		if rxBytes && txBytes && rxPackets && txPackets {
+			rates.Network = &net
+		}
+	}
+	if last.DiskIo != nil && cur.DiskIo != nil {
+		var disk DiskIoRates
+		var readBytes, writeBytes, readOps, writeOps bool
+		disk.ReadBytes, readBytes = rate(sumDiskStats(last.DiskIo.IoServiceBytes, "Read"), sumDiskStats(cur.DiskIo.IoServiceBytes, "Read"))
+		disk.WriteBytes, writeBytes = rate(sumDiskStats(last.DiskIo.IoServiceBytes, "Write"), sumDiskStats(cur.DiskIo.IoServiceBytes, "Write"))
+		disk.ReadOps, readOps = rate(sumDiskStats(last.DiskIo.IoServiced, "Read"), sumDiskStats(cur.DiskIo.IoServiced, "Read"))
+		disk.WriteOps, writeOps = rate(sumDiskStats(last.DiskIo.IoServiced, "Write"), sumDiskStats(cur.DiskIo.IoServiced, "Write"))
+		if readBytes && writeBytes && readOps && writeOps {
+			rates.DiskIo = &disk
+		}
+	}
+	return rates
+}
+
+func sumInterfaces(interfaces []v1.InterfaceStats) v1.InterfaceStats {
+	var sum v1.InterfaceStats
+	for _, i := range interfaces {
+		sum.RxBytes += i.RxBytes
+		sum.TxBytes += i.TxBytes
+		sum.RxPackets += i.RxPackets
+		sum.TxPackets += i.TxPackets
+	}
+	return sum
+}
+
+func sumDiskStats(stats []v1.PerDiskStats, key string) uint64 {
+	var sum uint64
+	for _, s := range stats {
+		sum += s.Stats[key]
+	}
+	return sum
+}
+
 // Get V2 container spec from v1 container info.
 func ContainerSpecFromV1(specV1 *v1.ContainerSpec, aliases []string, namespace string) ContainerSpec {
 	specV2 := ContainerSpec{
@@ -295,11 +379,18 @@ func ContainerSpecFromV1(specV1 *v1.ContainerSpec, aliases []string, namespace s
 		Image:            specV1.Image,
 		Labels:           specV1.Labels,
 		Envs:             specV1.Envs,
+		State:            specV1.State,
+		Mounts:           specV1.Mounts,
+		RestoredFrom:     specV1.RestoredFrom,
+		ContinuityId:     specV1.ContinuityId,
 	}
 	if specV1.HasCpu {
 		specV2.Cpu.Limit = specV1.Cpu.Limit
 		specV2.Cpu.MaxLimit = specV1.Cpu.MaxLimit
 		specV2.Cpu.Mask = specV1.Cpu.Mask
+		specV2.Cpu.EffectiveMask = specV1.Cpu.EffectiveMask
+		specV2.Cpu.MemoryNodes = specV1.Cpu.MemoryNodes
+		specV2.Cpu.IsolatedCpus = specV1.Cpu.IsolatedCpus
 	}
 	if specV1.HasMemory {
 		specV2.Memory.Limit = specV1.Memory.Limit
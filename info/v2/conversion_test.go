@@ -441,3 +441,48 @@ func TestInstCpuStats(t *testing.T) {
 		assert.Equal(t, c.want, got)
 	}
 }
+
+func TestAddRateStats(t *testing.T) {
+	diskStats := func(read, write uint64) []v1.PerDiskStats {
+		return []v1.PerDiskStats{
+			{Device: "sda", Stats: map[string]uint64{"Read": read, "Write": write}},
+			{Device: "sdb", Stats: map[string]uint64{"Read": read, "Write": write}},
+		}
+	}
+	stats := []*ContainerStats{
+		{
+			Timestamp: timestamp,
+			Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 1e9}},
+			Network: &NetworkStats{Interfaces: []v1.InterfaceStats{
+				{Name: "eth0", RxBytes: 1000, TxBytes: 2000, RxPackets: 10, TxPackets: 20},
+			}},
+			DiskIo: &v1.DiskIoStats{IoServiceBytes: diskStats(4096, 8192), IoServiced: diskStats(1, 2)},
+		},
+		{
+			Timestamp: timestamp.Add(2 * time.Second),
+			Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 4e9}},
+			Network: &NetworkStats{Interfaces: []v1.InterfaceStats{
+				{Name: "eth0", RxBytes: 3000, TxBytes: 6000, RxPackets: 30, TxPackets: 60},
+			}},
+			DiskIo: &v1.DiskIoStats{IoServiceBytes: diskStats(8192, 8192), IoServiced: diskStats(2, 2)},
+		},
+		{
+			// The interface was removed.
+			Timestamp: timestamp.Add(3 * time.Second),
+			Cpu:       &v1.CpuStats{Usage: v1.CpuUsage{Total: 4e9}},
+			Network:   &NetworkStats{},
+		},
+	}
+	AddRateStats(stats)
+
+	assert.Nil(t, stats[0].Rates)
+	cpu := 1.5
+	assert.Equal(t, &RateStats{
+		Interval: 2 * time.Second,
+		CpuUsage: &cpu,
+		Network:  &NetworkRates{RxBytes: 1000, TxBytes: 2000, RxPackets: 10, TxPackets: 20},
+		DiskIo:   &DiskIoRates{ReadBytes: 4096, ReadOps: 1},
+	}, stats[1].Rates)
+	idle := 0.0
+	assert.Equal(t, &RateStats{Interval: time.Second, CpuUsage: &idle}, stats[2].Rates)
+}
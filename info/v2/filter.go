@@ -0,0 +1,133 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cadvisor/info/v1"
+)
+
+// ContainerFilter selects containers by name, Kubernetes namespace and labels.
+// Empty fields match all containers.
+type ContainerFilter struct {
+	// Name or alias of the container.
+	Container string `json:"container,omitempty"`
+	// Kubernetes namespace of the pod the container belongs to.
+	Namespace string `json:"namespace,omitempty"`
+	// Requirements on the container labels, all of which must be met.
+	Selector []LabelRequirement `json:"selector,omitempty"`
+}
+
+type LabelOperator string
+
+const (
+	LabelEquals    LabelOperator = "="
+	LabelNotEquals LabelOperator = "!="
+	LabelExists    LabelOperator = "exists"
+	LabelNotExists LabelOperator = "!"
+)
+
+// LabelRequirement is a single requirement of a label selector.
+type LabelRequirement struct {
+	Key      string        `json:"key"`
+	Operator LabelOperator `json:"operator"`
+	Value    string        `json:"value,omitempty"`
+}
+
+// ParseLabelSelector parses a comma-separated label selector in the syntax
+// used by Kubernetes for equality-based requirements, e.g.
+// "app=web,tier!=cache,release,!canary".
+func ParseLabelSelector(selector string) ([]LabelRequirement, error) {
+	var requirements []LabelRequirement
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		var r LabelRequirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = LabelRequirement{Key: parts[0], Operator: LabelNotEquals, Value: parts[1]}
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			r = LabelRequirement{Key: parts[0], Operator: LabelEquals, Value: parts[1]}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			r = LabelRequirement{Key: parts[0], Operator: LabelEquals, Value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			r = LabelRequirement{Key: strings.TrimPrefix(term, "!"), Operator: LabelNotExists}
+		default:
+			r = LabelRequirement{Key: term, Operator: LabelExists}
+		}
+		r.Key = strings.TrimSpace(r.Key)
+		r.Value = strings.TrimSpace(r.Value)
+		if r.Key == "" {
+			return nil, fmt.Errorf("invalid label selector term %q: empty label name", term)
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// Matches reports whether a container with the given reference and labels
+// passes the filter.
+func (f *ContainerFilter) Matches(ref v1.ContainerReference, labels map[string]string) bool {
+	if f == nil {
+		return true
+	}
+	if f.Container != "" && !referenceHasName(ref, f.Container) {
+		return false
+	}
+	if f.Namespace != "" && labels[PodNamespaceLabel] != f.Namespace {
+		return false
+	}
+	for _, r := range f.Selector {
+		value, ok := labels[r.Key]
+		switch r.Operator {
+		case LabelEquals:
+			if !ok || value != r.Value {
+				return false
+			}
+		case LabelNotEquals:
+			if ok && value == r.Value {
+				return false
+			}
+		case LabelExists:
+			if !ok {
+				return false
+			}
+		case LabelNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func referenceHasName(ref v1.ContainerReference, name string) bool {
+	if ref.Name == name {
+		return true
+	}
+	for _, alias := range ref.Aliases {
+		if alias == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/google/cadvisor/info/v1"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	requirements, err := ParseLabelSelector("app=web, tier!=cache,release==stable,canary,!debug")
+	assert.Nil(t, err)
+	assert.Equal(t, []LabelRequirement{
+		{Key: "app", Operator: LabelEquals, Value: "web"},
+		{Key: "tier", Operator: LabelNotEquals, Value: "cache"},
+		{Key: "release", Operator: LabelEquals, Value: "stable"},
+		{Key: "canary", Operator: LabelExists},
+		{Key: "debug", Operator: LabelNotExists},
+	}, requirements)
+
+	requirements, err = ParseLabelSelector("")
+	assert.Nil(t, err)
+	assert.Empty(t, requirements)
+
+	_, err = ParseLabelSelector("=web")
+	assert.NotNil(t, err)
+}
+
+func TestContainerFilterMatches(t *testing.T) {
+	ref := v1.ContainerReference{Name: "/docker/0123abcd", Aliases: []string{"web-1", "0123abcd"}}
+	labels := map[string]string{PodNamespaceLabel: "default", "app": "web", "canary": "true"}
+
+	var nilFilter *ContainerFilter
+	assert.True(t, nilFilter.Matches(ref, labels))
+
+	for _, tc := range []struct {
+		selector string
+		filter   ContainerFilter
+		expected bool
+	}{
+		{filter: ContainerFilter{}, expected: true},
+		{filter: ContainerFilter{Container: "/docker/0123abcd"}, expected: true},
+		{filter: ContainerFilter{Container: "web-1"}, expected: true},
+		{filter: ContainerFilter{Container: "web-2"}, expected: false},
+		{filter: ContainerFilter{Namespace: "default"}, expected: true},
+		{filter: ContainerFilter{Namespace: "kube-system"}, expected: false},
+		{selector: "app=web,canary", expected: true},
+		{selector: "app!=web", expected: false},
+		{selector: "app=db", expected: false},
+		{selector: "tier!=cache,!debug", expected: true},
+		{selector: "!canary", expected: false},
+		{selector: "tier", expected: false},
+	} {
+		filter := tc.filter
+		if tc.selector != "" {
+			requirements, err := ParseLabelSelector(tc.selector)
+			assert.Nil(t, err)
+			filter.Selector = requirements
+		}
+		assert.Equal(t, tc.expected, filter.Matches(ref, labels), "%+v %q", tc.filter, tc.selector)
+	}
+}
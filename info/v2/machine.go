@@ -68,6 +68,33 @@ type Attributes struct {
 	// Describes cpu/memory layout and hierarchy.
 	Topology []v1.Node `json:"topology"`
 
+	// The command line the kernel was booted with.
+	KernelCmdline string `json:"kernel_cmdline,omitempty"`
+
+	// Sysctls commonly tuned for containers.
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+
+	// PRETTY_NAME of the host's os-release.
+	OSPrettyName string `json:"os_pretty_name,omitempty"`
+
+	// Flags of the kernel taint, empty if the kernel is not tainted.
+	KernelTaintFlags []string `json:"kernel_taint_flags,omitempty"`
+
+	// Active Linux security modules.
+	SecurityModules []string `json:"security_modules,omitempty"`
+
+	// SELinux mode: enforcing, permissive or disabled.
+	SELinuxMode string `json:"selinux_mode,omitempty"`
+
+	// AppArmor mode: enforce, complain or disabled.
+	AppArmorMode string `json:"apparmor_mode,omitempty"`
+
+	// Mode of the cgroup hierarchy: legacy, hybrid or unified.
+	CgroupMode string `json:"cgroup_mode,omitempty"`
+
+	// PCI devices of the machine.
+	PCIDevices []v1.PCIDevice `json:"pci_devices,omitempty"`
+
 	// Cloud provider the machine belongs to
 	CloudProvider v1.CloudProvider `json:"cloud_provider"`
 
@@ -92,6 +119,15 @@ func GetAttributes(mi *v1.MachineInfo, vi *v1.VersionInfo) Attributes {
 		DiskMap:            mi.DiskMap,
 		NetworkDevices:     mi.NetworkDevices,
 		Topology:           mi.Topology,
+		PCIDevices:         mi.PCIDevices,
+		KernelCmdline:      mi.KernelCmdline,
+		Sysctls:            mi.Sysctls,
+		OSPrettyName:       mi.OSPrettyName,
+		KernelTaintFlags:   mi.KernelTaintFlags,
+		SecurityModules:    mi.SecurityModules,
+		SELinuxMode:        mi.SELinuxMode,
+		AppArmorMode:       mi.AppArmorMode,
+		CgroupMode:         mi.CgroupMode,
 		CloudProvider:      mi.CloudProvider,
 		InstanceType:       mi.InstanceType,
 	}
@@ -113,6 +149,16 @@ type MachineStats struct {
 	Filesystem []MachineFsStats `json:"filesystem,omitempty"`
 	// Task load statistics
 	Load *v1.LoadStats `json:"load_stats,omitempty"`
+	// Kernel samepage merging statistics
+	Ksm *v1.KsmStats `json:"ksm,omitempty"`
+	// CPU frequency scaling statistics
+	CpuFreq *v1.CpuFreqStats `json:"cpu_freq,omitempty"`
+	// Energy consumed by the RAPL domains of the machine
+	Energy *v1.EnergyStats `json:"energy,omitempty"`
+	// Temperatures and fan speeds
+	Thermal *v1.ThermalStats `json:"thermal,omitempty"`
+	// Thermal throttling counters
+	ThermalThrottle *v1.ThermalThrottleStats `json:"thermal_throttle,omitempty"`
 }
 
 // MachineFsStats contains per filesystem capacity and usage information.
@@ -120,6 +166,9 @@ type MachineFsStats struct {
 	// The block device name associated with the filesystem.
 	Device string `json:"device"`
 
+	// Mount point of the filesystem.
+	Mountpoint string `json:"mountpoint,omitempty"`
+
 	// Type of filesystem.
 	Type string `json:"type"`
 
@@ -0,0 +1,149 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"time"
+
+	"github.com/google/cadvisor/info/v1"
+)
+
+// Labels set by the kubelet on the containers of a pod.
+const (
+	PodUIDLabel       = "io.kubernetes.pod.uid"
+	PodNameLabel      = "io.kubernetes.pod.name"
+	PodNamespaceLabel = "io.kubernetes.pod.namespace"
+	// Name of the container in the pod spec.
+	ContainerNameLabel = "io.kubernetes.container.name"
+)
+
+// PodInfo describes a Kubernetes pod and the containers it consists of.
+type PodInfo struct {
+	UID       string `json:"uid"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// Stats summed over the most recent sample of each container of the pod.
+	Stats PodStats `json:"stats"`
+
+	// Containers of the pod, keyed by container name.
+	Containers map[string]ContainerInfo `json:"containers"`
+}
+
+type PodStats struct {
+	// Time of the most recent container sample included in these stats.
+	Timestamp time.Time `json:"timestamp"`
+	// In nanoseconds (aggregated)
+	Cpu v1.CpuUsage `json:"cpu"`
+	// In nanocores per second (instantaneous)
+	CpuInst CpuInstUsage   `json:"cpu_inst"`
+	Memory  PodMemoryStats `json:"memory"`
+	// Network traffic summed over all interfaces.
+	Network    v1.InterfaceStats  `json:"network"`
+	Filesystem PodFilesystemStats `json:"filesystem"`
+}
+
+type PodMemoryStats struct {
+	// Units: Bytes.
+	Usage      uint64 `json:"usage"`
+	WorkingSet uint64 `json:"working_set"`
+	RSS        uint64 `json:"rss"`
+	Cache      uint64 `json:"cache"`
+}
+
+type PodFilesystemStats struct {
+	// Total number of bytes consumed by the containers.
+	TotalUsageBytes uint64 `json:"totalUsageBytes"`
+	// Number of bytes consumed by the root filesystems of the containers.
+	BaseUsageBytes uint64 `json:"baseUsageBytes"`
+	// Number of inodes used within the root filesystems of the containers.
+	InodeUsage uint64 `json:"inodeUsage"`
+}
+
+// PodsFromV1 groups the containers carrying the pod UID label by pod and sums
+// up their stats. Containers without the label are skipped.
+func PodsFromV1(containers map[string]*v1.ContainerInfo) map[string]PodInfo {
+	pods := map[string]PodInfo{}
+	for name, cont := range containers {
+		uid := cont.Spec.Labels[PodUIDLabel]
+		if uid == "" {
+			continue
+		}
+		pod, ok := pods[uid]
+		if !ok {
+			pod = PodInfo{
+				UID:        uid,
+				Name:       cont.Spec.Labels[PodNameLabel],
+				Namespace:  cont.Spec.Labels[PodNamespaceLabel],
+				Containers: map[string]ContainerInfo{},
+			}
+		}
+		stats := ContainerStatsFromV1(name, &cont.Spec, cont.Stats)
+		pod.Containers[name] = ContainerInfo{
+			Spec:  ContainerSpecFromV1(&cont.Spec, cont.Aliases, cont.Namespace),
+			Stats: stats,
+		}
+		if len(stats) > 0 {
+			pod.Stats.add(stats[len(stats)-1])
+		}
+		pods[uid] = pod
+	}
+	return pods
+}
+
+func (s *PodStats) add(stat *ContainerStats) {
+	if stat.Timestamp.After(s.Timestamp) {
+		s.Timestamp = stat.Timestamp
+	}
+	if stat.Cpu != nil {
+		s.Cpu.Total += stat.Cpu.Usage.Total
+		s.Cpu.User += stat.Cpu.Usage.User
+		s.Cpu.System += stat.Cpu.Usage.System
+	}
+	if stat.CpuInst != nil {
+		s.CpuInst.Total += stat.CpuInst.Usage.Total
+		s.CpuInst.User += stat.CpuInst.Usage.User
+		s.CpuInst.System += stat.CpuInst.Usage.System
+	}
+	if stat.Memory != nil {
+		s.Memory.Usage += stat.Memory.Usage
+		s.Memory.WorkingSet += stat.Memory.WorkingSet
+		s.Memory.RSS += stat.Memory.RSS
+		s.Memory.Cache += stat.Memory.Cache
+	}
+	if stat.Network != nil {
+		for _, iface := range stat.Network.Interfaces {
+			s.Network.RxBytes += iface.RxBytes
+			s.Network.RxPackets += iface.RxPackets
+			s.Network.RxErrors += iface.RxErrors
+			s.Network.RxDropped += iface.RxDropped
+			s.Network.TxBytes += iface.TxBytes
+			s.Network.TxPackets += iface.TxPackets
+			s.Network.TxErrors += iface.TxErrors
+			s.Network.TxDropped += iface.TxDropped
+		}
+	}
+	if stat.Filesystem != nil {
+		if stat.Filesystem.TotalUsageBytes != nil {
+			s.Filesystem.TotalUsageBytes += *stat.Filesystem.TotalUsageBytes
+		}
+		if stat.Filesystem.BaseUsageBytes != nil {
+			s.Filesystem.BaseUsageBytes += *stat.Filesystem.BaseUsageBytes
+		}
+		if stat.Filesystem.InodeUsage != nil {
+			s.Filesystem.InodeUsage += *stat.Filesystem.InodeUsage
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/google/cadvisor/info/v1"
+)
+
+func podContainer(uid string, cpu, memory, rxBytes, fsUsage uint64, hasNetwork bool) *v1.ContainerInfo {
+	return &v1.ContainerInfo{
+		Spec: v1.ContainerSpec{
+			Labels: map[string]string{
+				PodUIDLabel:       uid,
+				PodNameLabel:      "web-" + uid,
+				PodNamespaceLabel: "default",
+			},
+			HasCpu:        true,
+			HasMemory:     true,
+			HasNetwork:    hasNetwork,
+			HasFilesystem: true,
+		},
+		Stats: []*v1.ContainerStats{
+			{
+				Timestamp: timestamp,
+				Cpu:       v1.CpuStats{Usage: v1.CpuUsage{Total: 1, User: 1}},
+			},
+			{
+				Timestamp: timestamp.Add(time.Second),
+				Cpu:       v1.CpuStats{Usage: v1.CpuUsage{Total: cpu, User: cpu / 2, System: cpu / 2}},
+				Memory:    v1.MemoryStats{Usage: memory, WorkingSet: memory / 2},
+				Network: v1.NetworkStats{
+					Interfaces: []v1.InterfaceStats{{Name: "eth0", RxBytes: rxBytes, TxBytes: rxBytes / 2}},
+				},
+				Filesystem: []v1.FsStats{{Usage: fsUsage, BaseUsage: fsUsage / 2, Inodes: 10}},
+			},
+		},
+	}
+}
+
+func TestPodsFromV1(t *testing.T) {
+	containers := map[string]*v1.ContainerInfo{
+		"/kubepods/poda/sandbox": podContainer("a", 100, 1000, 4000, 0, true),
+		"/kubepods/poda/app":     podContainer("a", 200, 2000, 0, 600, false),
+		"/kubepods/podb/app":     podContainer("b", 300, 3000, 0, 800, false),
+		"/system.slice/docker.service": {
+			Spec: v1.ContainerSpec{HasCpu: true},
+		},
+	}
+
+	pods := PodsFromV1(containers)
+	assert.Len(t, pods, 2)
+
+	a := pods["a"]
+	assert.Equal(t, "a", a.UID)
+	assert.Equal(t, "web-a", a.Name)
+	assert.Equal(t, "default", a.Namespace)
+	assert.Len(t, a.Containers, 2)
+	assert.Len(t, a.Containers["/kubepods/poda/app"].Stats, 2)
+
+	assert.Equal(t, timestamp.Add(time.Second), a.Stats.Timestamp)
+	assert.Equal(t, v1.CpuUsage{Total: 300, User: 150, System: 150}, a.Stats.Cpu)
+	assert.Equal(t, uint64(298), a.Stats.CpuInst.Total)
+	assert.Equal(t, PodMemoryStats{Usage: 3000, WorkingSet: 1500}, a.Stats.Memory)
+	assert.Equal(t, uint64(4000), a.Stats.Network.RxBytes)
+	assert.Equal(t, uint64(2000), a.Stats.Network.TxBytes)
+	assert.Equal(t, PodFilesystemStats{TotalUsageBytes: 600, BaseUsageBytes: 300, InodeUsage: 20}, a.Stats.Filesystem)
+
+	b := pods["b"]
+	assert.Len(t, b.Containers, 1)
+	assert.Equal(t, uint64(300), b.Stats.Cpu.Total)
+	assert.Equal(t, v1.InterfaceStats{}, b.Stats.Network)
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import "time"
+
+// Query aggregates a metric of the stats kept in memory of a set of
+// containers, grouped by labels.
+type Query struct {
+	// Metric to aggregate, e.g. "memory_working_set_bytes".
+	Metric string `json:"metric"`
+	// How the samples of a container are reduced over the range, one of the
+	// QueryOver constants. Empty means QueryOverLast.
+	Over string `json:"over,omitempty"`
+	// How the values of the containers of a group are aggregated, one of
+	// the QueryOp constants. Empty means QueryOpSum.
+	Op string `json:"op,omitempty"`
+	// Labels of the containers grouping them; each container is its own
+	// group if empty.
+	By []string `json:"by,omitempty"`
+	// Range of the samples, ending now. Zero uses all the samples in memory.
+	Range time.Duration `json:"range,omitempty"`
+	// Maximum number of groups returned, those with the highest values. Zero
+	// returns all the groups.
+	Limit int `json:"limit,omitempty"`
+}
+
+const (
+	// Value of the latest sample.
+	QueryOverLast = "last"
+	// Average, minimum and maximum of the samples.
+	QueryOverAvg = "avg"
+	QueryOverMin = "min"
+	QueryOverMax = "max"
+	// Difference between the latest and the earliest samples.
+	QueryOverDelta = "delta"
+)
+
+const (
+	QueryOpSum = "sum"
+	QueryOpAvg = "avg"
+	QueryOpMax = "max"
+)
+
+// QueryContainerLabel is the label of the groups of a query without labels,
+// set to the name of the container.
+const QueryContainerLabel = "container"
+
+type QueryResult struct {
+	// Values of the labels grouping the containers, or the name of the
+	// container in QueryContainerLabel if the query has none.
+	Labels map[string]string `json:"labels"`
+	// Aggregated value of the group.
+	Value float64 `json:"value"`
+	// Number of containers in the group.
+	Containers int `json:"containers"`
+}
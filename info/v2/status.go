@@ -0,0 +1,37 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import "time"
+
+// Degradation is a feature of cAdvisor which is disabled, for all containers
+// or for one container, because its collector could not be set up.
+type Degradation struct {
+	// Name of the feature, e.g. "perf", "resctrl" or "nvidia".
+	Feature string `json:"feature"`
+	// Container the feature is disabled for, empty if disabled for all
+	// containers.
+	Container string `json:"container,omitempty"`
+	// Error which disabled the feature.
+	Reason string `json:"reason"`
+	// Time the feature was disabled.
+	Since time.Time `json:"since"`
+}
+
+// Status is the state of the features of cAdvisor.
+type Status struct {
+	// Features disabled, sorted by feature and container.
+	Degradations []Degradation `json:"degradations"`
+}
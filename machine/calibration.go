@@ -0,0 +1,195 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Size of the buffer used for calibration, larger than the last level
+	// caches of most machines. It is the memory allocated while a NUMA node is
+	// calibrated, the nodes being calibrated one after the other.
+	calibrationBufferSize = 64 << 20
+	// Number of copies of which the fastest one is used to compute bandwidth.
+	calibrationCopyRounds = 3
+	// Number of dependent loads used to compute latency.
+	calibrationLatencyLoads = 1 << 22
+
+	cacheLineSize = 64
+)
+
+type memoryCalibrationResult struct {
+	// Bytes read and written per second.
+	bandwidth uint64
+	// Average latency of a dependent load.
+	latency time.Duration
+}
+
+var (
+	calibrationOnce    sync.Once
+	calibrationResults map[int]memoryCalibrationResult
+
+	// Keeps the latency loop from being optimized away.
+	calibrationSink uint64
+)
+
+// applyMemoryCalibration annotates the NUMA nodes with their measured memory
+// bandwidth and latency if enabled. The measurement runs only once per
+// process, later calls reuse the results.
+func applyMemoryCalibration(topology []info.Node, enabled bool) {
+	if !enabled {
+		return
+	}
+	calibrationOnce.Do(func() {
+		calibrationResults = calibrateMemory(topology, calibrationBufferSize)
+	})
+	for i := range topology {
+		result, ok := calibrationResults[topology[i].Id]
+		if !ok {
+			continue
+		}
+		topology[i].MemoryBandwidth = result.bandwidth
+		topology[i].MemoryLatency = uint64(result.latency.Nanoseconds())
+	}
+}
+
+func calibrateMemory(topology []info.Node, size int) map[int]memoryCalibrationResult {
+	results := make(map[int]memoryCalibrationResult, len(topology))
+	for _, node := range topology {
+		var cpus []int
+		for _, core := range node.Cores {
+			cpus = append(cpus, core.Threads...)
+		}
+		if len(cpus) == 0 {
+			continue
+		}
+		klog.V(1).Infof("Calibrating memory of NUMA node %d", node.Id)
+		result, err := calibrateNode(cpus, size)
+		if err != nil {
+			klog.Warningf("Failed to calibrate memory of NUMA node %d: %v", node.Id, err)
+			continue
+		}
+		klog.V(1).Infof("NUMA node %d: memory bandwidth %d B/s, latency %v", node.Id, result.bandwidth, result.latency)
+		results[node.Id] = result
+		// Return the buffer of the node before calibrating the next one.
+		debug.FreeOSMemory()
+	}
+	return results
+}
+
+// calibrateNode runs the benchmark on the given CPUs. Buffers are allocated
+// while bound to these CPUs so the kernel's first-touch policy places them
+// on the local node.
+func calibrateNode(cpus []int, size int) (memoryCalibrationResult, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var original, set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &original); err != nil {
+		return memoryCalibrationResult{}, fmt.Errorf("unable to get CPU affinity: %v", err)
+	}
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		return memoryCalibrationResult{}, fmt.Errorf("unable to set CPU affinity to %v: %v", cpus, err)
+	}
+	defer func() {
+		if err := unix.SchedSetaffinity(0, &original); err != nil {
+			klog.Warningf("Unable to restore CPU affinity: %v", err)
+		}
+	}()
+
+	// A single buffer is shared by both measurements.
+	buffer := make([]uint64, size/8)
+	return memoryCalibrationResult{
+		bandwidth: measureBandwidth(buffer),
+		latency:   measureLatency(buffer),
+	}, nil
+}
+
+// measureBandwidth returns the bytes moved per second by the fastest of
+// several copies of the first half of the buffer to the second half, counting
+// both the read and the write as STREAM does.
+func measureBandwidth(buffer []uint64) uint64 {
+	half := len(buffer) / 2
+	src, dst := buffer[:half], buffer[half:2*half]
+	for i := range buffer {
+		buffer[i] = uint64(i)
+	}
+
+	var best time.Duration
+	for i := 0; i < calibrationCopyRounds; i++ {
+		start := time.Now()
+		copy(dst, src)
+		elapsed := time.Since(start)
+		if best == 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	if best <= 0 {
+		return 0
+	}
+	return uint64(float64(2*8*half) / best.Seconds())
+}
+
+// measureLatency chases pointers through a random cycle over all cache lines
+// of the buffer, which defeats hardware prefetching.
+func measureLatency(buffer []uint64) time.Duration {
+	const wordsPerLine = cacheLineSize / 8
+	lines := len(buffer) / wordsPerLine
+	if lines < 2 {
+		return 0
+	}
+	for line, next := range randomCycle(lines) {
+		buffer[line*wordsPerLine] = uint64(next * wordsPerLine)
+	}
+
+	position := uint64(0)
+	start := time.Now()
+	for i := 0; i < calibrationLatencyLoads; i++ {
+		position = buffer[position]
+	}
+	elapsed := time.Since(start)
+	calibrationSink = position
+	return elapsed / calibrationLatencyLoads
+}
+
+// randomCycle returns a permutation forming a single cycle over n elements,
+// i.e. following it from any element visits all others (Sattolo's algorithm).
+func randomCycle(n int) []int {
+	next := make([]int, n)
+	for i := range next {
+		next[i] = i
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := n - 1; i > 0; i-- {
+		j := r.Intn(i)
+		next[i], next[j] = next[j], next[i]
+	}
+	return next
+}
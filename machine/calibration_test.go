@@ -0,0 +1,69 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestRandomCycleVisitsAllElements(t *testing.T) {
+	const n = 1000
+	next := randomCycle(n)
+
+	visited := make(map[int]bool, n)
+	position := 0
+	for i := 0; i < n; i++ {
+		assert.False(t, visited[position], "element %d visited twice", position)
+		visited[position] = true
+		position = next[position]
+	}
+	assert.Equal(t, 0, position)
+	assert.Len(t, visited, n)
+}
+
+func TestCalibrateMemory(t *testing.T) {
+	var set unix.CPUSet
+	assert.Nil(t, unix.SchedGetaffinity(0, &set))
+	cpu := -1
+	for i := 0; i < 1024; i++ {
+		if set.IsSet(i) {
+			cpu = i
+			break
+		}
+	}
+	if cpu < 0 {
+		t.Skip("no usable CPU found")
+	}
+
+	topology := []info.Node{
+		{Id: 0, Cores: []info.Core{{Id: 0, Threads: []int{cpu}}}},
+		{Id: 1},
+	}
+	results := calibrateMemory(topology, 1<<20)
+	assert.Len(t, results, 1)
+	assert.NotZero(t, results[0].bandwidth)
+	assert.NotZero(t, results[0].latency)
+}
+
+func TestApplyMemoryCalibrationDisabled(t *testing.T) {
+	topology := []info.Node{{Id: 0, Cores: []info.Core{{Id: 0, Threads: []int{0}}}}}
+	applyMemoryCalibration(topology, false)
+	assert.Zero(t, topology[0].MemoryBandwidth)
+	assert.Zero(t, topology[0].MemoryLatency)
+}
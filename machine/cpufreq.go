@@ -0,0 +1,172 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	cpuSysfsDir = "/sys/devices/system/cpu"
+	msrDevice   = "/dev/cpu/%d/msr"
+
+	// Addresses of the IA32_MPERF and IA32_APERF model specific registers.
+	msrMperf = 0xe7
+	msrAperf = 0xe8
+)
+
+var cpuDirRegexp = regexp.MustCompile(`^cpu([0-9]+)$`)
+
+// msrReader reads a model specific register of a CPU.
+type msrReader func(cpu int, register int64) (uint64, error)
+
+// GetCpuFreqStats returns the frequency scaling state of the host's CPUs.
+// prev is the previous sample, if any, used to derive the effective frequency
+// of every CPU from its APERF/MPERF counters.
+func GetCpuFreqStats(prev *info.CpuFreqStats) (*info.CpuFreqStats, error) {
+	return getCpuFreqStats(cpuSysfsDir, readMsr, prev)
+}
+
+func getCpuFreqStats(dir string, msr msrReader, prev *info.CpuFreqStats) (*info.CpuFreqStats, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	stats := &info.CpuFreqStats{}
+	for _, entry := range entries {
+		matches := cpuDirRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		cpuFreqDir := filepath.Join(dir, entry.Name(), "cpufreq")
+		if _, err := os.Stat(cpuFreqDir); os.IsNotExist(err) {
+			// Offline CPUs and CPUs without a cpufreq driver.
+			continue
+		}
+		cpu, _ := strconv.Atoi(matches[1])
+		freq := info.CpuFreq{Cpu: cpu}
+		for name, value := range map[string]*uint64{
+			"scaling_cur_freq": &freq.Current,
+			"scaling_min_freq": &freq.ScalingMin,
+			"scaling_max_freq": &freq.ScalingMax,
+			"cpuinfo_max_freq": &freq.HardwareMax,
+		} {
+			*value, err = readUintFile(filepath.Join(cpuFreqDir, name))
+			if err != nil {
+				return nil, err
+			}
+		}
+		// base_frequency is only exposed by intel_pstate.
+		freq.Base, err = readUintFile(filepath.Join(cpuFreqDir, "base_frequency"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		// The MSRs are only readable with the msr module loaded and enough
+		// privileges, the effective frequency is not reported otherwise.
+		if aperf, err := msr(cpu, msrAperf); err == nil {
+			if mperf, err := msr(cpu, msrMperf); err == nil {
+				freq.Aperf, freq.Mperf = aperf, mperf
+			}
+		}
+		stats.PerCpu = append(stats.PerCpu, freq)
+	}
+	if len(stats.PerCpu) == 0 {
+		return nil, fmt.Errorf("no CPU in %s exposes cpufreq", dir)
+	}
+	sort.Slice(stats.PerCpu, func(i, j int) bool { return stats.PerCpu[i].Cpu < stats.PerCpu[j].Cpu })
+	setEffectiveFrequencies(prev, stats)
+
+	stats.IntelPstate, err = getIntelPstateStats(filepath.Join(dir, "intel_pstate"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// setEffectiveFrequencies computes the average frequency of every CPU since the
+// previous sample: APERF counts at the actual frequency and MPERF at the base
+// frequency while the CPU is not idle.
+func setEffectiveFrequencies(prev, cur *info.CpuFreqStats) {
+	if prev == nil {
+		return
+	}
+	previous := make(map[int]info.CpuFreq, len(prev.PerCpu))
+	for _, freq := range prev.PerCpu {
+		previous[freq.Cpu] = freq
+	}
+	for i := range cur.PerCpu {
+		freq := &cur.PerCpu[i]
+		p, ok := previous[freq.Cpu]
+		if !ok || freq.Base == 0 || p.Mperf == 0 || freq.Mperf <= p.Mperf || freq.Aperf < p.Aperf {
+			continue
+		}
+		freq.Effective = uint64(float64(freq.Base) * float64(freq.Aperf-p.Aperf) / float64(freq.Mperf-p.Mperf))
+	}
+}
+
+func getIntelPstateStats(dir string) (*info.IntelPstateStats, error) {
+	status, err := ioutil.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	stats := &info.IntelPstateStats{Status: string(bytes.TrimSpace(status))}
+	noTurbo, err := readUintFile(filepath.Join(dir, "no_turbo"))
+	if err != nil {
+		return nil, err
+	}
+	stats.NoTurbo = noTurbo == 1
+	if stats.MinPerfPct, err = readUintFile(filepath.Join(dir, "min_perf_pct")); err != nil {
+		return nil, err
+	}
+	if stats.MaxPerfPct, err = readUintFile(filepath.Join(dir, "max_perf_pct")); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func readUintFile(path string) (uint64, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(string(bytes.TrimSpace(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return value, nil
+}
+
+func readMsr(cpu int, register int64) (uint64, error) {
+	file, err := os.Open(fmt.Sprintf(msrDevice, cpu))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	buf := make([]byte, 8)
+	if _, err := file.ReadAt(buf, register); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
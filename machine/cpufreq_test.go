@@ -0,0 +1,80 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMsr returns register values that grow with every sample.
+type fakeMsr struct {
+	samples map[int]uint64
+}
+
+func (f *fakeMsr) read(cpu int, register int64) (uint64, error) {
+	if cpu == 1 {
+		return 0, fmt.Errorf("permission denied")
+	}
+	switch register {
+	case msrAperf:
+		return 3000000 * f.samples[cpu], nil
+	case msrMperf:
+		return 2000000 * f.samples[cpu], nil
+	}
+	return 0, fmt.Errorf("unexpected register %#x", register)
+}
+
+func TestGetCpuFreqStats(t *testing.T) {
+	msr := &fakeMsr{samples: map[int]uint64{0: 1, 10: 1}}
+	stats, err := getCpuFreqStats("testdata/cpufreq", msr.read, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &info.CpuFreqStats{
+		PerCpu: []info.CpuFreq{
+			{Cpu: 0, Current: 2800000, ScalingMin: 800000, ScalingMax: 3500000, HardwareMax: 4700000, Base: 2100000, Aperf: 3000000, Mperf: 2000000},
+			{Cpu: 1, Current: 1200000, ScalingMin: 800000, ScalingMax: 3500000, HardwareMax: 4700000},
+			{Cpu: 10, Current: 2800000, ScalingMin: 800000, ScalingMax: 3500000, HardwareMax: 4700000, Base: 2100000, Aperf: 3000000, Mperf: 2000000},
+		},
+		IntelPstate: &info.IntelPstateStats{
+			Status:     "active",
+			NoTurbo:    true,
+			MinPerfPct: 17,
+			MaxPerfPct: 80,
+		},
+	}, stats)
+
+	// The effective frequency is derived from the counters of two samples.
+	msr.samples[0] = 3
+	next, err := getCpuFreqStats("testdata/cpufreq", msr.read, stats)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3150000), next.PerCpu[0].Effective)
+	assert.Equal(t, uint64(0), next.PerCpu[1].Effective)
+	// The counters of CPU 10 did not move, e.g. because it was idle.
+	assert.Equal(t, uint64(0), next.PerCpu[2].Effective)
+}
+
+func TestGetCpuFreqStatsWithoutIntelPstate(t *testing.T) {
+	msr := &fakeMsr{}
+	stats, err := getCpuFreqStats("testdata/cpufreq_acpi", msr.read, nil)
+	assert.Nil(t, err)
+	assert.Len(t, stats.PerCpu, 1)
+	assert.Nil(t, stats.IntelPstate)
+
+	_, err = getCpuFreqStats("testdata/ksm", msr.read, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	powercapDir = "/sys/class/powercap"
+	hwmonDir    = "/sys/class/hwmon"
+
+	// Addresses of the AMD RAPL model specific registers.
+	msrAmdRaplPowerUnit   = 0xc0010299
+	msrAmdPkgEnergyStatus = 0xc001029b
+)
+
+var (
+	raplZoneRegexp       = regexp.MustCompile(`^intel-rapl:[0-9]+(:[0-9]+)?$`)
+	raplPackageRegexp    = regexp.MustCompile(`^package-([0-9]+)$`)
+	amdEnergySocketRegex = regexp.MustCompile(`^Esocket([0-9]+)$`)
+)
+
+type energyDomain struct {
+	socket int
+	domain string
+}
+
+// energySample is a raw reading of an energy counter, in microjoules.
+type energySample struct {
+	energyDomain
+	value uint64
+	// Value at which the counter wraps around, 0 if it does not.
+	maxRange uint64
+}
+
+// EnergyMeter reads the energy counters of the host's RAPL domains. It
+// accumulates them across wraparounds of the hardware counters, so the
+// energy it reports is monotonic and counted since the meter was created.
+type EnergyMeter struct {
+	powercapDir string
+	hwmonDir    string
+	cpuDir      string
+	procStat    string
+	msr         msrReader
+
+	last  map[energyDomain]uint64
+	total map[energyDomain]uint64
+}
+
+// NewEnergyMeter returns an EnergyMeter reading the counters of the host.
+func NewEnergyMeter() *EnergyMeter {
+	return newEnergyMeter(powercapDir, hwmonDir, cpuSysfsDir, procStat, readMsr)
+}
+
+func newEnergyMeter(powercapDir, hwmonDir, cpuDir, procStat string, msr msrReader) *EnergyMeter {
+	return &EnergyMeter{
+		powercapDir: powercapDir,
+		hwmonDir:    hwmonDir,
+		cpuDir:      cpuDir,
+		procStat:    procStat,
+		msr:         msr,
+		last:        map[energyDomain]uint64{},
+		total:       map[energyDomain]uint64{},
+	}
+}
+
+// Read returns the energy consumed by every RAPL domain since the meter was
+// created, together with the CPU time used on the host.
+func (m *EnergyMeter) Read() (*info.EnergyStats, error) {
+	// The powercap interface is preferred, it is also used for AMD CPUs on
+	// recent kernels. The amd_energy hwmon driver and the AMD MSRs are only
+	// fallbacks for older kernels.
+	samples, err := m.readPowercap()
+	if err == nil && len(samples) == 0 {
+		samples, err = m.readAmdEnergyHwmon()
+	}
+	if err == nil && len(samples) == 0 {
+		samples, err = m.readAmdMsr()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no RAPL energy counters found")
+	}
+
+	stats := &info.EnergyStats{}
+	stats.CpuTime, err = getBusyCPUTime(m.procStat)
+	if err != nil {
+		return nil, err
+	}
+	for _, sample := range samples {
+		if last, ok := m.last[sample.energyDomain]; ok {
+			switch {
+			case sample.value >= last:
+				m.total[sample.energyDomain] += sample.value - last
+			case sample.maxRange > 0:
+				m.total[sample.energyDomain] += sample.maxRange - last + sample.value
+			}
+		}
+		m.last[sample.energyDomain] = sample.value
+		stats.Domains = append(stats.Domains, info.EnergyDomainStats{
+			Socket: sample.socket,
+			Domain: sample.domain,
+			Energy: m.total[sample.energyDomain],
+		})
+		// Core and uncore are part of the package domain.
+		if sample.domain == "package" || sample.domain == "dram" {
+			stats.Estimated += m.total[sample.energyDomain]
+		}
+	}
+	sort.Slice(stats.Domains, func(i, j int) bool {
+		if stats.Domains[i].Socket != stats.Domains[j].Socket {
+			return stats.Domains[i].Socket < stats.Domains[j].Socket
+		}
+		return stats.Domains[i].Domain < stats.Domains[j].Domain
+	})
+	return stats, nil
+}
+
+// readPowercap reads the package zones of the intel-rapl powercap driver and
+// their subzones (core, uncore, dram).
+func (m *EnergyMeter) readPowercap() ([]energySample, error) {
+	entries, err := ioutil.ReadDir(m.powercapDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var samples []energySample
+	for _, entry := range entries {
+		if !raplZoneRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		parent := entry.Name()
+		if strings.Count(parent, ":") == 2 {
+			parent = parent[:strings.LastIndex(parent, ":")]
+		}
+		// The socket of a zone is given by the name of its package zone.
+		packageName, err := readTrimmedFile(filepath.Join(m.powercapDir, parent, "name"))
+		if err != nil {
+			return nil, err
+		}
+		matches := raplPackageRegexp.FindStringSubmatch(packageName)
+		if matches == nil {
+			// Platform (psys) zones do not belong to a socket.
+			continue
+		}
+		socket, _ := strconv.Atoi(matches[1])
+		domain := "package"
+		if parent != entry.Name() {
+			domain, err = readTrimmedFile(filepath.Join(m.powercapDir, entry.Name(), "name"))
+			if err != nil {
+				return nil, err
+			}
+		}
+		value, err := readUintFile(filepath.Join(m.powercapDir, entry.Name(), "energy_uj"))
+		if err != nil {
+			return nil, err
+		}
+		maxRange, err := readUintFile(filepath.Join(m.powercapDir, entry.Name(), "max_energy_range_uj"))
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, energySample{
+			energyDomain: energyDomain{socket: socket, domain: domain},
+			value:        value,
+			maxRange:     maxRange,
+		})
+	}
+	return samples, nil
+}
+
+// readAmdEnergyHwmon reads the per socket counters of the amd_energy hwmon
+// driver, which are accumulated to 64 bits by the driver.
+func (m *EnergyMeter) readAmdEnergyHwmon() ([]energySample, error) {
+	entries, err := ioutil.ReadDir(m.hwmonDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var samples []energySample
+	for _, entry := range entries {
+		dir := filepath.Join(m.hwmonDir, entry.Name())
+		if name, err := readTrimmedFile(filepath.Join(dir, "name")); err != nil || name != "amd_energy" {
+			continue
+		}
+		labels, err := filepath.Glob(filepath.Join(dir, "energy*_label"))
+		if err != nil {
+			return nil, err
+		}
+		for _, labelFile := range labels {
+			label, err := readTrimmedFile(labelFile)
+			if err != nil {
+				return nil, err
+			}
+			matches := amdEnergySocketRegex.FindStringSubmatch(label)
+			if matches == nil {
+				// Per core counters are not reported.
+				continue
+			}
+			socket, _ := strconv.Atoi(matches[1])
+			value, err := readUintFile(strings.TrimSuffix(labelFile, "_label") + "_input")
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, energySample{
+				energyDomain: energyDomain{socket: socket, domain: "package"},
+				value:        value,
+			})
+		}
+	}
+	return samples, nil
+}
+
+// readAmdMsr reads the package energy MSR of the first CPU of every socket.
+func (m *EnergyMeter) readAmdMsr() ([]energySample, error) {
+	cpus, err := filepath.Glob(filepath.Join(m.cpuDir, "cpu[0-9]*", "topology", "physical_package_id"))
+	if err != nil {
+		return nil, err
+	}
+	firstCPUs := map[int]int{}
+	for _, file := range cpus {
+		cpu, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(file))), "cpu"))
+		if err != nil {
+			continue
+		}
+		socket, err := readUintFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if first, ok := firstCPUs[int(socket)]; !ok || cpu < first {
+			firstCPUs[int(socket)] = cpu
+		}
+	}
+	var samples []energySample
+	for socket, cpu := range firstCPUs {
+		unit, err := m.msr(cpu, msrAmdRaplPowerUnit)
+		if err != nil {
+			// Not an AMD CPU or the msr module is not loaded.
+			return nil, nil
+		}
+		raw, err := m.msr(cpu, msrAmdPkgEnergyStatus)
+		if err != nil {
+			return nil, nil
+		}
+		// Bits 12:8 hold the energy status unit, in 1/2^ESU joules.
+		microJoules := 1e6 / math.Exp2(float64((unit>>8)&0x1f))
+		samples = append(samples, energySample{
+			energyDomain: energyDomain{socket: socket, domain: "package"},
+			value:        uint64(float64(raw&0xffffffff) * microJoules),
+			maxRange:     uint64(float64(1<<32) * microJoules),
+		})
+	}
+	return samples, nil
+}
+
+func readTrimmedFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
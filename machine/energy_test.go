@@ -0,0 +1,125 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noMsr(cpu int, register int64) (uint64, error) {
+	return 0, fmt.Errorf("no such device")
+}
+
+func writeZone(t *testing.T, dir, zone, name string, energy, maxRange uint64) {
+	zoneDir := filepath.Join(dir, zone)
+	require.NoError(t, os.MkdirAll(zoneDir, 0755))
+	for file, content := range map[string]string{
+		"name":                name,
+		"energy_uj":           fmt.Sprint(energy),
+		"max_energy_range_uj": fmt.Sprint(maxRange),
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(zoneDir, file), []byte(content+"\n"), 0644))
+	}
+}
+
+func TestEnergyMeterPowercap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "powercap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeZone(t, dir, "intel-rapl:0", "package-0", 1000, 10000)
+	writeZone(t, dir, "intel-rapl:0:0", "core", 500, 10000)
+	writeZone(t, dir, "intel-rapl:0:1", "dram", 200, 10000)
+	writeZone(t, dir, "intel-rapl:1", "package-1", 3000, 10000)
+	writeZone(t, dir, "intel-rapl:2", "psys", 9000, 10000)
+
+	meter := newEnergyMeter(dir, "testdata/missing", "testdata/missing", "testdata/proc_stat", noMsr)
+	stats, err := meter.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &info.EnergyStats{
+		CpuTime: 59220000000,
+		Domains: []info.EnergyDomainStats{
+			{Socket: 0, Domain: "core"},
+			{Socket: 0, Domain: "dram"},
+			{Socket: 0, Domain: "package"},
+			{Socket: 1, Domain: "package"},
+		},
+	}, stats)
+
+	// The package counter of socket 0 wraps around.
+	writeZone(t, dir, "intel-rapl:0", "package-0", 500, 10000)
+	writeZone(t, dir, "intel-rapl:0:1", "dram", 700, 10000)
+	writeZone(t, dir, "intel-rapl:1", "package-1", 4000, 10000)
+	stats, err = meter.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []info.EnergyDomainStats{
+		{Socket: 0, Domain: "core", Energy: 0},
+		{Socket: 0, Domain: "dram", Energy: 500},
+		{Socket: 0, Domain: "package", Energy: 9500},
+		{Socket: 1, Domain: "package", Energy: 1000},
+	}, stats.Domains)
+	assert.Equal(t, uint64(11000), stats.Estimated)
+}
+
+func TestEnergyMeterAmdEnergyHwmon(t *testing.T) {
+	meter := newEnergyMeter("testdata/missing", "testdata/energy_hwmon", "testdata/missing", "testdata/proc_stat", noMsr)
+	stats, err := meter.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []info.EnergyDomainStats{{Socket: 0, Domain: "package"}}, stats.Domains)
+}
+
+func TestEnergyMeterAmdMsr(t *testing.T) {
+	counters := map[int]uint64{0: 0xffffff00, 2: 0x100}
+	msr := func(cpu int, register int64) (uint64, error) {
+		switch register {
+		case msrAmdRaplPowerUnit:
+			// 1/2^16 J energy unit.
+			return 0x0a1003, nil
+		case msrAmdPkgEnergyStatus:
+			value, ok := counters[cpu]
+			if !ok {
+				return 0, fmt.Errorf("unexpected CPU %d", cpu)
+			}
+			return value, nil
+		}
+		return 0, fmt.Errorf("unexpected register %#x", register)
+	}
+	meter := newEnergyMeter("testdata/missing", "testdata/missing", "testdata/energy_cpus", "testdata/proc_stat", msr)
+	_, err := meter.Read()
+	require.NoError(t, err)
+
+	// The 32 bits counter of socket 0 wraps around.
+	counters[0] = 0x100
+	counters[2] = 0x10100
+	stats, err := meter.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []info.EnergyDomainStats{
+		{Socket: 0, Domain: "package", Energy: 7813},
+		{Socket: 1, Domain: "package", Energy: 1000000},
+	}, stats.Domains)
+}
+
+func TestEnergyMeterWithoutCounters(t *testing.T) {
+	meter := newEnergyMeter("testdata/missing", "testdata/missing", "testdata/energy_cpus", "testdata/proc_stat", noMsr)
+	_, err := meter.Read()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const dmiIDDir = "/sys/class/dmi/id"
+
+// dmiPlaceholders are values firmware vendors commonly leave in unset fields.
+var dmiPlaceholders = map[string]struct{}{
+	"to be filled by o.e.m.": {},
+	"default string":         {},
+	"not specified":          {},
+	"not applicable":         {},
+	"system product name":    {},
+	"system manufacturer":    {},
+	"system serial number":   {},
+	"system version":         {},
+	"none":                   {},
+	"0123456789":             {},
+}
+
+// GetHardwareInfo returns the hardware inventory from the DMI attributes in
+// dmiDir, nil if the platform has no DMI.
+func GetHardwareInfo(dmiDir string) (*info.HardwareInfo, error) {
+	if _, err := os.Stat(dmiDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var err error
+	hardware := &info.HardwareInfo{}
+	for _, attr := range []struct {
+		file  string
+		value *string
+	}{
+		{"sys_vendor", &hardware.SystemVendor},
+		{"product_name", &hardware.ProductName},
+		{"product_version", &hardware.ProductVersion},
+		{"product_serial", &hardware.SerialNumber},
+		{"board_vendor", &hardware.BoardVendor},
+		{"board_name", &hardware.BoardName},
+		{"board_serial", &hardware.BoardSerial},
+		{"chassis_asset_tag", &hardware.ChassisAssetTag},
+		{"bios_vendor", &hardware.BIOSVendor},
+		{"bios_version", &hardware.BIOSVersion},
+		{"bios_date", &hardware.BIOSDate},
+	} {
+		*attr.value, err = readDMIAttribute(filepath.Join(dmiDir, attr.file))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hardware, nil
+}
+
+// readDMIAttribute returns the value of a DMI attribute, empty if it is not
+// set or not readable by the current user, as serial numbers usually are.
+func readDMIAttribute(path string) (string, error) {
+	value, err := readTrimmedFile(path)
+	if os.IsNotExist(err) || os.IsPermission(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if _, ok := dmiPlaceholders[strings.ToLower(value)]; ok {
+		return "", nil
+	}
+	return value, nil
+}
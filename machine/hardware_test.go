@@ -0,0 +1,43 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHardwareInfo(t *testing.T) {
+	hardware, err := GetHardwareInfo("testdata/dmi/id")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.HardwareInfo{
+		SystemVendor: "Dell Inc.",
+		ProductName:  "PowerEdge R640",
+		SerialNumber: "7XJ4K13",
+		BoardVendor:  "Dell Inc.",
+		BoardName:    "0H28RR",
+		BIOSVendor:   "Dell Inc.",
+		BIOSVersion:  "2.10.2",
+		BIOSDate:     "02/24/2021",
+	}, hardware)
+}
+
+func TestGetHardwareInfoWithoutDMI(t *testing.T) {
+	hardware, err := GetHardwareInfo("testdata/missing")
+	assert.Nil(t, err)
+	assert.Nil(t, hardware)
+}
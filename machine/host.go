@@ -0,0 +1,164 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+const (
+	sysKernelSecurityDir = "/sys/kernel/security"
+	sysSELinuxDir        = "/sys/fs/selinux"
+	sysAppArmorDir       = "/sys/module/apparmor/parameters"
+	sysCgroupDir         = "/sys/fs/cgroup"
+)
+
+var osPrettyNameRegexp = regexp.MustCompile(`(?m)^PRETTY_NAME=(.*)$`)
+
+// Taint flags in the order of their bits in /proc/sys/kernel/tainted, see
+// https://www.kernel.org/doc/html/latest/admin-guide/tainted-kernels.html
+var kernelTaintFlags = []string{"P", "F", "S", "R", "M", "B", "U", "D", "A", "W", "C", "I", "O", "E", "L", "K", "X", "T", "N"}
+
+// hostMetadata describes the operating system of the host.
+type hostMetadata struct {
+	osPrettyName     string
+	kernelTaintFlags []string
+	securityModules  []string
+	selinuxMode      string
+	apparmorMode     string
+	cgroupMode       string
+}
+
+func (h *hostMetadata) apply(mi *info.MachineInfo) {
+	mi.OSPrettyName = h.osPrettyName
+	mi.KernelTaintFlags = h.kernelTaintFlags
+	mi.SecurityModules = h.securityModules
+	mi.SELinuxMode = h.selinuxMode
+	mi.AppArmorMode = h.apparmorMode
+	mi.CgroupMode = h.cgroupMode
+}
+
+// readHostMetadata returns the os-release name, kernel taint, active security
+// modules and cgroup mode of the host.
+func readHostMetadata(rootFs string) (*hostMetadata, error) {
+	return getHostMetadata(rootFs, sysKernelSecurityDir, sysSELinuxDir, sysAppArmorDir, sysCgroupDir, cgroups.IsCgroup2UnifiedMode())
+}
+
+func getHostMetadata(rootFs, securityDir, selinuxDir, apparmorDir, cgroupDir string, cgroupUnified bool) (*hostMetadata, error) {
+	var err error
+	metadata := &hostMetadata{}
+	metadata.osPrettyName, err = getOSPrettyName(rootFs)
+	if err != nil {
+		return nil, err
+	}
+	metadata.kernelTaintFlags, err = getKernelTaintFlags(filepath.Join(rootFs, "/proc/sys/kernel/tainted"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The lsm file lists the active modules since Linux 4.15.
+	lsm, err := readTrimmedFile(filepath.Join(securityDir, "lsm"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if lsm != "" {
+		metadata.securityModules = strings.Split(lsm, ",")
+	}
+
+	metadata.selinuxMode = "disabled"
+	enforce, err := readTrimmedFile(filepath.Join(selinuxDir, "enforce"))
+	if err == nil {
+		metadata.selinuxMode = "permissive"
+		if enforce == "1" {
+			metadata.selinuxMode = "enforcing"
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	metadata.apparmorMode = "disabled"
+	enabled, err := readTrimmedFile(filepath.Join(apparmorDir, "enabled"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if enabled == "Y" {
+		mode, err := readTrimmedFile(filepath.Join(apparmorDir, "mode"))
+		if err != nil {
+			return nil, err
+		}
+		metadata.apparmorMode = mode
+	}
+
+	switch {
+	case cgroupUnified:
+		metadata.cgroupMode = "unified"
+	case dirExists(filepath.Join(cgroupDir, "unified")):
+		metadata.cgroupMode = "hybrid"
+	default:
+		metadata.cgroupMode = "legacy"
+	}
+	return metadata, nil
+}
+
+// getOSPrettyName returns PRETTY_NAME of the os-release file below rootFs.
+func getOSPrettyName(rootFs string) (string, error) {
+	content, err := readTrimmedFile(filepath.Join(rootFs, "/etc/os-release"))
+	if os.IsNotExist(err) {
+		// /usr/lib/os-release in stateless systems like Clear Linux
+		content, err = readTrimmedFile(filepath.Join(rootFs, "/usr/lib/os-release"))
+	}
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if line := osPrettyNameRegexp.FindStringSubmatch(content); line != nil {
+		return strings.Trim(strings.TrimSpace(line[1]), "\""), nil
+	}
+	return "", nil
+}
+
+// getKernelTaintFlags returns the flags set in the kernel taint mask, nil if
+// the kernel is not tainted.
+func getKernelTaintFlags(taintedFile string) ([]string, error) {
+	value, err := readTrimmedFile(taintedFile)
+	if err != nil {
+		return nil, err
+	}
+	tainted, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var flags []string
+	for bit, flag := range kernelTaintFlags {
+		if tainted&(1<<uint(bit)) != 0 {
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+func dirExists(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHostMetadata(t *testing.T) {
+	metadata, err := getHostMetadata("testdata/host/rootfs", "testdata/host/security", "testdata/host/selinux",
+		"testdata/host/apparmor", "testdata/host/cgroup", false)
+	assert.Nil(t, err)
+
+	var mi info.MachineInfo
+	metadata.apply(&mi)
+	assert.Equal(t, "Ubuntu 20.04.2 LTS", mi.OSPrettyName)
+	assert.Equal(t, []string{"P", "O", "E"}, mi.KernelTaintFlags)
+	assert.Equal(t, []string{"lockdown", "capability", "yama", "apparmor"}, mi.SecurityModules)
+	assert.Equal(t, "permissive", mi.SELinuxMode)
+	assert.Equal(t, "enforce", mi.AppArmorMode)
+	assert.Equal(t, "hybrid", mi.CgroupMode)
+}
+
+func TestGetHostMetadataWithoutSecurityModules(t *testing.T) {
+	metadata, err := getHostMetadata("testdata/host/rootfs", "testdata/missing", "testdata/missing",
+		"testdata/missing", "testdata/missing", true)
+	assert.Nil(t, err)
+	assert.Nil(t, metadata.securityModules)
+	assert.Equal(t, "disabled", metadata.selinuxMode)
+	assert.Equal(t, "disabled", metadata.apparmorMode)
+	assert.Equal(t, "unified", metadata.cgroupMode)
+}
+
+func TestGetHostMetadataWithoutTaint(t *testing.T) {
+	_, err := getHostMetadata("testdata/missing", "testdata/missing", "testdata/missing",
+		"testdata/missing", "testdata/missing", false)
+	assert.NotNil(t, err)
+}
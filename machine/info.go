@@ -16,9 +16,11 @@ package machine
 
 import (
 	"bytes"
-	"flag"
+	"context"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -37,8 +39,34 @@ import (
 const hugepagesDirectory = "/sys/kernel/mm/hugepages/"
 const memoryControllerPath = "/sys/devices/system/edac/mc/"
 
-var machineIDFilePath = flag.String("machine_id_file", "/etc/machine-id,/var/lib/dbus/machine-id", "Comma-separated list of files to check for machine-id. Use the first one that exists.")
-var bootIDFilePath = flag.String("boot_id_file", "/proc/sys/kernel/random/boot_id", "Comma-separated list of files to check for boot-id. Use the first one that exists.")
+// InfoOptions configure the gathering of the machine info.
+type InfoOptions struct {
+	// Comma-separated lists of files to check for the machine and boot IDs,
+	// the first one that exists is used.
+	MachineIDFiles string
+	BootIDFiles    string
+	// Max time to wait for a section of the machine info, e.g. the topology
+	// or the filesystems. Sections which fail or time out are reported in
+	// the machine info errors.
+	SectionTimeout time.Duration
+	// Max time to wait for the metadata service of the detected cloud
+	// provider to describe the instance.
+	CloudMetadataTimeout time.Duration
+	// Whether the memory bandwidth and latency of the NUMA nodes are
+	// measured, once per process.
+	MemoryCalibration bool
+}
+
+// DefaultInfoOptions returns the options of the machine info of cAdvisor
+// started without flags.
+func DefaultInfoOptions() InfoOptions {
+	return InfoOptions{
+		MachineIDFiles:       "/etc/machine-id,/var/lib/dbus/machine-id",
+		BootIDFiles:          "/proc/sys/kernel/random/boot_id",
+		SectionTimeout:       10 * time.Second,
+		CloudMetadataTimeout: cloudinfo.DefaultTimeout,
+	}
+}
 
 func getInfoFromFiles(filePaths string) string {
 	if len(filePaths) == 0 {
@@ -54,102 +82,189 @@ func getInfoFromFiles(filePaths string) string {
 	return ""
 }
 
+// machineInfoSection gathers one part of the machine information. The
+// returned function stores the gathered data and is only applied if the
+// section completed in time, so late results never race with readers.
+type machineInfoSection struct {
+	name    string
+	collect func() (func(*info.MachineInfo), error)
+}
+
+type machineInfoSectionResult struct {
+	name  string
+	apply func(*info.MachineInfo)
+	err   error
+}
+
+// Info returns the machine info gathered with the default options.
 func Info(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool) (*info.MachineInfo, error) {
+	return InfoWithOptions(sysFs, fsInfo, inHostNamespace, DefaultInfoOptions())
+}
+
+// InfoWithOptions returns the machine info gathered as configured by options.
+func InfoWithOptions(sysFs sysfs.SysFs, fsInfo fs.FsInfo, inHostNamespace bool, options InfoOptions) (*info.MachineInfo, error) {
 	rootFs := "/"
 	if !inHostNamespace {
 		rootFs = "/rootfs"
 	}
 
-	cpuinfo, err := ioutil.ReadFile(filepath.Join(rootFs, "/proc/cpuinfo"))
-	if err != nil {
-		return nil, err
-	}
-	clockSpeed, err := GetClockSpeed(cpuinfo)
-	if err != nil {
-		return nil, err
-	}
-
-	memoryCapacity, err := GetMachineMemoryCapacity()
-	if err != nil {
-		return nil, err
-	}
-
-	memoryByType, err := GetMachineMemoryByType(memoryControllerPath)
-	if err != nil {
-		return nil, err
-	}
-
-	nvmInfo, err := nvm.GetInfo()
-	if err != nil {
-		return nil, err
-	}
-
-	hugePagesInfo, err := sysinfo.GetHugePagesInfo(sysFs, hugepagesDirectory)
-	if err != nil {
-		return nil, err
-	}
-
-	filesystems, err := fsInfo.GetGlobalFsInfo()
-	if err != nil {
-		klog.Errorf("Failed to get global filesystem information: %v", err)
-	}
-
-	diskMap, err := sysinfo.GetBlockDeviceInfo(sysFs)
-	if err != nil {
-		klog.Errorf("Failed to get disk map: %v", err)
-	}
-
-	netDevices, err := sysinfo.GetNetworkDevices(sysFs)
-	if err != nil {
-		klog.Errorf("Failed to get network devices: %v", err)
-	}
-
-	topology, numCores, err := GetTopology(sysFs)
-	if err != nil {
-		klog.Errorf("Failed to get topology information: %v", err)
+	sections := []machineInfoSection{
+		{"cpuinfo", func() (func(*info.MachineInfo), error) {
+			cpuinfo, err := ioutil.ReadFile(filepath.Join(rootFs, "/proc/cpuinfo"))
+			if err != nil {
+				return nil, err
+			}
+			clockSpeed, err := GetClockSpeed(cpuinfo)
+			if err != nil {
+				return nil, err
+			}
+			return func(mi *info.MachineInfo) {
+				mi.NumPhysicalCores = GetPhysicalCores(cpuinfo)
+				mi.NumSockets = GetSockets(cpuinfo)
+				mi.CpuFrequency = clockSpeed
+			}, nil
+		}},
+		{"memory", func() (func(*info.MachineInfo), error) {
+			memoryCapacity, err := GetMachineMemoryCapacity()
+			return func(mi *info.MachineInfo) { mi.MemoryCapacity = memoryCapacity }, err
+		}},
+		{"memory_by_type", func() (func(*info.MachineInfo), error) {
+			memoryByType, err := GetMachineMemoryByType(memoryControllerPath)
+			return func(mi *info.MachineInfo) { mi.MemoryByType = memoryByType }, err
+		}},
+		{"nvm", func() (func(*info.MachineInfo), error) {
+			nvmInfo, err := nvm.GetInfo()
+			if err != nil {
+				return nil, err
+			}
+			nvmInfo.Regions, err = nvm.GetPmemRegions()
+			return func(mi *info.MachineInfo) { mi.NVMInfo = nvmInfo }, err
+		}},
+		{"hugepages", func() (func(*info.MachineInfo), error) {
+			hugePagesInfo, err := sysinfo.GetHugePagesInfo(sysFs, hugepagesDirectory)
+			return func(mi *info.MachineInfo) { mi.HugePages = hugePagesInfo }, err
+		}},
+		{"filesystems", func() (func(*info.MachineInfo), error) {
+			filesystems, err := fsInfo.GetGlobalFsInfo()
+			return func(mi *info.MachineInfo) {
+				for i := range filesystems {
+					fs := filesystems[i]
+					inodes := uint64(0)
+					if fs.Inodes != nil {
+						inodes = *fs.Inodes
+					}
+					mi.Filesystems = append(mi.Filesystems, info.FsInfo{Device: fs.Device, DeviceMajor: uint64(fs.Major), DeviceMinor: uint64(fs.Minor), Type: fs.Type.String(), Capacity: fs.Capacity, Inodes: inodes, HasInodes: fs.Inodes != nil})
+				}
+			}, err
+		}},
+		{"disks", func() (func(*info.MachineInfo), error) {
+			diskMap, err := sysinfo.GetBlockDeviceInfo(sysFs)
+			return func(mi *info.MachineInfo) { mi.DiskMap = diskMap }, err
+		}},
+		{"network", func() (func(*info.MachineInfo), error) {
+			netDevices, err := sysinfo.GetNetworkDevices(sysFs)
+			return func(mi *info.MachineInfo) { mi.NetworkDevices = netDevices }, err
+		}},
+		{"topology", func() (func(*info.MachineInfo), error) {
+			topology, numCores, err := GetTopology(sysFs)
+			return func(mi *info.MachineInfo) {
+				mi.Topology = topology
+				mi.NumCores = numCores
+			}, err
+		}},
+		{"pci", func() (func(*info.MachineInfo), error) {
+			pciDevices, err := GetPCIDevices()
+			return func(mi *info.MachineInfo) { mi.PCIDevices = pciDevices }, err
+		}},
+		{"kernel", func() (func(*info.MachineInfo), error) {
+			cmdline, sysctls, err := GetKernelParameters(rootFs)
+			return func(mi *info.MachineInfo) {
+				mi.KernelCmdline = cmdline
+				mi.Sysctls = sysctls
+			}, err
+		}},
+		{"host", func() (func(*info.MachineInfo), error) {
+			metadata, err := readHostMetadata(rootFs)
+			if err != nil {
+				return nil, err
+			}
+			return metadata.apply, nil
+		}},
+		{"hardware", func() (func(*info.MachineInfo), error) {
+			hardware, err := GetHardwareInfo(dmiIDDir)
+			return func(mi *info.MachineInfo) { mi.Hardware = hardware }, err
+		}},
+		{"system_uuid", func() (func(*info.MachineInfo), error) {
+			systemUUID, err := sysinfo.GetSystemUUID(sysFs)
+			return func(mi *info.MachineInfo) { mi.SystemUUID = systemUUID }, err
+		}},
+		{"cloud", func() (func(*info.MachineInfo), error) {
+			ctx, cancel := context.WithTimeout(context.Background(), options.CloudMetadataTimeout)
+			defer cancel()
+			realCloudInfo, err := cloudinfo.Detect(ctx)
+			return func(mi *info.MachineInfo) {
+				mi.CloudProvider = realCloudInfo.GetCloudProvider()
+				mi.InstanceType = realCloudInfo.GetInstanceType()
+				mi.InstanceID = realCloudInfo.GetInstanceID()
+			}, err
+		}},
 	}
 
-	systemUUID, err := sysinfo.GetSystemUUID(sysFs)
-	if err != nil {
-		klog.Errorf("Failed to get system UUID: %v", err)
+	machineInfo := &info.MachineInfo{
+		Timestamp: time.Now(),
+		MachineID: getInfoFromFiles(filepath.Join(rootFs, options.MachineIDFiles)),
+		BootID:    getInfoFromFiles(filepath.Join(rootFs, options.BootIDFiles)),
 	}
+	sections = append(sections, providerSections(sections)...)
+	gatherMachineInfoSections(machineInfo, sections, options.SectionTimeout)
+	applyMemoryCalibration(machineInfo.Topology, options.MemoryCalibration)
 
-	realCloudInfo := cloudinfo.NewRealCloudInfo()
-	cloudProvider := realCloudInfo.GetCloudProvider()
-	instanceType := realCloudInfo.GetInstanceType()
-	instanceID := realCloudInfo.GetInstanceID()
+	return machineInfo, nil
+}
 
-	machineInfo := &info.MachineInfo{
-		Timestamp:        time.Now(),
-		NumCores:         numCores,
-		NumPhysicalCores: GetPhysicalCores(cpuinfo),
-		NumSockets:       GetSockets(cpuinfo),
-		CpuFrequency:     clockSpeed,
-		MemoryCapacity:   memoryCapacity,
-		MemoryByType:     memoryByType,
-		NVMInfo:          nvmInfo,
-		HugePages:        hugePagesInfo,
-		DiskMap:          diskMap,
-		NetworkDevices:   netDevices,
-		Topology:         topology,
-		MachineID:        getInfoFromFiles(filepath.Join(rootFs, *machineIDFilePath)),
-		SystemUUID:       systemUUID,
-		BootID:           getInfoFromFiles(filepath.Join(rootFs, *bootIDFilePath)),
-		CloudProvider:    cloudProvider,
-		InstanceType:     instanceType,
-		InstanceID:       instanceID,
+// gatherMachineInfoSections collects all sections concurrently. Sections that
+// fail or do not complete within the timeout are recorded in the error report
+// of the machine info, the data of all others is stored.
+func gatherMachineInfoSections(machineInfo *info.MachineInfo, sections []machineInfoSection, timeout time.Duration) {
+	results := make(chan machineInfoSectionResult, len(sections))
+	pending := make(map[string]struct{}, len(sections))
+	for _, section := range sections {
+		pending[section.name] = struct{}{}
+		go func(section machineInfoSection) {
+			apply, err := section.collect()
+			results <- machineInfoSectionResult{name: section.name, apply: apply, err: err}
+		}(section)
 	}
 
-	for i := range filesystems {
-		fs := filesystems[i]
-		inodes := uint64(0)
-		if fs.Inodes != nil {
-			inodes = *fs.Inodes
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for len(pending) > 0 {
+		select {
+		case result := <-results:
+			delete(pending, result.name)
+			if result.err != nil {
+				klog.Errorf("Failed to get %s information: %v", result.name, result.err)
+				machineInfo.Errors = append(machineInfo.Errors, info.MachineInfoError{
+					Section: result.name,
+					Message: result.err.Error(),
+				})
+				continue
+			}
+			result.apply(machineInfo)
+		case <-timer.C:
+			for name := range pending {
+				klog.Errorf("Timed out getting %s information after %v", name, timeout)
+				machineInfo.Errors = append(machineInfo.Errors, info.MachineInfoError{
+					Section: name,
+					Message: fmt.Sprintf("timed out after %v", timeout),
+				})
+			}
+			pending = nil
 		}
-		machineInfo.Filesystems = append(machineInfo.Filesystems, info.FsInfo{Device: fs.Device, DeviceMajor: uint64(fs.Major), DeviceMinor: uint64(fs.Minor), Type: fs.Type.String(), Capacity: fs.Capacity, Inodes: inodes, HasInodes: fs.Inodes != nil})
 	}
-
-	return machineInfo, nil
+	sort.Slice(machineInfo.Errors, func(i, j int) bool {
+		return machineInfo.Errors[i].Section < machineInfo.Errors[j].Section
+	})
 }
 
 func ContainerOsVersion() string {
@@ -0,0 +1,71 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherMachineInfoSectionsPartialResults(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	sections := []machineInfoSection{
+		{"memory", func() (func(*info.MachineInfo), error) {
+			return func(mi *info.MachineInfo) { mi.MemoryCapacity = 1024 }, nil
+		}},
+		{"topology", func() (func(*info.MachineInfo), error) {
+			return func(mi *info.MachineInfo) { mi.NumCores = 8 }, fmt.Errorf("missing sysfs")
+		}},
+		{"cloud", func() (func(*info.MachineInfo), error) {
+			<-release
+			return func(mi *info.MachineInfo) { mi.InstanceID = "late" }, nil
+		}},
+	}
+
+	machineInfo := &info.MachineInfo{}
+	gatherMachineInfoSections(machineInfo, sections, 50*time.Millisecond)
+
+	assert.Equal(t, uint64(1024), machineInfo.MemoryCapacity)
+	assert.Equal(t, 0, machineInfo.NumCores)
+	assert.Equal(t, info.InstanceID(""), machineInfo.InstanceID)
+	assert.Equal(t, []info.MachineInfoError{
+		{Section: "cloud", Message: "timed out after 50ms"},
+		{Section: "topology", Message: "missing sysfs"},
+	}, machineInfo.Errors)
+}
+
+func TestGatherMachineInfoSectionsNoErrors(t *testing.T) {
+	sections := []machineInfoSection{
+		{"memory", func() (func(*info.MachineInfo), error) {
+			return func(mi *info.MachineInfo) { mi.MemoryCapacity = 1024 }, nil
+		}},
+		{"topology", func() (func(*info.MachineInfo), error) {
+			return func(mi *info.MachineInfo) { mi.NumCores = 8 }, nil
+		}},
+	}
+
+	machineInfo := &info.MachineInfo{}
+	gatherMachineInfoSections(machineInfo, sections, time.Second)
+
+	assert.Equal(t, uint64(1024), machineInfo.MemoryCapacity)
+	assert.Equal(t, 8, machineInfo.NumCores)
+	assert.Empty(t, machineInfo.Errors)
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const transparentHugepageDir = "/sys/kernel/mm/transparent_hugepage"
+
+var (
+	// Sysctls reported in the machine info. They are commonly tuned for
+	// containerized workloads, so differences between machines point to
+	// configuration drift.
+	machineSysctls = []string{
+		"fs.file-max",
+		"fs.nr_open",
+		"kernel.pid_max",
+		"net.core.somaxconn",
+		"vm.max_map_count",
+		"vm.nr_hugepages",
+		"vm.nr_overcommit_hugepages",
+		"vm.overcommit_memory",
+		"vm.overcommit_ratio",
+		"vm.swappiness",
+	}
+	// Transparent hugepage settings list all choices with the selected one in
+	// brackets, e.g. "always [madvise] never".
+	selectedChoiceRegexp = regexp.MustCompile(`\[([^\]]+)\]`)
+)
+
+// GetKernelParameters returns the command line the kernel was booted with and
+// the values of the sysctls and transparent hugepage settings relevant to
+// containers. Transparent hugepage settings are reported with the
+// "transparent_hugepage." prefix.
+func GetKernelParameters(rootFs string) (string, map[string]string, error) {
+	return getKernelParameters(filepath.Join(rootFs, "/proc"), transparentHugepageDir)
+}
+
+func getKernelParameters(procDir, thpDir string) (string, map[string]string, error) {
+	cmdline, err := readTrimmedFile(filepath.Join(procDir, "cmdline"))
+	if err != nil {
+		return "", nil, err
+	}
+
+	sysctls := make(map[string]string, len(machineSysctls)+2)
+	for _, sysctl := range machineSysctls {
+		value, err := readTrimmedFile(filepath.Join(procDir, "sys", strings.Replace(sysctl, ".", "/", -1)))
+		if err != nil {
+			// Not all sysctls exist on all kernels.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", nil, err
+		}
+		sysctls[sysctl] = value
+	}
+	for _, setting := range []string{"enabled", "defrag"} {
+		value, err := readTrimmedFile(filepath.Join(thpDir, setting))
+		if err != nil {
+			// Kernels built without transparent hugepage support.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", nil, err
+		}
+		if matches := selectedChoiceRegexp.FindStringSubmatch(value); matches != nil {
+			value = matches[1]
+		}
+		sysctls["transparent_hugepage."+setting] = value
+	}
+	return cmdline, sysctls, nil
+}
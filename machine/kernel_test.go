@@ -0,0 +1,44 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKernelParameters(t *testing.T) {
+	cmdline, sysctls, err := getKernelParameters("testdata/kernel/proc", "testdata/kernel/transparent_hugepage")
+	assert.Nil(t, err)
+	assert.Equal(t, "BOOT_IMAGE=/boot/vmlinuz-5.10.0 root=UUID=4f1c6a3e ro isolcpus=2-3 nohz_full=2-3 default_hugepagesz=1G", cmdline)
+	// Missing sysctls are not reported.
+	assert.Equal(t, map[string]string{
+		"fs.nr_open":                   "1048576",
+		"kernel.pid_max":               "4194304",
+		"net.core.somaxconn":           "4096",
+		"vm.nr_hugepages":              "4",
+		"vm.overcommit_memory":         "1",
+		"vm.overcommit_ratio":          "50",
+		"vm.swappiness":                "60",
+		"transparent_hugepage.enabled": "madvise",
+		"transparent_hugepage.defrag":  "madvise",
+	}, sysctls)
+}
+
+func TestGetKernelParametersWithoutCmdline(t *testing.T) {
+	_, _, err := getKernelParameters("testdata/missing", "testdata/missing")
+	assert.NotNil(t, err)
+}
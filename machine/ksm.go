@@ -0,0 +1,72 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const ksmDir = "/sys/kernel/mm/ksm"
+
+// GetKsmStats returns the kernel samepage merging statistics of the host.
+func GetKsmStats() (*info.KsmStats, error) {
+	return getKsmStats(ksmDir)
+}
+
+func getKsmStats(dir string) (*info.KsmStats, error) {
+	stats := &info.KsmStats{}
+	for name, value := range map[string]*uint64{
+		"pages_shared":  &stats.PagesShared,
+		"pages_sharing": &stats.PagesSharing,
+		"full_scans":    &stats.FullScans,
+	} {
+		content, err := readKsmFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		*value, err = strconv.ParseUint(content, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %v", name, err)
+		}
+	}
+
+	// general_profit is only available since Linux 6.1.
+	content, err := readKsmFile(dir, "general_profit")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		stats.GeneralProfit, err = strconv.ParseInt(content, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse general_profit: %v", err)
+		}
+	}
+	return stats, nil
+}
+
+func readKsmFile(dir, name string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
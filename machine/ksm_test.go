@@ -0,0 +1,48 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKsmStats(t *testing.T) {
+	stats, err := getKsmStats("testdata/ksm")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.KsmStats{
+		PagesShared:   1250,
+		PagesSharing:  9100,
+		FullScans:     42,
+		GeneralProfit: -4096,
+	}, stats)
+}
+
+func TestGetKsmStatsWithoutGeneralProfit(t *testing.T) {
+	stats, err := getKsmStats("testdata/ksm_old")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.KsmStats{
+		PagesShared:  7,
+		PagesSharing: 21,
+		FullScans:    3,
+	}, stats)
+}
+
+func TestGetKsmStatsWhenKsmIsNotAvailable(t *testing.T) {
+	_, err := getKsmStats("testdata/missing_ksm")
+	assert.NotNil(t, err)
+}
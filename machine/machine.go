@@ -26,9 +26,6 @@ import (
 	"strconv"
 	"strings"
 
-	// s390/s390x changes
-	"runtime"
-
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils"
 	"github.com/google/cadvisor/utils/sysfs"
@@ -56,6 +53,9 @@ var (
 
 const sysFsCPUCoreID = "core_id"
 const sysFsCPUPhysicalPackageID = "physical_package_id"
+const sysFsCPUClusterID = "cluster_id"
+const sysFsCPUBookID = "book_id"
+const sysFsCPUDrawerID = "drawer_id"
 const sysFsCPUTopology = "topology"
 const memTypeFileName = "dimm_mem_type"
 const sizeFileName = "size"
@@ -65,8 +65,10 @@ func GetPhysicalCores(procInfo []byte) int {
 	numCores := getUniqueMatchesCount(string(procInfo), coreRegExp)
 	if numCores == 0 {
 		// read number of cores from /sys/bus/cpu/devices/cpu*/topology/core_id to deal with processors
-		// for which 'core id' is not available in /proc/cpuinfo
-		numCores = getUniqueCPUPropertyCount(cpuBusPath, sysFsCPUCoreID)
+		// for which 'core id' is not available in /proc/cpuinfo. Core ids are only unique within
+		// a cluster (arm64), a socket and a book and drawer (s390x).
+		numCores = getUniqueCPUPropertyCount(cpuBusPath, sysFsCPUCoreID, sysFsCPUClusterID, sysFsCPUPhysicalPackageID,
+			sysFsCPUBookID, sysFsCPUDrawerID)
 	}
 	if numCores == 0 {
 		klog.Errorf("Cannot read number of physical cores correctly, number of cores set to %d", numCores)
@@ -79,8 +81,9 @@ func GetSockets(procInfo []byte) int {
 	numSocket := getUniqueMatchesCount(string(procInfo), nodeRegExp)
 	if numSocket == 0 {
 		// read number of sockets from /sys/bus/cpu/devices/cpu*/topology/physical_package_id to deal with processors
-		// for which 'physical id' is not available in /proc/cpuinfo
-		numSocket = getUniqueCPUPropertyCount(cpuBusPath, sysFsCPUPhysicalPackageID)
+		// for which 'physical id' is not available in /proc/cpuinfo. On s390x socket ids are only
+		// unique within a book and a drawer.
+		numSocket = getUniqueCPUPropertyCount(cpuBusPath, sysFsCPUPhysicalPackageID, sysFsCPUBookID, sysFsCPUDrawerID)
 	}
 	if numSocket == 0 {
 		klog.Errorf("Cannot read number of sockets correctly, number of sockets set to %d", numSocket)
@@ -213,10 +216,6 @@ func GetMachineSwapCapacity() (uint64, error) {
 
 // GetTopology returns CPU topology reading information from sysfs
 func GetTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
-	// s390/s390x changes
-	if isSystemZ() {
-		return nil, getNumCores(), nil
-	}
 	return sysinfo.GetNodesInfo(sysFs)
 }
 
@@ -237,8 +236,10 @@ func parseCapacity(b []byte, r *regexp.Regexp) (uint64, error) {
 }
 
 // Looks for sysfs cpu path containing given CPU property, e.g. core_id or physical_package_id
-// and returns number of unique values of given property, exemplary usage: getting number of CPU physical cores
-func getUniqueCPUPropertyCount(cpuBusPath string, propertyName string) int {
+// and returns number of unique values of given property, exemplary usage: getting number of CPU physical cores.
+// Values of the optional properties, if present, are combined with the property value, e.g. to count
+// core ids that are only unique within a socket.
+func getUniqueCPUPropertyCount(cpuBusPath string, propertyName string, optionalPropertyNames ...string) int {
 	pathPattern := cpuBusPath + "cpu*[0-9]"
 	sysCPUPaths, err := filepath.Glob(pathPattern)
 	if err != nil {
@@ -264,7 +265,14 @@ func getUniqueCPUPropertyCount(cpuBusPath string, propertyName string) int {
 			klog.Errorf("Cannot open %s, number of unique %s  set to 0", propertyPath, propertyName)
 			return 0
 		}
-		uniques[string(propertyVal)] = true
+		key := strings.TrimSpace(string(propertyVal))
+		for _, optionalPropertyName := range optionalPropertyNames {
+			optionalVal, err := ioutil.ReadFile(filepath.Join(sysCPUPath, sysFsCPUTopology, optionalPropertyName))
+			if err == nil {
+				key += "/" + strings.TrimSpace(string(optionalVal))
+			}
+		}
+		uniques[key] = true
 	}
 	return len(uniques)
 }
@@ -313,15 +321,3 @@ func isRiscv64() bool {
 func isMips64() bool {
 	return strings.Contains(machineArch, "mips64")
 }
-
-// s390/s390x changes
-func getNumCores() int {
-	maxProcs := runtime.GOMAXPROCS(0)
-	numCPU := runtime.NumCPU()
-
-	if maxProcs < numCPU {
-		return maxProcs
-	}
-
-	return numCPU
-}
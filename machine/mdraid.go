@@ -0,0 +1,170 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	mdstatFile = "/proc/mdstat"
+	sysBlock   = "/sys/block"
+)
+
+// GetMdArrayStats returns the state of the host's software RAID arrays, nil
+// if the md driver is not loaded.
+func GetMdArrayStats() ([]info.MdArrayStats, error) {
+	return getMdArrayStats(mdstatFile, sysBlock)
+}
+
+func getMdArrayStats(mdstatFile, blockDir string) ([]info.MdArrayStats, error) {
+	arrays, err := parseMdstat(mdstatFile)
+	if err != nil {
+		return nil, err
+	}
+	for i := range arrays {
+		if err := readMdAttributes(filepath.Join(blockDir, arrays[i].Name, "md"), &arrays[i]); err != nil {
+			return nil, err
+		}
+	}
+	return arrays, nil
+}
+
+// parseMdstat returns the arrays listed in mdstat with their level, members
+// and whether they are active, e.g.
+//
+//	md0 : active raid5 sdd1[3](F) sdc1[2] sdb1[1] sda1[0]
+func parseMdstat(path string) ([]info.MdArrayStats, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var arrays []info.MdArrayStats
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "md") || fields[1] != ":" {
+			continue
+		}
+		array := info.MdArrayStats{Name: fields[0], State: fields[2]}
+		for _, field := range fields[3:] {
+			switch {
+			case strings.HasPrefix(field, "("):
+				// (read-only) or (auto-read-only)
+			case strings.Contains(field, "["):
+				array.Members = append(array.Members, parseMdMember(field))
+			default:
+				array.Level = field
+			}
+		}
+		arrays = append(arrays, array)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return arrays, nil
+}
+
+// parseMdMember parses a member of an array in mdstat, e.g. sdd1[3](F).
+func parseMdMember(field string) info.MdMember {
+	index := strings.Index(field, "[")
+	member := info.MdMember{Device: field[:index]}
+	flags := field[index:]
+	member.Faulty = strings.Contains(flags, "(F)")
+	member.Spare = strings.Contains(flags, "(S)")
+	return member
+}
+
+// readMdAttributes reads the state and sync progress of an array from its md
+// directory in sysfs. Attributes which do not apply, e.g. degraded for RAID0
+// arrays, are missing and left unset.
+func readMdAttributes(dir string, array *info.MdArrayStats) error {
+	read := func(name string) (string, error) {
+		value, err := readTrimmedFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return value, err
+	}
+	readUint := func(name string, dest *uint64) error {
+		value, err := read(name)
+		if err != nil || value == "" || value == "none" {
+			return err
+		}
+		*dest, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s of %s: %v", name, array.Name, err)
+		}
+		return nil
+	}
+
+	state, err := read("array_state")
+	if err != nil {
+		return err
+	}
+	if state != "" {
+		array.State = state
+	}
+	if err := readUint("raid_disks", &array.Disks); err != nil {
+		return err
+	}
+	if err := readUint("degraded", &array.Degraded); err != nil {
+		return err
+	}
+	if array.SyncAction, err = read("sync_action"); err != nil {
+		return err
+	}
+	if err := readUint("sync_speed", &array.SyncSpeed); err != nil {
+		return err
+	}
+
+	// Sectors done and to do, e.g. "89600 / 1046528", or none when idle.
+	completed, err := read("sync_completed")
+	if err != nil {
+		return err
+	}
+	var done, total uint64
+	if n, _ := fmt.Sscanf(completed, "%d / %d", &done, &total); n == 2 && total > 0 {
+		array.SyncCompleted = float64(done) / float64(total)
+	}
+	return nil
+}
+
+// MdArrayState returns the part of the state of an array whose transitions
+// are reported as events.
+func MdArrayState(array *info.MdArrayStats) *info.MdArrayState {
+	state := &info.MdArrayState{
+		State:      array.State,
+		Degraded:   array.Degraded,
+		SyncAction: array.SyncAction,
+	}
+	for _, member := range array.Members {
+		if member.Faulty {
+			state.FailedMembers = append(state.FailedMembers, member.Device)
+		}
+	}
+	return state
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMdArrayStats(t *testing.T) {
+	arrays, err := getMdArrayStats("testdata/mdraid/mdstat", "testdata/mdraid/block")
+	assert.Nil(t, err)
+	assert.Equal(t, []info.MdArrayStats{
+		{
+			Name:       "md1",
+			Level:      "raid1",
+			State:      "clean",
+			Disks:      2,
+			Members:    []info.MdMember{{Device: "sdb2"}, {Device: "sda2"}},
+			SyncAction: "idle",
+		},
+		{
+			Name:     "md0",
+			Level:    "raid5",
+			State:    "active",
+			Disks:    4,
+			Degraded: 1,
+			Members: []info.MdMember{
+				{Device: "sde1", Spare: true},
+				{Device: "sdd1", Faulty: true},
+				{Device: "sdc1"},
+				{Device: "sdb1"},
+				{Device: "sda1"},
+			},
+			SyncAction:    "recover",
+			SyncCompleted: 89600.0 / 1046528.0,
+			SyncSpeed:     22400,
+		},
+		{
+			Name:    "md127",
+			State:   "inactive",
+			Members: []info.MdMember{{Device: "sdf", Spare: true}},
+		},
+	}, arrays)
+
+	assert.Equal(t, &info.MdArrayState{
+		State:         "active",
+		Degraded:      1,
+		FailedMembers: []string{"sdd1"},
+		SyncAction:    "recover",
+	}, MdArrayState(&arrays[1]))
+}
+
+func TestGetMdArrayStatsWithoutMd(t *testing.T) {
+	arrays, err := getMdArrayStats("testdata/missing", "testdata/missing")
+	assert.Nil(t, err)
+	assert.Nil(t, arrays)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const pciDevicesDir = "/sys/bus/pci/devices"
+
+// GetPCIDevices returns the PCI devices of the host with their NUMA node and
+// IOMMU group, sorted by address.
+func GetPCIDevices() ([]info.PCIDevice, error) {
+	return getPCIDevices(pciDevicesDir)
+}
+
+func getPCIDevices(dir string) ([]info.PCIDevice, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Machines without a PCI bus, e.g. some ARM boards.
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// ReadDir returns the entries sorted by name, i.e. by address.
+	devices := make([]info.PCIDevice, 0, len(entries))
+	for _, entry := range entries {
+		devicePath := filepath.Join(dir, entry.Name())
+		device := info.PCIDevice{
+			Address:    entry.Name(),
+			NumaNode:   -1,
+			IommuGroup: -1,
+		}
+		if device.Class, err = readTrimmedFile(filepath.Join(devicePath, "class")); err != nil {
+			return nil, err
+		}
+		if device.Vendor, err = readTrimmedFile(filepath.Join(devicePath, "vendor")); err != nil {
+			return nil, err
+		}
+		if device.Device, err = readTrimmedFile(filepath.Join(devicePath, "device")); err != nil {
+			return nil, err
+		}
+		// Not available on kernels built without NUMA support.
+		if node, err := readIntFile(filepath.Join(devicePath, "numa_node")); err == nil {
+			device.NumaNode = int(node)
+		}
+		if group, err := os.Readlink(filepath.Join(devicePath, "iommu_group")); err == nil {
+			if id, err := strconv.Atoi(filepath.Base(group)); err == nil {
+				device.IommuGroup = id
+			}
+		}
+		if driver, err := os.Readlink(filepath.Join(devicePath, "driver")); err == nil {
+			device.Driver = filepath.Base(driver)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PCI addresses contain colons which are not allowed in file names of Go
+// modules, so the sysfs tree is created by the test.
+func makePCIDevice(t *testing.T, dir, address string, files map[string]string, links map[string]string) {
+	devicePath := filepath.Join(dir, address)
+	require.NoError(t, os.MkdirAll(devicePath, 0755))
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(devicePath, name), []byte(content+"\n"), 0644))
+	}
+	for name, target := range links {
+		require.NoError(t, os.Symlink(target, filepath.Join(devicePath, name)))
+	}
+}
+
+func TestGetPCIDevices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pci")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	makePCIDevice(t, dir, "0000:5e:00.0",
+		map[string]string{"class": "0x010802", "vendor": "0x144d", "device": "0xa808", "numa_node": "1"},
+		map[string]string{"iommu_group": "../../../kernel/iommu_groups/13", "driver": "../../../bus/pci/drivers/nvme"})
+	makePCIDevice(t, dir, "0000:00:00.0",
+		map[string]string{"class": "0x060000", "vendor": "0x8086", "device": "0x2020", "numa_node": "-1"},
+		nil)
+	// Kernels without NUMA support have no numa_node file.
+	makePCIDevice(t, dir, "0000:af:00.0",
+		map[string]string{"class": "0x030200", "vendor": "0x10de", "device": "0x1eb8"},
+		map[string]string{"iommu_group": "../../../kernel/iommu_groups/2"})
+
+	devices, err := getPCIDevices(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []info.PCIDevice{
+		{Address: "0000:00:00.0", Class: "0x060000", Vendor: "0x8086", Device: "0x2020", NumaNode: -1, IommuGroup: -1},
+		{Address: "0000:5e:00.0", Class: "0x010802", Vendor: "0x144d", Device: "0xa808", NumaNode: 1, IommuGroup: 13, Driver: "nvme"},
+		{Address: "0000:af:00.0", Class: "0x030200", Vendor: "0x10de", Device: "0x1eb8", NumaNode: -1, IommuGroup: 2},
+	}, devices)
+}
+
+func TestGetPCIDevicesWithoutPCIBus(t *testing.T) {
+	devices, err := getPCIDevices("testdata/pci_missing")
+	assert.Nil(t, err)
+	assert.Empty(t, devices)
+}
+
+func TestGetPCIDevicesWithMissingClass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pci")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	makePCIDevice(t, dir, "0000:00:00.0", map[string]string{"vendor": "0x8086", "device": "0x2020"}, nil)
+
+	_, err = getPCIDevices(dir)
+	assert.NotNil(t, err)
+}
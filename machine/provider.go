@@ -0,0 +1,106 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// InfoProvider provides a fragment of the machine information which cAdvisor
+// does not gather itself, e.g. vendor specific hardware inventory.
+type InfoProvider interface {
+	// Fragment returns the information to report. It is stored as JSON in
+	// MachineInfo.Extensions under the name of the provider; a nil fragment
+	// stores nothing.
+	Fragment() (interface{}, error)
+}
+
+// InfoProviderFunc adapts a function to the InfoProvider interface.
+type InfoProviderFunc func() (interface{}, error)
+
+func (f InfoProviderFunc) Fragment() (interface{}, error) {
+	return f()
+}
+
+var (
+	infoProvidersLock sync.Mutex
+	infoProviders     = map[string]InfoProvider{}
+)
+
+// RegisterInfoProvider registers a provider of additional machine
+// information, usually from the init function of the package implementing it.
+// Providers are gathered like the other sections of the machine information:
+// concurrently, subject to --machine_info_section_timeout, with failures
+// reported in MachineInfo.Errors under the name of the provider.
+func RegisterInfoProvider(name string, provider InfoProvider) {
+	infoProvidersLock.Lock()
+	defer infoProvidersLock.Unlock()
+	if _, alreadyRegistered := infoProviders[name]; alreadyRegistered {
+		klog.Warningf("Duplicate registration of machine info provider %s", name)
+	}
+	infoProviders[name] = provider
+}
+
+// providerSections returns a section per registered provider, sorted by name.
+// Providers named after one of the builtin sections are skipped.
+func providerSections(builtin []machineInfoSection) []machineInfoSection {
+	reserved := make(map[string]struct{}, len(builtin))
+	for _, section := range builtin {
+		reserved[section.name] = struct{}{}
+	}
+
+	infoProvidersLock.Lock()
+	defer infoProvidersLock.Unlock()
+	sections := make([]machineInfoSection, 0, len(infoProviders))
+	for name, provider := range infoProviders {
+		if _, ok := reserved[name]; ok {
+			klog.Warningf("Ignoring machine info provider %s, its name is reserved for a builtin section", name)
+			continue
+		}
+		sections = append(sections, providerSection(name, provider))
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].name < sections[j].name
+	})
+	return sections
+}
+
+func providerSection(name string, provider InfoProvider) machineInfoSection {
+	return machineInfoSection{name, func() (func(*info.MachineInfo), error) {
+		fragment, err := provider.Fragment()
+		if err != nil {
+			return nil, err
+		}
+		if fragment == nil {
+			return func(*info.MachineInfo) {}, nil
+		}
+		data, err := json.Marshal(fragment)
+		if err != nil {
+			return nil, err
+		}
+		return func(mi *info.MachineInfo) {
+			if mi.Extensions == nil {
+				mi.Extensions = make(map[string]json.RawMessage)
+			}
+			mi.Extensions[name] = data
+		}, nil
+	}}
+}
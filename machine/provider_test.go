@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfoProviders(t *testing.T) {
+	defer func() {
+		infoProvidersLock.Lock()
+		infoProviders = map[string]InfoProvider{}
+		infoProvidersLock.Unlock()
+	}()
+
+	RegisterInfoProvider("dmi", InfoProviderFunc(func() (interface{}, error) {
+		return map[string]string{"bios_vendor": "SeaBIOS"}, nil
+	}))
+	RegisterInfoProvider("broken", InfoProviderFunc(func() (interface{}, error) {
+		return nil, fmt.Errorf("no such device")
+	}))
+	RegisterInfoProvider("empty", InfoProviderFunc(func() (interface{}, error) {
+		return nil, nil
+	}))
+	RegisterInfoProvider("memory", InfoProviderFunc(func() (interface{}, error) {
+		return "shadowed", nil
+	}))
+
+	builtin := []machineInfoSection{
+		{"memory", func() (func(*info.MachineInfo), error) {
+			return func(mi *info.MachineInfo) { mi.MemoryCapacity = 1024 }, nil
+		}},
+	}
+	sections := providerSections(builtin)
+	var names []string
+	for _, section := range sections {
+		names = append(names, section.name)
+	}
+	assert.Equal(t, []string{"broken", "dmi", "empty"}, names)
+
+	machineInfo := &info.MachineInfo{}
+	gatherMachineInfoSections(machineInfo, append(builtin, sections...), time.Second)
+
+	assert.Equal(t, uint64(1024), machineInfo.MemoryCapacity)
+	assert.Equal(t, map[string]json.RawMessage{
+		"dmi": json.RawMessage(`{"bios_vendor":"SeaBIOS"}`),
+	}, machineInfo.Extensions)
+	assert.Equal(t, []info.MachineInfoError{
+		{Section: "broken", Message: "no such device"},
+	}, machineInfo.Errors)
+
+	clone := machineInfo.Clone()
+	assert.Equal(t, machineInfo.Extensions, clone.Extensions)
+	clone.Extensions["other"] = json.RawMessage(`{}`)
+	assert.NotContains(t, machineInfo.Extensions, "other")
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	iscsiSessionDir    = "/sys/class/iscsi_session"
+	iscsiConnectionDir = "/sys/class/iscsi_connection"
+	nvmeCtrlDir        = "/sys/class/nvme"
+
+	iscsiLoggedIn = "LOGGED_IN"
+	nvmeLive      = "live"
+)
+
+var (
+	iscsiSessionRegexp = regexp.MustCompile(`^session([0-9]+)$`)
+	nvmeCtrlRegexp     = regexp.MustCompile(`^nvme[0-9]+$`)
+	// Namespaces of a controller: nvme0n1, or nvme0c1n1 for the path of
+	// controller 1 to namespace nvme0n1 when native multipathing is enabled.
+	nvmeNamespaceRegexp = regexp.MustCompile(`^(nvme[0-9]+)(c[0-9]+)?(n[0-9]+)$`)
+)
+
+// StorageSessionMonitor reads the state of the host's sessions with network
+// attached storage. It counts the reconnects of every session across reads.
+type StorageSessionMonitor struct {
+	iscsiSessionDir    string
+	iscsiConnectionDir string
+	nvmeCtrlDir        string
+
+	connected  map[string]bool
+	reconnects map[string]uint64
+}
+
+// NewStorageSessionMonitor returns a StorageSessionMonitor reading the
+// sessions of the host.
+func NewStorageSessionMonitor() *StorageSessionMonitor {
+	return newStorageSessionMonitor(iscsiSessionDir, iscsiConnectionDir, nvmeCtrlDir)
+}
+
+func newStorageSessionMonitor(iscsiSessionDir, iscsiConnectionDir, nvmeCtrlDir string) *StorageSessionMonitor {
+	return &StorageSessionMonitor{
+		iscsiSessionDir:    iscsiSessionDir,
+		iscsiConnectionDir: iscsiConnectionDir,
+		nvmeCtrlDir:        nvmeCtrlDir,
+		connected:          map[string]bool{},
+		reconnects:         map[string]uint64{},
+	}
+}
+
+// Read returns the iSCSI sessions and NVMe over Fabrics controllers of the
+// host, sorted by transport and name.
+func (m *StorageSessionMonitor) Read() ([]info.StorageSessionStats, error) {
+	sessions, err := m.readIscsiSessions()
+	if err != nil {
+		return nil, err
+	}
+	controllers, err := m.readNvmeControllers()
+	if err != nil {
+		return nil, err
+	}
+	sessions = append(sessions, controllers...)
+
+	paths := map[string]uint64{}
+	connectedPaths := map[string]uint64{}
+	for _, session := range sessions {
+		paths[session.Target]++
+		if session.Connected {
+			connectedPaths[session.Target]++
+		}
+	}
+
+	// Sessions are keyed by their target too, the kernel reuses the names of
+	// deleted sessions.
+	seen := make(map[string]struct{}, len(sessions))
+	for i := range sessions {
+		session := &sessions[i]
+		session.Paths = paths[session.Target]
+		session.ConnectedPaths = connectedPaths[session.Target]
+
+		key := session.Transport + "/" + session.Name + "/" + session.Target
+		seen[key] = struct{}{}
+		if wasConnected, ok := m.connected[key]; ok && wasConnected && !session.Connected {
+			m.reconnects[key]++
+		}
+		m.connected[key] = session.Connected
+		session.Reconnects = m.reconnects[key]
+	}
+	for key := range m.connected {
+		if _, ok := seen[key]; !ok {
+			delete(m.connected, key)
+			delete(m.reconnects, key)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Transport != sessions[j].Transport {
+			return sessions[i].Transport < sessions[j].Transport
+		}
+		return sessions[i].Name < sessions[j].Name
+	})
+	return sessions, nil
+}
+
+// readIscsiSessions reads the sessions of the iscsi_session class, e.g.
+// session1/targetname, session1/state and the disks of its SCSI targets in
+// session1/device/target2:0:0/2:0:0:1/block/sdb.
+func (m *StorageSessionMonitor) readIscsiSessions() ([]info.StorageSessionStats, error) {
+	entries, err := ioutil.ReadDir(m.iscsiSessionDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var sessions []info.StorageSessionStats
+	for _, entry := range entries {
+		match := iscsiSessionRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		dir := filepath.Join(m.iscsiSessionDir, entry.Name())
+		session := info.StorageSessionStats{Name: entry.Name(), Transport: "iscsi"}
+		if session.Target, err = readTrimmedFile(filepath.Join(dir, "targetname")); err != nil {
+			if os.IsNotExist(err) {
+				// The session was deleted meanwhile.
+				continue
+			}
+			return nil, err
+		}
+		if session.State, err = readTrimmedFile(filepath.Join(dir, "state")); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		session.Connected = session.State == iscsiLoggedIn
+
+		// The address of the leading connection, connection<sid>:0.
+		connection := filepath.Join(m.iscsiConnectionDir, "connection"+match[1]+":0")
+		address, addressErr := readTrimmedFile(filepath.Join(connection, "persistent_address"))
+		port, portErr := readTrimmedFile(filepath.Join(connection, "persistent_port"))
+		if addressErr == nil && portErr == nil {
+			session.Address = net.JoinHostPort(address, port)
+		}
+
+		devices, err := filepath.Glob(filepath.Join(dir, "device", "target*", "*", "block", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range devices {
+			session.Devices = append(session.Devices, filepath.Base(device))
+		}
+		sort.Strings(session.Devices)
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// readNvmeControllers reads the controllers of the nvme class connected over
+// a fabric, e.g. nvme1/transport, nvme1/subsysnqn, nvme1/state and its
+// namespaces nvme1/nvme1n1. Controllers attached over PCIe are skipped.
+func (m *StorageSessionMonitor) readNvmeControllers() ([]info.StorageSessionStats, error) {
+	entries, err := ioutil.ReadDir(m.nvmeCtrlDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var controllers []info.StorageSessionStats
+	for _, entry := range entries {
+		if !nvmeCtrlRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		dir := filepath.Join(m.nvmeCtrlDir, entry.Name())
+		transport, err := readTrimmedFile(filepath.Join(dir, "transport"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if transport == "pcie" {
+			continue
+		}
+
+		controller := info.StorageSessionStats{Name: entry.Name(), Transport: "nvme-" + transport}
+		read := func(name string) (string, error) {
+			value, err := readTrimmedFile(filepath.Join(dir, name))
+			if os.IsNotExist(err) {
+				return "", nil
+			}
+			return value, err
+		}
+		if controller.Target, err = read("subsysnqn"); err != nil {
+			return nil, err
+		}
+		if controller.Address, err = read("address"); err != nil {
+			return nil, err
+		}
+		if controller.State, err = read("state"); err != nil {
+			return nil, err
+		}
+		controller.Connected = controller.State == nvmeLive
+
+		namespaces, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range namespaces {
+			match := nvmeNamespaceRegexp.FindStringSubmatch(namespace.Name())
+			if match == nil {
+				continue
+			}
+			// The paths of a multipath namespace are hidden, IO is issued
+			// to the namespace itself.
+			controller.Devices = append(controller.Devices, match[1]+match[3])
+		}
+		sort.Strings(controller.Devices)
+		controllers = append(controllers, controller)
+	}
+	return controllers, nil
+}
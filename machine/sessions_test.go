@@ -0,0 +1,142 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIqn = "iqn.2021-01.com.example:storage.lun1"
+	testNqn = "nqn.2014-08.org.nvmexpress:uuid:0c9a4a5e-4b54-4f5b-8d38-d2a0f1a8e6a1"
+)
+
+// writeSysfs creates the files of a sysfs tree, directories for empty
+// contents.
+func writeSysfs(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if content == "" {
+			require.NoError(t, os.MkdirAll(path, 0755))
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+}
+
+func TestStorageSessionMonitor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessions")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeSysfs(t, dir, map[string]string{
+		"iscsi_session/session1/targetname":                           testIqn,
+		"iscsi_session/session1/state":                                "LOGGED_IN",
+		"iscsi_session/session1/device/target2:0:0/2:0:0:1/block/sdc": "",
+		"iscsi_session/session1/device/target2:0:0/2:0:0:0/block/sdb": "",
+		"iscsi_session/session2/targetname":                           testIqn,
+		"iscsi_session/session2/state":                                "LOGGED_IN",
+		"iscsi_session/session2/device/target3:0:0/3:0:0:0/block/sdd": "",
+		"iscsi_connection/connection1:0/persistent_address":           "10.0.0.1",
+		"iscsi_connection/connection1:0/persistent_port":              "3260",
+		"iscsi_connection/connection2:0/persistent_address":           "fd00::2",
+		"iscsi_connection/connection2:0/persistent_port":              "3260",
+		"nvme/nvme0/transport":                                        "pcie",
+		"nvme/nvme0/nvme0n1":                                          "",
+		"nvme/nvme1/transport":                                        "tcp",
+		"nvme/nvme1/subsysnqn":                                        testNqn,
+		"nvme/nvme1/address":                                          "traddr=10.0.1.1,trsvcid=4420",
+		"nvme/nvme1/state":                                            "connecting",
+		"nvme/nvme1/nvme1c1n1":                                        "",
+		"nvme/nvme1/nvme1c1n2":                                        "",
+		"nvme/nvme-fabrics/dev":                                       "10:123",
+	})
+
+	monitor := newStorageSessionMonitor(filepath.Join(dir, "iscsi_session"), filepath.Join(dir, "iscsi_connection"), filepath.Join(dir, "nvme"))
+	sessions, err := monitor.Read()
+	require.NoError(t, err)
+	assert.Equal(t, []info.StorageSessionStats{
+		{
+			Name:           "session1",
+			Transport:      "iscsi",
+			Target:         testIqn,
+			Address:        "10.0.0.1:3260",
+			State:          "LOGGED_IN",
+			Connected:      true,
+			Paths:          2,
+			ConnectedPaths: 2,
+			Devices:        []string{"sdb", "sdc"},
+		},
+		{
+			Name:           "session2",
+			Transport:      "iscsi",
+			Target:         testIqn,
+			Address:        "[fd00::2]:3260",
+			State:          "LOGGED_IN",
+			Connected:      true,
+			Paths:          2,
+			ConnectedPaths: 2,
+			Devices:        []string{"sdd"},
+		},
+		{
+			Name:           "nvme1",
+			Transport:      "nvme-tcp",
+			Target:         testNqn,
+			Address:        "traddr=10.0.1.1,trsvcid=4420",
+			State:          "connecting",
+			Paths:          1,
+			ConnectedPaths: 0,
+			Devices:        []string{"nvme1n1", "nvme1n2"},
+		},
+	}, sessions)
+
+	// session2 fails and the NVMe controller connects.
+	writeSysfs(t, dir, map[string]string{
+		"iscsi_session/session2/state": "FAILED",
+		"nvme/nvme1/state":             "live",
+	})
+	sessions, err = monitor.Read()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), sessions[0].ConnectedPaths)
+	assert.Equal(t, uint64(0), sessions[0].Reconnects)
+	assert.False(t, sessions[1].Connected)
+	assert.Equal(t, uint64(1), sessions[1].Reconnects)
+	assert.True(t, sessions[2].Connected)
+	assert.Equal(t, uint64(0), sessions[2].Reconnects)
+
+	// session2 recovers; the counter is kept.
+	writeSysfs(t, dir, map[string]string{
+		"iscsi_session/session2/state": "LOGGED_IN",
+	})
+	sessions, err = monitor.Read()
+	require.NoError(t, err)
+	assert.True(t, sessions[1].Connected)
+	assert.Equal(t, uint64(1), sessions[1].Reconnects)
+	assert.Equal(t, uint64(2), sessions[1].ConnectedPaths)
+}
+
+func TestStorageSessionMonitorMissing(t *testing.T) {
+	monitor := newStorageSessionMonitor("testdata/missing", "testdata/missing", "testdata/missing")
+	sessions, err := monitor.Read()
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	procStat = "/proc/stat"
+
+	// USER_HZ, the unit of CPU times in /proc/stat, which is 100 on all
+	// architectures supported by cAdvisor.
+	userHZ = 100
+)
+
+// GetStealAndGuestTime returns the CPU time stolen from the host by the
+// hypervisor and the CPU time the host spent running guests, in nanoseconds.
+func GetStealAndGuestTime() (steal, guest uint64, err error) {
+	return getStealAndGuestTime(procStat)
+}
+
+func getStealAndGuestTime(path string) (uint64, uint64, error) {
+	times, err := readProcStatCPU(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return times[7], times[8] + times[9], nil
+}
+
+// GetBusyCPUTime returns the CPU time the host spent running tasks and
+// interrupts, in nanoseconds.
+func GetBusyCPUTime() (uint64, error) {
+	return getBusyCPUTime(procStat)
+}
+
+func getBusyCPUTime(path string) (uint64, error) {
+	times, err := readProcStatCPU(path)
+	if err != nil {
+		return 0, err
+	}
+	// user, nice, system, irq and softirq; guest time is included in user.
+	return times[0] + times[1] + times[2] + times[5] + times[6], nil
+}
+
+// readProcStatCPU returns the aggregated CPU times of the cpu line of
+// /proc/stat: user, nice, system, idle, iowait, irq, softirq, steal, guest and
+// guest_nice, in nanoseconds.
+func readProcStatCPU(path string) ([]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		if len(fields) < 11 {
+			return nil, fmt.Errorf("unexpected number of fields in the cpu line of %s", path)
+		}
+		times := make([]uint64, 10)
+		for i, field := range fields[1:11] {
+			ticks, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+			}
+			times[i] = ticks * uint64(time.Second/userHZ)
+		}
+		return times, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no cpu line found in %s", path)
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStealAndGuestTime(t *testing.T) {
+	steal, guest, err := getStealAndGuestTime("testdata/proc_stat")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(15200000000), steal)
+	assert.Equal(t, uint64(8420000000), guest)
+
+	_, _, err = getStealAndGuestTime("testdata/missing")
+	assert.NotNil(t, err)
+}
+
+func TestGetBusyCPUTime(t *testing.T) {
+	busy, err := getBusyCPUTime("testdata/proc_stat")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(59220000000), busy)
+}
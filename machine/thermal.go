@@ -0,0 +1,170 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const thermalDir = "/sys/class/thermal"
+
+var (
+	thermalZoneRegexp = regexp.MustCompile(`^thermal_zone([0-9]+)$`)
+	tripPointRegexp   = regexp.MustCompile(`^trip_point_([0-9]+)_type$`)
+	fanInputRegexp    = regexp.MustCompile(`^fan([0-9]+)_input$`)
+)
+
+// GetThermalStats returns the temperatures of the host's thermal zones and the
+// speed of the fans reported by hwmon drivers.
+func GetThermalStats() (*info.ThermalStats, error) {
+	return getThermalStats(thermalDir, hwmonDir)
+}
+
+func getThermalStats(thermalDir, hwmonDir string) (*info.ThermalStats, error) {
+	zones, err := getThermalZones(thermalDir)
+	if err != nil {
+		return nil, err
+	}
+	fans, err := getFans(hwmonDir)
+	if err != nil {
+		return nil, err
+	}
+	return &info.ThermalStats{Zones: zones, Fans: fans}, nil
+}
+
+func getThermalZones(dir string) ([]info.ThermalZoneStats, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var zones []info.ThermalZoneStats
+	for _, entry := range entries {
+		if !thermalZoneRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		zoneDir := filepath.Join(dir, entry.Name())
+		// Reading the temperature fails for zones whose sensor is not ready or
+		// disabled, they are skipped.
+		temp, err := readIntFile(filepath.Join(zoneDir, "temp"))
+		if err != nil {
+			continue
+		}
+		zoneType, err := readTrimmedFile(filepath.Join(zoneDir, "type"))
+		if err != nil {
+			return nil, err
+		}
+		zone := info.ThermalZoneStats{
+			Zone:        entry.Name(),
+			Type:        zoneType,
+			Temperature: temp,
+		}
+		files, err := ioutil.ReadDir(zoneDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			matches := tripPointRegexp.FindStringSubmatch(file.Name())
+			if matches == nil {
+				continue
+			}
+			tripType, err := readTrimmedFile(filepath.Join(zoneDir, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			tripTemp, err := readIntFile(filepath.Join(zoneDir, "trip_point_"+matches[1]+"_temp"))
+			if err != nil {
+				return nil, err
+			}
+			index, _ := strconv.Atoi(matches[1])
+			zone.TripPoints = append(zone.TripPoints, info.ThermalTripPoint{
+				Index:       index,
+				Type:        tripType,
+				Temperature: tripTemp,
+			})
+		}
+		sort.Slice(zone.TripPoints, func(i, j int) bool { return zone.TripPoints[i].Index < zone.TripPoints[j].Index })
+		zones = append(zones, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zoneIndex(zones[i].Zone) < zoneIndex(zones[j].Zone) })
+	return zones, nil
+}
+
+func zoneIndex(zone string) int {
+	index, _ := strconv.Atoi(strings.TrimPrefix(zone, "thermal_zone"))
+	return index
+}
+
+func getFans(dir string) ([]info.FanStats, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fans []info.FanStats
+	for _, entry := range entries {
+		chipDir := filepath.Join(dir, entry.Name())
+		files, err := ioutil.ReadDir(chipDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			matches := fanInputRegexp.FindStringSubmatch(file.Name())
+			if matches == nil {
+				continue
+			}
+			speed, err := readUintFile(filepath.Join(chipDir, file.Name()))
+			if err != nil {
+				// Disconnected fans fail to read.
+				continue
+			}
+			chip, err := readTrimmedFile(filepath.Join(chipDir, "name"))
+			if err != nil {
+				return nil, err
+			}
+			label, err := readTrimmedFile(filepath.Join(chipDir, "fan"+matches[1]+"_label"))
+			if err != nil {
+				label = "fan" + matches[1]
+			}
+			fans = append(fans, info.FanStats{
+				Device: entry.Name(),
+				Chip:   chip,
+				Fan:    label,
+				Speed:  speed,
+			})
+		}
+	}
+	return fans, nil
+}
+
+func readIntFile(path string) (int64, error) {
+	content, err := readTrimmedFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(content, 10, 64)
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetThermalStats(t *testing.T) {
+	stats, err := getThermalStats("testdata/thermal", "testdata/thermal_hwmon")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.ThermalStats{
+		// thermal_zone1 has no temperature and is skipped.
+		Zones: []info.ThermalZoneStats{
+			{
+				Zone:        "thermal_zone0",
+				Type:        "acpitz",
+				Temperature: 27800,
+				TripPoints: []info.ThermalTripPoint{
+					{Index: 0, Type: "critical", Temperature: 119000},
+				},
+			}, {
+				Zone:        "thermal_zone10",
+				Type:        "x86_pkg_temp",
+				Temperature: 54000,
+				TripPoints: []info.ThermalTripPoint{
+					{Index: 0, Type: "active", Temperature: 70000},
+					{Index: 1, Type: "passive", Temperature: 95000},
+				},
+			},
+		},
+		Fans: []info.FanStats{
+			{Device: "hwmon1", Chip: "nct6775", Fan: "fan1", Speed: 1250},
+			{Device: "hwmon1", Chip: "nct6775", Fan: "CPU Fan", Speed: 0},
+		},
+	}, stats)
+}
+
+func TestGetThermalStatsMissing(t *testing.T) {
+	stats, err := getThermalStats("testdata/missing", "testdata/missing")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.ThermalStats{}, stats)
+}
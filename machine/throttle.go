@@ -0,0 +1,97 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// GetThermalThrottleStats returns the thermal throttling counters of the
+// host's CPUs, exposed by the x86 thermal_throttle sysfs interface.
+func GetThermalThrottleStats() (*info.ThermalThrottleStats, error) {
+	return getThermalThrottleStats(cpuSysfsDir)
+}
+
+func getThermalThrottleStats(dir string) (*info.ThermalThrottleStats, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	stats := &info.ThermalThrottleStats{}
+	packages := map[int]bool{}
+	for _, entry := range entries {
+		matches := cpuDirRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		throttleDir := filepath.Join(dir, entry.Name(), "thermal_throttle")
+		if _, err := os.Stat(throttleDir); os.IsNotExist(err) {
+			// Offline CPUs and CPUs without thermal monitoring.
+			continue
+		}
+		cpu, _ := strconv.Atoi(matches[1])
+		core := info.CoreThrottleStats{Cpu: cpu}
+		core.Count, core.Time, err = readThrottleCounters(throttleDir, "core")
+		if err != nil {
+			return nil, err
+		}
+		stats.Cores = append(stats.Cores, core)
+
+		// Package counters are repeated for every CPU of the package.
+		id, err := readUintFile(filepath.Join(dir, entry.Name(), "topology", "physical_package_id"))
+		if err != nil {
+			return nil, err
+		}
+		if packages[int(id)] {
+			continue
+		}
+		packages[int(id)] = true
+		pkg := info.PackageThrottleStats{Package: int(id)}
+		pkg.Count, pkg.Time, err = readThrottleCounters(throttleDir, "package")
+		if err != nil {
+			return nil, err
+		}
+		stats.Packages = append(stats.Packages, pkg)
+	}
+	if len(stats.Cores) == 0 {
+		return nil, fmt.Errorf("no CPU in %s exposes thermal throttling counters", dir)
+	}
+	sort.Slice(stats.Cores, func(i, j int) bool { return stats.Cores[i].Cpu < stats.Cores[j].Cpu })
+	sort.Slice(stats.Packages, func(i, j int) bool { return stats.Packages[i].Package < stats.Packages[j].Package })
+	return stats, nil
+}
+
+// readThrottleCounters returns the number of throttling events of the given
+// scope and the time spent throttled, in nanoseconds.
+func readThrottleCounters(dir, scope string) (uint64, uint64, error) {
+	count, err := readUintFile(filepath.Join(dir, scope+"_throttle_count"))
+	if err != nil {
+		return 0, 0, err
+	}
+	// The total time is only available since Linux 5.7.
+	ms, err := readUintFile(filepath.Join(dir, scope+"_throttle_total_time_ms"))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, err
+	}
+	return count, ms * uint64(time.Millisecond), nil
+}
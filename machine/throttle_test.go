@@ -0,0 +1,50 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package machine
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetThermalThrottleStats(t *testing.T) {
+	stats, err := getThermalThrottleStats("testdata/throttle")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.ThermalThrottleStats{
+		Cores: []info.CoreThrottleStats{
+			{Cpu: 0, Count: 3, Time: 50000000},
+			{Cpu: 1, Count: 13, Time: 150000000},
+			{Cpu: 2, Count: 23, Time: 250000000},
+		},
+		Packages: []info.PackageThrottleStats{
+			{Package: 0, Count: 7, Time: 1200000000},
+			{Package: 1, Count: 2, Time: 300000000},
+		},
+	}, stats)
+}
+
+func TestGetThermalThrottleStatsWithoutTime(t *testing.T) {
+	stats, err := getThermalThrottleStats("testdata/throttle_old")
+	assert.Nil(t, err)
+	assert.Equal(t, &info.ThermalThrottleStats{
+		Cores:    []info.CoreThrottleStats{{Cpu: 0, Count: 4}},
+		Packages: []info.PackageThrottleStats{{Package: 0, Count: 1}},
+	}, stats)
+
+	_, err = getThermalThrottleStats("testdata/ksm")
+	assert.NotNil(t, err)
+}
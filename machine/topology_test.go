@@ -63,6 +63,18 @@ func TestPhysicalCoresFromWrongSysFs(t *testing.T) {
 	assert.Equal(t, 0, numPhysicalCores)
 }
 
+func TestPhysicalCoresReadingFromCpuBusWithClusters(t *testing.T) {
+	cpuBusPath = "./testdata/arm64_clusters/" // overwriting package variable to mock sysfs
+	testfile := "./testdata/cpuinfo_arm"      // mock cpuinfo without core id
+
+	testcpuinfo, err := ioutil.ReadFile(testfile)
+	assert.Nil(t, err)
+	assert.NotNil(t, testcpuinfo)
+
+	numPhysicalCores := GetPhysicalCores(testcpuinfo)
+	assert.Equal(t, 4, numPhysicalCores)
+}
+
 func TestSockets(t *testing.T) {
 	testfile := "./testdata/cpuinfo"
 
@@ -382,11 +394,58 @@ func TestTopologyWithNodesWithoutCPU(t *testing.T) {
 }
 
 func TestTopologyOnSystemZ(t *testing.T) {
-	machineArch = "s390" // overwrite package variable
-	nodes, cores, err := GetTopology(&fakesysfs.FakeSysFs{})
+	machineArch = "s390x" // overwrite package variable
+	sysFs := &fakesysfs.FakeSysFs{}
+	sysFs.SetNodesPaths([]string{}, nil)
+
+	cpus := []string{
+		"/sys/devices/system/cpu/cpu0",
+		"/sys/devices/system/cpu/cpu1",
+		"/sys/devices/system/cpu/cpu2",
+		"/sys/devices/system/cpu/cpu3",
+	}
+	sysFs.SetCPUsPaths(map[string][]string{"/sys/devices/system/cpu": cpus}, nil)
+	// Both books contain socket 0 and core 0.
+	sysFs.SetCoreThreads(map[string]string{cpus[0]: "0", cpus[1]: "0", cpus[2]: "0", cpus[3]: "0"}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{cpus[0]: "0", cpus[1]: "0", cpus[2]: "0", cpus[3]: "0"}, nil)
+	sysFs.SetBookIDs(map[string]string{cpus[0]: "0", cpus[1]: "0", cpus[2]: "1", cpus[3]: "1"})
+	sysFs.SetDrawerIDs(map[string]string{cpus[0]: "1", cpus[1]: "1", cpus[2]: "1", cpus[3]: "1"})
+
+	nodes, numCores, err := GetTopology(sysFs)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, numCores)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, []info.Core{
+		{Id: 0, Threads: []int{0, 1}, SocketID: 0, BookID: 0, DrawerID: 1},
+		{Id: 0, Threads: []int{2, 3}, SocketID: 0, BookID: 1, DrawerID: 1},
+	}, nodes[0].Cores)
+}
+
+func TestCoresAndSocketsOnSystemZ(t *testing.T) {
+	cpuBusPath = "./testdata/s390x/"       // overwriting package variable to mock sysfs
+	testfile := "./testdata/cpuinfo_s390x" // mock cpuinfo without core and physical id
+
+	testcpuinfo, err := ioutil.ReadFile(testfile)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, GetPhysicalCores(testcpuinfo))
+	assert.Equal(t, 2, GetSockets(testcpuinfo))
+}
+
+func TestCoresAndSocketsOnPowerSMT8(t *testing.T) {
+	maxFreqFile = ""                         // do not read the system max frequency
+	machineArch = "ppc64le"                  // overwrite package variable
+	cpuBusPath = "./testdata/ppc64le_smt8/"  // overwriting package variable to mock sysfs
+	testfile := "./testdata/cpuinfo_ppc64le" // mock cpuinfo without core and physical id
+
+	testcpuinfo, err := ioutil.ReadFile(testfile)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, GetPhysicalCores(testcpuinfo))
+	assert.Equal(t, 1, GetSockets(testcpuinfo))
+	clockSpeed, err := GetClockSpeed(testcpuinfo)
 	assert.Nil(t, err)
-	assert.Nil(t, nodes)
-	assert.NotNil(t, cores)
+	assert.Equal(t, uint64(2750000), clockSpeed)
 }
 
 func TestMemoryInfo(t *testing.T) {
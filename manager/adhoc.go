@@ -0,0 +1,111 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/watcher"
+
+	"k8s.io/klog/v2"
+)
+
+// adHocWatch is a cgroup monitored on request, which is no longer monitored
+// once it expires or the cgroup is removed.
+type adHocWatch struct {
+	expires time.Time
+	timer   *time.Timer
+}
+
+func (m *manager) WatchCgroup(containerName string, ttl time.Duration) (v2.AdHocWatch, error) {
+	name := path.Clean(containerName)
+	if !path.IsAbs(name) || name == "/" {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: invalid cgroup %q", ErrInvalidWatch, containerName)
+	}
+	if ttl <= 0 || ttl > m.options.AdHocWatchMaxTTL {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: the TTL must be positive and at most %v, got %v", ErrInvalidWatch, m.options.AdHocWatchMaxTTL, ttl)
+	}
+
+	m.containersLock.Lock()
+	defer m.containersLock.Unlock()
+	expires := time.Now().Add(ttl)
+	if w, ok := m.adHocWatches[name]; ok {
+		w.timer.Reset(ttl)
+		w.expires = expires
+		klog.V(2).Infof("Renewed the watch of cgroup %q until %v", name, expires)
+		return v2.AdHocWatch{Name: name, Expires: expires}, nil
+	}
+	if _, ok := m.containers.get(namespacedContainerName{Name: name}); ok {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: %q", ErrContainerMonitored, name)
+	}
+	if len(m.adHocWatches) >= m.options.AdHocWatchLimit {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: at most %d cgroups may be monitored on request", ErrTooManyWatches, m.options.AdHocWatchLimit)
+	}
+
+	if err := m.createContainerLocked(name, watcher.AdHoc); err != nil {
+		return v2.AdHocWatch{}, err
+	}
+	if _, ok := m.containers.get(namespacedContainerName{Name: name}); !ok {
+		return v2.AdHocWatch{}, fmt.Errorf("%w: cgroup %q does not exist", ErrUnknownContainer, name)
+	}
+	w := &adHocWatch{expires: expires}
+	w.timer = time.AfterFunc(ttl, func() {
+		m.expireAdHocWatch(name, w)
+	})
+	m.adHocWatches[name] = w
+	klog.V(2).Infof("Watching cgroup %q until %v", name, expires)
+	return v2.AdHocWatch{Name: name, Expires: expires}, nil
+}
+
+// expireAdHocWatch stops monitoring the cgroup of w once it expires.
+func (m *manager) expireAdHocWatch(name string, w *adHocWatch) {
+	m.containersLock.Lock()
+	defer m.containersLock.Unlock()
+	// The watch may have been renewed or removed meanwhile.
+	if m.adHocWatches[name] != w || time.Now().Before(w.expires) {
+		return
+	}
+	klog.V(2).Infof("The watch of cgroup %q expired", name)
+	if err := m.destroyContainerLocked(name); err != nil {
+		klog.Errorf("Failed to stop monitoring cgroup %q: %v", name, err)
+	}
+}
+
+func (m *manager) UnwatchCgroup(containerName string) error {
+	name := path.Clean(containerName)
+	m.containersLock.Lock()
+	defer m.containersLock.Unlock()
+	if _, ok := m.adHocWatches[name]; !ok {
+		return fmt.Errorf("%w: cgroup %q is not monitored on request", ErrUnknownContainer, name)
+	}
+	return m.destroyContainerLocked(name)
+}
+
+func (m *manager) GetAdHocWatches() []v2.AdHocWatch {
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+	watches := make([]v2.AdHocWatch, 0, len(m.adHocWatches))
+	for name, w := range m.adHocWatches {
+		watches = append(watches, v2.AdHocWatch{Name: name, Expires: w.expires})
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].Name < watches[j].Name
+	})
+	return watches
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cache/memory"
+	containertest "github.com/google/cadvisor/container/testing"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchCgroup(t *testing.T) {
+	m := createManagerAndAddContainers(memory.New(time.Minute, nil), nil, []string{"/a"}, func(*containertest.MockContainerHandler) {}, t)
+	m.adHocWatches = make(map[string]*adHocWatch)
+	renewed := &adHocWatch{expires: time.Now(), timer: time.NewTimer(time.Hour)}
+	defer renewed.timer.Stop()
+	m.adHocWatches["/b"] = renewed
+
+	for _, tc := range []struct {
+		name string
+		ttl  time.Duration
+		err  error
+	}{
+		{"relative", time.Minute, ErrInvalidWatch},
+		{"/", time.Minute, ErrInvalidWatch},
+		{"/c", 0, ErrInvalidWatch},
+		{"/c", m.options.AdHocWatchMaxTTL + time.Second, ErrInvalidWatch},
+		{"/a", time.Minute, ErrContainerMonitored},
+	} {
+		_, err := m.WatchCgroup(tc.name, tc.ttl)
+		assert.True(t, errors.Is(err, tc.err), "%s: %v", tc.name, err)
+	}
+
+	m.options.AdHocWatchLimit = 1
+	_, err := m.WatchCgroup("/c", time.Minute)
+	assert.True(t, errors.Is(err, ErrTooManyWatches), "%v", err)
+
+	// Watches are renewed whatever the limit.
+	w, err := m.WatchCgroup("/b/", 2*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "/b", w.Name)
+	assert.True(t, w.Expires.After(time.Now().Add(time.Hour)))
+	assert.Equal(t, []v2.AdHocWatch{w}, m.GetAdHocWatches())
+
+	// A renewed watch does not expire on its previous deadline.
+	renewed.expires = time.Now().Add(time.Hour)
+	m.expireAdHocWatch("/b", renewed)
+	assert.Len(t, m.GetAdHocWatches(), 1)
+
+	err = m.UnwatchCgroup("/a")
+	assert.True(t, errors.Is(err, ErrUnknownContainer), "%v", err)
+}
@@ -15,7 +15,6 @@
 package manager
 
 import (
-	"flag"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -32,6 +31,7 @@ import (
 	"github.com/google/cadvisor/cache/memory"
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/enrichment"
 	info "github.com/google/cadvisor/info/v1"
 	v2 "github.com/google/cadvisor/info/v2"
 	"github.com/google/cadvisor/stats"
@@ -43,10 +43,6 @@ import (
 	"k8s.io/utils/clock"
 )
 
-// Housekeeping interval.
-var enableLoadReader = flag.Bool("enable_load_reader", false, "Whether to enable cpu load reader")
-var HousekeepingInterval = flag.Duration("housekeeping_interval", 1*time.Second, "Interval between container housekeepings")
-
 // TODO: replace regular expressions with something simpler, such as strings.Split().
 // cgroup type chosen to fetch the cgroup path of a process.
 // Memory has been chosen, as it is one of the default cgroups that is enabled for most containers...
@@ -56,6 +52,12 @@ var cgroupMemoryPathRegExp = regexp.MustCompile(`memory[^:]*:(.*?)[,;$]`)
 // We should check cpu cgroup then.
 var cgroupCPUPathRegExp = regexp.MustCompile(`cpu[^:]*:(.*?)[,;$]`)
 
+const (
+	// Bounds of the backoff of retries of a failed container enrichment.
+	minEnrichmentBackoff = time.Second
+	maxEnrichmentBackoff = 5 * time.Minute
+)
+
 type containerInfo struct {
 	info.ContainerReference
 	Subcontainers []info.ContainerReference
@@ -71,6 +73,7 @@ type containerData struct {
 	summaryReader            *summary.StatsSummary
 	loadAvg                  float64 // smoothed load average seen so far.
 	housekeepingInterval     time.Duration
+	baseHousekeepingInterval time.Duration
 	maxHousekeepingInterval  time.Duration
 	allowDynamicHousekeeping bool
 	infoLastUpdatedTime      time.Time
@@ -91,17 +94,82 @@ type containerData struct {
 	// Tells the container to immediately collect stats
 	onDemandChan chan chan struct{}
 
+	// Whether stats are only collected on demand, after the first housekeeping.
+	onDemand bool
+
+	// Receive the stats as they are collected.
+	statsWatchers *statsWatchers
+
 	// Runs custom metric collectors.
 	collectorManager collector.CollectorManager
 
 	// nvidiaCollector updates stats for Nvidia GPUs attached to the container.
 	nvidiaCollector stats.Collector
 
+	// pluginCollector merges the stats of the stats plugins.
+	pluginCollector stats.Collector
+
 	// perfCollector updates stats for perf_event cgroup controller.
 	perfCollector stats.Collector
 
 	// resctrlCollector updates stats for resctrl controller.
 	resctrlCollector stats.Collector
+
+	// enrichers attach extra labels to the container.
+	enrichers []enrichment.Enricher
+
+	// Labels attached by the enrichers, nil until all enrichers succeeded.
+	// Guarded by lock, like the enrichment state below.
+	enrichedLabels map[string]string
+	// Whether the enrichers are running in the background.
+	enriching bool
+	// Time before which a failed enrichment isn't retried, and the backoff
+	// added after the next failure.
+	enrichmentRetry   time.Time
+	enrichmentBackoff time.Duration
+
+	// Continuity ID of a container restored from a checkpoint, guarded by
+	// lock.
+	continuityID string
+
+	// Whether the energy used by the host is attributed to the container.
+	estimateEnergy  bool
+	lastCpuUsage    uint64
+	estimatedEnergy uint64
+
+	// Whether the child cgroups of the container are monitored as nested
+	// containers.
+	nestedCgroups bool
+}
+
+// attributeEnergy adds to the energy of the container the share of the energy
+// used by the host matching the CPU time the container used since its previous
+// sample. The energy cost of a nanosecond of CPU time is derived from the two
+// latest samples of the root container.
+func (cd *containerData) attributeEnergy(stats *info.ContainerStats) {
+	usage := stats.Cpu.Usage.Total
+	defer func() { cd.lastCpuUsage = usage }()
+
+	root, err := cd.memoryCache.RecentStats("/", time.Time{}, time.Time{}, 2)
+	if err != nil || len(root) < 2 || root[0].Energy == nil || root[1].Energy == nil {
+		return
+	}
+	prev, cur := root[0].Energy, root[1].Energy
+	if cur.CpuTime > prev.CpuTime && cd.lastCpuUsage > 0 && usage > cd.lastCpuUsage {
+		var energy uint64
+		for i, domain := range cur.Domains {
+			// Core and uncore are part of the package domain.
+			if domain.Domain != "package" && domain.Domain != "dram" {
+				continue
+			}
+			if i < len(prev.Domains) && prev.Domains[i].Socket == domain.Socket && prev.Domains[i].Domain == domain.Domain && prev.Domains[i].Energy <= domain.Energy {
+				energy += domain.Energy - prev.Domains[i].Energy
+			}
+		}
+		share := float64(usage-cd.lastCpuUsage) / float64(cur.CpuTime-prev.CpuTime)
+		cd.estimatedEnergy += uint64(float64(energy) * share)
+	}
+	stats.Energy = &info.EnergyStats{Estimated: cd.estimatedEnergy}
 }
 
 // jitter returns a time.Duration between duration and duration + maxFactor * duration,
@@ -127,6 +195,7 @@ func (cd *containerData) Stop() error {
 	}
 	close(cd.stop)
 	cd.perfCollector.Destroy()
+	containerHousekeepingDuration.DeleteLabelValues(cd.info.Name)
 	return nil
 }
 
@@ -196,9 +265,23 @@ func (cd *containerData) GetInfo(shouldUpdateSubcontainers bool) (*containerInfo
 	return &cInfo, nil
 }
 
+// matches reports whether the container passes the filter, based on its last known spec.
+func (cd *containerData) matches(filter *v2.ContainerFilter) bool {
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	return filter.Matches(cd.info.ContainerReference, cd.info.Spec.Labels)
+}
+
+// labels returns the labels of the container in its last known spec.
+func (cd *containerData) labels() map[string]string {
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	return cd.info.Spec.Labels
+}
+
 func (cd *containerData) DerivedStats() (v2.DerivedStats, error) {
 	if cd.summaryReader == nil {
-		return v2.DerivedStats{}, fmt.Errorf("derived stats not enabled for container %q", cd.info.Name)
+		return v2.DerivedStats{}, fmt.Errorf("derived stats not enabled for container %q: %w", cd.info.Name, ErrCollectorDisabled)
 	}
 	return cd.summaryReader.DerivedStats()
 }
@@ -296,7 +379,7 @@ func (cd *containerData) getContainerPids(inHostNamespace bool) ([]string, error
 }
 
 func (cd *containerData) GetProcessList(cadvisorContainer string, inHostNamespace bool) ([]v2.ProcessInfo, error) {
-	format := "user,pid,ppid,stime,pcpu,pmem,rss,vsz,stat,time,comm,psr,cgroup"
+	format := "user,pid,ppid,stime,pcpu,pmem,rss,vsz,stat,time,nlwp,cls,comm,psr,cgroup"
 	out, err := cd.getPsOutput(inHostNamespace, format)
 	if err != nil {
 		return nil, err
@@ -330,17 +413,79 @@ func (cd *containerData) parseProcessList(cadvisorContainer string, inHostNamesp
 		fdCount = len(fds)
 		processInfo.FdCount = fdCount
 
+		ioPath := path.Join(rootfs, "/proc", strconv.Itoa(processInfo.Pid), "io")
+		processInfo.ReadBytes, processInfo.WriteBytes, err = readProcessIO(ioPath)
+		if err != nil {
+			klog.V(4).Infof("error while reading %q to measure io: %v", ioPath, err)
+		}
+
 		processes = append(processes, *processInfo)
 	}
 	return processes, nil
 }
 
+// readProcessIO returns the bytes read from and written to storage by a
+// process according to its io file.
+func readProcessIO(ioPath string) (uint64, uint64, error) {
+	out, err := ioutil.ReadFile(ioPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	var readBytes, writeBytes uint64
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		var value *uint64
+		switch fields[0] {
+		case "read_bytes:":
+			value = &readBytes
+		case "write_bytes:":
+			value = &writeBytes
+		default:
+			continue
+		}
+		*value, err = strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %v", strings.TrimSuffix(fields[0], ":"), fields[1], err)
+		}
+	}
+	return readBytes, writeBytes, nil
+}
+
+// parsePsTime returns the number of seconds of a ps time, formatted as
+// [DD-]HH:MM:SS.
+func parsePsTime(psTime string) (uint64, error) {
+	var days uint64
+	var err error
+	if i := strings.Index(psTime, "-"); i >= 0 {
+		days, err = strconv.ParseUint(psTime[:i], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		psTime = psTime[i+1:]
+	}
+	parts := strings.Split(psTime, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("expected [DD-]HH:MM:SS")
+	}
+	var values [3]uint64
+	for i, part := range parts {
+		values[i], err = strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return ((days*24+values[0])*60+values[1])*60 + values[2], nil
+}
+
 func (cd *containerData) isRoot() bool {
 	return cd.info.Name == "/"
 }
 
 func (cd *containerData) parsePsLine(line, cadvisorContainer string, inHostNamespace bool) (*v2.ProcessInfo, error) {
-	const expectedFields = 13
+	const expectedFields = 15
 	if len(line) == 0 {
 		return nil, nil
 	}
@@ -356,6 +501,7 @@ func (cd *containerData) parsePsLine(line, cadvisorContainer string, inHostNames
 	info.StartTime = fields[3]
 	info.Status = fields[8]
 	info.RunningTime = fields[9]
+	info.SchedulingPolicy = fields[11]
 
 	info.Pid, err = strconv.Atoi(fields[1])
 	if err != nil {
@@ -389,10 +535,19 @@ func (cd *containerData) parsePsLine(line, cadvisorContainer string, inHostNames
 	info.RSS *= 1024
 	info.VirtualSize *= 1024
 
+	info.CpuTimeSeconds, err = parsePsTime(fields[9])
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: %v", fields[9], err)
+	}
+	info.ThreadCount, err = strconv.Atoi(fields[10])
+	if err != nil {
+		return nil, fmt.Errorf("invalid thread count %q: %v", fields[10], err)
+	}
+
 	// According to `man ps`: The following user-defined format specifiers may contain spaces: args, cmd, comm, command,
 	// fname, ucmd, ucomm, lstart, bsdstart, start.
 	// Therefore we need to be able to parse comm that consists of multiple space-separated parts.
-	info.Cmd = strings.Join(fields[10:len(fields)-2], " ")
+	info.Cmd = strings.Join(fields[12:len(fields)-2], " ")
 
 	// These are last two parts of the line. We create a subslice of `fields` to handle comm that includes spaces.
 	lastTwoFields := fields[len(fields)-2:]
@@ -420,7 +575,7 @@ func (cd *containerData) parsePsLine(line, cadvisorContainer string, inHostNames
 	return &info, nil
 }
 
-func newContainerData(containerName string, memoryCache *memory.InMemoryCache, handler container.ContainerHandler, logUsage bool, collectorManager collector.CollectorManager, maxHousekeepingInterval time.Duration, allowDynamicHousekeeping bool, clock clock.Clock) (*containerData, error) {
+func newContainerData(containerName string, memoryCache *memory.InMemoryCache, handler container.ContainerHandler, logUsage bool, collectorManager collector.CollectorManager, options Options, clock clock.Clock) (*containerData, error) {
 	if memoryCache == nil {
 		return nil, fmt.Errorf("nil memory storage")
 	}
@@ -435,9 +590,10 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 	cont := &containerData{
 		handler:                  handler,
 		memoryCache:              memoryCache,
-		housekeepingInterval:     *HousekeepingInterval,
-		maxHousekeepingInterval:  maxHousekeepingInterval,
-		allowDynamicHousekeeping: allowDynamicHousekeeping,
+		housekeepingInterval:     options.HousekeepingInterval,
+		baseHousekeepingInterval: options.HousekeepingInterval,
+		maxHousekeepingInterval:  options.MaxHousekeepingInterval,
+		allowDynamicHousekeeping: options.AllowDynamicHousekeeping,
 		logUsage:                 logUsage,
 		loadAvg:                  -1.0, // negative value indicates uninitialized.
 		stop:                     make(chan struct{}),
@@ -447,12 +603,13 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 		perfCollector:            &stats.NoopCollector{},
 		nvidiaCollector:          &stats.NoopCollector{},
 		resctrlCollector:         &stats.NoopCollector{},
+		pluginCollector:          &stats.NoopCollector{},
 	}
 	cont.info.ContainerReference = ref
 
 	cont.loadDecay = math.Exp(float64(-cont.housekeepingInterval.Seconds() / 10))
 
-	if *enableLoadReader {
+	if options.EnableLoadReader {
 		// Create cpu load reader.
 		loadReader, err := cpuload.New()
 		if err != nil {
@@ -466,7 +623,7 @@ func newContainerData(containerName string, memoryCache *memory.InMemoryCache, h
 	if err != nil {
 		return nil, err
 	}
-	cont.summaryReader, err = summary.New(cont.info.Spec)
+	cont.summaryReader, err = summary.New(cont.info.Spec, options.Summary)
 	if err != nil {
 		cont.summaryReader = nil
 		klog.V(5).Infof("Failed to create summary reader for %q: %v", ref.Name, err)
@@ -492,9 +649,9 @@ func (cd *containerData) nextHousekeepingInterval() time.Duration {
 				if cd.housekeepingInterval > cd.maxHousekeepingInterval {
 					cd.housekeepingInterval = cd.maxHousekeepingInterval
 				}
-			} else if cd.housekeepingInterval != *HousekeepingInterval {
+			} else if cd.housekeepingInterval != cd.baseHousekeepingInterval {
 				// Lower interval back to the baseline.
-				cd.housekeepingInterval = *HousekeepingInterval
+				cd.housekeepingInterval = cd.baseHousekeepingInterval
 			}
 		}
 	}
@@ -519,8 +676,8 @@ func (cd *containerData) housekeeping() {
 
 	// Long housekeeping is either 100ms or half of the housekeeping interval.
 	longHousekeeping := 100 * time.Millisecond
-	if *HousekeepingInterval/2 < longHousekeeping {
-		longHousekeeping = *HousekeepingInterval / 2
+	if cd.baseHousekeepingInterval/2 < longHousekeeping {
+		longHousekeeping = cd.baseHousekeepingInterval / 2
 	}
 
 	// Housekeep every second.
@@ -565,6 +722,10 @@ func (cd *containerData) housekeeping() {
 				klog.Infof("[%s] %.3f cores (average: %.3f cores), %s of memory", cd.info.Name, instantUsageInCores, usageInCores, usageInHuman)
 			}
 		}
+		if cd.onDemand {
+			// The timer stays stopped, stats are collected through onDemandChan.
+			continue
+		}
 		houseKeepingTimer.Reset(cd.nextHousekeepingInterval())
 	}
 }
@@ -588,6 +749,8 @@ func (cd *containerData) housekeepingTick(timer <-chan time.Time, longHousekeepi
 	}
 	// Log if housekeeping took too long.
 	duration := cd.clock.Since(start)
+	housekeepingDuration.Observe(duration.Seconds())
+	containerHousekeepingDuration.WithLabelValues(cd.info.Name).Set(duration.Seconds())
 	if duration >= longHousekeeping {
 		klog.V(3).Infof("[%s] Housekeeping took %s", cd.info.Name, duration)
 	}
@@ -612,16 +775,59 @@ func (cd *containerData) updateSpec() error {
 	if err != nil {
 		return err
 	}
+	if provider, ok := cd.pluginCollector.(stats.MetricSpecProvider); ok {
+		customMetrics = append(customMetrics, provider.MetricSpecs()...)
+	}
 	if len(customMetrics) > 0 {
 		spec.HasCustomMetrics = true
 		spec.CustomMetrics = customMetrics
 	}
+
 	cd.lock.Lock()
 	defer cd.lock.Unlock()
+	if cd.enrichedLabels == nil && len(cd.enrichers) > 0 && !cd.enriching && !cd.clock.Now().Before(cd.enrichmentRetry) {
+		cd.enriching = true
+		go cd.enrich(cd.info.ContainerReference, spec)
+	}
+	spec.Labels = enrichment.MergeLabels(spec.Labels, cd.enrichedLabels)
+	spec.ContinuityId = cd.continuityID
 	cd.info.Spec = spec
 	return nil
 }
 
+// enrich collects the labels of the enrichers in the background, so that a
+// slow enricher doesn't delay spec updates, and attaches them to the spec once
+// all enrichers succeeded. Failed enrichments are retried with an exponential
+// backoff.
+func (cd *containerData) enrich(ref info.ContainerReference, spec info.ContainerSpec) {
+	labels, err := enrichment.Labels(cd.enrichers, ref, spec)
+
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	cd.enriching = false
+	if err != nil {
+		cd.enrichmentBackoff *= 2
+		if cd.enrichmentBackoff < minEnrichmentBackoff {
+			cd.enrichmentBackoff = minEnrichmentBackoff
+		} else if cd.enrichmentBackoff > maxEnrichmentBackoff {
+			cd.enrichmentBackoff = maxEnrichmentBackoff
+		}
+		cd.enrichmentRetry = cd.clock.Now().Add(cd.enrichmentBackoff)
+		klog.V(4).Infof("Enrichment of container %q incomplete, retrying in %v: %v", ref.Name, cd.enrichmentBackoff, err)
+		return
+	}
+	cd.enrichedLabels = labels
+	cd.info.Spec.Labels = enrichment.MergeLabels(cd.info.Spec.Labels, labels)
+}
+
+// setContinuityID sets the continuity ID of a restored container.
+func (cd *containerData) setContinuityID(continuityID string) {
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	cd.continuityID = continuityID
+	cd.info.Spec.ContinuityId = continuityID
+}
+
 // Calculate new smoothed load average using the new sample of runnable threads.
 // The decay used ensures that the load will stabilize on a new constant value within
 // 10 seconds.
@@ -643,8 +849,10 @@ func (cd *containerData) updateStats() error {
 
 		// Stats may be partially populated, push those before we return an error.
 		statsErr = fmt.Errorf("%v, continuing to push stats", statsErr)
+		collectorErrors.WithLabelValues(statsCollector).Inc()
 	}
 	if stats == nil {
+		droppedSamples.WithLabelValues(noStatsReason).Inc()
 		return statsErr
 	}
 	if cd.loadReader != nil {
@@ -653,6 +861,8 @@ func (cd *containerData) updateStats() error {
 		if err == nil {
 			loadStats, err := cd.loadReader.GetCpuLoad(cd.info.Name, path)
 			if err != nil {
+				collectorErrors.WithLabelValues(loadCollector).Inc()
+				droppedSamples.WithLabelValues(incompleteStatsReason).Inc()
 				return fmt.Errorf("failed to get load stat for %q - path %q, error %s", cd.info.Name, path, err)
 			}
 			stats.TaskStats = loadStats
@@ -661,6 +871,9 @@ func (cd *containerData) updateStats() error {
 			stats.Cpu.LoadAverage = int32(cd.loadAvg * 1000)
 		}
 	}
+	if cd.estimateEnergy {
+		cd.attributeEnergy(stats)
+	}
 	if cd.summaryReader != nil {
 		err := cd.summaryReader.AddSample(*stats)
 		if err != nil {
@@ -670,15 +883,14 @@ func (cd *containerData) updateStats() error {
 	}
 	var customStatsErr error
 	cm := cd.collectorManager.(*collector.GenericCollectorManager)
-	if len(cm.Collectors) > 0 {
-		if cm.NextCollectionTime.Before(cd.clock.Now()) {
-			customStats, err := cd.updateCustomStats()
-			if customStats != nil {
-				stats.CustomMetrics = customStats
-			}
-			if err != nil {
-				customStatsErr = err
-			}
+	if next, ok := cm.NextCollection(); ok && next.Before(cd.clock.Now()) {
+		customStats, err := cd.updateCustomStats()
+		if customStats != nil {
+			stats.CustomMetrics = customStats
+		}
+		if err != nil {
+			collectorErrors.WithLabelValues(applicationCollector).Inc()
+			customStatsErr = err
 		}
 	}
 
@@ -692,12 +904,24 @@ func (cd *containerData) updateStats() error {
 
 	resctrlStatsErr := cd.resctrlCollector.UpdateStats(stats)
 
+	pluginStatsErr := cd.pluginCollector.UpdateStats(stats)
+	if pluginStatsErr != nil && cd.allowErrorLogging() {
+		klog.Warningf("Failed to collect the stats of plugins for %q: %v", cd.info.Name, pluginStatsErr)
+	}
+
+	for collector, err := range map[string]error{nvidiaCollector: nvidiaStatsErr, perfCollector: perfStatsErr, resctrlCollector: resctrlStatsErr, pluginCollector: pluginStatsErr} {
+		if err != nil {
+			collectorErrors.WithLabelValues(collector).Inc()
+		}
+	}
+
 	ref, err := cd.handler.ContainerReference()
 	if err != nil {
 		// Ignore errors if the container is dead.
 		if !cd.handler.Exists() {
 			return nil
 		}
+		droppedSamples.WithLabelValues(incompleteStatsReason).Inc()
 		return err
 	}
 
@@ -707,8 +931,15 @@ func (cd *containerData) updateStats() error {
 
 	err = cd.memoryCache.AddStats(&cInfo, stats)
 	if err != nil {
+		droppedSamples.WithLabelValues(cacheErrorReason).Inc()
 		return err
 	}
+	if cd.statsWatchers != nil {
+		cd.lock.Lock()
+		spec := cd.info.Spec
+		cd.lock.Unlock()
+		cd.statsWatchers.notify(ref, spec, stats)
+	}
 	if statsErr != nil {
 		return statsErr
 	}
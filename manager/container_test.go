@@ -27,11 +27,13 @@ import (
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
 	containertest "github.com/google/cadvisor/container/testing"
+	"github.com/google/cadvisor/enrichment"
 	info "github.com/google/cadvisor/info/v1"
 	itest "github.com/google/cadvisor/info/v1/test"
 	v2 "github.com/google/cadvisor/info/v2"
 
 	"github.com/mindprince/gonvml"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	clock "k8s.io/utils/clock/testing"
@@ -53,7 +55,7 @@ func setupContainerData(t *testing.T, spec info.ContainerSpec) (*containerData,
 	)
 	memoryCache := memory.New(60, nil)
 	fakeClock := clock.NewFakeClock(time.Now())
-	ret, err := newContainerData(containerName, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, 60*time.Second, true, fakeClock)
+	ret, err := newContainerData(containerName, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, DefaultOptions(), fakeClock)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -152,6 +154,62 @@ func TestUpdateStats(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestUpdateStatsDropped(t *testing.T) {
+	cd, mockHandler, memoryCache, _ := newTestContainerData(t)
+	mockHandler.On("GetStats").Return((*info.ContainerStats)(nil), fmt.Errorf("cgroup gone"))
+	mockHandler.On("Exists").Return(true)
+
+	errorsBefore := testutil.ToFloat64(collectorErrors.WithLabelValues(statsCollector))
+	droppedBefore := testutil.ToFloat64(droppedSamples.WithLabelValues(noStatsReason))
+	assert.Error(t, cd.updateStats())
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(collectorErrors.WithLabelValues(statsCollector)))
+	assert.Equal(t, droppedBefore+1, testutil.ToFloat64(droppedSamples.WithLabelValues(noStatsReason)))
+
+	_, err := memoryCache.RecentStats(containerName, time.Time{}, time.Time{}, -1)
+	assert.Error(t, err)
+}
+
+func TestAttributeEnergy(t *testing.T) {
+	cd, _, memoryCache, _ := newTestContainerData(t)
+	cd.estimateEnergy = true
+
+	// The container uses a quarter of the CPU time used on the host, which
+	// used 4000uJ in the package and DRAM domains.
+	root := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/"}}
+	now := time.Now()
+	for i, energy := range []info.EnergyStats{{
+		CpuTime: 1000,
+		Domains: []info.EnergyDomainStats{
+			{Socket: 0, Domain: "core", Energy: 100},
+			{Socket: 0, Domain: "dram", Energy: 1000},
+			{Socket: 0, Domain: "package", Energy: 2000},
+		},
+	}, {
+		CpuTime: 5000,
+		Domains: []info.EnergyDomainStats{
+			{Socket: 0, Domain: "core", Energy: 2100},
+			{Socket: 0, Domain: "dram", Energy: 2000},
+			{Socket: 0, Domain: "package", Energy: 5000},
+		},
+	}} {
+		energy := energy
+		require.NoError(t, memoryCache.AddStats(root, &info.ContainerStats{
+			Timestamp: now.Add(time.Duration(i)),
+			Energy:    &energy,
+		}))
+	}
+
+	stats := &info.ContainerStats{}
+	stats.Cpu.Usage.Total = 1000
+	cd.attributeEnergy(stats)
+	assert.Equal(t, &info.EnergyStats{}, stats.Energy)
+
+	stats = &info.ContainerStats{}
+	stats.Cpu.Usage.Total = 2000
+	cd.attributeEnergy(stats)
+	assert.Equal(t, &info.EnergyStats{Estimated: 1000}, stats.Energy)
+}
+
 func TestUpdateSpec(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	cd, mockHandler, _, _ := newTestContainerData(t)
@@ -168,6 +226,73 @@ func TestUpdateSpec(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+type fakeEnricher struct {
+	lock   sync.Mutex
+	labels map[string]string
+	err    error
+	calls  int
+}
+
+func (e *fakeEnricher) Name() string { return "fake" }
+
+func (e *fakeEnricher) Labels(ref info.ContainerReference, spec info.ContainerSpec) (map[string]string, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.calls++
+	return e.labels, e.err
+}
+
+func (e *fakeEnricher) set(labels map[string]string, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.labels, e.err = labels, err
+}
+
+func (e *fakeEnricher) callCount() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.calls
+}
+
+// specLabels returns the labels of the current spec of the container.
+func specLabels(cd *containerData) map[string]string {
+	cd.lock.Lock()
+	defer cd.lock.Unlock()
+	return cd.info.Spec.Labels
+}
+
+func TestUpdateSpecEnrichment(t *testing.T) {
+	spec := info.ContainerSpec{Labels: map[string]string{"team": "runtime", "app": "web"}}
+	cd, _, _, fakeClock := setupContainerData(t, spec)
+	enricher := &fakeEnricher{}
+	enricher.set(nil, fmt.Errorf("sidecar unavailable"))
+	cd.enrichers = []enrichment.Enricher{enricher}
+
+	// Enrichment runs in the background and the spec is updated without it.
+	require.NoError(t, cd.updateSpec())
+	assert.Equal(t, spec.Labels, specLabels(cd))
+	assert.Eventually(t, func() bool { return enricher.callCount() == 1 }, time.Second, time.Millisecond)
+
+	// A failed enrichment is retried after a backoff.
+	enricher.set(map[string]string{"team": "storage", "cost_center": "1234"}, nil)
+	assert.Eventually(t, func() bool {
+		cd.lock.Lock()
+		defer cd.lock.Unlock()
+		return !cd.enriching
+	}, time.Second, time.Millisecond)
+	require.NoError(t, cd.updateSpec())
+	assert.Equal(t, 1, enricher.callCount())
+
+	// The labels are attached once all enrichers succeeded and cached afterwards.
+	fakeClock.Step(minEnrichmentBackoff)
+	require.NoError(t, cd.updateSpec())
+	expected := map[string]string{"team": "runtime", "app": "web", "cost_center": "1234"}
+	assert.Eventually(t, func() bool { return reflect.DeepEqual(expected, specLabels(cd)) }, time.Second, time.Millisecond)
+	require.NoError(t, cd.updateSpec())
+	assert.Equal(t, 2, enricher.callCount())
+	assert.Equal(t, expected, specLabels(cd))
+}
+
 func TestGetInfo(t *testing.T) {
 	spec := itest.GenerateRandomContainerSpec(4)
 	subcontainers := []info.ContainerReference{
@@ -260,6 +385,36 @@ func TestOnDemandHousekeeping(t *testing.T) {
 	mockHandler.AssertExpectations(t)
 }
 
+func TestOnDemandHousekeepingMode(t *testing.T) {
+	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
+	stats := statsList[0]
+
+	cd, mockHandler, memoryCache, fakeClock := newTestContainerData(t)
+	mockHandler.On("GetStats").Return(stats, nil)
+	cd.onDemand = true
+	go cd.housekeeping()
+	defer func() {
+		err := cd.Stop()
+		assert.NoError(t, err)
+	}()
+
+	// Stats are collected once when housekeeping starts.
+	assert.Eventually(t, func() bool {
+		fakeClock.Step(time.Nanosecond)
+		cd.lock.Lock()
+		defer cd.lock.Unlock()
+		return !cd.statsLastUpdatedTime.IsZero()
+	}, time.Second, time.Millisecond)
+	checkNumStats(t, memoryCache, 1)
+
+	// Later on, they are only collected when requested.
+	fakeClock.Step(time.Hour)
+	cd.OnDemandHousekeeping(time.Minute)
+	checkNumStats(t, memoryCache, 2)
+	cd.OnDemandHousekeeping(time.Minute)
+	checkNumStats(t, memoryCache, 2)
+}
+
 func TestConcurrentOnDemandHousekeeping(t *testing.T) {
 	statsList := itest.GenerateRandomStats(1, 4, 1*time.Second)
 	stats := statsList[0]
@@ -354,8 +509,8 @@ func TestOnDemandHousekeepingRace(t *testing.T) {
 }
 
 var psOutput = [][]byte{
-	[]byte("root       15886       2 23:51  0.1  0.0     0      0 I    00:00:00 kworker/u8:3-ev   3 -\nroot       15887       2 23:51  0.0  0.0     0      0 I<   00:00:00 kworker/1:2H      1 -\nubuntu     15888    1804 23:51  0.0  0.0  2832  10176 R+   00:00:00 ps                1 8:devices:/user.slice,6:pids:/user.slice/user-1000.slice/session-3.scope,5:blkio:/user.slice,2:cpu,cpuacct:/user.slice,1:na"),
-	[]byte("root         104       2 21:34  0.0  0.0     0      0 I<   00:00:00 kthrotld          3 -\nroot         105       2 21:34  0.0  0.0     0      0 S    00:00:00 irq/41-aerdrv     0 -\nroot         107       2 21:34  0.0  0.0     0      0 I<   00:00:00 DWC Notificatio   3 -\nroot         109       2 21:34  0.0  0.0     0      0 S<   00:00:00 vchiq-slot/0      1 -\nroot         110       2 21:34  0.0  0.0     0      0 S<   00:00:00 vchiq-recy/0      3 -"),
+	[]byte("root       15886       2 23:51  0.1  0.0     0      0 I    00:00:00     1 TS kworker/u8:3-ev   3 -\nroot       15887       2 23:51  0.0  0.0     0      0 I<   00:00:00     1 TS kworker/1:2H      1 -\nubuntu     15888    1804 23:51  0.0  0.0  2832  10176 R+   00:00:00     1 TS ps                1 8:devices:/user.slice,6:pids:/user.slice/user-1000.slice/session-3.scope,5:blkio:/user.slice,2:cpu,cpuacct:/user.slice,1:na"),
+	[]byte("root         104       2 21:34  0.0  0.0     0      0 I<   00:00:00     1 TS kthrotld          3 -\nroot         105       2 21:34  0.0  0.0     0      0 S    00:00:00     1 TS irq/41-aerdrv     0 -\nroot         107       2 21:34  0.0  0.0     0      0 I<   00:00:00     1 TS DWC Notificatio   3 -\nroot         109       2 21:34  0.0  0.0     0      0 S<   00:00:00     1 TS vchiq-slot/0      1 -\nroot         110       2 21:34  0.0  0.0     0      0 S<   00:00:00     1 TS vchiq-recy/0      3 -"),
 }
 
 func TestParseProcessList(t *testing.T) {
@@ -380,23 +535,26 @@ var psLine = []struct {
 }{
 	{
 		name:              "plain process with cgroup",
-		line:              "ubuntu     15888    1804 23:51  0.1  0.0  2832  10176 R+   00:10:00 cadvisor            1 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
+		line:              "ubuntu     15888    1804 23:51  0.1  0.0  2832  10176 R+   00:10:00     1 TS cadvisor            1 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cadvisorContainer: "/docker/cadvisor",
 		isHostNamespace:   true,
 		process: &v2.ProcessInfo{
-			User:          "ubuntu",
-			Pid:           15888,
-			Ppid:          1804,
-			StartTime:     "23:51",
-			PercentCpu:    0.1,
-			PercentMemory: 0.0,
-			RSS:           2899968,
-			VirtualSize:   10420224,
-			Status:        "R+",
-			RunningTime:   "00:10:00",
-			CgroupPath:    "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
-			Cmd:           "cadvisor",
-			Psr:           1,
+			User:             "ubuntu",
+			Pid:              15888,
+			Ppid:             1804,
+			StartTime:        "23:51",
+			PercentCpu:       0.1,
+			PercentMemory:    0.0,
+			RSS:              2899968,
+			VirtualSize:      10420224,
+			Status:           "R+",
+			RunningTime:      "00:10:00",
+			CpuTimeSeconds:   600,
+			ThreadCount:      1,
+			SchedulingPolicy: "TS",
+			CgroupPath:       "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
+			Cmd:              "cadvisor",
+			Psr:              1,
 		},
 		cd: &containerData{
 			info: containerInfo{ContainerReference: info.ContainerReference{Name: "/"}},
@@ -404,22 +562,25 @@ var psLine = []struct {
 	},
 	{
 		name:              "process with space in name and no cgroup",
-		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00 DWC Notificatio   3 -",
+		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00     1 TS DWC Notificatio   3 -",
 		cadvisorContainer: "/docker/cadvisor",
 		process: &v2.ProcessInfo{
-			User:          "root",
-			Pid:           107,
-			Ppid:          2,
-			StartTime:     "21:34",
-			PercentCpu:    0.0,
-			PercentMemory: 0.1,
-			RSS:           3072,
-			VirtualSize:   4096,
-			Status:        "I<",
-			RunningTime:   "00:20:00",
-			CgroupPath:    "/",
-			Cmd:           "DWC Notificatio",
-			Psr:           3,
+			User:             "root",
+			Pid:              107,
+			Ppid:             2,
+			StartTime:        "21:34",
+			PercentCpu:       0.0,
+			PercentMemory:    0.1,
+			RSS:              3072,
+			VirtualSize:      4096,
+			Status:           "I<",
+			RunningTime:      "00:20:00",
+			CpuTimeSeconds:   1200,
+			ThreadCount:      1,
+			SchedulingPolicy: "TS",
+			CgroupPath:       "/",
+			Cmd:              "DWC Notificatio",
+			Psr:              3,
 		},
 		cd: &containerData{
 			info: containerInfo{ContainerReference: info.ContainerReference{Name: "/"}},
@@ -427,23 +588,26 @@ var psLine = []struct {
 	},
 	{
 		name:              "process with highly unusual name (one 2 three 4 five 6 eleven), cgroup to be ignored",
-		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00 one 2 three 4 five 6 eleven   3 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
+		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00     1 TS one 2 three 4 five 6 eleven   3 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cadvisorContainer: "/docker/cadvisor",
 		isHostNamespace:   true,
 		process: &v2.ProcessInfo{
-			User:          "root",
-			Pid:           107,
-			Ppid:          2,
-			StartTime:     "21:34",
-			PercentCpu:    0.0,
-			PercentMemory: 0.1,
-			RSS:           3072,
-			VirtualSize:   4096,
-			Status:        "I<",
-			RunningTime:   "00:20:00",
-			Cmd:           "one 2 three 4 five 6 eleven",
-			Psr:           3,
-			CgroupPath:    "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
+			User:             "root",
+			Pid:              107,
+			Ppid:             2,
+			StartTime:        "21:34",
+			PercentCpu:       0.0,
+			PercentMemory:    0.1,
+			RSS:              3072,
+			VirtualSize:      4096,
+			Status:           "I<",
+			RunningTime:      "00:20:00",
+			CpuTimeSeconds:   1200,
+			ThreadCount:      1,
+			SchedulingPolicy: "TS",
+			Cmd:              "one 2 three 4 five 6 eleven",
+			Psr:              3,
+			CgroupPath:       "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		},
 		cd: &containerData{
 			info: containerInfo{ContainerReference: info.ContainerReference{Name: "/"}},
@@ -453,12 +617,12 @@ var psLine = []struct {
 		name:              "wrong field count",
 		line:              "ps output it is not",
 		cadvisorContainer: "/docker/cadvisor",
-		err:               fmt.Errorf("expected at least 13 fields, found 5: output: \"ps output it is not\""),
+		err:               fmt.Errorf("expected at least 15 fields, found 5: output: \"ps output it is not\""),
 		cd:                &containerData{},
 	},
 	{
 		name:              "ps running in cadvisor container should be ignored",
-		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00 ps   3 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
+		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00     1 TS ps   3 10:cpuset:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,9:devices:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,8:pids:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,7:memory:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,6:freezer:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,5:perf_event:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,4:blkio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,3:cpu,cpuacct:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,2:net_cls,net_prio:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831,1:name=systemd:/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cadvisorContainer: "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cd: &containerData{
 			info: containerInfo{ContainerReference: info.ContainerReference{Name: "/"}},
@@ -466,20 +630,23 @@ var psLine = []struct {
 	},
 	{
 		name: "non-root container but process belongs to the container",
-		line: "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00 sleep inf   3 10:cpuset:/docker/some-random-container,9:devices:/docker/some-random-container,8:pids:/docker/some-random-container,7:memory:/docker/some-random-container,6:freezer:/docker/some-random-container,5:perf_event:/docker/some-random-container,4:blkio:/docker/some-random-container,3:cpu,cpuacct:/docker/some-random-container,2:net_cls,net_prio:/docker/some-random-container,1:name=systemd:/docker/some-random-container",
+		line: "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00     1 TS sleep inf   3 10:cpuset:/docker/some-random-container,9:devices:/docker/some-random-container,8:pids:/docker/some-random-container,7:memory:/docker/some-random-container,6:freezer:/docker/some-random-container,5:perf_event:/docker/some-random-container,4:blkio:/docker/some-random-container,3:cpu,cpuacct:/docker/some-random-container,2:net_cls,net_prio:/docker/some-random-container,1:name=systemd:/docker/some-random-container",
 		process: &v2.ProcessInfo{
-			User:          "root",
-			Pid:           107,
-			Ppid:          2,
-			StartTime:     "21:34",
-			PercentCpu:    0.0,
-			PercentMemory: 0.1,
-			RSS:           3072,
-			VirtualSize:   4096,
-			Status:        "I<",
-			RunningTime:   "00:20:00",
-			Cmd:           "sleep inf",
-			Psr:           3,
+			User:             "root",
+			Pid:              107,
+			Ppid:             2,
+			StartTime:        "21:34",
+			PercentCpu:       0.0,
+			PercentMemory:    0.1,
+			RSS:              3072,
+			VirtualSize:      4096,
+			Status:           "I<",
+			RunningTime:      "00:20:00",
+			CpuTimeSeconds:   1200,
+			ThreadCount:      1,
+			SchedulingPolicy: "TS",
+			Cmd:              "sleep inf",
+			Psr:              3,
 		},
 		cadvisorContainer: "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cd: &containerData{
@@ -488,7 +655,7 @@ var psLine = []struct {
 	},
 	{
 		name:              "non-root container and process belonging to another container",
-		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00 sleep inf   3 10:cpuset:/docker/some-random-container,9:devices:/docker/some-random-container,8:pids:/docker/some-random-container,7:memory:/docker/some-random-container,6:freezer:/docker/some-random-container,5:perf_event:/docker/some-random-container,4:blkio:/docker/some-random-container,3:cpu,cpuacct:/docker/some-random-container,2:net_cls,net_prio:/docker/some-random-container,1:name=systemd:/docker/some-random-container",
+		line:              "root         107       2 21:34  0.0  0.1     3      4 I<   00:20:00     1 TS sleep inf   3 10:cpuset:/docker/some-random-container,9:devices:/docker/some-random-container,8:pids:/docker/some-random-container,7:memory:/docker/some-random-container,6:freezer:/docker/some-random-container,5:perf_event:/docker/some-random-container,4:blkio:/docker/some-random-container,3:cpu,cpuacct:/docker/some-random-container,2:net_cls,net_prio:/docker/some-random-container,1:name=systemd:/docker/some-random-container",
 		cadvisorContainer: "/docker/dd479c33249f6c3f0f1189aa88f07dad3eeb3e6fedfc71385c27ddd699994831",
 		cd: &containerData{
 			info: containerInfo{ContainerReference: info.ContainerReference{Name: "/docker/some-other-container"}},
@@ -506,6 +673,31 @@ func TestParsePsLine(t *testing.T) {
 	}
 }
 
+func TestParsePsTime(t *testing.T) {
+	for psTime, expected := range map[string]uint64{
+		"00:00:00":    0,
+		"00:10:05":    605,
+		"12:00:00":    43200,
+		"2-01:00:01":  176401,
+		"123-00:00:0": 10627200,
+	} {
+		seconds, err := parsePsTime(psTime)
+		assert.NoError(t, err, psTime)
+		assert.Equal(t, expected, seconds, psTime)
+	}
+	for _, psTime := range []string{"", "10:00", "a-00:00:00", "00:0x:00"} {
+		_, err := parsePsTime(psTime)
+		assert.Error(t, err, psTime)
+	}
+}
+
+func TestReadProcessIO(t *testing.T) {
+	readBytes, writeBytes, err := readProcessIO("testdata/proc_io")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8192), readBytes)
+	assert.Equal(t, uint64(4096), writeBytes)
+}
+
 var cgroupCases = []struct {
 	name    string
 	cgroups string
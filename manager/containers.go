@@ -0,0 +1,103 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// Number of shards of a containerMap.
+const containerMapShards = 32
+
+// containerMap holds the monitored containers by name and by alias. It is
+// sharded by name, so that the lookups of the API and of the Prometheus
+// collector neither contend with each other nor wait for the registration of
+// containers on nodes with a high container churn.
+type containerMap struct {
+	shards [containerMapShards]containerMapShard
+}
+
+type containerMapShard struct {
+	lock       sync.RWMutex
+	containers map[namespacedContainerName]*containerData
+}
+
+func newContainerMap() *containerMap {
+	c := &containerMap{}
+	for i := range c.shards {
+		c.shards[i].containers = make(map[namespacedContainerName]*containerData)
+	}
+	return c
+}
+
+func (c *containerMap) shard(name namespacedContainerName) *containerMapShard {
+	h := fnv.New32a()
+	h.Write([]byte(name.Namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(name.Name))
+	return &c.shards[h.Sum32()%containerMapShards]
+}
+
+func (c *containerMap) get(name namespacedContainerName) (*containerData, bool) {
+	s := c.shard(name)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	cont, ok := s.containers[name]
+	return cont, ok
+}
+
+func (c *containerMap) set(name namespacedContainerName, cont *containerData) {
+	s := c.shard(name)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.containers[name] = cont
+}
+
+func (c *containerMap) delete(name namespacedContainerName) {
+	s := c.shard(name)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.containers, name)
+}
+
+// len returns the number of names and aliases in the map.
+func (c *containerMap) len() int {
+	n := 0
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.lock.RLock()
+		n += len(s.containers)
+		s.lock.RUnlock()
+	}
+	return n
+}
+
+// forEach calls f for the names and aliases in the map until it returns
+// false. The shards are locked one after the other, so entries changed
+// meanwhile may or may not be seen; f must not change the map.
+func (c *containerMap) forEach(f func(name namespacedContainerName, cont *containerData) bool) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.lock.RLock()
+		for name, cont := range s.containers {
+			if !f(name, cont) {
+				s.lock.RUnlock()
+				return
+			}
+		}
+		s.lock.RUnlock()
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/cadvisor/watcher"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// registeredContainer returns the container registered under name, nil if
+// there is none.
+func registeredContainer(m *manager, name string) *containerData {
+	cont, _ := m.containers.get(namespacedContainerName{Name: name})
+	return cont
+}
+
+func TestContainerMap(t *testing.T) {
+	c := newContainerMap()
+	a, b := &containerData{}, &containerData{}
+	c.set(namespacedContainerName{Name: "/a"}, a)
+	c.set(namespacedContainerName{Namespace: "docker", Name: "a"}, a)
+	c.set(namespacedContainerName{Name: "/b"}, b)
+
+	cont, ok := c.get(namespacedContainerName{Name: "/a"})
+	assert.True(t, ok)
+	assert.Equal(t, a, cont)
+	cont, ok = c.get(namespacedContainerName{Namespace: "docker", Name: "a"})
+	assert.True(t, ok)
+	assert.Equal(t, a, cont)
+	_, ok = c.get(namespacedContainerName{Name: "a"})
+	assert.False(t, ok)
+	assert.Equal(t, 3, c.len())
+
+	seen := map[namespacedContainerName]*containerData{}
+	c.forEach(func(name namespacedContainerName, cont *containerData) bool {
+		seen[name] = cont
+		return true
+	})
+	assert.Len(t, seen, 3)
+
+	visited := 0
+	c.forEach(func(namespacedContainerName, *containerData) bool {
+		visited++
+		return false
+	})
+	assert.Equal(t, 1, visited)
+
+	c.delete(namespacedContainerName{Name: "/a"})
+	_, ok = c.get(namespacedContainerName{Name: "/a"})
+	assert.False(t, ok)
+	assert.Equal(t, 2, c.len())
+}
+
+func TestProcessWatchEventsBatchesPendingEvents(t *testing.T) {
+	m := &manager{
+		containers:    newContainerMap(),
+		eventsChannel: make(chan watcher.ContainerEvent, 2*maxWatchEventBatch),
+	}
+	for i := 0; i < 2*maxWatchEventBatch-1; i++ {
+		// Events of containers which are not monitored are no-ops.
+		m.eventsChannel <- watcher.ContainerEvent{EventType: watcher.ContainerDelete, Name: fmt.Sprintf("/%d", i)}
+	}
+
+	batch := m.processWatchEvents([]watcher.ContainerEvent{{EventType: watcher.ContainerDelete, Name: "/first"}})
+	assert.Len(t, batch, maxWatchEventBatch)
+	assert.Equal(t, "/first", batch[0].Name)
+	assert.Equal(t, "/0", batch[1].Name)
+	assert.Len(t, m.eventsChannel, maxWatchEventBatch)
+
+	batch = m.processWatchEvents(batch[:0])
+	assert.Len(t, batch, maxWatchEventBatch)
+	assert.Empty(t, m.eventsChannel)
+}
+
+// BenchmarkContainerChurn measures the lookups of containers while others
+// are registered and removed, as the API and the Prometheus collector do on
+// nodes with a high container churn.
+func BenchmarkContainerChurn(b *testing.B) {
+	const containers = 1000
+	c := newContainerMap()
+	for i := 0; i < containers; i++ {
+		c.set(namespacedContainerName{Name: fmt.Sprintf("/pod/%d", i)}, &containerData{})
+	}
+
+	var next int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			n := atomic.AddInt64(&next, 1)
+			name := namespacedContainerName{Name: fmt.Sprintf("/pod/%d", n%containers)}
+			// One in ten operations is a container restarting.
+			if i%10 == 0 {
+				c.delete(name)
+				c.set(name, &containerData{})
+				continue
+			}
+			c.get(name)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+var (
+	featureDisabledDesc = prometheus.NewDesc(
+		"cadvisor_internal_feature_disabled",
+		"Set to 1 when a feature is disabled for all containers because its collector could not be set up.",
+		[]string{"feature", "reason"}, nil)
+	featureDisabledContainersDesc = prometheus.NewDesc(
+		"cadvisor_internal_feature_disabled_containers",
+		"Number of containers a feature is disabled for because its collector could not be set up.",
+		[]string{"feature"}, nil)
+)
+
+type degradationKey struct {
+	feature   string
+	container string
+}
+
+// degradations records the features whose collectors could not be set up,
+// which otherwise silently collect nothing. It is a Prometheus collector of
+// the features disabled.
+type degradations struct {
+	lock    sync.Mutex
+	entries map[degradationKey]v2.Degradation
+}
+
+func newDegradations() *degradations {
+	return &degradations{entries: make(map[degradationKey]v2.Degradation)}
+}
+
+// update records that feature is disabled for container, or for all
+// containers if container is empty, if err is not nil, and that it is
+// enabled otherwise.
+func (d *degradations) update(feature, container string, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	key := degradationKey{feature: feature, container: container}
+	previous, disabled := d.entries[key]
+	if err == nil {
+		if disabled {
+			klog.Infof("Feature %s is enabled again%s", feature, forContainer(container))
+			delete(d.entries, key)
+		}
+		return
+	}
+	if disabled && previous.Reason == err.Error() {
+		return
+	}
+	if container == "" {
+		klog.Warningf("Feature %s is disabled: %v", feature, err)
+	}
+	d.entries[key] = v2.Degradation{
+		Feature:   feature,
+		Container: container,
+		Reason:    err.Error(),
+		Since:     time.Now(),
+	}
+}
+
+func forContainer(container string) string {
+	if container == "" {
+		return ""
+	}
+	return " for container " + container
+}
+
+// removeContainer forgets the features disabled for container.
+func (d *degradations) removeContainer(container string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for key := range d.entries {
+		if key.container == container {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// list returns the features disabled sorted by feature and container.
+func (d *degradations) list() []v2.Degradation {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	list := make([]v2.Degradation, 0, len(d.entries))
+	for _, entry := range d.entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Feature != list[j].Feature {
+			return list[i].Feature < list[j].Feature
+		}
+		return list[i].Container < list[j].Container
+	})
+	return list
+}
+
+func (d *degradations) Describe(ch chan<- *prometheus.Desc) {
+	ch <- featureDisabledDesc
+	ch <- featureDisabledContainersDesc
+}
+
+func (d *degradations) Collect(ch chan<- prometheus.Metric) {
+	containers := make(map[string]int)
+	for _, entry := range d.list() {
+		if entry.Container != "" {
+			containers[entry.Feature]++
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(featureDisabledDesc, prometheus.GaugeValue, 1, entry.Feature, entry.Reason)
+	}
+	for feature, count := range containers {
+		ch <- prometheus.MustNewConstMetric(featureDisabledContainersDesc, prometheus.GaugeValue, float64(count), feature)
+	}
+}
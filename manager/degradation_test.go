@@ -0,0 +1,60 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDegradations(t *testing.T) {
+	d := newDegradations()
+	d.update(perfCollector, "", errors.New("built without libpfm"))
+	d.update(resctrlCollector, "/a", errors.New("no mon group"))
+	d.update(resctrlCollector, "/b", errors.New("no mon group"))
+	d.update(nvidiaCollector, "/a", nil)
+
+	list := d.list()
+	require.Len(t, list, 3)
+	assert.Equal(t, perfCollector, list[0].Feature)
+	assert.Equal(t, "", list[0].Container)
+	assert.Equal(t, "built without libpfm", list[0].Reason)
+	assert.False(t, list[0].Since.IsZero())
+	assert.Equal(t, "/a", list[1].Container)
+	assert.Equal(t, "/b", list[2].Container)
+
+	expected := `
+# HELP cadvisor_internal_feature_disabled Set to 1 when a feature is disabled for all containers because its collector could not be set up.
+# TYPE cadvisor_internal_feature_disabled gauge
+cadvisor_internal_feature_disabled{feature="perf",reason="built without libpfm"} 1
+# HELP cadvisor_internal_feature_disabled_containers Number of containers a feature is disabled for because its collector could not be set up.
+# TYPE cadvisor_internal_feature_disabled_containers gauge
+cadvisor_internal_feature_disabled_containers{feature="resctrl"} 2
+`
+	assert.NoError(t, testutil.CollectAndCompare(d, strings.NewReader(expected)))
+
+	// Features set up again and removed containers are forgotten.
+	d.update(perfCollector, "", nil)
+	d.removeContainer("/a")
+	list = d.list()
+	require.Len(t, list, 1)
+	assert.Equal(t, resctrlCollector, list[0].Feature)
+	assert.Equal(t, "/b", list[0].Container)
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	v2 "github.com/google/cadvisor/info/v2"
+)
+
+// labelRequirement is a requirement of a label selector.
+type labelRequirement struct {
+	key   string
+	value string
+	// Whether the label must have value, or else not have it.
+	equal bool
+	// Whether only the presence of the label is required, or its absence if
+	// equal is false.
+	exists bool
+}
+
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.key]
+	if r.exists {
+		return ok == r.equal
+	}
+	return (ok && value == r.value) == r.equal
+}
+
+// parseLabelSelector parses a comma-separated list of key=value, key==value,
+// key!=value, key or !key requirements.
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	var requirements []labelRequirement
+	for _, item := range strings.Split(selector, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var r labelRequirement
+		switch {
+		case strings.Contains(item, "!="):
+			parts := strings.SplitN(item, "!=", 2)
+			r = labelRequirement{key: parts[0], value: parts[1]}
+		case strings.Contains(item, "="):
+			parts := strings.SplitN(item, "=", 2)
+			r = labelRequirement{key: parts[0], value: strings.TrimPrefix(parts[1], "="), equal: true}
+		case strings.HasPrefix(item, "!"):
+			r = labelRequirement{key: item[1:], exists: true}
+		default:
+			r = labelRequirement{key: item, equal: true, exists: true}
+		}
+		r.key, r.value = strings.TrimSpace(r.key), strings.TrimSpace(r.value)
+		if r.key == "" || strings.ContainsAny(r.key, "!=") {
+			return nil, fmt.Errorf("invalid label requirement %q", item)
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// containerFilter selects the containers monitored by their names, labels
+// and Kubernetes namespaces, so that the others are skipped at discovery.
+// The root container is always monitored.
+type containerFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+	labels  []labelRequirement
+	// Kubernetes namespaces included, any if empty, and excluded.
+	namespaces         map[string]bool
+	excludedNamespaces map[string]bool
+
+	lock sync.Mutex
+	// Names of the containers excluded by their labels, remembered as the
+	// labels of a container do not change.
+	excluded map[string]bool
+}
+
+// newContainerFilter returns the filter set by the flags, nil if they do
+// not filter any container.
+func newContainerFilter(include, exclude, labelSelector, namespaces string) (*containerFilter, error) {
+	f := &containerFilter{
+		namespaces:         make(map[string]bool),
+		excludedNamespaces: make(map[string]bool),
+		excluded:           make(map[string]bool),
+	}
+	var err error
+	if include != "" {
+		if f.include, err = regexp.Compile(include); err != nil {
+			return nil, fmt.Errorf("invalid container_include: %v", err)
+		}
+	}
+	if exclude != "" {
+		if f.exclude, err = regexp.Compile(exclude); err != nil {
+			return nil, fmt.Errorf("invalid container_exclude: %v", err)
+		}
+	}
+	if f.labels, err = parseLabelSelector(labelSelector); err != nil {
+		return nil, fmt.Errorf("invalid container_label_selector: %v", err)
+	}
+	for _, namespace := range strings.Split(namespaces, ",") {
+		namespace = strings.TrimSpace(namespace)
+		switch {
+		case namespace == "":
+		case strings.HasPrefix(namespace, "!"):
+			f.excludedNamespaces[namespace[1:]] = true
+		default:
+			f.namespaces[namespace] = true
+		}
+	}
+	if f.include == nil && f.exclude == nil && !f.filtersLabels() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// filtersLabels returns whether containers are filtered by their labels,
+// which requires creating their handlers.
+func (f *containerFilter) filtersLabels() bool {
+	return len(f.labels) > 0 || len(f.namespaces) > 0 || len(f.excludedNamespaces) > 0
+}
+
+// includesName returns whether the container named name may be monitored,
+// before its labels are known.
+func (f *containerFilter) includesName(name string) bool {
+	if f == nil || name == "/" {
+		return true
+	}
+	if f.exclude != nil && f.exclude.MatchString(name) {
+		return false
+	}
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return !f.excluded[name]
+}
+
+// includesLabels returns whether the container named name with labels is
+// monitored.
+func (f *containerFilter) includesLabels(name string, labels map[string]string) bool {
+	if f == nil || name == "/" || !f.filtersLabels() {
+		return true
+	}
+	included := true
+	for _, r := range f.labels {
+		if !r.matches(labels) {
+			included = false
+			break
+		}
+	}
+	if namespace, ok := labels[v2.PodNamespaceLabel]; ok && included {
+		included = !f.excludedNamespaces[namespace] && (len(f.namespaces) == 0 || f.namespaces[namespace])
+	}
+	if !included {
+		f.lock.Lock()
+		f.excluded[name] = true
+		f.lock.Unlock()
+	}
+	return included
+}
+
+// retain forgets the containers excluded by their labels which are not in
+// names, the names of all the containers which exist.
+func (f *containerFilter) retain(names map[string]bool) {
+	if f == nil {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for name := range f.excluded {
+		if !names[name] {
+			delete(f.excluded, name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerFilterNames(t *testing.T) {
+	f, err := newContainerFilter("^/(kubepods|docker)", "^/kubepods/besteffort", "", "")
+	require.NoError(t, err)
+	for name, included := range map[string]bool{
+		"/":                          true,
+		"/docker/abc":                true,
+		"/kubepods/burstable/pod1":   true,
+		"/kubepods/besteffort/pod2":  false,
+		"/system.slice/sshd.service": false,
+	} {
+		assert.Equal(t, included, f.includesName(name), name)
+	}
+	// Filtering by name does not require the labels.
+	assert.False(t, f.filtersLabels())
+
+	f, err = newContainerFilter("", "", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, f)
+	assert.True(t, f.includesName("/system.slice"))
+	assert.True(t, f.includesLabels("/system.slice", nil))
+}
+
+func TestContainerFilterLabels(t *testing.T) {
+	f, err := newContainerFilter("", "", "io.kubernetes.container.name!=POD, app, !debug, tier==web", "!kube-system")
+	require.NoError(t, err)
+	require.True(t, f.filtersLabels())
+	for _, tc := range []struct {
+		name     string
+		labels   map[string]string
+		included bool
+	}{
+		{"/", nil, true},
+		{"/a", map[string]string{"app": "x", "tier": "web"}, true},
+		{"/b", map[string]string{"app": "x", "tier": "web", "io.kubernetes.container.name": "POD"}, false},
+		{"/c", map[string]string{"tier": "web"}, false},
+		{"/d", map[string]string{"app": "x", "tier": "web", "debug": ""}, false},
+		{"/e", map[string]string{"app": "x", "tier": "db"}, false},
+		{"/f", map[string]string{"app": "x", "tier": "web", "io.kubernetes.pod.namespace": "kube-system"}, false},
+		{"/g", map[string]string{"app": "x", "tier": "web", "io.kubernetes.pod.namespace": "default"}, true},
+	} {
+		assert.Equal(t, tc.included, f.includesLabels(tc.name, tc.labels), tc.name)
+	}
+
+	// Containers excluded by their labels are skipped by name until they
+	// no longer exist.
+	assert.False(t, f.includesName("/b"))
+	assert.True(t, f.includesName("/a"))
+	f.retain(map[string]bool{"/c": true})
+	assert.True(t, f.includesName("/b"))
+	assert.False(t, f.includesName("/c"))
+}
+
+func TestContainerFilterNamespaces(t *testing.T) {
+	f, err := newContainerFilter("", "", "", "default,monitoring")
+	require.NoError(t, err)
+	assert.True(t, f.includesLabels("/a", map[string]string{"io.kubernetes.pod.namespace": "monitoring"}))
+	assert.False(t, f.includesLabels("/b", map[string]string{"io.kubernetes.pod.namespace": "kube-system"}))
+	// Containers outside of pods are not filtered by namespace.
+	assert.True(t, f.includesLabels("/system.slice", nil))
+}
+
+func TestContainerFilterInvalid(t *testing.T) {
+	for _, flags := range [][4]string{
+		{"(", "", "", ""},
+		{"", "[", "", ""},
+		{"", "", "=value", ""},
+		{"", "", "!", ""},
+		{"", "", "!a=b", ""},
+	} {
+		_, err := newContainerFilter(flags[0], flags[1], flags[2], flags[3])
+		assert.Error(t, err, "%v", flags)
+	}
+}
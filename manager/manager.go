@@ -16,11 +16,13 @@
 package manager
 
 import (
-	"flag"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,8 +32,10 @@ import (
 	"github.com/google/cadvisor/cache/memory"
 	"github.com/google/cadvisor/collector"
 	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
 	"github.com/google/cadvisor/container/docker"
 	"github.com/google/cadvisor/container/raw"
+	"github.com/google/cadvisor/enrichment"
 	"github.com/google/cadvisor/events"
 	"github.com/google/cadvisor/fs"
 	info "github.com/google/cadvisor/info/v1"
@@ -41,25 +45,48 @@ import (
 	"github.com/google/cadvisor/perf"
 	"github.com/google/cadvisor/resctrl"
 	"github.com/google/cadvisor/stats"
+	"github.com/google/cadvisor/stats/plugin"
 	"github.com/google/cadvisor/utils/oomparser"
 	"github.com/google/cadvisor/utils/sysfs"
+	"github.com/google/cadvisor/utils/sysinfo"
 	"github.com/google/cadvisor/version"
 	"github.com/google/cadvisor/watcher"
 
 	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/opencontainers/runc/libcontainer/cgroups/fs2"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 )
 
-var globalHousekeepingInterval = flag.Duration("global_housekeeping_interval", 1*time.Minute, "Interval between global housekeepings")
-var updateMachineInfoInterval = flag.Duration("update_machine_info_interval", 5*time.Minute, "Interval between machine info updates.")
-var logCadvisorUsage = flag.Bool("log_cadvisor_usage", false, "Whether to log the usage of the cAdvisor container")
-var eventStorageAgeLimit = flag.String("event_storage_age_limit", "default=24h", "Max length of time for which to store events (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is a duration. Default is applied to all non-specified event types")
-var eventStorageEventLimit = flag.String("event_storage_event_limit", "default=100000", "Max number of events to store (per type). Value is a comma separated list of key values, where the keys are event types (e.g.: creation, oom) or \"default\" and the value is an integer. Default is applied to all non-specified event types")
-var applicationMetricsCountLimit = flag.Int("application_metrics_count_limit", 100, "Max number of application metrics to store (per container)")
+var (
+	// ErrUnknownContainer is wrapped by the errors of requests for containers
+	// the manager does not know about.
+	ErrUnknownContainer = errors.New("unknown container")
+	// ErrCollectorDisabled is wrapped by the errors of requests for stats
+	// which are not collected.
+	ErrCollectorDisabled = errors.New("collector disabled")
+	// ErrCollectorAPIDisabled is wrapped by the errors of requests changing
+	// the collectors of containers while --enable_collector_api is unset.
+	ErrCollectorAPIDisabled = errors.New("collector API disabled")
+	// ErrCollectorExists is wrapped by the errors of requests adding a
+	// collector whose name is already registered for the container.
+	ErrCollectorExists = errors.New("collector already exists")
+	// ErrInvalidWatch is wrapped by the errors of requests monitoring a
+	// cgroup with an invalid name or TTL.
+	ErrInvalidWatch = errors.New("invalid watch")
+	// ErrContainerMonitored is wrapped by the errors of requests monitoring
+	// a cgroup which is already monitored without having been requested.
+	ErrContainerMonitored = errors.New("container already monitored")
+	// ErrTooManyWatches is wrapped by the errors of requests monitoring a
+	// cgroup once --adhoc_watch_limit cgroups are monitored on request.
+	ErrTooManyWatches = errors.New("too many watches")
+	// ErrInvalidQuery is wrapped by the errors of queries with an unknown
+	// metric or operator.
+	ErrInvalidQuery = errors.New("invalid query")
+)
 
 // The Manager interface defines operations for starting a manager and getting
 // container and machine information.
@@ -121,6 +148,20 @@ type Manager interface {
 	// Get ps output for a container.
 	GetProcessList(containerName string, options v2.RequestOptions) ([]v2.ProcessInfo, error)
 
+	// Get the usage of the writable layer of a container broken down by
+	// directory up to depth levels.
+	GetStorageBreakdown(containerName string, depth int, options v2.RequestOptions) (v2.StorageBreakdown, error)
+
+	// Get the names of the application metrics collectors of a container.
+	GetCollectors(containerName string, options v2.RequestOptions) ([]string, error)
+
+	// Add an application metrics collector to a container and return the
+	// specs of its metrics. The collector is only validated if dryRun is set.
+	AddCollector(containerName, collectorName string, config []byte, dryRun bool, options v2.RequestOptions) ([]info.MetricSpec, error)
+
+	// Remove an application metrics collector from a container.
+	RemoveCollector(containerName, collectorName string, options v2.RequestOptions) error
+
 	// Get events streamed through passedChannel that fit the request.
 	WatchForEvents(request *events.Request) (*events.EventChannel, error)
 
@@ -129,6 +170,31 @@ type Manager interface {
 
 	CloseEventChannel(watchID int)
 
+	// Get the stats of the requested containers streamed as they are collected.
+	WatchForStats(containerName string, options v2.RequestOptions) (*StatsChannel, error)
+
+	CloseStatsChannel(watchID int)
+
+	// Get the collectors of the metrics about cAdvisor itself.
+	InternalMetrics() []prometheus.Collector
+
+	// Get the features of cAdvisor which are disabled and why.
+	GetStatus() v2.Status
+
+	// Monitor the cgroup named containerName for ttl, or extend the ttl if
+	// it is already monitored on request.
+	WatchCgroup(containerName string, ttl time.Duration) (v2.AdHocWatch, error)
+
+	// Stop monitoring a cgroup monitored on request.
+	UnwatchCgroup(containerName string) error
+
+	// Get the cgroups monitored on request.
+	GetAdHocWatches() []v2.AdHocWatch
+
+	// Aggregate a metric of the stats in memory of the subcontainers of a
+	// container, grouped by labels, sorted by decreasing value.
+	Query(containerName string, query v2.Query, options v2.RequestOptions) ([]v2.QueryResult, error)
+
 	// Get status information about docker.
 	DockerInfo() (info.DockerStatus, error)
 
@@ -139,17 +205,40 @@ type Manager interface {
 	DebugInfo() map[string][]string
 }
 
-// Housekeeping configuration for the manager
+// Housekeeping configuration for the manager, see New.
 type HouskeepingConfig = struct {
 	Interval     *time.Duration
 	AllowDynamic *bool
 }
 
-// New takes a memory storage and returns a new manager.
+// New takes a memory storage and returns a new manager. The options not
+// given are the defaults.
+//
+// Deprecated: use NewWithOptions, which configures all options.
 func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig HouskeepingConfig, includedMetricsSet container.MetricSet, collectorHTTPClient *http.Client, rawContainerCgroupPathPrefixWhiteList []string, perfEventsFile string) (Manager, error) {
+	options := DefaultOptions()
+	if houskeepingConfig.Interval != nil {
+		options.HousekeepingInterval = *houskeepingConfig.Interval
+	}
+	if houskeepingConfig.AllowDynamic != nil {
+		options.AllowDynamicHousekeeping = *houskeepingConfig.AllowDynamic
+	}
+	options.IncludedMetrics = includedMetricsSet
+	options.CollectorHTTPClient = collectorHTTPClient
+	options.RawContainerCgroupPathPrefixWhiteList = rawContainerCgroupPathPrefixWhiteList
+	options.PerfEventsFile = perfEventsFile
+	return NewWithOptions(memoryCache, sysfs, options)
+}
+
+// NewWithOptions takes a memory storage and returns a new manager configured
+// by options.
+func NewWithOptions(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, options Options) (Manager, error) {
 	if memoryCache == nil {
 		return nil, fmt.Errorf("manager requires memory storage")
 	}
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manager options: %v", err)
+	}
 
 	// Detect the container we are running on.
 	selfContainer := "/"
@@ -165,7 +254,7 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 		klog.V(2).Infof("cAdvisor running in container: %q", selfContainer)
 	}
 
-	context := fs.Context{}
+	context := fs.Context{ExtraMounts: options.ExtraFsMounts}
 
 	if err := container.InitializeFSContext(&context); err != nil {
 		return nil, err
@@ -187,40 +276,64 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 	eventsChannel := make(chan watcher.ContainerEvent, 16)
 
 	newManager := &manager{
-		containers:                            make(map[namespacedContainerName]*containerData),
-		quitChannels:                          make([]chan error, 0, 2),
-		memoryCache:                           memoryCache,
-		fsInfo:                                fsInfo,
-		sysFs:                                 sysfs,
-		cadvisorContainer:                     selfContainer,
-		inHostNamespace:                       inHostNamespace,
-		startupTime:                           time.Now(),
-		maxHousekeepingInterval:               *houskeepingConfig.Interval,
-		allowDynamicHousekeeping:              *houskeepingConfig.AllowDynamic,
-		includedMetrics:                       includedMetricsSet,
-		containerWatchers:                     []watcher.ContainerWatcher{},
-		eventsChannel:                         eventsChannel,
-		collectorHTTPClient:                   collectorHTTPClient,
-		nvidiaManager:                         accelerators.NewNvidiaManager(includedMetricsSet),
-		rawContainerCgroupPathPrefixWhiteList: rawContainerCgroupPathPrefixWhiteList,
-	}
-
-	machineInfo, err := machine.Info(sysfs, fsInfo, inHostNamespace)
+		options:           options,
+		containers:        newContainerMap(),
+		quitChannels:      make([]chan error, 0, 2),
+		memoryCache:       memoryCache,
+		fsInfo:            fsInfo,
+		sysFs:             sysfs,
+		cadvisorContainer: selfContainer,
+		inHostNamespace:   inHostNamespace,
+		startupTime:       time.Now(),
+		statsWatchers:     newStatsWatchers(),
+		containerWatchers: []watcher.ContainerWatcher{},
+		eventsChannel:     eventsChannel,
+		degradations:      newDegradations(),
+		adHocWatches:      make(map[string]*adHocWatch),
+		restoreLineage:    newRestoreLineage(),
+	}
+
+	newManager.containerFilter, err = newContainerFilter(options.ContainerInclude, options.ContainerExclude, options.ContainerLabelSelector, options.ContainerNamespaces)
+	if err != nil {
+		return nil, err
+	}
+	if options.NestedCgroupsContainers != "" {
+		newManager.nestedCgroupsContainers = regexp.MustCompile(options.NestedCgroupsContainers)
+	}
+
+	newManager.nvidiaManager, err = accelerators.NewNvidiaManager(options.IncludedMetrics)
+	newManager.degradations.update(nvidiaCollector, "", err)
+
+	machineInfo, err := machine.InfoWithOptions(sysfs, fsInfo, inHostNamespace, options.MachineInfo)
 	if err != nil {
 		return nil, err
 	}
 	newManager.machineInfo = *machineInfo
 	klog.V(1).Infof("Machine: %+v", newManager.machineInfo)
 
-	newManager.perfManager, err = perf.NewManager(perfEventsFile, machineInfo.Topology)
-	if err != nil {
+	newManager.perfManager, err = perf.NewManager(options.PerfEventsFile, machineInfo.Topology)
+	if err != nil && !errors.Is(err, stats.ErrUnavailable) {
 		return nil, err
 	}
+	newManager.degradations.update(perfCollector, "", err)
 
 	newManager.resctrlManager, err = resctrl.NewManager(selfContainer)
 	if err != nil {
 		klog.V(4).Infof("Cannot gather resctrl metrics: %v", err)
 	}
+	if options.IncludedMetrics.Has(container.ResctrlMetrics) {
+		newManager.degradations.update(resctrlCollector, "", err)
+	}
+
+	newManager.pluginManager, err = plugin.NewManager(options.StatsPlugins, options.StatsPluginTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	newManager.enrichers, err = enrichment.Enrichers()
+	if err != nil {
+		klog.Warningf("Some container enrichers are unavailable: %v", err)
+	}
 
 	versionInfo, err := getVersionInfo()
 	if err != nil {
@@ -228,7 +341,15 @@ func New(memoryCache *memory.InMemoryCache, sysfs sysfs.SysFs, houskeepingConfig
 	}
 	klog.V(1).Infof("Version: %+v", *versionInfo)
 
-	newManager.eventHandler = events.NewEventManager(parseEventsStoragePolicy())
+	if options.EventStore != nil {
+		newManager.eventHandler, err = events.NewPersistentEventManager(options.EventStoragePolicy, options.EventStore)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore events: %v", err)
+		}
+	} else {
+		newManager.eventHandler = events.NewEventManager(options.EventStoragePolicy)
+	}
+	newManager.internalMetrics = newInternalMetrics(newManager)
 	return newManager, nil
 }
 
@@ -242,36 +363,51 @@ type namespacedContainerName struct {
 }
 
 type manager struct {
-	containers               map[namespacedContainerName]*containerData
-	containersLock           sync.RWMutex
-	memoryCache              *memory.InMemoryCache
-	fsInfo                   fs.FsInfo
-	sysFs                    sysfs.SysFs
-	machineMu                sync.RWMutex // protects machineInfo
-	machineInfo              info.MachineInfo
-	quitChannels             []chan error
-	cadvisorContainer        string
-	inHostNamespace          bool
-	eventHandler             events.EventManager
-	startupTime              time.Time
-	maxHousekeepingInterval  time.Duration
-	allowDynamicHousekeeping bool
-	includedMetrics          container.MetricSet
-	containerWatchers        []watcher.ContainerWatcher
-	eventsChannel            chan watcher.ContainerEvent
-	collectorHTTPClient      *http.Client
-	nvidiaManager            stats.Manager
-	perfManager              stats.Manager
-	resctrlManager           stats.Manager
-	// List of raw container cgroup path prefix whitelist.
-	rawContainerCgroupPathPrefixWhiteList []string
+	options    Options
+	containers *containerMap
+	// Serializes the registration and removal of containers. Lookups of
+	// containers go through the shards of containers instead.
+	containersLock    sync.RWMutex
+	memoryCache       *memory.InMemoryCache
+	fsInfo            fs.FsInfo
+	sysFs             sysfs.SysFs
+	machineMu         sync.RWMutex // protects machineInfo
+	machineInfo       info.MachineInfo
+	quitChannels      []chan error
+	cadvisorContainer string
+	inHostNamespace   bool
+	statsdListener    *collector.StatsdListener
+	internalMetrics   []prometheus.Collector
+	collectorsLock    sync.Mutex // serializes changes of collectors through the API
+	eventHandler      events.EventManager
+	startupTime       time.Time
+	containerWatchers []watcher.ContainerWatcher
+	eventsChannel     chan watcher.ContainerEvent
+	nvidiaManager     stats.Manager
+	perfManager       stats.Manager
+	resctrlManager    stats.Manager
+	pluginManager     *plugin.Manager
+	enrichers         []enrichment.Enricher
+	statsWatchers     *statsWatchers
+	// Features disabled because their collectors could not be set up.
+	degradations *degradations
+	// Selects the containers monitored, nil if all are.
+	containerFilter *containerFilter
+	// Matches the names of the containers whose child cgroups are
+	// monitored, nil if none.
+	nestedCgroupsContainers *regexp.Regexp
+	// Cgroups monitored on request by name, guarded by containersLock.
+	adHocWatches map[string]*adHocWatch
+	// Lineage of the containers restored from checkpoints.
+	restoreLineage *restoreLineage
 }
 
 // Start the container manager.
 func (m *manager) Start() error {
-	m.containerWatchers = container.InitializePlugins(m, m.fsInfo, m.includedMetrics)
+	common.SetFsUsageInterval(m.options.FsUsageInterval)
+	m.containerWatchers = container.InitializePlugins(m, m.fsInfo, m.options.IncludedMetrics)
 
-	err := raw.Register(m, m.fsInfo, m.includedMetrics, m.rawContainerCgroupPathPrefixWhiteList)
+	err := raw.Register(m, m.fsInfo, m.options.IncludedMetrics, m.options.RawContainerCgroupPathPrefixWhiteList)
 	if err != nil {
 		klog.Errorf("Registration of the raw container factory failed: %v", err)
 	}
@@ -282,6 +418,18 @@ func (m *manager) Start() error {
 	}
 	m.containerWatchers = append(m.containerWatchers, rawWatcher)
 
+	if m.options.StatsdListenAddress != "" {
+		procRoot := "/"
+		if !m.inHostNamespace {
+			procRoot = "/rootfs"
+		}
+		m.statsdListener, err = collector.NewStatsdListener(m.options.StatsdListenAddress, m.options.ApplicationMetricsCountLimit, procRoot)
+		if err != nil {
+			return fmt.Errorf("failed to start statsd listener: %v", err)
+		}
+		m.statsdListener.Start()
+	}
+
 	// Watch for OOMs.
 	err = m.watchForNewOoms()
 	if err != nil {
@@ -322,11 +470,18 @@ func (m *manager) Start() error {
 	m.quitChannels = append(m.quitChannels, quitUpdateMachineInfo)
 	go m.updateMachineInfo(quitUpdateMachineInfo)
 
+	if m.options.IncludedMetrics.Has(container.MdRaidMetrics) {
+		quitMdArrays := make(chan error)
+		m.quitChannels = append(m.quitChannels, quitMdArrays)
+		go m.watchMdArrays(quitMdArrays)
+	}
+
 	return nil
 }
 
 func (m *manager) Stop() error {
 	defer m.nvidiaManager.Destroy()
+	defer m.pluginManager.Destroy()
 	defer m.destroyPerfCollectors()
 	// Stop and wait on all quit channels.
 	for i, c := range m.quitChannels {
@@ -340,31 +495,172 @@ func (m *manager) Stop() error {
 		}
 	}
 	m.quitChannels = make([]chan error, 0, 2)
+	if m.statsdListener != nil {
+		if err := m.statsdListener.Stop(); err != nil {
+			klog.Warningf("Failed to stop statsd listener: %v", err)
+		}
+	}
 	nvm.Finalize()
 	perf.Finalize()
 	return nil
 }
 
 func (m *manager) destroyPerfCollectors() {
-	for _, container := range m.containers {
-		container.perfCollector.Destroy()
-	}
+	m.containers.forEach(func(_ namespacedContainerName, cont *containerData) bool {
+		cont.perfCollector.Destroy()
+		return true
+	})
 }
 
 func (m *manager) updateMachineInfo(quit chan error) {
-	ticker := time.NewTicker(*updateMachineInfoInterval)
+	ticker := time.NewTicker(m.options.UpdateMachineInfoInterval)
 	for {
 		select {
 		case <-ticker.C:
-			info, err := machine.Info(m.sysFs, m.fsInfo, m.inHostNamespace)
+			info, err := machine.InfoWithOptions(m.sysFs, m.fsInfo, m.inHostNamespace, m.options.MachineInfo)
 			if err != nil {
 				klog.Errorf("Could not get machine info: %v", err)
 				break
 			}
 			m.machineMu.Lock()
+			changes := diffMachineInfo(&m.machineInfo, info)
 			m.machineInfo = *info
 			m.machineMu.Unlock()
 			klog.V(5).Infof("Update machine info: %+v", *info)
+			if len(changes) > 0 {
+				klog.V(1).Infof("Machine info changed: %+v", changes)
+				m.addMachineInfoChangedEvent(info.Timestamp, changes)
+			}
+		case <-quit:
+			ticker.Stop()
+			quit <- nil
+			return
+		}
+	}
+}
+
+func (m *manager) addMachineInfoChangedEvent(timestamp time.Time, changes []info.MachineInfoChange) {
+	newEvent := &info.Event{
+		ContainerName: "/",
+		Timestamp:     timestamp,
+		EventType:     info.EventMachineInfoChanged,
+		EventData: info.EventData{
+			MachineInfoChanged: &info.MachineInfoChangedEventData{
+				Changes: changes,
+			},
+		},
+	}
+	if err := m.eventHandler.AddEvent(newEvent); err != nil {
+		klog.Errorf("Failed to add machine info changed event: %v", err)
+	}
+}
+
+// diffMachineInfo returns the hardware relevant fields that differ between
+// the old and the new machine info.
+func diffMachineInfo(oldInfo, newInfo *info.MachineInfo) []info.MachineInfoChange {
+	var changes []info.MachineInfoChange
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, info.MachineInfoChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	add("num_cores", strconv.Itoa(oldInfo.NumCores), strconv.Itoa(newInfo.NumCores))
+	add("num_physical_cores", strconv.Itoa(oldInfo.NumPhysicalCores), strconv.Itoa(newInfo.NumPhysicalCores))
+	add("num_sockets", strconv.Itoa(oldInfo.NumSockets), strconv.Itoa(newInfo.NumSockets))
+	add("online_cpus", onlineCPUs(oldInfo.Topology), onlineCPUs(newInfo.Topology))
+	add("memory_capacity", strconv.FormatUint(oldInfo.MemoryCapacity, 10), strconv.FormatUint(newInfo.MemoryCapacity, 10))
+	add("network_devices", netDeviceNames(oldInfo.NetworkDevices), netDeviceNames(newInfo.NetworkDevices))
+	add("disk_map", diskNames(oldInfo.DiskMap), diskNames(newInfo.DiskMap))
+
+	// Queue settings of disks, which udev rules or administrators may change
+	// at runtime, e.g. the scheduler. Changed fields are named after the disk,
+	// ex. disk_map.sda.scheduler.
+	devices := make([]string, 0, len(newInfo.DiskMap))
+	for device := range newInfo.DiskMap {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		oldDisk, ok := oldInfo.DiskMap[device]
+		newDisk := newInfo.DiskMap[device]
+		if !ok || oldDisk.Name != newDisk.Name {
+			continue
+		}
+		prefix := "disk_map." + newDisk.Name + "."
+		add(prefix+"scheduler", oldDisk.Scheduler, newDisk.Scheduler)
+		add(prefix+"rotational", strconv.FormatBool(oldDisk.Rotational), strconv.FormatBool(newDisk.Rotational))
+		add(prefix+"nr_requests", strconv.FormatUint(oldDisk.NrRequests, 10), strconv.FormatUint(newDisk.NrRequests, 10))
+		add(prefix+"queue_depth", strconv.FormatUint(oldDisk.QueueDepth, 10), strconv.FormatUint(newDisk.QueueDepth, 10))
+		add(prefix+"write_cache", oldDisk.WriteCache, newDisk.WriteCache)
+		add(prefix+"discard", strconv.FormatBool(oldDisk.Discard), strconv.FormatBool(newDisk.Discard))
+		// e.g. a multipath device losing one of its paths.
+		add(prefix+"slaves", strings.Join(oldDisk.Slaves, ","), strings.Join(newDisk.Slaves, ","))
+	}
+	return changes
+}
+
+// onlineCPUs returns the ids of the CPUs in the topology, which only lists
+// online CPUs, in the kernel's list format.
+func onlineCPUs(topology []info.Node) string {
+	cpus := sysinfo.GetOnlineCPUs(topology)
+	sort.Ints(cpus)
+	var ranges []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+func netDeviceNames(devices []info.NetInfo) string {
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, device.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func diskNames(disks map[string]info.DiskInfo) string {
+	names := make([]string, 0, len(disks))
+	for _, disk := range disks {
+		names = append(names, disk.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// watchMdArrays polls the state of the host's md arrays and adds an event
+// whenever an array is assembled, stopped or changes state, e.g. when it
+// becomes degraded or starts resyncing.
+func (m *manager) watchMdArrays(quit chan error) {
+	var arrays []info.MdArrayStats
+	ticker := time.NewTicker(m.options.HousekeepingInterval)
+	for first := true; ; first = false {
+		newArrays, err := machine.GetMdArrayStats()
+		if err != nil {
+			klog.Errorf("Could not get md array stats: %v", err)
+		} else {
+			if !first {
+				timestamp := time.Now()
+				for _, change := range diffMdArrays(arrays, newArrays) {
+					klog.V(1).Infof("md array %s changed: %+v -> %+v", change.Array, change.Old, change.New)
+					m.addMdArrayChangedEvent(timestamp, change)
+				}
+			}
+			arrays = newArrays
+		}
+
+		select {
+		case <-ticker.C:
 		case <-quit:
 			ticker.Stop()
 			quit <- nil
@@ -373,14 +669,67 @@ func (m *manager) updateMachineInfo(quit chan error) {
 	}
 }
 
+func (m *manager) addMdArrayChangedEvent(timestamp time.Time, change *info.MdArrayChangedEventData) {
+	newEvent := &info.Event{
+		ContainerName: "/",
+		Timestamp:     timestamp,
+		EventType:     info.EventMdArrayChanged,
+		EventData: info.EventData{
+			MdArrayChanged: change,
+		},
+	}
+	if err := m.eventHandler.AddEvent(newEvent); err != nil {
+		klog.Errorf("Failed to add md array changed event: %v", err)
+	}
+}
+
+// diffMdArrays returns the state transitions of the arrays between two polls,
+// sorted by array name.
+func diffMdArrays(oldArrays, newArrays []info.MdArrayStats) []*info.MdArrayChangedEventData {
+	states := make(map[string]*info.MdArrayChangedEventData)
+	for i := range oldArrays {
+		states[oldArrays[i].Name] = &info.MdArrayChangedEventData{
+			Array: oldArrays[i].Name,
+			Old:   machine.MdArrayState(&oldArrays[i]),
+		}
+	}
+	for i := range newArrays {
+		change, ok := states[newArrays[i].Name]
+		if !ok {
+			change = &info.MdArrayChangedEventData{Array: newArrays[i].Name}
+			states[newArrays[i].Name] = change
+		}
+		change.New = machine.MdArrayState(&newArrays[i])
+	}
+
+	var changes []*info.MdArrayChangedEventData
+	for _, change := range states {
+		if !mdArrayStateEqual(change.Old, change.New) {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Array < changes[j].Array
+	})
+	return changes
+}
+
+func mdArrayStateEqual(a, b *info.MdArrayState) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.State == b.State && a.Degraded == b.Degraded && a.SyncAction == b.SyncAction &&
+		strings.Join(a.FailedMembers, ",") == strings.Join(b.FailedMembers, ",")
+}
+
 func (m *manager) globalHousekeeping(quit chan error) {
 	// Long housekeeping is either 100ms or half of the housekeeping interval.
 	longHousekeeping := 100 * time.Millisecond
-	if *globalHousekeepingInterval/2 < longHousekeeping {
-		longHousekeeping = *globalHousekeepingInterval / 2
+	if m.options.GlobalHousekeepingInterval/2 < longHousekeeping {
+		longHousekeeping = m.options.GlobalHousekeepingInterval / 2
 	}
 
-	ticker := time.NewTicker(*globalHousekeepingInterval)
+	ticker := time.NewTicker(m.options.GlobalHousekeepingInterval)
 	for {
 		select {
 		case t := <-ticker.C:
@@ -407,19 +756,12 @@ func (m *manager) globalHousekeeping(quit chan error) {
 }
 
 func (m *manager) getContainerData(containerName string) (*containerData, error) {
-	var cont *containerData
-	var ok bool
-	func() {
-		m.containersLock.RLock()
-		defer m.containersLock.RUnlock()
-
-		// Ensure we have the container.
-		cont, ok = m.containers[namespacedContainerName{
-			Name: containerName,
-		}]
-	}()
+	// Ensure we have the container.
+	cont, ok := m.containers.get(namespacedContainerName{
+		Name: containerName,
+	})
 	if !ok {
-		return nil, fmt.Errorf("unknown container %q", containerName)
+		return nil, fmt.Errorf("%w %q", ErrUnknownContainer, containerName)
 	}
 	return cont, nil
 }
@@ -485,10 +827,14 @@ func (m *manager) GetContainerInfo(containerName string, query *info.ContainerIn
 	if err != nil {
 		return nil, err
 	}
-	return m.containerDataToContainerInfo(cont, query)
+	m.updateStatsOnDemand(map[string]*containerData{cont.info.Name: cont})
+	return m.containerDataToContainerInfo(cont, query, v2.ResolutionRaw)
 }
 
 func (m *manager) GetContainerInfoV2(containerName string, options v2.RequestOptions) (map[string]v2.ContainerInfo, error) {
+	if err := m.checkResolution(options.Resolution); err != nil {
+		return nil, err
+	}
 	containers, err := m.getRequestedContainers(containerName, options)
 	if err != nil {
 		return nil, err
@@ -508,7 +854,7 @@ func (m *manager) GetContainerInfoV2(containerName string, options v2.RequestOpt
 		}
 		result.Spec = m.getV2Spec(cinfo)
 
-		stats, err := m.memoryCache.RecentStats(name, nilTime, nilTime, options.Count)
+		stats, aggregations, err := m.recentStats(name, nilTime, nilTime, options.Count, options.Resolution)
 		if err != nil {
 			errs.append(name, "RecentStats", err)
 			infos[name] = result
@@ -516,20 +862,51 @@ func (m *manager) GetContainerInfoV2(containerName string, options v2.RequestOpt
 		}
 
 		result.Stats = v2.ContainerStatsFromV1(containerName, &cinfo.Spec, stats)
+		for i := range aggregations {
+			result.Stats[i].Aggregation = &aggregations[i]
+		}
 		infos[name] = result
 	}
 
 	return infos, errs.OrNil()
 }
 
-func (m *manager) containerDataToContainerInfo(cont *containerData, query *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
+// Returns an error if stats of the given resolution are not kept.
+func (m *manager) checkResolution(resolution string) error {
+	if resolution == v2.ResolutionDownsampled && !m.memoryCache.DownsamplingEnabled() {
+		return memory.ErrDownsamplingDisabled
+	}
+	return nil
+}
+
+// Returns the stats of a container at the given resolution, along with their
+// aggregations when downsampled.
+func (m *manager) recentStats(name string, start, end time.Time, maxStats int, resolution string) ([]*info.ContainerStats, []v2.StatsAggregation, error) {
+	if resolution != v2.ResolutionDownsampled {
+		stats, err := m.memoryCache.RecentStats(name, start, end, maxStats)
+		return stats, nil, err
+	}
+	downsampled, err := m.memoryCache.DownsampledStats(name, start, end, maxStats)
+	if err != nil {
+		return nil, nil, err
+	}
+	stats := make([]*info.ContainerStats, len(downsampled))
+	aggregations := make([]v2.StatsAggregation, len(downsampled))
+	for i, d := range downsampled {
+		stats[i] = d.Stats
+		aggregations[i] = d.Aggregation
+	}
+	return stats, aggregations, nil
+}
+
+func (m *manager) containerDataToContainerInfo(cont *containerData, query *info.ContainerInfoRequest, resolution string) (*info.ContainerInfo, error) {
 	// Get the info from the container.
 	cinfo, err := cont.GetInfo(true)
 	if err != nil {
 		return nil, err
 	}
 
-	stats, err := m.memoryCache.RecentStats(cinfo.Name, query.Start, query.End, query.NumStats)
+	stats, _, err := m.recentStats(cinfo.Name, query.Start, query.End, query.NumStats, resolution)
 	if err != nil {
 		return nil, err
 	}
@@ -545,36 +922,34 @@ func (m *manager) containerDataToContainerInfo(cont *containerData, query *info.
 }
 
 func (m *manager) getContainer(containerName string) (*containerData, error) {
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-	cont, ok := m.containers[namespacedContainerName{Name: containerName}]
+	cont, ok := m.containers.get(namespacedContainerName{Name: containerName})
 	if !ok {
-		return nil, fmt.Errorf("unknown container %q", containerName)
+		return nil, fmt.Errorf("%w %q", ErrUnknownContainer, containerName)
 	}
 	return cont, nil
 }
 
 func (m *manager) getSubcontainers(containerName string) map[string]*containerData {
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-	containersMap := make(map[string]*containerData, len(m.containers))
+	containersMap := make(map[string]*containerData)
 
 	// Get all the unique subcontainers of the specified container
 	matchedName := path.Join(containerName, "/")
-	for i := range m.containers {
-		if m.containers[i] == nil {
-			continue
+	m.containers.forEach(func(_ namespacedContainerName, cont *containerData) bool {
+		if cont == nil {
+			return true
 		}
-		name := m.containers[i].info.Name
+		name := cont.info.Name
 		if name == containerName || strings.HasPrefix(name, matchedName) {
-			containersMap[m.containers[i].info.Name] = m.containers[i]
+			containersMap[name] = cont
 		}
-	}
+		return true
+	})
 	return containersMap
 }
 
 func (m *manager) SubcontainersInfo(containerName string, query *info.ContainerInfoRequest) ([]*info.ContainerInfo, error) {
 	containersMap := m.getSubcontainers(containerName)
+	m.updateStatsOnDemand(containersMap)
 
 	containers := make([]*containerData, 0, len(containersMap))
 	for _, cont := range containersMap {
@@ -584,25 +959,25 @@ func (m *manager) SubcontainersInfo(containerName string, query *info.ContainerI
 }
 
 func (m *manager) getAllDockerContainers() map[string]*containerData {
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-	containers := make(map[string]*containerData, len(m.containers))
+	containers := make(map[string]*containerData)
 
 	// Get containers in the Docker namespace.
-	for name, cont := range m.containers {
+	m.containers.forEach(func(name namespacedContainerName, cont *containerData) bool {
 		if name.Namespace == docker.DockerNamespace {
 			containers[cont.info.Name] = cont
 		}
-	}
+		return true
+	})
 	return containers
 }
 
 func (m *manager) AllDockerContainers(query *info.ContainerInfoRequest) (map[string]info.ContainerInfo, error) {
 	containers := m.getAllDockerContainers()
+	m.updateStatsOnDemand(containers)
 
 	output := make(map[string]info.ContainerInfo, len(containers))
 	for name, cont := range containers {
-		inf, err := m.containerDataToContainerInfo(cont, query)
+		inf, err := m.containerDataToContainerInfo(cont, query, v2.ResolutionRaw)
 		if err != nil {
 			// Ignore the error because of race condition and return best-effort result.
 			if err == memory.ErrDataNotFound {
@@ -617,29 +992,31 @@ func (m *manager) AllDockerContainers(query *info.ContainerInfoRequest) (map[str
 }
 
 func (m *manager) getDockerContainer(containerName string) (*containerData, error) {
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-
 	// Check for the container in the Docker container namespace.
-	cont, ok := m.containers[namespacedContainerName{
+	cont, ok := m.containers.get(namespacedContainerName{
 		Namespace: docker.DockerNamespace,
 		Name:      containerName,
-	}]
+	})
 
 	// Look for container by short prefix name if no exact match found.
 	if !ok {
-		for contName, c := range m.containers {
+		unique := true
+		m.containers.forEach(func(contName namespacedContainerName, c *containerData) bool {
 			if contName.Namespace == docker.DockerNamespace && strings.HasPrefix(contName.Name, containerName) {
-				if cont == nil {
-					cont = c
-				} else {
-					return nil, fmt.Errorf("unable to find container. Container %q is not unique", containerName)
+				if cont != nil {
+					unique = false
+					return false
 				}
+				cont = c
 			}
+			return true
+		})
+		if !unique {
+			return nil, fmt.Errorf("unable to find container. Container %q is not unique", containerName)
 		}
 
 		if cont == nil {
-			return nil, fmt.Errorf("unable to find Docker container %q", containerName)
+			return nil, fmt.Errorf("unable to find Docker container %q: %w", containerName, ErrUnknownContainer)
 		}
 	}
 
@@ -651,8 +1028,9 @@ func (m *manager) DockerContainer(containerName string, query *info.ContainerInf
 	if err != nil {
 		return info.ContainerInfo{}, err
 	}
+	m.updateStatsOnDemand(map[string]*containerData{container.info.Name: container})
 
-	inf, err := m.containerDataToContainerInfo(container, query)
+	inf, err := m.containerDataToContainerInfo(container, query, v2.ResolutionRaw)
 	if err != nil {
 		return info.ContainerInfo{}, err
 	}
@@ -667,7 +1045,7 @@ func (m *manager) containerDataSliceToContainerInfoSlice(containers []*container
 	// Get the info for each container.
 	output := make([]*info.ContainerInfo, 0, len(containers))
 	for i := range containers {
-		cinfo, err := m.containerDataToContainerInfo(containers[i], query)
+		cinfo, err := m.containerDataToContainerInfo(containers[i], query, v2.ResolutionRaw)
 		if err != nil {
 			// Skip containers with errors, we try to degrade gracefully.
 			klog.V(4).Infof("convert container data to container info failed with error %s", err.Error())
@@ -680,6 +1058,9 @@ func (m *manager) containerDataSliceToContainerInfoSlice(containers []*container
 }
 
 func (m *manager) GetRequestedContainersInfo(containerName string, options v2.RequestOptions) (map[string]*info.ContainerInfo, error) {
+	if err := m.checkResolution(options.Resolution); err != nil {
+		return nil, err
+	}
 	containers, err := m.getRequestedContainers(containerName, options)
 	if err != nil {
 		return nil, err
@@ -690,7 +1071,7 @@ func (m *manager) GetRequestedContainersInfo(containerName string, options v2.Re
 		NumStats: options.Count,
 	}
 	for name, data := range containers {
-		info, err := m.containerDataToContainerInfo(data, &query)
+		info, err := m.containerDataToContainerInfo(data, &query, options.Resolution)
 		if err != nil {
 			errs.append(name, "containerDataToContainerInfo", err)
 		}
@@ -712,7 +1093,7 @@ func (m *manager) getRequestedContainers(containerName string, options v2.Reques
 		} else {
 			containersMap = m.getSubcontainers(containerName)
 			if len(containersMap) == 0 {
-				return containersMap, fmt.Errorf("unknown container: %q", containerName)
+				return containersMap, fmt.Errorf("%w: %q", ErrUnknownContainer, containerName)
 			}
 		}
 	case v2.TypeDocker:
@@ -732,21 +1113,43 @@ func (m *manager) getRequestedContainers(containerName string, options v2.Reques
 	default:
 		return containersMap, fmt.Errorf("invalid request type %q", options.IdType)
 	}
-	if options.MaxAge != nil {
-		// update stats for all containers in containersMap
-		var waitGroup sync.WaitGroup
-		waitGroup.Add(len(containersMap))
-		for _, container := range containersMap {
-			go func(cont *containerData) {
-				cont.OnDemandHousekeeping(*options.MaxAge)
-				waitGroup.Done()
-			}(container)
+	if options.Filter != nil {
+		for name, cont := range containersMap {
+			if !cont.matches(options.Filter) {
+				delete(containersMap, name)
+			}
 		}
-		waitGroup.Wait()
+	}
+	if options.MaxAge != nil {
+		housekeepContainers(containersMap, *options.MaxAge)
+	} else {
+		m.updateStatsOnDemand(containersMap)
 	}
 	return containersMap, nil
 }
 
+// updateStatsOnDemand collects the stats of containers which are older than
+// the on demand stats TTL, when stats are only collected on demand.
+func (m *manager) updateStatsOnDemand(containers map[string]*containerData) {
+	if m.options.OnDemandHousekeeping {
+		housekeepContainers(containers, m.options.OnDemandStatsTTL)
+	}
+}
+
+// housekeepContainers updates the stats older than maxAge of all containers
+// and waits for the updates to complete.
+func housekeepContainers(containers map[string]*containerData, maxAge time.Duration) {
+	var waitGroup sync.WaitGroup
+	waitGroup.Add(len(containers))
+	for _, container := range containers {
+		go func(cont *containerData) {
+			cont.OnDemandHousekeeping(maxAge)
+			waitGroup.Done()
+		}(container)
+	}
+	waitGroup.Wait()
+}
+
 func (m *manager) GetDirFsInfo(dir string) (v2.FsInfo, error) {
 	device, err := m.fsInfo.GetDirFsDevice(dir)
 	if err != nil {
@@ -825,14 +1228,11 @@ func (m *manager) GetVersionInfo() (*info.VersionInfo, error) {
 }
 
 func (m *manager) Exists(containerName string) bool {
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-
 	namespacedName := namespacedContainerName{
 		Name: containerName,
 	}
 
-	_, ok := m.containers[namespacedName]
+	_, ok := m.containers.get(namespacedName)
 	return ok
 }
 
@@ -859,6 +1259,126 @@ func (m *manager) GetProcessList(containerName string, options v2.RequestOptions
 	return ps, nil
 }
 
+func (m *manager) GetStorageBreakdown(containerName string, depth int, options v2.RequestOptions) (v2.StorageBreakdown, error) {
+	// Only support a single container, the breakdown does not need updated stats.
+	options.Recursive = false
+	options.MaxAge = nil
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return v2.StorageBreakdown{}, err
+	}
+	if len(conts) != 1 {
+		return v2.StorageBreakdown{}, fmt.Errorf("Expected the request to match only one container")
+	}
+	for name, cont := range conts {
+		storageDirHandler, ok := cont.handler.(container.StorageDirHandler)
+		if !ok || storageDirHandler.GetRootfsStorageDir() == "" {
+			return v2.StorageBreakdown{}, fmt.Errorf("container %q has no writable layer", name)
+		}
+		usage, truncated, err := fs.GetDirUsageBreakdown(storageDirHandler.GetRootfsStorageDir(), depth, fs.BreakdownLimits{
+			MaxFiles:       m.options.StorageBreakdownMaxFiles,
+			FilesPerSecond: m.options.StorageBreakdownFilesPerSecond,
+		})
+		if err != nil {
+			return v2.StorageBreakdown{}, err
+		}
+		return v2.StorageBreakdown{
+			Timestamp: time.Now(),
+			Usage:     dirUsageToV2(usage),
+			Truncated: truncated,
+		}, nil
+	}
+	return v2.StorageBreakdown{}, nil
+}
+
+func dirUsageToV2(usage fs.DirUsage) v2.DirUsage {
+	result := v2.DirUsage{
+		Path:   "/" + usage.Path,
+		Bytes:  usage.Usage.Bytes,
+		Inodes: usage.Usage.Inodes,
+	}
+	for _, child := range usage.Children {
+		result.Children = append(result.Children, dirUsageToV2(child))
+	}
+	return result
+}
+
+// getCollectorContainer returns the single container whose collectors are
+// requested.
+func (m *manager) getCollectorContainer(containerName string, options v2.RequestOptions) (*containerData, error) {
+	options.Recursive = false
+	options.MaxAge = nil
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(conts) != 1 {
+		return nil, fmt.Errorf("Expected the request to match only one container")
+	}
+	for _, cont := range conts {
+		return cont, nil
+	}
+	return nil, nil
+}
+
+func (m *manager) InternalMetrics() []prometheus.Collector {
+	return m.internalMetrics
+}
+
+func (m *manager) GetStatus() v2.Status {
+	return v2.Status{Degradations: m.degradations.list()}
+}
+
+func (m *manager) GetCollectors(containerName string, options v2.RequestOptions) ([]string, error) {
+	cont, err := m.getCollectorContainer(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	return cont.collectorManager.CollectorNames(), nil
+}
+
+func (m *manager) AddCollector(containerName, collectorName string, config []byte, dryRun bool, options v2.RequestOptions) ([]info.MetricSpec, error) {
+	if !m.options.EnableCollectorAPI {
+		return nil, fmt.Errorf("%w: cannot add collector %q", ErrCollectorAPIDisabled, collectorName)
+	}
+	cont, err := m.getCollectorContainer(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	newCollector, err := collector.NewValidatedCollector(collectorName, config, m.options.ApplicationMetricsCountLimit, cont.handler, m.options.CollectorHTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	m.collectorsLock.Lock()
+	defer m.collectorsLock.Unlock()
+	for _, name := range cont.collectorManager.CollectorNames() {
+		if name == collectorName {
+			return nil, fmt.Errorf("%w: %q in container %q", ErrCollectorExists, collectorName, cont.info.Name)
+		}
+	}
+	specs := newCollector.GetSpec()
+	if dryRun {
+		return specs, nil
+	}
+	klog.V(2).Infof("Adding collector %q to container %q", collectorName, cont.info.Name)
+	return specs, cont.collectorManager.RegisterCollector(newCollector)
+}
+
+func (m *manager) RemoveCollector(containerName, collectorName string, options v2.RequestOptions) error {
+	if !m.options.EnableCollectorAPI {
+		return fmt.Errorf("%w: cannot remove collector %q", ErrCollectorAPIDisabled, collectorName)
+	}
+	cont, err := m.getCollectorContainer(containerName, options)
+	if err != nil {
+		return err
+	}
+	m.collectorsLock.Lock()
+	defer m.collectorsLock.Unlock()
+	klog.V(2).Infof("Removing collector %q from container %q", collectorName, cont.info.Name)
+	return cont.collectorManager.UnregisterCollector(collectorName)
+}
+
 func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *containerData) error {
 	for k, v := range collectorConfigs {
 		configFile, err := cont.ReadFile(v, m.inHostNamespace)
@@ -867,8 +1387,8 @@ func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *c
 		}
 		klog.V(4).Infof("Got config from %q: %q", v, configFile)
 
-		if strings.HasPrefix(k, "prometheus") || strings.HasPrefix(k, "Prometheus") {
-			newCollector, err := collector.NewPrometheusCollector(k, configFile, *applicationMetricsCountLimit, cont.handler, m.collectorHTTPClient)
+		if collector.IsPrometheusConfig(k) {
+			newCollector, err := collector.NewPrometheusCollector(k, configFile, m.options.ApplicationMetricsCountLimit, cont.handler, m.options.CollectorHTTPClient)
 			if err != nil {
 				return fmt.Errorf("failed to create collector for container %q, config %q: %v", cont.info.Name, k, err)
 			}
@@ -877,7 +1397,7 @@ func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *c
 				return fmt.Errorf("failed to register collector for container %q, config %q: %v", cont.info.Name, k, err)
 			}
 		} else {
-			newCollector, err := collector.NewCollector(k, configFile, *applicationMetricsCountLimit, cont.handler, m.collectorHTTPClient)
+			newCollector, err := collector.NewCollector(k, configFile, m.options.ApplicationMetricsCountLimit, cont.handler, m.options.CollectorHTTPClient)
 			if err != nil {
 				return fmt.Errorf("failed to create collector for container %q, config %q: %v", cont.info.Name, k, err)
 			}
@@ -890,6 +1410,25 @@ func (m *manager) registerCollectors(collectorConfigs map[string]string, cont *c
 	return nil
 }
 
+// registerDiscoveredCollector scrapes the Prometheus endpoint announced by
+// the labels of the container, unless a config file already describes one.
+func (m *manager) registerDiscoveredCollector(labels map[string]string, collectorConfigs map[string]string, cont *containerData) error {
+	for k := range collectorConfigs {
+		if collector.IsPrometheusConfig(k) {
+			return nil
+		}
+	}
+	config, ok, err := collector.GetDiscoveredPrometheusConfig(labels)
+	if err != nil || !ok {
+		return err
+	}
+	newCollector, err := collector.NewPrometheusCollectorFromConfig("prometheus", config, m.options.ApplicationMetricsCountLimit, cont.handler, m.options.CollectorHTTPClient)
+	if err != nil {
+		return fmt.Errorf("failed to create discovered collector for container %q: %v", cont.info.Name, err)
+	}
+	return cont.collectorManager.RegisterCollector(newCollector)
+}
+
 // Create a container.
 func (m *manager) createContainer(containerName string, watchSource watcher.ContainerWatchSource) error {
 	m.containersLock.Lock()
@@ -904,11 +1443,22 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 	}
 
 	// Check that the container didn't already exist.
-	if _, ok := m.containers[namespacedName]; ok {
+	if _, ok := m.containers.get(namespacedName); ok {
+		return nil
+	}
+	// The cgroups monitored on request, and the nested cgroups of the
+	// containers opted in, are not filtered.
+	nested := watchSource == watcher.Raw && m.hasNestedParent(containerName)
+	filtered := watchSource != watcher.AdHoc && !nested
+	if filtered && !m.containerFilter.includesName(containerName) {
+		klog.V(4).Infof("ignoring container %q filtered out", containerName)
 		return nil
 	}
 
 	handler, accept, err := container.NewContainerHandler(containerName, watchSource, m.inHostNamespace)
+	if err == nil && !accept && nested {
+		handler, accept, err = container.NewContainerHandler(containerName, watcher.AdHoc, m.inHostNamespace)
+	}
 	if err != nil {
 		return err
 	}
@@ -917,16 +1467,32 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 		klog.V(4).Infof("ignoring container %q", containerName)
 		return nil
 	}
+	if filtered && m.containerFilter != nil && m.containerFilter.filtersLabels() {
+		spec, err := handler.GetSpec()
+		if err != nil {
+			handler.Cleanup()
+			return err
+		}
+		if !m.containerFilter.includesLabels(containerName, spec.Labels) {
+			handler.Cleanup()
+			klog.V(4).Infof("ignoring container %q filtered out by its labels", containerName)
+			return nil
+		}
+	}
 	collectorManager, err := collector.NewCollectorManager()
 	if err != nil {
 		return err
 	}
 
-	logUsage := *logCadvisorUsage && containerName == m.cadvisorContainer
-	cont, err := newContainerData(containerName, m.memoryCache, handler, logUsage, collectorManager, m.maxHousekeepingInterval, m.allowDynamicHousekeeping, clock.RealClock{})
+	logUsage := m.options.LogCadvisorUsage && containerName == m.cadvisorContainer
+	cont, err := newContainerData(containerName, m.memoryCache, handler, logUsage, collectorManager, m.options, clock.RealClock{})
 	if err != nil {
 		return err
 	}
+	cont.enrichers = m.enrichers
+	cont.onDemand = m.options.OnDemandHousekeeping
+	cont.statsWatchers = m.statsWatchers
+	cont.estimateEnergy = m.options.IncludedMetrics.Has(container.PowerMetrics) && containerName != "/"
 
 	if cgroups.IsCgroup2UnifiedMode() {
 		perfCgroupPath := path.Join(fs2.UnifiedMountpoint, containerName)
@@ -934,6 +1500,7 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 		if err != nil {
 			klog.Errorf("Perf event metrics will not be available for container %q: %v", containerName, err)
 		}
+		m.degradations.update(perfCollector, containerName, err)
 	} else {
 		devicesCgroupPath, err := handler.GetCgroupPath("devices")
 		if err != nil {
@@ -942,7 +1509,11 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 			cont.nvidiaCollector, err = m.nvidiaManager.GetCollector(devicesCgroupPath)
 			if err != nil {
 				klog.V(4).Infof("GPU metrics may be unavailable/incomplete for container %s: %s", cont.info.Name, err)
+			} else {
+				// NVML is initialized now if it failed at startup.
+				m.degradations.update(nvidiaCollector, "", nil)
 			}
+			m.degradations.update(nvidiaCollector, containerName, err)
 		}
 		perfCgroupPath, err := handler.GetCgroupPath("perf_event")
 		if err != nil {
@@ -952,10 +1523,11 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 			if err != nil {
 				klog.Errorf("Perf event metrics will not be available for container %q: %v", containerName, err)
 			}
+			m.degradations.update(perfCollector, containerName, err)
 		}
 	}
 
-	if m.includedMetrics.Has(container.ResctrlMetrics) {
+	if m.options.IncludedMetrics.Has(container.ResctrlMetrics) {
 		resctrlPath, err := intelrdt.GetIntelRdtPath(containerName)
 		if err != nil {
 			klog.V(4).Infof("Error getting resctrl path: %q", err)
@@ -964,24 +1536,46 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 			if err != nil {
 				klog.V(4).Infof("resctrl metrics will not be available for container %s: %s", cont.info.Name, err)
 			}
+			m.degradations.update(resctrlCollector, containerName, err)
 		}
 	}
 
+	cont.pluginCollector = m.pluginManager.GetCollector(cont.info.ContainerReference, cont.info.Spec.Labels)
+
+	cont.perfCollector = stats.NewIntervalCollector(cont.perfCollector, m.options.PerfInterval, func(dst, src *info.ContainerStats) {
+		dst.PerfStats = src.PerfStats
+		dst.PerfUncoreStats = src.PerfUncoreStats
+	}, clock.RealClock{})
+	cont.resctrlCollector = stats.NewIntervalCollector(cont.resctrlCollector, m.options.ResctrlInterval, func(dst, src *info.ContainerStats) {
+		dst.Resctrl = src.Resctrl
+	}, clock.RealClock{})
+
 	// Add collectors
 	labels := handler.GetContainerLabels()
+	cont.nestedCgroups = nested || m.reportsNestedCgroups(containerName, labels)
 	collectorConfigs := collector.GetCollectorConfigs(labels)
 	err = m.registerCollectors(collectorConfigs, cont)
 	if err != nil {
 		klog.Warningf("Failed to register collectors for %q: %v", containerName, err)
 	}
+	err = m.registerDiscoveredCollector(labels, collectorConfigs, cont)
+	if err != nil {
+		klog.Warningf("Failed to register discovered collector for %q: %v", containerName, err)
+	}
+	if m.statsdListener != nil {
+		err = cont.collectorManager.RegisterCollector(m.statsdListener.Register(containerName, handler.GetContainerIPAddress()))
+		if err != nil {
+			klog.Warningf("Failed to register statsd collector for %q: %v", containerName, err)
+		}
+	}
 
 	// Add the container name and all its aliases. The aliases must be within the namespace of the factory.
-	m.containers[namespacedName] = cont
+	m.containers.set(namespacedName, cont)
 	for _, alias := range cont.info.Aliases {
-		m.containers[namespacedContainerName{
+		m.containers.set(namespacedContainerName{
 			Namespace: cont.info.Namespace,
 			Name:      alias,
-		}] = cont
+		}, cont)
 	}
 
 	klog.V(3).Infof("Added container: %q (aliases: %v, namespace: %q)", containerName, cont.info.Aliases, cont.info.Namespace)
@@ -1006,6 +1600,28 @@ func (m *manager) createContainerLocked(containerName string, watchSource watche
 		return err
 	}
 
+	if contSpec.RestoredFrom != "" {
+		// Counters start over in a restored container, link it to the
+		// checkpointed one.
+		continuityID := m.restoreLineage.restored(containerID(contRef), contSpec.RestoredFrom, time.Now())
+		cont.setContinuityID(continuityID)
+		klog.V(3).Infof("Container %q was restored from %q (continuity ID %q)", contRef.Name, contSpec.RestoredFrom, continuityID)
+		err = m.eventHandler.AddEvent(&info.Event{
+			ContainerName: contRef.Name,
+			Timestamp:     contSpec.CreationTime,
+			EventType:     info.EventContainerRestored,
+			EventData: info.EventData{
+				ContainerRestored: &info.ContainerRestoredEventData{
+					RestoredFrom: contSpec.RestoredFrom,
+					ContinuityId: continuityID,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// Start the container's housekeeping.
 	return cont.Start()
 }
@@ -1021,7 +1637,7 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 	namespacedName := namespacedContainerName{
 		Name: containerName,
 	}
-	cont, ok := m.containers[namespacedName]
+	cont, ok := m.containers.get(namespacedName)
 	if !ok {
 		// Already destroyed, done.
 		return nil
@@ -1033,10 +1649,19 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 		return err
 	}
 
+	if m.statsdListener != nil {
+		m.statsdListener.Unregister(containerName)
+	}
+	m.degradations.removeContainer(containerName)
+	if w, ok := m.adHocWatches[containerName]; ok {
+		w.timer.Stop()
+		delete(m.adHocWatches, containerName)
+	}
+
 	// Remove the container from our records (and all its aliases).
-	delete(m.containers, namespacedName)
+	m.containers.delete(namespacedName)
 	for _, alias := range cont.info.Aliases {
-		delete(m.containers, namespacedContainerName{
+		m.containers.delete(namespacedContainerName{
 			Namespace: cont.info.Namespace,
 			Name:      alias,
 		})
@@ -1047,6 +1672,7 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 	if err != nil {
 		return err
 	}
+	m.restoreLineage.deleted(containerID(contRef), time.Now())
 
 	newEvent := &info.Event{
 		ContainerName: contRef.Name,
@@ -1063,11 +1689,9 @@ func (m *manager) destroyContainerLocked(containerName string) error {
 // Detect all containers that have been added or deleted from the specified container.
 func (m *manager) getContainersDiff(containerName string) (added []info.ContainerReference, removed []info.ContainerReference, err error) {
 	// Get all subcontainers recursively.
-	m.containersLock.RLock()
-	cont, ok := m.containers[namespacedContainerName{
+	cont, ok := m.containers.get(namespacedContainerName{
 		Name: containerName,
-	}]
-	m.containersLock.RUnlock()
+	})
 	if !ok {
 		return nil, nil, fmt.Errorf("failed to find container %q while checking for new containers", containerName)
 	}
@@ -1083,19 +1707,20 @@ func (m *manager) getContainersDiff(containerName string) (added []info.Containe
 
 	// Determine which were added and which were removed.
 	allContainersSet := make(map[string]*containerData)
-	for name, d := range m.containers {
+	m.containers.forEach(func(name namespacedContainerName, d *containerData) bool {
 		// Only add the canonical name.
 		if d.info.Name == name.Name {
 			allContainersSet[name.Name] = d
 		}
-	}
+		return true
+	})
 
 	// Added containers
 	for _, c := range allContainers {
 		delete(allContainersSet, c.Name)
-		_, ok := m.containers[namespacedContainerName{
+		_, ok := m.containers.get(namespacedContainerName{
 			Name: c.Name,
-		}]
+		})
 		if !ok {
 			added = append(added, c)
 		}
@@ -1115,6 +1740,19 @@ func (m *manager) detectSubcontainers(containerName string) error {
 	if err != nil {
 		return err
 	}
+	// Parents are added before their nested cgroups.
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].Name < added[j].Name
+	})
+	if containerName == "/" {
+		// The containers filtered out are never added, so those which still
+		// exist are listed as added.
+		existing := make(map[string]bool, len(added))
+		for _, cont := range added {
+			existing[cont.Name] = true
+		}
+		m.containerFilter.retain(existing)
+	}
 
 	// Add the new containers.
 	for _, cont := range added {
@@ -1160,21 +1798,11 @@ func (m *manager) watchForNewContainers(quit chan error) error {
 
 	// Listen to events from the container handler.
 	go func() {
+		batch := make([]watcher.ContainerEvent, 0, maxWatchEventBatch)
 		for {
 			select {
 			case event := <-m.eventsChannel:
-				switch {
-				case event.EventType == watcher.ContainerAdd:
-					switch event.WatchSource {
-					default:
-						err = m.createContainer(event.Name, event.WatchSource)
-					}
-				case event.EventType == watcher.ContainerDelete:
-					err = m.destroyContainer(event.Name)
-				}
-				if err != nil {
-					klog.Warningf("Failed to process watch event %+v: %v", event, err)
-				}
+				batch = m.processWatchEvents(append(batch[:0], event))
 			case <-quit:
 				var errs partialFailure
 
@@ -1199,6 +1827,43 @@ func (m *manager) watchForNewContainers(quit chan error) error {
 	return nil
 }
 
+// Maximum number of pending watch events processed under one acquisition of
+// containersLock.
+const maxWatchEventBatch = 64
+
+// processWatchEvents processes the given watch events along with the ones
+// pending in the events channel, up to maxWatchEventBatch, so that a burst of
+// containers being created and destroyed takes containersLock once instead of
+// once per event. The events are processed in order. It returns the batch for
+// reuse.
+func (m *manager) processWatchEvents(batch []watcher.ContainerEvent) []watcher.ContainerEvent {
+drain:
+	for len(batch) < maxWatchEventBatch {
+		select {
+		case event := <-m.eventsChannel:
+			batch = append(batch, event)
+		default:
+			break drain
+		}
+	}
+
+	m.containersLock.Lock()
+	defer m.containersLock.Unlock()
+	for _, event := range batch {
+		var err error
+		switch event.EventType {
+		case watcher.ContainerAdd:
+			err = m.createContainerLocked(event.Name, event.WatchSource)
+		case watcher.ContainerDelete:
+			err = m.destroyContainerLocked(event.Name)
+		}
+		if err != nil {
+			klog.Warningf("Failed to process watch event %+v: %v", event, err)
+		}
+	}
+	return batch
+}
+
 func (m *manager) watchForNewOoms() error {
 	klog.V(2).Infof("Started watching for new ooms in manager")
 	outStream := make(chan *oomparser.OomInstance, 10)
@@ -1257,53 +1922,6 @@ func (m *manager) CloseEventChannel(watchID int) {
 	m.eventHandler.StopWatch(watchID)
 }
 
-// Parses the events StoragePolicy from the flags.
-func parseEventsStoragePolicy() events.StoragePolicy {
-	policy := events.DefaultStoragePolicy()
-
-	// Parse max age.
-	parts := strings.Split(*eventStorageAgeLimit, ",")
-	for _, part := range parts {
-		items := strings.Split(part, "=")
-		if len(items) != 2 {
-			klog.Warningf("Unknown event storage policy %q when parsing max age", part)
-			continue
-		}
-		dur, err := time.ParseDuration(items[1])
-		if err != nil {
-			klog.Warningf("Unable to parse event max age duration %q: %v", items[1], err)
-			continue
-		}
-		if items[0] == "default" {
-			policy.DefaultMaxAge = dur
-			continue
-		}
-		policy.PerTypeMaxAge[info.EventType(items[0])] = dur
-	}
-
-	// Parse max number.
-	parts = strings.Split(*eventStorageEventLimit, ",")
-	for _, part := range parts {
-		items := strings.Split(part, "=")
-		if len(items) != 2 {
-			klog.Warningf("Unknown event storage policy %q when parsing max event limit", part)
-			continue
-		}
-		val, err := strconv.Atoi(items[1])
-		if err != nil {
-			klog.Warningf("Unable to parse integer from %q: %v", items[1], err)
-			continue
-		}
-		if items[0] == "default" {
-			policy.DefaultMaxNumEvents = val
-			continue
-		}
-		policy.PerTypeMaxNumEvents[info.EventType(items[0])] = val
-	}
-
-	return policy
-}
-
 func (m *manager) DockerImages() ([]info.DockerImage, error) {
 	return docker.Images()
 }
@@ -1316,16 +1934,11 @@ func (m *manager) DebugInfo() map[string][]string {
 	debugInfo := container.DebugInfo()
 
 	// Get unique containers.
-	var conts map[*containerData]struct{}
-	func() {
-		m.containersLock.RLock()
-		defer m.containersLock.RUnlock()
-
-		conts = make(map[*containerData]struct{}, len(m.containers))
-		for _, c := range m.containers {
-			conts[c] = struct{}{}
-		}
-	}()
+	conts := make(map[*containerData]struct{}, m.containers.len())
+	m.containers.forEach(func(_ namespacedContainerName, c *containerData) bool {
+		conts[c] = struct{}{}
+		return true
+	})
 
 	// List containers.
 	lines := make([]string, 0, len(conts))
@@ -1388,14 +2001,28 @@ func getVersionInfo() (*info.VersionInfo, error) {
 }
 
 // Helper for accumulating partial failures.
-type partialFailure []string
+type partialFailure []error
 
 func (f *partialFailure) append(id, operation string, err error) {
-	*f = append(*f, fmt.Sprintf("[%q: %s: %s]", id, operation, err))
+	*f = append(*f, fmt.Errorf("[%q: %s: %w]", id, operation, err))
 }
 
 func (f partialFailure) Error() string {
-	return fmt.Sprintf("partial failures: %s", strings.Join(f, ", "))
+	failures := make([]string, len(f))
+	for i, err := range f {
+		failures[i] = err.Error()
+	}
+	return fmt.Sprintf("partial failures: %s", strings.Join(failures, ", "))
+}
+
+// Is reports whether any of the failures matches target.
+func (f partialFailure) Is(target error) bool {
+	for _, err := range f {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
 }
 
 func (f partialFailure) OrNil() error {
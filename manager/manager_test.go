@@ -34,6 +34,7 @@ import (
 	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	clock "k8s.io/utils/clock/testing"
 
 	// install all the container runtimes included in the library version for testing.
@@ -55,9 +56,10 @@ func createManagerAndAddContainers(
 ) *manager {
 	container.ClearContainerHandlerFactories()
 	mif := &manager{
-		containers:   make(map[namespacedContainerName]*containerData),
+		containers:   newContainerMap(),
 		quitChannels: make([]chan error, 0, 2),
 		memoryCache:  memoryCache,
+		options:      DefaultOptions(),
 	}
 	for _, name := range containers {
 		mockHandler := containertest.NewMockContainerHandler(name)
@@ -66,19 +68,19 @@ func createManagerAndAddContainers(
 			spec,
 			nil,
 		).Once()
-		cont, err := newContainerData(name, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, 60*time.Second, true, clock.NewFakeClock(time.Now()))
+		cont, err := newContainerData(name, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, DefaultOptions(), clock.NewFakeClock(time.Now()))
 		if err != nil {
 			t.Fatal(err)
 		}
-		mif.containers[namespacedContainerName{
+		mif.containers.set(namespacedContainerName{
 			Name: name,
-		}] = cont
+		}, cont)
 		// Add Docker containers under their namespace.
 		if strings.HasPrefix(name, "/docker") {
-			mif.containers[namespacedContainerName{
+			mif.containers.set(namespacedContainerName{
 				Namespace: docker.DockerNamespace,
 				Name:      strings.TrimPrefix(name, "/docker/"),
-			}] = cont
+			}, cont)
 		}
 		f(mockHandler)
 	}
@@ -94,9 +96,10 @@ func createManagerAndAddSubContainers(
 ) *manager {
 	container.ClearContainerHandlerFactories()
 	mif := &manager{
-		containers:   make(map[namespacedContainerName]*containerData),
+		containers:   newContainerMap(),
 		quitChannels: make([]chan error, 0, 2),
 		memoryCache:  memoryCache,
+		options:      DefaultOptions(),
 	}
 
 	subcontainers1 := []info.ContainerReference{
@@ -129,19 +132,19 @@ func createManagerAndAddSubContainers(
 			subcontainerList[idx],
 			nil,
 		)
-		cont, err := newContainerData(name, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, 60*time.Second, true, clock.NewFakeClock(time.Now()))
+		cont, err := newContainerData(name, memoryCache, mockHandler, false, &collector.GenericCollectorManager{}, DefaultOptions(), clock.NewFakeClock(time.Now()))
 		if err != nil {
 			t.Fatal(err)
 		}
-		mif.containers[namespacedContainerName{
+		mif.containers.set(namespacedContainerName{
 			Name: name,
-		}] = cont
+		}, cont)
 		// Add Docker containers under their namespace.
 		if strings.HasPrefix(name, "/docker") {
-			mif.containers[namespacedContainerName{
+			mif.containers.set(namespacedContainerName{
 				Namespace: docker.DockerNamespace,
 				Name:      strings.TrimPrefix(name, "/docker/"),
-			}] = cont
+			}, cont)
 		}
 		f(mockHandler)
 	}
@@ -355,6 +358,43 @@ func TestGetContainerInfoV2(t *testing.T) {
 	}
 }
 
+func TestGetRequestedContainersWithFilter(t *testing.T) {
+	containers := []string{
+		"/",
+		"/c1",
+		"/c2",
+		"/c3",
+	}
+	m := createManagerAndAddContainers(memory.New(time.Minute, nil), &fakesysfs.FakeSysFs{}, containers, func(h *containertest.MockContainerHandler) {}, t)
+	labels := map[string]map[string]string{
+		"/c1": {v2.PodNamespaceLabel: "default", "app": "web"},
+		"/c2": {v2.PodNamespaceLabel: "default", "app": "db"},
+		"/c3": {v2.PodNamespaceLabel: "kube-system", "app": "web"},
+	}
+	for name, l := range labels {
+		registeredContainer(m, name).info.Spec.Labels = l
+	}
+
+	names := func(filter *v2.ContainerFilter) []string {
+		conts, err := m.getRequestedContainers("/", v2.RequestOptions{IdType: v2.TypeName, Recursive: true, Filter: filter})
+		require.NoError(t, err)
+		var out []string
+		for name := range conts {
+			out = append(out, name)
+		}
+		return out
+	}
+
+	assert.ElementsMatch(t, containers, names(nil))
+	assert.ElementsMatch(t, []string{"/c1", "/c2"}, names(&v2.ContainerFilter{Namespace: "default"}))
+	assert.ElementsMatch(t, []string{"/c2"}, names(&v2.ContainerFilter{Container: "/c2"}))
+	assert.ElementsMatch(t, []string{"/c1"}, names(&v2.ContainerFilter{
+		Namespace: "default",
+		Selector:  []v2.LabelRequirement{{Key: "app", Operator: v2.LabelEquals, Value: "web"}},
+	}))
+	assert.Empty(t, names(&v2.ContainerFilter{Container: "/unknown"}))
+}
+
 func TestGetContainerInfoV2Failure(t *testing.T) {
 	successful := "/"
 	statless := "/c1"
@@ -386,7 +426,7 @@ func TestGetContainerInfoV2Failure(t *testing.T) {
 	assert.NoError(t, err) // Use up default GetSpec call, and replace below
 	handlerMap[failing].On("GetSpec").Return(info.ContainerSpec{}, mockErr)
 	handlerMap[failing].On("Exists").Return(true)
-	m.containers[namespacedContainerName{Name: failing}].infoLastUpdatedTime = time.Time{} // Force GetSpec.
+	registeredContainer(m, failing).infoLastUpdatedTime = time.Time{} // Force GetSpec.
 
 	infos, err := m.GetContainerInfoV2("/", options)
 	if err == nil {
@@ -533,3 +573,87 @@ func TestDockerContainersInfo(t *testing.T) {
 		t.Errorf("expected error %q but received %q", expectedError, err)
 	}
 }
+
+func TestDiffMachineInfo(t *testing.T) {
+	oldInfo := &info.MachineInfo{
+		NumCores:       4,
+		NumSockets:     1,
+		MemoryCapacity: 1024,
+		NetworkDevices: []info.NetInfo{{Name: "eth0"}},
+		DiskMap:        map[string]info.DiskInfo{"8:0": {Name: "sda"}},
+	}
+	newInfo := &info.MachineInfo{
+		NumCores:       8,
+		NumSockets:     1,
+		MemoryCapacity: 1024,
+		NetworkDevices: []info.NetInfo{{Name: "eth1"}, {Name: "eth0"}},
+		DiskMap:        map[string]info.DiskInfo{"8:0": {Name: "sda"}},
+	}
+
+	oldInfo.Topology = []info.Node{{Cores: []info.Core{{Threads: []int{0, 2}}, {Threads: []int{1, 3}}}}}
+	newInfo.Topology = []info.Node{{Cores: []info.Core{{Threads: []int{0}}, {Threads: []int{1, 3}}}}}
+
+	assert.Empty(t, diffMachineInfo(oldInfo, oldInfo))
+	assert.Equal(t, []info.MachineInfoChange{
+		{Field: "num_cores", Old: "4", New: "8"},
+		{Field: "online_cpus", Old: "0-3", New: "0-1,3"},
+		{Field: "network_devices", Old: "eth0", New: "eth0,eth1"},
+	}, diffMachineInfo(oldInfo, newInfo))
+}
+
+func TestDiffMachineInfoDiskSettings(t *testing.T) {
+	oldInfo := &info.MachineInfo{
+		DiskMap: map[string]info.DiskInfo{
+			"8:0":   {Name: "sda", Scheduler: "mq-deadline", Rotational: true, NrRequests: 64, QueueDepth: 32, WriteCache: "write back"},
+			"259:0": {Name: "nvme0n1", Scheduler: "none", NrRequests: 1023, Discard: true},
+			"253:0": {Name: "dm-0", Scheduler: "none", Slaves: []string{"sdc", "sdd"}},
+		},
+	}
+	newInfo := &info.MachineInfo{
+		DiskMap: map[string]info.DiskInfo{
+			"8:0":   {Name: "sda", Scheduler: "bfq", Rotational: true, NrRequests: 64, QueueDepth: 1, WriteCache: "write through"},
+			"259:0": {Name: "nvme0n1", Scheduler: "none", NrRequests: 1023, Discard: true},
+			"8:16":  {Name: "sdb", Scheduler: "bfq"},
+			"253:0": {Name: "dm-0", Scheduler: "none", Slaves: []string{"sdd"}},
+		},
+	}
+
+	assert.Equal(t, []info.MachineInfoChange{
+		{Field: "disk_map", Old: "dm-0,nvme0n1,sda", New: "dm-0,nvme0n1,sda,sdb"},
+		{Field: "disk_map.dm-0.slaves", Old: "sdc,sdd", New: "sdd"},
+		{Field: "disk_map.sda.scheduler", Old: "mq-deadline", New: "bfq"},
+		{Field: "disk_map.sda.queue_depth", Old: "32", New: "1"},
+		{Field: "disk_map.sda.write_cache", Old: "write back", New: "write through"},
+	}, diffMachineInfo(oldInfo, newInfo))
+}
+
+func TestDiffMdArrays(t *testing.T) {
+	oldArrays := []info.MdArrayStats{
+		{Name: "md0", Level: "raid5", State: "clean", Disks: 3, SyncAction: "idle"},
+		{Name: "md1", Level: "raid1", State: "clean", Disks: 2, SyncAction: "idle"},
+		{Name: "md2", Level: "raid0", State: "clean"},
+	}
+	newArrays := []info.MdArrayStats{
+		{Name: "md0", Level: "raid5", State: "active", Disks: 3, Degraded: 1, SyncAction: "recover",
+			Members: []info.MdMember{{Device: "sda1"}, {Device: "sdb1", Faulty: true}, {Device: "sdc1"}}},
+		{Name: "md1", Level: "raid1", State: "clean", Disks: 2, SyncAction: "idle"},
+		{Name: "md3", Level: "raid1", State: "clean", Disks: 2, SyncAction: "resync"},
+	}
+
+	assert.Equal(t, []*info.MdArrayChangedEventData{
+		{
+			Array: "md0",
+			Old:   &info.MdArrayState{State: "clean", SyncAction: "idle"},
+			New:   &info.MdArrayState{State: "active", Degraded: 1, FailedMembers: []string{"sdb1"}, SyncAction: "recover"},
+		},
+		{
+			Array: "md2",
+			Old:   &info.MdArrayState{State: "clean"},
+		},
+		{
+			Array: "md3",
+			New:   &info.MdArrayState{State: "clean", SyncAction: "resync"},
+		},
+	}, diffMdArrays(oldArrays, newArrays))
+	assert.Empty(t, diffMdArrays(newArrays, newArrays))
+}
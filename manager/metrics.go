@@ -0,0 +1,102 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collectors whose errors are counted by collectorErrors.
+const (
+	statsCollector       = "stats"
+	loadCollector        = "load"
+	applicationCollector = "application"
+	nvidiaCollector      = "nvidia"
+	perfCollector        = "perf"
+	resctrlCollector     = "resctrl"
+	pluginCollector      = "plugin"
+)
+
+// Reasons of the samples dropped, counted by droppedSamples.
+const (
+	// The stats of the container could not be read.
+	noStatsReason = "no_stats"
+	// The stats were read but could not be completed.
+	incompleteStatsReason = "incomplete_stats"
+	// The stats could not be added to the memory cache.
+	cacheErrorReason = "cache_error"
+)
+
+var (
+	housekeepingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "cadvisor_internal_housekeeping_duration_seconds",
+		Help:    "Duration of the housekeepings of all containers.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+	containerHousekeepingDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cadvisor_internal_container_housekeeping_duration_seconds",
+		Help: "Duration of the last housekeeping of a container.",
+	}, []string{"container"})
+	collectorErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cadvisor_internal_collector_errors_total",
+		Help: "Number of housekeepings during which a collector failed.",
+	}, []string{"collector"})
+	droppedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cadvisor_internal_dropped_samples_total",
+		Help: "Number of housekeepings whose stats were dropped.",
+	}, []string{"reason"})
+)
+
+func init() {
+	// Export the counters before any error.
+	for _, collector := range []string{statsCollector, loadCollector, applicationCollector, nvidiaCollector, perfCollector, resctrlCollector, pluginCollector} {
+		collectorErrors.WithLabelValues(collector)
+	}
+	for _, reason := range []string{noStatsReason, incompleteStatsReason, cacheErrorReason} {
+		droppedSamples.WithLabelValues(reason)
+	}
+}
+
+// newInternalMetrics returns the collectors of the metrics about the state
+// of m.
+func newInternalMetrics(m *manager) []prometheus.Collector {
+	return []prometheus.Collector{
+		housekeepingDuration,
+		containerHousekeepingDuration,
+		collectorErrors,
+		droppedSamples,
+		m.degradations,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "cadvisor_internal_watcher_queue_length",
+			Help: "Number of container creation and deletion events waiting to be processed.",
+		}, func() float64 {
+			return float64(len(m.eventsChannel))
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "cadvisor_internal_memory_cache_containers",
+			Help: "Number of containers in the memory cache.",
+		}, func() float64 {
+			containers, _ := m.memoryCache.Size()
+			return float64(containers)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "cadvisor_internal_memory_cache_stats",
+			Help: "Number of stats in the memory cache.",
+		}, func() float64 {
+			_, stats := m.memoryCache.Size()
+			return float64(stats)
+		}),
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"path"
+)
+
+// NestedCgroupsLabel is the container label opting a container in the
+// monitoring of its child cgroups when set to "true".
+const NestedCgroupsLabel = "io.cadvisor.nested_cgroups"
+
+// reportsNestedCgroups returns whether the child cgroups of the container
+// named name with labels are monitored.
+func (m *manager) reportsNestedCgroups(name string, labels map[string]string) bool {
+	if labels[NestedCgroupsLabel] == "true" {
+		return true
+	}
+	return m.nestedCgroupsContainers != nil && m.nestedCgroupsContainers.MatchString(name)
+}
+
+// hasNestedParent returns whether the closest monitored ancestor of the
+// cgroup named name has its child cgroups monitored. It must be called with
+// containersLock held.
+func (m *manager) hasNestedParent(name string) bool {
+	for dir := path.Dir(name); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		if cont, ok := m.containers.get(namespacedContainerName{Name: dir}); ok {
+			return cont.nestedCgroups
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cache/memory"
+	containertest "github.com/google/cadvisor/container/testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNestedCgroups(t *testing.T) {
+	m := createManagerAndAddContainers(memory.New(time.Minute, nil), nil, []string{"/", "/docker/a", "/docker/b", "/lxc/c"}, func(*containertest.MockContainerHandler) {}, t)
+	m.nestedCgroupsContainers = regexp.MustCompile("^/lxc/")
+
+	assert.True(t, m.reportsNestedCgroups("/docker/a", map[string]string{NestedCgroupsLabel: "true"}))
+	assert.False(t, m.reportsNestedCgroups("/docker/b", map[string]string{NestedCgroupsLabel: "false"}))
+	assert.True(t, m.reportsNestedCgroups("/lxc/c", nil))
+	registeredContainer(m, "/docker/a").nestedCgroups = true
+	registeredContainer(m, "/lxc/c").nestedCgroups = true
+
+	for name, nested := range map[string]bool{
+		"/docker/a/jvm":                 true,
+		"/docker/a/jvm/gc":              true,
+		"/docker/b/jvm":                 false,
+		"/lxc/c/system.slice/a.service": true,
+		"/docker/a":                     false,
+		"/system.slice":                 false,
+	} {
+		assert.Equal(t, nested, m.hasNestedParent(name), name)
+	}
+}
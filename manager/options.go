@@ -0,0 +1,179 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/google/cadvisor/container"
+	"github.com/google/cadvisor/container/common"
+	"github.com/google/cadvisor/events"
+	"github.com/google/cadvisor/machine"
+	"github.com/google/cadvisor/summary"
+)
+
+// Options configure a manager.
+type Options struct {
+	// Interval between the housekeepings of a container. When dynamic
+	// housekeeping is allowed, it is raised up to MaxHousekeepingInterval
+	// while the stats of the container don't change.
+	HousekeepingInterval     time.Duration
+	MaxHousekeepingInterval  time.Duration
+	AllowDynamicHousekeeping bool
+	// Whether container stats are only collected when they are requested,
+	// and the maximum age of the stats served without collecting them again.
+	OnDemandHousekeeping bool
+	OnDemandStatsTTL     time.Duration
+	// Interval between the global housekeepings, and between the updates of
+	// the machine info.
+	GlobalHousekeepingInterval time.Duration
+	UpdateMachineInfoInterval  time.Duration
+	// Intervals between the perf event and resctrl measurements of a
+	// container, 0 measures at every container housekeeping.
+	PerfInterval    time.Duration
+	ResctrlInterval time.Duration
+	// Path of the perf events configuration, empty if perf events are not
+	// measured.
+	PerfEventsFile string
+	// Metrics collected.
+	IncludedMetrics container.MetricSet
+	// Prefixes of the paths of the raw cgroups which are monitored even when
+	// the other container factories only monitor their own containers.
+	RawContainerCgroupPathPrefixWhiteList []string
+	// Whether the cpu load of the containers is read.
+	EnableLoadReader bool
+	// Whether the usage of the cAdvisor container is logged.
+	LogCadvisorUsage bool
+
+	// Client of the application metrics collectors.
+	CollectorHTTPClient *http.Client
+	// Max number of application metrics stored per container.
+	ApplicationMetricsCountLimit int
+	// Address of the statsd listener receiving application metrics,
+	// udp://<host>:<port> or unix://<path>, disabled if empty.
+	StatsdListenAddress string
+	// Whether application metrics collectors can be added to and removed
+	// from containers through the API.
+	EnableCollectorAPI bool
+
+	// Mount points of the host whose filesystems are tracked in the machine
+	// stats whatever their type.
+	ExtraFsMounts []string
+	// Interval between the disk usage measurements of the container
+	// filesystems.
+	FsUsageInterval time.Duration
+	// How the machine info is gathered.
+	MachineInfo machine.InfoOptions
+
+	// Max number of files visited, in total and per second, to break down
+	// the usage of the writable layer of a container.
+	StorageBreakdownMaxFiles       int
+	StorageBreakdownFilesPerSecond int
+
+	// Select the containers monitored, see newContainerFilter.
+	ContainerInclude       string
+	ContainerExclude       string
+	ContainerLabelSelector string
+	ContainerNamespaces    string
+	// Regular expression matching the names of the containers whose child
+	// cgroups are monitored as nested containers, none if empty.
+	NestedCgroupsContainers string
+
+	// Addresses of the plugins collecting container stats, unix://<path> or
+	// <host>:<port>, and max duration of the calls to the plugins.
+	StatsPlugins       []string
+	StatsPluginTimeout time.Duration
+
+	// Windows and quantiles of the summary API besides the default ones.
+	Summary summary.Config
+
+	// How long and how many events are kept, and where they are persisted,
+	// in memory only if nil.
+	EventStoragePolicy events.StoragePolicy
+	EventStore         events.Store
+
+	// Longest time a cgroup may be monitored on request before the request
+	// is renewed, and max number of cgroups monitored on request at once.
+	AdHocWatchMaxTTL time.Duration
+	AdHocWatchLimit  int
+}
+
+// DefaultOptions returns the options of the managers of cAdvisor started
+// without flags.
+func DefaultOptions() Options {
+	return Options{
+		HousekeepingInterval:                  time.Second,
+		MaxHousekeepingInterval:               60 * time.Second,
+		AllowDynamicHousekeeping:              true,
+		OnDemandStatsTTL:                      5 * time.Second,
+		GlobalHousekeepingInterval:            time.Minute,
+		UpdateMachineInfoInterval:             5 * time.Minute,
+		IncludedMetrics:                       container.AllMetrics.Difference(container.DefaultDisabledMetrics()),
+		RawContainerCgroupPathPrefixWhiteList: []string{""},
+		CollectorHTTPClient:                   http.DefaultClient,
+		FsUsageInterval:                       common.DefaultPeriod,
+		MachineInfo:                           machine.DefaultInfoOptions(),
+		ApplicationMetricsCountLimit:          100,
+		StorageBreakdownMaxFiles:              100000,
+		StorageBreakdownFilesPerSecond:        10000,
+		StatsPluginTimeout:                    time.Second,
+		EventStoragePolicy:                    events.DefaultStoragePolicy(),
+		AdHocWatchMaxTTL:                      24 * time.Hour,
+		AdHocWatchLimit:                       100,
+	}
+}
+
+// validate returns an error if the options can't configure a manager.
+func (o *Options) validate() error {
+	if o.HousekeepingInterval <= 0 {
+		return fmt.Errorf("the housekeeping interval must be positive, got %v", o.HousekeepingInterval)
+	}
+	if o.MaxHousekeepingInterval < o.HousekeepingInterval {
+		return fmt.Errorf("the max housekeeping interval %v is shorter than the housekeeping interval %v", o.MaxHousekeepingInterval, o.HousekeepingInterval)
+	}
+	if o.GlobalHousekeepingInterval <= 0 || o.UpdateMachineInfoInterval <= 0 {
+		return fmt.Errorf("the global housekeeping and machine info update intervals must be positive")
+	}
+	if o.FsUsageInterval <= 0 {
+		return fmt.Errorf("the filesystem usage interval must be positive, got %v", o.FsUsageInterval)
+	}
+	if o.MachineInfo.SectionTimeout <= 0 || o.MachineInfo.CloudMetadataTimeout <= 0 {
+		return fmt.Errorf("the machine info timeouts must be positive")
+	}
+	if len(o.StatsPlugins) > 0 && o.StatsPluginTimeout <= 0 {
+		return fmt.Errorf("the stats plugin timeout must be positive, got %v", o.StatsPluginTimeout)
+	}
+	if o.IncludedMetrics == nil {
+		return fmt.Errorf("no included metrics")
+	}
+	if o.CollectorHTTPClient == nil {
+		return fmt.Errorf("no collector HTTP client")
+	}
+	for _, mountpoint := range o.ExtraFsMounts {
+		if !filepath.IsAbs(mountpoint) {
+			return fmt.Errorf("extra filesystem mount point %q is not an absolute path", mountpoint)
+		}
+	}
+	if o.NestedCgroupsContainers != "" {
+		if _, err := regexp.Compile(o.NestedCgroupsContainers); err != nil {
+			return fmt.Errorf("invalid nested cgroups containers: %v", err)
+		}
+	}
+	return o.Summary.Validate()
+}
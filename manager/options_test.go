@@ -0,0 +1,51 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/container"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	options := DefaultOptions()
+	assert.NoError(t, options.validate())
+	assert.True(t, options.IncludedMetrics.Has(container.CpuUsageMetrics))
+	assert.False(t, options.IncludedMetrics.Has(container.ProcessMetrics))
+}
+
+func TestOptionsValidate(t *testing.T) {
+	for name, f := range map[string]func(*Options){
+		"no housekeeping interval":      func(o *Options) { o.HousekeepingInterval = 0 },
+		"short max housekeeping":        func(o *Options) { o.MaxHousekeepingInterval = time.Millisecond },
+		"no global housekeeping":        func(o *Options) { o.GlobalHousekeepingInterval = 0 },
+		"no machine info update":        func(o *Options) { o.UpdateMachineInfoInterval = -time.Second },
+		"no included metrics":           func(o *Options) { o.IncludedMetrics = nil },
+		"no collector client":           func(o *Options) { o.CollectorHTTPClient = nil },
+		"invalid nested cgroups regexp": func(o *Options) { o.NestedCgroupsContainers = "(" },
+		"invalid summary quantile":      func(o *Options) { o.Summary.Quantiles = []float64{2} },
+		"relative extra fs mount":       func(o *Options) { o.ExtraFsMounts = []string{"data"} },
+		"no fs usage interval":          func(o *Options) { o.FsUsageInterval = 0 },
+		"no machine info timeout":       func(o *Options) { o.MachineInfo.SectionTimeout = 0 },
+	} {
+		options := DefaultOptions()
+		f(&options)
+		assert.Error(t, options.validate(), name)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+)
+
+// queryMetrics are the metrics of the stats which can be queried, named
+// after the Prometheus metrics without their "container_" prefix.
+var queryMetrics = map[string]func(s *info.ContainerStats) float64{
+	"cpu_usage_seconds_total": func(s *info.ContainerStats) float64 {
+		return float64(s.Cpu.Usage.Total) / float64(time.Second)
+	},
+	"memory_usage_bytes": func(s *info.ContainerStats) float64 {
+		return float64(s.Memory.Usage)
+	},
+	"memory_working_set_bytes": func(s *info.ContainerStats) float64 {
+		return float64(s.Memory.WorkingSet)
+	},
+	"memory_rss": func(s *info.ContainerStats) float64 {
+		return float64(s.Memory.RSS)
+	},
+	"network_receive_bytes_total": func(s *info.ContainerStats) float64 {
+		var sum uint64
+		for _, i := range s.Network.Interfaces {
+			sum += i.RxBytes
+		}
+		return float64(sum)
+	},
+	"network_transmit_bytes_total": func(s *info.ContainerStats) float64 {
+		var sum uint64
+		for _, i := range s.Network.Interfaces {
+			sum += i.TxBytes
+		}
+		return float64(sum)
+	},
+	"fs_usage_bytes": func(s *info.ContainerStats) float64 {
+		var sum uint64
+		for _, fs := range s.Filesystem {
+			sum += fs.Usage
+		}
+		return float64(sum)
+	},
+	"processes": func(s *info.ContainerStats) float64 {
+		return float64(s.Processes.ProcessCount)
+	},
+}
+
+// queryGroup is the set of containers sharing the values of the labels
+// grouping them in a query.
+type queryGroup struct {
+	labels map[string]string
+	values []float64
+}
+
+func (m *manager) Query(containerName string, query v2.Query, options v2.RequestOptions) ([]v2.QueryResult, error) {
+	metric, ok := queryMetrics[query.Metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown metric %q", ErrInvalidQuery, query.Metric)
+	}
+	over := query.Over
+	switch over {
+	case "":
+		over = v2.QueryOverLast
+	case v2.QueryOverLast, v2.QueryOverAvg, v2.QueryOverMin, v2.QueryOverMax, v2.QueryOverDelta:
+	default:
+		return nil, fmt.Errorf("%w: unknown reduction over time %q", ErrInvalidQuery, query.Over)
+	}
+	op := query.Op
+	switch op {
+	case "":
+		op = v2.QueryOpSum
+	case v2.QueryOpSum, v2.QueryOpAvg, v2.QueryOpMax:
+	default:
+		return nil, fmt.Errorf("%w: unknown aggregation %q", ErrInvalidQuery, query.Op)
+	}
+	if query.Range < 0 || query.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative range or limit", ErrInvalidQuery)
+	}
+
+	// The subcontainers of the requested container are queried.
+	options.Recursive = true
+	conts, err := m.getRequestedContainers(containerName, options)
+	if err != nil {
+		return nil, err
+	}
+	var start time.Time
+	if query.Range > 0 {
+		start = time.Now().Add(-query.Range)
+	}
+	groups := make(map[string]*queryGroup)
+	for name, cont := range conts {
+		if options.IdType == v2.TypeName && name == containerName {
+			continue
+		}
+		stats, err := m.memoryCache.RecentStats(cont.info.Name, start, time.Time{}, -1)
+		if err != nil || len(stats) == 0 {
+			continue
+		}
+		labels := map[string]string{}
+		if len(query.By) == 0 {
+			labels[v2.QueryContainerLabel] = cont.info.Name
+		}
+		contLabels := cont.labels()
+		for _, l := range query.By {
+			labels[l] = contLabels[l]
+		}
+		key := groupKey(labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &queryGroup{labels: labels}
+			groups[key] = g
+		}
+		g.values = append(g.values, reduceOverTime(over, stats, metric))
+	}
+
+	results := make([]v2.QueryResult, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, v2.QueryResult{
+			Labels:     g.labels,
+			Value:      aggregate(op, g.values),
+			Containers: len(g.values),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Value != results[j].Value {
+			return results[i].Value > results[j].Value
+		}
+		return groupKey(results[i].Labels) < groupKey(results[j].Labels)
+	})
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+	return results, nil
+}
+
+// groupKey returns a string identifying the values of labels.
+func groupKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%q=%q,", k, labels[k])
+	}
+	return b.String()
+}
+
+// reduceOverTime reduces the metric of stats, sorted oldest first, to a
+// single value.
+func reduceOverTime(over string, stats []*info.ContainerStats, metric func(s *info.ContainerStats) float64) float64 {
+	last := metric(stats[len(stats)-1])
+	switch over {
+	case v2.QueryOverDelta:
+		return last - metric(stats[0])
+	case v2.QueryOverAvg, v2.QueryOverMin, v2.QueryOverMax:
+		values := make([]float64, len(stats))
+		for i, s := range stats {
+			values[i] = metric(s)
+		}
+		switch over {
+		case v2.QueryOverAvg:
+			return aggregate(v2.QueryOpAvg, values)
+		case v2.QueryOverMax:
+			return aggregate(v2.QueryOpMax, values)
+		}
+		min := values[0]
+		for _, v := range values[1:] {
+			if v < min {
+				min = v
+			}
+		}
+		return min
+	default:
+		return last
+	}
+}
+
+// aggregate aggregates values with the op of a query.
+func aggregate(op string, values []float64) float64 {
+	var sum float64
+	max := values[0]
+	for _, v := range values {
+		sum += v
+		if v > max {
+			max = v
+		}
+	}
+	switch op {
+	case v2.QueryOpAvg:
+		return sum / float64(len(values))
+	case v2.QueryOpMax:
+		return max
+	default:
+		return sum
+	}
+}
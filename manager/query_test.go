@@ -0,0 +1,91 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cache/memory"
+	containertest "github.com/google/cadvisor/container/testing"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	memoryCache := memory.New(time.Minute, nil)
+	names := []string{"/", "/a", "/b", "/c"}
+	m := createManagerAndAddContainers(memoryCache, nil, names, func(*containertest.MockContainerHandler) {}, t)
+	workingSets := map[string][]uint64{
+		"/":  {1000, 2000, 3000},
+		"/a": {100, 150, 200},
+		"/b": {300, 300, 300},
+		"/c": {10, 100, 400},
+	}
+	teams := map[string]string{"/a": "x", "/b": "x", "/c": "y"}
+	now := time.Now()
+	for _, name := range names {
+		registeredContainer(m, name).info.Spec.Labels = map[string]string{"team": teams[name]}
+		for i, ws := range workingSets[name] {
+			stats := &info.ContainerStats{Timestamp: now.Add(time.Duration(i-3) * time.Second)}
+			stats.Memory.WorkingSet = ws
+			require.NoError(t, memoryCache.AddStats(&info.ContainerInfo{ContainerReference: info.ContainerReference{Name: name}}, stats))
+		}
+	}
+	opts := v2.RequestOptions{IdType: v2.TypeName}
+
+	// Top 2 containers by memory growth.
+	results, err := m.Query("/", v2.Query{Metric: "memory_working_set_bytes", Over: v2.QueryOverDelta, Limit: 2}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []v2.QueryResult{
+		{Labels: map[string]string{v2.QueryContainerLabel: "/c"}, Value: 390, Containers: 1},
+		{Labels: map[string]string{v2.QueryContainerLabel: "/a"}, Value: 100, Containers: 1},
+	}, results)
+
+	results, err = m.Query("/", v2.Query{Metric: "memory_working_set_bytes", By: []string{"team"}}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []v2.QueryResult{
+		{Labels: map[string]string{"team": "x"}, Value: 500, Containers: 2},
+		{Labels: map[string]string{"team": "y"}, Value: 400, Containers: 1},
+	}, results)
+
+	results, err = m.Query("/", v2.Query{Metric: "memory_working_set_bytes", Over: v2.QueryOverAvg, Op: v2.QueryOpMax, By: []string{"team"}}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []v2.QueryResult{
+		{Labels: map[string]string{"team": "x"}, Value: 300, Containers: 2},
+		{Labels: map[string]string{"team": "y"}, Value: 170, Containers: 1},
+	}, results)
+
+	// Only the latest sample is in range.
+	results, err = m.Query("/", v2.Query{Metric: "memory_working_set_bytes", Over: v2.QueryOverMin, Range: 1500 * time.Millisecond, Limit: 1}, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []v2.QueryResult{
+		{Labels: map[string]string{v2.QueryContainerLabel: "/c"}, Value: 400, Containers: 1},
+	}, results)
+
+	for _, query := range []v2.Query{
+		{Metric: "memory"},
+		{Metric: "memory_rss", Over: "median"},
+		{Metric: "memory_rss", Op: "min"},
+		{Metric: "memory_rss", Limit: -1},
+	} {
+		_, err := m.Query("/", query, opts)
+		assert.True(t, errors.Is(err, ErrInvalidQuery), "%+v: %v", query, err)
+	}
+}
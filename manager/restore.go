@@ -0,0 +1,84 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+// Time the lineage of a restored container is remembered after the container
+// is deleted, for the containers restored from its checkpoints.
+const restoreLineageRetention = 7 * 24 * time.Hour
+
+type restoredContainer struct {
+	continuityID string
+	// Time the container was deleted, zero while it runs.
+	deleted time.Time
+}
+
+// restoreLineage links containers restored from CRIU checkpoints to the first
+// container of their chain of restores, so that a workload checkpointed and
+// restored several times keeps one continuity ID. Only restored containers
+// are recorded; the continuity ID of any other container is its own ID.
+type restoreLineage struct {
+	lock       sync.Mutex
+	containers map[string]restoredContainer
+}
+
+func newRestoreLineage() *restoreLineage {
+	return &restoreLineage{containers: make(map[string]restoredContainer)}
+}
+
+// restored records that container id was restored from a checkpoint of
+// container restoredFrom and returns its continuity ID.
+func (l *restoreLineage) restored(id, restoredFrom string, now time.Time) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for k, c := range l.containers {
+		if !c.deleted.IsZero() && now.Sub(c.deleted) > restoreLineageRetention {
+			delete(l.containers, k)
+		}
+	}
+
+	continuityID := restoredFrom
+	if predecessor, ok := l.containers[restoredFrom]; ok {
+		continuityID = predecessor.continuityID
+	}
+	l.containers[id] = restoredContainer{continuityID: continuityID}
+	return continuityID
+}
+
+// deleted records the deletion of container id, whose lineage is remembered
+// for restoreLineageRetention.
+func (l *restoreLineage) deleted(id string, now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if c, ok := l.containers[id]; ok {
+		c.deleted = now
+		l.containers[id] = c
+	}
+}
+
+// containerID returns the ID the runtime knows a container by, its name for
+// containers without one.
+func containerID(ref info.ContainerReference) string {
+	if ref.Id != "" {
+		return ref.Id
+	}
+	return ref.Name
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreLineage(t *testing.T) {
+	l := newRestoreLineage()
+	now := time.Unix(1600000000, 0)
+
+	// a is checkpointed and restored as b, b as c.
+	assert.Equal(t, "a", l.restored("b", "a", now))
+	l.deleted("b", now.Add(time.Hour))
+	assert.Equal(t, "a", l.restored("c", "b", now.Add(2*time.Hour)))
+
+	// Podman restores into a container with the same ID.
+	assert.Equal(t, "a", l.restored("c", "c", now.Add(3*time.Hour)))
+
+	// The lineage of deleted containers is forgotten after a while.
+	l.deleted("c", now.Add(4*time.Hour))
+	assert.Equal(t, "c", l.restored("d", "c", now.Add(5*time.Hour+restoreLineageRetention)))
+	assert.NotContains(t, l.containers, "b")
+}
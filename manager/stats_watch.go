@@ -0,0 +1,168 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/cadvisor/container/docker"
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"k8s.io/klog/v2"
+)
+
+// Number of stats buffered for a watcher. Stats are dropped when a watcher
+// does not keep up, so that housekeeping never blocks on slow watchers.
+const statsChannelSize = 100
+
+// StatsChannel receives the stats of the watched containers as they are
+// collected by housekeeping. The stats of each update hold a single sample.
+type StatsChannel struct {
+	watchID int
+	channel chan *info.ContainerInfo
+}
+
+func NewStatsChannel(watchID int) *StatsChannel {
+	return &StatsChannel{
+		watchID: watchID,
+		channel: make(chan *info.ContainerInfo, statsChannelSize),
+	}
+}
+
+func (c *StatsChannel) GetChannel() chan *info.ContainerInfo {
+	return c.channel
+}
+
+func (c *StatsChannel) GetWatchId() int {
+	return c.watchID
+}
+
+type statsWatch struct {
+	containerName string
+	recursive     bool
+	// Only matches containers of this namespace when set.
+	namespace string
+	filter    *v2.ContainerFilter
+	channel   *StatsChannel
+}
+
+func (w *statsWatch) matches(ref info.ContainerReference, labels map[string]string) bool {
+	if w.namespace != "" && ref.Namespace != w.namespace {
+		return false
+	}
+	if ref.Name != w.containerName {
+		if !w.recursive {
+			return false
+		}
+		if w.containerName != "/" && !strings.HasPrefix(ref.Name, w.containerName+"/") {
+			return false
+		}
+	}
+	return w.filter == nil || w.filter.Matches(ref, labels)
+}
+
+type statsWatchers struct {
+	lock     sync.RWMutex
+	lastID   int
+	watchers map[int]*statsWatch
+}
+
+func newStatsWatchers() *statsWatchers {
+	return &statsWatchers{watchers: map[int]*statsWatch{}}
+}
+
+func (s *statsWatchers) add(w *statsWatch) *StatsChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastID++
+	w.channel = NewStatsChannel(s.lastID)
+	s.watchers[s.lastID] = w
+	return w.channel
+}
+
+func (s *statsWatchers) remove(watchID int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	w, ok := s.watchers[watchID]
+	if !ok {
+		klog.Errorf("Could not find stats watcher %v", watchID)
+		return
+	}
+	close(w.channel.channel)
+	delete(s.watchers, watchID)
+}
+
+// notify sends the stats of a container to the matching watchers.
+func (s *statsWatchers) notify(ref info.ContainerReference, spec info.ContainerSpec, stats *info.ContainerStats) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for id, w := range s.watchers {
+		if !w.matches(ref, spec.Labels) {
+			continue
+		}
+		update := &info.ContainerInfo{
+			ContainerReference: ref,
+			Spec:               spec,
+			Stats:              []*info.ContainerStats{stats},
+		}
+		select {
+		case w.channel.channel <- update:
+		default:
+			klog.V(4).Infof("Dropping stats of container %q for slow stats watcher %v", ref.Name, id)
+		}
+	}
+}
+
+// WatchForStats returns a channel receiving the stats of the requested
+// containers as they are collected. Only the IdType, Recursive and Filter
+// request options are used.
+func (m *manager) WatchForStats(containerName string, options v2.RequestOptions) (*StatsChannel, error) {
+	w := &statsWatch{
+		containerName: containerName,
+		recursive:     options.Recursive,
+		filter:        options.Filter,
+	}
+	switch options.IdType {
+	case v2.TypeName:
+		if !options.Recursive {
+			if _, err := m.getContainer(containerName); err != nil {
+				return nil, err
+			}
+		}
+	case v2.TypeDocker:
+		w.namespace = docker.DockerNamespace
+		if !options.Recursive {
+			cont, err := m.getDockerContainer(strings.TrimPrefix(containerName, "/"))
+			if err != nil {
+				return nil, err
+			}
+			w.containerName = cont.info.Name
+		} else if containerName != "/" {
+			return nil, fmt.Errorf("invalid request for docker container %q with subcontainers", containerName)
+		}
+	default:
+		return nil, fmt.Errorf("invalid request type %q", options.IdType)
+	}
+	return m.statsWatchers.add(w), nil
+}
+
+// CloseStatsChannel stops sending stats to the channel returned by
+// WatchForStats and closes it.
+func (m *manager) CloseStatsChannel(watchID int) {
+	m.statsWatchers.remove(watchID)
+}
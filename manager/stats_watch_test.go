@@ -0,0 +1,93 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/cadvisor/cache/memory"
+	containertest "github.com/google/cadvisor/container/testing"
+	info "github.com/google/cadvisor/info/v1"
+	itest "github.com/google/cadvisor/info/v1/test"
+	v2 "github.com/google/cadvisor/info/v2"
+	"github.com/google/cadvisor/utils/sysfs/fakesysfs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receivedStats(c *StatsChannel) []string {
+	var names []string
+	for {
+		select {
+		case update := <-c.GetChannel():
+			names = append(names, update.Name)
+		default:
+			return names
+		}
+	}
+}
+
+func TestWatchForStats(t *testing.T) {
+	containers := []string{"/", "/a", "/a/b", "/ab"}
+	m := createManagerAndAddContainers(memory.New(time.Minute, nil), &fakesysfs.FakeSysFs{}, containers, func(h *containertest.MockContainerHandler) {
+		h.On("GetStats").Return(itest.GenerateRandomStats(1, 4, time.Second)[0], nil)
+	}, t)
+	m.statsWatchers = newStatsWatchers()
+	m.containers.forEach(func(_ namespacedContainerName, cont *containerData) bool {
+		cont.statsWatchers = m.statsWatchers
+		return true
+	})
+	registeredContainer(m, "/ab").info.Spec.Labels = map[string]string{"app": "web"}
+
+	all, err := m.WatchForStats("/", v2.RequestOptions{IdType: v2.TypeName, Recursive: true})
+	require.NoError(t, err)
+	a, err := m.WatchForStats("/a", v2.RequestOptions{IdType: v2.TypeName, Recursive: true})
+	require.NoError(t, err)
+	single, err := m.WatchForStats("/a", v2.RequestOptions{IdType: v2.TypeName})
+	require.NoError(t, err)
+	filtered, err := m.WatchForStats("/", v2.RequestOptions{
+		IdType:    v2.TypeName,
+		Recursive: true,
+		Filter:    &v2.ContainerFilter{Selector: []v2.LabelRequirement{{Key: "app", Operator: v2.LabelEquals, Value: "web"}}},
+	})
+	require.NoError(t, err)
+	_, err = m.WatchForStats("/unknown", v2.RequestOptions{IdType: v2.TypeName})
+	assert.Error(t, err)
+
+	for _, name := range containers {
+		require.NoError(t, registeredContainer(m, name).updateStats())
+	}
+	assert.Equal(t, containers, receivedStats(all))
+	assert.Equal(t, []string{"/a", "/a/b"}, receivedStats(a))
+	assert.Equal(t, []string{"/a"}, receivedStats(single))
+	assert.Equal(t, []string{"/ab"}, receivedStats(filtered))
+
+	m.CloseStatsChannel(all.GetWatchId())
+	_, ok := <-all.GetChannel()
+	assert.False(t, ok)
+	require.NoError(t, registeredContainer(m, "/a").updateStats())
+	assert.Equal(t, []string{"/a"}, receivedStats(a))
+}
+
+func TestStatsWatchersDropWhenFull(t *testing.T) {
+	watchers := newStatsWatchers()
+	c := watchers.add(&statsWatch{containerName: "/", recursive: true})
+	for i := 0; i < statsChannelSize+10; i++ {
+		watchers.notify(info.ContainerReference{Name: "/a"}, info.ContainerSpec{}, &info.ContainerStats{})
+	}
+	assert.Len(t, receivedStats(c), statsChannelSize)
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/common/model"
+)
+
+// Prefixes of the entries of a container label mapping selecting container
+// labels and environment variables.
+const (
+	labelMappingPrefix = "label:"
+	envMappingPrefix   = "env:"
+)
+
+// containerLabelRule exports the container labels or environment variables
+// whose keys match pattern.
+type containerLabelRule struct {
+	env     bool
+	pattern string
+	// Whether pattern is a shell pattern, or else a key.
+	glob bool
+	// Name of the exported label, ContainerLabelPrefix or ContainerEnvPrefix
+	// followed by the key if empty.
+	name string
+}
+
+func (r containerLabelRule) matches(key string) bool {
+	if r.env {
+		// Environment variables are lowercased by some container runtimes.
+		key = strings.ToLower(key)
+	}
+	if !r.glob {
+		return key == r.pattern
+	}
+	ok, _ := path.Match(r.pattern, key)
+	return ok
+}
+
+func (r containerLabelRule) labelName(key string) string {
+	switch {
+	case r.name != "":
+		return r.name
+	case r.env:
+		return ContainerEnvPrefix + key
+	default:
+		return ContainerLabelPrefix + key
+	}
+}
+
+func parseContainerLabelRule(entry string) (containerLabelRule, error) {
+	var r containerLabelRule
+	switch {
+	case strings.HasPrefix(entry, labelMappingPrefix):
+		entry, r.glob = strings.TrimPrefix(entry, labelMappingPrefix), true
+	case strings.HasPrefix(entry, envMappingPrefix):
+		entry, r.glob, r.env = strings.TrimPrefix(entry, envMappingPrefix), true, true
+	}
+	parts := strings.SplitN(entry, "=", 2)
+	r.pattern = strings.TrimSpace(parts[0])
+	if len(parts) == 2 {
+		r.name = strings.TrimSpace(parts[1])
+		if !model.LabelName(r.name).IsValid() {
+			return r, fmt.Errorf("invalid label name %q", r.name)
+		}
+		switch r.name {
+		case LabelID, LabelName, LabelImage, LabelContinuityID, LabelIdentity:
+			return r, fmt.Errorf("label name %q is reserved", r.name)
+		}
+	}
+	if r.pattern == "" {
+		return r, fmt.Errorf("missing key in %q", entry)
+	}
+	if r.env {
+		r.pattern = strings.ToLower(r.pattern)
+	}
+	if r.glob {
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return r, fmt.Errorf("invalid pattern %q: %v", r.pattern, err)
+		}
+		r.glob = strings.ContainsAny(r.pattern, `*?[\`)
+		if r.glob && r.name != "" {
+			return r, fmt.Errorf("the keys matching %q cannot be renamed to a single label", r.pattern)
+		}
+	}
+	return r, nil
+}
+
+// MappedContainerLabels returns a ContainerLabelsFunc that exports the
+// container name, first alias, image name as well as the container labels
+// and environment variables selected by mapping. Each entry of mapping is
+// one of:
+//
+//	key                  the container label key, exported as container_label_<key>
+//	label:<pattern>      the container labels whose keys match the shell pattern
+//	env:<pattern>        the environment variables whose keys match the shell
+//	                     pattern, exported as container_env_<key>
+//	label:<key>=<name>   the container label key, exported as name
+//	env:<key>=<name>     the environment variable key, exported as name
+//
+// The first entry matching a key applies. Environment variables are only
+// known for the containers whose runtime collects them.
+func MappedContainerLabels(mapping []string) (ContainerLabelsFunc, error) {
+	var rules []containerLabelRule
+	for _, entry := range mapping {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		r, err := parseContainerLabelRule(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	export := func(set map[string]string, values map[string]string, env bool) {
+		for k, v := range values {
+			for _, r := range rules {
+				if r.env == env && r.matches(k) {
+					set[r.labelName(k)] = v
+					break
+				}
+			}
+		}
+	}
+	return func(container *info.ContainerInfo) map[string]string {
+		set := map[string]string{LabelID: container.Name}
+		if len(container.Aliases) > 0 {
+			set[LabelName] = container.Aliases[0]
+		}
+		if image := container.Spec.Image; len(image) > 0 {
+			set[LabelImage] = image
+		}
+		export(set, container.Spec.Labels, false)
+		export(set, container.Spec.Envs, true)
+		return set
+	}, nil
+}
+
+// WithContinuityLabel returns a ContainerLabelsFunc that adds the continuity
+// ID of a container to the labels exported by labelsFunc: the ID of the first
+// container of a chain of containers restored from checkpoints of each
+// other, or the container's own ID if it was not restored. Series of the
+// same workload can be joined on it across restores, which reset counters.
+func WithContinuityLabel(labelsFunc ContainerLabelsFunc) ContainerLabelsFunc {
+	return func(container *info.ContainerInfo) map[string]string {
+		set := labelsFunc(container)
+		switch {
+		case container.Spec.ContinuityId != "":
+			set[LabelContinuityID] = container.Spec.ContinuityId
+		case container.Id != "":
+			set[LabelContinuityID] = container.Id
+		default:
+			set[LabelContinuityID] = container.Name
+		}
+		return set
+	}
+}
+
+// ContainerIdentity returns an identity of a container which is stable across
+// its restarts, unlike its ID: a hash of the UID of its pod and its name in
+// the pod for Kubernetes containers, else of the name given by its runtime,
+// its first alias, or of its cgroup name for containers without aliases.
+func ContainerIdentity(container *info.ContainerInfo) string {
+	key := container.Name
+	if len(container.Aliases) > 0 {
+		key = container.Aliases[0]
+	}
+	podUID, name := container.Spec.Labels[v2.PodUIDLabel], container.Spec.Labels[v2.ContainerNameLabel]
+	if podUID != "" && name != "" {
+		key = podUID + "/" + name
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithIdentityLabel returns a ContainerLabelsFunc that adds the identity of a
+// container, see ContainerIdentity, to the labels exported by labelsFunc.
+// Series of a container restarted by Kubernetes can be joined on it, and the
+// id and name labels can be dropped to bound the number of series created by
+// restarts.
+func WithIdentityLabel(labelsFunc ContainerLabelsFunc) ContainerLabelsFunc {
+	return func(container *info.ContainerInfo) map[string]string {
+		set := labelsFunc(container)
+		set[LabelIdentity] = ContainerIdentity(container)
+		return set
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappedContainerLabels(t *testing.T) {
+	labelsFunc, err := MappedContainerLabels([]string{
+		"team",
+		"label:io.kubernetes.pod.name=pod",
+		"label:app.kubernetes.io/*",
+		"env:JAVA_*",
+		"env:region=zone",
+		"",
+	})
+	require.NoError(t, err)
+
+	labels := labelsFunc(&info.ContainerInfo{
+		ContainerReference: info.ContainerReference{
+			Name:    "/docker/abc",
+			Aliases: []string{"web", "abc"},
+		},
+		Spec: info.ContainerSpec{
+			Image: "nginx",
+			Labels: map[string]string{
+				"team":                        "infra",
+				"team*":                       "literal keys do not match patterns",
+				"io.kubernetes.pod.name":      "web-0",
+				"app.kubernetes.io/name":      "web",
+				"app.kubernetes.io/component": "frontend",
+				"secret":                      "not exported",
+			},
+			Envs: map[string]string{
+				"java_opts": "-Xmx1g",
+				"REGION":    "eu-west1",
+				"PATH":      "/bin",
+			},
+		},
+	})
+	assert.Equal(t, map[string]string{
+		"id":                                     "/docker/abc",
+		"name":                                   "web",
+		"image":                                  "nginx",
+		"container_label_team":                   "infra",
+		"pod":                                    "web-0",
+		"container_label_app.kubernetes.io/name": "web",
+		"container_label_app.kubernetes.io/component": "frontend",
+		"container_env_java_opts":                     "-Xmx1g",
+		"zone":                                        "eu-west1",
+	}, labels)
+}
+
+func TestMappedContainerLabelsErrors(t *testing.T) {
+	for _, entry := range []string{
+		"label:app=not a label",
+		"env:=zone",
+		"label:app.kubernetes.io/*=app",
+		"label:[=app",
+		"label:app=id",
+		"label:app=continuity_id",
+		"label:app=identity",
+	} {
+		_, err := MappedContainerLabels([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestWithContinuityLabel(t *testing.T) {
+	labelsFunc := WithContinuityLabel(BaseContainerLabels(nil))
+	for _, tc := range []struct {
+		container info.ContainerInfo
+		expected  string
+	}{
+		{
+			container: info.ContainerInfo{
+				ContainerReference: info.ContainerReference{Id: "c", Name: "/docker/c"},
+				Spec:               info.ContainerSpec{RestoredFrom: "b", ContinuityId: "a"},
+			},
+			expected: "a",
+		},
+		{
+			container: info.ContainerInfo{
+				ContainerReference: info.ContainerReference{Id: "a", Name: "/docker/a"},
+			},
+			expected: "a",
+		},
+		{
+			container: info.ContainerInfo{
+				ContainerReference: info.ContainerReference{Name: "/system.slice"},
+			},
+			expected: "/system.slice",
+		},
+	} {
+		labels := labelsFunc(&tc.container)
+		assert.Equal(t, tc.expected, labels[LabelContinuityID], tc.container.Name)
+		assert.Equal(t, tc.container.Name, labels[LabelID])
+	}
+}
+
+func TestContainerIdentity(t *testing.T) {
+	pod := map[string]string{
+		"io.kubernetes.pod.uid":        "8b9bd3f2-4b2e-4c3a-9a1e-1f3c8a7d2e10",
+		"io.kubernetes.container.name": "web",
+	}
+	first := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Id: "abc", Name: "/kubepods/pod8b9bd3f2/abc", Aliases: []string{"k8s_web_web-0_default_8b9bd3f2_0", "abc"}},
+		Spec:               info.ContainerSpec{Labels: pod},
+	}
+	restarted := &info.ContainerInfo{
+		ContainerReference: info.ContainerReference{Id: "def", Name: "/kubepods/pod8b9bd3f2/def", Aliases: []string{"k8s_web_web-0_default_8b9bd3f2_1", "def"}},
+		Spec:               info.ContainerSpec{Labels: pod},
+	}
+	identity := ContainerIdentity(first)
+	assert.Len(t, identity, 16)
+	assert.Equal(t, identity, ContainerIdentity(restarted))
+
+	// Containers outside of pods are identified by their names.
+	named := &info.ContainerInfo{ContainerReference: info.ContainerReference{Id: "abc", Name: "/docker/abc", Aliases: []string{"web", "abc"}}}
+	renamed := &info.ContainerInfo{ContainerReference: info.ContainerReference{Id: "abc", Name: "/docker/abc", Aliases: []string{"db", "abc"}}}
+	assert.NotEqual(t, identity, ContainerIdentity(named))
+	assert.NotEqual(t, ContainerIdentity(named), ContainerIdentity(renamed))
+	assert.NotEmpty(t, ContainerIdentity(&info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/system.slice"}}))
+
+	labels := WithIdentityLabel(BaseContainerLabels(nil))(restarted)
+	assert.Equal(t, identity, labels[LabelIdentity])
+	assert.Equal(t, "/kubepods/pod8b9bd3f2/def", labels[LabelID])
+}
@@ -40,3 +40,10 @@ type infoProvider interface {
 	// GetMachineInfo provides information about the machine.
 	GetMachineInfo() (*info.MachineInfo, error)
 }
+
+// derivedStatsProvider is implemented by infoProviders computing the
+// percentiles of the usage of containers, usually manager.Manager.
+type derivedStatsProvider interface {
+	// GetDerivedStats gets the usage percentiles of the requested containers.
+	GetDerivedStats(containerName string, options v2.RequestOptions) (map[string]v2.DerivedStats, error)
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// ExemplarTraceIDLabel is the exemplar label holding the trace ID.
+const ExemplarTraceIDLabel = "trace_id"
+
+// OpenMetricsOptions configures the parts of the container metrics which
+// are only exposed in the OpenMetrics format.
+type OpenMetricsOptions struct {
+	// Container label holding a trace ID, attached as exemplar to the
+	// counters of the container. Empty disables exemplars.
+	ExemplarLabel string
+	// Records the created timestamps of counters, written as `_created`
+	// samples by WriteOpenMetrics. Nil disables created timestamps.
+	Created *CreatedTimestamps
+}
+
+// CreatedTimestamps holds the created timestamps of the counters gathered
+// during a single scrape.
+type CreatedTimestamps struct {
+	lock    sync.Mutex
+	created map[*dto.Metric]time.Time
+}
+
+func NewCreatedTimestamps() *CreatedTimestamps {
+	return &CreatedTimestamps{created: map[*dto.Metric]time.Time{}}
+}
+
+func (c *CreatedTimestamps) set(m *dto.Metric, t time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.created[m] = t
+}
+
+func (c *CreatedTimestamps) get(m *dto.Metric) (time.Time, bool) {
+	if c == nil {
+		return time.Time{}, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t, ok := c.created[m]
+	return t, ok
+}
+
+// openMetricsCounter attaches an exemplar to a counter and records its
+// created timestamp when the counter is written.
+type openMetricsCounter struct {
+	prometheus.Metric
+	exemplar *dto.Exemplar
+	created  time.Time
+	recorder *CreatedTimestamps
+}
+
+func (m *openMetricsCounter) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	if out.Counter != nil && m.exemplar != nil {
+		out.Counter.Exemplar = m.exemplar
+	}
+	if m.recorder != nil && !m.created.IsZero() {
+		m.recorder.set(out, m.created)
+	}
+	return nil
+}
+
+func newOpenMetricsCounter(metric prometheus.Metric, opts OpenMetricsOptions, traceID string, value metricValue, created time.Time) prometheus.Metric {
+	if traceID == "" && (opts.Created == nil || created.IsZero()) {
+		return metric
+	}
+	m := &openMetricsCounter{
+		Metric:   metric,
+		created:  created,
+		recorder: opts.Created,
+	}
+	if traceID != "" {
+		m.exemplar = &dto.Exemplar{
+			Label: []*dto.LabelPair{{
+				Name:  proto.String(ExemplarTraceIDLabel),
+				Value: proto.String(traceID),
+			}},
+			Value: proto.Float64(value.value),
+		}
+		if ts, err := ptypes.TimestampProto(value.timestamp); err == nil && !value.timestamp.IsZero() {
+			m.exemplar.Timestamp = ts
+		}
+	}
+	return m
+}
+
+// WriteOpenMetrics encodes the metric families in the OpenMetrics text
+// format. Each counter sample with a created timestamp recorded in created
+// is followed by its `_created` sample.
+func WriteOpenMetrics(w io.Writer, families []*dto.MetricFamily, created *CreatedTimestamps) error {
+	for _, family := range families {
+		if family.GetType() != dto.MetricType_COUNTER || created == nil {
+			if _, err := expfmt.MetricFamilyToOpenMetrics(w, family); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeCounterWithCreated(w, family, created); err != nil {
+			return err
+		}
+	}
+	_, err := expfmt.FinalizeOpenMetrics(w)
+	return err
+}
+
+func writeCounterWithCreated(w io.Writer, family *dto.MetricFamily, created *CreatedTimestamps) error {
+	name := strings.TrimSuffix(family.GetName(), "_total")
+	var buf bytes.Buffer
+	for i, m := range family.Metric {
+		buf.Reset()
+		single := &dto.MetricFamily{
+			Name:   family.Name,
+			Help:   family.Help,
+			Type:   family.Type,
+			Metric: []*dto.Metric{m},
+		}
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, single); err != nil {
+			return err
+		}
+		// The metadata of the family is written only once, before its first sample.
+		if err := writeSamples(w, &buf, i == 0); err != nil {
+			return err
+		}
+
+		t, ok := created.get(m)
+		if !ok {
+			continue
+		}
+		buf.Reset()
+		createdFamily := &dto.MetricFamily{
+			Name: proto.String(name + "_created"),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{{
+				Label:       m.Label,
+				Gauge:       &dto.Gauge{Value: proto.Float64(float64(t.UnixNano()) / float64(time.Second))},
+				TimestampMs: m.TimestampMs,
+			}},
+		}
+		if _, err := expfmt.MetricFamilyToOpenMetrics(&buf, createdFamily); err != nil {
+			return err
+		}
+		if err := writeSamples(w, &buf, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSamples copies the encoded lines to w, dropping the metadata lines
+// unless withMetadata is set.
+func writeSamples(w io.Writer, encoded io.Reader, withMetadata bool) error {
+	scanner := bufio.NewScanner(encoded)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !withMetadata && len(line) > 0 && line[0] == '#' {
+			continue
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
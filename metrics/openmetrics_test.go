@@ -0,0 +1,69 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/cadvisor/container"
+	v2 "github.com/google/cadvisor/info/v2"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatherOpenMetrics(t *testing.T, opts OpenMetricsOptions) string {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, DefaultContainerLabels, container.MetricSet{container.CpuUsageMetrics: struct{}{}}, now, v2.RequestOptions{})
+	c.SetOpenMetricsOptions(opts)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteOpenMetrics(&buf, families, opts.Created))
+	return buf.String()
+}
+
+func TestOpenMetricsCreatedAndExemplars(t *testing.T) {
+	out := gatherOpenMetrics(t, OpenMetricsOptions{
+		ExemplarLabel: "foo.label",
+		Created:       NewCreatedTimestamps(),
+	})
+
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+	assert.Equal(t, 1, strings.Count(out, "# TYPE container_cpu_usage_seconds counter\n"))
+
+	const labels = `{container_env_foo_env="prod",container_label_foo_label="bar",cpu="cpu00",id="testcontainer",image="test",name="testcontaineralias"}`
+	assert.Contains(t, out,
+		"container_cpu_usage_seconds_total"+labels+` 2e-09 1.395066363e+09 # {trace_id="bar"} 2e-09 1.395066363e+09`+"\n"+
+			"container_cpu_usage_seconds_created"+labels+" 1.257894e+09 1.395066363e+09\n")
+
+	// Gauges have neither exemplars nor created timestamps.
+	assert.NotContains(t, out, "container_last_seen_created")
+	assert.Contains(t, out, `container_last_seen{container_env_foo_env="prod",container_label_foo_label="bar",id="testcontainer",image="test",name="testcontaineralias"} 1.395066363e+09 1.395066363e+09`+"\n")
+}
+
+func TestOpenMetricsWithoutOptions(t *testing.T) {
+	out := gatherOpenMetrics(t, OpenMetricsOptions{})
+
+	assert.Contains(t, out, "# TYPE container_cpu_usage_seconds counter\n")
+	assert.NotContains(t, out, "_created")
+	assert.NotContains(t, out, "trace_id")
+	assert.True(t, strings.HasSuffix(out, "# EOF\n"))
+}
@@ -50,6 +50,57 @@ func fsValues(fsStats []info.FsStats, valueFn func(*info.FsStats) float64, times
 	return values
 }
 
+// mdArrayValues is a helper method for assembling per md array stats. The
+// name of the array is the first label.
+func mdArrayValues(s *info.ContainerStats, valueFn func(*info.MdArrayStats) (float64, []string)) metricValues {
+	values := make(metricValues, 0, len(s.MdArrays))
+	for i := range s.MdArrays {
+		value, labels := valueFn(&s.MdArrays[i])
+		values = append(values, metricValue{
+			value:     value,
+			labels:    append([]string{s.MdArrays[i].Name}, labels...),
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
+// storageSessionValues is a helper method for assembling per storage session
+// stats, labeled by the session, its transport and target.
+func storageSessionValues(s *info.ContainerStats, valueFn func(*info.StorageSessionStats) (float64, []string)) metricValues {
+	values := make(metricValues, 0, len(s.StorageSessions))
+	for i := range s.StorageSessions {
+		session := &s.StorageSessions[i]
+		value, labels := valueFn(session)
+		values = append(values, metricValue{
+			value:     value,
+			labels:    append([]string{session.Name, session.Transport, session.Target}, labels...),
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
+// storageTargetValues is a helper method for assembling the path counts of
+// storage targets, which are reported by every session with the target.
+func storageTargetValues(s *info.ContainerStats, valueFn func(*info.StorageSessionStats) float64) metricValues {
+	var values metricValues
+	seen := map[string]struct{}{}
+	for i := range s.StorageSessions {
+		session := &s.StorageSessions[i]
+		if _, ok := seen[session.Target]; ok {
+			continue
+		}
+		seen[session.Target] = struct{}{}
+		values = append(values, metricValue{
+			value:     valueFn(session),
+			labels:    []string{session.Transport, session.Target},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
 // ioValues is a helper method for assembling per-disk and per-filesystem stats.
 func ioValues(ioStats []info.PerDiskStats, ioType string, ioValueFn func(uint64) float64,
 	fsStats []info.FsStats, valueFn func(*info.FsStats) float64, timestamp time.Time) metricValues {
@@ -99,6 +150,8 @@ type PrometheusCollector struct {
 	containerLabelsFunc ContainerLabelsFunc
 	includedMetrics     container.MetricSet
 	opts                v2.RequestOptions
+	openMetrics         OpenMetricsOptions
+	appMetricsOnly      bool
 }
 
 // NewPrometheusCollector returns a new PrometheusCollector. The passed
@@ -222,6 +275,410 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 							timestamp: s.Timestamp,
 						}}
 				},
+			}, {
+				name:      "container_cpu_cfs_bursts_total",
+				help:      "Number of periods in which the container used burst capacity beyond its quota.",
+				valueType: prometheus.CounterValue,
+				condition: func(s info.ContainerSpec) bool { return s.Cpu.Quota != 0 },
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Cpu.CFS.Bursts),
+							timestamp: s.Timestamp,
+						}}
+				},
+			}, {
+				name:      "container_cpu_cfs_burst_seconds_total",
+				help:      "Total time duration the container has run on burst capacity beyond its quota.",
+				valueType: prometheus.CounterValue,
+				condition: func(s info.ContainerSpec) bool { return s.Cpu.Quota != 0 },
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{
+						{
+							value:     float64(s.Cpu.CFS.BurstTime) / float64(time.Second),
+							timestamp: s.Timestamp,
+						}}
+				},
+			}, {
+				name:      "container_cpu_wait_seconds_total",
+				help:      "Total time duration tasks of the container have been waiting on a runqueue, as accounted by the cgroup.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					// wait_sum is only reported on cgroup v1 with schedstats enabled.
+					if s.Cpu.WaitTime == 0 {
+						return nil
+					}
+					return metricValues{
+						{
+							value:     float64(s.Cpu.WaitTime) / float64(time.Second),
+							timestamp: s.Timestamp,
+						}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.CpuStealMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_cpu_guest_seconds_total",
+				help:      "Cumulative cpu time spent running virtual CPUs of guests.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Cpu.Usage.Guest) / float64(time.Second), timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_cpu_steal_seconds_total",
+				help:      "Cumulative cpu time stolen by the hypervisor.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					// Steal time is only known for the root container and VM-isolated containers.
+					if s.Cpu.Usage.Steal == 0 {
+						return nil
+					}
+					return metricValues{{value: float64(s.Cpu.Usage.Steal) / float64(time.Second), timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.CpuFrequencyMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_cpu_frequency_hertz",
+				help:        "Current frequency of the CPU as reported by cpufreq.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return cpuFreqValues(s, func(f info.CpuFreq) uint64 { return f.Current })
+				},
+			}, {
+				name:        "container_cpu_effective_frequency_hertz",
+				help:        "Average frequency the CPU ran at since the previous sample, derived from APERF/MPERF.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return cpuFreqValues(s, func(f info.CpuFreq) uint64 { return f.Effective })
+				},
+			}, {
+				name:        "container_cpu_scaling_max_frequency_hertz",
+				help:        "Highest frequency the CPU is currently allowed to run at by its cpufreq policy.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return cpuFreqValues(s, func(f info.CpuFreq) uint64 { return f.ScalingMax })
+				},
+			}, {
+				name:        "container_cpu_hardware_max_frequency_hertz",
+				help:        "Highest frequency the CPU supports, including turbo.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return cpuFreqValues(s, func(f info.CpuFreq) uint64 { return f.HardwareMax })
+				},
+			}, {
+				name:      "container_cpu_turbo_disabled",
+				help:      "1 if turbo frequencies are disabled in the intel_pstate driver, 0 otherwise.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.CpuFreq == nil || s.CpuFreq.IntelPstate == nil {
+						return nil
+					}
+					value := 0.0
+					if s.CpuFreq.IntelPstate.NoTurbo {
+						value = 1
+					}
+					return metricValues{{value: value, timestamp: s.Timestamp}}
+				},
+			}, {
+				name:      "container_cpu_max_performance_ratio",
+				help:      "Performance limit set in the intel_pstate driver, as a ratio of the maximum supported performance.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.CpuFreq == nil || s.CpuFreq.IntelPstate == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.CpuFreq.IntelPstate.MaxPerfPct) / 100, timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.PowerMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_energy_domain_joules_total",
+				help:        "Energy consumed by a RAPL domain of the host since cAdvisor started.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"socket", "domain"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Energy == nil {
+						return nil
+					}
+					values := make(metricValues, 0, len(s.Energy.Domains))
+					for _, domain := range s.Energy.Domains {
+						values = append(values, metricValue{
+							value:     float64(domain.Energy) / 1e6,
+							labels:    []string{strconv.Itoa(domain.Socket), domain.Domain},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			}, {
+				name:      "container_energy_estimated_joules_total",
+				help:      "Energy consumed by the host attributed to the container according to its share of the CPU time used.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Energy == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Energy.Estimated) / 1e6, timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.ThermalMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_thermal_zone_temperature_celsius",
+				help:        "Temperature of a thermal zone of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"zone", "type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Thermal == nil {
+						return nil
+					}
+					values := make(metricValues, 0, len(s.Thermal.Zones))
+					for _, zone := range s.Thermal.Zones {
+						values = append(values, metricValue{
+							value:     float64(zone.Temperature) / 1000,
+							labels:    []string{zone.Zone, zone.Type},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			}, {
+				name:        "container_thermal_zone_trip_point_celsius",
+				help:        "Temperature at which the kernel starts cooling a thermal zone of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"zone", "type", "trip_point", "trip_type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Thermal == nil {
+						return nil
+					}
+					var values metricValues
+					for _, zone := range s.Thermal.Zones {
+						for _, trip := range zone.TripPoints {
+							values = append(values, metricValue{
+								value:     float64(trip.Temperature) / 1000,
+								labels:    []string{zone.Zone, zone.Type, strconv.Itoa(trip.Index), trip.Type},
+								timestamp: s.Timestamp,
+							})
+						}
+					}
+					return values
+				},
+			}, {
+				name:        "container_fan_speed_rpm",
+				help:        "Speed of a fan of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "chip", "fan"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Thermal == nil {
+						return nil
+					}
+					values := make(metricValues, 0, len(s.Thermal.Fans))
+					for _, fan := range s.Thermal.Fans {
+						values = append(values, metricValue{
+							value:     float64(fan.Speed),
+							labels:    []string{fan.Device, fan.Chip, fan.Fan},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.ThermalThrottleMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_cpu_core_throttles_total",
+				help:        "Number of times a CPU core of the host was throttled because it was too hot.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return coreThrottleValues(s, func(c info.CoreThrottleStats) float64 { return float64(c.Count) })
+				},
+			}, {
+				name:        "container_cpu_core_throttled_seconds_total",
+				help:        "Time a CPU core of the host was throttled because it was too hot.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"cpu"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return coreThrottleValues(s, func(c info.CoreThrottleStats) float64 { return float64(c.Time) / float64(time.Second) })
+				},
+			}, {
+				name:        "container_cpu_package_throttles_total",
+				help:        "Number of times a CPU package of the host was throttled because it was too hot.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"package"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return packageThrottleValues(s, func(p info.PackageThrottleStats) float64 { return float64(p.Count) })
+				},
+			}, {
+				name:        "container_cpu_package_throttled_seconds_total",
+				help:        "Time a CPU package of the host was throttled because it was too hot.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"package"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return packageThrottleValues(s, func(p info.PackageThrottleStats) float64 { return float64(p.Time) / float64(time.Second) })
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.MdRaidMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_md_array_info",
+				help:        "A metric with a constant '1' value labeled by the RAID level and state of a software RAID (md) array of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"md_device", "level", "state"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return mdArrayValues(s, func(array *info.MdArrayStats) (float64, []string) {
+						return 1, []string{array.Level, array.State}
+					})
+				},
+			}, {
+				name:        "container_md_array_disks",
+				help:        "Number of devices of a complete software RAID (md) array of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"md_device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return mdArrayValues(s, func(array *info.MdArrayStats) (float64, []string) {
+						return float64(array.Disks), nil
+					})
+				},
+			}, {
+				name:        "container_md_array_degraded_disks",
+				help:        "Number of devices missing from a software RAID (md) array of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"md_device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return mdArrayValues(s, func(array *info.MdArrayStats) (float64, []string) {
+						return float64(array.Degraded), nil
+					})
+				},
+			}, {
+				name:        "container_md_array_failed_disks",
+				help:        "Number of failed member devices of a software RAID (md) array of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"md_device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return mdArrayValues(s, func(array *info.MdArrayStats) (float64, []string) {
+						failed := 0
+						for _, member := range array.Members {
+							if member.Faulty {
+								failed++
+							}
+						}
+						return float64(failed), nil
+					})
+				},
+			}, {
+				name:        "container_md_array_sync_completed_ratio",
+				help:        "Progress of the sync action (e.g. resync, recover or check) in progress on a software RAID (md) array of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"md_device", "action"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					var values metricValues
+					for _, array := range s.MdArrays {
+						if array.SyncAction == "" || array.SyncAction == "idle" {
+							continue
+						}
+						values = append(values, metricValue{
+							value:     array.SyncCompleted,
+							labels:    []string{array.Name, array.SyncAction},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.StorageSessionMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_storage_session_info",
+				help:        "A metric with a constant '1' value labeled by the address and state of an iSCSI session or NVMe over Fabrics controller of the host.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"session", "transport", "target", "address", "state"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return storageSessionValues(s, func(session *info.StorageSessionStats) (float64, []string) {
+						return 1, []string{session.Address, session.State}
+					})
+				},
+			}, {
+				name:        "container_storage_session_connected",
+				help:        "Whether an iSCSI session or NVMe over Fabrics controller of the host is able to carry IO.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"session", "transport", "target"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return storageSessionValues(s, func(session *info.StorageSessionStats) (float64, []string) {
+						if session.Connected {
+							return 1, nil
+						}
+						return 0, nil
+					})
+				},
+			}, {
+				name:        "container_storage_session_reconnects_total",
+				help:        "Cumulative count of the times an iSCSI session or NVMe over Fabrics controller of the host was seen leaving the connected state.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"session", "transport", "target"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return storageSessionValues(s, func(session *info.StorageSessionStats) (float64, []string) {
+						return float64(session.Reconnects), nil
+					})
+				},
+			}, {
+				name:        "container_storage_session_device_info",
+				help:        "A metric with a constant '1' value mapping an iSCSI session or NVMe over Fabrics controller of the host to the block devices it backs.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"session", "transport", "target", "device"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					var values metricValues
+					for _, session := range s.StorageSessions {
+						for _, device := range session.Devices {
+							values = append(values, metricValue{
+								value:     1,
+								labels:    []string{session.Name, session.Transport, session.Target, device},
+								timestamp: s.Timestamp,
+							})
+						}
+					}
+					return values
+				},
+			}, {
+				name:        "container_storage_target_paths",
+				help:        "Number of sessions of the host with an iSCSI target or NVMe subsystem.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"transport", "target"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return storageTargetValues(s, func(session *info.StorageSessionStats) float64 {
+						return float64(session.Paths)
+					})
+				},
+			}, {
+				name:        "container_storage_target_connected_paths",
+				help:        "Number of connected sessions of the host with an iSCSI target or NVMe subsystem.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"transport", "target"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return storageTargetValues(s, func(session *info.StorageSessionStats) float64 {
+						return float64(session.ConnectedPaths)
+					})
+				},
 			},
 		}...)
 	}
@@ -301,6 +758,11 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 							labels:    []string{"iowaiting"},
 							timestamp: s.Timestamp,
 						},
+						{
+							value:     float64(s.TaskStats.NrZombie),
+							labels:    []string{"zombie"},
+							timestamp: s.Timestamp,
+						},
 					}
 				},
 			},
@@ -453,6 +915,18 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					}
 				},
 			},
+			{
+				name:        "container_memory_events_total",
+				help:        "Cumulative count of cgroup v2 memory events.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"event", "scope"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, 12)
+					values = append(values, memoryEventsValues(s.Memory.LocalEvents, "container", s.Timestamp)...)
+					values = append(values, memoryEventsValues(s.Memory.Events, "hierarchy", s.Timestamp)...)
+					return values
+				},
+			},
 		}...)
 	}
 	if includedMetrics.Has(container.MemoryNumaMetrics) {
@@ -482,6 +956,202 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.MemoryStatMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_memory_anon_bytes",
+				help:      "Size of anonymous memory in bytes, including transparent hugepages.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Anon })
+				},
+			},
+			{
+				name:      "container_memory_file_bytes",
+				help:      "Size of page cache memory in bytes, including tmpfs and shared memory.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.File })
+				},
+			},
+			{
+				name:      "container_memory_kernel_stack_bytes",
+				help:      "Size of memory allocated to kernel stacks in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.KernelStack })
+				},
+			},
+			{
+				name:      "container_memory_pagetables_bytes",
+				help:      "Size of memory allocated for page tables in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Pagetables })
+				},
+			},
+			{
+				name:      "container_memory_percpu_bytes",
+				help:      "Size of memory used by per-cpu kernel data structures in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Percpu })
+				},
+			},
+			{
+				name:      "container_memory_sock_bytes",
+				help:      "Size of memory used in network transmission buffers in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Sock })
+				},
+			},
+			{
+				name:      "container_memory_shmem_bytes",
+				help:      "Size of swap-backed cached memory, such as tmpfs and shm segments, in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Shmem })
+				},
+			},
+			{
+				name:      "container_memory_file_mapped_bytes",
+				help:      "Size of page cache mapped into process address spaces in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.FileMapped })
+				},
+			},
+			{
+				name:      "container_memory_file_dirty_bytes",
+				help:      "Size of page cache modified but not yet written back in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.FileDirty })
+				},
+			},
+			{
+				name:      "container_memory_file_writeback_bytes",
+				help:      "Size of page cache being written back in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.FileWriteback })
+				},
+			},
+			{
+				name:      "container_memory_anon_thp_bytes",
+				help:      "Size of anonymous memory backed by transparent hugepages in bytes.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.AnonThp })
+				},
+			},
+			{
+				name:        "container_memory_lru_bytes",
+				help:        "Size of memory on the memory management LRU lists in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"lru"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, []string{"inactive_anon", "active_anon", "inactive_file", "active_file", "unevictable"},
+						func(b *info.MemoryStatBreakdown) uint64 { return b.InactiveAnon },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.ActiveAnon },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.InactiveFile },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.ActiveFile },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.Unevictable })
+				},
+			},
+			{
+				name:        "container_memory_slab_bytes",
+				help:        "Size of slab memory in bytes.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, []string{"reclaimable", "unreclaimable"},
+						func(b *info.MemoryStatBreakdown) uint64 { return b.SlabReclaimable },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.SlabUnreclaimable })
+				},
+			},
+			{
+				name:        "container_memory_workingset_refault_total",
+				help:        "Cumulative count of refaults of previously evicted pages.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, []string{"anon", "file"},
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetRefaultAnon },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetRefaultFile })
+				},
+			},
+			{
+				name:        "container_memory_workingset_activate_total",
+				help:        "Cumulative count of refaulted pages that were immediately activated.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, []string{"anon", "file"},
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetActivateAnon },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetActivateFile })
+				},
+			},
+			{
+				name:        "container_memory_workingset_restore_total",
+				help:        "Cumulative count of restored pages which had been detected as a workingset before they got reclaimed.",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"type"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, []string{"anon", "file"},
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetRestoreAnon },
+						func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetRestoreFile })
+				},
+			},
+			{
+				name:      "container_memory_workingset_nodereclaim_total",
+				help:      "Cumulative count of times a shadow node has been reclaimed.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.WorkingsetNodereclaim })
+				},
+			},
+			{
+				name:      "container_memory_pgscan_total",
+				help:      "Cumulative count of pages scanned by the page reclaim code.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Pgscan })
+				},
+			},
+			{
+				name:      "container_memory_pgsteal_total",
+				help:      "Cumulative count of pages reclaimed by the page reclaim code.",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return memoryStatValues(s, nil, func(b *info.MemoryStatBreakdown) uint64 { return b.Pgsteal })
+				},
+			},
+		}...)
+	}
+	if includedMetrics.Has(container.SlabMetrics) {
+		c.containerMetrics = append(c.containerMetrics, containerMetric{
+			name:        "container_memory_slab_cache_bytes",
+			help:        "Size of the dentry and inode slab caches charged to the container in bytes.",
+			valueType:   prometheus.GaugeValue,
+			extraLabels: []string{"cache"},
+			getValues: func(s *info.ContainerStats) metricValues {
+				if s.Memory.Slab == nil {
+					return nil
+				}
+				values := make(metricValues, 0, len(s.Memory.Slab.Caches))
+				for cache, size := range s.Memory.Slab.Caches {
+					values = append(values, metricValue{
+						value:     float64(size),
+						labels:    []string{cache},
+						timestamp: s.Timestamp,
+					})
+				}
+				return values
+			},
+		})
+	}
 	if includedMetrics.Has(container.AcceleratorUsageMetrics) {
 		c.containerMetrics = append(c.containerMetrics, []containerMetric{
 			{
@@ -892,6 +1562,77 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.NetworkQueueMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:        "container_network_queue_packets_total",
+				help:        "Cumulative count of packets handled by a network device queue",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "direction", "queue"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Network.Queues))
+					for _, value := range s.Network.Queues {
+						values = append(values, metricValue{
+							value:     float64(value.Packets),
+							labels:    []string{value.Interface, value.Direction, strconv.Itoa(value.Queue)},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			}, {
+				name:        "container_network_queue_bytes_total",
+				help:        "Cumulative count of bytes handled by a network device queue",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "direction", "queue"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Network.Queues))
+					for _, value := range s.Network.Queues {
+						values = append(values, metricValue{
+							value:     float64(value.Bytes),
+							labels:    []string{value.Interface, value.Direction, strconv.Itoa(value.Queue)},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			}, {
+				name:        "container_network_queue_packets_dropped_total",
+				help:        "Cumulative count of packets dropped by a network device queue",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "direction", "queue"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Network.Queues))
+					for _, value := range s.Network.Queues {
+						values = append(values, metricValue{
+							value:     float64(value.Drops),
+							labels:    []string{value.Interface, value.Direction, strconv.Itoa(value.Queue)},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			}, {
+				name:        "container_network_queue_xdp_packets_total",
+				help:        "Cumulative count of packets processed by XDP on a network device queue, by XDP action",
+				valueType:   prometheus.CounterValue,
+				extraLabels: []string{"interface", "direction", "queue", "action"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Network.Queues))
+					for _, value := range s.Network.Queues {
+						for action, count := range value.Xdp {
+							values = append(values, metricValue{
+								value:     float64(count),
+								labels:    []string{value.Interface, value.Direction, strconv.Itoa(value.Queue), action},
+								timestamp: s.Timestamp,
+							})
+						}
+					}
+					return values
+				},
+			},
+		}...)
+	}
 	if includedMetrics.Has(container.NetworkTcpUsageMetrics) {
 		c.containerMetrics = append(c.containerMetrics, []containerMetric{
 			{
@@ -1523,6 +2264,30 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					return metricValues{{value: float64(s.Processes.FdCount), timestamp: s.Timestamp}}
 				},
 			},
+			{
+				name:      "container_file_descriptors_process_max",
+				help:      "Largest number of open file descriptors of a single process of the container.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.MaxProcessFdCount), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_inotify_instances",
+				help:      "Number of inotify instances of the container.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.InotifyInstances), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_inotify_watches",
+				help:      "Number of inotify watches of the container.",
+				valueType: prometheus.GaugeValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					return metricValues{{value: float64(s.Processes.InotifyWatches), timestamp: s.Timestamp}}
+				},
+			},
 			{
 				name:      "container_sockets",
 				help:      "Number of open sockets for the container.",
@@ -1574,6 +2339,23 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 					return values
 				},
 			},
+			{
+				name:        "container_ulimits_hard",
+				help:        "Hard ulimit values for the container root process. Unlimited if -1, except priority and nice",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"ulimit"},
+				getValues: func(s *info.ContainerStats) metricValues {
+					values := make(metricValues, 0, len(s.Processes.Ulimits))
+					for _, ulimit := range s.Processes.Ulimits {
+						values = append(values, metricValue{
+							value:     float64(ulimit.HardLimit),
+							labels:    []string{ulimit.Name},
+							timestamp: s.Timestamp,
+						})
+					}
+					return values
+				},
+			},
 		}...)
 	}
 	if includedMetrics.Has(container.PerfMetrics) {
@@ -1725,6 +2507,32 @@ func NewPrometheusCollector(i infoProvider, f ContainerLabelsFunc, includedMetri
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.GvisorMetrics) {
+		c.containerMetrics = append(c.containerMetrics, []containerMetric{
+			{
+				name:      "container_gvisor_syscalls_total",
+				help:      "Cumulative count of system calls intercepted by the gVisor Sentry",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Gvisor == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Gvisor.Syscalls), timestamp: s.Timestamp}}
+				},
+			},
+			{
+				name:      "container_gvisor_platform_switches_total",
+				help:      "Cumulative count of switches between the application and the gVisor Sentry",
+				valueType: prometheus.CounterValue,
+				getValues: func(s *info.ContainerStats) metricValues {
+					if s.Gvisor == nil {
+						return nil
+					}
+					return metricValues{{value: float64(s.Gvisor.PlatformSwitches), timestamp: s.Timestamp}}
+				},
+			},
+		}...)
+	}
 	return c
 }
 
@@ -1736,6 +2544,19 @@ var (
 	cpuSharesDesc   = prometheus.NewDesc("container_spec_cpu_shares", "CPU share of the container.", nil, nil)
 )
 
+// SetOpenMetricsOptions enables the exemplars and created timestamps of
+// counters. They are only exposed when the metrics are encoded in the
+// OpenMetrics format, see WriteOpenMetrics.
+func (c *PrometheusCollector) SetOpenMetricsOptions(opts OpenMetricsOptions) {
+	c.openMetrics = opts
+}
+
+// SetAppMetricsOnly restricts the exported metrics to the application metrics
+// of the containers, skipping their resource usage and spec.
+func (c *PrometheusCollector) SetAppMetricsOnly() {
+	c.appMetricsOnly = true
+}
+
 // Describe describes all the metrics ever exported by cadvisor. It
 // implements prometheus.PrometheusCollector.
 func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -1754,7 +2575,9 @@ func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
 // Prometheus metrics. It implements prometheus.PrometheusCollector.
 func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
 	c.errors.Set(0)
-	c.collectVersionInfo(ch)
+	if !c.appMetricsOnly {
+		c.collectVersionInfo(ch)
+	}
 	c.collectContainersInfo(ch)
 	c.errors.Collect(ch)
 }
@@ -1770,6 +2593,12 @@ const (
 	LabelName = "name"
 	// LabelImage is the name of the image label.
 	LabelImage = "image"
+	// LabelContinuityID is the name of the label identifying a container
+	// across restores from checkpoints.
+	LabelContinuityID = "continuity_id"
+	// LabelIdentity is the name of the label identifying a container across
+	// restarts.
+	LabelIdentity = "identity"
 )
 
 // DefaultContainerLabels implements ContainerLabelsFunc. It exports the
@@ -1817,6 +2646,9 @@ func BaseContainerLabels(whiteList []string) func(container *info.ContainerInfo)
 	}
 }
 
+// Health check statuses reported by container_health_status.
+var healthStatuses = []string{"starting", "healthy", "unhealthy"}
+
 func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric) {
 	containers, err := c.infoProvider.GetRequestedContainersInfo("/", c.opts)
 	if err != nil {
@@ -1830,8 +2662,17 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 			rawLabels[l] = struct{}{}
 		}
 	}
+	var derivedStats map[string]v2.DerivedStats
+	if p, ok := c.infoProvider.(derivedStatsProvider); ok && !c.appMetricsOnly && c.includedMetrics.Has(container.CpuUsageQuantileMetrics) {
+		// The stats of the other containers are returned when some are
+		// missing, e.g. for containers without cpu and memory.
+		derivedStats, err = p.GetDerivedStats("/", c.opts)
+		if err != nil {
+			klog.V(4).Infof("Couldn't get the derived stats of some containers: %s", err)
+		}
+	}
 
-	for _, cont := range containers {
+	for name, cont := range containers {
 		values := make([]string, 0, len(rawLabels))
 		labels := make([]string, 0, len(rawLabels))
 		containerLabels := c.containerLabelsFunc(cont)
@@ -1850,6 +2691,13 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 			}
 		}
 
+		if c.appMetricsOnly {
+			if len(cont.Stats) > 0 && c.includedMetrics.Has(container.AppMetrics) {
+				c.collectAppMetrics(ch, cont, cont.Stats[0], labels, values)
+			}
+			continue
+		}
+
 		// Container spec
 		desc := prometheus.NewDesc("container_start_time_seconds", "Start time of the container since unix epoch in seconds.", labels, nil)
 		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.CreationTime.Unix()), values...)
@@ -1873,39 +2721,114 @@ func (c *PrometheusCollector) collectContainersInfo(ch chan<- prometheus.Metric)
 			desc = prometheus.NewDesc("container_spec_memory_reservation_limit_bytes", "Memory reservation limit for the container.", labels, nil)
 			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, specMemoryValue(cont.Spec.Memory.Reservation), values...)
 		}
+		if cont.Spec.State != nil {
+			desc := prometheus.NewDesc("container_restart_count", "Number of times the container was restarted by its runtime.", labels, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(cont.Spec.State.RestartCount), values...)
+			if health := cont.Spec.State.Health; health != nil {
+				desc = prometheus.NewDesc("container_health_status", "Health check status of the container, 1 for the current status.", append(labels, "status"), nil)
+				for _, status := range healthStatuses {
+					value := 0.0
+					if status == health.Status {
+						value = 1
+					}
+					ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, append(values, status)...)
+				}
+			}
+		}
+
+		if d, ok := derivedStats[name]; ok {
+			collectCpuUsageQuantiles(ch, d, labels, values)
+		}
 
 		// Now for the actual metrics
 		if len(cont.Stats) == 0 {
 			continue
 		}
 		stats := cont.Stats[0]
+		var traceID string
+		if c.openMetrics.ExemplarLabel != "" {
+			traceID = cont.Spec.Labels[c.openMetrics.ExemplarLabel]
+		}
 		for _, cm := range c.containerMetrics {
 			if cm.condition != nil && !cm.condition(cont.Spec) {
 				continue
 			}
 			desc := cm.desc(labels)
 			for _, metricValue := range cm.getValues(stats) {
-				ch <- prometheus.NewMetricWithTimestamp(
+				metric := prometheus.NewMetricWithTimestamp(
 					metricValue.timestamp,
 					prometheus.MustNewConstMetric(desc, cm.valueType, float64(metricValue.value), append(values, metricValue.labels...)...),
 				)
+				if cm.valueType == prometheus.CounterValue {
+					metric = newOpenMetricsCounter(metric, c.openMetrics, traceID, metricValue, cont.Spec.CreationTime)
+				}
+				ch <- metric
 			}
 		}
 		if c.includedMetrics.Has(container.AppMetrics) {
-			for metricLabel, v := range stats.CustomMetrics {
-				for _, metric := range v {
-					clabels := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					cvalues := make([]string, len(rawLabels), len(rawLabels)+len(metric.Labels))
-					copy(clabels, labels)
-					copy(cvalues, values)
-					for label, value := range metric.Labels {
-						clabels = append(clabels, sanitizeLabelName("app_"+label))
-						cvalues = append(cvalues, value)
+			c.collectAppMetrics(ch, cont, stats, labels, values)
+		}
+	}
+}
+
+// collectAppMetrics exports the application metrics of a container, with the
+// given container labels.
+func (c *PrometheusCollector) collectAppMetrics(ch chan<- prometheus.Metric, cont *info.ContainerInfo, stats *info.ContainerStats, labels, values []string) {
+	honorLabels := map[string]bool{}
+	for _, spec := range cont.Spec.CustomMetrics {
+		honorLabels[spec.Name] = spec.HonorLabels
+	}
+	for metricLabel, v := range stats.CustomMetrics {
+		for _, metric := range v {
+			clabels := make([]string, len(labels), len(labels)+len(metric.Labels))
+			cvalues := make([]string, len(labels), len(labels)+len(metric.Labels))
+			copy(clabels, labels)
+			copy(cvalues, values)
+		appLabels:
+			for label, value := range metric.Labels {
+				if !honorLabels[metricLabel] {
+					clabels = append(clabels, sanitizeLabelName("app_"+label))
+					cvalues = append(cvalues, value)
+					continue
+				}
+				// Scraped labels override container labels of the same name.
+				label = sanitizeLabelName(label)
+				for i := range labels {
+					if clabels[i] == label {
+						cvalues[i] = value
+						continue appLabels
 					}
-					desc := prometheus.NewDesc(metricLabel, "Custom application metric.", clabels, nil)
-					ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(metric.FloatValue), cvalues...)
 				}
+				clabels = append(clabels, label)
+				cvalues = append(cvalues, value)
 			}
+			desc := prometheus.NewDesc(metricLabel, "Custom application metric.", clabels, nil)
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(metric.FloatValue), cvalues...)
+		}
+	}
+}
+
+// collectCpuUsageQuantiles exports the quantiles of the CPU usage rate of a
+// container over the windows of its derived stats, with the given container
+// labels.
+func collectCpuUsageQuantiles(ch chan<- prometheus.Metric, d v2.DerivedStats, labels, values []string) {
+	desc := prometheus.NewDesc("container_cpu_usage_quantile", "Quantile of the CPU usage rate of the container over a window, in cores.", append(labels, "quantile", "window"), nil)
+	windows := map[string]v2.Usage{"1m": d.MinuteUsage, "1h": d.HourUsage, "1d": d.DayUsage}
+	for w, usage := range d.Windows {
+		windows[w] = usage
+	}
+	for w, usage := range windows {
+		p := usage.Cpu
+		if !p.Present {
+			continue
+		}
+		quantiles := map[string]uint64{"0.5": p.Fifty, "0.9": p.Ninety, "0.95": p.NinetyFive, "0.99": p.NinetyNine, "0.999": p.NinetyNineNine}
+		for q, v := range p.Quantiles {
+			quantiles[q] = v
+		}
+		for q, v := range quantiles {
+			// Derived stats are in milliCPUs.
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v)/1000, append(values, q, w)...)
 		}
 	}
 }
@@ -1939,6 +2862,20 @@ func sanitizeLabelName(name string) string {
 	return invalidNameCharRE.ReplaceAllString(name, "_")
 }
 
+func memoryEventsValues(events *info.MemoryEvents, scope string, timestamp time.Time) metricValues {
+	if events == nil {
+		return nil
+	}
+	return metricValues{
+		{value: float64(events.Low), labels: []string{"low", scope}, timestamp: timestamp},
+		{value: float64(events.High), labels: []string{"high", scope}, timestamp: timestamp},
+		{value: float64(events.Max), labels: []string{"max", scope}, timestamp: timestamp},
+		{value: float64(events.Oom), labels: []string{"oom", scope}, timestamp: timestamp},
+		{value: float64(events.OomKill), labels: []string{"oom_kill", scope}, timestamp: timestamp},
+		{value: float64(events.OomGroupKill), labels: []string{"oom_group_kill", scope}, timestamp: timestamp},
+	}
+}
+
 func getNumaStatsPerNode(nodeStats map[uint8]uint64, labels []string, timestamp time.Time) metricValues {
 	mValues := make(metricValues, 0, len(nodeStats))
 	for node, stat := range nodeStats {
@@ -1948,6 +2885,75 @@ func getNumaStatsPerNode(nodeStats map[uint8]uint64, labels []string, timestamp
 	return mValues
 }
 
+// coreThrottleValues returns one value per CPU of the host.
+func coreThrottleValues(s *info.ContainerStats, value func(info.CoreThrottleStats) float64) metricValues {
+	if s.ThermalThrottle == nil {
+		return nil
+	}
+	values := make(metricValues, 0, len(s.ThermalThrottle.Cores))
+	for _, core := range s.ThermalThrottle.Cores {
+		values = append(values, metricValue{
+			value:     value(core),
+			labels:    []string{fmt.Sprintf("cpu%02d", core.Cpu)},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
+// packageThrottleValues returns one value per CPU package of the host.
+func packageThrottleValues(s *info.ContainerStats, value func(info.PackageThrottleStats) float64) metricValues {
+	if s.ThermalThrottle == nil {
+		return nil
+	}
+	values := make(metricValues, 0, len(s.ThermalThrottle.Packages))
+	for _, pkg := range s.ThermalThrottle.Packages {
+		values = append(values, metricValue{
+			value:     value(pkg),
+			labels:    []string{strconv.Itoa(pkg.Package)},
+			timestamp: s.Timestamp,
+		})
+	}
+	return values
+}
+
+// cpuFreqValues returns one value per CPU of the host, converted from kHz.
+// CPUs for which the value is unknown are skipped.
+func cpuFreqValues(s *info.ContainerStats, value func(info.CpuFreq) uint64) metricValues {
+	if s.CpuFreq == nil {
+		return nil
+	}
+	values := make(metricValues, 0, len(s.CpuFreq.PerCpu))
+	for _, freq := range s.CpuFreq.PerCpu {
+		if v := value(freq); v > 0 {
+			values = append(values, metricValue{
+				value:     float64(v) * 1000,
+				labels:    []string{fmt.Sprintf("cpu%02d", freq.Cpu)},
+				timestamp: s.Timestamp,
+			})
+		}
+	}
+	return values
+}
+
+// memoryStatValues returns a value of the memory.stat breakdown for each of
+// valueFns, labelled with the matching entry of labels if set. It returns no
+// values when the breakdown was not collected.
+func memoryStatValues(s *info.ContainerStats, labels []string, valueFns ...func(*info.MemoryStatBreakdown) uint64) metricValues {
+	if s.Memory.Breakdown == nil {
+		return nil
+	}
+	values := make(metricValues, 0, len(valueFns))
+	for i, valueFn := range valueFns {
+		value := metricValue{value: float64(valueFn(s.Memory.Breakdown)), timestamp: s.Timestamp}
+		if labels != nil {
+			value.labels = []string{labels[i]}
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
 func getPerCPUCorePerfEvents(s *info.ContainerStats) metricValues {
 	values := make(metricValues, 0, len(s.PerfStats))
 	for _, metric := range s.PerfStats {
@@ -52,6 +52,18 @@ func (p testSubcontainersInfoProvider) GetMachineInfo() (*info.MachineInfo, erro
 		MachineID:  "machine-id-test",
 		SystemUUID: "system-uuid-test",
 		BootID:     "boot-id-test",
+		DiskMap: map[string]info.DiskInfo{
+			"8:0":   {Name: "sda", Major: 8, Minor: 0},
+			"253:0": {Name: "dm-0", Major: 253, Minor: 0, DmName: "vg0-root", DmType: "LVM", Slaves: []string{"sda2"}, PhysicalDisks: []string{"sda"}},
+		},
+		Hardware: &info.HardwareInfo{
+			SystemVendor: "Dell Inc.",
+			ProductName:  "PowerEdge R640",
+			SerialNumber: "7XJ4K13",
+			BIOSVendor:   "Dell Inc.",
+			BIOSVersion:  "2.10.2",
+			BIOSDate:     "02/24/2021",
+		},
 		Topology: []info.Node{
 			{
 				Id:     0,
@@ -290,6 +302,15 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 				Processes: info.ProcessSpec{
 					Limit: 100,
 				},
+				HasCustomMetrics: true,
+				CustomMetrics: []info.MetricSpec{
+					{
+						Name:        "container_custom_app_metric_3",
+						Type:        info.MetricGauge,
+						Format:      info.FloatType,
+						HonorLabels: true,
+					},
+				},
 				CreationTime: time.Unix(1257894000, 0),
 				Labels: map[string]string{
 					"foo.label": "bar",
@@ -297,6 +318,13 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 				Envs: map[string]string{
 					"foo+env": "prod",
 				},
+				State: &info.ContainerState{
+					RestartCount: 3,
+					Health: &info.ContainerHealth{
+						Status:        "healthy",
+						FailingStreak: 0,
+					},
+				},
 			},
 			Stats: []*info.ContainerStats{
 				{
@@ -307,17 +335,22 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							PerCpu: []uint64{2, 3, 4, 5},
 							User:   6,
 							System: 7,
+							Guest:  8,
+							Steal:  9,
 						},
 						CFS: info.CpuCFS{
 							Periods:          723,
 							ThrottledPeriods: 18,
 							ThrottledTime:    1724314000,
+							Bursts:           4,
+							BurstTime:        125000000,
 						},
 						Schedstat: info.CpuSchedstat{
 							RunTime:      53643567,
 							RunqueueTime: 479424566378,
 							RunPeriods:   984285,
 						},
+						WaitTime:    2375000000,
 						LoadAverage: 2,
 					},
 					Memory: info.MemoryStats{
@@ -346,6 +379,51 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						RSS:        15,
 						MappedFile: 16,
 						Swap:       8192,
+						Events: &info.MemoryEvents{
+							Low:          20,
+							High:         21,
+							Max:          22,
+							Oom:          23,
+							OomKill:      24,
+							OomGroupKill: 25,
+						},
+						LocalEvents: &info.MemoryEvents{
+							High:    17,
+							Oom:     18,
+							OomKill: 19,
+						},
+						Slab: &info.MemorySlabStats{
+							Caches: map[string]uint64{"dentry": 262144, "inode": 622592},
+						},
+						Breakdown: &info.MemoryStatBreakdown{
+							Anon:                   100,
+							File:                   101,
+							KernelStack:            102,
+							Pagetables:             103,
+							Percpu:                 104,
+							Sock:                   105,
+							Shmem:                  106,
+							FileMapped:             107,
+							FileDirty:              108,
+							FileWriteback:          109,
+							AnonThp:                110,
+							InactiveAnon:           111,
+							ActiveAnon:             112,
+							InactiveFile:           113,
+							ActiveFile:             114,
+							Unevictable:            115,
+							SlabReclaimable:        116,
+							SlabUnreclaimable:      117,
+							WorkingsetRefaultAnon:  118,
+							WorkingsetRefaultFile:  119,
+							WorkingsetActivateAnon: 120,
+							WorkingsetActivateFile: 121,
+							WorkingsetRestoreAnon:  122,
+							WorkingsetRestoreFile:  123,
+							WorkingsetNodereclaim:  124,
+							Pgscan:                 125,
+							Pgsteal:                126,
+						},
 					},
 					Hugetlb: map[string]info.HugetlbStats{
 						"2Mi": {
@@ -384,6 +462,17 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 								TxDropped: 21,
 							},
 						},
+						Queues: []info.InterfaceQueueStats{
+							{
+								Interface: "eth0",
+								Direction: "rx",
+								Queue:     0,
+								Packets:   15,
+								Bytes:     14,
+								Drops:     17,
+								Xdp:       map[string]uint64{"drop": 3},
+							},
+						},
 						Tcp: info.TcpStat{
 							Established: 13,
 							SynSent:     0,
@@ -527,6 +616,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 					Filesystem: []info.FsStats{
 						{
 							Device:          "sda1",
+							Mountpoint:      "/",
 							InodesFree:      524288,
 							Inodes:          2097152,
 							Limit:           22,
@@ -545,6 +635,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						},
 						{
 							Device:          "sda2",
+							Mountpoint:      "/data",
 							InodesFree:      262144,
 							Inodes:          2097152,
 							Limit:           37,
@@ -581,11 +672,14 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						},
 					},
 					Processes: info.ProcessStats{
-						ProcessCount:   1,
-						FdCount:        5,
-						SocketCount:    3,
-						ThreadsCurrent: 5,
-						ThreadsMax:     100,
+						ProcessCount:      1,
+						FdCount:           5,
+						MaxProcessFdCount: 5,
+						SocketCount:       3,
+						InotifyInstances:  1,
+						InotifyWatches:    12,
+						ThreadsCurrent:    5,
+						ThreadsMax:        100,
 						Ulimits: []info.UlimitSpec{
 							{
 								Name:      "max_open_files",
@@ -600,6 +694,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 						NrStopped:         52,
 						NrUninterruptible: 53,
 						NrIoWait:          54,
+						NrZombie:          55,
 					},
 					CustomMetrics: map[string][]info.MetricVal{
 						"container_custom_app_metric_1": {
@@ -629,7 +724,7 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 								FloatValue: float64(3),
 								Timestamp:  time.Now(),
 								Label:      "testlabel3",
-								Labels:     map[string]string{"test_label": "test_value"},
+								Labels:     map[string]string{"test_label": "test_value", "image": "app"},
 							},
 						},
 					},
@@ -708,6 +803,104 @@ func (p testSubcontainersInfoProvider) GetRequestedContainersInfo(string, v2.Req
 							},
 						},
 					},
+					Gvisor: &info.GvisorStats{
+						Syscalls:         43245,
+						PlatformSwitches: 12345,
+					},
+					CpuFreq: &info.CpuFreqStats{
+						PerCpu: []info.CpuFreq{
+							{Cpu: 0, Current: 2800000, ScalingMin: 800000, ScalingMax: 3500000, HardwareMax: 4700000, Base: 2100000, Effective: 3150000},
+							{Cpu: 1, Current: 1200000, ScalingMin: 800000, ScalingMax: 3500000, HardwareMax: 4700000},
+						},
+						IntelPstate: &info.IntelPstateStats{
+							Status:     "active",
+							NoTurbo:    true,
+							MinPerfPct: 17,
+							MaxPerfPct: 80,
+						},
+					},
+					Energy: &info.EnergyStats{
+						Domains: []info.EnergyDomainStats{
+							{Socket: 0, Domain: "dram", Energy: 1500000},
+							{Socket: 0, Domain: "package", Energy: 42000000},
+						},
+						CpuTime:   123000000,
+						Estimated: 43500000,
+					},
+					Thermal: &info.ThermalStats{
+						Zones: []info.ThermalZoneStats{
+							{
+								Zone:        "thermal_zone0",
+								Type:        "x86_pkg_temp",
+								Temperature: 54000,
+								TripPoints: []info.ThermalTripPoint{
+									{Index: 0, Type: "passive", Temperature: 95000},
+								},
+							},
+						},
+						Fans: []info.FanStats{
+							{Device: "hwmon1", Chip: "nct6775", Fan: "CPU Fan", Speed: 1250},
+						},
+					},
+					ThermalThrottle: &info.ThermalThrottleStats{
+						Cores: []info.CoreThrottleStats{
+							{Cpu: 0, Count: 3, Time: 50000000},
+							{Cpu: 1, Count: 13, Time: 150000000},
+						},
+						Packages: []info.PackageThrottleStats{
+							{Package: 0, Count: 7, Time: 1200000000},
+						},
+					},
+					MdArrays: []info.MdArrayStats{
+						{
+							Name:          "md0",
+							Level:         "raid5",
+							State:         "active",
+							Disks:         4,
+							Degraded:      1,
+							Members:       []info.MdMember{{Device: "sdd1", Faulty: true}, {Device: "sdc1"}, {Device: "sdb1"}, {Device: "sda1"}},
+							SyncAction:    "recover",
+							SyncCompleted: 0.25,
+							SyncSpeed:     22400,
+						},
+						{Name: "md1", Level: "raid1", State: "clean", Disks: 2, SyncAction: "idle"},
+					},
+					StorageSessions: []info.StorageSessionStats{
+						{
+							Name:           "session1",
+							Transport:      "iscsi",
+							Target:         "iqn.2021-01.com.example:storage.lun1",
+							Address:        "10.0.0.1:3260",
+							State:          "LOGGED_IN",
+							Connected:      true,
+							Paths:          2,
+							ConnectedPaths: 1,
+							Devices:        []string{"sdb"},
+						},
+						{
+							Name:           "session2",
+							Transport:      "iscsi",
+							Target:         "iqn.2021-01.com.example:storage.lun1",
+							Address:        "10.0.0.2:3260",
+							State:          "FAILED",
+							Paths:          2,
+							ConnectedPaths: 1,
+							Reconnects:     3,
+							Devices:        []string{"sdc"},
+						},
+						{
+							Name:           "nvme1",
+							Transport:      "nvme-tcp",
+							Target:         "nqn.2014-08.org.nvmexpress:uuid:0c9a4a5e",
+							Address:        "traddr=10.0.1.1,trsvcid=4420",
+							State:          "live",
+							Connected:      true,
+							Paths:          1,
+							ConnectedPaths: 1,
+							Reconnects:     1,
+							Devices:        []string{"nvme1n1"},
+						},
+					},
 				},
 			},
 		},
@@ -19,6 +19,7 @@ import (
 
 	"github.com/google/cadvisor/container"
 	info "github.com/google/cadvisor/info/v1"
+	v2 "github.com/google/cadvisor/info/v2"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"k8s.io/klog/v2"
@@ -59,11 +60,29 @@ func (metric *machineMetric) desc(baseLabels []string) *prometheus.Desc {
 	return prometheus.NewDesc(metric.name, metric.help, append(baseLabels, metric.extraLabels...), nil)
 }
 
+// machineFsMetric describes a metric of the filesystems of the machine,
+// labeled by device and mount point.
+type machineFsMetric struct {
+	name      string
+	help      string
+	getValue  func(fs *info.FsStats) float64
+	condition func(fs *info.FsStats) bool
+}
+
+var machineFsLabelNames = append(append([]string{}, baseLabelsNames...), "device", "mountpoint")
+
+func (metric *machineFsMetric) desc() *prometheus.Desc {
+	return prometheus.NewDesc(metric.name, metric.help, machineFsLabelNames, nil)
+}
+
 // PrometheusMachineCollector implements prometheus.Collector.
 type PrometheusMachineCollector struct {
 	infoProvider   infoProvider
 	errors         prometheus.Gauge
 	machineMetrics []machineMetric
+	// Metrics of the filesystems of the root container, empty if disk
+	// usage metrics are disabled.
+	fsMetrics []machineFsMetric
 }
 
 // NewPrometheusMachineCollector returns a new PrometheusCollector.
@@ -149,6 +168,36 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 					return metricValues{{value: float64(machineInfo.NVMInfo.AvgPowerBudget), timestamp: machineInfo.Timestamp}}
 				},
 			},
+			{
+				name:        "machine_hardware_info",
+				help:        "A metric with a constant '1' value labeled by the hardware inventory of the machine.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"system_vendor", "product_name", "serial_number", "bios_vendor", "bios_version", "bios_date"},
+				condition:   func(machineInfo *info.MachineInfo) bool { return machineInfo.Hardware != nil },
+				getValues: func(machineInfo *info.MachineInfo) metricValues {
+					hardware := machineInfo.Hardware
+					return metricValues{{
+						value:     1,
+						labels:    []string{hardware.SystemVendor, hardware.ProductName, hardware.SerialNumber, hardware.BIOSVendor, hardware.BIOSVersion, hardware.BIOSDate},
+						timestamp: machineInfo.Timestamp,
+					}}
+				},
+			},
+			{
+				name:        "machine_disk_stack_info",
+				help:        "A metric with a constant '1' value mapping stacked block devices (e.g. LVM volumes, LUKS or multipath devices) to the physical disks they are built on.",
+				valueType:   prometheus.GaugeValue,
+				extraLabels: []string{"device", "physical_device"},
+				condition: func(machineInfo *info.MachineInfo) bool {
+					for _, disk := range machineInfo.DiskMap {
+						if len(disk.PhysicalDisks) > 0 {
+							return true
+						}
+					}
+					return false
+				},
+				getValues: getDiskStack,
+			},
 		},
 	}
 
@@ -192,6 +241,34 @@ func NewPrometheusMachineCollector(i infoProvider, includedMetrics container.Met
 			},
 		}...)
 	}
+	if includedMetrics.Has(container.DiskUsageMetrics) {
+		hasInodes := func(fs *info.FsStats) bool { return fs.HasInodes }
+		c.fsMetrics = []machineFsMetric{
+			{
+				name:     "machine_fs_capacity_bytes",
+				help:     "Number of bytes of a filesystem of the machine.",
+				getValue: func(fs *info.FsStats) float64 { return float64(fs.Limit) },
+			}, {
+				name:     "machine_fs_usage_bytes",
+				help:     "Number of bytes used on a filesystem of the machine.",
+				getValue: func(fs *info.FsStats) float64 { return float64(fs.Usage) },
+			}, {
+				name:     "machine_fs_available_bytes",
+				help:     "Number of bytes available to non-root users on a filesystem of the machine.",
+				getValue: func(fs *info.FsStats) float64 { return float64(fs.Available) },
+			}, {
+				name:      "machine_fs_inodes",
+				help:      "Number of inodes of a filesystem of the machine.",
+				getValue:  func(fs *info.FsStats) float64 { return float64(fs.Inodes) },
+				condition: hasInodes,
+			}, {
+				name:      "machine_fs_inodes_free",
+				help:      "Number of free inodes of a filesystem of the machine.",
+				getValue:  func(fs *info.FsStats) float64 { return float64(fs.InodesFree) },
+				condition: hasInodes,
+			},
+		}
+	}
 	return c
 }
 
@@ -202,6 +279,9 @@ func (collector *PrometheusMachineCollector) Describe(ch chan<- *prometheus.Desc
 	for _, metric := range collector.machineMetrics {
 		ch <- metric.desc([]string{})
 	}
+	for _, metric := range collector.fsMetrics {
+		ch <- metric.desc()
+	}
 }
 
 // Collect fetches information about machine and delivers them as
@@ -245,6 +325,55 @@ func (collector *PrometheusMachineCollector) collectMachineInfo(ch chan<- promet
 		}
 
 	}
+
+	if len(collector.fsMetrics) > 0 {
+		collector.collectFsStats(ch, baseLabelsValues)
+	}
+}
+
+// collectFsStats exports the most recent filesystem stats of the root
+// container, including the filesystems mounted at --extra_fs_mounts.
+func (collector *PrometheusMachineCollector) collectFsStats(ch chan<- prometheus.Metric, baseLabelsValues []string) {
+	containers, err := collector.infoProvider.GetRequestedContainersInfo("/", v2.RequestOptions{
+		IdType: v2.TypeName,
+		Count:  1,
+	})
+	if err != nil {
+		collector.errors.Set(1)
+		klog.Warningf("Couldn't get filesystem stats of the machine: %s", err)
+		return
+	}
+	for _, cont := range containers {
+		if len(cont.Stats) == 0 {
+			continue
+		}
+		stats := cont.Stats[len(cont.Stats)-1]
+		for i := range stats.Filesystem {
+			fs := &stats.Filesystem[i]
+			labelValues := append(append([]string{}, baseLabelsValues...), fs.Device, fs.Mountpoint)
+			for _, metric := range collector.fsMetrics {
+				if metric.condition != nil && !metric.condition(fs) {
+					continue
+				}
+				ch <- prometheus.NewMetricWithTimestamp(stats.Timestamp,
+					prometheus.MustNewConstMetric(metric.desc(), prometheus.GaugeValue, metric.getValue(fs), labelValues...))
+			}
+		}
+	}
+}
+
+func getDiskStack(machineInfo *info.MachineInfo) metricValues {
+	var values metricValues
+	for _, disk := range machineInfo.DiskMap {
+		for _, physicalDisk := range disk.PhysicalDisks {
+			values = append(values, metricValue{
+				value:     1,
+				labels:    []string{"/dev/" + disk.Name, "/dev/" + physicalDisk},
+				timestamp: machineInfo.Timestamp,
+			})
+		}
+	}
+	return values
 }
 
 func getMemoryByType(machineInfo *info.MachineInfo, property string) metricValues {
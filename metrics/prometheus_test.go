@@ -17,6 +17,7 @@ package metrics
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,6 +60,19 @@ func TestPrometheusCollectorWithPerfAggregated(t *testing.T) {
 	testPrometheusCollector(t, reg, "testdata/prometheus_metrics_perf_aggregated")
 }
 
+func TestPrometheusCollectorAppMetricsOnly(t *testing.T) {
+	c := NewPrometheusCollector(testSubcontainersInfoProvider{}, func(container *info.ContainerInfo) map[string]string {
+		s := DefaultContainerLabels(container)
+		s["zone.name"] = "hello"
+		return s
+	}, container.AllMetrics, now, v2.RequestOptions{})
+	c.SetAppMetricsOnly()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	testPrometheusCollector(t, reg, "testdata/prometheus_metrics_app")
+}
+
 func testPrometheusCollector(t *testing.T, gatherer prometheus.Gatherer, metricsFile string) {
 	wantMetrics, err := os.Open(metricsFile)
 	if err != nil {
@@ -71,6 +85,48 @@ func testPrometheusCollector(t *testing.T, gatherer prometheus.Gatherer, metrics
 	}
 }
 
+type derivedStatsInfoProvider struct {
+	testSubcontainersInfoProvider
+}
+
+func (p derivedStatsInfoProvider) GetDerivedStats(string, v2.RequestOptions) (map[string]v2.DerivedStats, error) {
+	return map[string]v2.DerivedStats{
+		"testcontainer": {
+			MinuteUsage: v2.Usage{
+				Cpu: v2.Percentiles{Present: true, Fifty: 100, Ninety: 200, NinetyFive: 300, NinetyNine: 400, NinetyNineNine: 500},
+			},
+			Windows: map[string]v2.Usage{
+				"5m": {Cpu: v2.Percentiles{Present: true, Fifty: 1000, Ninety: 1000, NinetyFive: 1000, NinetyNine: 1500, NinetyNineNine: 2000, Quantiles: map[string]uint64{"0.75": 1000}}},
+			},
+		},
+	}, nil
+}
+
+func TestPrometheusCollectorCpuUsageQuantiles(t *testing.T) {
+	c := NewPrometheusCollector(derivedStatsInfoProvider{}, func(container *info.ContainerInfo) map[string]string {
+		return map[string]string{LabelID: container.Name}
+	}, container.MetricSet{container.CpuUsageQuantileMetrics: struct{}{}}, now, v2.RequestOptions{})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+
+	err := testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP container_cpu_usage_quantile Quantile of the CPU usage rate of the container over a window, in cores.
+# TYPE container_cpu_usage_quantile gauge
+container_cpu_usage_quantile{id="testcontainer",quantile="0.5",window="1m"} 0.1
+container_cpu_usage_quantile{id="testcontainer",quantile="0.9",window="1m"} 0.2
+container_cpu_usage_quantile{id="testcontainer",quantile="0.95",window="1m"} 0.3
+container_cpu_usage_quantile{id="testcontainer",quantile="0.99",window="1m"} 0.4
+container_cpu_usage_quantile{id="testcontainer",quantile="0.999",window="1m"} 0.5
+container_cpu_usage_quantile{id="testcontainer",quantile="0.5",window="5m"} 1
+container_cpu_usage_quantile{id="testcontainer",quantile="0.75",window="5m"} 1
+container_cpu_usage_quantile{id="testcontainer",quantile="0.9",window="5m"} 1
+container_cpu_usage_quantile{id="testcontainer",quantile="0.95",window="5m"} 1
+container_cpu_usage_quantile{id="testcontainer",quantile="0.99",window="5m"} 1.5
+container_cpu_usage_quantile{id="testcontainer",quantile="0.999",window="5m"} 2
+`), "container_cpu_usage_quantile")
+	assert.NoError(t, err)
+}
+
 func TestPrometheusCollector_scrapeFailure(t *testing.T) {
 	provider := &erroringSubcontainersInfoProvider{
 		successfulProvider: testSubcontainersInfoProvider{},
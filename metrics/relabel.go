@@ -0,0 +1,412 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+// RelabelConfig holds the rules applied to the gathered metric families
+// before they are exposed.
+type RelabelConfig struct {
+	// Regular expressions matched against the whole metric family name.
+	// Matching families are not exposed.
+	DropMetrics []string `yaml:"drop_metrics"`
+	// Labels removed from all metrics. Series which become identical once
+	// the labels are removed are merged by summing their values.
+	DropLabels []string `yaml:"drop_labels"`
+	// Labels renamed on all metrics, keyed by their original name. A renamed
+	// label replaces a label already carrying the new name.
+	RenameLabels map[string]string `yaml:"rename_labels"`
+	// Labels kept or dropped on the families matching a policy, the first
+	// matching policy applying.
+	LabelPolicies []LabelPolicy `yaml:"label_policies"`
+	// Bound on the number of series exposed, nil if unbounded.
+	SeriesBudget *SeriesBudget `yaml:"series_budget"`
+
+	dropMetrics []*regexp.Regexp
+	dropLabels  map[string]struct{}
+}
+
+// Labels dropped by default when the series exceed their budget.
+var defaultBudgetDropLabels = []string{LabelID, LabelImage}
+
+// SeriesBudget bounds the number of series exposed, e.g. on nodes whose
+// container churn would otherwise make the scrapes grow without bounds.
+type SeriesBudget struct {
+	// Maximum number of series exposed after the other rules applied.
+	// Summaries and histograms count as one series.
+	MaxSeries int `yaml:"max_series"`
+	// Low priority labels dropped from all metrics, one after the other in
+	// order, while the series exceed max_series. id and image if empty.
+	DropLabels []string `yaml:"drop_labels"`
+	// Number of series below which the dropped labels are restored, 90% of
+	// max_series if zero. Labels are kept dropped while the series are
+	// between low_water_series and max_series, so that they do not flip on
+	// every scrape.
+	LowWaterSeries int `yaml:"low_water_series"`
+
+	lock sync.Mutex
+	// Number of labels of DropLabels dropped on the last scrape.
+	dropped int
+}
+
+// LabelPolicy selects the labels of some metric families, by their names
+// before they are renamed.
+type LabelPolicy struct {
+	// Regular expression matched against the whole metric family name.
+	Metrics string `yaml:"metrics"`
+	// Labels kept on the metrics, the others being dropped. All labels are
+	// kept if empty.
+	KeepLabels []string `yaml:"keep_labels"`
+	// Labels dropped from the metrics, in addition to drop_labels.
+	DropLabels []string `yaml:"drop_labels"`
+
+	metrics    *regexp.Regexp
+	keepLabels map[string]struct{}
+	dropLabels map[string]struct{}
+}
+
+// drops returns whether the policy drops the label name.
+func (p *LabelPolicy) drops(name string) bool {
+	if _, ok := p.dropLabels[name]; ok {
+		return true
+	}
+	if len(p.keepLabels) == 0 {
+		return false
+	}
+	_, ok := p.keepLabels[name]
+	return !ok
+}
+
+func labelSet(field string, names []string) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("%s: invalid label name %q", field, name)
+		}
+		set[name] = struct{}{}
+	}
+	return set, nil
+}
+
+// ReadRelabelConfig reads and validates the relabel config stored in file.
+func ReadRelabelConfig(file string) (*RelabelConfig, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read metrics config %q: %v", file, err)
+	}
+	config, err := ParseRelabelConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics config %q: %v", file, err)
+	}
+	return config, nil
+}
+
+// ParseRelabelConfig parses and validates a YAML relabel config.
+func ParseRelabelConfig(data []byte) (*RelabelConfig, error) {
+	config := &RelabelConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+	for _, expr := range config.DropMetrics {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("drop_metrics: %v", err)
+		}
+		config.dropMetrics = append(config.dropMetrics, re)
+	}
+	var err error
+	if config.dropLabels, err = labelSet("drop_labels", config.DropLabels); err != nil {
+		return nil, err
+	}
+	for from, to := range config.RenameLabels {
+		if !model.LabelName(from).IsValid() || !model.LabelName(to).IsValid() {
+			return nil, fmt.Errorf("rename_labels: invalid label rename %q to %q", from, to)
+		}
+	}
+	for i := range config.LabelPolicies {
+		policy := &config.LabelPolicies[i]
+		if policy.Metrics == "" {
+			return nil, fmt.Errorf("label_policies: missing metrics")
+		}
+		if policy.metrics, err = regexp.Compile("^(?:" + policy.Metrics + ")$"); err != nil {
+			return nil, fmt.Errorf("label_policies: %v", err)
+		}
+		if policy.keepLabels, err = labelSet("label_policies: keep_labels", policy.KeepLabels); err != nil {
+			return nil, err
+		}
+		if policy.dropLabels, err = labelSet("label_policies: drop_labels", policy.DropLabels); err != nil {
+			return nil, err
+		}
+	}
+	if budget := config.SeriesBudget; budget != nil {
+		if budget.MaxSeries <= 0 {
+			return nil, fmt.Errorf("series_budget: max_series must be positive")
+		}
+		if len(budget.DropLabels) == 0 {
+			budget.DropLabels = defaultBudgetDropLabels
+		}
+		if _, err = labelSet("series_budget: drop_labels", budget.DropLabels); err != nil {
+			return nil, err
+		}
+		if budget.LowWaterSeries == 0 {
+			budget.LowWaterSeries = budget.MaxSeries * 9 / 10
+		}
+		if budget.LowWaterSeries < 0 || budget.LowWaterSeries >= budget.MaxSeries {
+			return nil, fmt.Errorf("series_budget: low_water_series must be positive and less than max_series")
+		}
+	}
+	return config, nil
+}
+
+// NewRelabelingGatherer returns a gatherer applying config to the metric
+// families gathered by g. A nil config returns g unchanged.
+func NewRelabelingGatherer(g prometheus.Gatherer, config *RelabelConfig) prometheus.Gatherer {
+	if config == nil {
+		return g
+	}
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		return config.apply(families), err
+	})
+}
+
+func (c *RelabelConfig) apply(families []*dto.MetricFamily) []*dto.MetricFamily {
+	result := families[:0]
+	for _, family := range families {
+		if c.dropsMetric(family.GetName()) {
+			continue
+		}
+		c.relabel(family)
+		result = append(result, family)
+	}
+	if c.SeriesBudget != nil {
+		result = c.SeriesBudget.apply(result)
+	}
+	return result
+}
+
+// apply drops the low priority labels from families until their series fit
+// in the budget, and adds metrics telling whether they did not. Labels
+// dropped on the previous scrape stay dropped until the series fall to the
+// low water mark. The id label of the raw cgroups, which have neither a name
+// nor an image, is never dropped.
+func (b *SeriesBudget) apply(families []*dto.MetricFamily) []*dto.MetricFamily {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	series := countSeries(families)
+	if series <= b.LowWaterSeries {
+		b.dropped = 0
+	}
+	raw := rawCgroupSeries(families)
+	dropped := 0
+	for _, name := range b.DropLabels {
+		if dropped >= b.dropped && countSeries(families) <= b.MaxSeries {
+			break
+		}
+		for _, family := range families {
+			dropLabel(family, name, raw)
+		}
+		dropped++
+	}
+	b.dropped = dropped
+
+	exceeded := 0.0
+	if dropped > 0 {
+		exceeded = 1
+	}
+	families = append(families,
+		gaugeFamily("cadvisor_series_budget_exceeded", "Set to 1 when the series exceeded the series budget and low priority labels were dropped.", exceeded),
+		gaugeFamily("cadvisor_series_before_budget", "Number of series before low priority labels were dropped to fit in the series budget.", float64(series)))
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+	return families
+}
+
+// rawCgroupSeries returns the series of raw cgroups, identified by their id
+// only.
+func rawCgroupSeries(families []*dto.MetricFamily) map[*dto.Metric]bool {
+	raw := map[*dto.Metric]bool{}
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			identified := false
+			for _, pair := range metric.Label {
+				if (pair.GetName() == LabelName || pair.GetName() == LabelImage) && pair.GetValue() != "" {
+					identified = true
+					break
+				}
+			}
+			if !identified {
+				raw[metric] = true
+			}
+		}
+	}
+	return raw
+}
+
+func countSeries(families []*dto.MetricFamily) int {
+	series := 0
+	for _, family := range families {
+		series += len(family.Metric)
+	}
+	return series
+}
+
+func gaugeFamily(name, help string, value float64) *dto.MetricFamily {
+	metricType := dto.MetricType_GAUGE
+	return &dto.MetricFamily{
+		Name:   &name,
+		Help:   &help,
+		Type:   &metricType,
+		Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: &value}}},
+	}
+}
+
+// dropLabel removes the label name from the metrics of family, except the id
+// of raw cgroups, keeping one of the series which end up with the same
+// labels. Counters are summed, the values of the other series cannot be.
+func dropLabel(family *dto.MetricFamily, name string, raw map[*dto.Metric]bool) {
+	dropped := false
+	for _, metric := range family.Metric {
+		if name == LabelID && raw[metric] {
+			continue
+		}
+		// The label pairs may be shared with the collector, they are not
+		// changed in place.
+		labels := make([]*dto.LabelPair, 0, len(metric.Label))
+		for _, pair := range metric.Label {
+			if pair.GetName() == name {
+				dropped = true
+				continue
+			}
+			labels = append(labels, pair)
+		}
+		metric.Label = labels
+	}
+	if dropped {
+		mergeSeries(family, mergeMetric)
+	}
+}
+
+func (c *RelabelConfig) dropsMetric(name string) bool {
+	for _, re := range c.dropMetrics {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// policy returns the label policy of the family named name, nil if none.
+func (c *RelabelConfig) policy(name string) *LabelPolicy {
+	for i := range c.LabelPolicies {
+		if c.LabelPolicies[i].metrics.MatchString(name) {
+			return &c.LabelPolicies[i]
+		}
+	}
+	return nil
+}
+
+// relabel drops and renames the labels of the metrics of family, merging
+// the series which end up with the same labels.
+func (c *RelabelConfig) relabel(family *dto.MetricFamily) {
+	policy := c.policy(family.GetName())
+	if len(c.dropLabels) == 0 && len(c.RenameLabels) == 0 && policy == nil {
+		return
+	}
+	for _, metric := range family.Metric {
+		labels := make(map[string]*dto.LabelPair, len(metric.Label))
+		for _, pair := range metric.Label {
+			name := pair.GetName()
+			if _, ok := c.dropLabels[name]; ok {
+				continue
+			}
+			if policy != nil && policy.drops(name) {
+				continue
+			}
+			if to, ok := c.RenameLabels[name]; ok {
+				labels[to] = &dto.LabelPair{Name: &to, Value: pair.Value}
+				continue
+			}
+			if _, ok := labels[name]; !ok {
+				labels[name] = pair
+			}
+		}
+		// The label pairs may be shared with the collector, they are not
+		// changed in place.
+		metric.Label = make([]*dto.LabelPair, 0, len(labels))
+		for _, pair := range labels {
+			metric.Label = append(metric.Label, pair)
+		}
+		sort.Slice(metric.Label, func(i, j int) bool {
+			return metric.Label[i].GetName() < metric.Label[j].GetName()
+		})
+	}
+	mergeSeries(family, mergeMetric)
+}
+
+// mergeSeries merges the series of family with the same labels, which must
+// be sorted by name, with merge.
+func mergeSeries(family *dto.MetricFamily, merge func(dst, src *dto.Metric)) {
+	seen := make(map[string]*dto.Metric, len(family.Metric))
+	metrics := family.Metric[:0]
+	for _, metric := range family.Metric {
+		key := labelsKey(metric.Label)
+		if existing, ok := seen[key]; ok {
+			merge(existing, metric)
+			continue
+		}
+		seen[key] = metric
+		metrics = append(metrics, metric)
+	}
+	family.Metric = metrics
+}
+
+func labelsKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+	for _, pair := range labels {
+		b.WriteString(pair.GetName())
+		b.WriteByte(model.SeparatorByte)
+		b.WriteString(pair.GetValue())
+		b.WriteByte(model.SeparatorByte)
+	}
+	return b.String()
+}
+
+// mergeMetric adds the value of src to dst if both are counters. Gauges,
+// summaries and histograms cannot be summed, the first series is kept for
+// them.
+func mergeMetric(dst, src *dto.Metric) {
+	if dst.Counter == nil || src.Counter == nil {
+		return
+	}
+	*dst.Counter.Value = dst.Counter.GetValue() + src.Counter.GetValue()
+	if src.GetTimestampMs() > dst.GetTimestampMs() {
+		dst.TimestampMs = src.TimestampMs
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatherRelabeled(t *testing.T, config *RelabelConfig) string {
+	reg := prometheus.NewRegistry()
+	usage := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "container_cpu_usage_seconds_total", Help: "Cumulative cpu time consumed."}, []string{"id", "image", "pod_name"})
+	usage.WithLabelValues("/a", "busybox", "web").Add(1)
+	usage.WithLabelValues("/b", "nginx", "web").Add(2)
+	tasks := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "container_tasks_state", Help: "Number of tasks in given state."}, []string{"id"})
+	tasks.WithLabelValues("/a").Set(3)
+	reg.MustRegister(usage, tasks)
+
+	families, err := NewRelabelingGatherer(reg, config).Gather()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	for _, family := range families {
+		_, err := expfmt.MetricFamilyToText(&buf, family)
+		require.NoError(t, err)
+	}
+	return buf.String()
+}
+
+func TestRelabelingGatherer(t *testing.T) {
+	config, err := ParseRelabelConfig([]byte(`
+drop_metrics:
+  - container_tasks_.*
+drop_labels:
+  - image
+rename_labels:
+  pod_name: pod
+`))
+	require.NoError(t, err)
+
+	out := gatherRelabeled(t, config)
+	assert.NotContains(t, out, "container_tasks_state")
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/a",pod="web"} 1`+"\n")
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/b",pod="web"} 2`+"\n")
+	assert.NotContains(t, out, "image=")
+}
+
+func TestRelabelingGathererMergesSeries(t *testing.T) {
+	config, err := ParseRelabelConfig([]byte("drop_labels: [id, image]\n"))
+	require.NoError(t, err)
+
+	out := gatherRelabeled(t, config)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{pod_name="web"} 3`+"\n")
+	assert.Contains(t, out, "container_tasks_state 3\n")
+}
+
+func TestRelabelingGathererKeepsFirstGaugeSeries(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	limit := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "container_spec_memory_limit_bytes", Help: "Memory limit for the container."}, []string{"id", "image"})
+	limit.WithLabelValues("/a", "busybox").Set(1024)
+	limit.WithLabelValues("/b", "busybox").Set(2048)
+	reg.MustRegister(limit)
+	config, err := ParseRelabelConfig([]byte("drop_labels: [id]\n"))
+	require.NoError(t, err)
+
+	families, err := NewRelabelingGatherer(reg, config).Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Len(t, families[0].Metric, 1)
+	assert.Equal(t, 1024.0, families[0].Metric[0].GetGauge().GetValue())
+}
+
+func TestRelabelingGathererLabelPolicies(t *testing.T) {
+	config, err := ParseRelabelConfig([]byte(`
+rename_labels:
+  pod_name: pod
+label_policies:
+  - metrics: container_cpu_.*
+    keep_labels: [id, pod_name]
+  - metrics: container_.*
+    drop_labels: [id]
+`))
+	require.NoError(t, err)
+
+	out := gatherRelabeled(t, config)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/a",pod="web"} 1`+"\n")
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/b",pod="web"} 2`+"\n")
+	// Only the first matching policy applies.
+	assert.Contains(t, out, "container_tasks_state 3\n")
+}
+
+func TestRelabelingGathererWithoutConfig(t *testing.T) {
+	out := gatherRelabeled(t, nil)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/a",image="busybox",pod_name="web"} 1`+"\n")
+	assert.Contains(t, out, "container_tasks_state")
+}
+
+func TestRelabelingGathererSeriesBudget(t *testing.T) {
+	// Three series fit, the budget metrics are not counted.
+	config, err := ParseRelabelConfig([]byte("series_budget: {max_series: 3}\n"))
+	require.NoError(t, err)
+	out := gatherRelabeled(t, config)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{id="/a",image="busybox",pod_name="web"} 1`+"\n")
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 0\n")
+	assert.Contains(t, out, "cadvisor_series_before_budget 3\n")
+
+	// Labels are dropped until the series fit, pod_name is kept. The raw
+	// cgroup of container_tasks_state keeps its id.
+	config, err = ParseRelabelConfig([]byte("series_budget: {max_series: 2, drop_labels: [id, image, pod_name]}\n"))
+	require.NoError(t, err)
+	out = gatherRelabeled(t, config)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{pod_name="web"} 3`+"\n")
+	assert.Contains(t, out, `container_tasks_state{id="/a"} 3`+"\n")
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 1\n")
+	assert.Contains(t, out, "cadvisor_series_before_budget 3\n")
+
+	// id and image are dropped by default, even if the series still do not
+	// fit.
+	config, err = ParseRelabelConfig([]byte("series_budget: {max_series: 1}\n"))
+	require.NoError(t, err)
+	out = gatherRelabeled(t, config)
+	assert.Contains(t, out, `container_cpu_usage_seconds_total{pod_name="web"} 3`+"\n")
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 1\n")
+}
+
+func TestSeriesBudgetHysteresis(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	usage := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "container_memory_usage_bytes", Help: "Current memory usage in bytes."}, []string{"id", "image", "name"})
+	reg.MustRegister(usage)
+	usage.WithLabelValues("/", "", "").Set(100)
+	for i := 0; i < 9; i++ {
+		usage.WithLabelValues(fmt.Sprintf("/c%d", i), "busybox", fmt.Sprintf("c%d", i)).Set(float64(i + 1))
+	}
+	config, err := ParseRelabelConfig([]byte("series_budget: {max_series: 10, low_water_series: 8, drop_labels: [id, name]}\n"))
+	require.NoError(t, err)
+	g := NewRelabelingGatherer(reg, config)
+	gather := func() string {
+		families, err := g.Gather()
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		for _, family := range families {
+			_, err := expfmt.MetricFamilyToText(&buf, family)
+			require.NoError(t, err)
+		}
+		return buf.String()
+	}
+
+	out := gather()
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 0\n")
+	assert.Contains(t, out, `container_memory_usage_bytes{id="/c0",image="busybox",name="c0"} 1`+"\n")
+
+	// The gauges of the merged series are not summed, the root cgroup keeps
+	// its id.
+	usage.WithLabelValues("/c9", "busybox", "c9").Set(10)
+	out = gather()
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 1\n")
+	assert.Contains(t, out, `container_memory_usage_bytes{image="busybox"} 1`+"\n")
+	assert.Contains(t, out, `container_memory_usage_bytes{id="/",image=""} 100`+"\n")
+
+	// The labels stay dropped above the low water mark.
+	usage.DeleteLabelValues("/c9", "busybox", "c9")
+	out = gather()
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 1\n")
+	assert.Contains(t, out, "cadvisor_series_before_budget 10\n")
+
+	usage.DeleteLabelValues("/c8", "busybox", "c8")
+	usage.DeleteLabelValues("/c7", "busybox", "c7")
+	out = gather()
+	assert.Contains(t, out, "cadvisor_series_budget_exceeded 0\n")
+	assert.Contains(t, out, `container_memory_usage_bytes{id="/c0",image="busybox",name="c0"} 1`+"\n")
+}
+
+func TestParseRelabelConfigErrors(t *testing.T) {
+	for _, config := range []string{
+		"drop_metrics: ['container_(']",
+		"drop_labels: ['not-a-label']",
+		"rename_labels: {pod_name: 'pod name'}",
+		"unknown_field: true",
+		"label_policies: [{keep_labels: [id]}]",
+		"label_policies: [{metrics: 'container_(', keep_labels: [id]}]",
+		"label_policies: [{metrics: container_.*, drop_labels: ['not-a-label']}]",
+		"series_budget: {drop_labels: [id]}",
+		"series_budget: {max_series: 10, drop_labels: ['not-a-label']}",
+		"series_budget: {max_series: 10, low_water_series: 10}",
+		"series_budget: {max_series: 10, low_water_series: -1}",
+	} {
+		_, err := ParseRelabelConfig([]byte(config))
+		assert.Error(t, err, config)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+const (
+	ndDevicesDir = "/sys/bus/nd/devices"
+	// Device DAX character devices are children of the claiming dax device.
+	daxDeviceGlob = "dax[0-9]*.[0-9]*"
+)
+
+var (
+	regionRegexp    = regexp.MustCompile(`^region([0-9]+)$`)
+	namespaceRegexp = regexp.MustCompile(`^namespace([0-9]+)\.[0-9]+$`)
+	// Personalities of a namespace (fsdax, sector, devdax) are separate devices
+	// which refer to the namespace they claim.
+	claimRegexp = regexp.MustCompile(`^(pfn|btt|dax)[0-9]+\.[0-9]+$`)
+)
+
+// GetPmemRegions returns the persistent memory regions of the host and the
+// namespaces carved out of them as reported by the libnvdimm subsystem.
+func GetPmemRegions() ([]info.PmemRegion, error) {
+	return getPmemRegions(ndDevicesDir)
+}
+
+func getPmemRegions(dir string) ([]info.PmemRegion, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No NVDIMM bus registered.
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	claims := map[string]string{}
+	for _, entry := range entries {
+		if !claimRegexp.MatchString(entry.Name()) {
+			continue
+		}
+		namespace, err := readTrimmedFile(filepath.Join(dir, entry.Name(), "namespace"))
+		if err != nil {
+			return nil, err
+		}
+		// Idle seed devices do not claim any namespace.
+		if namespace != "" {
+			claims[namespace] = entry.Name()
+		}
+	}
+
+	regions := []info.PmemRegion{}
+	regionIndex := map[int]int{}
+	for _, entry := range entries {
+		matches := regionRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		regionPath := filepath.Join(dir, entry.Name())
+		devtype, err := readTrimmedFile(filepath.Join(regionPath, "devtype"))
+		if err != nil {
+			return nil, err
+		}
+		// Only persistent memory regions can be used in App Direct mode.
+		if devtype != "nd_pmem" {
+			continue
+		}
+		region := info.PmemRegion{NumaNode: -1}
+		region.Id, _ = strconv.Atoi(matches[1])
+		if region.Size, err = readUintFile(filepath.Join(regionPath, "size")); err != nil {
+			return nil, err
+		}
+		if region.AvailableSize, err = readUintFile(filepath.Join(regionPath, "available_size")); err != nil {
+			return nil, err
+		}
+		if node, err := readTrimmedFile(filepath.Join(regionPath, "numa_node")); err == nil {
+			if region.NumaNode, err = strconv.Atoi(node); err != nil {
+				return nil, err
+			}
+		}
+		regionIndex[region.Id] = len(regions)
+		regions = append(regions, region)
+	}
+
+	for _, entry := range entries {
+		matches := namespaceRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		regionID, _ := strconv.Atoi(matches[1])
+		index, ok := regionIndex[regionID]
+		if !ok {
+			continue
+		}
+		namespace, err := getPmemNamespace(dir, entry.Name(), claims[entry.Name()])
+		if err != nil {
+			return nil, err
+		}
+		// Every region has an unconfigured seed namespace of size zero.
+		if namespace.Size == 0 {
+			continue
+		}
+		regions[index].Namespaces = append(regions[index].Namespaces, namespace)
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Id < regions[j].Id })
+	return regions, nil
+}
+
+func getPmemNamespace(dir, name, claim string) (info.PmemNamespace, error) {
+	namespacePath := filepath.Join(dir, name)
+	namespace := info.PmemNamespace{Name: name}
+	var err error
+	if namespace.Mode, err = readTrimmedFile(filepath.Join(namespacePath, "mode")); err != nil {
+		return namespace, err
+	}
+	if namespace.Size, err = readUintFile(filepath.Join(namespacePath, "size")); err != nil {
+		return namespace, err
+	}
+	// Label-less namespaces have no UUID.
+	namespace.UUID, _ = readTrimmedFile(filepath.Join(namespacePath, "uuid"))
+
+	// The block or character device is exposed by the device claiming the
+	// namespace, or by the namespace itself in raw mode.
+	devicePath := namespacePath
+	if claim != "" {
+		devicePath = filepath.Join(dir, claim)
+	}
+	devices, err := ioutil.ReadDir(filepath.Join(devicePath, "block"))
+	if err == nil && len(devices) > 0 {
+		namespace.Device = "/dev/" + devices[0].Name()
+		return namespace, nil
+	}
+	daxDevices, err := filepath.Glob(filepath.Join(devicePath, daxDeviceGlob))
+	if err != nil {
+		return namespace, err
+	}
+	if len(daxDevices) > 0 {
+		namespace.Device = "/dev/" + filepath.Base(daxDevices[0])
+	}
+	return namespace, nil
+}
+
+func readTrimmedFile(path string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func readUintFile(path string) (uint64, error) {
+	content, err := readTrimmedFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nvm
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPmemRegions(t *testing.T) {
+	regions, err := getPmemRegions("testdata/nd")
+	assert.Nil(t, err)
+	assert.Equal(t, []info.PmemRegion{
+		{
+			Id:            0,
+			Size:          270582939648,
+			AvailableSize: 0,
+			NumaNode:      0,
+			Namespaces: []info.PmemNamespace{
+				{Name: "namespace0.0", Mode: "fsdax", Size: 270582939648, UUID: "2a0f0c5a-9c2b-4d5e-8f7a-1b2c3d4e5f60", Device: "/dev/pmem0"},
+			},
+		},
+		{
+			Id:            1,
+			Size:          270582939648,
+			AvailableSize: 135291469824,
+			NumaNode:      1,
+			Namespaces: []info.PmemNamespace{
+				{Name: "namespace1.0", Mode: "devdax", Size: 135291469824, UUID: "7c1d4a2e-3f5b-4c6d-9e8f-0a1b2c3d4e5f", Device: "/dev/dax1.0"},
+			},
+		},
+	}, regions)
+}
+
+func TestGetPmemRegionsWithoutNvdimmBus(t *testing.T) {
+	regions, err := getPmemRegions("testdata/missing")
+	assert.Nil(t, err)
+	assert.Empty(t, regions)
+}
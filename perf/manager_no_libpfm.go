@@ -18,6 +18,8 @@
 package perf
 
 import (
+	"fmt"
+
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/stats"
 
@@ -26,5 +28,8 @@ import (
 
 func NewManager(configFile string, topology []info.Node) (stats.Manager, error) {
 	klog.V(1).Info("cAdvisor is build without cgo and/or libpfm support. Perf event counters are not available.")
+	if configFile != "" {
+		return &stats.NoopManager{}, fmt.Errorf("%w: cAdvisor is built without cgo and/or libpfm support", stats.ErrUnavailable)
+	}
 	return &stats.NoopManager{}, nil
 }
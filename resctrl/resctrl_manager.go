@@ -18,6 +18,7 @@
 package resctrl
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/google/cadvisor/stats"
@@ -44,5 +45,5 @@ func NewManager(id string) (stats.Manager, error) {
 		return &manager{id: id}, nil
 	}
 
-	return &stats.NoopManager{}, nil
+	return &stats.NoopManager{}, fmt.Errorf("%w: neither Intel RDT MBM nor CMT is enabled", stats.ErrUnavailable)
 }
@@ -0,0 +1,77 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"sync"
+	"time"
+
+	v1 "github.com/google/cadvisor/info/v1"
+	"k8s.io/utils/clock"
+)
+
+// MergeFunc copies the stats gathered by a collector from src to dst.
+type MergeFunc func(dst, src *v1.ContainerStats)
+
+type intervalCollector struct {
+	collector Collector
+	interval  time.Duration
+	merge     MergeFunc
+	clock     clock.Clock
+
+	lock        sync.Mutex
+	last        *v1.ContainerStats
+	lastUpdated time.Time
+}
+
+// NewIntervalCollector returns a collector calling collector at most once per
+// interval. In between, the last gathered stats are copied into the container
+// stats with merge. A non-positive interval returns collector unchanged.
+func NewIntervalCollector(collector Collector, interval time.Duration, merge MergeFunc, clock clock.Clock) Collector {
+	if interval <= 0 {
+		return collector
+	}
+	return &intervalCollector{
+		collector: collector,
+		interval:  interval,
+		merge:     merge,
+		clock:     clock,
+	}
+}
+
+func (c *intervalCollector) UpdateStats(stats *v1.ContainerStats) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+	if c.last == nil || now.Sub(c.lastUpdated) >= c.interval {
+		last := &v1.ContainerStats{}
+		if err := c.collector.UpdateStats(last); err != nil {
+			// Keep serving the previous stats and retry on the next update.
+			if c.last != nil {
+				c.merge(stats, c.last)
+			}
+			return err
+		}
+		c.last = last
+		c.lastUpdated = now
+	}
+	c.merge(stats, c.last)
+	return nil
+}
+
+func (c *intervalCollector) Destroy() {
+	c.collector.Destroy()
+}
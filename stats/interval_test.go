@@ -0,0 +1,87 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	clock "k8s.io/utils/clock/testing"
+)
+
+type countingCollector struct {
+	NoopDestroy
+	calls int
+	err   error
+}
+
+func (c *countingCollector) UpdateStats(stats *v1.ContainerStats) error {
+	c.calls++
+	if c.err != nil {
+		return c.err
+	}
+	stats.Resctrl = v1.ResctrlStats{MemoryBandwidth: []v1.MemoryBandwidthStats{{TotalBytes: uint64(c.calls)}}}
+	return nil
+}
+
+func mergeResctrl(dst, src *v1.ContainerStats) {
+	dst.Resctrl = src.Resctrl
+}
+
+func TestIntervalCollector(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Unix(0, 0))
+	inner := &countingCollector{}
+	c := NewIntervalCollector(inner, time.Minute, mergeResctrl, fakeClock)
+
+	for i := 0; i < 3; i++ {
+		stats := &v1.ContainerStats{}
+		assert.NoError(t, c.UpdateStats(stats))
+		assert.Equal(t, uint64(1), stats.Resctrl.MemoryBandwidth[0].TotalBytes)
+		fakeClock.Step(10 * time.Second)
+	}
+	assert.Equal(t, 1, inner.calls)
+
+	fakeClock.Step(time.Minute)
+	stats := &v1.ContainerStats{}
+	assert.NoError(t, c.UpdateStats(stats))
+	assert.Equal(t, uint64(2), stats.Resctrl.MemoryBandwidth[0].TotalBytes)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestIntervalCollectorKeepsStatsOnError(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Unix(0, 0))
+	inner := &countingCollector{}
+	c := NewIntervalCollector(inner, time.Minute, mergeResctrl, fakeClock)
+	assert.NoError(t, c.UpdateStats(&v1.ContainerStats{}))
+
+	inner.err = errors.New("failed")
+	fakeClock.Step(time.Minute)
+	stats := &v1.ContainerStats{}
+	assert.Error(t, c.UpdateStats(stats))
+	assert.Equal(t, uint64(1), stats.Resctrl.MemoryBandwidth[0].TotalBytes)
+
+	// The failed measurement is retried on the next update.
+	assert.Error(t, c.UpdateStats(&v1.ContainerStats{}))
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestIntervalCollectorDisabled(t *testing.T) {
+	inner := &countingCollector{}
+	c := NewIntervalCollector(inner, 0, mergeResctrl, clock.NewFakeClock(time.Unix(0, 0)))
+	assert.Equal(t, inner, c)
+}
@@ -16,10 +16,16 @@
 package stats
 
 import (
+	"errors"
+
 	v1 "github.com/google/cadvisor/info/v1"
 	"k8s.io/klog/v2"
 )
 
+// ErrUnavailable is returned, along with a manager whose collectors collect
+// nothing, by the constructors of managers which cannot be set up.
+var ErrUnavailable = errors.New("collector unavailable")
+
 type NoopManager struct {
 	NoopDestroy
 }
@@ -0,0 +1,208 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/stats"
+
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+const unixPrefix = "unix://"
+
+// Manager connects to the plugins and creates the collectors merging their
+// stats into the stats of containers.
+type Manager struct {
+	plugins []*plugin
+	// Max duration of the calls to the plugins.
+	timeout time.Duration
+}
+
+// plugin is the connection to a plugin.
+type plugin struct {
+	address string
+	conn    *grpc.ClientConn
+	client  StatsCollectorClient
+
+	lock sync.Mutex
+	// Description of the plugin, nil until it is described.
+	info *PluginInfo
+	// Specs of the metrics of the plugin by name.
+	specs map[string]info.MetricSpec
+}
+
+// NewManager connects to the plugins served at addresses, unix://<path> or
+// <host>:<port>, each call to a plugin lasting at most timeout. The plugins
+// which are not serving yet are retried when stats are collected.
+func NewManager(addresses []string, timeout time.Duration) (*Manager, error) {
+	m := &Manager{timeout: timeout}
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		opts := []grpc.DialOption{grpc.WithInsecure()}
+		target := address
+		if strings.HasPrefix(address, unixPrefix) {
+			target = strings.TrimPrefix(address, unixPrefix)
+			opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, path string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			}))
+		}
+		conn, err := grpc.Dial(target, opts...)
+		if err != nil {
+			m.Destroy()
+			return nil, fmt.Errorf("failed to connect to stats plugin %q: %v", address, err)
+		}
+		m.plugins = append(m.plugins, &plugin{address: address, conn: conn, client: NewStatsCollectorClient(conn)})
+	}
+	return m, nil
+}
+
+// Destroy closes the connections to the plugins.
+func (m *Manager) Destroy() {
+	for _, p := range m.plugins {
+		if err := p.conn.Close(); err != nil {
+			klog.V(4).Infof("Failed to close the connection to stats plugin %q: %v", p.address, err)
+		}
+	}
+}
+
+// GetCollector returns the collector of the stats of the plugins for the
+// container ref.
+func (m *Manager) GetCollector(ref info.ContainerReference, labels map[string]string) stats.Collector {
+	if len(m.plugins) == 0 {
+		return &stats.NoopCollector{}
+	}
+	return &collector{
+		manager: m,
+		request: &CollectStatsRequest{
+			ContainerName: ref.Name,
+			Aliases:       ref.Aliases,
+			Labels:        labels,
+		},
+	}
+}
+
+// describe returns the specs of the metrics of p, describing it first if it
+// wasn't yet.
+func (p *plugin) describe(ctx context.Context) (map[string]info.MetricSpec, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.info != nil {
+		return p.specs, nil
+	}
+	pluginInfo, err := p.client.Describe(ctx, &DescribeRequest{})
+	if err != nil {
+		return nil, err
+	}
+	p.specs = make(map[string]info.MetricSpec, len(pluginInfo.Metrics))
+	for _, spec := range pluginInfo.Metrics {
+		if spec.Name == "" {
+			continue
+		}
+		p.specs[spec.Name] = info.MetricSpec{
+			Name:   spec.Name,
+			Type:   info.MetricType(spec.Type),
+			Format: info.DataType(spec.Format),
+			Units:  spec.Units,
+		}
+	}
+	p.info = pluginInfo
+	klog.V(1).Infof("Collecting the stats of plugin %q from %s", pluginInfo.Name, p.address)
+	return p.specs, nil
+}
+
+// collector merges the stats of the plugins into the stats of a container.
+type collector struct {
+	manager *Manager
+	request *CollectStatsRequest
+}
+
+// UpdateStats adds the metrics of the plugins described by their specs to
+// the custom metrics of s.
+func (c *collector) UpdateStats(s *info.ContainerStats) error {
+	var errs []string
+	for _, p := range c.manager.plugins {
+		if err := c.collect(p, s); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.address, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to collect the stats of plugins: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (c *collector) collect(p *plugin, s *info.ContainerStats) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.manager.timeout)
+	defer cancel()
+	specs, err := p.describe(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.CollectStats(ctx, c.request)
+	if err != nil {
+		return err
+	}
+	for _, metric := range resp.Metrics {
+		spec, ok := specs[metric.Name]
+		if !ok {
+			klog.V(4).Infof("Ignoring metric %q of stats plugin %q, which it does not describe", metric.Name, p.address)
+			continue
+		}
+		val := info.MetricVal{
+			Labels:     metric.Labels,
+			Timestamp:  s.Timestamp,
+			FloatValue: metric.Value,
+		}
+		if spec.Format == info.IntType {
+			val.IntValue = int64(metric.Value)
+		}
+		if s.CustomMetrics == nil {
+			s.CustomMetrics = make(map[string][]info.MetricVal)
+		}
+		s.CustomMetrics[metric.Name] = append(s.CustomMetrics[metric.Name], val)
+	}
+	return nil
+}
+
+// MetricSpecs returns the specs of the metrics of the plugins described so
+// far, sorted by name.
+func (c *collector) MetricSpecs() []info.MetricSpec {
+	var specs []info.MetricSpec
+	for _, p := range c.manager.plugins {
+		p.lock.Lock()
+		for _, spec := range p.specs {
+			specs = append(specs, spec)
+		}
+		p.lock.Unlock()
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].Name < specs[j].Name
+	})
+	return specs
+}
+
+func (c *collector) Destroy() {}
@@ -0,0 +1,127 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/stats"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type fakePlugin struct {
+	requests chan *CollectStatsRequest
+	err      error
+}
+
+func (p *fakePlugin) Describe(context.Context, *DescribeRequest) (*PluginInfo, error) {
+	return &PluginInfo{
+		Name: "smartnic",
+		Metrics: []*MetricSpec{
+			{Name: "smartnic_rx_packets_total", Type: "cumulative", Format: "int", Units: "packets"},
+			{Name: "smartnic_temperature_celsius", Type: "gauge", Format: "float", Units: "celsius"},
+		},
+	}, nil
+}
+
+func (p *fakePlugin) CollectStats(_ context.Context, req *CollectStatsRequest) (*CollectStatsResponse, error) {
+	p.requests <- req
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &CollectStatsResponse{
+		Metrics: []*Metric{
+			{Name: "smartnic_rx_packets_total", Labels: map[string]string{"port": "0"}, Value: 42},
+			{Name: "smartnic_temperature_celsius", Value: 51.5},
+			{Name: "smartnic_undescribed", Value: 1},
+		},
+	}, nil
+}
+
+func startPlugin(t *testing.T, p *fakePlugin) (string, func()) {
+	dir, err := ioutil.TempDir("", "plugin")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "plugin.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	RegisterStatsCollectorServer(s, p)
+	go s.Serve(listener)
+	return unixPrefix + path, func() {
+		s.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	p := &fakePlugin{requests: make(chan *CollectStatsRequest, 10)}
+	address, stop := startPlugin(t, p)
+	defer stop()
+	m, err := NewManager([]string{address}, 5*time.Second)
+	require.NoError(t, err)
+	defer m.Destroy()
+
+	ref := info.ContainerReference{Name: "/docker/abc", Aliases: []string{"web", "abc"}}
+	c := m.GetCollector(ref, map[string]string{"app": "web"})
+	now := time.Now()
+	s := &info.ContainerStats{Timestamp: now}
+	require.NoError(t, c.UpdateStats(s))
+
+	req := <-p.requests
+	assert.Equal(t, "/docker/abc", req.ContainerName)
+	assert.Equal(t, []string{"web", "abc"}, req.Aliases)
+	assert.Equal(t, map[string]string{"app": "web"}, req.Labels)
+	assert.Equal(t, map[string][]info.MetricVal{
+		"smartnic_rx_packets_total":    {{Labels: map[string]string{"port": "0"}, Timestamp: now, IntValue: 42, FloatValue: 42}},
+		"smartnic_temperature_celsius": {{Timestamp: now, FloatValue: 51.5}},
+	}, s.CustomMetrics)
+
+	assert.Equal(t, []info.MetricSpec{
+		{Name: "smartnic_rx_packets_total", Type: info.MetricCumulative, Format: info.IntType, Units: "packets"},
+		{Name: "smartnic_temperature_celsius", Type: info.MetricGauge, Format: info.FloatType, Units: "celsius"},
+	}, c.(stats.MetricSpecProvider).MetricSpecs())
+
+	p.err = errors.New("device gone")
+	assert.Error(t, c.UpdateStats(&info.ContainerStats{}))
+}
+
+func TestCollectorUnavailablePlugin(t *testing.T) {
+	m, err := NewManager([]string{"unix:///nonexistent/plugin.sock"}, 100*time.Millisecond)
+	require.NoError(t, err)
+	defer m.Destroy()
+
+	c := m.GetCollector(info.ContainerReference{Name: "/a"}, nil)
+	s := &info.ContainerStats{}
+	assert.Error(t, c.UpdateStats(s))
+	assert.Empty(t, s.CustomMetrics)
+	assert.Empty(t, c.(stats.MetricSpecProvider).MetricSpecs())
+}
+
+func TestNoPlugins(t *testing.T) {
+	m, err := NewManager(nil, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, &stats.NoopCollector{}, m.GetCollector(info.ContainerReference{Name: "/a"}, nil))
+}
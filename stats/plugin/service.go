@@ -0,0 +1,117 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin defines the cadvisor.plugin.v1.StatsCollector service of
+// the plugins collecting container stats out of tree, along with its client,
+// and the collectors merging the stats of the plugins into the stats of
+// containers.
+package plugin
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	serviceName = "cadvisor.plugin.v1.StatsCollector"
+
+	describeMethod     = "/" + serviceName + "/Describe"
+	collectStatsMethod = "/" + serviceName + "/CollectStats"
+)
+
+// StatsCollectorServer is implemented by plugins, the servers of the
+// cadvisor.plugin.v1.StatsCollector service.
+type StatsCollectorServer interface {
+	Describe(context.Context, *DescribeRequest) (*PluginInfo, error)
+	CollectStats(context.Context, *CollectStatsRequest) (*CollectStatsResponse, error)
+}
+
+// RegisterStatsCollectorServer registers srv as the
+// cadvisor.plugin.v1.StatsCollector service of s.
+func RegisterStatsCollectorServer(s *grpc.Server, srv StatsCollectorServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*StatsCollectorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Describe", Handler: describeHandler},
+		{MethodName: "CollectStats", Handler: collectStatsHandler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugin.proto",
+}
+
+func describeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsCollectorServer).Describe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: describeMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsCollectorServer).Describe(ctx, req.(*DescribeRequest))
+	})
+}
+
+func collectStatsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CollectStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StatsCollectorServer).CollectStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: collectStatsMethod}
+	return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StatsCollectorServer).CollectStats(ctx, req.(*CollectStatsRequest))
+	})
+}
+
+// StatsCollectorClient is a client of the cadvisor.plugin.v1.StatsCollector
+// service.
+type StatsCollectorClient interface {
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*PluginInfo, error)
+	CollectStats(ctx context.Context, in *CollectStatsRequest, opts ...grpc.CallOption) (*CollectStatsResponse, error)
+}
+
+type statsCollectorClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewStatsCollectorClient returns a client of the
+// cadvisor.plugin.v1.StatsCollector service served on cc.
+func NewStatsCollectorClient(cc *grpc.ClientConn) StatsCollectorClient {
+	return &statsCollectorClient{cc: cc}
+}
+
+func (c *statsCollectorClient) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*PluginInfo, error) {
+	out := new(PluginInfo)
+	if err := c.cc.Invoke(ctx, describeMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *statsCollectorClient) CollectStats(ctx context.Context, in *CollectStatsRequest, opts ...grpc.CallOption) (*CollectStatsResponse, error) {
+	out := new(CollectStatsResponse)
+	if err := c.cc.Invoke(ctx, collectStatsMethod, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
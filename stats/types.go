@@ -33,3 +33,9 @@ type Collector interface {
 	Destroy()
 	UpdateStats(*info.ContainerStats) error
 }
+
+// MetricSpecProvider is implemented by the Collectors adding custom metrics
+// to ContainerStats, returning the specs of these metrics.
+type MetricSpecProvider interface {
+	MetricSpecs() []info.MetricSpec
+}
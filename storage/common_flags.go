@@ -26,3 +26,7 @@ var ArgDbName = flag.String("storage_driver_db", "cadvisor", "database name")
 var ArgDbTable = flag.String("storage_driver_table", "stats", "table name")
 var ArgDbIsSecure = flag.Bool("storage_driver_secure", false, "use secure connection with database")
 var ArgDbBufferDuration = flag.Duration("storage_driver_buffer_duration", 60*time.Second, "Writes in the storage driver will be buffered for this duration, and committed to the non memory backends as a single transaction")
+var ArgWALDir = flag.String("storage_driver_wal_dir", "", "Directory of the write-ahead log spilling the stats a storage driver fails to write, replayed once its backend is available again. Empty disables the write-ahead log")
+var ArgWALMaxBytes = flag.Int64("storage_driver_wal_max_bytes", 256<<20, "Maximum size in bytes of the write-ahead log of a storage driver, the oldest stats are dropped beyond it")
+var ArgWALRetryInterval = flag.Duration("storage_driver_wal_retry_interval", 30*time.Second, "Interval between attempts to replay the write-ahead log of a storage driver")
+var ArgQueueSize = flag.Int("storage_driver_queue_size", 10000, "Number of stats queued for each storage driver, stats are dropped for a driver whose queue is full")
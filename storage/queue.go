@@ -0,0 +1,144 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+var (
+	writeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cadvisor_storage_driver_write_duration_seconds",
+		Help:    "Duration of the writes of stats to a storage driver, including the flushes of its buffer.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"driver"})
+	writeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cadvisor_storage_driver_write_errors_total",
+		Help: "Number of writes of stats to a storage driver that failed.",
+	}, []string{"driver"})
+	droppedStats = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cadvisor_storage_driver_dropped_stats_total",
+		Help: "Number of stats dropped because the queue of a storage driver was full.",
+	}, []string{"driver"})
+	queueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cadvisor_storage_driver_queue_length",
+		Help: "Number of stats waiting to be written to a storage driver.",
+	}, []string{"driver"})
+)
+
+// Metrics returns the collectors of the metrics about the storage drivers.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{writeDuration, writeErrors, droppedStats, queueLength}
+}
+
+// queuedDriver writes stats to a storage driver from its own goroutine, so
+// that a slow or failing driver holds back neither the housekeeping nor the
+// other drivers.
+type queuedDriver struct {
+	name     string
+	driver   StorageDriver
+	queue    chan Sample
+	dropping bool
+	lock     sync.Mutex
+	done     chan struct{}
+	// Guards queue against sends once it is closed.
+	closeLock sync.RWMutex
+	closed    bool
+}
+
+// NewQueuedDriver wraps driver so that AddStats queues stats, up to
+// queueSize, instead of writing them. Stats are dropped while the queue is
+// full.
+func NewQueuedDriver(name string, driver StorageDriver, queueSize int) (StorageDriver, error) {
+	if queueSize <= 0 {
+		return nil, fmt.Errorf("invalid storage driver queue size %d", queueSize)
+	}
+	q := &queuedDriver{
+		name:   name,
+		driver: driver,
+		queue:  make(chan Sample, queueSize),
+		done:   make(chan struct{}),
+	}
+	// Export the metrics of the driver before any write.
+	writeErrors.WithLabelValues(name)
+	droppedStats.WithLabelValues(name)
+	queueLength.WithLabelValues(name).Set(0)
+	go q.run()
+	return q, nil
+}
+
+func (q *queuedDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	q.closeLock.RLock()
+	defer q.closeLock.RUnlock()
+	if q.closed {
+		return fmt.Errorf("storage driver %q is closed", q.name)
+	}
+	select {
+	case q.queue <- Sample{ContainerInfo: cInfo, Stats: stats}:
+		queueLength.WithLabelValues(q.name).Set(float64(len(q.queue)))
+		q.setDropping(false)
+	default:
+		droppedStats.WithLabelValues(q.name).Inc()
+		q.setDropping(true)
+	}
+	return nil
+}
+
+// Logs when the driver starts and stops dropping stats.
+func (q *queuedDriver) setDropping(dropping bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.dropping == dropping {
+		return
+	}
+	q.dropping = dropping
+	if dropping {
+		klog.Warningf("Storage driver %q is falling behind, dropping stats", q.name)
+	} else {
+		klog.Infof("Storage driver %q caught up", q.name)
+	}
+}
+
+func (q *queuedDriver) run() {
+	defer close(q.done)
+	for sample := range q.queue {
+		queueLength.WithLabelValues(q.name).Set(float64(len(q.queue)))
+		start := time.Now()
+		err := q.driver.AddStats(sample.ContainerInfo, sample.Stats)
+		writeDuration.WithLabelValues(q.name).Observe(time.Since(start).Seconds())
+		if err != nil {
+			writeErrors.WithLabelValues(q.name).Inc()
+			klog.Errorf("Failed to write stats to storage driver %q: %v", q.name, err)
+		}
+	}
+}
+
+// Close writes the queued stats and closes the driver. Stats added
+// afterwards are rejected.
+func (q *queuedDriver) Close() error {
+	q.closeLock.Lock()
+	q.closed = true
+	close(q.queue)
+	q.closeLock.Unlock()
+	<-q.done
+	return q.driver.Close()
+}
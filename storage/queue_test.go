@@ -0,0 +1,120 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingDriver records the stats it is given, blocking until released.
+type blockingDriver struct {
+	release chan struct{}
+	lock    sync.Mutex
+	added   []uint64
+	fail    bool
+	closed  bool
+}
+
+func (d *blockingDriver) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	<-d.release
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.added = append(d.added, stats.Cpu.Usage.Total)
+	if d.fail {
+		return fmt.Errorf("write failed")
+	}
+	return nil
+}
+
+func (d *blockingDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+// Resets the metrics of the drivers, shared by all the tests.
+func resetMetrics(names ...string) {
+	for _, name := range names {
+		writeDuration.DeleteLabelValues(name)
+		writeErrors.DeleteLabelValues(name)
+		droppedStats.DeleteLabelValues(name)
+		queueLength.DeleteLabelValues(name)
+	}
+}
+
+func testSample(id uint64) (*info.ContainerInfo, *info.ContainerStats) {
+	return &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/test"}},
+		&info.ContainerStats{Timestamp: time.Now(), Cpu: info.CpuStats{Usage: info.CpuUsage{Total: id}}}
+}
+
+func TestQueuedDriverDropsWhenFull(t *testing.T) {
+	resetMetrics("blocking")
+	driver := &blockingDriver{release: make(chan struct{})}
+	q, err := NewQueuedDriver("blocking", driver, 2)
+	require.NoError(t, err)
+
+	// The first stats are taken off the queue while the driver blocks, then
+	// the queue fills up.
+	require.NoError(t, q.AddStats(testSample(1)))
+	assert.Eventually(t, func() bool { return len(q.(*queuedDriver).queue) == 0 }, time.Second, time.Millisecond)
+	for i := uint64(2); i <= 5; i++ {
+		require.NoError(t, q.AddStats(testSample(i)))
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(droppedStats.WithLabelValues("blocking")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(queueLength.WithLabelValues("blocking")))
+
+	close(driver.release)
+	require.NoError(t, q.Close())
+	assert.Equal(t, []uint64{1, 2, 3}, driver.added)
+	assert.True(t, driver.closed)
+	// Stats added once the driver is closed are rejected.
+	assert.Error(t, q.AddStats(testSample(6)))
+}
+
+func TestQueuedDriverIndependentFailures(t *testing.T) {
+	resetMetrics("failing", "working")
+	failing := &blockingDriver{release: make(chan struct{}), fail: true}
+	working := &blockingDriver{release: make(chan struct{})}
+	close(failing.release)
+	close(working.release)
+	f, err := NewQueuedDriver("failing", failing, 10)
+	require.NoError(t, err)
+	w, err := NewQueuedDriver("working", working, 10)
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 3; i++ {
+		require.NoError(t, f.AddStats(testSample(i)))
+		require.NoError(t, w.AddStats(testSample(i)))
+	}
+	require.NoError(t, f.Close())
+	require.NoError(t, w.Close())
+	assert.Equal(t, []uint64{1, 2, 3}, working.added)
+	assert.Equal(t, 3.0, testutil.ToFloat64(writeErrors.WithLabelValues("failing")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(writeErrors.WithLabelValues("working")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(droppedStats.WithLabelValues("working")))
+}
+
+func TestQueuedDriverInvalidSize(t *testing.T) {
+	_, err := NewQueuedDriver("invalid", &blockingDriver{}, 0)
+	assert.Error(t, err)
+}
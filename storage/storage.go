@@ -16,9 +16,12 @@ package storage
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 
 	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
 )
 
 type StorageDriver interface {
@@ -46,7 +49,21 @@ func New(name string) (StorageDriver, error) {
 	if !ok {
 		return nil, fmt.Errorf("unknown backend storage driver: %s", name)
 	}
-	return f()
+	driver, err := f()
+	if err != nil || *ArgWALDir == "" {
+		return driver, err
+	}
+	batchDriver, ok := driver.(BatchStorageDriver)
+	if !ok {
+		klog.Warningf("Storage driver %q does not support the write-ahead log", name)
+		return driver, nil
+	}
+	wal, err := NewWAL(batchDriver, filepath.Join(*ArgWALDir, name), *ArgWALMaxBytes, *ArgDbBufferDuration, *ArgWALRetryInterval)
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	return wal, nil
 }
 
 func ListDrivers() []string {
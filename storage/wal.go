@@ -0,0 +1,384 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"k8s.io/klog/v2"
+)
+
+// Sample is the stats of a container, as passed to StorageDriver.AddStats.
+type Sample struct {
+	ContainerInfo *info.ContainerInfo  `json:"container_info"`
+	Stats         *info.ContainerStats `json:"stats"`
+}
+
+// BatchStorageDriver is a storage driver able to write a batch of samples
+// synchronously, which the write-ahead log needs to know whether the samples
+// have been written.
+type BatchStorageDriver interface {
+	StorageDriver
+
+	// WriteBatch writes samples to the backend, bypassing the buffering
+	// of AddStats. An error means that none of the samples may have been
+	// written.
+	WriteBatch(samples []Sample) error
+}
+
+const walSuffix = ".wal"
+
+// wal buffers samples and writes them in batches with a storage driver.
+// Batches the driver fails to write are spilled to a bounded on-disk queue
+// and replayed in order once the driver writes again.
+type wal struct {
+	driver         BatchStorageDriver
+	queue          *diskQueue
+	bufferDuration time.Duration
+	retryInterval  time.Duration
+	lastWrite      time.Time
+	samples        []Sample
+	lock           sync.Mutex
+	// Serializes the writes to the driver and the queue, which keeps the
+	// batches in order.
+	writeLock    sync.Mutex
+	readyToFlush func() bool
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// NewWAL wraps driver with a write-ahead log in dir of at most maxBytes.
+// Samples are buffered in memory for bufferDuration and the queued batches,
+// including those left by a previous run, replayed every retryInterval.
+func NewWAL(driver BatchStorageDriver, dir string, maxBytes int64, bufferDuration, retryInterval time.Duration) (StorageDriver, error) {
+	queue, err := openDiskQueue(dir, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	w := &wal{
+		driver:         driver,
+		queue:          queue,
+		bufferDuration: bufferDuration,
+		retryInterval:  retryInterval,
+		lastWrite:      time.Now(),
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	w.readyToFlush = w.defaultReadyToFlush
+	go w.replayLoop()
+	return w, nil
+}
+
+func (w *wal) defaultReadyToFlush() bool {
+	return time.Since(w.lastWrite) >= w.bufferDuration
+}
+
+func (w *wal) AddStats(cInfo *info.ContainerInfo, stats *info.ContainerStats) error {
+	if stats == nil {
+		return nil
+	}
+	var samplesToFlush []Sample
+	func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+
+		// Only the reference and the spec are needed to write stats.
+		w.samples = append(w.samples, Sample{
+			ContainerInfo: &info.ContainerInfo{ContainerReference: cInfo.ContainerReference, Spec: cInfo.Spec},
+			Stats:         stats,
+		})
+		if w.readyToFlush() {
+			samplesToFlush = w.samples
+			w.samples = nil
+			w.lastWrite = time.Now()
+		}
+	}()
+	if len(samplesToFlush) > 0 {
+		return w.write(samplesToFlush)
+	}
+	return nil
+}
+
+// Writes samples, or spills them to disk when batches are already queued or
+// the write fails.
+func (w *wal) write(samples []Sample) error {
+	w.writeLock.Lock()
+	defer w.writeLock.Unlock()
+	if w.queue.len() == 0 {
+		err := w.driver.WriteBatch(samples)
+		if err == nil {
+			return nil
+		}
+		klog.Warningf("Spilling %d samples to the write-ahead log %s: %v", len(samples), w.queue.dir, err)
+	}
+	return w.queue.push(samples)
+}
+
+func (w *wal) replayLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		w.replay()
+	}
+}
+
+// Writes the queued batches in order, until a write fails.
+func (w *wal) replay() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if !w.replayBatch() {
+			return
+		}
+	}
+}
+
+// Writes the oldest queued batch, returns whether it was written.
+func (w *wal) replayBatch() bool {
+	w.writeLock.Lock()
+	defer w.writeLock.Unlock()
+	name, samples, ok := w.queue.peek()
+	if !ok {
+		return false
+	}
+	if err := w.driver.WriteBatch(samples); err != nil {
+		klog.V(2).Infof("Failed to replay the write-ahead log %s, %d batches queued: %v", w.queue.dir, w.queue.len(), err)
+		return false
+	}
+	w.queue.remove(name)
+	if w.queue.len() == 0 {
+		klog.Infof("Replayed the write-ahead log %s", w.queue.dir)
+	}
+	return true
+}
+
+// Close writes the buffered samples, or spills them to disk, and closes the
+// driver.
+func (w *wal) Close() error {
+	close(w.stop)
+	<-w.done
+	w.lock.Lock()
+	samples := w.samples
+	w.samples = nil
+	w.lock.Unlock()
+	var errs []string
+	if len(samples) > 0 {
+		if err := w.write(samples); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := w.driver.Close(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// diskQueue is a FIFO of batches of samples, each stored in a gzipped JSON
+// lines file named after its sequence number.
+type diskQueue struct {
+	dir      string
+	maxBytes int64
+	lock     sync.Mutex
+	// Queued files, oldest first.
+	names []string
+	sizes map[string]int64
+	size  int64
+	next  uint64
+}
+
+func openDiskQueue(dir string, maxBytes int64) (*diskQueue, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("invalid write-ahead log size %d", maxBytes)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the write-ahead log directory: %v", err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	q := &diskQueue{dir: dir, maxBytes: maxBytes, sizes: map[string]int64{}}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, walSuffix) {
+			// Leftovers of interrupted writes.
+			if strings.HasSuffix(name, walSuffix+".tmp") {
+				os.Remove(filepath.Join(dir, name))
+			}
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seq >= q.next {
+			q.next = seq + 1
+		}
+		q.names = append(q.names, name)
+		q.sizes[name] = file.Size()
+		q.size += file.Size()
+	}
+	// Names are zero padded, sorting them sorts the sequence numbers.
+	sort.Strings(q.names)
+	if len(q.names) > 0 {
+		klog.Infof("Found %d batches in the write-ahead log %s", len(q.names), dir)
+	}
+	return q, nil
+}
+
+func (q *diskQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.names)
+}
+
+// Appends a batch, dropping the oldest batches beyond the size limit.
+func (q *diskQueue) push(samples []Sample) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, sample := range samples {
+		if err := encoder.Encode(sample); err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	size := int64(buf.Len())
+	if size > q.maxBytes {
+		return fmt.Errorf("dropped %d samples larger than the write-ahead log", len(samples))
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	dropped := 0
+	for q.size+size > q.maxBytes && len(q.names) > 0 {
+		q.removeLocked(q.names[0])
+		dropped++
+	}
+	if dropped > 0 {
+		klog.Warningf("The write-ahead log %s is full, dropped its %d oldest batches", q.dir, dropped)
+	}
+
+	name := fmt.Sprintf("%020d%s", q.next, walSuffix)
+	path := filepath.Join(q.dir, name)
+	if err := ioutil.WriteFile(path+".tmp", buf.Bytes(), 0600); err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to write the write-ahead log: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write the write-ahead log: %v", err)
+	}
+	q.next++
+	q.names = append(q.names, name)
+	q.sizes[name] = size
+	q.size += size
+	return nil
+}
+
+// Returns the oldest batch, skipping the unreadable ones.
+func (q *diskQueue) peek() (string, []Sample, bool) {
+	for {
+		q.lock.Lock()
+		if len(q.names) == 0 {
+			q.lock.Unlock()
+			return "", nil, false
+		}
+		name := q.names[0]
+		q.lock.Unlock()
+
+		samples, err := readBatch(filepath.Join(q.dir, name))
+		if err == nil {
+			return name, samples, true
+		}
+		klog.Warningf("Dropping unreadable write-ahead log batch %s: %v", name, err)
+		q.remove(name)
+	}
+}
+
+func (q *diskQueue) remove(name string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.removeLocked(name)
+}
+
+func (q *diskQueue) removeLocked(name string) {
+	size, ok := q.sizes[name]
+	if !ok {
+		// Already dropped to make room.
+		return
+	}
+	for i, n := range q.names {
+		if n == name {
+			q.names = append(q.names[:i], q.names[i+1:]...)
+			break
+		}
+	}
+	delete(q.sizes, name)
+	q.size -= size
+	if err := os.Remove(filepath.Join(q.dir, name)); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("Failed to remove write-ahead log batch %s: %v", name, err)
+	}
+}
+
+func readBatch(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var samples []Sample
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	for decoder.More() {
+		var sample Sample
+		if err := decoder.Decode(&sample); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchDriver records the samples it writes, or fails while unavailable.
+type batchDriver struct {
+	lock        sync.Mutex
+	unavailable bool
+	written     []int64
+	closed      bool
+}
+
+func (d *batchDriver) AddStats(*info.ContainerInfo, *info.ContainerStats) error {
+	return fmt.Errorf("unexpected call")
+}
+
+func (d *batchDriver) WriteBatch(samples []Sample) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.unavailable {
+		return fmt.Errorf("backend unavailable")
+	}
+	for _, sample := range samples {
+		d.written = append(d.written, int64(sample.Stats.Cpu.Usage.Total))
+	}
+	return nil
+}
+
+func (d *batchDriver) setUnavailable(unavailable bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.unavailable = unavailable
+}
+
+func (d *batchDriver) writtenSamples() []int64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]int64(nil), d.written...)
+}
+
+func (d *batchDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func newTestWAL(t *testing.T, driver *batchDriver, dir string, maxBytes int64) *wal {
+	w, err := NewWAL(driver, dir, maxBytes, time.Minute, time.Hour)
+	require.NoError(t, err)
+	ret := w.(*wal)
+	// Flush every sample.
+	ret.readyToFlush = func() bool { return true }
+	return ret
+}
+
+func addSample(t *testing.T, w *wal, id int64) {
+	cInfo := &info.ContainerInfo{ContainerReference: info.ContainerReference{Name: "/test"}}
+	stats := &info.ContainerStats{Timestamp: time.Now(), Cpu: info.CpuStats{Usage: info.CpuUsage{Total: uint64(id)}}}
+	require.NoError(t, w.AddStats(cInfo, stats))
+}
+
+func testDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestWALSpillAndReplay(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	driver := &batchDriver{}
+	w := newTestWAL(t, driver, dir, 1<<20)
+
+	addSample(t, w, 1)
+	driver.setUnavailable(true)
+	addSample(t, w, 2)
+	addSample(t, w, 3)
+	assert.Equal(t, 2, w.queue.len())
+	w.replay()
+	assert.Equal(t, 2, w.queue.len())
+
+	// Batches are written in order once the backend is available again,
+	// new ones queued behind the spilled ones.
+	driver.setUnavailable(false)
+	addSample(t, w, 4)
+	assert.Equal(t, []int64{1}, driver.writtenSamples())
+	w.replay()
+	assert.Equal(t, []int64{1, 2, 3, 4}, driver.writtenSamples())
+	assert.Equal(t, 0, w.queue.len())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	require.NoError(t, w.Close())
+	assert.True(t, driver.closed)
+}
+
+func TestWALReplaysPreviousRun(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	driver := &batchDriver{unavailable: true}
+	w := newTestWAL(t, driver, dir, 1<<20)
+	addSample(t, w, 1)
+	addSample(t, w, 2)
+	require.NoError(t, w.Close())
+
+	driver = &batchDriver{}
+	replaying, err := NewWAL(driver, dir, 1<<20, time.Minute, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer replaying.Close()
+	assert.Eventually(t, func() bool {
+		return len(driver.writtenSamples()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{1, 2}, driver.writtenSamples())
+}
+
+func TestWALDropsOldestBatches(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	driver := &batchDriver{unavailable: true}
+	w := newTestWAL(t, driver, dir, 1<<20)
+	defer w.Close()
+	addSample(t, w, 1)
+	// Room for two and a half batches.
+	maxBytes := w.queue.size * 5 / 2
+	w.queue.maxBytes = maxBytes
+
+	for i := int64(2); i <= 5; i++ {
+		addSample(t, w, i)
+	}
+	assert.True(t, w.queue.size <= maxBytes)
+	assert.Equal(t, 2, w.queue.len())
+
+	driver.setUnavailable(false)
+	w.replay()
+	assert.Equal(t, []int64{4, 5}, driver.writtenSamples())
+}
+
+func TestWALSkipsUnreadableBatches(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	driver := &batchDriver{unavailable: true}
+	w := newTestWAL(t, driver, dir, 1<<20)
+	defer w.Close()
+	addSample(t, w, 1)
+	addSample(t, w, 2)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, w.queue.names[0]), []byte("garbage"), 0600))
+
+	driver.setUnavailable(false)
+	w.replay()
+	assert.Equal(t, []int64{2}, driver.writtenSamples())
+	assert.Equal(t, 0, w.queue.len())
+}
+
+// serialDriver fails the writes overlapping another write.
+type serialDriver struct {
+	batchDriver
+	writing int32
+}
+
+func (d *serialDriver) WriteBatch(samples []Sample) error {
+	if !atomic.CompareAndSwapInt32(&d.writing, 0, 1) {
+		return fmt.Errorf("concurrent write")
+	}
+	defer atomic.StoreInt32(&d.writing, 0)
+	time.Sleep(time.Millisecond)
+	return d.batchDriver.WriteBatch(samples)
+}
+
+func TestWALSerializesWrites(t *testing.T) {
+	dir := testDir(t)
+	defer os.RemoveAll(dir)
+	driver := &serialDriver{}
+	w, err := NewWAL(driver, dir, 1<<20, time.Minute, time.Hour)
+	require.NoError(t, err)
+	w.(*wal).readyToFlush = func() bool { return true }
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			addSample(t, w.(*wal), id)
+		}(int64(i))
+	}
+	wg.Wait()
+	assert.Len(t, driver.writtenSamples(), 10)
+	assert.Equal(t, 0, w.(*wal).queue.len())
+	require.NoError(t, w.Close())
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 
 	info "github.com/google/cadvisor/info/v2"
 )
@@ -47,6 +48,10 @@ func (s Uint64Slice) GetPercentile(d float64) uint64 {
 	n := float64(d * (float64(count) + 1))
 	idx, frac := math.Modf(n)
 	index := int(idx)
+	if index < 1 {
+		// Low quantiles of few samples.
+		return s[0]
+	}
 	percentile := float64(s[index-1])
 	if index > 1 && index < count {
 		percentile += frac * float64(s[index]-s[index-1])
@@ -81,6 +86,12 @@ type Percentile interface {
 type resource struct {
 	// list of samples being tracked.
 	samples Uint64Slice
+	// 99th and 99.9th percentiles of the added samples.
+	ninetyNine     Uint64Slice
+	ninetyNineNine Uint64Slice
+	// additional quantiles to compute and their samples.
+	quantiles       []float64
+	quantileSamples []Uint64Slice
 	// average from existing samples.
 	mean mean
 	// maximum value seen so far in the added samples.
@@ -98,17 +109,32 @@ func (r *resource) Add(p info.Percentiles) {
 	r.mean.Add(p.Mean)
 	// Selecting 90p of 90p :(
 	r.samples = append(r.samples, p.Ninety)
+	// Tail quantiles are aggregated from the same quantile of the samples,
+	// the 90p would hide them.
+	r.ninetyNine = append(r.ninetyNine, p.NinetyNine)
+	r.ninetyNineNine = append(r.ninetyNineNine, p.NinetyNineNine)
+	for i, q := range r.quantiles {
+		r.quantileSamples[i] = append(r.quantileSamples[i], p.Quantiles[QuantileName(q)])
+	}
 }
 
 // Add a single sample. Internally, we convert it to a fake percentile sample.
 func (r *resource) AddSample(val uint64) {
 	sample := info.Percentiles{
-		Present:    true,
-		Mean:       val,
-		Max:        val,
-		Fifty:      val,
-		Ninety:     val,
-		NinetyFive: val,
+		Present:        true,
+		Mean:           val,
+		Max:            val,
+		Fifty:          val,
+		Ninety:         val,
+		NinetyFive:     val,
+		NinetyNine:     val,
+		NinetyNineNine: val,
+	}
+	if len(r.quantiles) > 0 {
+		sample.Quantiles = make(map[string]uint64, len(r.quantiles))
+		for _, q := range r.quantiles {
+			sample.Quantiles[QuantileName(q)] = val
+		}
 	}
 	r.Add(sample)
 }
@@ -121,21 +147,44 @@ func (r *resource) GetAllPercentiles() info.Percentiles {
 	p.Fifty = r.samples.GetPercentile(0.5)
 	p.Ninety = r.samples.GetPercentile(0.9)
 	p.NinetyFive = r.samples.GetPercentile(0.95)
+	p.NinetyNine = r.ninetyNine.GetPercentile(0.99)
+	p.NinetyNineNine = r.ninetyNineNine.GetPercentile(0.999)
+	if len(r.quantiles) > 0 {
+		p.Quantiles = make(map[string]uint64, len(r.quantiles))
+		for i, q := range r.quantiles {
+			p.Quantiles[QuantileName(q)] = r.quantileSamples[i].GetPercentile(q)
+		}
+	}
 	p.Present = true
 	return p
 }
 
-func NewResource(size int) Percentile {
-	return &resource{
-		samples: make(Uint64Slice, 0, size),
-		mean:    mean{count: 0, Mean: 0},
+// NewResource returns a Percentile tracking up to size samples, which
+// computes the given quantiles in addition to the fixed percentiles.
+func NewResource(size int, quantiles ...float64) Percentile {
+	r := &resource{
+		samples:         make(Uint64Slice, 0, size),
+		ninetyNine:      make(Uint64Slice, 0, size),
+		ninetyNineNine:  make(Uint64Slice, 0, size),
+		quantiles:       quantiles,
+		quantileSamples: make([]Uint64Slice, len(quantiles)),
+		mean:            mean{count: 0, Mean: 0},
+	}
+	for i := range r.quantileSamples {
+		r.quantileSamples[i] = make(Uint64Slice, 0, size)
 	}
+	return r
+}
+
+// QuantileName returns the key of quantile q in info.Percentiles.Quantiles.
+func QuantileName(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
 }
 
 // Return aggregated percentiles from the provided percentile samples.
-func GetDerivedPercentiles(stats []*info.Usage) info.Usage {
-	cpu := NewResource(len(stats))
-	memory := NewResource(len(stats))
+func GetDerivedPercentiles(stats []*info.Usage, quantiles ...float64) info.Usage {
+	cpu := NewResource(len(stats), quantiles...)
+	memory := NewResource(len(stats), quantiles...)
 	for _, stat := range stats {
 		cpu.Add(stat.Cpu)
 		memory.Add(stat.Memory)
@@ -175,10 +224,10 @@ func getCPURate(latest, previous secondSample) (uint64, error) {
 }
 
 // Returns a percentile sample for a minute by aggregating seconds samples.
-func GetMinutePercentiles(stats []*secondSample) info.Usage {
+func GetMinutePercentiles(stats []*secondSample, quantiles ...float64) info.Usage {
 	lastSample := secondSample{}
-	cpu := NewResource(len(stats))
-	memory := NewResource(len(stats))
+	cpu := NewResource(len(stats), quantiles...)
+	memory := NewResource(len(stats), quantiles...)
 	for _, stat := range stats {
 		if !lastSample.Timestamp.IsZero() {
 			cpuRate, err := getCPURate(*stat, lastSample)
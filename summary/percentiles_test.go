@@ -15,6 +15,7 @@
 package summary
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -78,25 +79,29 @@ func TestAggregates(t *testing.T) {
 	usage := GetMinutePercentiles(stats)
 	// Cpu mean, max, and 90p should all be 1000 ms/s.
 	cpuExpected := info.Percentiles{
-		Present:    true,
-		Mean:       1000,
-		Max:        1000,
-		Fifty:      1000,
-		Ninety:     1000,
-		NinetyFive: 1000,
-	}
-	if usage.Cpu != cpuExpected {
+		Present:        true,
+		Mean:           1000,
+		Max:            1000,
+		Fifty:          1000,
+		Ninety:         1000,
+		NinetyFive:     1000,
+		NinetyNine:     1000,
+		NinetyNineNine: 1000,
+	}
+	if !reflect.DeepEqual(usage.Cpu, cpuExpected) {
 		t.Errorf("cpu stats are %+v. Expected %+v", usage.Cpu, cpuExpected)
 	}
 	memExpected := info.Percentiles{
-		Present:    true,
-		Mean:       50 * 1024,
-		Max:        99 * 1024,
-		Fifty:      50 * 1024,
-		Ninety:     90 * 1024,
-		NinetyFive: 95 * 1024,
-	}
-	if usage.Memory != memExpected {
+		Present:        true,
+		Mean:           50 * 1024,
+		Max:            99 * 1024,
+		Fifty:          50 * 1024,
+		Ninety:         90 * 1024,
+		NinetyFive:     95 * 1024,
+		NinetyNine:     99 * 1024,
+		NinetyNineNine: 99 * 1024,
+	}
+	if !reflect.DeepEqual(usage.Memory, memExpected) {
 		t.Errorf("memory stats are mean %+v. Expected %+v", usage.Memory, memExpected)
 	}
 }
@@ -127,25 +132,29 @@ func TestSamplesCloseInTimeIgnored(t *testing.T) {
 	usage := GetMinutePercentiles(stats)
 	// Cpu mean, max, and 90p should all be 1000 ms/s. All high-value samples are discarded.
 	cpuExpected := info.Percentiles{
-		Present:    true,
-		Mean:       1000,
-		Max:        1000,
-		Fifty:      1000,
-		Ninety:     1000,
-		NinetyFive: 1000,
-	}
-	if usage.Cpu != cpuExpected {
+		Present:        true,
+		Mean:           1000,
+		Max:            1000,
+		Fifty:          1000,
+		Ninety:         1000,
+		NinetyFive:     1000,
+		NinetyNine:     1000,
+		NinetyNineNine: 1000,
+	}
+	if !reflect.DeepEqual(usage.Cpu, cpuExpected) {
 		t.Errorf("cpu stats are %+v. Expected %+v", usage.Cpu, cpuExpected)
 	}
 	memExpected := info.Percentiles{
-		Present:    true,
-		Mean:       50 * 1024,
-		Max:        99 * 1024,
-		Fifty:      50 * 1024,
-		Ninety:     90 * 1024,
-		NinetyFive: 95 * 1024,
-	}
-	if usage.Memory != memExpected {
+		Present:        true,
+		Mean:           50 * 1024,
+		Max:            99 * 1024,
+		Fifty:          50 * 1024,
+		Ninety:         90 * 1024,
+		NinetyFive:     95 * 1024,
+		NinetyNine:     99 * 1024,
+		NinetyNineNine: 99 * 1024,
+	}
+	if !reflect.DeepEqual(usage.Memory, memExpected) {
 		t.Errorf("memory stats are mean %+v. Expected %+v", usage.Memory, memExpected)
 	}
 }
@@ -158,45 +167,85 @@ func TestDerivedStats(t *testing.T) {
 		s := &info.Usage{
 			PercentComplete: 100,
 			Cpu: info.Percentiles{
-				Present:    true,
-				Mean:       i * Nanosecond,
-				Max:        i * Nanosecond,
-				Fifty:      i * Nanosecond,
-				Ninety:     i * Nanosecond,
-				NinetyFive: i * Nanosecond,
+				Present:        true,
+				Mean:           i * Nanosecond,
+				Max:            i * Nanosecond,
+				Fifty:          i * Nanosecond,
+				Ninety:         i * Nanosecond,
+				NinetyFive:     i * Nanosecond,
+				NinetyNine:     i * Nanosecond,
+				NinetyNineNine: i * Nanosecond,
 			},
 			Memory: info.Percentiles{
-				Present:    true,
-				Mean:       i * 1024,
-				Max:        i * 1024,
-				Fifty:      i * 1024,
-				Ninety:     i * 1024,
-				NinetyFive: i * 1024,
+				Present:        true,
+				Mean:           i * 1024,
+				Max:            i * 1024,
+				Fifty:          i * 1024,
+				Ninety:         i * 1024,
+				NinetyFive:     i * 1024,
+				NinetyNine:     i * 1024,
+				NinetyNineNine: i * 1024,
 			},
 		}
 		stats = append(stats, s)
 	}
 	usage := GetDerivedPercentiles(stats)
 	cpuExpected := info.Percentiles{
-		Present:    true,
-		Mean:       50 * Nanosecond,
-		Max:        99 * Nanosecond,
-		Fifty:      50 * Nanosecond,
-		Ninety:     90 * Nanosecond,
-		NinetyFive: 95 * Nanosecond,
-	}
-	if usage.Cpu != cpuExpected {
+		Present:        true,
+		Mean:           50 * Nanosecond,
+		Max:            99 * Nanosecond,
+		Fifty:          50 * Nanosecond,
+		Ninety:         90 * Nanosecond,
+		NinetyFive:     95 * Nanosecond,
+		NinetyNine:     99 * Nanosecond,
+		NinetyNineNine: 99 * Nanosecond,
+	}
+	if !reflect.DeepEqual(usage.Cpu, cpuExpected) {
 		t.Errorf("cpu stats are %+v. Expected %+v", usage.Cpu, cpuExpected)
 	}
 	memExpected := info.Percentiles{
-		Present:    true,
-		Mean:       50 * 1024,
-		Max:        99 * 1024,
-		Fifty:      50 * 1024,
-		Ninety:     90 * 1024,
-		NinetyFive: 95 * 1024,
-	}
-	if usage.Memory != memExpected {
+		Present:        true,
+		Mean:           50 * 1024,
+		Max:            99 * 1024,
+		Fifty:          50 * 1024,
+		Ninety:         90 * 1024,
+		NinetyFive:     95 * 1024,
+		NinetyNine:     99 * 1024,
+		NinetyNineNine: 99 * 1024,
+	}
+	if !reflect.DeepEqual(usage.Memory, memExpected) {
 		t.Errorf("memory stats are mean %+v. Expected %+v", usage.Memory, memExpected)
 	}
 }
+
+func TestQuantiles(t *testing.T) {
+	N := uint64(100)
+	var i uint64
+	ct := time.Now()
+	stats := make([]*secondSample, 0, N)
+	for i = 1; i < N; i++ {
+		stats = append(stats, &secondSample{
+			Timestamp: ct.Add(time.Duration(i) * time.Second),
+			Cpu:       i * Nanosecond,
+			Memory:    i * 1024,
+		})
+	}
+	usage := GetMinutePercentiles(stats, 0.25, 0.75)
+	expected := map[string]uint64{"0.25": 25 * 1024, "0.75": 75 * 1024}
+	if !reflect.DeepEqual(usage.Memory.Quantiles, expected) {
+		t.Errorf("memory quantiles are %v. Expected %v", usage.Memory.Quantiles, expected)
+	}
+
+	derived := GetDerivedPercentiles([]*info.Usage{&usage}, 0.25, 0.75)
+	if !reflect.DeepEqual(derived.Memory.Quantiles, expected) {
+		t.Errorf("derived memory quantiles are %v. Expected %v", derived.Memory.Quantiles, expected)
+	}
+	if derived.Cpu.Quantiles["0.25"] != 1000 {
+		t.Errorf("derived cpu quantiles are %v. Expected 1000", derived.Cpu.Quantiles)
+	}
+}
+
+func TestLowPercentileOfFewSamples(t *testing.T) {
+	assertPercentile(t, Uint64Slice{10}, 0.1, 10)
+	assertPercentile(t, Uint64Slice{10, 20}, 0.2, 10)
+}
@@ -41,12 +41,48 @@ type availableResources struct {
 	Memory bool
 }
 
+// Config selects the windows and quantiles computed in addition to the
+// minute, hour and day percentiles.
+type Config struct {
+	// Windows over which minute samples are aggregated, whole minutes.
+	Windows []time.Duration
+	// Quantiles computed in addition to the fixed percentiles, in (0, 1).
+	Quantiles []float64
+}
+
+// Validate checks that the windows and quantiles can be computed.
+func (c Config) Validate() error {
+	for _, w := range c.Windows {
+		if w < time.Minute || w%time.Minute != 0 {
+			return fmt.Errorf("invalid window %v: must be a whole number of minutes", w)
+		}
+	}
+	for _, q := range c.Quantiles {
+		if q <= 0 || q >= 1 {
+			return fmt.Errorf("invalid quantile %v: must be between 0 and 1", q)
+		}
+	}
+	return nil
+}
+
+// WindowName returns the key of window w in info.DerivedStats.Windows, e.g.
+// "5m" or "6h".
+func WindowName(w time.Duration) string {
+	if w%time.Hour == 0 {
+		return fmt.Sprintf("%dh", w/time.Hour)
+	}
+	return fmt.Sprintf("%dm", w/time.Minute)
+}
+
 type StatsSummary struct {
 	// Resources being tracked for this container.
 	available availableResources
+	// Additional windows and quantiles to compute.
+	config Config
 	// list of second samples. The list is cleared when a new minute samples is generated.
 	secondSamples []*secondSample
-	// minute percentiles. We track 24 * 60 maximum samples.
+	// minute percentiles. We track an hour of samples, or the largest
+	// configured window.
 	minuteSamples *SamplesBuffer
 	// latest derived instant, minute, hour, and day stats. Instant sample updated every second.
 	// Others updated every minute.
@@ -79,7 +115,7 @@ func (s *StatsSummary) AddSample(stat v1.ContainerStats) error {
 	if elapsed > 60*time.Second {
 		// Make a minute sample. This works with dynamic housekeeping as long
 		// as we keep max dynamic houskeeping period close to a minute.
-		minuteSample := GetMinutePercentiles(s.secondSamples)
+		minuteSample := GetMinutePercentiles(s.secondSamples, s.config.Quantiles...)
 		// Clear seconds samples. Keep the latest sample for continuity.
 		// Copying and resizing helps avoid slice re-allocation.
 		s.secondSamples[0] = s.secondSamples[numSamples-1]
@@ -134,6 +170,16 @@ func (s *StatsSummary) updateDerivedStats() error {
 	}
 	derived.HourUsage = hourUsage
 	derived.DayUsage = dayUsage
+	if len(s.config.Windows) > 0 {
+		derived.Windows = make(map[string]info.Usage, len(s.config.Windows))
+		for _, w := range s.config.Windows {
+			usage, err := s.getDerivedUsage(int(w / time.Minute))
+			if err != nil {
+				return fmt.Errorf("failed to compute %v usage: %v", w, err)
+			}
+			derived.Windows[WindowName(w)] = usage
+		}
+	}
 
 	s.dataLock.Lock()
 	defer s.dataLock.Unlock()
@@ -154,7 +200,7 @@ func (s *StatsSummary) getDerivedUsage(n int) (info.Usage, error) {
 		return info.Usage{}, fmt.Errorf("failed to retrieve any minute stats")
 	}
 	// We generate derived stats even with partial data.
-	usage := GetDerivedPercentiles(samples)
+	usage := GetDerivedPercentiles(samples, s.config.Quantiles...)
 	// Assumes we have equally placed minute samples.
 	usage.PercentComplete = int32(numSamples * 100 / n)
 	return usage, nil
@@ -168,8 +214,11 @@ func (s *StatsSummary) DerivedStats() (info.DerivedStats, error) {
 	return s.derivedStats, nil
 }
 
-func New(spec v1.ContainerSpec) (*StatsSummary, error) {
-	summary := StatsSummary{}
+func New(spec v1.ContainerSpec, config Config) (*StatsSummary, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	summary := StatsSummary{config: config}
 	if spec.HasCpu {
 		summary.available.Cpu = true
 	}
@@ -179,6 +228,12 @@ func New(spec v1.ContainerSpec) (*StatsSummary, error) {
 	if !summary.available.Cpu && !summary.available.Memory {
 		return nil, fmt.Errorf("none of the resources are being tracked")
 	}
-	summary.minuteSamples = NewSamplesBuffer(60 /* one hour */)
+	size := 60 // one hour
+	for _, w := range config.Windows {
+		if n := int(w / time.Minute); n > size {
+			size = n
+		}
+	}
+	summary.minuteSamples = NewSamplesBuffer(size)
 	return &summary, nil
 }
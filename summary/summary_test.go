@@ -0,0 +1,67 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/google/cadvisor/info/v1"
+)
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, Config{Windows: []time.Duration{5 * time.Minute, 6 * time.Hour}, Quantiles: []float64{0.75}}.Validate())
+	assert.Error(t, Config{Windows: []time.Duration{30 * time.Second}}.Validate())
+	assert.Error(t, Config{Windows: []time.Duration{90 * time.Second}}.Validate())
+	assert.Error(t, Config{Quantiles: []float64{1}}.Validate())
+	assert.Error(t, Config{Quantiles: []float64{0}}.Validate())
+}
+
+func TestWindowName(t *testing.T) {
+	assert.Equal(t, "5m", WindowName(5*time.Minute))
+	assert.Equal(t, "90m", WindowName(90*time.Minute))
+	assert.Equal(t, "6h", WindowName(6*time.Hour))
+}
+
+func TestWindows(t *testing.T) {
+	config := Config{Windows: []time.Duration{2 * time.Minute, 2 * time.Hour}, Quantiles: []float64{0.75}}
+	s, err := New(v1.ContainerSpec{HasCpu: true, HasMemory: true}, config)
+	require.NoError(t, err)
+
+	ct := time.Now()
+	var cpu uint64
+	// Four minutes of samples at 1 cpu/s, with the memory growing every minute.
+	for i := 0; i <= 4*60; i += 10 {
+		cpu += 10 * Nanosecond
+		stat := v1.ContainerStats{Timestamp: ct.Add(time.Duration(i) * time.Second)}
+		stat.Cpu.Usage.Total = cpu
+		stat.Memory.WorkingSet = uint64(i/60+1) * 1024
+		require.NoError(t, s.AddSample(stat))
+	}
+
+	derived, err := s.DerivedStats()
+	require.NoError(t, err)
+	require.Len(t, derived.Windows, 2)
+	twoMinutes := derived.Windows["2m"]
+	assert.EqualValues(t, 100, twoMinutes.PercentComplete)
+	assert.EqualValues(t, 1000, twoMinutes.Cpu.NinetyNine)
+	assert.EqualValues(t, 1000, twoMinutes.Cpu.Quantiles["0.75"])
+	twoHours := derived.Windows["2h"]
+	assert.EqualValues(t, 2, twoHours.PercentComplete)
+	assert.EqualValues(t, 4*1024, twoHours.Memory.Max)
+}
@@ -15,7 +15,7 @@
 package cloudinfo
 
 import (
-	"io/ioutil"
+	"context"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -27,6 +27,7 @@ import (
 )
 
 const (
+	sysVendorFileName        = "/sys/class/dmi/id/sys_vendor"
 	productVerFileName       = "/sys/class/dmi/id/product_version"
 	biosVerFileName          = "/sys/class/dmi/id/bios_vendor"
 	systemdOSReleaseFileName = "/etc/os-release"
@@ -34,45 +35,45 @@ const (
 )
 
 func init() {
-	cloudinfo.RegisterCloudProvider(info.AWS, &provider{})
+	cloudinfo.RegisterDetector(info.AWS, detector{})
 }
 
-type provider struct{}
+type detector struct{}
 
-var _ cloudinfo.CloudProvider = provider{}
+var _ cloudinfo.Detector = detector{}
 
-func (provider) IsActiveProvider() bool {
-	return fileContainsAmazonIdentifier(productVerFileName) ||
+// Active checks the DMI data, which Xen instances report as "amazon" and
+// Nitro instances as "Amazon EC2".
+func (detector) Active() bool {
+	return fileContainsAmazonIdentifier(sysVendorFileName) ||
+		fileContainsAmazonIdentifier(productVerFileName) ||
 		fileContainsAmazonIdentifier(biosVerFileName) ||
 		fileContainsAmazonIdentifier(systemdOSReleaseFileName)
 }
 
 func fileContainsAmazonIdentifier(filename string) bool {
-	fileContent, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return false
-	}
-
-	return strings.Contains(string(fileContent), amazon)
+	return strings.Contains(strings.ToLower(cloudinfo.ReadDMI(filename)), amazon)
 }
 
-func getAwsMetadata(name string) string {
-	sess, err := session.NewSession(&aws.Config{})
+// Instance queries the instance metadata service. The client uses IMDSv2
+// session tokens, falling back to IMDSv1 if the instance does not support
+// them.
+func (detector) Instance(ctx context.Context) (cloudinfo.Instance, error) {
+	sess, err := session.NewSession(&aws.Config{MaxRetries: aws.Int(0)})
 	if err != nil {
-		return info.UnknownInstance
+		return cloudinfo.Instance{}, err
 	}
 	client := ec2metadata.New(sess)
-	data, err := client.GetMetadata(name)
+	instanceType, err := client.GetMetadataWithContext(ctx, "instance-type")
 	if err != nil {
-		return info.UnknownInstance
+		return cloudinfo.Instance{}, err
 	}
-	return data
-}
-
-func (provider) GetInstanceType() info.InstanceType {
-	return info.InstanceType(getAwsMetadata("instance-type"))
-}
-
-func (provider) GetInstanceID() info.InstanceID {
-	return info.InstanceID(getAwsMetadata("instance-id"))
+	instanceID, err := client.GetMetadataWithContext(ctx, "instance-id")
+	if err != nil {
+		return cloudinfo.Instance{}, err
+	}
+	return cloudinfo.Instance{
+		Type: info.InstanceType(instanceType),
+		ID:   info.InstanceID(instanceID),
+	}, nil
 }
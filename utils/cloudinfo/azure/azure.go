@@ -15,44 +15,56 @@
 package cloudinfo
 
 import (
-	"io/ioutil"
-	"strings"
+	"context"
+	"encoding/json"
+	"net/http"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/cloudinfo"
 )
 
 const (
-	sysVendorFileName    = "/sys/class/dmi/id/sys_vendor"
-	biosUUIDFileName     = "/sys/class/dmi/id/product_uuid"
-	microsoftCorporation = "Microsoft Corporation"
+	sysVendorFileName       = "/sys/class/dmi/id/sys_vendor"
+	chassisAssetTagFileName = "/sys/class/dmi/id/chassis_asset_tag"
+	microsoftCorporation    = "Microsoft Corporation"
+	// azureChassisAssetTag tells Azure virtual machines apart from other
+	// Hyper-V guests.
+	azureChassisAssetTag = "7783-7084-3265-9085-8269-3286-77"
 )
 
+// computeMetadataURL is the compute section of the Azure Instance Metadata
+// Service.
+var computeMetadataURL = "http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01&format=json"
+
 func init() {
-	cloudinfo.RegisterCloudProvider(info.Azure, &provider{})
+	cloudinfo.RegisterDetector(info.Azure, detector{})
 }
 
-type provider struct{}
+type detector struct{}
 
-var _ cloudinfo.CloudProvider = provider{}
+var _ cloudinfo.Detector = detector{}
 
-func (provider) IsActiveProvider() bool {
-	data, err := ioutil.ReadFile(sysVendorFileName)
-	if err != nil {
-		return false
-	}
-	return strings.Contains(string(data), microsoftCorporation)
+func (detector) Active() bool {
+	return cloudinfo.ReadDMI(sysVendorFileName) == microsoftCorporation &&
+		cloudinfo.ReadDMI(chassisAssetTagFileName) == azureChassisAssetTag
 }
 
-// TODO: Implement method.
-func (provider) GetInstanceType() info.InstanceType {
-	return info.UnknownInstance
+type computeMetadata struct {
+	VMSize string `json:"vmSize"`
+	VMID   string `json:"vmId"`
 }
 
-func (provider) GetInstanceID() info.InstanceID {
-	data, err := ioutil.ReadFile(biosUUIDFileName)
+func (detector) Instance(ctx context.Context) (cloudinfo.Instance, error) {
+	data, err := cloudinfo.GetMetadata(ctx, computeMetadataURL, http.Header{"Metadata": {"true"}})
 	if err != nil {
-		return info.UnNamedInstance
+		return cloudinfo.Instance{}, err
+	}
+	var compute computeMetadata
+	if err := json.Unmarshal(data, &compute); err != nil {
+		return cloudinfo.Instance{}, err
 	}
-	return info.InstanceID(strings.TrimSuffix(string(data), "\n"))
+	return cloudinfo.Instance{
+		Type: info.InstanceType(compute.VMSize),
+		ID:   info.InstanceID(compute.VMID),
+	}, nil
 }
@@ -17,17 +17,48 @@
 package cloudinfo
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
 	info "github.com/google/cadvisor/info/v1"
 	"k8s.io/klog/v2"
 )
 
+// DefaultTimeout bounds the queries to metadata services made by
+// NewRealCloudInfo.
+const DefaultTimeout = 2 * time.Second
+
 type CloudInfo interface {
 	GetCloudProvider() info.CloudProvider
 	GetInstanceType() info.InstanceType
 	GetInstanceID() info.InstanceID
 }
 
+// Instance describes the cloud instance the machine is.
+type Instance struct {
+	Type info.InstanceType
+	ID   info.InstanceID
+}
+
+// Detector detects the cloud provider operating this instance and queries
+// its metadata service.
+type Detector interface {
+	// Active determines whether this is the cloud provider operating this
+	// instance. It only looks at local data (e.g. DMI), so that machines
+	// outside of the cloud are never slowed down by metadata queries.
+	Active() bool
+	// Instance describes this instance from the metadata service of the
+	// provider. It must return once ctx is done.
+	Instance(ctx context.Context) (Instance, error)
+}
+
 // CloudProvider is an abstraction for providing cloud-specific information.
+//
+// Deprecated: implement Detector instead, the queries of a CloudProvider
+// cannot be bounded by a timeout.
 type CloudProvider interface {
 	// IsActiveProvider determines whether this is the cloud provider operating
 	// this instance.
@@ -40,14 +71,41 @@ type CloudProvider interface {
 	GetInstanceID() info.InstanceID
 }
 
-var providers = map[info.CloudProvider]CloudProvider{}
+var (
+	detectorsLock sync.Mutex
+	detectors     = map[info.CloudProvider]Detector{}
+	// detected caches the instance once it was successfully described,
+	// it does not change while cAdvisor is running.
+	detected *realCloudInfo
+)
+
+// RegisterDetector registers the detector of the given cloud provider.
+func RegisterDetector(name info.CloudProvider, detector Detector) {
+	detectorsLock.Lock()
+	defer detectorsLock.Unlock()
+	if _, alreadyRegistered := detectors[name]; alreadyRegistered {
+		klog.Warningf("Duplicate registration of CloudProvider %s", name)
+	}
+	detectors[name] = detector
+}
 
 // RegisterCloudProvider registers the given cloud provider
+//
+// Deprecated: use RegisterDetector.
 func RegisterCloudProvider(name info.CloudProvider, provider CloudProvider) {
-	if _, alreadyRegistered := providers[name]; alreadyRegistered {
-		klog.Warningf("Duplicate registration of CloudProvider %s", name)
-	}
-	providers[name] = provider
+	RegisterDetector(name, cloudProviderDetector{provider})
+}
+
+type cloudProviderDetector struct {
+	provider CloudProvider
+}
+
+func (d cloudProviderDetector) Active() bool {
+	return d.provider.IsActiveProvider()
+}
+
+func (d cloudProviderDetector) Instance(context.Context) (Instance, error) {
+	return Instance{Type: d.provider.GetInstanceType(), ID: d.provider.GetInstanceID()}, nil
 }
 
 type realCloudInfo struct {
@@ -56,15 +114,56 @@ type realCloudInfo struct {
 	instanceID    info.InstanceID
 }
 
+// NewRealCloudInfo detects the cloud provider, bounding metadata queries by
+// DefaultTimeout. Failures are logged and reported as unknown instances.
 func NewRealCloudInfo() CloudInfo {
-	for name, provider := range providers {
-		if provider.IsActiveProvider() {
-			return &realCloudInfo{
-				cloudProvider: name,
-				instanceType:  provider.GetInstanceType(),
-				instanceID:    provider.GetInstanceID(),
-			}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	cloudInfo, err := Detect(ctx)
+	if err != nil {
+		klog.Warningf("Failed to detect the cloud instance: %v", err)
+	}
+	return cloudInfo
+}
+
+// Detect detects the cloud provider operating this instance and describes the
+// instance from its metadata service, which must respond before ctx is done.
+// Detectors are tried in the order of their names, the first active one is
+// used. If it fails, the provider is still returned along with the error.
+func Detect(ctx context.Context) (CloudInfo, error) {
+	detectorsLock.Lock()
+	defer detectorsLock.Unlock()
+	if detected != nil {
+		return detected, nil
+	}
+
+	names := make([]string, 0, len(detectors))
+	for name := range detectors {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		detector := detectors[info.CloudProvider(name)]
+		if !detector.Active() {
+			continue
+		}
+		cloudInfo := &realCloudInfo{
+			cloudProvider: info.CloudProvider(name),
+			instanceType:  info.UnknownInstance,
+			instanceID:    info.UnNamedInstance,
 		}
+		instance, err := detector.Instance(ctx)
+		if err != nil {
+			return cloudInfo, fmt.Errorf("failed to query %s instance metadata: %v", name, err)
+		}
+		if instance.Type != "" {
+			cloudInfo.instanceType = instance.Type
+		}
+		if instance.ID != "" {
+			cloudInfo.instanceID = instance.ID
+		}
+		detected = cloudInfo
+		return cloudInfo, nil
 	}
 
 	// No registered active provider.
@@ -72,7 +171,7 @@ func NewRealCloudInfo() CloudInfo {
 		cloudProvider: info.UnknownProvider,
 		instanceType:  info.UnknownInstance,
 		instanceID:    info.UnNamedInstance,
-	}
+	}, nil
 }
 
 func (i *realCloudInfo) GetCloudProvider() info.CloudProvider {
@@ -0,0 +1,110 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudinfo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDetector struct {
+	active   bool
+	instance Instance
+	// block makes Instance wait for its context to be done.
+	block   bool
+	queries int
+}
+
+func (d *fakeDetector) Active() bool {
+	return d.active
+}
+
+func (d *fakeDetector) Instance(ctx context.Context) (Instance, error) {
+	d.queries++
+	if d.block {
+		<-ctx.Done()
+		return Instance{}, ctx.Err()
+	}
+	return d.instance, nil
+}
+
+func resetDetectors(registered map[info.CloudProvider]Detector) {
+	detectorsLock.Lock()
+	defer detectorsLock.Unlock()
+	detectors = registered
+	detected = nil
+}
+
+func TestDetectUnknown(t *testing.T) {
+	defer resetDetectors(map[info.CloudProvider]Detector{})
+	inactive := &fakeDetector{}
+	resetDetectors(map[info.CloudProvider]Detector{info.AWS: inactive})
+
+	cloudInfo, err := Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, info.UnknownProvider, cloudInfo.GetCloudProvider())
+	assert.Equal(t, info.InstanceType(info.UnknownInstance), cloudInfo.GetInstanceType())
+	assert.Equal(t, info.UnNamedInstance, cloudInfo.GetInstanceID())
+	// Inactive providers never query their metadata service.
+	assert.Equal(t, 0, inactive.queries)
+}
+
+func TestDetect(t *testing.T) {
+	defer resetDetectors(map[info.CloudProvider]Detector{})
+	gce := &fakeDetector{active: true, instance: Instance{Type: "e2-small", ID: "1234"}}
+	resetDetectors(map[info.CloudProvider]Detector{
+		info.AWS:       &fakeDetector{},
+		info.GCE:       gce,
+		info.OpenStack: &fakeDetector{active: true, instance: Instance{Type: "m1.small"}},
+	})
+
+	cloudInfo, err := Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, info.GCE, cloudInfo.GetCloudProvider())
+	assert.Equal(t, info.InstanceType("e2-small"), cloudInfo.GetInstanceType())
+	assert.Equal(t, info.InstanceID("1234"), cloudInfo.GetInstanceID())
+
+	// The instance is only described once.
+	_, err = Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, gce.queries)
+}
+
+func TestDetectTimeout(t *testing.T) {
+	defer resetDetectors(map[info.CloudProvider]Detector{})
+	azure := &fakeDetector{active: true, block: true}
+	resetDetectors(map[info.CloudProvider]Detector{info.Azure: azure})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cloudInfo, err := Detect(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, info.Azure, cloudInfo.GetCloudProvider())
+	assert.Equal(t, info.InstanceType(info.UnknownInstance), cloudInfo.GetInstanceType())
+	assert.Equal(t, info.UnNamedInstance, cloudInfo.GetInstanceID())
+
+	// Failures are retried.
+	azure.block = false
+	azure.instance = Instance{Type: "Standard_D2s_v3", ID: "vm-id"}
+	cloudInfo, err = Detect(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, info.InstanceID("vm-id"), cloudInfo.GetInstanceID())
+	assert.Equal(t, 2, azure.queries)
+}
@@ -15,14 +15,14 @@
 package gce
 
 import (
-	"io/ioutil"
+	"context"
+	"net/http"
 	"strings"
 
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/utils/cloudinfo"
 
 	"cloud.google.com/go/compute/metadata"
-	"k8s.io/klog/v2"
 )
 
 const (
@@ -31,36 +31,41 @@ const (
 )
 
 func init() {
-	cloudinfo.RegisterCloudProvider(info.GCE, &provider{})
+	cloudinfo.RegisterDetector(info.GCE, detector{})
 }
 
-type provider struct{}
+type detector struct{}
 
-var _ cloudinfo.CloudProvider = provider{}
+var _ cloudinfo.Detector = detector{}
 
-func (provider) IsActiveProvider() bool {
-	data, err := ioutil.ReadFile(gceProductName)
-	if err != nil {
-		klog.V(2).Infof("Error while reading product_name: %v", err)
-		return false
-	}
-	return strings.Contains(string(data), google)
+func (detector) Active() bool {
+	return strings.Contains(cloudinfo.ReadDMI(gceProductName), google)
 }
 
-func (provider) GetInstanceType() info.InstanceType {
-	machineType, err := metadata.Get("instance/machine-type")
+func (detector) Instance(ctx context.Context) (cloudinfo.Instance, error) {
+	client := metadata.NewClient(&http.Client{Transport: contextTransport{ctx}})
+	machineType, err := client.Get("instance/machine-type")
+	if err != nil {
+		return cloudinfo.Instance{}, err
+	}
+	instanceID, err := client.Get("instance/id")
 	if err != nil {
-		return info.UnknownInstance
+		return cloudinfo.Instance{}, err
 	}
 
 	responseParts := strings.Split(machineType, "/") // Extract the instance name from the machine type.
-	return info.InstanceType(responseParts[len(responseParts)-1])
+	return cloudinfo.Instance{
+		Type: info.InstanceType(responseParts[len(responseParts)-1]),
+		ID:   info.InstanceID(instanceID),
+	}, nil
 }
 
-func (provider) GetInstanceID() info.InstanceID {
-	instanceID, err := metadata.Get("instance/id")
-	if err != nil {
-		return info.UnknownInstance
-	}
-	return info.InstanceID(info.InstanceType(instanceID))
+// contextTransport bounds the requests of the metadata client, which does not
+// take a context, by ctx.
+type contextTransport struct {
+	ctx context.Context
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(req.WithContext(t.ctx))
 }
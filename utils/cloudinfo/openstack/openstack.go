@@ -0,0 +1,76 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/utils/cloudinfo"
+)
+
+const (
+	sysVendorFileName   = "/sys/class/dmi/id/sys_vendor"
+	productNameFileName = "/sys/class/dmi/id/product_name"
+	openStack           = "OpenStack"
+)
+
+var (
+	// metadataURL is the OpenStack flavored document of the metadata service.
+	metadataURL = "http://169.254.169.254/openstack/latest/meta_data.json"
+	// instanceTypeURL is the EC2 compatible instance type, i.e. the name of
+	// the flavor, which the OpenStack document does not contain.
+	instanceTypeURL = "http://169.254.169.254/latest/meta-data/instance-type"
+)
+
+func init() {
+	cloudinfo.RegisterDetector(info.OpenStack, detector{})
+}
+
+type detector struct{}
+
+var _ cloudinfo.Detector = detector{}
+
+// Active checks the DMI data set by Nova, e.g. "OpenStack Foundation" and
+// "OpenStack Nova".
+func (detector) Active() bool {
+	return strings.Contains(cloudinfo.ReadDMI(sysVendorFileName), openStack) ||
+		strings.Contains(cloudinfo.ReadDMI(productNameFileName), openStack)
+}
+
+type metadata struct {
+	UUID string `json:"uuid"`
+}
+
+func (detector) Instance(ctx context.Context) (cloudinfo.Instance, error) {
+	data, err := cloudinfo.GetMetadata(ctx, metadataURL, nil)
+	if err != nil {
+		return cloudinfo.Instance{}, err
+	}
+	var md metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return cloudinfo.Instance{}, err
+	}
+	instance := cloudinfo.Instance{ID: info.InstanceID(md.UUID)}
+
+	// The EC2 compatible API may be disabled, the instance type is then
+	// reported as unknown.
+	if flavor, err := cloudinfo.GetMetadata(ctx, instanceTypeURL, nil); err == nil {
+		instance.Type = info.InstanceType(strings.TrimSpace(string(flavor)))
+	}
+	return instance, nil
+}
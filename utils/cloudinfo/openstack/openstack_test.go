@@ -0,0 +1,63 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/cadvisor/utils/cloudinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstance(t *testing.T) {
+	ec2Compatible := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/openstack/latest/meta_data.json":
+			w.Write([]byte(`{"uuid": "d8e02d56-2648-49a3-bf97-6be8f1204f38", "name": "node-1"}`))
+		case "/latest/meta-data/instance-type":
+			if ec2Compatible {
+				w.Write([]byte("m1.small"))
+				return
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(metadata, instanceType string) {
+		metadataURL, instanceTypeURL = metadata, instanceType
+	}(metadataURL, instanceTypeURL)
+	metadataURL = server.URL + "/openstack/latest/meta_data.json"
+	instanceTypeURL = server.URL + "/latest/meta-data/instance-type"
+
+	instance, err := detector{}.Instance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cloudinfo.Instance{Type: "m1.small", ID: "d8e02d56-2648-49a3-bf97-6be8f1204f38"}, instance)
+
+	ec2Compatible = false
+	instance, err = detector{}.Instance(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cloudinfo.Instance{ID: "d8e02d56-2648-49a3-bf97-6be8f1204f38"}, instance)
+
+	metadataURL = server.URL + "/missing"
+	_, err = detector{}.Instance(context.Background())
+	assert.Error(t, err)
+}
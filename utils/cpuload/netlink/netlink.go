@@ -52,10 +52,20 @@ func (m netlinkMessage) toRawMsg() (rawmsg syscall.NetlinkMessage) {
 	return rawmsg
 }
 
+// cgroupStats is the cgroupstats struct of the kernel, see
+// include/uapi/linux/cgroupstats.h.
+type cgroupStats struct {
+	NrSleeping        uint64
+	NrRunning         uint64
+	NrStopped         uint64
+	NrUninterruptible uint64
+	NrIoWait          uint64
+}
+
 type loadStatsResp struct {
 	Header    syscall.NlMsghdr
 	GenHeader genMsghdr
-	Stats     info.LoadStats
+	Stats     cgroupStats
 }
 
 // Return required padding to align 'size' to 'alignment'.
@@ -236,5 +246,11 @@ func getLoadStats(id uint16, cfd *os.File, conn *Connection) (info.LoadStats, er
 	if err != nil {
 		return info.LoadStats{}, err
 	}
-	return parsedmsg.Stats, nil
+	return info.LoadStats{
+		NrSleeping:        parsedmsg.Stats.NrSleeping,
+		NrRunning:         parsedmsg.Stats.NrRunning,
+		NrStopped:         parsedmsg.Stats.NrStopped,
+		NrUninterruptible: parsedmsg.Stats.NrUninterruptible,
+		NrIoWait:          parsedmsg.Stats.NrIoWait,
+	}, nil
 }
@@ -0,0 +1,151 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ethtool reads driver statistics of network devices, i.e. the
+// counters reported by `ethtool -S`.
+package ethtool
+
+import (
+	"bytes"
+	"fmt"
+	"unsafe"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"golang.org/x/sys/unix"
+)
+
+// Driver specific statistics are only exposed through the SIOCETHTOOL ioctl,
+// the ethtool netlink interface carries the standardized counters only.
+const (
+	ethtoolGStrings  = 0x0000001b
+	ethtoolGStats    = 0x0000001d
+	ethtoolGSsetInfo = 0x00000037
+
+	ethSsStats    = 1
+	ethGStringLen = 32
+
+	// Upper bound on the number of statistics a driver may report, protects
+	// against allocating huge buffers on bogus replies.
+	maxStats = 1 << 16
+)
+
+type ifreq struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+type ssetInfo struct {
+	cmd      uint32
+	reserved uint32
+	mask     uint64
+	data     uint32
+}
+
+// Client issues ethtool requests over an AF_INET datagram socket.
+type Client struct {
+	fd int
+}
+
+// NewClient opens the socket used to send ethtool requests.
+func NewClient() (*Client, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ethtool socket: %v", err)
+	}
+	return &Client{fd: fd}, nil
+}
+
+// Close releases the socket held by the client.
+func (c *Client) Close() error {
+	return unix.Close(c.fd)
+}
+
+// Stats returns the driver statistics of the interface keyed by counter name.
+func (c *Client) Stats(ifName string) (map[string]uint64, error) {
+	count, err := c.statsCount(ifName)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return map[string]uint64{}, nil
+	}
+
+	// struct ethtool_gstrings: cmd, string_set, len followed by the strings.
+	names := make([]byte, 12+count*ethGStringLen)
+	*(*uint32)(unsafe.Pointer(&names[0])) = ethtoolGStrings
+	*(*uint32)(unsafe.Pointer(&names[4])) = ethSsStats
+	*(*uint32)(unsafe.Pointer(&names[8])) = count
+	if err := c.ioctl(ifName, unsafe.Pointer(&names[0])); err != nil {
+		return nil, fmt.Errorf("failed to get stat names of %q: %v", ifName, err)
+	}
+
+	// struct ethtool_stats: cmd, n_stats followed by the 64-bit values.
+	values := make([]uint64, 1+count)
+	header := (*[2]uint32)(unsafe.Pointer(&values[0]))
+	header[0], header[1] = ethtoolGStats, count
+	if err := c.ioctl(ifName, unsafe.Pointer(&values[0])); err != nil {
+		return nil, fmt.Errorf("failed to get stat values of %q: %v", ifName, err)
+	}
+
+	stats := make(map[string]uint64, count)
+	for i := uint32(0); i < count; i++ {
+		name := names[12+i*ethGStringLen : 12+(i+1)*ethGStringLen]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		stats[string(name)] = values[1+i]
+	}
+	return stats, nil
+}
+
+// QueueStats returns the per-queue statistics of the interface.
+func (c *Client) QueueStats(ifName string) ([]info.InterfaceQueueStats, error) {
+	stats, err := c.Stats(ifName)
+	if err != nil {
+		return nil, err
+	}
+	return parseQueueStats(ifName, stats), nil
+}
+
+func (c *Client) statsCount(ifName string) (uint32, error) {
+	req := ssetInfo{
+		cmd:  ethtoolGSsetInfo,
+		mask: 1 << ethSsStats,
+	}
+	if err := c.ioctl(ifName, unsafe.Pointer(&req)); err != nil {
+		return 0, fmt.Errorf("failed to get stats count of %q: %v", ifName, err)
+	}
+	if req.mask == 0 {
+		return 0, nil
+	}
+	if req.data > maxStats {
+		return 0, fmt.Errorf("driver of %q reports too many stats: %d", ifName, req.data)
+	}
+	return req.data, nil
+}
+
+func (c *Client) ioctl(ifName string, data unsafe.Pointer) error {
+	if len(ifName) >= unix.IFNAMSIZ {
+		return fmt.Errorf("interface name %q is too long", ifName)
+	}
+	req := ifreq{data: data}
+	copy(req.name[:], ifName)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(c.fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
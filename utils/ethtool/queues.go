@@ -0,0 +1,114 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtool
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+var (
+	// Matches e.g. "rx_queue_0_packets" (virtio_net, ixgbe, ice),
+	// "rx0_packets" (mlx5) and "rx-0.rx_packets" (i40e).
+	directionFirstRegexp = regexp.MustCompile(`^(rx|tx)[_-]?(?:queue[_-])?(\d+)[._](.+)$`)
+	// Matches e.g. "queue_0_rx_cnt" (ena).
+	queueFirstRegexp = regexp.MustCompile(`^queue_(\d+)_(rx|tx)_(.+)$`)
+)
+
+type queueKey struct {
+	direction string
+	queue     int
+}
+
+// parseQueueStats groups the driver statistics of an interface by queue.
+// Counters that cannot be attributed to a queue are ignored.
+func parseQueueStats(ifName string, stats map[string]uint64) []info.InterfaceQueueStats {
+	queues := map[queueKey]*info.InterfaceQueueStats{}
+	for name, value := range stats {
+		direction, queue, counter, ok := splitQueueStatName(name)
+		if !ok {
+			continue
+		}
+		key := queueKey{direction: direction, queue: queue}
+		q, ok := queues[key]
+		if !ok {
+			q = &info.InterfaceQueueStats{
+				Interface: ifName,
+				Direction: direction,
+				Queue:     queue,
+			}
+			queues[key] = q
+		}
+		setQueueCounter(q, counter, value)
+	}
+
+	result := make([]info.InterfaceQueueStats, 0, len(queues))
+	for _, q := range queues {
+		result = append(result, *q)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Direction != result[j].Direction {
+			return result[i].Direction < result[j].Direction
+		}
+		return result[i].Queue < result[j].Queue
+	})
+	return result
+}
+
+func splitQueueStatName(name string) (string, int, string, bool) {
+	var direction, queue, counter string
+	if m := directionFirstRegexp.FindStringSubmatch(name); m != nil {
+		direction, queue, counter = m[1], m[2], m[3]
+	} else if m := queueFirstRegexp.FindStringSubmatch(name); m != nil {
+		direction, queue, counter = m[2], m[1], m[3]
+	} else {
+		return "", 0, "", false
+	}
+	index, err := strconv.Atoi(queue)
+	if err != nil {
+		return "", 0, "", false
+	}
+	return direction, index, strings.TrimPrefix(counter, direction+"_"), true
+}
+
+func setQueueCounter(q *info.InterfaceQueueStats, counter string, value uint64) {
+	if strings.HasPrefix(counter, "xdp_") {
+		action := strings.TrimPrefix(counter, "xdp_")
+		if !strings.HasSuffix(action, "ss") {
+			// Drivers disagree on plurals, e.g. "xdp_drop" vs. "xdp_drops".
+			action = strings.TrimSuffix(action, "s")
+		}
+		if action == "" || action == "packet" || action == "byte" {
+			return
+		}
+		if q.Xdp == nil {
+			q.Xdp = map[string]uint64{}
+		}
+		q.Xdp[action] += value
+		return
+	}
+	switch counter {
+	case "packets", "cnt":
+		q.Packets = value
+	case "bytes":
+		q.Bytes = value
+	case "drops", "dropped", "drop":
+		q.Drops = value
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ethtool
+
+import (
+	"testing"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQueueStatsVirtio(t *testing.T) {
+	stats := map[string]uint64{
+		"rx_queue_0_packets":       10,
+		"rx_queue_0_bytes":         1000,
+		"rx_queue_0_drops":         1,
+		"rx_queue_0_xdp_drops":     2,
+		"rx_queue_0_xdp_redirects": 3,
+		"rx_queue_0_xdp_packets":   5,
+		"tx_queue_0_packets":       20,
+		"tx_queue_0_bytes":         2000,
+		"tx_queue_0_xdp_tx":        4,
+		"tx_queue_1_packets":       30,
+		"rx_csum_errors":           7,
+	}
+
+	expected := []info.InterfaceQueueStats{
+		{
+			Interface: "eth0",
+			Direction: "rx",
+			Queue:     0,
+			Packets:   10,
+			Bytes:     1000,
+			Drops:     1,
+			Xdp:       map[string]uint64{"drop": 2, "redirect": 3},
+		},
+		{
+			Interface: "eth0",
+			Direction: "tx",
+			Queue:     0,
+			Packets:   20,
+			Bytes:     2000,
+			Xdp:       map[string]uint64{"tx": 4},
+		},
+		{
+			Interface: "eth0",
+			Direction: "tx",
+			Queue:     1,
+			Packets:   30,
+		},
+	}
+	assert.Equal(t, expected, parseQueueStats("eth0", stats))
+}
+
+func TestParseQueueStatsDriverNaming(t *testing.T) {
+	stats := map[string]uint64{
+		// mlx5
+		"rx0_packets":  1,
+		"rx0_xdp_pass": 2,
+		// i40e
+		"tx-1.tx_packets": 3,
+		"tx-1.tx_bytes":   4,
+		// ena
+		"queue_2_rx_cnt":   5,
+		"queue_2_rx_drops": 6,
+	}
+
+	expected := []info.InterfaceQueueStats{
+		{Interface: "eth0", Direction: "rx", Queue: 0, Packets: 1, Xdp: map[string]uint64{"pass": 2}},
+		{Interface: "eth0", Direction: "rx", Queue: 2, Packets: 5, Drops: 6},
+		{Interface: "eth0", Direction: "tx", Queue: 1, Packets: 3, Bytes: 4},
+	}
+	assert.Equal(t, expected, parseQueueStats("eth0", stats))
+}
@@ -67,6 +67,12 @@ type FakeSysFs struct {
 	physicalPackageIDs   map[string]string
 	physicalPackageIDErr map[string]error
 
+	clusterIDs    map[string]string
+	bookIDs       map[string]string
+	drawerIDs     map[string]string
+	cpuCapacities map[string]string
+	midrs         map[string]string
+
 	memTotal string
 	memErr   error
 
@@ -77,6 +83,9 @@ type FakeSysFs struct {
 	hugePagesNrErr error
 
 	onlineCPUs map[string]interface{}
+
+	blockDeviceSlaves map[string][]string
+	blockDeviceDisks  map[string]string
 }
 
 func (fs *FakeSysFs) GetNodesPaths() ([]string, error) {
@@ -95,6 +104,26 @@ func (fs *FakeSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
 	return fs.physicalPackageIDs[cpuPath], fs.physicalPackageIDErr[cpuPath]
 }
 
+func (fs *FakeSysFs) GetCPUClusterID(cpuPath string) (string, error) {
+	return fs.clusterIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetCPUBookID(cpuPath string) (string, error) {
+	return fs.bookIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetCPUDrawerID(cpuPath string) (string, error) {
+	return fs.drawerIDs[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetCPUCapacity(cpuPath string) (string, error) {
+	return fs.cpuCapacities[cpuPath], nil
+}
+
+func (fs *FakeSysFs) GetCPUMIDR(cpuPath string) (string, error) {
+	return fs.midrs[cpuPath], nil
+}
+
 func (fs *FakeSysFs) GetMemInfo(nodePath string) (string, error) {
 	return fs.memTotal, fs.memErr
 }
@@ -125,6 +154,37 @@ func (fs *FakeSysFs) GetBlockDeviceNumbers(name string) (string, error) {
 	return "8:0\n", nil
 }
 
+func (fs *FakeSysFs) GetBlockDevicePCIAddress(name string) (string, error) {
+	return "0000:00:04.0", nil
+}
+
+func (fs *FakeSysFs) GetBlockDeviceAttribute(name string, attribute string) (string, error) {
+	value, ok := map[string]string{
+		"queue/rotational":        "1\n",
+		"queue/nr_requests":       "64\n",
+		"queue/write_cache":       "write back\n",
+		"queue/discard_max_bytes": "2147450880\n",
+		"device/queue_depth":      "32\n",
+		"dm/name":                 "vg0-root\n",
+		"dm/uuid":                 "LVM-Lk1hW8SJ3sDvoLbVgwp8t8iLkTCC3cHi\n",
+	}[attribute]
+	if !ok {
+		return "", &os.PathError{Op: "open", Path: attribute, Err: os.ErrNotExist}
+	}
+	return value, nil
+}
+
+func (fs *FakeSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	return fs.blockDeviceSlaves[name], nil
+}
+
+func (fs *FakeSysFs) GetBlockDeviceDisk(name string) (string, error) {
+	if disk, ok := fs.blockDeviceDisks[name]; ok {
+		return disk, nil
+	}
+	return name, nil
+}
+
 func (fs *FakeSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 	return []os.FileInfo{&fs.info}, nil
 }
@@ -145,6 +205,10 @@ func (fs *FakeSysFs) GetNetworkStatValue(name string, stat string) (uint64, erro
 	return 1024, nil
 }
 
+func (fs *FakeSysFs) GetNetworkPCIAddress(name string) (string, error) {
+	return "0000:00:03.0", nil
+}
+
 func (fs *FakeSysFs) GetCaches(id int) ([]os.FileInfo, error) {
 	fs.info.EntryName = "index0"
 	return []os.FileInfo{&fs.info}, nil
@@ -178,6 +242,33 @@ func (fs *FakeSysFs) SetPhysicalPackageIDs(physicalPackageIDs map[string]string,
 	fs.physicalPackageIDErr = physicalPackageIDErrors
 }
 
+func (fs *FakeSysFs) SetClusterIDs(clusterIDs map[string]string) {
+	fs.clusterIDs = clusterIDs
+}
+
+func (fs *FakeSysFs) SetBookIDs(bookIDs map[string]string) {
+	fs.bookIDs = bookIDs
+}
+
+func (fs *FakeSysFs) SetDrawerIDs(drawerIDs map[string]string) {
+	fs.drawerIDs = drawerIDs
+}
+
+func (fs *FakeSysFs) SetCPUCapacities(cpuCapacities map[string]string) {
+	fs.cpuCapacities = cpuCapacities
+}
+
+func (fs *FakeSysFs) SetMIDRs(midrs map[string]string) {
+	fs.midrs = midrs
+}
+
+// SetBlockDeviceStack sets the slaves of block devices and the disks of
+// partitions.
+func (fs *FakeSysFs) SetBlockDeviceStack(slaves map[string][]string, partitionDisks map[string]string) {
+	fs.blockDeviceSlaves = slaves
+	fs.blockDeviceDisks = partitionDisks
+}
+
 func (fs *FakeSysFs) SetMemory(memTotal string, err error) {
 	fs.memTotal = memTotal
 	fs.memErr = err
@@ -30,15 +30,21 @@ import (
 )
 
 const (
-	blockDir     = "/sys/block"
-	cacheDir     = "/sys/devices/system/cpu/cpu"
-	netDir       = "/sys/class/net"
-	dmiDir       = "/sys/class/dmi"
-	ppcDevTree   = "/proc/device-tree"
-	s390xDevTree = "/etc" // s390/s390x changes
+	blockDir      = "/sys/block"
+	blockClassDir = "/sys/class/block"
+	cacheDir      = "/sys/devices/system/cpu/cpu"
+	netDir        = "/sys/class/net"
+	dmiDir        = "/sys/class/dmi"
+	ppcDevTree    = "/proc/device-tree"
+	s390xDevTree  = "/etc" // s390/s390x changes
 
 	coreIDFilePath    = "/topology/core_id"
 	packageIDFilePath = "/topology/physical_package_id"
+	clusterIDFilePath = "/topology/cluster_id"
+	bookIDFilePath    = "/topology/book_id"
+	drawerIDFilePath  = "/topology/drawer_id"
+	capacityFilePath  = "/cpu_capacity"
+	midrFilePath      = "/regs/identification/midr_el1"
 	meminfoFile       = "meminfo"
 
 	cpuDirPattern  = "cpu*[0-9]"
@@ -50,6 +56,8 @@ const (
 
 var (
 	nodeDir = "/sys/devices/system/node/"
+
+	pciAddressRegexp = regexp.MustCompile(`^[0-9a-f]{4,}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
 )
 
 type CacheInfo struct {
@@ -73,6 +81,16 @@ type SysFs interface {
 	GetCoreID(coreIDFilePath string) (string, error)
 	// Get physical package id for specified CPU
 	GetCPUPhysicalPackageID(cpuPath string) (string, error)
+	// Get cluster id for specified CPU, empty if the kernel does not expose it.
+	GetCPUClusterID(cpuPath string) (string, error)
+	// Get s390x book id for specified CPU, empty on other architectures.
+	GetCPUBookID(cpuPath string) (string, error)
+	// Get s390x drawer id for specified CPU, empty on other architectures.
+	GetCPUDrawerID(cpuPath string) (string, error)
+	// Get relative compute capacity for specified CPU, empty if the kernel does not expose it.
+	GetCPUCapacity(cpuPath string) (string, error)
+	// Get arm64 Main ID Register for specified CPU, empty on other architectures.
+	GetCPUMIDR(cpuPath string) (string, error)
 	// Get total memory for specified NUMA node
 	GetMemInfo(nodeDir string) (string, error)
 	// Get hugepages from specified directory
@@ -87,12 +105,24 @@ type SysFs interface {
 	GetBlockDeviceScheduler(string) (string, error)
 	// Get device major:minor number string.
 	GetBlockDeviceNumbers(string) (string, error)
+	// Get PCI address of the device backing the block device, empty if it is not a PCI device.
+	GetBlockDevicePCIAddress(string) (string, error)
+	// Get an attribute of the block device, relative to its directory, e.g. queue/nr_requests.
+	GetBlockDeviceAttribute(name string, attribute string) (string, error)
+	// Get the names of the block devices or partitions the block device is built on, e.g. the
+	// physical volumes of an LVM volume or the paths of a multipath device.
+	GetBlockDeviceSlaves(name string) ([]string, error)
+	// Get the name of the disk containing the given partition, or the name itself if it is not
+	// a partition.
+	GetBlockDeviceDisk(name string) (string, error)
 
 	GetNetworkDevices() ([]os.FileInfo, error)
 	GetNetworkAddress(string) (string, error)
 	GetNetworkMtu(string) (string, error)
 	GetNetworkSpeed(string) (string, error)
 	GetNetworkStatValue(dev string, stat string) (uint64, error)
+	// Get PCI address of the device backing the network interface, empty if it is not a PCI device.
+	GetNetworkPCIAddress(string) (string, error)
 
 	// Get directory information for available caches accessible to given cpu.
 	GetCaches(id int) ([]os.FileInfo, error)
@@ -139,6 +169,38 @@ func (fs *realSysFs) GetCPUPhysicalPackageID(cpuPath string) (string, error) {
 	return strings.TrimSpace(string(packageID)), err
 }
 
+func (fs *realSysFs) GetCPUClusterID(cpuPath string) (string, error) {
+	return readOptionalCPUFile(cpuPath, clusterIDFilePath)
+}
+
+func (fs *realSysFs) GetCPUBookID(cpuPath string) (string, error) {
+	return readOptionalCPUFile(cpuPath, bookIDFilePath)
+}
+
+func (fs *realSysFs) GetCPUDrawerID(cpuPath string) (string, error) {
+	return readOptionalCPUFile(cpuPath, drawerIDFilePath)
+}
+
+func (fs *realSysFs) GetCPUCapacity(cpuPath string) (string, error) {
+	return readOptionalCPUFile(cpuPath, capacityFilePath)
+}
+
+func (fs *realSysFs) GetCPUMIDR(cpuPath string) (string, error) {
+	return readOptionalCPUFile(cpuPath, midrFilePath)
+}
+
+// readOptionalCPUFile reads a file below the CPU directory, the file missing
+// is not an error as not every architecture and kernel provides it.
+func readOptionalCPUFile(cpuPath string, file string) (string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("%s%s", cpuPath, file))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
 func (fs *realSysFs) GetMemInfo(nodePath string) (string, error) {
 	meminfoPath := fmt.Sprintf("%s/%s", nodePath, meminfoFile)
 	meminfo, err := ioutil.ReadFile(meminfoPath)
@@ -189,6 +251,47 @@ func (fs *realSysFs) GetBlockDeviceSize(name string) (string, error) {
 	return string(size), nil
 }
 
+func (fs *realSysFs) GetBlockDevicePCIAddress(name string) (string, error) {
+	return pciAddress(path.Join(blockDir, name))
+}
+
+func (fs *realSysFs) GetBlockDeviceAttribute(name string, attribute string) (string, error) {
+	value, err := ioutil.ReadFile(path.Join(blockDir, name, attribute))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func (fs *realSysFs) GetBlockDeviceSlaves(name string) ([]string, error) {
+	slaves, err := ioutil.ReadDir(path.Join(blockClassDir, name, "slaves"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(slaves))
+	for _, slave := range slaves {
+		names = append(names, slave.Name())
+	}
+	return names, nil
+}
+
+func (fs *realSysFs) GetBlockDeviceDisk(name string) (string, error) {
+	devicePath := path.Join(blockClassDir, name)
+	if _, err := os.Stat(path.Join(devicePath, "partition")); os.IsNotExist(err) {
+		return name, nil
+	} else if err != nil {
+		return "", err
+	}
+	// Partitions are subdirectories of their disk.
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(filepath.Dir(resolved)), nil
+}
+
 func (fs *realSysFs) GetNetworkDevices() ([]os.FileInfo, error) {
 	files, err := ioutil.ReadDir(netDir)
 	if err != nil {
@@ -249,6 +352,27 @@ func (fs *realSysFs) GetNetworkStatValue(dev string, stat string) (uint64, error
 	return s, nil
 }
 
+func (fs *realSysFs) GetNetworkPCIAddress(name string) (string, error) {
+	return pciAddress(path.Join(netDir, name))
+}
+
+// pciAddress resolves the sysfs entry of a device to its location in the
+// device tree, e.g. /sys/devices/pci0000:00/0000:00:1d.0/nvme/nvme0/nvme0n1,
+// and returns the address of the closest PCI device it hangs off. Virtual
+// devices have no PCI ancestor and yield an empty address.
+func pciAddress(devicePath string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return "", err
+	}
+	for dir := resolved; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if base := filepath.Base(dir); pciAddressRegexp.MatchString(base) {
+			return base, nil
+		}
+	}
+	return "", nil
+}
+
 func (fs *realSysFs) GetCaches(id int) ([]os.FileInfo, error) {
 	cpuPath := fmt.Sprintf("%s%d/cache", cacheDir, id)
 	return ioutil.ReadDir(cpuPath)
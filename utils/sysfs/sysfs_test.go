@@ -15,11 +15,14 @@
 package sysfs
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetNodes(t *testing.T) {
@@ -141,3 +144,34 @@ func TestIsCPUOnlineNoFileAndCPU0MustBeOnline(t *testing.T) {
 	online = sysFs.IsCPUOnline("./testdata/missing_online/node0/cpu33")
 	assert.False(t, online)
 }
+
+func TestPCIAddress(t *testing.T) {
+	// PCI addresses contain colons which are not allowed in file names of Go
+	// modules, so the sysfs tree is created by the test.
+	dir, err := ioutil.TempDir("", "sysfs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	devices := map[string]string{
+		"nvme0n1": "devices/pci0000:00/0000:00:1c.4/0000:3c:00.0/nvme/nvme0/nvme0n1",
+		"eth0":    "devices/pci0000:00/0000:00:03.0/virtio0/net/eth0",
+		"lo":      "devices/virtual/net/lo",
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "class"), 0755))
+	for name, device := range devices {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, device), 0755))
+		require.NoError(t, os.Symlink(filepath.Join("..", device), filepath.Join(dir, "class", name)))
+	}
+
+	for name, expected := range map[string]string{
+		"nvme0n1": "0000:3c:00.0",
+		"eth0":    "0000:00:03.0",
+		"lo":      "",
+	} {
+		address, err := pciAddress(filepath.Join(dir, "class", name))
+		assert.Nil(t, err)
+		assert.Equal(t, expected, address, name)
+	}
+
+	_, err = pciAddress(filepath.Join(dir, "class", "missing"))
+	assert.NotNil(t, err)
+}
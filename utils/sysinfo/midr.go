@@ -0,0 +1,72 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sysinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type midrPart struct {
+	implementer uint64
+	part        uint64
+}
+
+// Known arm64 cores by implementer and part number, see the MIDR_EL1
+// register description in the Arm Architecture Reference Manual and the
+// kernel's arch/arm64/include/asm/cputype.h.
+var midrParts = map[midrPart]string{
+	{0x41, 0xd03}: "cortex-a53",
+	{0x41, 0xd04}: "cortex-a35",
+	{0x41, 0xd05}: "cortex-a55",
+	{0x41, 0xd07}: "cortex-a57",
+	{0x41, 0xd08}: "cortex-a72",
+	{0x41, 0xd09}: "cortex-a73",
+	{0x41, 0xd0a}: "cortex-a75",
+	{0x41, 0xd0b}: "cortex-a76",
+	{0x41, 0xd0c}: "neoverse-n1",
+	{0x41, 0xd0d}: "cortex-a77",
+	{0x41, 0xd40}: "neoverse-v1",
+	{0x41, 0xd41}: "cortex-a78",
+	{0x41, 0xd44}: "cortex-x1",
+	{0x41, 0xd46}: "cortex-a510",
+	{0x41, 0xd47}: "cortex-a710",
+	{0x41, 0xd48}: "cortex-x2",
+	{0x41, 0xd49}: "neoverse-n2",
+	{0x41, 0xd4f}: "neoverse-v2",
+	{0x43, 0x0af}: "thunderx2",
+	{0x48, 0xd01}: "tsv110",
+	{0x50, 0x000}: "x-gene",
+	{0x61, 0x022}: "apple-icestorm",
+	{0x61, 0x023}: "apple-firestorm",
+	{0xc0, 0xac3}: "ampere-1",
+}
+
+// parseMIDR returns the core model encoded in the value of the MIDR_EL1
+// register, e.g. "0x00000000413fd0c1". Unknown cores are reported by their
+// implementer and part number.
+func parseMIDR(midr string) (string, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(midr, "0x"), 16, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid MIDR %q: %v", midr, err)
+	}
+	implementer := (value >> 24) & 0xff
+	part := (value >> 4) & 0xfff
+	if model, ok := midrParts[midrPart{implementer, part}]; ok {
+		return model, nil
+	}
+	return fmt.Sprintf("0x%02x:0x%03x", implementer, part), nil
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -88,12 +89,117 @@ func GetBlockDeviceInfo(sysfs sysfs.SysFs) (map[string]info.DiskInfo, error) {
 				diskInfo.Scheduler = string(matches[1])
 			}
 		}
+		getBlockDeviceQueueSettings(sysfs, &diskInfo)
+		if err := getBlockDeviceStack(sysfs, &diskInfo); err != nil {
+			klog.V(4).Infof("Unable to resolve the devices below block device %s: %v", name, err)
+		}
+		diskInfo.PCIAddress, err = sysfs.GetBlockDevicePCIAddress(name)
+		if err != nil {
+			klog.V(4).Infof("Unable to get PCI address of block device %s: %v", name, err)
+		}
 		device := fmt.Sprintf("%d:%d", diskInfo.Major, diskInfo.Minor)
 		diskMap[device] = diskInfo
 	}
 	return diskMap, nil
 }
 
+// getBlockDeviceQueueSettings reads the queue settings of the disk. Devices
+// lacking some of them, e.g. device mapper devices without a queue depth,
+// report the defaults.
+func getBlockDeviceQueueSettings(sysfs sysfs.SysFs, disk *info.DiskInfo) {
+	readUint := func(attribute string) uint64 {
+		value, err := sysfs.GetBlockDeviceAttribute(disk.Name, attribute)
+		if err != nil {
+			klog.V(4).Infof("Unable to read %s of block device %s: %v", attribute, disk.Name, err)
+			return 0
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			klog.V(4).Infof("Unable to parse %s of block device %s: %v", attribute, disk.Name, err)
+			return 0
+		}
+		return n
+	}
+	disk.Rotational = readUint("queue/rotational") == 1
+	disk.NrRequests = readUint("queue/nr_requests")
+	disk.QueueDepth = readUint("device/queue_depth")
+	disk.Discard = readUint("queue/discard_max_bytes") > 0
+	if writeCache, err := sysfs.GetBlockDeviceAttribute(disk.Name, "queue/write_cache"); err == nil {
+		disk.WriteCache = strings.TrimSpace(writeCache)
+	}
+}
+
+// getBlockDeviceStack resolves the device mapper name and type of the disk and
+// the devices it is built on, down to the physical disks.
+func getBlockDeviceStack(sysfs sysfs.SysFs, disk *info.DiskInfo) error {
+	if strings.HasPrefix(disk.Name, "dm-") {
+		if name, err := sysfs.GetBlockDeviceAttribute(disk.Name, "dm/name"); err == nil {
+			disk.DmName = strings.TrimSpace(name)
+		}
+		if uuid, err := sysfs.GetBlockDeviceAttribute(disk.Name, "dm/uuid"); err == nil {
+			disk.DmType = dmType(strings.TrimSpace(uuid))
+		}
+	}
+
+	slaves, err := sysfs.GetBlockDeviceSlaves(disk.Name)
+	if err != nil || len(slaves) == 0 {
+		return err
+	}
+	sort.Strings(slaves)
+	disk.Slaves = slaves
+	physical := map[string]struct{}{}
+	if err := findPhysicalDisks(sysfs, slaves, physical, 0); err != nil {
+		return err
+	}
+	for name := range physical {
+		disk.PhysicalDisks = append(disk.PhysicalDisks, name)
+	}
+	sort.Strings(disk.PhysicalDisks)
+	return nil
+}
+
+// maxBlockDeviceStackDepth protects against cycles in broken sysfs trees.
+const maxBlockDeviceStackDepth = 16
+
+// findPhysicalDisks adds the disks at the bottom of the stacks of the given
+// devices, which may be partitions, to physical.
+func findPhysicalDisks(sysfs sysfs.SysFs, devices []string, physical map[string]struct{}, depth int) error {
+	if depth > maxBlockDeviceStackDepth {
+		return fmt.Errorf("block device stack deeper than %d devices", maxBlockDeviceStackDepth)
+	}
+	for _, device := range devices {
+		slaves, err := sysfs.GetBlockDeviceSlaves(device)
+		if err != nil {
+			return err
+		}
+		if len(slaves) > 0 {
+			if err := findPhysicalDisks(sysfs, slaves, physical, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		disk, err := sysfs.GetBlockDeviceDisk(device)
+		if err != nil {
+			return err
+		}
+		physical[disk] = struct{}{}
+	}
+	return nil
+}
+
+// dmType returns the subsystem which created a device mapper device from
+// the prefix of its UUID, e.g. LVM-<uuid> or part1-mpath-<wwid>.
+func dmType(uuid string) string {
+	if uuid == "" {
+		return ""
+	}
+	prefix := strings.SplitN(uuid, "-", 2)[0]
+	if strings.HasPrefix(prefix, "part") {
+		return "part"
+	}
+	return prefix
+}
+
 // Get information about network devices present on the system.
 func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 	devs, err := sysfs.GetNetworkDevices()
@@ -143,6 +249,10 @@ func GetNetworkDevices(sysfs sysfs.SysFs) ([]info.NetInfo, error) {
 			}
 			netInfo.Speed = s
 		}
+		netInfo.PCIAddress, err = sysfs.GetNetworkPCIAddress(name)
+		if err != nil {
+			klog.V(4).Infof("Unable to get PCI address of network device %s: %v", name, err)
+		}
 		netDevices = append(netDevices, netInfo)
 	}
 	return netDevices, nil
@@ -245,6 +355,7 @@ func GetNodesInfo(sysFs sysfs.SysFs) ([]info.Node, int, error) {
 
 		nodes = append(nodes, node)
 	}
+	setCoreTypes(nodes)
 	return nodes, allLogicalCoresCount, err
 }
 
@@ -289,6 +400,7 @@ func getCPUTopology(sysFs sysfs.SysFs) ([]info.Node, int, error) {
 		}
 		nodes = append(nodes, node)
 	}
+	setCoreTypes(nodes)
 	return nodes, cpusCount, nil
 }
 
@@ -401,9 +513,24 @@ func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string) ([]info.Core, error) {
 			return nil, err
 		}
 
+		// On arm64 core ids are only unique within a cluster, on s390x within
+		// a book and a drawer.
+		clusterID, err := getOptionalInt(sysFs.GetCPUClusterID(cpuDir))
+		if err != nil {
+			return nil, err
+		}
+		bookID, err := getOptionalInt(sysFs.GetCPUBookID(cpuDir))
+		if err != nil {
+			return nil, err
+		}
+		drawerID, err := getOptionalInt(sysFs.GetCPUDrawerID(cpuDir))
+		if err != nil {
+			return nil, err
+		}
+
 		coreIDx := -1
 		for id, core := range cores {
-			if core.Id == physicalID {
+			if core.Id == physicalID && core.ClusterID == clusterID && core.BookID == bookID && core.DrawerID == drawerID {
 				coreIDx = id
 			}
 		}
@@ -414,6 +541,12 @@ func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string) ([]info.Core, error) {
 		desiredCore := &cores[coreIDx]
 
 		desiredCore.Id = physicalID
+		desiredCore.ClusterID = clusterID
+		desiredCore.BookID = bookID
+		desiredCore.DrawerID = drawerID
+		if err := addArmCoreInfo(sysFs, cpuDir, desiredCore); err != nil {
+			return nil, err
+		}
 		if len(desiredCore.Threads) == 0 {
 			desiredCore.Threads = []int{cpuID}
 		} else {
@@ -437,6 +570,72 @@ func getCoresInfo(sysFs sysfs.SysFs, cpuDirs []string) ([]info.Core, error) {
 	return cores, nil
 }
 
+// addArmCoreInfo adds the capacity and the MIDR derived model of the CPU to
+// the core, both are only exposed on some platforms.
+func addArmCoreInfo(sysFs sysfs.SysFs, cpuDir string, core *info.Core) error {
+	capacity, err := sysFs.GetCPUCapacity(cpuDir)
+	if err != nil {
+		return err
+	}
+	if capacity != "" {
+		core.Capacity, err = strconv.ParseUint(capacity, 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+
+	midr, err := sysFs.GetCPUMIDR(cpuDir)
+	if err != nil {
+		return err
+	}
+	if midr != "" {
+		core.Model, err = parseMIDR(midr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCoreTypes marks the cores of heterogeneous (big.LITTLE) machines as
+// performance or efficiency cores based on their capacity.
+func setCoreTypes(nodes []info.Node) {
+	maxCapacity := uint64(0)
+	heterogeneous := false
+	for _, node := range nodes {
+		for _, core := range node.Cores {
+			if maxCapacity != 0 && core.Capacity != maxCapacity {
+				heterogeneous = true
+			}
+			if core.Capacity > maxCapacity {
+				maxCapacity = core.Capacity
+			}
+		}
+	}
+	if !heterogeneous {
+		return
+	}
+	for i := range nodes {
+		for j := range nodes[i].Cores {
+			core := &nodes[i].Cores[j]
+			if core.Capacity == maxCapacity {
+				core.Type = info.CoreTypePerformance
+			} else {
+				core.Type = info.CoreTypeEfficiency
+			}
+		}
+	}
+}
+
+// getOptionalInt parses the value returned by a sysfs getter that returns an
+// empty value for files the kernel does not expose, which are reported as 0.
+func getOptionalInt(value string, err error) (int, error) {
+	if err != nil || value == "" {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
+
 // GetCacheInfo return information about a cache accessible from the given cpu thread
 func GetCacheInfo(sysFs sysfs.SysFs, id int) ([]sysfs.CacheInfo, error) {
 	caches, err := sysFs.GetCaches(id)
@@ -1127,6 +1127,45 @@ func TestGetCoresInfoWithOnlineOfflineFile(t *testing.T) {
 	assert.Equal(t, expected, cores)
 }
 
+func TestGetCoresInfoOnArm64(t *testing.T) {
+	sysFs := &fakesysfs.FakeSysFs{}
+	cpus := []string{
+		"/fakeSysfs/devices/system/node/node0/cpu0",
+		"/fakeSysfs/devices/system/node/node0/cpu1",
+		"/fakeSysfs/devices/system/node/node0/cpu2",
+	}
+	sysFs.SetOnlineCPUs(map[string]interface{}{cpus[0]: nil, cpus[1]: nil, cpus[2]: nil})
+	sysFs.SetCoreThreads(map[string]string{cpus[0]: "0", cpus[1]: "1", cpus[2]: "0"}, nil)
+	sysFs.SetPhysicalPackageIDs(map[string]string{cpus[0]: "0", cpus[1]: "0", cpus[2]: "0"}, nil)
+	sysFs.SetClusterIDs(map[string]string{cpus[0]: "0", cpus[1]: "0", cpus[2]: "1"})
+	sysFs.SetCPUCapacities(map[string]string{cpus[0]: "446", cpus[1]: "446", cpus[2]: "1024"})
+	sysFs.SetMIDRs(map[string]string{cpus[0]: "0x00000000412fd050", cpus[1]: "0x00000000412fd050", cpus[2]: "0x00000000414fd0b0"})
+
+	cores, err := getCoresInfo(sysFs, cpus)
+	assert.NoError(t, err)
+	nodes := []info.Node{{Cores: cores}}
+	setCoreTypes(nodes)
+	expected := []info.Core{
+		{Id: 0, Threads: []int{0}, ClusterID: 0, Capacity: 446, Model: "cortex-a55", Type: info.CoreTypeEfficiency},
+		{Id: 1, Threads: []int{1}, ClusterID: 0, Capacity: 446, Model: "cortex-a55", Type: info.CoreTypeEfficiency},
+		{Id: 0, Threads: []int{2}, ClusterID: 1, Capacity: 1024, Model: "cortex-a76", Type: info.CoreTypePerformance},
+	}
+	assert.Equal(t, expected, nodes[0].Cores)
+}
+
+func TestParseMIDR(t *testing.T) {
+	model, err := parseMIDR("0x00000000413fd0c1")
+	assert.NoError(t, err)
+	assert.Equal(t, "neoverse-n1", model)
+
+	model, err = parseMIDR("0x00000000461fd010")
+	assert.NoError(t, err)
+	assert.Equal(t, "0x46:0xd01", model)
+
+	_, err = parseMIDR("unknown")
+	assert.Error(t, err)
+}
+
 func TestGetBlockDeviceInfo(t *testing.T) {
 	fakeSys := fakesysfs.FakeSysFs{}
 	disks, err := GetBlockDeviceInfo(&fakeSys)
@@ -1151,6 +1190,47 @@ func TestGetBlockDeviceInfo(t *testing.T) {
 	if disk.Scheduler != "cfq" {
 		t.Errorf("expected to get scheduler type of cfq. Got %q", disk.Scheduler)
 	}
+	if disk.PCIAddress != "0000:00:04.0" {
+		t.Errorf("expected to get PCI address 0000:00:04.0. Got %q", disk.PCIAddress)
+	}
+	assert.True(t, disk.Rotational)
+	assert.Equal(t, uint64(64), disk.NrRequests)
+	assert.Equal(t, uint64(32), disk.QueueDepth)
+	assert.Equal(t, "write back", disk.WriteCache)
+	assert.True(t, disk.Discard)
+}
+
+func TestGetBlockDeviceStack(t *testing.T) {
+	fakeSys := fakesysfs.FakeSysFs{}
+	// An LVM volume spanning a LUKS device on sda2 and a multipath device.
+	fakeSys.SetBlockDeviceStack(map[string][]string{
+		"dm-1": {"dm-0", "dm-2"},
+		"dm-0": {"sda2"},
+		"dm-2": {"sdc", "sdb"},
+	}, map[string]string{"sda2": "sda"})
+
+	disk := info.DiskInfo{Name: "dm-1"}
+	assert.NoError(t, getBlockDeviceStack(&fakeSys, &disk))
+	assert.Equal(t, "vg0-root", disk.DmName)
+	assert.Equal(t, "LVM", disk.DmType)
+	assert.Equal(t, []string{"dm-0", "dm-2"}, disk.Slaves)
+	assert.Equal(t, []string{"sda", "sdb", "sdc"}, disk.PhysicalDisks)
+
+	disk = info.DiskInfo{Name: "sdb"}
+	assert.NoError(t, getBlockDeviceStack(&fakeSys, &disk))
+	assert.Equal(t, info.DiskInfo{Name: "sdb"}, disk)
+
+	fakeSys.SetBlockDeviceStack(map[string][]string{"dm-0": {"dm-0"}}, nil)
+	disk = info.DiskInfo{Name: "dm-0"}
+	assert.Error(t, getBlockDeviceStack(&fakeSys, &disk))
+}
+
+func TestDmType(t *testing.T) {
+	assert.Equal(t, "LVM", dmType("LVM-Lk1hW8SJ3sDvoLbVgwp8t8iLkTCC3cHi"))
+	assert.Equal(t, "CRYPT", dmType("CRYPT-LUKS2-4f1b2c0e7d6a4b8e9c1d2e3f4a5b6c7d-luks"))
+	assert.Equal(t, "mpath", dmType("mpath-3600508b400105e210000900000490000"))
+	assert.Equal(t, "part", dmType("part1-mpath-3600508b400105e210000900000490000"))
+	assert.Equal(t, "", dmType(""))
 }
 
 func TestGetNetworkDevices(t *testing.T) {
@@ -1176,6 +1256,9 @@ func TestGetNetworkDevices(t *testing.T) {
 	if eth.MacAddress != "42:01:02:03:04:f4" {
 		t.Errorf("expected mac address to be '42:01:02:03:04:f4'. Found %q", eth.MacAddress)
 	}
+	if eth.PCIAddress != "0000:00:03.0" {
+		t.Errorf("expected PCI address to be 0000:00:03.0. Found %q", eth.PCIAddress)
+	}
 }
 
 func TestIgnoredNetworkDevices(t *testing.T) {
@@ -1,4 +1,4 @@
-// Copyright 2015 Google Inc. All Rights Reserved.
+// Copyright 2014 Google Inc. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -14,7 +14,12 @@
 
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 // Returns a mask of all cores on the machine if the passed-in mask is empty.
 func FixCpuMask(mask string, cores int) string {
@@ -27,3 +32,56 @@ func FixCpuMask(mask string, cores int) string {
 	}
 	return mask
 }
+
+// ParseCpuList parses a list of CPUs in the kernel's list format, e.g.
+// "0-3,8,10-11", into a sorted list of CPU ids.
+func ParseCpuList(list string) ([]int, error) {
+	cpus := map[int]struct{}{}
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return []int{}, nil
+	}
+	for _, item := range strings.Split(list, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list %q: %v", list, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid cpu list %q: %v", list, err)
+			}
+		}
+		if last < first {
+			return nil, fmt.Errorf("invalid cpu list %q: range %q is decreasing", list, item)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus[cpu] = struct{}{}
+		}
+	}
+	result := make([]int, 0, len(cpus))
+	for cpu := range cpus {
+		result = append(result, cpu)
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// FormatCpuList formats a sorted list of CPU ids in the kernel's list format.
+func FormatCpuList(cpus []int) string {
+	ranges := []string{}
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.Itoa(cpus[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCpuList(t *testing.T) {
+	for list, expected := range map[string][]int{
+		"":              {},
+		"0":             {0},
+		"0-3,8,10-11\n": {0, 1, 2, 3, 8, 10, 11},
+		"4,0-1,1":       {0, 1, 4},
+	} {
+		cpus, err := ParseCpuList(list)
+		assert.Nil(t, err, list)
+		assert.Equal(t, expected, cpus, list)
+	}
+
+	for _, list := range []string{"(null)", "1-", "3-1", "0,,1"} {
+		_, err := ParseCpuList(list)
+		assert.NotNil(t, err, list)
+	}
+}
+
+func TestFormatCpuList(t *testing.T) {
+	assert.Equal(t, "", FormatCpuList(nil))
+	assert.Equal(t, "5", FormatCpuList([]int{5}))
+	assert.Equal(t, "0-3,8,10-11", FormatCpuList([]int{0, 1, 2, 3, 8, 10, 11}))
+}
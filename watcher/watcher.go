@@ -28,6 +28,9 @@ type ContainerWatchSource int
 
 const (
 	Raw ContainerWatchSource = iota
+	// AdHoc is the source of the cgroups monitored on request, and of the
+	// nested cgroups of the containers opted in.
+	AdHoc
 )
 
 // ContainerEvent represents a